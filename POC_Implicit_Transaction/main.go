@@ -6,50 +6,77 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
-	_ "github.com/sijms/go-ora/v2"
+	"sql-learn2/bulkinsert"
+	"sql-learn2/logging"
+	"sql-learn2/oraconn"
+	"sql-learn2/sessioncheck"
+
+	"github.com/jmoiron/sqlx"
 )
 
 func main() {
 	// Flags
-	setup := flag.Bool("setup", false, "Create table and insert 1,000,000 rows")
+	setup := flag.Bool("setup", false, "Create table and insert -rows rows")
+	rows := flag.Int("rows", 1000000, "Number of rows to prepare in setup mode")
+	batchSize := flag.Int("batch", 100000, "Rows per array-bound INSERT in setup mode")
 	test := flag.Bool("test", false, "Run update with timeout to demonstrate implicit transaction")
 	timeout := flag.Duration("timeout", 100000*time.Millisecond, "Timeout for update operation in test mode")
+	matrix := flag.Bool("matrix", false, "Run every update mode (implicit, explicit tx, prepared, chunked) under each -timeouts value and print a comparison")
+	timeouts := flag.String("timeouts", "100ms,500ms,2s,10s", "Comma separated timeouts for -matrix")
+	chunk := flag.Int("chunk", 100000, "Rows per chunk for the chunked mode in -matrix")
+	repeat := flag.Int("repeat", 1, "Runs per mode and timeout in -matrix")
+	adminUser := flag.String("admin-user", oraconn.Getenv("ORA_ADMIN_USER", ""), "Privileged user for checking the cancelled session server-side in test mode")
+	adminPass := flag.String("admin-pass", oraconn.Getenv("ORA_ADMIN_PASS", ""), "Password for -admin-user")
+	verifyWait := flag.Duration("verify-wait", 30*time.Second, "How long to watch the cancelled session in test mode")
 
 	// Connection flags
-	user := flag.String("user", getEnv("ORA_USER", "LEARN1"), "Oracle username")
-	pass := flag.String("pass", getEnv("ORA_PASS", "Welcome"), "Oracle password")
-	host := flag.String("host", getEnv("ORA_HOST", "localhost"), "Oracle host")
-	port := flag.String("port", getEnv("ORA_PORT", "1521"), "Oracle port")
-	service := flag.String("service", getEnv("ORA_SERVICE", "XE"), "Oracle service name")
+	var ora oraconn.Config
+	ora.RegisterFlags(flag.CommandLine)
+	var logCfg logging.Config
+	logCfg.RegisterFlags(flag.CommandLine)
 
 	flag.Parse()
+	if _, err := logCfg.Setup(); err != nil {
+		log.Fatalf("%v", err)
+	}
 
-	dsn := fmt.Sprintf("oracle://%s:%s@%s:%s/%s", *user, *pass, *host, *port, *service)
-	db, err := sql.Open("oracle", dsn)
-	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
+	if *rows <= 0 || *batchSize <= 0 || *chunk <= 0 {
+		log.Fatalf("-rows, -batch and -chunk must be positive")
 	}
-	defer db.Close()
 
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+	db, err := oraconn.Connect(context.Background(), ora)
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
 	}
-	log.Println("Connected to Oracle.")
+	defer db.Close()
+	log.Printf("Connected to Oracle: %s", ora)
 
 	if *setup {
-		runSetup(db)
+		runSetup(db, *rows, *batchSize)
 	} else if *test {
-		runTest(db, *timeout)
+		var admin *sql.DB
+		if *adminUser != "" {
+			if admin, err = oraconn.Open(ora.WithCredentials(*adminUser, *adminPass)); err != nil {
+				log.Fatalf("Failed to open admin connection: %v", err)
+			}
+			defer admin.Close()
+		}
+		runTest(db, admin, *timeout, *verifyWait)
+	} else if *matrix {
+		list, err := parseDurations(*timeouts)
+		if err != nil {
+			log.Fatalf("Invalid -timeouts: %v", err)
+		}
+		runMatrix(db, list, *chunk, max(*repeat, 1))
 	} else {
-		fmt.Println("Please specify -setup or -test")
+		fmt.Println("Please specify -setup, -test or -matrix")
 		flag.Usage()
 	}
 }
 
-func runSetup(db *sql.DB) {
+func runSetup(db *sql.DB, rows, batchSize int) {
 	log.Println("Running setup...")
 
 	// Create table if not exists
@@ -75,8 +102,8 @@ func runSetup(db *sql.DB) {
 		log.Fatalf("Failed to count rows: %v", err)
 	}
 
-	if count == 1000000 {
-		log.Println("Table already has 1,000,000 rows. Resetting dates...")
+	if count == rows {
+		log.Printf("Table already has %d rows. Resetting dates...", rows)
 		_, err = db.Exec("UPDATE Implicit SET updated_at = TO_DATE('2000-01-01', 'YYYY-MM-DD')")
 		if err != nil {
 			log.Fatalf("Failed to reset dates: %v", err)
@@ -85,29 +112,47 @@ func runSetup(db *sql.DB) {
 		return
 	}
 
-	log.Printf("Table has %d rows. Truncating and inserting 1,000,000 rows... (this may take 10-30 seconds)", count)
+	log.Printf("Table has %d rows. Truncating and inserting %d rows...", count, rows)
 	// Truncate
 	_, err = db.Exec("TRUNCATE TABLE Implicit")
 	if err != nil {
 		log.Fatalf("Failed to truncate: %v", err)
 	}
 
-	// Bulk insert using PL/SQL
-	_, err = db.Exec(`
-		BEGIN
-			FOR i IN 1..1000000 LOOP
-				INSERT INTO Implicit (id, updated_at) VALUES (i, TO_DATE('2000-01-01', 'YYYY-MM-DD'));
-			END LOOP;
-			COMMIT;
-		END;
-	`)
-	if err != nil {
-		log.Fatalf("Failed to insert rows: %v", err)
+	// Bulk insert using array binding, one commit per batch
+	start := time.Now()
+	base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	xdb := sqlx.NewDb(db, "oracle")
+	for first := 1; first <= rows; first += batchSize {
+		n := min(batchSize, rows-first+1)
+		ids := make([]int64, n)
+		dates := make([]time.Time, n)
+		for i := range n {
+			ids[i] = int64(first + i)
+			dates[i] = base
+		}
+		if _, err := bulkinsert.InsertBatched(context.Background(), xdb, "Implicit", []string{"id", "updated_at"}, ids, dates); err != nil {
+			log.Fatalf("Failed to insert rows %d-%d: %v", first, first+n-1, err)
+		}
 	}
-	log.Println("Inserted 1,000,000 rows with date 2000-01-01.")
+	log.Printf("Inserted %d rows with date 2000-01-01 in %v.", rows, time.Since(start))
 }
 
-func runTest(db *sql.DB, timeout time.Duration) {
+func runTest(db, admin *sql.DB, timeout, verifyWait time.Duration) {
+	// A dedicated connection so the session can be identified and watched
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to get connection: %v", err)
+	}
+	defer conn.Close()
+
+	var session sessioncheck.Session
+	if admin != nil {
+		if session, err = sessioncheck.Identify(context.Background(), conn, "POC_IMPLICIT_TX"); err != nil {
+			log.Fatalf("Failed to identify session: %v", err)
+		}
+		log.Printf("Update runs in %s", session)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
@@ -116,7 +161,7 @@ func runTest(db *sql.DB, timeout time.Duration) {
 	start := time.Now()
 
 	// Run update without explicit transaction
-	_, err := db.ExecContext(ctx, "UPDATE Implicit SET updated_at = SYSDATE")
+	_, err = conn.ExecContext(ctx, "UPDATE Implicit SET updated_at = SYSDATE")
 	duration := time.Since(start)
 
 	if err == nil {
@@ -126,6 +171,12 @@ func runTest(db *sql.DB, timeout time.Duration) {
 		log.Printf("Operation duration: %v", duration)
 	}
 
+	// Did the statement (and its rollback) really stop server-side?
+	if admin != nil && err != nil {
+		report := sessioncheck.Verify(context.Background(), admin, session, verifyWait, 200*time.Millisecond)
+		fmt.Print(report.Render())
+	}
+
 	// Verify results
 	var updatedCount int
 	err = db.QueryRow("SELECT COUNT(*) FROM Implicit WHERE updated_at > TO_DATE('2000-01-01', 'YYYY-MM-DD')").Scan(&updatedCount)
@@ -133,12 +184,17 @@ func runTest(db *sql.DB, timeout time.Duration) {
 		log.Fatalf("Failed to query rows: %v", err)
 	}
 
-	log.Printf("Rows updated: %d / 1,000,000", updatedCount)
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM Implicit").Scan(&total); err != nil {
+		log.Fatalf("Failed to count rows: %v", err)
+	}
+
+	log.Printf("Rows updated: %d / %d", updatedCount, total)
 
 	if updatedCount == 0 {
 		log.Println("RESULT: SUCCESS. No rows were updated.")
 		log.Println("Conclusion: The implicit transaction was successfully rolled back upon timeout/cancellation.")
-	} else if updatedCount == 1000000 {
+	} else if updatedCount == total {
 		log.Println("RESULT: FAILURE (for test purpose). All rows were updated.")
 		log.Println("Conclusion: The operation finished before the timeout.")
 	} else {
@@ -146,10 +202,3 @@ func runTest(db *sql.DB, timeout time.Duration) {
 		log.Println("Conclusion: Partial update occurred? (Should not happen in atomic transaction).")
 	}
 }
-
-func getEnv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
-	}
-	return fallback
-}
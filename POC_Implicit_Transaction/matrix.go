@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// updateMode is one way of issuing the big UPDATE
+type updateMode struct {
+	Name string
+	Run  func(ctx context.Context, db *sql.DB) error
+}
+
+const updateSQL = "UPDATE Implicit SET updated_at = SYSDATE"
+
+func updateModes(chunkSize int) []updateMode {
+	return []updateMode{
+		{Name: "implicit", Run: func(ctx context.Context, db *sql.DB) error {
+			_, err := db.ExecContext(ctx, updateSQL)
+			return err
+		}},
+		{Name: "explicit-tx", Run: func(ctx context.Context, db *sql.DB) error {
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback()
+			if _, err := tx.ExecContext(ctx, updateSQL); err != nil {
+				return err
+			}
+			return tx.Commit()
+		}},
+		{Name: "prepared", Run: func(ctx context.Context, db *sql.DB) error {
+			stmt, err := db.PrepareContext(ctx, updateSQL)
+			if err != nil {
+				return err
+			}
+			defer stmt.Close()
+			_, err = stmt.ExecContext(ctx)
+			return err
+		}},
+		{Name: fmt.Sprintf("chunked-%d", chunkSize), Run: func(ctx context.Context, db *sql.DB) error {
+			var maxID int
+			if err := db.QueryRowContext(ctx, "SELECT NVL(MAX(id), 0) FROM Implicit").Scan(&maxID); err != nil {
+				return err
+			}
+			// Each chunk is its own implicit transaction, committed on success
+			for lo := 1; lo <= maxID; lo += chunkSize {
+				if _, err := db.ExecContext(ctx, updateSQL+" WHERE id BETWEEN :1 AND :2", lo, lo+chunkSize-1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+	}
+}
+
+// matrixResult is one cell of the comparison
+type matrixResult struct {
+	Mode     string
+	Timeout  time.Duration
+	Duration time.Duration
+	Err      error
+	Updated  int
+	Total    int
+}
+
+func (r matrixResult) outcome() string {
+	switch r.Updated {
+	case 0:
+		return "NONE (rolled back)"
+	case r.Total:
+		return "ALL"
+	}
+	return "PARTIAL"
+}
+
+func resetDates(db *sql.DB) error {
+	_, err := db.Exec("UPDATE Implicit SET updated_at = TO_DATE('2000-01-01', 'YYYY-MM-DD')")
+	return err
+}
+
+// runMatrix runs every mode under every timeout, resetting the table between runs
+func runMatrix(db *sql.DB, timeouts []time.Duration, chunkSize, repeat int) {
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM Implicit").Scan(&total); err != nil {
+		log.Fatalf("Failed to count rows: %v", err)
+	}
+	if total == 0 {
+		log.Fatalf("Table Implicit is empty; run -setup first")
+	}
+
+	var results []matrixResult
+	for _, mode := range updateModes(chunkSize) {
+		for _, timeout := range timeouts {
+			for i := 0; i < repeat; i++ {
+				if err := resetDates(db); err != nil {
+					log.Fatalf("Failed to reset dates: %v", err)
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				start := time.Now()
+				err := mode.Run(ctx, db)
+				duration := time.Since(start)
+				cancel()
+
+				var updated int
+				if qerr := db.QueryRow("SELECT COUNT(*) FROM Implicit WHERE updated_at > TO_DATE('2000-01-01', 'YYYY-MM-DD')").Scan(&updated); qerr != nil {
+					log.Fatalf("Failed to query rows: %v", qerr)
+				}
+				r := matrixResult{Mode: mode.Name, Timeout: timeout, Duration: duration, Err: err, Updated: updated, Total: total}
+				log.Printf("%-16s timeout=%-8v run %d: %s, %d/%d rows, %v", r.Mode, r.Timeout, i+1, r.outcome(), r.Updated, r.Total, r.Duration.Round(time.Millisecond))
+				results = append(results, r)
+			}
+		}
+	}
+	if err := resetDates(db); err != nil {
+		log.Printf("Warning: failed to reset dates after matrix: %v", err)
+	}
+	printMatrix(results)
+}
+
+func printMatrix(results []matrixResult) {
+	fmt.Println()
+	fmt.Println("=== Commit behavior matrix ===")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODE\tTIMEOUT\tDURATION\tROWS UPDATED\tOUTCOME\tERROR")
+	for _, r := range results {
+		errText := "-"
+		if r.Err != nil {
+			errText = firstLine(r.Err.Error())
+		}
+		fmt.Fprintf(w, "%s\t%v\t%v\t%d / %d\t%s\t%s\n", r.Mode, r.Timeout, r.Duration.Round(time.Millisecond), r.Updated, r.Total, r.outcome(), errText)
+	}
+	w.Flush()
+
+	fmt.Println()
+	fmt.Println("Only chunked mode can leave PARTIAL results: each chunk commits on its own,")
+	fmt.Println("while the single-statement modes are all-or-nothing.")
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	if len(s) > 60 {
+		s = s[:57] + "..."
+	}
+	return s
+}
+
+// parseDurations parses a comma separated list such as "100ms,1s,5s"
+func parseDurations(s string) ([]time.Duration, error) {
+	var out []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %q: %w", part, err)
+		}
+		out = append(out, d)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no durations given")
+	}
+	return out, nil
+}
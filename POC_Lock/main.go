@@ -2,42 +2,35 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"flag"
-	"fmt"
 	"log"
-	"os"
 	"sync"
 	"time"
 
-	_ "github.com/sijms/go-ora/v2"
+	"sql-learn2/logging"
+	"sql-learn2/oraconn"
 )
 
 func main() {
 	// Connection flags
-	user := flag.String("user", getEnv("ORA_USER", "LEARN1"), "Oracle username")
-	pass := flag.String("pass", getEnv("ORA_PASS", "Welcome"), "Oracle password")
-	host := flag.String("host", getEnv("ORA_HOST", "localhost"), "Oracle host")
-	port := flag.String("port", getEnv("ORA_PORT", "1521"), "Oracle port")
-	service := flag.String("service", getEnv("ORA_SERVICE", "XE"), "Oracle service name")
+	var ora oraconn.Config
+	ora.RegisterFlags(flag.CommandLine)
+	var logCfg logging.Config
+	logCfg.RegisterFlags(flag.CommandLine)
 	flag.Parse()
+	if _, err := logCfg.Setup(); err != nil {
+		log.Fatalf("%v", err)
+	}
 
-	// Build DSN
-	dsn := fmt.Sprintf("oracle://%s:%s@%s:%s/%s", *user, *pass, *host, *port, *service)
+	ctx := context.Background()
 
 	// Connect
-	db, err := sql.Open("oracle", dsn)
+	db, err := oraconn.Connect(ctx, ora)
 	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
+		log.Fatalf("Failed to connect: %v", err)
 	}
 	defer db.Close()
-
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
-	}
-	log.Println("✓ Connected to Oracle")
-
-	ctx := context.Background()
+	log.Printf("✓ Connected to Oracle: %s", ora)
 
 	// Step 1: Cleanup and setup tables
 	log.Println("Step 1: Cleaning up and creating tables A, B, C, EVENT_LOG...")
@@ -99,10 +92,3 @@ func main() {
 
 	log.Println("\n✓ Demo completed successfully")
 }
-
-func getEnv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
-	}
-	return fallback
-}
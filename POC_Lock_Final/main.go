@@ -4,58 +4,164 @@ import (
 	"context"
 	"database/sql"
 	"flag"
-	"fmt"
 	"log"
-	"os"
 	"time"
 
-	_ "github.com/sijms/go-ora/v2"
+	"sql-learn2/lockflow"
+	"sql-learn2/logging"
+	"sql-learn2/oraconn"
 )
 
 func main() {
 	// Connection flags
-	user := flag.String("user", getEnv("ORA_USER", "LEARN1"), "Oracle username")
-	pass := flag.String("pass", getEnv("ORA_PASS", "Welcome"), "Oracle password")
-	host := flag.String("host", getEnv("ORA_HOST", "localhost"), "Oracle host")
-	port := flag.String("port", getEnv("ORA_PORT", "1521"), "Oracle port")
-	service := flag.String("service", getEnv("ORA_SERVICE", "XE"), "Oracle service name")
+	var ora oraconn.Config
+	ora.RegisterFlags(flag.CommandLine)
+	var logCfg logging.Config
+	logCfg.RegisterFlags(flag.CommandLine)
 	hideExpected := flag.Bool("hide-expected", true, "Hide expected timeline flows")
+	deadlock := flag.Bool("deadlock", false, "Run the canned deadlock scenario (ORA-00060) instead of the default flows")
+	scenarioPath := flag.String("scenario", oraconn.Getenv("LOCK_SCENARIO", ""), "Path to a YAML/JSON scenario file (default: built-in CHAIN/EARLY flows)")
+	lockMonitor := flag.Bool("lock-monitor", false, "Poll v$session/v$lock during the run and report who blocked whom (needs SELECT_CATALOG_ROLE)")
+	lockMonitorInterval := flag.Duration("lock-monitor-interval", 200*time.Millisecond, "Polling interval for -lock-monitor")
+	timelineOut := flag.String("timeline-out", "", "Also write the timeline as a Gantt chart (.svg or .html)")
+	eventsJSON := flag.String("events-json", "", "Write all timeline events and event log entries to this JSON file")
+	chaos := flag.Bool("chaos", false, "Inject random delays and (with -chaos-admin-user) session kills")
+	chaosSeed := flag.Int64("chaos-seed", 0, "Chaos random seed (0 = time-based)")
+	chaosMaxDelay := flag.Duration("chaos-max-delay", 500*time.Millisecond, "Upper bound of injected delays")
+	chaosDelayProb := flag.Float64("chaos-delay-prob", 0.3, "Probability of a delay before each SQL step")
+	chaosKillProb := flag.Float64("chaos-kill-prob", 0.2, "Probability of killing the session after each SQL step")
+	chaosKillFlow := flag.String("chaos-kill-flow", "", "Only kill this flow's session")
+	chaosMaxKills := flag.Int("chaos-max-kills", 1, "Maximum number of sessions to kill")
+	chaosAdminUser := flag.String("chaos-admin-user", oraconn.Getenv("ORA_ADMIN_USER", ""), "Privileged user for ALTER SYSTEM KILL SESSION")
+	chaosAdminPass := flag.String("chaos-admin-pass", oraconn.Getenv("ORA_ADMIN_PASS", ""), "Password for -chaos-admin-user")
+	logFallback := flag.String("log-fallback", "event_log_fallback.jsonl", "File for events that cannot be written to EVENT_LOG (empty disables)")
+	timelineWidth := flag.Int("timeline-width", 60, "Width of the ASCII timeline in characters")
+	timelineResolution := flag.Duration("timeline-resolution", 0, "Time per timeline character, e.g. 100ms (overrides -timeline-width)")
+	serverStats := flag.Bool("server-stats", false, "Capture per-step DB time, CPU and lock wait from v$sess_time_model/v$session_event")
 	flag.Parse()
-
-	// Build DSN
-	dsn := fmt.Sprintf("oracle://%s:%s@%s:%s/%s", *user, *pass, *host, *port, *service)
+	if _, err := logCfg.Setup(); err != nil {
+		log.Fatalf("%v", err)
+	}
 
 	// Connect
-	db, err := sql.Open("oracle", dsn)
+	db, err := oraconn.Connect(context.Background(), ora)
 	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
+		log.Fatalf("Failed to connect: %v", err)
 	}
 	defer db.Close()
-
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+	log.Printf("✓ Connected to Oracle: %s", ora)
+
+	var scenario *lockflow.Scenario
+	if *scenarioPath != "" {
+		scenario, err = lockflow.LoadScenario(*scenarioPath)
+		if err != nil {
+			log.Fatalf("Failed to load scenario: %v", err)
+		}
+		log.Printf("✓ Loaded scenario %s (%d flows)", *scenarioPath, len(scenario.Flows))
 	}
-	log.Println("✓ Connected to Oracle")
 
 	ctx := context.Background()
 
 	// Step 1: Cleanup and setup tables
 	log.Println("Step 1: Cleaning up and creating tables A, B, C, EVENT_LOG...")
-	if err := CleanupTables(ctx, db); err != nil {
+	if err := lockflow.CleanupTables(ctx, db); err != nil {
 		log.Fatalf("Cleanup failed: %v", err)
 	}
-	if err := CreateTables(ctx, db); err != nil {
+	if err := lockflow.CreateTables(ctx, db); err != nil {
 		log.Fatalf("Table creation failed: %v", err)
 	}
+	if scenario != nil {
+		if err := scenario.RunSetup(ctx, db); err != nil {
+			log.Fatalf("Scenario setup failed: %v", err)
+		}
+	}
 	log.Println("✓ Tables created and sample data inserted")
 
 	// Step 2: Initialize Runner
-	runner := NewRunner(db)
+	runner := lockflow.NewRunner(db)
 	defer runner.Close()
+	runner.SetTimelineOptions(lockflow.TimelineOptions{Width: *timelineWidth, Resolution: *timelineResolution})
+	if *logFallback != "" {
+		if err := runner.SetLogFallback(*logFallback); err != nil {
+			log.Fatalf("Failed to set log fallback: %v", err)
+		}
+	}
+	if *chaos {
+		var admin *sql.DB
+		if *chaosAdminUser != "" {
+			admin, err = oraconn.Connect(ctx, ora.WithCredentials(*chaosAdminUser, *chaosAdminPass))
+			if err != nil {
+				log.Fatalf("Failed to connect admin: %v", err)
+			}
+			defer admin.Close()
+		}
+		cfg := lockflow.ChaosConfig{
+			Seed:      *chaosSeed,
+			MaxDelay:  *chaosMaxDelay,
+			DelayProb: *chaosDelayProb,
+			KillProb:  *chaosKillProb,
+			KillFlow:  *chaosKillFlow,
+			MaxKills:  *chaosMaxKills,
+		}
+		logChaosConfig(cfg, admin)
+		runner.EnableChaos(cfg, admin)
+	}
+	if *serverStats {
+		runner.EnableServerStats()
+	}
+	if *lockMonitor {
+		runner.EnableLockMonitor(*lockMonitorInterval)
+	}
 
 	// Step 3: Define Flows
 	log.Println("Step 3: Defining Flows...")
 
+	switch {
+	case scenario != nil:
+		scenario.Apply(runner)
+	case *deadlock:
+		lockflow.DefineDeadlockFlows(runner)
+	default:
+		defineDefaultFlows(runner)
+	}
+
+	// Step 4: Run All Flows
+	if err := runner.RunAll(ctx); err != nil {
+		log.Fatalf("Invalid flows: %v", err)
+	}
+
+	// Step 5: Report Results
+	runner.Report(ctx, !*hideExpected)
+	if *timelineOut != "" {
+		if err := runner.ExportTimeline(*timelineOut, !*hideExpected); err != nil {
+			log.Printf("Failed to export timeline: %v", err)
+		} else {
+			log.Printf("✓ Timeline written to %s", *timelineOut)
+		}
+	}
+
+	if *eventsJSON != "" {
+		if err := runner.ExportEventsJSON(*eventsJSON); err != nil {
+			log.Printf("Failed to export events: %v", err)
+		} else {
+			log.Printf("✓ Events written to %s", *eventsJSON)
+		}
+	}
+
+	// Step 6: Display final state of table C
+	log.Println("\n=== Final rows in table C ===")
+	if err := lockflow.DisplayTableC(ctx, db); err != nil {
+		log.Printf("Failed to display table C: %v", err)
+	}
+
+	if runner.Failed() {
+		log.Fatalf("✗ Step assertions failed")
+	}
+	log.Println("\n✓ Demo completed successfully")
+}
+
+// defineDefaultFlows registers the built-in CHAIN/EARLY experiment
+func defineDefaultFlows(runner *lockflow.Runner) {
 	// CHAIN Flow
 	// 1. Lock A
 	// 2. Update B (Wait 4s inside)
@@ -77,50 +183,13 @@ func main() {
 	early.AddUpdate("C", "Updating C.id=1 (early_data column)", "UPDATE C SET early_data = 'UPDATED_EARLY' WHERE id = 1")
 	early.AddUpdate("C", "Updating C.id=1 (early_data column)", "BEGIN DBMS_SESSION.SLEEP(15); UPDATE C SET early_data = 'UPDATED_EARLY 2' WHERE id = 1; END;")
 	early.AddUpdate("SLEEP", "Wait 5s", "BEGIN DBMS_SESSION.SLEEP(5); END;")
-
-	// NONTX Flow (Reader)
-	// Select B at 3, 6, 9, 12 seconds
-	/*
-		nontx := runner.AddNonTxFlow("TX")
-
-		nontx.AddWait(5 * time.Second)
-		//nontx.AddUpdate("B", "Update B (Sleep 10s)", "BEGIN UPDATE B SET data = 'NONTX_SLEEP' WHERE id = 1; DBMS_SESSION.SLEEP(1); END;")
-
-			nontx.AddUpdate(
-				"B",
-				"Update B (No Sleep)",
-				"UPDATE B SET data = 'NONTX_SLEEP' WHERE id = 2",
-			)
-
-
-		nontx.AddWait(3 * time.Second)
-		nontx.AddQuery("B", "Read B (3s)", "SELECT id, data FROM B WHERE id = 1")
-		nontx.AddWait(3 * time.Second)
-		nontx.AddQuery("B", "Read B (6s)", "SELECT id, data FROM B WHERE id = 1")
-		nontx.AddWait(3 * time.Second)
-		nontx.AddQuery("B", "Read B (9s)", "SELECT id, data FROM B WHERE id = 1")
-		nontx.AddWait(3 * time.Second)
-		nontx.AddQuery("B", "Read B (12s)", "SELECT id, data FROM B WHERE id = 1")
-	*/
-
-	// Step 4: Run All Flows
-	runner.RunAll(ctx)
-
-	// Step 5: Report Results
-	runner.Report(ctx, !*hideExpected)
-
-	// Step 7: Display final state of table C
-	log.Println("\n=== Final rows in table C ===")
-	if err := DisplayTableC(ctx, db); err != nil {
-		log.Printf("Failed to display table C: %v", err)
-	}
-
-	log.Println("\n✓ Demo completed successfully")
 }
 
-func getEnv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// logChaosConfig prints the active chaos settings at startup
+func logChaosConfig(cfg lockflow.ChaosConfig, admin *sql.DB) {
+	log.Printf("Chaos enabled: delay<=%v p=%.2f, kill p=%.2f flow=%q max=%d",
+		cfg.MaxDelay, cfg.DelayProb, cfg.KillProb, cfg.KillFlow, cfg.MaxKills)
+	if admin == nil && cfg.KillProb > 0 {
+		log.Println("Chaos: no -chaos-admin-user given, session kills are disabled")
 	}
-	return fallback
 }
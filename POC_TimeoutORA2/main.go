@@ -1,65 +1,195 @@
 package main
 
 import (
+	"log"
+
 	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
-	"os"
+	"sort"
+	"strconv"
 	"time"
 
-	_ "github.com/sijms/go-ora/v2"
+	"sql-learn2/logging"
+	"sql-learn2/oraconn"
+	"sql-learn2/oraerr"
+	"sql-learn2/sessioncheck"
 )
 
+// runResult is the outcome of one cancelled call
+type runResult struct {
+	Elapsed     time.Duration
+	Err         error
+	Interrupted bool // the call returned before the server-side sleep could finish
+	ConnOK      bool // the same connection answered a follow-up query
+	Server      *sessioncheck.Report
+}
+
 func main() {
 	// Connection flags
-	user := flag.String("user", getEnv("ORA_USER", "LEARN1"), "Oracle username")
-	pass := flag.String("pass", getEnv("ORA_PASS", "Welcome"), "Oracle password")
-	host := flag.String("host", getEnv("ORA_HOST", "localhost"), "Oracle host")
-	port := flag.String("port", getEnv("ORA_PORT", "1521"), "Oracle port")
-	service := flag.String("service", getEnv("ORA_SERVICE", "XE"), "Oracle service name")
+	var ora oraconn.Config
+	ora.RegisterFlags(flag.CommandLine)
+	var logCfg logging.Config
+	logCfg.RegisterFlags(flag.CommandLine)
+
+	// Experiment flags
+	sleep := flag.Duration("sleep", 7*time.Second, "Server-side DBMS_SESSION.SLEEP duration")
+	timeout := flag.Duration("timeout", 1*time.Second, "Context timeout for the call")
+	repeat := flag.Int("repeat", 1, "Number of runs")
+	oob := flag.Bool("oob", true, "Enable out-of-band break (ENABLE_OOB)")
+	driverTimeout := flag.Int("driver-timeout", 3, "go-ora TIMEOUT connection option in seconds (0 = not set)")
+
+	// Server-side verification (needs SELECT on V$SESSION/V$TRANSACTION)
+	adminUser := flag.String("admin-user", oraconn.Getenv("ORA_ADMIN_USER", ""), "Privileged user for checking the cancelled session server-side")
+	adminPass := flag.String("admin-pass", oraconn.Getenv("ORA_ADMIN_PASS", ""), "Password for -admin-user")
+	verifyWait := flag.Duration("verify-wait", 0, "How long to watch the cancelled session (default: -sleep + 2s)")
 	flag.Parse()
+	if _, err := logCfg.Setup(); err != nil {
+		log.Fatalf("%v", err)
+	}
 
-	// Build DSN
-	// We add ENABLE_OOB=true to attempt Out-Of-Band interrupts (if supported).
-	dsn := fmt.Sprintf("oracle://%s:%s@%s:%s/%s?ENABLE_OOB=true&TIMEOUT=3", *user, *pass, *host, *port, *service)
+	// ENABLE_OOB=true attempts Out-Of-Band interrupts (if supported).
+	cfg := ora
+	if *oob {
+		cfg = cfg.WithOption("ENABLE_OOB", "true")
+	}
+	if *driverTimeout > 0 {
+		cfg = cfg.WithOption("TIMEOUT", strconv.Itoa(*driverTimeout))
+	}
 
-	conn, err := sql.Open("oracle", dsn)
+	db, err := oraconn.Open(cfg)
 	if err != nil {
 		fmt.Println("can't open connection: ", err)
 		return
 	}
 	defer func() {
-		err = conn.Close()
+		err = db.Close()
 		if err != nil {
 			fmt.Println("can't close connection: ", err)
 			return
 		}
 	}()
+
+	var admin *sql.DB
+	if *adminUser != "" {
+		if admin, err = oraconn.Open(ora.WithCredentials(*adminUser, *adminPass)); err != nil {
+			fmt.Println("can't open admin connection: ", err)
+			return
+		}
+		defer admin.Close()
+	}
+	if *verifyWait <= 0 {
+		*verifyWait = *sleep + 2*time.Second
+	}
+
+	fmt.Printf("dsn=%s\n", cfg)
+	fmt.Printf("sleep=%v timeout=%v repeat=%d oob=%v driver-timeout=%ds\n", *sleep, *timeout, *repeat, *oob, *driverTimeout)
 	t := time.Now()
-	defer func() {
-		fmt.Println("finish: ", time.Now().Sub(t))
-	}()
-	execCtx, execCancel := context.WithTimeout(context.Background(), 1*time.Second)
-	defer execCancel()
+	results := make([]runResult, 0, *repeat)
+	for i := 0; i < *repeat; i++ {
+		r := runOnce(db, admin, *sleep, *timeout, *verifyWait)
+		status := "returned after the sleep"
+		if r.Interrupted {
+			status = "interrupted"
+		}
+		fmt.Printf("run %d: %v, %s, conn ok=%v, err=%v\n", i+1, r.Elapsed.Round(time.Millisecond), status, r.ConnOK, r.Err)
+		if r.Server != nil {
+			fmt.Print(r.Server.Render())
+		}
+		results = append(results, r)
+	}
+	report(results, *sleep, *timeout)
+	fmt.Println("finish: ", time.Since(t))
+}
 
-	stmt, err := conn.PrepareContext(execCtx, "begin DBMS_SESSION.SLEEP(7); end;")
+// runOnce runs the sleep on a dedicated connection with the timeout, then
+// checks whether that connection is still usable. With admin set it also
+// watches the session server-side after the client gave up.
+func runOnce(db, admin *sql.DB, sleep, timeout, verifyWait time.Duration) runResult {
+	conn, err := db.Conn(context.Background())
 	if err != nil {
-		fmt.Println("prepare error: ", err)
-		return
+		return runResult{Err: fmt.Errorf("get connection: %w", err)}
 	}
-	defer stmt.Close()
+	defer conn.Close()
 
-	_, err = stmt.ExecContext(execCtx)
-	if err != nil {
-		fmt.Println(err)
-		return
+	var session sessioncheck.Session
+	if admin != nil {
+		if session, err = sessioncheck.Identify(context.Background(), conn, "POC_TIMEOUT_ORA2"); err != nil {
+			return runResult{Err: err}
+		}
+	}
+
+	execCtx, execCancel := context.WithTimeout(context.Background(), timeout)
+	defer execCancel()
+
+	query := fmt.Sprintf("begin DBMS_SESSION.SLEEP(%.3f); end;", sleep.Seconds())
+	start := time.Now()
+	_, err = conn.ExecContext(execCtx, query)
+	r := runResult{Elapsed: time.Since(start), Err: err}
+	r.Interrupted = err != nil && r.Elapsed < sleep
+
+	if admin != nil && err != nil {
+		report := sessioncheck.Verify(context.Background(), admin, session, verifyWait, 100*time.Millisecond)
+		r.Server = &report
 	}
+
+	checkCtx, checkCancel := context.WithTimeout(context.Background(), sleep+5*time.Second)
+	defer checkCancel()
+	var one int
+	r.ConnOK = conn.QueryRowContext(checkCtx, "SELECT 1 FROM dual").Scan(&one) == nil
+	return r
 }
 
-func getEnv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// report prints how often the call was interrupted and the latency distribution
+func report(results []runResult, sleep, timeout time.Duration) {
+	var interrupted, connOK, deadline, ora1013, success int
+	verdicts := make(map[sessioncheck.Verdict]int)
+	elapsed := make([]time.Duration, 0, len(results))
+	for _, r := range results {
+		elapsed = append(elapsed, r.Elapsed)
+		if r.Interrupted {
+			interrupted++
+		}
+		if r.ConnOK {
+			connOK++
+		}
+		if r.Server != nil {
+			verdicts[r.Server.Verdict]++
+		}
+		switch {
+		case r.Err == nil:
+			success++
+		case errors.Is(r.Err, context.DeadlineExceeded):
+			deadline++
+		case oraerr.Code(r.Err) == 1013:
+			ora1013++
+		}
+	}
+	n := len(results)
+	if n == 0 {
+		return
+	}
+	sort.Slice(elapsed, func(i, j int) bool { return elapsed[i] < elapsed[j] })
+	pct := func(p float64) time.Duration {
+		return elapsed[min(n-1, int(p*float64(n)))].Round(time.Millisecond)
+	}
+
+	fmt.Println()
+	fmt.Println("=== Cancellation statistics ===")
+	fmt.Printf("runs:                 %d\n", n)
+	fmt.Printf("interrupted:          %d (%.0f%%)  returned before the %v sleep\n", interrupted, 100*float64(interrupted)/float64(n), sleep)
+	fmt.Printf("completed anyway:     %d\n", n-interrupted)
+	fmt.Printf("errors:               context deadline %d, ORA-01013 %d, other %d, none %d\n", deadline, ora1013, n-success-deadline-ora1013, success)
+	fmt.Printf("connection reusable:  %d/%d\n", connOK, n)
+	if len(verdicts) > 0 {
+		fmt.Printf("server-side session:  %s %d, %s %d, %s %d, %s %d\n",
+			sessioncheck.VerdictClean, verdicts[sessioncheck.VerdictClean],
+			sessioncheck.VerdictRunning, verdicts[sessioncheck.VerdictRunning],
+			sessioncheck.VerdictTxOpen, verdicts[sessioncheck.VerdictTxOpen],
+			sessioncheck.VerdictGone, verdicts[sessioncheck.VerdictGone])
 	}
-	return fallback
+	fmt.Printf("latency:              min %v  p50 %v  p90 %v  p99 %v  max %v\n", pct(0), pct(0.5), pct(0.9), pct(0.99), elapsed[n-1].Round(time.Millisecond))
+	fmt.Printf("overshoot vs timeout: p50 %v  max %v\n", (pct(0.5) - timeout).Round(time.Millisecond), (elapsed[n-1] - timeout).Round(time.Millisecond))
 }
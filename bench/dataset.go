@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+
+	"sql-learn2/dynamic"
+)
+
+// dataset is the generated input every strategy loads, held column-wise
+// so the array-bind strategies can slice it without conversion
+type dataset struct {
+	ids     []int64
+	codes   []string
+	names   []string
+	amounts []float64
+	qtys    []int64
+}
+
+var columns = []string{"ID", "CODE", "NAME", "AMOUNT", "QTY"}
+
+// columnDefs match what csvdb creates from the CSV types row, so every
+// strategy writes into an identical table
+var columnDefs = []dynamic.ColumnDef{
+	{Name: "ID", Type: dynamic.Number, Nullable: true},
+	{Name: "CODE", Type: dynamic.Varchar2, Nullable: true},
+	{Name: "NAME", Type: dynamic.Varchar2, Nullable: true},
+	{Name: "AMOUNT", Type: dynamic.Number, Nullable: true},
+	{Name: "QTY", Type: dynamic.Number, Nullable: true},
+}
+
+func newDataset(rows int, seed uint64) *dataset {
+	r := rand.New(rand.NewPCG(seed, seed))
+	d := &dataset{
+		ids:     make([]int64, rows),
+		codes:   make([]string, rows),
+		names:   make([]string, rows),
+		amounts: make([]float64, rows),
+		qtys:    make([]int64, rows),
+	}
+	for i := range rows {
+		d.ids[i] = int64(i + 1)
+		d.codes[i] = fmt.Sprintf("C%08d", r.IntN(100_000_000))
+		d.names[i] = fmt.Sprintf("Item %d %x", i+1, r.Uint32())
+		d.amounts[i] = float64(r.IntN(1_000_000)) / 100
+		d.qtys[i] = int64(r.IntN(1000))
+	}
+	return d
+}
+
+func (d *dataset) len() int { return len(d.ids) }
+
+// row returns row i in column order
+func (d *dataset) row(i int) []interface{} {
+	return []interface{}{d.ids[i], d.codes[i], d.names[i], d.amounts[i], d.qtys[i]}
+}
+
+// batch returns rows [lo, hi) as one typed slice per column
+func (d *dataset) batch(lo, hi int) []interface{} {
+	return []interface{}{d.ids[lo:hi], d.codes[lo:hi], d.names[lo:hi], d.amounts[lo:hi], d.qtys[lo:hi]}
+}
+
+// writeCSV writes the dataset in csvdb's format: header, types row, data
+func (d *dataset) writeCSV(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	_ = w.Write(columns)
+	_ = w.Write([]string{"NUMBER", "VARCHAR2", "VARCHAR2", "NUMBER", "NUMBER"})
+	for i := range d.len() {
+		_ = w.Write([]string{
+			strconv.FormatInt(d.ids[i], 10), d.codes[i], d.names[i],
+			strconv.FormatFloat(d.amounts[i], 'f', 2, 64), strconv.FormatInt(d.qtys[i], 10),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
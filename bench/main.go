@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sql-learn2/dynamic"
+	"sql-learn2/logging"
+	"sql-learn2/oraconn"
+	"sql-learn2/oraerr"
+)
+
+// bench loads the same generated dataset with each ingestion strategy in
+// the repo and prints a comparison table.
+//
+//	go run ./bench -rows 200000 -batch 20000 -format markdown
+func main() {
+	rows := flag.Int("rows", 50000, "Rows in the generated dataset")
+	batch := flag.Int("batch", 10000, "Rows per batch for the array-bind strategies")
+	seed := flag.Uint64("seed", 1, "Random seed for the dataset")
+	only := flag.String("strategies", "all", "Comma-separated strategies to run: csvdb, bulkinsert, insert-all, bulk_load_v3, direct-path")
+	prefix := flag.String("table-prefix", "BENCH", "Target tables are <prefix>_<STRATEGY>")
+	format := flag.String("format", "markdown", "Report format: markdown or csv")
+	out := flag.String("out", "", "Write the report to this file instead of stdout")
+	keep := flag.Bool("keep", false, "Keep the target tables after the run")
+	var ora oraconn.Config
+	ora.RegisterFlags(flag.CommandLine)
+	var logCfg logging.Config
+	logCfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := logCfg.Setup(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if *format != "markdown" && *format != "csv" {
+		log.Fatalf("invalid -format %q (use markdown or csv)", *format)
+	}
+	if *rows <= 0 || *batch <= 0 {
+		log.Fatalf("-rows and -batch must be positive")
+	}
+	selected, err := lookupStrategies(*only)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	ctx := context.Background()
+	db, err := oraconn.ConnectX(ctx, ora)
+	if err != nil {
+		oraerr.Fatal("connect oracle", err)
+	}
+	defer db.Close()
+	log.Printf("Connected to %s", ora)
+
+	data := newDataset(*rows, *seed)
+	dir, err := os.MkdirTemp("", "bench")
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	csvPath := filepath.Join(dir, "bench.csv")
+	if err := data.writeCSV(csvPath); err != nil {
+		log.Fatalf("write dataset: %v", err)
+	}
+	log.Printf("Generated %d rows (seed %d)", *rows, *seed)
+
+	var results []result
+	for _, s := range selected {
+		table := strings.ToUpper(*prefix + "_" + strings.NewReplacer("-", "_").Replace(s.name))
+		e := env{db: db, table: table, data: data, csvPath: csvPath, batch: *batch}
+		r := runOne(ctx, s, e)
+		if r.err != nil {
+			log.Printf("%s failed: %v", s.name, r.err)
+		} else {
+			log.Printf("%s: %d rows in %v (%.0f rows/s)", s.name, r.rows, r.duration.Round(time.Millisecond), r.rowsPerSec())
+		}
+		results = append(results, r)
+		if !*keep {
+			if _, err := db.ExecContext(ctx, "DROP TABLE "+table+" PURGE"); err != nil && oraerr.Code(err) != 942 {
+				log.Printf("drop %s: %v", table, err)
+			}
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "csv" {
+		err = writeCSV(w, results)
+	} else {
+		err = writeMarkdown(w, results, *rows, *batch)
+	}
+	if err != nil {
+		log.Fatalf("write report: %v", err)
+	}
+}
+
+// runOne prepares the table, times the load and counts what arrived
+func runOne(ctx context.Context, s strategy, e env) result {
+	r := result{strategy: s.name, about: s.about}
+	if s.create {
+		if r.err = dynamic.CreateOrReplaceTable(ctx, e.db.DB, e.table, columnDefs); r.err != nil {
+			return r
+		}
+	}
+	start := time.Now()
+	r.err = s.run(ctx, e)
+	r.duration = time.Since(start)
+	if r.err != nil {
+		return r
+	}
+	if r.err = e.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+e.table).Scan(&r.rows); r.err != nil {
+		return r
+	}
+	if r.rows != int64(e.data.len()) {
+		r.err = fmt.Errorf("table has %d rows, expected %d", r.rows, e.data.len())
+	}
+	return r
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type result struct {
+	strategy string
+	about    string
+	rows     int64 // rows counted in the table afterwards
+	duration time.Duration
+	err      error
+}
+
+func (r result) rowsPerSec() float64 {
+	if r.duration <= 0 {
+		return 0
+	}
+	return float64(r.rows) / r.duration.Seconds()
+}
+
+// speedup is relative to the slowest successful strategy
+func speedup(r result, results []result) float64 {
+	var slowest float64
+	for _, o := range results {
+		if o.err == nil && (slowest == 0 || o.rowsPerSec() < slowest) {
+			slowest = o.rowsPerSec()
+		}
+	}
+	if slowest == 0 || r.err != nil {
+		return 0
+	}
+	return r.rowsPerSec() / slowest
+}
+
+func writeMarkdown(w io.Writer, results []result, rows, batch int) error {
+	fmt.Fprintf(w, "Ingestion benchmark: %d rows, batch size %d\n\n", rows, batch)
+	fmt.Fprintln(w, "| Strategy | Rows | Duration | Rows/s | Speedup | Notes |")
+	fmt.Fprintln(w, "|---|---:|---:|---:|---:|---|")
+	for _, r := range results {
+		notes := r.about
+		if r.err != nil {
+			notes = "FAILED: " + r.err.Error()
+		}
+		_, err := fmt.Fprintf(w, "| %s | %d | %v | %.0f | %.1fx | %s |\n",
+			r.strategy, r.rows, r.duration.Round(time.Millisecond), r.rowsPerSec(), speedup(r, results), notes)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCSV(w io.Writer, results []result) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"strategy", "rows", "duration_ms", "rows_per_sec", "speedup", "error"})
+	for _, r := range results {
+		errStr := ""
+		if r.err != nil {
+			errStr = r.err.Error()
+		}
+		_ = cw.Write([]string{
+			r.strategy,
+			strconv.FormatInt(r.rows, 10),
+			strconv.FormatInt(r.duration.Milliseconds(), 10),
+			strconv.FormatFloat(r.rowsPerSec(), 'f', 0, 64),
+			strconv.FormatFloat(speedup(r, results), 'f', 2, 64),
+			errStr,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	bulkloadv3 "sql-learn2/bulk_load_v3"
+	"sql-learn2/bulk_load_v3/rp_dynamic"
+	"sql-learn2/bulkinsert"
+	"sql-learn2/csvdb"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// env is what a strategy gets: the connection, the target table (already
+// created empty, except for csvdb which creates its own) and the input
+type env struct {
+	db      *sqlx.DB
+	table   string
+	data    *dataset
+	csvPath string
+	batch   int
+}
+
+type strategy struct {
+	name   string
+	about  string
+	create bool // the harness creates the empty table first
+	run    func(ctx context.Context, e env) error
+}
+
+var strategies = []strategy{
+	{"csvdb", "csvdb.LoadCSVToDBWithOptions: reads the CSV file, array-bound INSERT batches", false, runCSVDB},
+	{"bulkinsert", "bulkinsert.InsertBatched: typed array binds, one transaction per batch", true, runBulkInsert},
+	{"insert-all", "bulkinsert.InsertBatched with StrategyInsertAll: multi-row INSERT ALL, one bind per value", true, runInsertAll},
+	{"bulk_load_v3", "bulk_load_v3 loader: Source -> batches -> rp_dynamic.Repo array binds", true, runBulkLoadV3},
+	{"direct-path", "INSERT /*+ APPEND_VALUES */ array binds, commit per batch", true, runDirectPath},
+}
+
+func lookupStrategies(names string) ([]strategy, error) {
+	if names == "" || names == "all" {
+		return strategies, nil
+	}
+	var out []strategy
+	for _, n := range strings.Split(names, ",") {
+		n = strings.TrimSpace(n)
+		found := false
+		for _, s := range strategies {
+			if s.name == n {
+				out = append(out, s)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown strategy %q", n)
+		}
+	}
+	return out, nil
+}
+
+func runCSVDB(ctx context.Context, e env) error {
+	_, err := csvdb.LoadCSVToDBWithOptions(ctx, e.db.DB, e.csvPath, csvdb.Options{Table: e.table, BatchSize: e.batch})
+	return err
+}
+
+func runBulkInsert(ctx context.Context, e env) error {
+	for lo := 0; lo < e.data.len(); lo += e.batch {
+		hi := min(lo+e.batch, e.data.len())
+		if _, err := bulkinsert.InsertBatched(ctx, e.db, e.table, columns, e.data.batch(lo, hi)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runInsertAll(ctx context.Context, e env) error {
+	return runBulkInsert(bulkinsert.WithStrategy(ctx, bulkinsert.StrategyInsertAll), e)
+}
+
+func runBulkLoadV3(ctx context.Context, e env) error {
+	cfg := bulkloadv3.Config{
+		Repo:      rp_dynamic.NewRepo(e.db),
+		TableName: e.table,
+		Columns:   columns,
+		BatchSize: e.batch,
+	}
+	return bulkloadv3.Run(ctx, cfg, &datasetSource{data: e.data})
+}
+
+// datasetSource feeds the in-memory dataset to bulk_load_v3, so the
+// comparison measures the loader rather than CSV parsing
+type datasetSource struct {
+	data *dataset
+	next int
+}
+
+func (s *datasetSource) Validate(context.Context) error { return nil }
+
+func (s *datasetSource) Next(context.Context) (interface{}, error) {
+	if s.next >= s.data.len() {
+		return nil, io.EOF
+	}
+	s.next++
+	return s.next - 1, nil
+}
+
+func (s *datasetSource) Convert(raw interface{}) ([]interface{}, error) {
+	return s.data.row(raw.(int)), nil
+}
+
+// runDirectPath loads above the high-water mark. Each batch commits on its
+// own: after a direct-path insert the table cannot be touched again in the
+// same transaction (ORA-12838).
+func runDirectPath(ctx context.Context, e env) error {
+	placeholders := make([]string, len(columns))
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf(":%d", i+1)
+	}
+	insertSQL := fmt.Sprintf("INSERT /*+ APPEND_VALUES */ INTO %s (%s) VALUES (%s)",
+		e.table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	for lo := 0; lo < e.data.len(); lo += e.batch {
+		hi := min(lo+e.batch, e.data.len())
+		if err := execTx(ctx, e.db.DB, insertSQL, e.data.batch(lo, hi)); err != nil {
+			return fmt.Errorf("direct-path batch at row %d: %w", lo+1, err)
+		}
+	}
+	return nil
+}
+
+func execTx(ctx context.Context, db *sql.DB, query string, args []interface{}) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
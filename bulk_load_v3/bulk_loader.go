@@ -6,19 +6,25 @@ import (
 	"io"
 	"log/slog"
 	"runtime/debug"
+	"slices"
 	"time"
 
 	"sql-learn2/bulk_load_v3/rp_dynamic"
+	"sql-learn2/bulkinsert"
+	"sql-learn2/internal/resume"
+	"sql-learn2/logging"
+	"sql-learn2/metrics"
+	"sql-learn2/retry"
 )
 
 const (
-	LogFieldTable    = "table"
+	LogFieldTable    = logging.FieldTable
 	LogFieldRowIndex = "row_index"
 	LogFieldRawData  = "raw_data"
-	LogFieldErr      = "error"
-	LogFieldDuration = "duration"
-	LogFieldRowCount = "row_count"
-	LogFieldFile     = "file"
+	LogFieldErr      = logging.FieldError
+	LogFieldDuration = logging.FieldDuration
+	LogFieldRowCount = logging.FieldRows
+	LogFieldFile     = logging.FieldFile
 )
 
 // Config holds configuration for the bulk load operation.
@@ -28,6 +34,52 @@ type Config struct {
 	Columns   []string
 	BatchSize int
 	MVName    string
+	Logger    *slog.Logger    // defaults to slog.Default()
+	Metrics   metrics.Metrics // rows, batches, errors and batch latency; nil records nothing
+
+	// Retry reruns a failed batch insert or materialized view refresh on
+	// network errors and lock timeouts, or on the errors its Retryable
+	// accepts (see retry.RetryableCodes for more ORA codes). The zero value
+	// does not retry. A batch whose commit was lost with the connection may
+	// be inserted twice.
+	Retry retry.Policy
+
+	// Reject, when set, receives each row that Convert or the buffer
+	// refused, and the load goes on without it; an error it returns stops
+	// the run. MaxRejects > 0 stops the run once more rows than that are
+	// rejected. RejectFile writes the rows to a file instead, which a
+	// resumed load continues from its checkpoint; Loader.Rejected counts
+	// them. Set Reject or RejectFile, not both.
+	Reject     func(RejectedRow) error
+	RejectFile *RejectFile
+	MaxRejects int
+
+	// CheckpointFile, when set, records after every inserted batch how far
+	// the load got: the rows committed and the source offset past them; the
+	// source must implement Seeker. With Resume, an existing checkpoint
+	// continues the load from there, keeping the table instead of
+	// truncating it and skipping the batches other workers committed ahead
+	// of the one that failed; without one the load starts over. The file is
+	// removed once Run succeeds.
+	CheckpointFile string
+	Resume         bool
+
+	// Workers is the number of batches inserted at once (default 1), each
+	// BulkInsert on its own pooled connection. Rows are read and converted
+	// while earlier batches are inserted, with one full buffer per worker
+	// queued at most. Batches may commit out of order, which the checkpoint
+	// records; the first failure stops the load.
+	Workers int
+
+	// Mode is TruncateInsert by default; Append keeps the rows in the table
+	// and Merge upserts the source rows on KeyColumns, which must be among
+	// Columns. Merge batches are always array-bound.
+	Mode       LoadMode
+	KeyColumns []string
+
+	// Insert selects how batches are sent; bulkinsert.StrategyInsertAll for
+	// drivers without array binding. Empty keeps the strategy in ctx.
+	Insert bulkinsert.Strategy
 }
 
 // Source defines the interface for input data handling.
@@ -49,24 +101,34 @@ type Source interface {
 
 // Loader handles the bulk load operation.
 type Loader struct {
-	cfg    Config
-	src    Source
-	logger *slog.Logger
+	cfg      Config
+	src      Source
+	logger   *slog.Logger
+	metrics  metrics.Metrics
+	reject   func(RejectedRow) error // Config.Reject or RejectFile.Add
+	rejected int
+	start    checkpoint // where the run began: the checkpoint resumed, or nothing
 }
 
 // NewLoader creates a new Loader instance.
 func NewLoader(cfg Config, src Source) *Loader {
+	logger := logging.Or(cfg.Logger).With(LogFieldTable, cfg.TableName)
 	if cfg.BatchSize <= 0 {
 		cfg.BatchSize = 100
-		slog.Warn("BatchSize was <= 0, defaulting to 100")
+		logger.Warn("BatchSize was <= 0, defaulting to 100")
 	}
 
-	logger := slog.With(LogFieldTable, cfg.TableName)
-	return &Loader{
-		cfg:    cfg,
-		src:    src,
-		logger: logger,
+	l := &Loader{
+		cfg:     cfg,
+		src:     src,
+		logger:  logger,
+		metrics: metrics.Or(cfg.Metrics),
+		reject:  cfg.Reject,
 	}
+	if cfg.RejectFile != nil {
+		l.reject = cfg.RejectFile.Add
+	}
+	return l
 }
 
 // Run executes the bulk load process.
@@ -81,8 +143,14 @@ func (l *Loader) Run(ctx context.Context) (err error) {
 		return err
 	}
 
+	// Repository calls log through the loader's logger
+	ctx = logging.WithLogger(ctx, l.logger)
+	if l.cfg.Insert != "" {
+		ctx = bulkinsert.WithStrategy(ctx, l.cfg.Insert)
+	}
+
 	runStart := time.Now()
-	l.logger.Info("Starting bulk load process...")
+	l.logger.Info("Starting bulk load process...", "mode", l.cfg.Mode)
 
 	// 1. Preparation
 	if err := l.prepare(ctx); err != nil {
@@ -100,10 +168,25 @@ func (l *Loader) Run(ctx context.Context) (err error) {
 		return err
 	}
 
-	l.logger.Info("Batch Done.", LogFieldDuration, time.Since(runStart), LogFieldRowCount, totalRows)
+	if l.rejected > 0 {
+		l.logger.Warn("Rows rejected", "rejected", l.rejected)
+	}
+	if path := l.cfg.CheckpointFile; path != "" {
+		if err := resume.Remove(path); err != nil {
+			l.logger.Warn("Checkpoint not removed", "checkpoint", path, LogFieldErr, err)
+		}
+	}
+	l.logger.Info("Batch Done.", LogFieldDuration, time.Since(runStart), LogFieldRowCount, totalRows, "rejected", l.rejected,
+		"resumed_rows", l.start.Rows)
 	return nil
 }
 
+// Rejected is the number of rows the last Run rejected, those of the load
+// it resumed included
+func (l *Loader) Rejected() int {
+	return l.rejected
+}
+
 func (l *Loader) validateConfig() error {
 	if l.cfg.Repo == nil {
 		return fmt.Errorf("repository (Repo) is required")
@@ -114,10 +197,32 @@ func (l *Loader) validateConfig() error {
 	if len(l.cfg.Columns) == 0 {
 		return fmt.Errorf("target columns are required")
 	}
+	if l.cfg.Mode == Merge {
+		if len(l.cfg.KeyColumns) == 0 {
+			return fmt.Errorf("merge needs key columns")
+		}
+		for _, k := range l.cfg.KeyColumns {
+			if !slices.Contains(l.cfg.Columns, k) {
+				return fmt.Errorf("key column %s is not a target column", k)
+			}
+		}
+		if l.cfg.Insert == bulkinsert.StrategyInsertAll {
+			return fmt.Errorf("merge needs array binding, not %s", l.cfg.Insert)
+		}
+	}
+	if l.cfg.Reject != nil && l.cfg.RejectFile != nil {
+		return fmt.Errorf("set Reject or RejectFile, not both")
+	}
+	if l.cfg.Resume && l.cfg.CheckpointFile == "" {
+		return fmt.Errorf("resume needs a checkpoint file")
+	}
+	if _, ok := l.src.(Seeker); l.cfg.CheckpointFile != "" && !ok {
+		return fmt.Errorf("checkpoint needs a source implementing Seeker, %T does not", l.src)
+	}
 	return nil
 }
 
-// prepare handles source validation and table truncation.
+// prepare handles source validation and, in TruncateInsert mode, table truncation.
 func (l *Loader) prepare(ctx context.Context) error {
 	// Diagram: Open CSV File -> Validate CSV
 	l.logger.Info("Validating source...")
@@ -125,6 +230,37 @@ func (l *Loader) prepare(ctx context.Context) error {
 		return fmt.Errorf("source validation failed: %w", err)
 	}
 
+	l.start = checkpoint{Table: l.cfg.TableName}
+	if path := l.cfg.CheckpointFile; path != "" {
+		ckpt, err := l.resumePoint(path)
+		if err != nil {
+			return err
+		}
+		if ckpt != nil {
+			if err := l.src.(Seeker).Seek(ctx, ckpt.Offset); err != nil {
+				return fmt.Errorf("seek source to checkpoint: %w", err)
+			}
+			if rf := l.cfg.RejectFile; rf != nil {
+				if err := rf.reopen(ckpt.Rejected, ckpt.RejectSize); err != nil {
+					return err
+				}
+			}
+			// The table keeps the rows committed before
+			l.start = *ckpt
+			l.logger.Info("Resuming load", "resumed_rows", ckpt.Rows, "source_rows", ckpt.Index, "offset", ckpt.Offset)
+			return nil
+		}
+		// A checkpoint left by an earlier run no longer matches the table
+		if err := resume.Remove(path); err != nil {
+			return fmt.Errorf("remove old checkpoint: %w", err)
+		}
+	}
+
+	if l.cfg.Mode != TruncateInsert {
+		l.logger.Info("Keeping existing rows", "mode", l.cfg.Mode)
+		return nil
+	}
+
 	// Diagram: Truncate Table
 	l.logger.Info("Truncating table...")
 	truncStart := time.Now()
@@ -135,88 +271,241 @@ func (l *Loader) prepare(ctx context.Context) error {
 	return nil
 }
 
-// process handles reading, converting, buffering, and inserting rows.
+// resumePoint returns the checkpoint to continue from with Config.Resume,
+// or nil to load from the start
+func (l *Loader) resumePoint(path string) (*checkpoint, error) {
+	if !l.cfg.Resume {
+		return nil, nil
+	}
+	ckpt, err := resume.Read[checkpoint](path)
+	if err != nil {
+		return nil, err
+	}
+	if ckpt == nil {
+		l.logger.Info("No checkpoint to resume, loading from the start", "checkpoint", path)
+		return nil, nil
+	}
+	if ckpt.Table != l.cfg.TableName {
+		return nil, fmt.Errorf("checkpoint is for table %s, not %s", ckpt.Table, l.cfg.TableName)
+	}
+	return ckpt, nil
+}
+
+// process handles reading, converting, buffering, and inserting rows. Rows
+// are read and converted here while full buffers are inserted by the
+// Config.Workers insert workers.
 func (l *Loader) process(ctx context.Context) (int, error) {
 	l.logger.Info("Starting row processing...")
-	builder := rp_dynamic.NewBulkInsertBuilder(l.cfg.TableName, l.cfg.Columns...)
+	in := l.startInserters(ctx, l.start)
+	totalRows, err := l.read(in)
+	if err != nil {
+		in.cancel() // the load stops at the bad row
+	}
+	if insErr := in.wait(); insErr != nil {
+		return totalRows, insErr
+	}
+	if err != nil {
+		return totalRows, err
+	}
+	l.logger.Info("Inserted total rows.", LogFieldRowCount, totalRows)
+	return totalRows, nil
+}
+
+// read fills builders from the source and submits each full one to in,
+// returning the rows buffered. It stops early once an insert has failed.
+func (l *Loader) read(in *inserters) (int, error) {
+	ctx := in.ctx
+	builder := l.newBuilder()
 	rowCount := 0
 	totalRows := 0
+	index := l.start.Index // source rows read, rejected ones included
+	first := index + 1     // index of the first row in builder
+	l.rejected = l.start.Rejected
+	// skipped takes the rows committed ahead of the interrupted load's
+	// failed batch, only to reject the bad ones among them again
+	var skipped *rp_dynamic.BulkInsertBuilder
+	// offset is where the row Next reads starts, which ends the batch
+	// before it; only tracked for the checkpoint
+	var offset int64
+	seeker, _ := l.src.(Seeker)
+	if l.cfg.CheckpointFile == "" {
+		seeker = nil
+	}
 	batchReadStart := time.Now()
 
 	for {
+		if seeker != nil {
+			offset = seeker.Offset()
+		}
 		// Diagram: Read Line
 		rawRow, err := l.src.Next(ctx)
 		if err == io.EOF {
 			break
 		}
+		if ctx.Err() != nil {
+			return totalRows, nil // an insert failed; wait reports it
+		}
 		if err != nil {
+			l.metrics.IncErrors(l.cfg.TableName, metrics.StageRead)
 			return totalRows, fmt.Errorf("read line failed: %w", err)
 		}
 
 		// Diagram: Is Buffer Full?
 		if rowCount >= l.cfg.BatchSize {
 			// Diagram: Buffer Has Rows -> Insert Bulk
-			if err := l.flushBatch(ctx, builder, rowCount, time.Since(batchReadStart)); err != nil {
-				return totalRows, err
+			if !in.submit(l.job(builder, rowCount, batchReadStart, first, index, offset)) {
+				return totalRows, nil
 			}
 			// Diagram: Reset Buffer
-			builder = rp_dynamic.NewBulkInsertBuilder(l.cfg.TableName, l.cfg.Columns...)
+			builder = l.newBuilder()
 			rowCount = 0
+			first = index + 1
 			batchReadStart = time.Now()
 		}
 
-		currentLine := totalRows + 1
-		rowLogger := l.logger.With(LogFieldRowIndex, currentLine)
-
-		// Diagram: Parse And Validate Row
-		values, err := l.src.Convert(rawRow)
+		index++
+		if l.start.Done.Contains(index) {
+			// committed by the interrupted load ahead of its failed batch; the
+			// rows it rejected there were dropped with its checkpoint
+			if rowCount == 0 {
+				first = index + 1
+			}
+			if l.reject != nil {
+				if skipped == nil {
+					skipped = l.newBuilder()
+				}
+				if _, err := l.addRow(skipped, index, rawRow); err != nil {
+					return totalRows, err
+				}
+			}
+			continue
+		}
+		added, err := l.addRow(builder, index, rawRow)
 		if err != nil {
-			rowLogger.Error("Row conversion failed", LogFieldRawData, rawRow, LogFieldErr, err)
-			return totalRows, fmt.Errorf("row conversion failed: %w", err)
+			return totalRows, err
 		}
-
-		// Diagram: Add Row To Buffer
-		if err := builder.AddRow(values...); err != nil {
-			rowLogger.Error("Add row to buffer failed", LogFieldRawData, rawRow, LogFieldErr, err)
-			return totalRows, fmt.Errorf("add row to buffer failed: %w", err)
+		if added {
+			rowCount++
+			totalRows++
 		}
-		rowCount++
-		totalRows++
 	}
 
 	// Diagram: Done -> Buffer Has Rows? -> Insert Bulk
 	if rowCount > 0 {
 		l.logger.Info("Inserting remaining rows...", LogFieldRowCount, rowCount, LogFieldDuration, time.Since(batchReadStart))
-		if err := l.flushBatch(ctx, builder, rowCount, time.Since(batchReadStart)); err != nil {
-			l.logger.Error("Final bulk insert failed", LogFieldErr, err)
-			return totalRows, fmt.Errorf("final bulk insert failed: %w", err)
+		j := l.job(builder, rowCount, batchReadStart, first, index, offset)
+		j.final = true
+		in.submit(j)
+	}
+	return totalRows, nil
+}
+
+// job is the batch of rowCount rows in builder, source rows first to index
+func (l *Loader) job(builder *rp_dynamic.BulkInsertBuilder, rowCount int, readStart time.Time, first, index int, offset int64) flushJob {
+	j := flushJob{builder: builder, rows: rowCount, read: time.Since(readStart), first: first, index: index, offset: offset,
+		rejected: l.rejected}
+	if rf := l.cfg.RejectFile; rf != nil {
+		j.rejectSize = rf.size
+	}
+	return j
+}
+
+// addRow converts rawRow and adds it to builder, reporting false for a row
+// Convert or the buffer refused and Config.Reject took; without Reject
+// that row fails the load
+func (l *Loader) addRow(builder *rp_dynamic.BulkInsertBuilder, index int, rawRow interface{}) (bool, error) {
+	rowLogger := l.logger.With(LogFieldRowIndex, index)
+
+	// Diagram: Parse And Validate Row
+	values, err := l.src.Convert(rawRow)
+	if err != nil {
+		l.metrics.IncErrors(l.cfg.TableName, metrics.StageConvert)
+		if l.reject == nil {
+			rowLogger.Error("Row conversion failed", LogFieldRawData, rawRow, LogFieldErr, err)
+			return false, fmt.Errorf("row conversion failed: %w", err)
 		}
+		return false, l.rejectRow(rowLogger, RejectedRow{Index: index, Raw: rawRow, Err: err})
 	}
 
-	l.logger.Info("Inserted total rows.", LogFieldRowCount, totalRows)
-	return totalRows, nil
+	// Diagram: Add Row To Buffer
+	if err := builder.AddRow(values...); err != nil {
+		l.metrics.IncErrors(l.cfg.TableName, metrics.StageConvert)
+		if l.reject == nil {
+			rowLogger.Error("Add row to buffer failed", LogFieldRawData, rawRow, LogFieldErr, err)
+			return false, fmt.Errorf("add row to buffer failed: %w", err)
+		}
+		return false, l.rejectRow(rowLogger, RejectedRow{Index: index, Raw: rawRow, Err: err})
+	}
+	return true, nil
+}
+
+// newBuilder returns an empty buffer, merging on Config.KeyColumns in Merge mode
+func (l *Loader) newBuilder() *rp_dynamic.BulkInsertBuilder {
+	if l.cfg.Mode == Merge {
+		return rp_dynamic.NewBulkMergeBuilder(l.cfg.TableName, l.cfg.KeyColumns, l.cfg.Columns...)
+	}
+	return rp_dynamic.NewBulkInsertBuilder(l.cfg.TableName, l.cfg.Columns...)
+}
+
+// rejectRow hands r to Config.Reject or RejectFile, failing once more than
+// MaxRejects rows were rejected
+func (l *Loader) rejectRow(rowLogger *slog.Logger, r RejectedRow) error {
+	if l.cfg.MaxRejects > 0 && l.rejected >= l.cfg.MaxRejects {
+		return fmt.Errorf("more than %d rejected rows, last: %w", l.cfg.MaxRejects, r.Err)
+	}
+	rowLogger.Warn("Row rejected", LogFieldRawData, r.Raw, LogFieldErr, r.Err)
+	if err := l.reject(r); err != nil {
+		return fmt.Errorf("reject row %d: %w", r.Index, err)
+	}
+	l.rejected++
+	return nil
 }
 
 // flushBatch inserts the current buffer into the database.
 func (l *Loader) flushBatch(ctx context.Context, builder *rp_dynamic.BulkInsertBuilder, count int, readDuration time.Duration) error {
 	l.logger.Info("Inserting batch...", LogFieldRowCount, count, LogFieldDuration, readDuration)
 	flushStart := time.Now()
-	if err := l.cfg.Repo.BulkInsert(ctx, builder); err != nil {
+	err := retry.Do(ctx, l.retryPolicy("Bulk insert"), func(ctx context.Context) error {
+		return l.cfg.Repo.BulkInsert(ctx, builder)
+	})
+	if err != nil {
 		l.logger.Error("Bulk insert failed", LogFieldErr, err)
+		l.metrics.IncErrors(l.cfg.TableName, metrics.StageInsert)
 		return fmt.Errorf("bulk insert failed: %w", err)
 	}
-	l.logger.Info("Batch inserted", LogFieldDuration, time.Since(flushStart))
+	flushDuration := time.Since(flushStart)
+	l.metrics.AddRows(l.cfg.TableName, count)
+	l.metrics.IncBatches(l.cfg.TableName)
+	l.metrics.ObserveBatch(l.cfg.TableName, flushDuration)
+	l.logger.Info("Batch inserted", LogFieldDuration, flushDuration)
 	return nil
 }
 
+// retryPolicy is Config.Retry, logging each failed attempt of what unless
+// the caller's policy does
+func (l *Loader) retryPolicy(what string) retry.Policy {
+	p := l.cfg.Retry
+	if p.OnRetry == nil {
+		p.OnRetry = func(attempt int, err error, wait time.Duration) {
+			l.logger.Warn(what+" failed, retrying", "attempt", attempt, "wait", wait, LogFieldErr, err)
+		}
+	}
+	return p
+}
+
 // refreshMatView handles materialized view refresh.
 func (l *Loader) refreshMatView(ctx context.Context) error {
 	// Diagram: Refresh Material View
 	if l.cfg.MVName != "" {
 		l.logger.Info("Refreshing materialized view...", "mv", l.cfg.MVName)
 		refreshStart := time.Now()
-		if _, err := l.cfg.Repo.RefreshMaterializedView(ctx, l.cfg.MVName); err != nil {
+		err := retry.Do(ctx, l.retryPolicy("Refresh MV"), func(ctx context.Context) error {
+			_, err := l.cfg.Repo.RefreshMaterializedView(ctx, l.cfg.MVName)
+			return err
+		})
+		if err != nil {
 			l.logger.Error("Refresh MV failed", LogFieldErr, err)
+			l.metrics.IncErrors(l.cfg.TableName, metrics.StageRefresh)
 			return err
 		}
 		l.logger.Info("MV Refreshed", LogFieldDuration, time.Since(refreshStart))
@@ -5,10 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"sql-learn2/bulk_load_v3/rp_dynamic"
+	"sql-learn2/bulkinsert"
+	"sql-learn2/internal/resume"
+	"sql-learn2/retry"
 )
 
 // --- Mocks ---
@@ -295,6 +305,17 @@ func TestRun_ValidationErrors(t *testing.T) {
 			},
 			expectErr: "target columns are required",
 		},
+		{
+			name: "Reject And RejectFile",
+			config: Config{
+				Repo:       repo,
+				TableName:  "T",
+				Columns:    []string{"C"},
+				Reject:     func(RejectedRow) error { return nil },
+				RejectFile: &RejectFile{Path: "t.bad"},
+			},
+			expectErr: "set Reject or RejectFile, not both",
+		},
 	}
 
 	for _, tt := range tests {
@@ -422,3 +443,742 @@ func TestRun_Recovery(t *testing.T) {
 		t.Errorf("Unexpected error format: %v", err)
 	}
 }
+
+type MockMetrics struct {
+	Rows      int
+	Batches   int
+	Errors    map[string]int
+	Latencies int
+}
+
+func (m *MockMetrics) AddRows(table string, n int) { m.Rows += n }
+func (m *MockMetrics) IncBatches(table string)     { m.Batches++ }
+func (m *MockMetrics) IncErrors(table, stage string) {
+	if m.Errors == nil {
+		m.Errors = map[string]int{}
+	}
+	m.Errors[stage]++
+}
+func (m *MockMetrics) ObserveBatch(table string, d time.Duration) { m.Latencies++ }
+
+func TestRun_Metrics(t *testing.T) {
+	curr := 0
+	src := &MockSource{
+		NextFunc: func(ctx context.Context) (interface{}, error) {
+			if curr >= 5 {
+				return nil, io.EOF
+			}
+			curr++
+			return curr, nil
+		},
+	}
+	m := &MockMetrics{}
+	cfg := createValidConfig(&MockRepo{})
+	cfg.BatchSize = 2
+	cfg.Metrics = m
+
+	if err := Run(context.Background(), cfg, src); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if m.Rows != 5 || m.Batches != 3 || m.Latencies != 3 || len(m.Errors) != 0 {
+		t.Errorf("Unexpected metrics: %+v", m)
+	}
+
+	// A conversion failure is counted by stage
+	m = &MockMetrics{}
+	cfg.Metrics = m
+	srcBad := &MockSource{
+		NextFunc: func(ctx context.Context) (interface{}, error) { return "bad", nil },
+		ConvertFunc: func(rawRow interface{}) ([]interface{}, error) {
+			return nil, errors.New("parse error")
+		},
+	}
+	if err := Run(context.Background(), cfg, srcBad); err == nil {
+		t.Fatal("Expected conversion error")
+	}
+	if m.Errors["convert"] != 1 || m.Rows != 0 {
+		t.Errorf("Unexpected metrics after failure: %+v", m)
+	}
+}
+
+func TestRun_RetryBatch(t *testing.T) {
+	lockErr := errors.New("ORA-00054: resource busy and acquire with NOWAIT specified")
+	tests := []struct {
+		name      string
+		policy    retry.Policy
+		failures  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{"no policy", retry.Policy{}, 1, 1, true},
+		{"recovers", retry.Policy{MaxAttempts: 3, Initial: time.Millisecond}, 2, 3, false},
+		{"exhausted", retry.Policy{MaxAttempts: 2, Initial: time.Millisecond}, 5, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			repo := &MockRepo{
+				BulkInsertFunc: func(ctx context.Context, builder *rp_dynamic.BulkInsertBuilder) error {
+					calls++
+					if calls <= tt.failures {
+						return lockErr
+					}
+					return nil
+				},
+			}
+			sent := false
+			src := &MockSource{
+				NextFunc: func(ctx context.Context) (interface{}, error) {
+					if sent {
+						return nil, io.EOF
+					}
+					sent = true
+					return "row", nil
+				},
+			}
+			cfg := createValidConfig(repo)
+			cfg.Retry = tt.policy
+			err := Run(context.Background(), cfg, src)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("BulkInsert calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRun_RetryRefresh(t *testing.T) {
+	blip := errors.New("ORA-03113: end-of-file on communication channel")
+	snapshot := errors.New("ORA-01555: snapshot too old")
+	fast := retry.Policy{MaxAttempts: 3, Initial: time.Millisecond}
+	withCodes := fast
+	withCodes.Retryable = retry.RetryableCodes(1555)
+	tests := []struct {
+		name      string
+		policy    retry.Policy
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{"no policy", retry.Policy{}, blip, 1, true},
+		{"connection blip", fast, blip, 3, false},
+		{"code not retryable", fast, snapshot, 1, true},
+		{"extra code", withCodes, snapshot, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			repo := &MockRepo{
+				RefreshMaterializedViewFunc: func(ctx context.Context, name string) (time.Duration, error) {
+					if calls++; calls <= 2 {
+						return 0, tt.err
+					}
+					return time.Millisecond, nil
+				},
+			}
+			cfg := createValidConfig(repo)
+			cfg.Retry = tt.policy
+			err := Run(context.Background(), cfg, &MockSource{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("RefreshMaterializedView calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRun_Reject(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxRejects   int
+		sinkErr      error
+		wantErr      string
+		wantRejected []int
+		wantInserted []int
+	}{
+		{"keeps loading", 0, nil, "", []int{2, 4}, []int{1, 3, 5}},
+		{"within max", 2, nil, "", []int{2, 4}, []int{1, 3, 5}},
+		{"past max", 1, nil, "more than 1 rejected rows, last: odd rows only", []int{2}, nil},
+		{"sink fails", 0, errors.New("disk full"), "reject row 2: disk full", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inserted []int
+			repo := &MockRepo{
+				BulkInsertFunc: func(ctx context.Context, builder *rp_dynamic.BulkInsertBuilder) error {
+					for _, v := range builder.GetArgs()[0].([]interface{}) {
+						inserted = append(inserted, v.(int))
+					}
+					return nil
+				},
+			}
+			src := rowsSource(5)
+			src.ConvertFunc = func(rawRow interface{}) ([]interface{}, error) {
+				if rawRow.(int)%2 == 0 {
+					return nil, errors.New("odd rows only")
+				}
+				return []interface{}{rawRow}, nil
+			}
+			var rejected []int
+			cfg := createValidConfig(repo)
+			cfg.MaxRejects = tt.maxRejects
+			cfg.Reject = func(r RejectedRow) error {
+				if tt.sinkErr != nil {
+					return tt.sinkErr
+				}
+				if r.Raw != r.Index || r.Err.Error() != "odd rows only" {
+					t.Errorf("rejected %+v", r)
+				}
+				rejected = append(rejected, r.Index)
+				return nil
+			}
+			l := NewLoader(cfg, src)
+			err := l.Run(context.Background())
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if !slices.Equal(rejected, tt.wantRejected) || l.Rejected() != len(tt.wantRejected) {
+				t.Errorf("rejected %v (Rejected() = %d), want %v", rejected, l.Rejected(), tt.wantRejected)
+			}
+			if !slices.Equal(inserted, tt.wantInserted) {
+				t.Errorf("inserted %v, want %v", inserted, tt.wantInserted)
+			}
+		})
+	}
+}
+
+// seekSource yields the ints 1..n; its offset is the rows read
+type seekSource struct {
+	MockSource
+	n, pos int
+}
+
+func newSeekSource(n int) *seekSource {
+	s := &seekSource{n: n}
+	s.NextFunc = func(ctx context.Context) (interface{}, error) {
+		if s.pos >= s.n {
+			return nil, io.EOF
+		}
+		s.pos++
+		return s.pos, nil
+	}
+	return s
+}
+
+func (s *seekSource) Offset() int64 { return int64(s.pos) }
+
+func (s *seekSource) Seek(ctx context.Context, offset int64) error {
+	s.pos = int(offset)
+	return nil
+}
+
+func TestRun_CheckpointResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "load.ckpt")
+	var inserted []int
+	truncates := 0
+	failOn := 3
+	repo := &MockRepo{
+		TruncateFunc: func(ctx context.Context, tableName string) error {
+			truncates++
+			return nil
+		},
+		BulkInsertFunc: func(ctx context.Context, builder *rp_dynamic.BulkInsertBuilder) error {
+			if failOn--; failOn == 0 {
+				return errors.New("connection lost")
+			}
+			for _, v := range builder.GetArgs()[0].([]interface{}) {
+				inserted = append(inserted, v.(int))
+			}
+			return nil
+		},
+	}
+	src := newSeekSource(7)
+	src.ConvertFunc = func(rawRow interface{}) ([]interface{}, error) {
+		if rawRow == 2 {
+			return nil, errors.New("bad row")
+		}
+		return []interface{}{rawRow}, nil
+	}
+	cfg := createValidConfig(repo)
+	cfg.BatchSize = 2
+	cfg.CheckpointFile = path
+	cfg.Reject = func(RejectedRow) error { return nil }
+
+	// rows 1,3 and 4,5 are inserted, then 6,7 fails
+	if err := Run(context.Background(), cfg, src); err == nil {
+		t.Fatal("expected the third batch to fail")
+	}
+	ckpt, err := resume.Read[checkpoint](path)
+	if err != nil || ckpt == nil {
+		t.Fatalf("checkpoint = %v, %v", ckpt, err)
+	}
+	if want := (checkpoint{Table: "TEST_TABLE", Rows: 4, Index: 5, Offset: 5, Rejected: 1}); !reflect.DeepEqual(*ckpt, want) {
+		t.Errorf("checkpoint = %+v, want %+v", *ckpt, want)
+	}
+
+	cfg.Resume = true
+	if err := Run(context.Background(), cfg, newSeekSource(7)); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if want := []int{1, 3, 4, 5, 6, 7}; !slices.Equal(inserted, want) {
+		t.Errorf("inserted %v, want %v", inserted, want)
+	}
+	if truncates != 1 {
+		t.Errorf("truncated %d times, want only by the first run", truncates)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("checkpoint kept after the load completed: %v", err)
+	}
+}
+
+func TestRun_CheckpointRejectFile(t *testing.T) {
+	dir := t.TempDir()
+	path, badPath := filepath.Join(dir, "load.ckpt"), filepath.Join(dir, "load.bad")
+	failSecond := true
+	var inserted []int
+	repo := &MockRepo{
+		BulkInsertFunc: func(ctx context.Context, builder *rp_dynamic.BulkInsertBuilder) error {
+			rows := builder.GetArgs()[0].([]interface{})
+			if rows[0] == 4 && failSecond {
+				return errors.New("connection lost")
+			}
+			for _, v := range rows {
+				inserted = append(inserted, v.(int))
+			}
+			return nil
+		},
+	}
+	convert := func(rawRow interface{}) ([]interface{}, error) {
+		if rawRow == 2 || rawRow == 5 {
+			return nil, errors.New("bad row")
+		}
+		return []interface{}{rawRow}, nil
+	}
+	run := func() error {
+		src := newSeekSource(6)
+		src.ConvertFunc = convert
+		rf := &RejectFile{Path: badPath}
+		cfg := createValidConfig(repo)
+		cfg.BatchSize, cfg.CheckpointFile, cfg.Resume, cfg.RejectFile = 2, path, !failSecond, rf
+		err := Run(context.Background(), cfg, src)
+		if cerr := rf.Close(); cerr != nil {
+			t.Fatal(cerr)
+		}
+		return err
+	}
+
+	// rows 1,3 are inserted with row 2 rejected; row 5 is rejected, then 4,6 fails
+	if err := run(); err == nil {
+		t.Fatal("expected the second batch to fail")
+	}
+	header := "ROW_INDEX,ERROR,RAW_DATA\n"
+	data, err := os.ReadFile(badPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := header + "2,bad row,2\n5,bad row,5\n"; string(data) != want {
+		t.Fatalf("reject file after the failure:\n%s\nwant\n%s", data, want)
+	}
+	ckpt, err := resume.Read[checkpoint](path)
+	if err != nil || ckpt == nil {
+		t.Fatalf("checkpoint = %v, %v", ckpt, err)
+	}
+	want := checkpoint{Table: "TEST_TABLE", Rows: 2, Index: 3, Offset: 3, Rejected: 1,
+		RejectSize: int64(len(header + "2,bad row,2\n"))}
+	if !reflect.DeepEqual(*ckpt, want) {
+		t.Errorf("checkpoint = %+v, want %+v", *ckpt, want)
+	}
+
+	// row 5, past the checkpoint, is rejected again but not written twice
+	failSecond = false
+	if err := run(); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if data, err = os.ReadFile(badPath); err != nil {
+		t.Fatal(err)
+	}
+	if want := header + "2,bad row,2\n5,bad row,5\n"; string(data) != want {
+		t.Errorf("reject file after resuming:\n%s\nwant\n%s", data, want)
+	}
+	if want := []int{1, 3, 4, 6}; !slices.Equal(inserted, want) {
+		t.Errorf("inserted %v, want %v", inserted, want)
+	}
+}
+
+func TestRun_CheckpointRejectsSkippedBatch(t *testing.T) {
+	dir := t.TempDir()
+	path, badPath := filepath.Join(dir, "load.ckpt"), filepath.Join(dir, "load.bad")
+	var mu sync.Mutex
+	var inserted []int
+	failFirst := true
+	secondDone := make(chan struct{})
+	repo := &MockRepo{
+		BulkInsertFunc: func(ctx context.Context, builder *rp_dynamic.BulkInsertBuilder) error {
+			rows := builder.GetArgs()[0].([]interface{})
+			if rows[0] == 1 && failFirst {
+				// the batch of rows 3,5 commits before this one fails
+				<-secondDone
+				return errors.New("connection lost")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, v := range rows {
+				inserted = append(inserted, v.(int))
+			}
+			if rows[0] == 3 {
+				close(secondDone)
+			}
+			return nil
+		},
+	}
+	run := func() (*Loader, error) {
+		src := newSeekSource(5)
+		src.ConvertFunc = func(rawRow interface{}) ([]interface{}, error) {
+			if rawRow == 4 {
+				return nil, errors.New("bad row")
+			}
+			return []interface{}{rawRow}, nil
+		}
+		rf := &RejectFile{Path: badPath}
+		cfg := createValidConfig(repo)
+		cfg.BatchSize, cfg.Workers, cfg.CheckpointFile, cfg.Resume, cfg.RejectFile = 2, 2, path, !failFirst, rf
+		l := NewLoader(cfg, src)
+		err := l.Run(context.Background())
+		if cerr := rf.Close(); cerr != nil {
+			t.Fatal(cerr)
+		}
+		return l, err
+	}
+
+	if _, err := run(); err == nil {
+		t.Fatal("expected the first batch to fail")
+	}
+	ckpt, err := resume.Read[checkpoint](path)
+	if err != nil || ckpt == nil {
+		t.Fatalf("checkpoint = %v, %v", ckpt, err)
+	}
+	if want := (checkpoint{Table: "TEST_TABLE", Rows: 2, Done: resume.Ranges{{First: 3, Last: 5}}}); !reflect.DeepEqual(*ckpt, want) {
+		t.Errorf("checkpoint = %+v, want %+v", *ckpt, want)
+	}
+
+	// row 4 sits in the batch skipped on resume, so it is rejected again there
+	failFirst = false
+	l, err := run()
+	if err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	data, err := os.ReadFile(badPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ROW_INDEX,ERROR,RAW_DATA\n4,bad row,4\n"; string(data) != want {
+		t.Errorf("reject file:\n%s\nwant\n%s", data, want)
+	}
+	if l.Rejected() != 1 {
+		t.Errorf("Rejected() = %d, want 1", l.Rejected())
+	}
+	if want := []int{3, 5, 1, 2}; !slices.Equal(inserted, want) {
+		t.Errorf("inserted %v, want %v", inserted, want)
+	}
+}
+
+func TestRun_CheckpointWorkers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "load.ckpt")
+	var mu sync.Mutex
+	var inserted []int
+	failFirst := true
+	secondDone := make(chan struct{})
+	repo := &MockRepo{
+		BulkInsertFunc: func(ctx context.Context, builder *rp_dynamic.BulkInsertBuilder) error {
+			rows := builder.GetArgs()[0].([]interface{})
+			if rows[0] == 1 && failFirst {
+				// the batch of rows 3,4 commits before this one fails
+				<-secondDone
+				return errors.New("connection lost")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, v := range rows {
+				inserted = append(inserted, v.(int))
+			}
+			if rows[0] == 3 {
+				close(secondDone)
+			}
+			return nil
+		},
+	}
+	cfg := createValidConfig(repo)
+	cfg.BatchSize, cfg.Workers, cfg.CheckpointFile = 2, 2, path
+
+	if err := Run(context.Background(), cfg, newSeekSource(4)); err == nil {
+		t.Fatal("expected the first batch to fail")
+	}
+	ckpt, err := resume.Read[checkpoint](path)
+	if err != nil || ckpt == nil {
+		t.Fatalf("checkpoint = %v, %v", ckpt, err)
+	}
+	want := checkpoint{Table: "TEST_TABLE", Rows: 2, Done: resume.Ranges{{First: 3, Last: 4}}}
+	if !reflect.DeepEqual(*ckpt, want) {
+		t.Errorf("checkpoint = %+v, want %+v", *ckpt, want)
+	}
+
+	failFirst, cfg.Resume = false, true
+	if err := Run(context.Background(), cfg, newSeekSource(4)); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	// rows 3,4 are not inserted a second time
+	if want := []int{3, 4, 1, 2}; !slices.Equal(inserted, want) {
+		t.Errorf("inserted %v, want %v", inserted, want)
+	}
+}
+
+func TestRun_CheckpointErrors(t *testing.T) {
+	dir := t.TempDir()
+	otherTable := filepath.Join(dir, "other.ckpt")
+	if err := resume.Write(otherTable, checkpoint{Table: "OTHER", Rows: 2}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		src     Source
+		path    string
+		resume  bool
+		wantErr string
+	}{
+		{"not a seeker", &MockSource{}, filepath.Join(dir, "a.ckpt"), false, "checkpoint needs a source implementing Seeker, *bulkloadv3.MockSource does not"},
+		{"resume without file", newSeekSource(1), "", true, "resume needs a checkpoint file"},
+		{"other table", newSeekSource(1), otherTable, true, "checkpoint is for table OTHER, not TEST_TABLE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createValidConfig(&MockRepo{})
+			cfg.CheckpointFile, cfg.Resume = tt.path, tt.resume
+			err := Run(context.Background(), cfg, tt.src)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRun_StaleCheckpointRemoved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "load.ckpt")
+	if err := resume.Write(path, checkpoint{Table: "TEST_TABLE", Rows: 2, Index: 2, Offset: 2}); err != nil {
+		t.Fatal(err)
+	}
+	// a fresh run truncates, so an old checkpoint must not outlive it
+	cfg := createValidConfig(&MockRepo{
+		TruncateFunc: func(ctx context.Context, tableName string) error {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("checkpoint still there at truncate: %v", err)
+			}
+			return errors.New("stop here")
+		},
+	})
+	cfg.CheckpointFile = path
+	_ = Run(context.Background(), cfg, newSeekSource(3))
+}
+
+// rowsSource yields the ints 1..n
+func rowsSource(n int) *MockSource {
+	var mu sync.Mutex
+	i := 0
+	return &MockSource{
+		NextFunc: func(ctx context.Context) (interface{}, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if i >= n {
+				return nil, io.EOF
+			}
+			i++
+			return i, nil
+		},
+	}
+}
+
+func TestRun_Workers(t *testing.T) {
+	var mu sync.Mutex
+	var got []int
+	repo := &MockRepo{
+		BulkInsertFunc: func(ctx context.Context, builder *rp_dynamic.BulkInsertBuilder) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, v := range builder.GetArgs()[0].([]interface{}) {
+				got = append(got, v.(int))
+			}
+			return nil
+		},
+	}
+	m := &MockMetrics{}
+	cfg := createValidConfig(repo)
+	cfg.BatchSize = 2
+	cfg.Workers = 4
+	cfg.Metrics = &lockedMetrics{m: m}
+	if err := Run(context.Background(), cfg, rowsSource(11)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	slices.Sort(got)
+	if len(got) != 11 || got[0] != 1 || got[10] != 11 {
+		t.Errorf("inserted %v, want 1-11 once each", got)
+	}
+	if m.Rows != 11 || m.Batches != 6 {
+		t.Errorf("Unexpected metrics: %+v", m)
+	}
+}
+
+func TestRun_ReadsWhileInserting(t *testing.T) {
+	// the first insert only finishes once the reader is into the next batch
+	readAhead := make(chan struct{})
+	calls := 0
+	repo := &MockRepo{
+		BulkInsertFunc: func(ctx context.Context, builder *rp_dynamic.BulkInsertBuilder) error {
+			if calls++; calls == 1 {
+				select {
+				case <-readAhead:
+				case <-time.After(5 * time.Second):
+					return errors.New("reader waited for the insert")
+				}
+			}
+			return nil
+		},
+	}
+	i := 0
+	src := &MockSource{
+		NextFunc: func(ctx context.Context) (interface{}, error) {
+			if i == 4 {
+				return nil, io.EOF
+			}
+			if i++; i == 4 {
+				close(readAhead)
+			}
+			return i, nil
+		},
+	}
+	cfg := createValidConfig(repo)
+	cfg.BatchSize = 2
+	if err := Run(context.Background(), cfg, src); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("BulkInsert calls = %d, want 2", calls)
+	}
+}
+
+func TestRun_WorkersFail(t *testing.T) {
+	var calls atomic.Int32
+	repo := &MockRepo{
+		BulkInsertFunc: func(ctx context.Context, builder *rp_dynamic.BulkInsertBuilder) error {
+			if calls.Add(1) == 2 {
+				return errors.New("insert boom")
+			}
+			return nil
+		},
+	}
+	cfg := createValidConfig(repo)
+	cfg.BatchSize = 1
+	cfg.Workers = 3
+	err := Run(context.Background(), cfg, rowsSource(1000))
+	if err == nil || !strings.Contains(err.Error(), "bulk insert failed: insert boom") {
+		t.Fatalf("err = %v, want the insert failure", err)
+	}
+	if n := calls.Load(); n >= 1000 {
+		t.Errorf("%d batches inserted after the failure, want the reader to stop", n)
+	}
+}
+
+// lockedMetrics serializes calls to m for concurrent insert workers
+type lockedMetrics struct {
+	mu sync.Mutex
+	m  *MockMetrics
+}
+
+func (l *lockedMetrics) AddRows(table string, n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.m.AddRows(table, n)
+}
+
+func (l *lockedMetrics) IncBatches(table string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.m.IncBatches(table)
+}
+
+func (l *lockedMetrics) IncErrors(table, stage string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.m.IncErrors(table, stage)
+}
+
+func (l *lockedMetrics) ObserveBatch(table string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.m.ObserveBatch(table, d)
+}
+
+func TestRun_Modes(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         LoadMode
+		keys         []string
+		wantTruncate bool
+		wantMerge    bool
+	}{
+		{"truncate insert", TruncateInsert, nil, true, false},
+		{"append", Append, nil, false, false},
+		{"merge", Merge, []string{"COL1"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truncated := false
+			var merged []bool
+			cfg := createValidConfig(&MockRepo{
+				TruncateFunc: func(ctx context.Context, tableName string) error {
+					truncated = true
+					return nil
+				},
+				BulkInsertFunc: func(ctx context.Context, builder *rp_dynamic.BulkInsertBuilder) error {
+					merged = append(merged, builder.IsMerge())
+					return nil
+				},
+			})
+			cfg.Mode, cfg.KeyColumns, cfg.BatchSize = tt.mode, tt.keys, 2
+			if err := Run(context.Background(), cfg, rowsSource(3)); err != nil {
+				t.Fatal(err)
+			}
+			if truncated != tt.wantTruncate {
+				t.Errorf("truncated = %v, want %v", truncated, tt.wantTruncate)
+			}
+			if want := []bool{tt.wantMerge, tt.wantMerge}; !slices.Equal(merged, want) {
+				t.Errorf("merge batches = %v, want %v", merged, want)
+			}
+		})
+	}
+}
+
+func TestRun_MergeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		insert  bulkinsert.Strategy
+		wantErr string
+	}{
+		{"no keys", nil, "", "merge needs key columns"},
+		{"unknown key", []string{"ID"}, "", "key column ID is not a target column"},
+		{"insert all", []string{"COL1"}, bulkinsert.StrategyInsertAll, "merge needs array binding, not insert-all"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createValidConfig(&MockRepo{})
+			cfg.Mode, cfg.KeyColumns, cfg.Insert = Merge, tt.keys, tt.insert
+			err := Run(context.Background(), cfg, &MockSource{})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
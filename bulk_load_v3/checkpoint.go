@@ -0,0 +1,30 @@
+package bulkloadv3
+
+import (
+	"context"
+
+	"sql-learn2/internal/resume"
+)
+
+// Seeker is implemented by sources a load can resume (see Config.Resume).
+// Offset is the position just past the last row Next returned; Seek, called
+// after Validate, makes Next continue from a position Offset reported.
+type Seeker interface {
+	Offset() int64
+	Seek(ctx context.Context, offset int64) error
+}
+
+// checkpoint records how far a load got: the source rows up to Offset are
+// committed or rejected, and so are the rows in Done past it, batches that
+// committed ahead of one that failed. Rejected rows up to Offset are the
+// first RejectSize bytes of Config.RejectFile; a resumed load drops the
+// rest and rejects those rows again.
+type checkpoint struct {
+	Table      string        `json:"table"`
+	Rows       int           `json:"rows"`   // rows committed up to Offset and in Done
+	Index      int           `json:"index"`  // source rows up to Offset, rejected ones included
+	Offset     int64         `json:"offset"` // Seeker.Offset after those rows
+	Done       resume.Ranges `json:"done,omitempty"`
+	Rejected   int           `json:"rejected,omitempty"`
+	RejectSize int64         `json:"reject_size,omitempty"`
+}
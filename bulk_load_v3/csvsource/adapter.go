@@ -5,10 +5,10 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
-	"log/slog"
 	"os"
 
 	"sql-learn2/bulk_load_v3"
+	"sql-learn2/logging"
 )
 
 // sourceAdapter adapts CsvSource to the bulkloadv3.Source interface.
@@ -21,7 +21,9 @@ type sourceAdapter struct {
 // Validate opens the CSV file, validates that all required headers exist,
 // and prepares the column mapping.
 func (a *sourceAdapter) Validate(ctx context.Context) error {
-	slog.Info("Opening CSV for validation", bulkloadv3.LogFieldFile, a.cfg.FilePath, bulkloadv3.LogFieldTable, a.cfg.TableName)
+	// The loader's logger already carries the table field
+	logger := logging.FromContext(ctx).With(bulkloadv3.LogFieldFile, a.cfg.FilePath)
+	logger.Info("Opening CSV for validation")
 
 	if err := a.openFile(); err != nil {
 		return err
@@ -36,7 +38,7 @@ func (a *sourceAdapter) Validate(ctx context.Context) error {
 		return err
 	}
 
-	slog.Info("CSV validation successful", bulkloadv3.LogFieldFile, a.cfg.FilePath, bulkloadv3.LogFieldTable, a.cfg.TableName)
+	logger.Info("CSV validation successful")
 	return nil
 }
 
@@ -51,13 +53,48 @@ func (a *sourceAdapter) openFile() error {
 		return fmt.Errorf("failed to open file %s: %w", a.cfg.FilePath, err)
 	}
 	a.file = f
+	a.newReader(0)
+	// Enforce that all records have the same number of fields as the first record (header).
+	a.reader.FieldsPerRecord = 0
+	return nil
+}
 
-	a.reader = csv.NewReader(f)
+// newReader reads the file from its current position, which is offset
+func (a *sourceAdapter) newReader(offset int64) {
+	a.reader = csv.NewReader(a.file)
 	if a.cfg.Delimiter != 0 {
 		a.reader.Comma = a.cfg.Delimiter
 	}
-	// Enforce that all records have the same number of fields as the first record (header).
-	a.reader.FieldsPerRecord = 0
+	a.start = offset
+}
+
+// Offset is the byte offset in the file past the last record read
+func (a *sourceAdapter) Offset() int64 {
+	if a.reader == nil {
+		return 0
+	}
+	return a.start + a.reader.InputOffset()
+}
+
+// Seek makes Next continue at offset, a position Offset reported for the
+// same file; the header must have been read by Validate
+func (a *sourceAdapter) Seek(ctx context.Context, offset int64) error {
+	if a.reader == nil {
+		return fmt.Errorf("reader not initialized (call Validate first)")
+	}
+	st, err := a.file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", a.cfg.FilePath, err)
+	}
+	if first := a.Offset(); offset < first || offset > st.Size() {
+		return fmt.Errorf("offset %d is outside the data rows of %s (bytes %d-%d)", offset, a.cfg.FilePath, first, st.Size())
+	}
+	if _, err := a.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("seek %s: %w", a.cfg.FilePath, err)
+	}
+	fields := a.reader.FieldsPerRecord // set from the header
+	a.newReader(offset)
+	a.reader.FieldsPerRecord = fields
 	return nil
 }
 
@@ -2,6 +2,8 @@ package csvsource
 
 import (
 	"strconv"
+
+	"sql-learn2/numformat"
 )
 
 // ParserFunc defines the function signature for converting a CSV string value to a DB value.
@@ -46,3 +48,18 @@ func ParseNullableInt(s string) (interface{}, error) {
 	}
 	return strconv.Atoi(s)
 }
+
+// ParseIntIn returns a parser for integers written in format f, e.g. "1.234" with numformat.European.
+func ParseIntIn(f numformat.Format) ParserFunc {
+	return func(s string) (interface{}, error) {
+		n, err := f.ParseInt(s)
+		return int(n), err
+	}
+}
+
+// ParseFloatIn returns a parser for decimals written in format f, e.g. "1.234,56" with numformat.European.
+func ParseFloatIn(f numformat.Format) ParserFunc {
+	return func(s string) (interface{}, error) {
+		return f.ParseFloat(s)
+	}
+}
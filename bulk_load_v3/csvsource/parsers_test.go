@@ -2,6 +2,8 @@ package csvsource
 
 import (
 	"testing"
+
+	"sql-learn2/numformat"
 )
 
 func TestParseInt(t *testing.T) {
@@ -133,3 +135,28 @@ func TestParseNullableInt(t *testing.T) {
 		}
 	}
 }
+
+func TestParseIn(t *testing.T) {
+	tests := []struct {
+		parser   ParserFunc
+		input    string
+		expected interface{}
+		wantErr  bool
+	}{
+		{ParseFloatIn(numformat.European), "1.234,56", 1234.56, false},
+		{ParseFloatIn(numformat.European), "€ 0,5", 0.5, false},
+		{ParseFloatIn(numformat.European), "1.5", nil, true},
+		{ParseIntIn(numformat.English), "1,000", 1000, false},
+		{ParseIntIn(numformat.English), "1,0", nil, true},
+	}
+	for _, tt := range tests {
+		val, err := tt.parser(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parse %q error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && val != tt.expected {
+			t.Errorf("parse %q = %v, want %v", tt.input, val, tt.expected)
+		}
+	}
+}
@@ -2,13 +2,15 @@ package csvsource
 
 import (
 	"fmt"
-	"strconv"
+
+	"sql-learn2/numformat"
 )
 
 // RowParser helps simplify row conversion by collecting errors.
 // It allows declarative parsing of fields without checking error on every step.
 type RowParser struct {
-	err error
+	err    error
+	number numformat.Format
 }
 
 // NewRowParser creates a new RowParser.
@@ -16,17 +18,23 @@ func NewRowParser() *RowParser {
 	return &RowParser{}
 }
 
+// NewRowParserIn creates a RowParser whose numeric methods accept format f,
+// e.g. numformat.European for "1.234,56".
+func NewRowParserIn(f numformat.Format) *RowParser {
+	return &RowParser{number: f}
+}
+
 // Int parses a string as an integer.
 func (p *RowParser) Int(s string, field string) interface{} {
 	if p.err != nil {
 		return nil
 	}
-	val, err := strconv.Atoi(s)
+	val, err := p.number.ParseInt(s)
 	if err != nil {
 		p.err = fmt.Errorf("invalid %s '%s': %w", field, s, err)
 		return nil
 	}
-	return val
+	return int(val)
 }
 
 // Float64 parses a string as a float64.
@@ -34,7 +42,7 @@ func (p *RowParser) Float64(s string, field string) interface{} {
 	if p.err != nil {
 		return nil
 	}
-	val, err := strconv.ParseFloat(s, 64)
+	val, err := p.number.ParseFloat(s)
 	if err != nil {
 		p.err = fmt.Errorf("invalid %s '%s': %w", field, s, err)
 		return nil
@@ -69,12 +77,12 @@ func (p *RowParser) NullableInt(s string, field string) interface{} {
 	if s == "" {
 		return nil
 	}
-	val, err := strconv.Atoi(s)
+	val, err := p.number.ParseInt(s)
 	if err != nil {
 		p.err = fmt.Errorf("invalid %s '%s': %w", field, s, err)
 		return nil
 	}
-	return val
+	return int(val)
 }
 
 // Err returns the first error encountered during parsing.
@@ -4,10 +4,16 @@ import (
 	"context"
 	"encoding/csv"
 	"fmt"
+	"log/slog"
 	"os"
 	"runtime/debug"
+
 	"sql-learn2/bulk_load_v3"
 	"sql-learn2/bulk_load_v3/rp_dynamic"
+	"sql-learn2/bulkinsert"
+	"sql-learn2/dynamic"
+	"sql-learn2/metrics"
+	"sql-learn2/retry"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -30,6 +36,29 @@ type Config struct {
 	TableName string
 	BatchSize int
 	MVName    string
+	Logger    *slog.Logger            // defaults to slog.Default()
+	Metrics   metrics.Metrics         // optional, see bulkloadv3.Config
+	Retry     retry.Policy            // optional, see bulkloadv3.Config
+	Workers   int                     // concurrent batch inserts, see bulkloadv3.Config
+	Insert    bulkinsert.Strategy     // optional, see bulkloadv3.Config
+	Truncate  dynamic.TruncateOptions // optional REUSE STORAGE / DELETE fallback for the initial truncate
+
+	// RejectFile, when set, collects the rows that fail to parse (see
+	// bulkloadv3.RejectFile) and the load goes on without them, a resumed
+	// load continuing the file; MaxRejects > 0 fails it once more rows than
+	// that are rejected
+	RejectFile string
+	MaxRejects int
+
+	// Mode and KeyColumns choose between truncating, appending to and
+	// merging into the table, see bulkloadv3.Config
+	Mode       bulkloadv3.LoadMode
+	KeyColumns []string
+
+	// CheckpointFile and Resume let an interrupted load continue where it
+	// stopped, see bulkloadv3.Config
+	CheckpointFile string
+	Resume         bool
 }
 
 // CsvSource implements bulkloadv3.Source using the native encoding/csv package.
@@ -38,6 +67,7 @@ type CsvSource struct {
 
 	file   *os.File
 	reader *csv.Reader
+	start  int64 // file offset reader started at
 
 	// columnIndices maps the index in cfg.Parsers to the index in the CSV row.
 	// columnIndices[i] is the CSV index for cfg.Parsers[i].
@@ -70,6 +100,16 @@ func (s *CsvSource) Run(ctx context.Context) (err error) {
 	}
 
 	loaderCfg := s.createLoaderConfig(dbColumns)
+	if s.cfg.RejectFile != "" {
+		rf := &bulkloadv3.RejectFile{Path: s.cfg.RejectFile}
+		defer func() {
+			if cerr := rf.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		loaderCfg.RejectFile = rf
+		loaderCfg.MaxRejects = s.cfg.MaxRejects
+	}
 	loader := bulkloadv3.NewLoader(loaderCfg, &sourceAdapter{CsvSource: s})
 	return loader.Run(ctx)
 }
@@ -100,12 +140,23 @@ func (s *CsvSource) extractDBColumns() ([]string, error) {
 
 func (s *CsvSource) createLoaderConfig(dbColumns []string) bulkloadv3.Config {
 	repo := rp_dynamic.NewRepo(s.cfg.DB)
+	repo.TruncateOptions = s.cfg.Truncate
 	return bulkloadv3.Config{
 		Repo:      repo,
 		TableName: s.cfg.TableName,
 		Columns:   dbColumns,
 		BatchSize: s.cfg.BatchSize,
 		MVName:    s.cfg.MVName,
+		Logger:    s.cfg.Logger,
+		Metrics:   s.cfg.Metrics,
+		Retry:     s.cfg.Retry,
+		Workers:   s.cfg.Workers,
+		Insert:    s.cfg.Insert,
+
+		CheckpointFile: s.cfg.CheckpointFile,
+		Resume:         s.cfg.Resume,
+		Mode:           s.cfg.Mode,
+		KeyColumns:     s.cfg.KeyColumns,
 	}
 }
 
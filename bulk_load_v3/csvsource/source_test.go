@@ -3,8 +3,10 @@ package csvsource
 import (
 	"context"
 	"encoding/csv"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -316,3 +318,62 @@ func searchString(s, substr string) bool {
 	}
 	return false
 }
+
+func TestSeek(t *testing.T) {
+	filePath := createTempCSV(t, [][]string{
+		{"ID", "NAME"},
+		{"1", "Alice"},
+		{"2", "Bob, Jr."},
+		{"3", "Carol"},
+	})
+	cfg := Config{
+		FilePath:  filePath,
+		TableName: "TEST_TABLE",
+		Parsers:   []Parser{{CSVHeader: "ID", DBColumn: "USER_ID", ParserFunc: ParseInt}},
+	}
+	ctx := context.Background()
+	open := func() *sourceAdapter {
+		src, closer := New(cfg)
+		t.Cleanup(func() { closer() })
+		adapter := &sourceAdapter{CsvSource: src}
+		if err := adapter.Validate(ctx); err != nil {
+			t.Fatalf("Validate failed: %v", err)
+		}
+		return adapter
+	}
+
+	first := open()
+	afterHeader := first.Offset()
+	if _, err := first.Next(ctx); err != nil {
+		t.Fatal(err)
+	}
+	offset := first.Offset()
+
+	resumed := open()
+	if err := resumed.Seek(ctx, offset); err != nil {
+		t.Fatalf("Seek failed: %v", err)
+	}
+	var ids []string
+	for {
+		row, err := resumed.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, row.([]string)[0])
+	}
+	if strings.Join(ids, ",") != "2,3" {
+		t.Errorf("rows after Seek = %v, want 2,3", ids)
+	}
+	if st, err := os.Stat(filePath); err != nil || resumed.Offset() != st.Size() {
+		t.Errorf("Offset at the end = %d, want the file size (%v)", resumed.Offset(), err)
+	}
+
+	for _, bad := range []int64{afterHeader - 1, 1 << 20} {
+		if err := open().Seek(ctx, bad); err == nil || !strings.Contains(err.Error(), "outside the data rows") {
+			t.Errorf("Seek(%d) = %v, want an out of range error", bad, err)
+		}
+	}
+}
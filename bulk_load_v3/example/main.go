@@ -5,26 +5,71 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"os"
+	"net/http"
 	"time"
 
+	"sql-learn2/bulk_load_v3"
 	"sql-learn2/bulk_load_v3/csvsource"
+	"sql-learn2/bulkinsert"
+	"sql-learn2/dynamic"
+	"sql-learn2/logging"
+	"sql-learn2/metrics"
+	"sql-learn2/oraconn"
+	"sql-learn2/oraerr"
+	"sql-learn2/retry"
+	"sql-learn2/tracing"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/sijms/go-ora/v2"
 )
 
 // Main function demonstrating the usage of bulk_load_v3 with the csvsource helper.
 func main() {
 	// Configuration
-	user := flag.String("user", getEnv("ORA_USER", "LEARN1"), "Oracle username")
-	pass := flag.String("pass", getEnv("ORA_PASS", "Welcome"), "Oracle password")
-	host := flag.String("host", getEnv("ORA_HOST", "localhost"), "Oracle host")
-	port := flag.String("port", getEnv("ORA_PORT", "1521"), "Oracle port")
-	service := flag.String("service", getEnv("ORA_SERVICE", "XE"), "Oracle service name")
+	var ora oraconn.Config
+	ora.RegisterFlags(flag.CommandLine)
+	var logCfg logging.Config
+	logCfg.RegisterFlags(flag.CommandLine)
+	metricsAddr := flag.String("metrics-addr", "", "Serve expvar metrics on this address at /debug/vars, e.g. :9090")
+	retries := flag.Int("retries", 3, "Extra attempts for a batch insert or MV refresh failing with a network error or lock timeout")
+	retryWait := flag.Duration("retry-wait", retry.Default.Initial, "Wait before the first retry, doubling for each further one")
+	retryCodes := flag.String("retry-codes", "", "Comma-separated ORA error numbers to retry as well, e.g. 1555,8177")
+	workers := flag.Int("workers", 1, "Batches inserted at once, each on its own connection, while the next rows are read")
+	rejectFile := flag.String("reject-file", "", "Write rows that fail to parse to this CSV and keep loading, instead of failing the load")
+	maxRejects := flag.Int("max-rejects", 0, "With -reject-file, fail once more rows than this are rejected (0 = no limit)")
+	checkpointFile := flag.String("checkpoint-file", "", "Record after every batch how far the load got, so -resume can continue it after a crash")
+	resume := flag.Bool("resume", false, "Continue an interrupted load from -checkpoint-file instead of truncating PRODUCT")
+	mode := flag.String("mode", "truncate", "What happens to the rows in PRODUCT: truncate (reload), append or merge (upsert on PRODUCT_ID)")
+	insert := flag.String("insert", "array", "How batches are sent: array (array binds) or insert-all (INSERT ALL statements, for drivers without array binding)")
+	reuseStorage := flag.Bool("reuse-storage", false, "Truncate PRODUCT with REUSE STORAGE to keep its extents between reloads")
 	flag.Parse()
-
-	dbConnStr := fmt.Sprintf("oracle://%s:%s@%s:%s/%s", *user, *pass, *host, *port, *service)
+	if _, err := logCfg.Setup(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	insertStrategy, err := bulkinsert.ParseStrategy(*insert)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	loadMode, err := bulkloadv3.ParseLoadMode(*mode)
+	if err != nil {
+		log.Fatalf("-mode: %v", err)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), "bulk-load-v3-example")
+	if err != nil {
+		log.Fatalf("tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Rows, batches, errors and batch latency per table
+	loadMetrics := metrics.NewExpvar("bulk_load")
+	if *metricsAddr != "" {
+		go func() {
+			// expvar registers /debug/vars on the default mux
+			if err := http.ListenAndServe(*metricsAddr, nil); err != nil {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+		log.Printf("Serving metrics on http://%s/debug/vars", *metricsAddr)
+	}
 
 	const (
 		tableName = "PRODUCT"
@@ -52,12 +97,13 @@ func main() {
 	runTime := time.Now().In(loc)
 
 	fmt.Println("Starting Bulk Load Example...")
-	fmt.Printf("Connecting to DB: %s (masked)\n", dbConnStr)
+	fmt.Printf("Connecting to DB: %s\n", ora)
 
-	db, err := sqlx.Open("oracle", dbConnStr)
+	sqlDB, err := oraconn.Open(ora)
 	if err != nil {
 		log.Fatalf("Failed to open DB driver: %v", err)
 	}
+	db := sqlx.NewDb(sqlDB, "oracle")
 	defer db.Close()
 
 	// Ensure the connection is valid (this will fail if no DB is running)
@@ -66,6 +112,15 @@ func main() {
 		log.Println("Continuing to demonstrate structure, but execution will likely fail at DB operations.")
 	}
 
+	retryPolicy := retry.Default
+	retryPolicy.MaxAttempts = *retries + 1
+	retryPolicy.Initial = *retryWait
+	if codes, err := retry.ParseCodes(*retryCodes); err != nil {
+		log.Fatalf("-retry-codes: %v", err)
+	} else if len(codes) > 0 {
+		retryPolicy.Retryable = retry.RetryableCodes(codes...)
+	}
+
 	// Initialize the CSV Source using the reusable library
 	src, closer := csvsource.New(csvsource.Config{
 		FilePath:            csvFile,
@@ -88,7 +143,19 @@ func main() {
 				return runTime, nil
 			}},
 		},
-		MVName: "MV_PRODUCT",
+		MVName:     "MV_PRODUCT",
+		Metrics:    loadMetrics,
+		Retry:      retryPolicy,
+		Workers:    *workers,
+		RejectFile: *rejectFile,
+		MaxRejects: *maxRejects,
+		Insert:     insertStrategy,
+		Truncate:   dynamic.TruncateOptions{ReuseStorage: *reuseStorage},
+
+		CheckpointFile: *checkpointFile,
+		Resume:         *resume,
+		Mode:           loadMode,
+		KeyColumns:     []string{colID},
 	})
 	defer closer()
 
@@ -96,15 +163,8 @@ func main() {
 	start := time.Now()
 
 	if err := src.Run(ctx); err != nil {
-		log.Fatalf("Bulk load failed: %v", err)
+		oraerr.Fatal("Bulk load failed", err)
 	}
 
 	log.Printf("Bulk load completed in %v", time.Since(start))
 }
-
-func getEnv(key, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
-		return value
-	}
-	return fallback
-}
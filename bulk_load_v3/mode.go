@@ -0,0 +1,42 @@
+package bulkloadv3
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LoadMode says what a load does with the rows already in the table
+type LoadMode int
+
+const (
+	// TruncateInsert empties the table and inserts the source rows
+	TruncateInsert LoadMode = iota
+	// Append inserts the source rows next to the existing ones
+	Append
+	// Merge updates the rows matching a source row on Config.KeyColumns
+	// and inserts the others
+	Merge
+)
+
+// ParseLoadMode parses a -mode flag value: truncate, append or merge
+func ParseLoadMode(s string) (LoadMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "truncate", "truncate-insert":
+		return TruncateInsert, nil
+	case "append":
+		return Append, nil
+	case "merge", "upsert":
+		return Merge, nil
+	}
+	return 0, fmt.Errorf("unknown load mode %q (use truncate, append or merge)", s)
+}
+
+func (m LoadMode) String() string {
+	switch m {
+	case Append:
+		return "append"
+	case Merge:
+		return "merge"
+	}
+	return "truncate"
+}
@@ -0,0 +1,29 @@
+package bulkloadv3
+
+import "testing"
+
+func TestParseLoadMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    LoadMode
+		wantErr bool
+	}{
+		{"", TruncateInsert, false},
+		{"truncate", TruncateInsert, false},
+		{"Append", Append, false},
+		{" merge ", Merge, false},
+		{"upsert", Merge, false},
+		{"replace", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLoadMode(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLoadMode(%q) = %v, %v; want %v, err %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+		if err == nil {
+			if back, _ := ParseLoadMode(got.String()); back != got {
+				t.Errorf("%v does not round-trip through String", got)
+			}
+		}
+	}
+}
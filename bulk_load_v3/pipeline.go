@@ -0,0 +1,144 @@
+package bulkloadv3
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"sql-learn2/bulk_load_v3/rp_dynamic"
+	"sql-learn2/internal/resume"
+)
+
+// flushJob is one full buffer handed from the reader to the insert workers
+type flushJob struct {
+	seq     int // submission order, set by submit
+	builder *rp_dynamic.BulkInsertBuilder
+	rows    int
+	read    time.Duration // time spent reading and converting the rows
+	final   bool          // the remaining rows after the source ended
+	first   int           // source index of the first row, for the checkpoint
+	index   int           // source rows read up to the last one
+	offset  int64         // Seeker.Offset after the last row
+	// rows rejected up to the last one, and the Config.RejectFile bytes
+	// holding them
+	rejected   int
+	rejectSize int64
+}
+
+// span is the source rows of j, rejected ones included
+func (j flushJob) span() resume.Range {
+	return resume.Range{First: j.first, Last: j.index}
+}
+
+// inserters run flushBatch for the jobs the reader submits, Config.Workers
+// at a time. The first failure cancels the others and the reader.
+type inserters struct {
+	parent context.Context
+	ctx    context.Context // canceled on the first failure
+	cancel context.CancelFunc
+	jobs   chan flushJob
+	wg     sync.WaitGroup
+	seq    int // next job number
+
+	mu  sync.Mutex
+	err error
+
+	// save, when set, is called under mu with ckpt after every inserted
+	// job: batches may commit out of order, so progress keeps the jobs
+	// inserted past a gap in ckpt.Done for a resumed load to skip
+	save     func(checkpoint)
+	ckpt     checkpoint
+	progress *resume.Tracker[flushJob]
+}
+
+// startInserters starts the insert workers; the jobs channel holds up to
+// one waiting builder per worker, so at most 2*workers buffers are in memory.
+// With Config.CheckpointFile the checkpoint moves on from base as batches
+// are inserted.
+func (l *Loader) startInserters(ctx context.Context, base checkpoint) *inserters {
+	workers := max(l.cfg.Workers, 1)
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	in := &inserters{parent: parent, ctx: ctx, cancel: cancel, jobs: make(chan flushJob, workers)}
+	if path := l.cfg.CheckpointFile; path != "" {
+		in.ckpt, in.progress = base, resume.NewTracker(base.Index, base.Done, flushJob.span)
+		in.save = func(c checkpoint) {
+			if err := resume.Write(path, c); err != nil {
+				l.logger.Warn("Checkpoint not saved", "checkpoint", path, LogFieldErr, err)
+			}
+		}
+	}
+	for range workers {
+		in.wg.Add(1)
+		go func() {
+			defer in.wg.Done()
+			for j := range in.jobs {
+				if ctx.Err() != nil {
+					continue // dropped behind the failure that stopped the load
+				}
+				if err := l.flushBatch(ctx, j.builder, j.rows, j.read); err != nil {
+					if j.final {
+						l.logger.Error("Final bulk insert failed", LogFieldErr, err)
+						err = fmt.Errorf("final bulk insert failed: %w", err)
+					}
+					in.fail(ctx, err)
+					continue
+				}
+				in.inserted(j)
+			}
+		}()
+	}
+	return in
+}
+
+// inserted adds j to the checkpoint, advancing it over the contiguous run
+// of inserted jobs past j if it can
+func (in *inserters) inserted(j flushJob) {
+	if in.save == nil {
+		return
+	}
+	j.builder = nil // inserted; only its position is kept
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.ckpt.Rows += j.rows
+	if run := in.progress.Commit(j.seq, j); len(run) > 0 {
+		at := run[len(run)-1]
+		in.ckpt.Offset, in.ckpt.Rejected, in.ckpt.RejectSize = at.offset, at.rejected, at.rejectSize
+	}
+	in.ckpt.Index, in.ckpt.Done = in.progress.Pos(), in.progress.Done()
+	in.save(in.ckpt)
+}
+
+// fail records err unless an earlier failure already canceled the workers
+func (in *inserters) fail(ctx context.Context, err error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.err == nil && ctx.Err() == nil {
+		in.err = err
+	}
+	in.cancel()
+}
+
+// submit queues j and reports false once the workers have failed
+func (in *inserters) submit(j flushJob) bool {
+	j.seq = in.seq
+	in.seq++
+	select {
+	case in.jobs <- j:
+		return true
+	case <-in.ctx.Done():
+		return false
+	}
+}
+
+// wait lets the workers insert the queued batches and returns the first failure
+func (in *inserters) wait() error {
+	close(in.jobs)
+	in.wg.Wait()
+	in.cancel()
+	if in.err == nil {
+		return in.parent.Err() // canceled from outside
+	}
+	return in.err
+}
@@ -0,0 +1,111 @@
+package bulkloadv3
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// RejectedRow is a source row the loader skipped (see Config.Reject)
+type RejectedRow struct {
+	Index int         // 1-based position of the row in the source
+	Raw   interface{} // the row as Source.Next returned it
+	Err   error       // why Convert or the buffer refused it
+}
+
+// RejectFile is a Config.Reject sink writing rejected rows to a CSV with
+// the columns ROW_INDEX, ERROR and then the raw row: the fields of a
+// []string (as csvsource reads them), anything else formatted with %v.
+// The file is created with the first rejected row. Set it as
+// Config.RejectFile for a checkpointed load to continue it on resume.
+type RejectFile struct {
+	Path string
+
+	f     *os.File
+	w     *csv.Writer
+	count int
+	size  int64 // bytes written, header included
+}
+
+// Add writes r; use it as Config.Reject
+func (rf *RejectFile) Add(r RejectedRow) error {
+	if rf.w == nil {
+		f, err := os.Create(rf.Path)
+		if err != nil {
+			return fmt.Errorf("create reject file: %w", err)
+		}
+		w := csv.NewWriter(f)
+		if err := w.Write([]string{"ROW_INDEX", "ERROR", "RAW_DATA"}); err != nil {
+			f.Close()
+			return fmt.Errorf("write reject file: %w", err)
+		}
+		rf.f, rf.w = f, w
+	}
+	rec := []string{strconv.Itoa(r.Index), r.Err.Error()}
+	if fields, ok := r.Raw.([]string); ok {
+		rec = append(rec, fields...)
+	} else {
+		rec = append(rec, fmt.Sprint(r.Raw))
+	}
+	// flushed row by row, so a failing disk stops the load at this row and
+	// size always matches the file for a checkpoint
+	rf.w.Write(rec)
+	rf.w.Flush()
+	err := rf.w.Error()
+	if err == nil {
+		rf.size, err = rf.f.Seek(0, io.SeekCurrent)
+	}
+	if err != nil {
+		return fmt.Errorf("write reject file: %w", err)
+	}
+	rf.count++
+	return nil
+}
+
+// reopen continues the file of an interrupted load, whose checkpoint had
+// count rows in its first size bytes. What was written past them is
+// dropped: those rows are read and rejected again.
+func (rf *RejectFile) reopen(count int, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(rf.Path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("open reject file: %w", err)
+	}
+	if err = f.Truncate(size); err == nil {
+		_, err = f.Seek(size, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("open reject file: %w", err)
+	}
+	rf.f, rf.w = f, csv.NewWriter(f)
+	rf.count, rf.size = count, size
+	return nil
+}
+
+// Count is the rows written so far, those kept from the load resumed
+// included
+func (rf *RejectFile) Count() int {
+	return rf.count
+}
+
+// Close closes the file; it is a no-op when nothing was rejected
+func (rf *RejectFile) Close() error {
+	if rf.f == nil {
+		return nil
+	}
+	rf.w.Flush()
+	err := rf.w.Error()
+	if cerr := rf.f.Close(); err == nil {
+		err = cerr
+	}
+	rf.f = nil
+	if err != nil {
+		return fmt.Errorf("write reject file: %w", err)
+	}
+	return nil
+}
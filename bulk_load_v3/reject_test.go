@@ -0,0 +1,60 @@
+package bulkloadv3
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRejectFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "load.bad")
+	rf := &RejectFile{Path: path}
+	if err := rf.Close(); err != nil {
+		t.Fatalf("Close before any row: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("file created without rejects: %v", err)
+	}
+
+	rows := []RejectedRow{
+		{Index: 3, Raw: []string{"7", "a,b"}, Err: errors.New("bad int")},
+		{Index: 9, Raw: map[string]int{"id": 1}, Err: errors.New("no code")},
+	}
+	for _, r := range rows {
+		if err := rf.Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "ROW_INDEX,ERROR,RAW_DATA\n" +
+		"3,bad int,7,\"a,b\"\n" +
+		"9,no code,map[id:1]\n"
+	if string(data) != want {
+		t.Errorf("reject file:\n%s\nwant\n%s", data, want)
+	}
+	if rf.Count() != 2 {
+		t.Errorf("Count = %d, want 2", rf.Count())
+	}
+}
+
+func TestRejectFile_WriteError(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("no /dev/full")
+	}
+	rf := &RejectFile{Path: "/dev/full"}
+	err := rf.Add(RejectedRow{Index: 1, Raw: "x", Err: errors.New("bad")})
+	if err == nil || !strings.Contains(err.Error(), "write reject file") {
+		t.Errorf("Add = %v, want the write error", err)
+	}
+	if rf.Count() != 0 {
+		t.Errorf("Count = %d, want 0", rf.Count())
+	}
+}
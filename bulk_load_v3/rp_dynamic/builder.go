@@ -2,6 +2,7 @@ package rp_dynamic
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -9,6 +10,7 @@ import (
 type BulkInsertBuilder struct {
 	tableName string
 	columns   []string
+	keys      []string // set by NewBulkMergeBuilder
 	// data holds the data in column-oriented format: data[colIndex][rowIndex]
 	data [][]interface{}
 }
@@ -28,6 +30,15 @@ func NewBulkInsertBuilder(tableName string, columns ...string) *BulkInsertBuilde
 	}
 }
 
+// NewBulkMergeBuilder creates a builder whose rows are merged into the
+// table on the key columns instead of inserted: matched rows get the other
+// columns updated, the rest are inserted. The keys must be among columns.
+func NewBulkMergeBuilder(tableName string, keys []string, columns ...string) *BulkInsertBuilder {
+	b := NewBulkInsertBuilder(tableName, columns...)
+	b.keys = keys
+	return b
+}
+
 // AddRow adds a single row of values to the builder.
 // The order of values must match the order of columns defined in NewBulkInsertBuilder.
 func (b *BulkInsertBuilder) AddRow(values ...interface{}) error {
@@ -54,6 +65,44 @@ func (b *BulkInsertBuilder) GetSQL() string {
 		strings.Join(placeholders, ", "))
 }
 
+// GetMergeSQL generates the MERGE statement of a NewBulkMergeBuilder, with
+// the row bound as :1, :2, etc. in the order of the columns. A table whose
+// columns are all keys only gets the missing rows inserted.
+func (b *BulkInsertBuilder) GetMergeSQL() string {
+	selectItems := make([]string, len(b.columns))
+	for i, c := range b.columns {
+		selectItems[i] = fmt.Sprintf(":%d AS %s", i+1, c)
+	}
+	onConds := make([]string, len(b.keys))
+	for i, k := range b.keys {
+		onConds[i] = fmt.Sprintf("t.%s = s.%s", k, k)
+	}
+	var sets []string
+	values := make([]string, len(b.columns))
+	for i, c := range b.columns {
+		values[i] = "s." + c
+		if !slices.Contains(b.keys, c) {
+			sets = append(sets, fmt.Sprintf("t.%s = s.%s", c, c))
+		}
+	}
+
+	query := fmt.Sprintf("MERGE INTO %s t USING (SELECT %s FROM DUAL) s ON (%s)",
+		b.tableName,
+		strings.Join(selectItems, ", "),
+		strings.Join(onConds, " AND "))
+	if len(sets) > 0 {
+		query += " WHEN MATCHED THEN UPDATE SET " + strings.Join(sets, ", ")
+	}
+	return query + fmt.Sprintf(" WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)",
+		strings.Join(b.columns, ", "),
+		strings.Join(values, ", "))
+}
+
+// IsMerge reports whether the builder came from NewBulkMergeBuilder
+func (b *BulkInsertBuilder) IsMerge() bool {
+	return len(b.keys) > 0
+}
+
 // GetArgs returns the arguments to be passed to stmt.Exec.
 // It returns a slice of slices, where each inner slice represents a column of data.
 func (b *BulkInsertBuilder) GetArgs() []interface{} {
@@ -63,3 +112,11 @@ func (b *BulkInsertBuilder) GetArgs() []interface{} {
 	}
 	return args
 }
+
+// GetNumRows returns the number of rows added so far.
+func (b *BulkInsertBuilder) GetNumRows() int {
+	if len(b.data) == 0 {
+		return 0
+	}
+	return len(b.data[0])
+}
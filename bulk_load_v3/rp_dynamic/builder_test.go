@@ -72,6 +72,9 @@ func TestAddRow(t *testing.T) {
 	if len(builder.data[1]) != 2 || builder.data[1][1] != "Bob" {
 		t.Errorf("expected data[1][1] to be 'Bob'")
 	}
+	if n := builder.GetNumRows(); n != 2 {
+		t.Errorf("expected GetNumRows() to be 2, got %d", n)
+	}
 }
 
 func TestGetSQL(t *testing.T) {
@@ -106,6 +109,51 @@ func TestGetSQL(t *testing.T) {
 	}
 }
 
+func TestGetMergeSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []string
+		columns  []string
+		expected string
+	}{
+		{
+			name:    "Single Key",
+			keys:    []string{"ID"},
+			columns: []string{"ID", "CODE", "PRICE"},
+			expected: "MERGE INTO PRODUCTS t USING (SELECT :1 AS ID, :2 AS CODE, :3 AS PRICE FROM DUAL) s ON (t.ID = s.ID) " +
+				"WHEN MATCHED THEN UPDATE SET t.CODE = s.CODE, t.PRICE = s.PRICE " +
+				"WHEN NOT MATCHED THEN INSERT (ID, CODE, PRICE) VALUES (s.ID, s.CODE, s.PRICE)",
+		},
+		{
+			name:    "Composite Key",
+			keys:    []string{"ID", "CODE"},
+			columns: []string{"ID", "CODE", "PRICE"},
+			expected: "MERGE INTO PRODUCTS t USING (SELECT :1 AS ID, :2 AS CODE, :3 AS PRICE FROM DUAL) s ON (t.ID = s.ID AND t.CODE = s.CODE) " +
+				"WHEN MATCHED THEN UPDATE SET t.PRICE = s.PRICE " +
+				"WHEN NOT MATCHED THEN INSERT (ID, CODE, PRICE) VALUES (s.ID, s.CODE, s.PRICE)",
+		},
+		{
+			name:    "Keys Only",
+			keys:    []string{"ID"},
+			columns: []string{"ID"},
+			expected: "MERGE INTO PRODUCTS t USING (SELECT :1 AS ID FROM DUAL) s ON (t.ID = s.ID) " +
+				"WHEN NOT MATCHED THEN INSERT (ID) VALUES (s.ID)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBulkMergeBuilder("PRODUCTS", tt.keys, tt.columns...)
+			if !builder.IsMerge() {
+				t.Error("IsMerge() = false")
+			}
+			if got := builder.GetMergeSQL(); got != tt.expected {
+				t.Errorf("GetMergeSQL() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestGetArgs(t *testing.T) {
 	builder := NewBulkInsertBuilder("TEST_TABLE", "ID", "NAME")
 
@@ -3,9 +3,13 @@ package rp_dynamic
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
+	"sql-learn2/bulkinsert"
+	"sql-learn2/dynamic"
+	"sql-learn2/logging"
+	"sql-learn2/tracing"
+
 	"github.com/jmoiron/sqlx"
 )
 
@@ -24,6 +28,9 @@ type Repository interface {
 // Repo implements the Repository interface.
 type Repo struct {
 	db *sqlx.DB
+
+	// TruncateOptions adds REUSE STORAGE or the DELETE fallback to Truncate
+	TruncateOptions dynamic.TruncateOptions
 }
 
 // NewRepo creates a new Repo instance.
@@ -33,22 +40,53 @@ func NewRepo(db *sqlx.DB) *Repo {
 
 // Truncate executes a TRUNCATE TABLE command.
 func (r *Repo) Truncate(ctx context.Context, tableName string) error {
-	query := fmt.Sprintf("TRUNCATE TABLE %s", tableName)
-	_, err := r.db.ExecContext(ctx, query)
+	_, err := dynamic.TruncateTable(ctx, r.db.DB, tableName, r.TruncateOptions)
 	return err
 }
 
-// BulkInsert executes the bulk insert using the provided builder.
-func (r *Repo) BulkInsert(ctx context.Context, builder *BulkInsertBuilder) error {
+// BulkInsert executes the bulk insert using the provided builder; a
+// NewBulkMergeBuilder builder is sent as an array-bound MERGE instead.
+// With bulkinsert.StrategyInsertAll in ctx the rows go out as INSERT ALL
+// statements in one transaction instead of a single array-bound INSERT.
+func (r *Repo) BulkInsert(ctx context.Context, builder *BulkInsertBuilder) (err error) {
+	ctx, span := tracing.Start(ctx, tracing.SpanBatchInsert,
+		tracing.String(tracing.AttrTable, builder.tableName), tracing.Int(tracing.AttrRows, builder.GetNumRows()))
+	defer func() { span.End(err) }()
+
+	insertAll := bulkinsert.StrategyFromContext(ctx) == bulkinsert.StrategyInsertAll
 	query := builder.GetSQL()
+	if builder.IsMerge() {
+		if insertAll {
+			return fmt.Errorf("merge into %s needs array binding, not %s", builder.tableName, bulkinsert.StrategyInsertAll)
+		}
+		query = builder.GetMergeSQL()
+	} else if insertAll {
+		return r.insertAll(ctx, builder)
+	}
 	args := builder.GetArgs()
-	_, err := r.db.ExecContext(ctx, query, args...)
+	_, err = r.db.ExecContext(ctx, query, args...)
 	return err
 }
 
+func (r *Repo) insertAll(ctx context.Context, builder *BulkInsertBuilder) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := bulkinsert.ExecInsertAll(ctx, tx, builder.tableName, builder.columns, builder.GetArgs()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // RefreshMaterializedView refreshes the specified materialized view.
-func (r *Repo) RefreshMaterializedView(ctx context.Context, name string) (time.Duration, error) {
-	log.Printf("Insert committed. Refreshing MV %s (COMPLETE, ATOMIC) ...", name)
+func (r *Repo) RefreshMaterializedView(ctx context.Context, name string) (d time.Duration, err error) {
+	ctx, span := tracing.Start(ctx, tracing.SpanMVRefresh, tracing.String("mview", name))
+	defer func() { span.End(err) }()
+
+	logger := logging.FromContext(ctx).With("mview", name)
+	logger.Info("Insert committed. Refreshing MV (COMPLETE, ATOMIC) ...")
 	refreshStart := time.Now()
 
 	refreshSQL := `
@@ -60,11 +98,12 @@ BEGIN
   );
 END;`
 
-	_, err := r.db.ExecContext(ctx, refreshSQL, name)
+	_, err = r.db.ExecContext(ctx, refreshSQL, name)
 	if err != nil {
 		return 0, fmt.Errorf("refresh materialized view %s failed: %w", name, err)
 	}
 
-	log.Println("Refresh complete.")
-	return time.Since(refreshStart), nil
+	refreshDuration := time.Since(refreshStart)
+	logger.Info("Refresh complete.", logging.FieldDuration, refreshDuration)
+	return refreshDuration, nil
 }
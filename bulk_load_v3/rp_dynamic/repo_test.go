@@ -0,0 +1,82 @@
+package rp_dynamic
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"sql-learn2/bulkinsert"
+	"sql-learn2/internal/sqlfake"
+)
+
+func TestRepo_BulkInsertStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy bulkinsert.Strategy
+		want     []string
+	}{
+		{"array bind", bulkinsert.StrategyArrayBind, []string{"INSERT INTO T (A, B) VALUES (:1, :2)"}},
+		{"insert all", bulkinsert.StrategyInsertAll, []string{
+			"INSERT ALL\n  INTO T (A, B) VALUES (:1, :2)\n  INTO T (A, B) VALUES (:3, :4)\nSELECT 1 FROM DUAL",
+			"COMMIT",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			b := NewBulkInsertBuilder("T", "A", "B")
+			_ = b.AddRow(1, "x")
+			_ = b.AddRow(2, "y")
+			ctx := bulkinsert.WithStrategy(context.Background(), tt.strategy)
+			if err := NewRepo(sqlx.NewDb(f.DB, "oracle")).BulkInsert(ctx, b); err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Queries(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queries = %q, want %q", got, tt.want)
+			}
+			if tt.strategy == bulkinsert.StrategyInsertAll {
+				if args := f.Calls()[0].Args; !reflect.DeepEqual(args, []any{int64(1), "x", int64(2), "y"}) {
+					t.Errorf("args = %v", args)
+				}
+			}
+		})
+	}
+}
+
+func TestRepo_BulkMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy bulkinsert.Strategy
+		want     []string
+		wantErr  string
+	}{
+		{"array bind", bulkinsert.StrategyArrayBind, []string{
+			"MERGE INTO T t USING (SELECT :1 AS A, :2 AS B FROM DUAL) s ON (t.A = s.A) " +
+				"WHEN MATCHED THEN UPDATE SET t.B = s.B WHEN NOT MATCHED THEN INSERT (A, B) VALUES (s.A, s.B)",
+		}, ""},
+		{"insert all", bulkinsert.StrategyInsertAll, nil, "merge into T needs array binding, not insert-all"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			b := NewBulkMergeBuilder("T", []string{"A"}, "A", "B")
+			_ = b.AddRow(1, "x")
+			ctx := bulkinsert.WithStrategy(context.Background(), tt.strategy)
+			err := NewRepo(sqlx.NewDb(f.DB, "oracle")).BulkInsert(ctx, b)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Queries(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queries = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,7 +3,8 @@ package bulkinsert
 import (
 	"context"
 	"fmt"
-	"log"
+
+	"sql-learn2/logging"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -85,6 +86,6 @@ func ExampleBasicUsage(ctx context.Context, db *sqlx.DB) error {
 		return fmt.Errorf("bulk insert failed: %w", err)
 	}
 
-	log.Printf("Successfully inserted %d rows in %v", builder.GetNumRows(), duration)
+	logging.FromContext(ctx).Info("Successfully inserted rows", logging.FieldRows, builder.GetNumRows(), logging.FieldDuration, duration)
 	return nil
 }
@@ -2,36 +2,85 @@ package bulkinsert
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
-	"log"
+	"reflect"
 	"time"
 
+	"sql-learn2/logging"
+	"sql-learn2/metrics"
+	"sql-learn2/retry"
+	"sql-learn2/tracing"
+
 	"github.com/jmoiron/sqlx"
 )
 
-// executeInsertBatch executes the bulk insert within a transaction.
+// executeInsertBatch executes the bulk insert within a transaction, traced as one span
+// and recorded in the ctx metrics (see metrics.WithMetrics). Retryable failures
+// rerun the transaction as the ctx retry policy allows (see retry.WithPolicy).
+// With StrategyInsertAll in ctx the batch goes out as INSERT ALL statements instead.
 // Returns the insert duration (excluding commit time) and any error encountered.
-func executeInsertBatch(ctx context.Context, db *sqlx.DB, insertSQL string, columnData []interface{}) (time.Duration, error) {
+func executeInsertBatch(ctx context.Context, db *sqlx.DB, tableName string, columnNames []string, insertSQL string, columnData []interface{}) (d time.Duration, err error) {
+	rows := batchRows(columnData)
+	ctx, span := tracing.Start(ctx, tracing.SpanBatchInsert,
+		tracing.String(tracing.AttrTable, tableName), tracing.Int(tracing.AttrRows, rows))
+	m := metrics.FromContext(ctx)
 	insStart := time.Now()
+	defer func() {
+		span.End(err)
+		if err != nil {
+			m.IncErrors(tableName, metrics.StageInsert)
+			return
+		}
+		m.AddRows(tableName, rows)
+		m.IncBatches(tableName)
+		m.ObserveBatch(tableName, time.Since(insStart))
+	}()
+
+	p := retry.FromContext(ctx)
+	if p.OnRetry == nil {
+		p.OnRetry = func(attempt int, err error, wait time.Duration) {
+			logging.FromContext(ctx).Warn("Insert batch failed, retrying", logging.FieldTable, tableName,
+				"attempt", attempt, "wait", wait, logging.FieldError, err)
+		}
+	}
+	exec := func(ctx context.Context, tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, insertSQL)
+		if err != nil {
+			return fmt.Errorf("prepare insert statement failed: %w", err)
+		}
+		defer stmt.Close()
+		if _, err := stmt.ExecContext(ctx, columnData...); err != nil {
+			return fmt.Errorf("insert batch failed: %w", err)
+		}
+		return nil
+	}
+	if StrategyFromContext(ctx) == StrategyInsertAll {
+		exec = func(ctx context.Context, tx *sql.Tx) error {
+			return ExecInsertAll(ctx, tx, tableName, columnNames, columnData)
+		}
+	}
+	err = retry.Do(ctx, p, func(ctx context.Context) error {
+		d, err = insertTx(ctx, db, exec)
+		return err
+	})
+	return d, err
+}
 
+// insertTx runs one attempt of the batch insert in its own transaction
+func insertTx(ctx context.Context, db *sqlx.DB, exec func(context.Context, *sql.Tx) error) (time.Duration, error) {
+	insStart := time.Now()
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, fmt.Errorf("begin transaction failed: %w", err)
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
-	if err != nil {
-		return 0, fmt.Errorf("prepare insert statement failed: %w", err)
+	if err := exec(ctx, tx); err != nil {
+		return 0, err
 	}
-	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, columnData...)
-	if err != nil {
-		return 0, fmt.Errorf("insert batch failed: %w", err)
-	}
-
-	log.Println("Committing transaction...")
+	logging.FromContext(ctx).Debug("Committing transaction...")
 	commitStart := time.Now()
 	if err := tx.Commit(); err != nil {
 		return 0, fmt.Errorf("commit failed: %w", err)
@@ -41,3 +90,14 @@ func executeInsertBatch(ctx context.Context, db *sqlx.DB, insertSQL string, colu
 	insDuration := time.Since(insStart) - commitDuration
 	return insDuration, nil
 }
+
+// batchRows is the length of the first column slice, i.e. the rows in the batch
+func batchRows(columnData []interface{}) int {
+	if len(columnData) == 0 {
+		return 0
+	}
+	if v := reflect.ValueOf(columnData[0]); v.Kind() == reflect.Slice {
+		return v.Len()
+	}
+	return 1
+}
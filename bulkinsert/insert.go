@@ -3,9 +3,10 @@ package bulkinsert
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
+	"sql-learn2/logging"
+
 	"github.com/jmoiron/sqlx"
 )
 
@@ -34,14 +35,15 @@ func InsertBatched(ctx context.Context, db *sqlx.DB, tableName string, columnNam
 	}
 
 	insertSQL := buildInsertSQL(tableName, columnNames)
-	log.Println("Starting bulk insert...")
+	logger := logging.FromContext(ctx).With(logging.FieldTable, tableName)
+	logger.Debug("Starting bulk insert...")
 
-	insDuration, err := executeInsertBatch(ctx, db, insertSQL, columnData)
+	insDuration, err := executeInsertBatch(ctx, db, tableName, columnNames, insertSQL, columnData)
 	if err != nil {
 		return 0, err
 	}
 
-	log.Println("Bulk insert completed successfully")
+	logger.Info("Bulk insert completed", logging.FieldDuration, insDuration)
 	return insDuration, nil
 }
 
@@ -71,21 +73,22 @@ func InsertStructs(ctx context.Context, db *sqlx.DB, tableName string, columnNam
 	}
 
 	insertSQL := buildInsertSQL(tableName, columnNames)
-	log.Printf("Generated SQL: %s", insertSQL)
-	log.Printf("Starting bulk insert of %d rows...", len(rows))
+	logger := logging.FromContext(ctx).With(logging.FieldTable, tableName)
+	logger.Debug("Generated SQL", "sql", insertSQL)
+	logger.Debug("Starting bulk insert...", logging.FieldRows, len(rows))
 
 	// Convert row-oriented data to column-oriented typed arrays
-	columnData, err := transposeRowsToColumns(rows, columnNames)
+	columnData, err := transposeRowsToColumns(logger, rows, columnNames)
 	if err != nil {
 		return 0, err
 	}
 
 	// Execute the batch insert
-	insDuration, err := executeInsertBatch(ctx, db, insertSQL, columnData)
+	insDuration, err := executeInsertBatch(ctx, db, tableName, columnNames, insertSQL, columnData)
 	if err != nil {
 		return 0, err
 	}
 
-	log.Println("Bulk insert completed successfully")
+	logger.Info("Bulk insert completed", logging.FieldRows, len(rows), logging.FieldDuration, insDuration)
 	return insDuration, nil
 }
@@ -18,3 +18,34 @@ func buildInsertSQL(tableName string, columnNames []string) string {
 		strings.Join(columnNames, ", "),
 		strings.Join(placeholders, ", "))
 }
+
+// insertAllColumns is the most columns Oracle takes in one INSERT ALL,
+// summed over its INTO clauses; past it the statement fails (ORA-24335)
+const insertAllColumns = 999
+
+// insertAllChunk is how many rows of numCols columns fit in one INSERT ALL
+func insertAllChunk(numCols int) int {
+	return max(1, insertAllColumns/max(numCols, 1))
+}
+
+// buildInsertAllSQL constructs a multi-row INSERT ALL for rows rows, with
+// placeholders numbered row by row (:1..:n for the first row, and so on).
+func buildInsertAllSQL(tableName string, columnNames []string, rows int) string {
+	var b strings.Builder
+	b.WriteString("INSERT ALL")
+	cols := strings.Join(columnNames, ", ")
+	n := 0
+	for r := 0; r < rows; r++ {
+		fmt.Fprintf(&b, "\n  INTO %s (%s) VALUES (", tableName, cols)
+		for c := range columnNames {
+			n++
+			if c > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, ":%d", n)
+		}
+		b.WriteString(")")
+	}
+	b.WriteString("\nSELECT 1 FROM DUAL")
+	return b.String()
+}
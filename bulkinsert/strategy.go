@@ -0,0 +1,85 @@
+package bulkinsert
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Strategy selects how a batch is sent to the database
+type Strategy string
+
+const (
+	// StrategyArrayBind binds one slice per column to a single INSERT (default)
+	StrategyArrayBind Strategy = "array"
+	// StrategyInsertAll sends INSERT ALL ... SELECT 1 FROM DUAL statements
+	// with one bind per value, for drivers or proxies without array binding
+	StrategyInsertAll Strategy = "insert-all"
+)
+
+// ParseStrategy parses "array" or "insert-all"; empty means array
+func ParseStrategy(s string) (Strategy, error) {
+	switch Strategy(strings.ToLower(strings.TrimSpace(s))) {
+	case "", StrategyArrayBind:
+		return StrategyArrayBind, nil
+	case StrategyInsertAll:
+		return StrategyInsertAll, nil
+	}
+	return "", fmt.Errorf("unknown insert strategy %q (use array or insert-all)", s)
+}
+
+type strategyKey struct{}
+
+// WithStrategy returns ctx carrying the insert strategy used by
+// InsertBatched, InsertStructs and rp_dynamic.Repo
+func WithStrategy(ctx context.Context, s Strategy) context.Context {
+	return context.WithValue(ctx, strategyKey{}, s)
+}
+
+// StrategyFromContext returns the strategy in ctx, StrategyArrayBind if none
+func StrategyFromContext(ctx context.Context) Strategy {
+	if s, ok := ctx.Value(strategyKey{}).(Strategy); ok && s != "" {
+		return s
+	}
+	return StrategyArrayBind
+}
+
+// Execer is satisfied by *sql.DB, *sql.Tx, *sqlx.DB and *sqlx.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// ExecInsertAll inserts column-oriented data (one slice per column, as for
+// array binding) with INSERT ALL statements of as many rows as fit in
+// Oracle's limit of insertAllColumns columns per statement.
+// Run it in a transaction to keep the batch atomic.
+func ExecInsertAll(ctx context.Context, db Execer, tableName string, columnNames []string, columnData []interface{}) error {
+	if len(columnData) != len(columnNames) {
+		return fmt.Errorf("mismatched columns: got %d data slices for %d columns", len(columnData), len(columnNames))
+	}
+	cols := make([]reflect.Value, len(columnData))
+	for i, c := range columnData {
+		cols[i] = reflect.ValueOf(c)
+		if cols[i].Kind() != reflect.Slice {
+			return fmt.Errorf("column %s: expected a slice, got %T", columnNames[i], c)
+		}
+	}
+	rows := batchRows(columnData)
+	chunk := insertAllChunk(len(columnNames))
+	args := make([]any, 0, chunk*len(columnNames))
+	for lo := 0; lo < rows; lo += chunk {
+		hi := min(lo+chunk, rows)
+		args = args[:0]
+		for r := lo; r < hi; r++ {
+			for _, c := range cols {
+				args = append(args, c.Index(r).Interface())
+			}
+		}
+		if _, err := db.ExecContext(ctx, buildInsertAllSQL(tableName, columnNames, hi-lo), args...); err != nil {
+			return fmt.Errorf("insert all rows %d-%d: %w", lo+1, hi, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package bulkinsert
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/logging"
+)
+
+func TestBuildInsertAllSQL(t *testing.T) {
+	got := buildInsertAllSQL("T", []string{"A", "B"}, 2)
+	want := "INSERT ALL\n  INTO T (A, B) VALUES (:1, :2)\n  INTO T (A, B) VALUES (:3, :4)\nSELECT 1 FROM DUAL"
+	if got != want {
+		t.Errorf("buildInsertAllSQL =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestInsertAllChunk(t *testing.T) {
+	tests := []struct {
+		cols, want int
+	}{
+		{1, 999},
+		{2, 499},
+		{11, 90},
+		{999, 1},
+		{1500, 1},
+	}
+	for _, tt := range tests {
+		if got := insertAllChunk(tt.cols); got != tt.want {
+			t.Errorf("insertAllChunk(%d) = %d, want %d", tt.cols, got, tt.want)
+		}
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Strategy
+		wantErr bool
+	}{
+		{"", StrategyArrayBind, false},
+		{"array", StrategyArrayBind, false},
+		{"INSERT-ALL", StrategyInsertAll, false},
+		{"rows", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStrategy(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseStrategy(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestInsertBatched_Strategy(t *testing.T) {
+	quiet := logging.WithLogger(context.Background(), logging.Discard())
+	ids := make([]int64, insertAllChunk(2)+2)
+	for i := range ids {
+		ids[i] = int64(i)
+	}
+	names := make([]string, len(ids))
+	tests := []struct {
+		name     string
+		strategy Strategy
+		want     []int // bind count per statement
+	}{
+		{"array bind", StrategyArrayBind, []int{2}},
+		{"insert all chunked", StrategyInsertAll, []int{2 * insertAllChunk(2), 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			ctx := WithStrategy(quiet, tt.strategy)
+			if _, err := InsertBatched(ctx, sqlx.NewDb(f.DB, "oracle"), "T", []string{"ID", "NAME"}, ids, names); err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, c := range f.Calls() {
+				if strings.HasPrefix(c.Query, "INSERT") {
+					got = append(got, len(c.Args))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("binds per statement = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
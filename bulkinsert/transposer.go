@@ -1,11 +1,16 @@
 package bulkinsert
 
-import "log"
+import (
+	"fmt"
+	"log/slog"
+
+	"sql-learn2/logging"
+)
 
 // transposeRowsToColumns converts row-oriented data to column-oriented typed arrays.
 // This is required for go-ora array binding which expects concrete typed slices.
 // Returns a slice of typed arrays (one per column) ready for batch insert.
-func transposeRowsToColumns(rows [][]interface{}, columnNames []string) ([]interface{}, error) {
+func transposeRowsToColumns(logger *slog.Logger, rows [][]interface{}, columnNames []string) ([]interface{}, error) {
 	numCols := len(columnNames)
 	columnData := make([]interface{}, numCols)
 
@@ -14,14 +19,14 @@ func transposeRowsToColumns(rows [][]interface{}, columnNames []string) ([]inter
 		sample := findSampleValue(rows, colIdx)
 
 		// Build typed array for this column
-		typedArray, err := buildTypedColumnArray(rows, colIdx, columnNames[colIdx], sample)
+		typedArray, err := buildTypedColumnArray(logger, rows, colIdx, columnNames[colIdx], sample)
 		if err != nil {
 			return nil, err
 		}
 		columnData[colIdx] = typedArray
 
 		// Log the binding type for troubleshooting
-		log.Printf("Binding column %s as %T (rows=%d)", columnNames[colIdx], typedArray, len(rows))
+		logger.Debug("Binding column", "column", columnNames[colIdx], "type", fmt.Sprintf("%T", typedArray), logging.FieldRows, len(rows))
 	}
 
 	return columnData, nil
@@ -2,10 +2,17 @@ package bulkinsert
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
+
+	go_ora "github.com/sijms/go-ora/v2"
 )
 
+// maxInlineString is the longest string bound as a plain VARCHAR2 value;
+// longer ones only fit a CLOB column and are sent through the driver's LOB
+// support (temporary LOBs written in chunks) instead
+const maxInlineString = 32767
+
 // buildInt64Array builds a typed []int64 slice from column data.
 // Supports int, int32, int64, uint, uint32, uint64 types.
 func buildInt64Array(rows [][]interface{}, colIdx int, columnName string) ([]int64, error) {
@@ -64,7 +71,7 @@ func buildBoolArray(rows [][]interface{}, colIdx int, columnName string) ([]bool
 		}
 		arr[i] = vb
 	}
-	return arr,
+	return arr, nil
 }
 
 // buildTimeArray builds a typed []time.Time slice from column data.
@@ -97,21 +104,49 @@ func buildStringArray(rows [][]interface{}, colIdx int, columnName string) ([]st
 	return arr, nil
 }
 
+// buildClobArray builds a typed []go_ora.Clob slice from column data.
+// Supports go_ora.Clob and string; nil is NULL.
+func buildClobArray(rows [][]interface{}, colIdx int, columnName string) ([]go_ora.Clob, error) {
+	arr := make([]go_ora.Clob, len(rows))
+	for i, row := range rows {
+		switch vv := row[colIdx].(type) {
+		case go_ora.Clob:
+			arr[i] = vv
+		case string:
+			arr[i] = go_ora.Clob{String: vv, Valid: true}
+		case nil:
+		default:
+			return nil, fmt.Errorf("column %s (index %d) type mismatch: expected string or go_ora.Clob, got %T at row %d", columnName, colIdx, vv, i)
+		}
+	}
+	return arr, nil
+}
+
+// hasLongString reports whether a string in the column exceeds maxInlineString
+func hasLongString(rows [][]interface{}, colIdx int) bool {
+	for _, row := range rows {
+		if s, ok := row[colIdx].(string); ok && len(s) > maxInlineString {
+			return true
+		}
+	}
+	return false
+}
+
 // buildGenericArray builds a generic []interface{} slice from column data.
 // This is a fallback for unsupported types and may not work with all drivers.
-func buildGenericArray(rows [][]interface{}, colIdx int, columnName string, sampleType interface{}) []interface{} {
+func buildGenericArray(logger *slog.Logger, rows [][]interface{}, colIdx int, columnName string, sampleType interface{}) []interface{} {
 	numRows := len(rows)
 	arr := make([]interface{}, numRows)
 	for i, row := range rows {
 		arr[i] = row[colIdx]
 	}
-	log.Printf("Warning: binding column %s with generic []interface{} (type %T)", columnName, sampleType)
+	logger.Warn("Binding column with generic []interface{}", "column", columnName, "type", fmt.Sprintf("%T", sampleType))
 	return arr
 }
 
 // buildTypedColumnArray builds a typed array for a single column based on sample value type.
 // Returns the typed array as interface{} and any error encountered.
-func buildTypedColumnArray(rows [][]interface{}, colIdx int, columnName string, sample interface{}) (interface{}, error) {
+func buildTypedColumnArray(logger *slog.Logger, rows [][]interface{}, colIdx int, columnName string, sample interface{}) (interface{}, error) {
 	switch sample.(type) {
 	case int64, int, int32, uint, uint32, uint64:
 		return buildInt64Array(rows, colIdx, columnName)
@@ -122,9 +157,14 @@ func buildTypedColumnArray(rows [][]interface{}, colIdx int, columnName string,
 	case time.Time:
 		return buildTimeArray(rows, colIdx, columnName)
 	case string:
+		if hasLongString(rows, colIdx) {
+			return buildClobArray(rows, colIdx, columnName)
+		}
 		return buildStringArray(rows, colIdx, columnName)
+	case go_ora.Clob:
+		return buildClobArray(rows, colIdx, columnName)
 	default:
 		// Fallback for unsupported types
-		return buildGenericArray(rows, colIdx, columnName, sample), nil
+		return buildGenericArray(logger, rows, colIdx, columnName, sample), nil
 	}
 }
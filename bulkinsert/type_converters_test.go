@@ -0,0 +1,37 @@
+package bulkinsert
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	go_ora "github.com/sijms/go-ora/v2"
+
+	"sql-learn2/logging"
+)
+
+func TestBuildTypedColumnArray_Clob(t *testing.T) {
+	long := strings.Repeat("x", maxInlineString+1)
+	tests := []struct {
+		name    string
+		col     []interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{"short strings stay strings", []interface{}{"a", "b"}, []string{"a", "b"}, false},
+		{"long string switches to lob", []interface{}{"a", long}, []go_ora.Clob{{String: "a", Valid: true}, {String: long, Valid: true}}, false},
+		{"explicit clob with null", []interface{}{go_ora.Clob{String: "a", Valid: true}, nil, "b"},
+			[]go_ora.Clob{{String: "a", Valid: true}, {}, {String: "b", Valid: true}}, false},
+		{"clob mixed with int", []interface{}{go_ora.Clob{String: "a", Valid: true}, 1}, nil, true},
+	}
+	for _, tt := range tests {
+		rows := make([][]interface{}, len(tt.col))
+		for i, v := range tt.col {
+			rows[i] = []interface{}{v}
+		}
+		got, err := buildTypedColumnArray(logging.Discard(), rows, 0, "C", findSampleValue(rows, 0))
+		if (err != nil) != tt.wantErr || (!tt.wantErr && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("%s: got %#v, %v; want %#v", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package bulkload
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -15,6 +16,55 @@ type TimingReport struct {
 	TotalDuration   time.Duration
 }
 
+// ClearMode says how the target is emptied before the insert
+type ClearMode int
+
+const (
+	Truncate ClearMode = iota // TRUNCATE TABLE (default)
+	Delete                    // DELETE FROM and commit; works when other tables reference the target
+	Append                    // keep existing rows
+)
+
+// Config describes one load: which table, which columns, how rows are made
+// and which materialized views to refresh afterwards.
+type Config struct {
+	Table     string
+	Columns   []string
+	Generate  func(rowNum int) []any // values in Columns order; rowNum starts at 1
+	Rows      int
+	BatchSize int // rows per insert batch; <= 0 inserts in a single batch
+	Clear     ClearMode
+	MViews    []string // refreshed together, COMPLETE and ATOMIC; empty skips the refresh
+	DependsOn []string // parent tables that LoadAll must load first
+}
+
+// BulkDataColumns are the columns of BULK_DATA (setup_materialized_view.sql)
+var BulkDataColumns = []string{"ID", "DATA_VALUE", "DESCRIPTION", "STATUS", "CREATED_AT"}
+
+// BulkDataConfig is the BULK_DATA / MV_BULK_DATA load used by the MV refresh simulation
+func BulkDataConfig(bulkCount, batchSize int, createdAt time.Time) Config {
+	return Config{
+		Table:     "BULK_DATA",
+		Columns:   BulkDataColumns,
+		Generate:  BulkDataRow(createdAt),
+		Rows:      bulkCount,
+		BatchSize: batchSize,
+		MViews:    []string{"MV_BULK_DATA"},
+	}
+}
+
+func (c Config) validate() error {
+	switch {
+	case c.Table == "":
+		return errors.New("bulkload: Table is required")
+	case len(c.Columns) == 0:
+		return errors.New("bulkload: Columns are required")
+	case c.Generate == nil:
+		return errors.New("bulkload: Generate is required")
+	}
+	return nil
+}
+
 // ExecuteBulkLoad performs the complete bulk load operation in three steps:
 // 1. TRUNCATE base table BULK_DATA
 // 2. INSERT bulk data in batches with the given CREATED_AT timestamp
@@ -28,15 +78,26 @@ type TimingReport struct {
 //   - createdAt: timestamp to use for all inserted rows
 //
 // Returns TimingReport with durations for each operation and error if any step fails.
+// It is Load with BulkDataConfig.
 func ExecuteBulkLoad(ctx context.Context, db *sqlx.DB, bulkCount int, batchSize int, createdAt time.Time) (*TimingReport, error) {
-	// Step 1: Truncate BULK_DATA table
-	if err := truncateTable(ctx, db); err != nil {
+	return Load(ctx, db, BulkDataConfig(bulkCount, batchSize, createdAt))
+}
+
+// Load clears cfg.Table according to cfg.Clear, inserts cfg.Rows generated
+// rows in batches and refreshes cfg.MViews.
+func Load(ctx context.Context, db *sqlx.DB, cfg Config) (*TimingReport, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	// Step 1: Empty the target
+	if err := clearTable(ctx, db, cfg.Table, cfg.Clear); err != nil {
 		return nil, err
 	}
 
 	// Step 2: Insert bulk data and measure total operation time
 	operationStart := time.Now()
-	insertDuration, err := insertBulkData(ctx, db, bulkCount, batchSize, createdAt)
+	insertDuration, err := insertBulkData(ctx, db, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -44,8 +105,8 @@ func ExecuteBulkLoad(ctx context.Context, db *sqlx.DB, bulkCount int, batchSize
 	// Calculate commit duration (time between insert end and now)
 	commitDuration := time.Since(operationStart) - insertDuration
 
-	// Step 3: Refresh materialized view
-	refreshDuration, err := refreshMaterializedView(ctx, db)
+	// Step 3: Refresh materialized views
+	refreshDuration, err := refreshMaterializedViews(ctx, db, cfg.MViews)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,89 @@
+package bulkload
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/logging"
+)
+
+var quiet = logging.WithLogger(context.Background(), logging.Discard())
+
+var testTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestLoad(t *testing.T) {
+	gen := func(n int) []any { return []any{n, "x"} }
+	tests := []struct {
+		name    string
+		cfg     Config
+		clear   string // first statement
+		inserts int
+		refresh string // refresh list bind, "" = no refresh
+	}{
+		{"truncate and refresh", Config{Table: "T", Columns: []string{"ID", "V"}, Generate: gen, Rows: 5, BatchSize: 2, MViews: []string{"MV1", "MV2"}},
+			"TRUNCATE TABLE T", 3, "MV1,MV2"},
+		{"delete, single batch", Config{Table: "T", Columns: []string{"ID", "V"}, Generate: gen, Rows: 3, Clear: Delete},
+			"DELETE FROM T", 1, ""},
+		{"append", Config{Table: "T", Columns: []string{"ID", "V"}, Generate: gen, Rows: 1, Clear: Append},
+			"INSERT INTO T", 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			if _, err := Load(quiet, sqlx.NewDb(f.DB, "oracle"), tt.cfg); err != nil {
+				t.Fatal(err)
+			}
+			calls := f.Calls()
+			if !strings.HasPrefix(calls[0].Query, tt.clear) {
+				t.Errorf("first statement = %q, want %q", calls[0].Query, tt.clear)
+			}
+			inserts, refresh := 0, ""
+			for _, c := range calls {
+				switch {
+				case strings.HasPrefix(c.Query, "INSERT INTO T"):
+					inserts++
+				case strings.Contains(c.Query, "DBMS_MVIEW.REFRESH"):
+					refresh = c.Args[0].(string)
+				}
+			}
+			if inserts != tt.inserts || refresh != tt.refresh {
+				t.Errorf("inserts = %d, refresh = %q; want %d, %q", inserts, refresh, tt.inserts, tt.refresh)
+			}
+		})
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	f := sqlfake.New(t)
+	db := sqlx.NewDb(f.DB, "oracle")
+	tests := []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{Columns: []string{"A"}, Generate: func(int) []any { return nil }}, "Table is required"},
+		{Config{Table: "T", Generate: func(int) []any { return nil }}, "Columns are required"},
+		{Config{Table: "T", Columns: []string{"A"}}, "Generate is required"},
+		{Config{Table: "T", Columns: []string{"A", "B"}, Generate: func(int) []any { return []any{1} }, Rows: 1},
+			"T row 1: generator returned 1 values for 2 columns"},
+	}
+	for _, tt := range tests {
+		if _, err := Load(quiet, db, tt.cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("err = %v, want %q", err, tt.want)
+		}
+	}
+}
+
+func TestBulkDataConfig(t *testing.T) {
+	cfg := BulkDataConfig(10, 5, testTime)
+	if cfg.Table != "BULK_DATA" || len(cfg.MViews) != 1 || cfg.MViews[0] != "MV_BULK_DATA" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if row := cfg.Generate(10); len(row) != len(cfg.Columns) || row[3] != "INACTIVE" || row[1] != "VAL_10" {
+		t.Errorf("row 10 = %v", row)
+	}
+}
@@ -3,57 +3,52 @@ package bulkload
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"sql-learn2/bulkinsert"
+	"sql-learn2/logging"
 
 	"github.com/jmoiron/sqlx"
 )
 
-// generateBatchData creates a batch of test data rows using the Row/Column style
-// demonstrated in bulkinsert.ExampleBasicUsage. It returns column names and row data
-// in the legacy return format expected by callers.
-func generateBatchData(batchStart, batchCount int, createdAt time.Time) ([]string, [][]interface{}) {
-	// Step 1: Define column names as variables (for pointer-like references)
-	colID := "ID"
-	colDataValue := "DATA_VALUE"
-	colDescription := "DESCRIPTION"
-	colStatus := "STATUS"
-	colCreatedAt := "CREATED_AT"
-
-	// Step 2: Build rows using bulkinsert.Row/Column for readability and safety
-	rowsDef := make(bulkinsert.Rows, 0, batchCount)
-	for i := 0; i < batchCount; i++ {
-		rowNum := batchStart + i
+// BulkDataRow generates BULK_DATA rows (BulkDataColumns order) with a fixed CREATED_AT
+func BulkDataRow(createdAt time.Time) func(rowNum int) []any {
+	return func(rowNum int) []any {
 		status := "ACTIVE"
 		if rowNum%10 == 0 {
 			status = "INACTIVE"
 		}
-
-		rowsDef = append(rowsDef, bulkinsert.Row{
-			bulkinsert.Column{Name: colID, Value: rowNum},
-			bulkinsert.Column{Name: colDataValue, Value: fmt.Sprintf("VAL_%d", rowNum)},
-			bulkinsert.Column{Name: colDescription, Value: fmt.Sprintf("Generated row #%d", rowNum)},
-			bulkinsert.Column{Name: colStatus, Value: status},
-			bulkinsert.Column{Name: colCreatedAt, Value: createdAt},
-		})
+		return []any{rowNum, fmt.Sprintf("VAL_%d", rowNum), fmt.Sprintf("Generated row #%d", rowNum), status, createdAt}
 	}
+}
 
-	// Step 3: Convert to the required return types
-	return rowsDef.GetColumnsNames(), rowsDef.GetRows()
+// generateBatchData builds batchCount rows starting at row number batchStart
+// in the column/row format bulkinsert.InsertStructs takes
+func generateBatchData(cfg Config, batchStart, batchCount int) ([][]interface{}, error) {
+	rows := make([][]interface{}, 0, batchCount)
+	for i := 0; i < batchCount; i++ {
+		vals := cfg.Generate(batchStart + i)
+		if len(vals) != len(cfg.Columns) {
+			return nil, fmt.Errorf("%s row %d: generator returned %d values for %d columns",
+				cfg.Table, batchStart+i, len(vals), len(cfg.Columns))
+		}
+		rows = append(rows, vals)
+	}
+	return rows, nil
 }
 
-// insertBulkData inserts bulk data in batches.
-// batchSize controls rows per batch; if <= 0 it falls back to a single batch of bulkCount.
-func insertBulkData(ctx context.Context, db *sqlx.DB, bulkCount int, batchSize int, createdAt time.Time) (time.Duration, error) {
+// insertBulkData inserts cfg.Rows rows in batches.
+// cfg.BatchSize controls rows per batch; if <= 0 it falls back to a single batch.
+func insertBulkData(ctx context.Context, db *sqlx.DB, cfg Config) (time.Duration, error) {
+	bulkCount, batchSize := cfg.Rows, cfg.BatchSize
 	if bulkCount <= 0 {
 		return 0, nil
 	}
 	if batchSize <= 0 || batchSize > bulkCount {
 		batchSize = bulkCount
 	}
-	log.Printf("Inserting %d rows with CREATED_AT = %s in batches of %d", bulkCount, createdAt.Format("2006-01-02 15:04:05"), batchSize)
+	logger := logging.FromContext(ctx).With(logging.FieldTable, cfg.Table)
+	logger.Info("Inserting rows", logging.FieldRows, bulkCount, "batch_size", batchSize)
 
 	var totalInsert time.Duration
 	startID := 1
@@ -68,10 +63,14 @@ func insertBulkData(ctx context.Context, db *sqlx.DB, bulkCount int, batchSize i
 		batchNum++
 
 		// Pre-batch progress log so users see ongoing work before each insert starts
-		log.Printf("Batch %d/%d: starting insert of %d rows (remaining before: %d)", batchNum, totalBatches, n, remaining)
+		batchLogger := logger.With(logging.FieldBatch, fmt.Sprintf("%d/%d", batchNum, totalBatches))
+		batchLogger.Info("Starting batch insert", logging.FieldRows, n, "remaining", remaining)
 
-		columnNames, rows := generateBatchData(startID, n, createdAt)
-		insDuration, err := bulkinsert.InsertStructs(ctx, db, "BULK_DATA", columnNames, rows)
+		rows, err := generateBatchData(cfg, startID, n)
+		if err != nil {
+			return totalInsert, err
+		}
+		insDuration, err := bulkinsert.InsertStructs(ctx, db, cfg.Table, cfg.Columns, rows)
 		if err != nil {
 			return totalInsert, err
 		}
@@ -79,7 +78,7 @@ func insertBulkData(ctx context.Context, db *sqlx.DB, bulkCount int, batchSize i
 		startID += n
 		remaining -= n
 
-		log.Printf("Batch %d/%d: inserted %d rows (remaining: %d)", batchNum, totalBatches, n, remaining)
+		batchLogger.Info("Batch inserted", logging.FieldRows, n, "remaining", remaining, logging.FieldDuration, insDuration)
 	}
 
 	return totalInsert, nil
@@ -0,0 +1,180 @@
+package bulkload
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"sql-learn2/logging"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TableTiming is one table's share of a LoadAll run
+type TableTiming struct {
+	Table          string
+	Rows           int
+	ClearDuration  time.Duration
+	InsertDuration time.Duration
+}
+
+// MultiReport holds per-table timings and the shared MV refresh of a LoadAll run
+type MultiReport struct {
+	Tables          []TableTiming // in load order
+	RefreshDuration time.Duration
+	TotalDuration   time.Duration
+}
+
+// LoadAll loads related tables in one run the way the production refresh jobs do:
+// all tables are cleared children first, then loaded parents first (by
+// Config.DependsOn), then every MV of every table is refreshed in one atomic
+// refresh. A parent that another table in the set references is cleared with
+// DELETE instead of TRUNCATE, which Oracle refuses for referenced tables.
+func LoadAll(ctx context.Context, db *sqlx.DB, cfgs []Config) (*MultiReport, error) {
+	for _, c := range cfgs {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+	}
+	ordered, err := orderTables(cfgs)
+	if err != nil {
+		return nil, err
+	}
+	logger := logging.FromContext(ctx)
+	names := make([]string, len(ordered))
+	for i, c := range ordered {
+		names[i] = c.Table
+	}
+	logger.Info("Loading tables", "order", strings.Join(names, " -> "))
+
+	referenced := map[string]bool{}
+	for _, c := range ordered {
+		for _, p := range c.DependsOn {
+			referenced[strings.ToUpper(p)] = true
+		}
+	}
+
+	start := time.Now()
+	report := &MultiReport{Tables: make([]TableTiming, len(ordered))}
+	for i := len(ordered) - 1; i >= 0; i-- {
+		c := ordered[i]
+		mode := c.Clear
+		if mode == Truncate && referenced[strings.ToUpper(c.Table)] {
+			logger.Info("Table is referenced by another table in the set; deleting instead of truncating", logging.FieldTable, c.Table)
+			mode = Delete
+		}
+		t0 := time.Now()
+		if err := clearTable(ctx, db, c.Table, mode); err != nil {
+			return nil, err
+		}
+		report.Tables[i] = TableTiming{Table: c.Table, Rows: max(c.Rows, 0), ClearDuration: time.Since(t0)}
+	}
+
+	var mviews []string
+	seen := map[string]bool{}
+	for i, c := range ordered {
+		d, err := insertBulkData(ctx, db, c)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", c.Table, err)
+		}
+		report.Tables[i].InsertDuration = d
+		for _, mv := range c.MViews {
+			if !seen[strings.ToUpper(mv)] {
+				seen[strings.ToUpper(mv)] = true
+				mviews = append(mviews, mv)
+			}
+		}
+	}
+
+	if report.RefreshDuration, err = refreshMaterializedViews(ctx, db, mviews); err != nil {
+		return nil, err
+	}
+	report.TotalDuration = time.Since(start)
+	return report, nil
+}
+
+// orderTables sorts cfgs so every table comes after the tables it depends on,
+// keeping the given order otherwise. Dependencies outside the set are ignored.
+func orderTables(cfgs []Config) ([]Config, error) {
+	index := make(map[string]int, len(cfgs))
+	for i, c := range cfgs {
+		key := strings.ToUpper(c.Table)
+		if _, dup := index[key]; dup {
+			return nil, fmt.Errorf("bulkload: table %s listed twice", c.Table)
+		}
+		index[key] = i
+	}
+	pending := make([]int, len(cfgs)) // unloaded parents per table
+	children := make([][]int, len(cfgs))
+	for i, c := range cfgs {
+		for _, p := range c.DependsOn {
+			j, ok := index[strings.ToUpper(p)]
+			if !ok || j == i {
+				continue
+			}
+			pending[i]++
+			children[j] = append(children[j], i)
+		}
+	}
+	var ready, out []int
+	for i := range cfgs {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		i := ready[0]
+		ready = ready[1:]
+		out = append(out, i)
+		for _, ch := range children[i] {
+			if pending[ch]--; pending[ch] == 0 {
+				ready = append(ready, ch)
+			}
+		}
+	}
+	if len(out) != len(cfgs) {
+		var cyc []string
+		for i, n := range pending {
+			if n > 0 {
+				cyc = append(cyc, cfgs[i].Table)
+			}
+		}
+		return nil, fmt.Errorf("bulkload: dependency cycle between %s", strings.Join(cyc, ", "))
+	}
+	ordered := make([]Config, len(out))
+	for k, i := range out {
+		ordered[k] = cfgs[i]
+	}
+	return ordered, nil
+}
+
+// ForeignKeyParents reads USER_CONSTRAINTS and returns, for each of tables,
+// the other tables in the list its foreign keys point at, ready for Config.DependsOn
+func ForeignKeyParents(ctx context.Context, db *sqlx.DB, tables []string) (map[string][]string, error) {
+	in := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		in[strings.ToUpper(t)] = true
+	}
+	rows, err := db.QueryContext(ctx, `SELECT DISTINCT c.TABLE_NAME, p.TABLE_NAME
+FROM USER_CONSTRAINTS c
+JOIN USER_CONSTRAINTS p ON p.OWNER = c.R_OWNER AND p.CONSTRAINT_NAME = c.R_CONSTRAINT_NAME
+WHERE c.CONSTRAINT_TYPE = 'R'`)
+	if err != nil {
+		return nil, fmt.Errorf("read foreign keys: %w", err)
+	}
+	defer rows.Close()
+	out := map[string][]string{}
+	for rows.Next() {
+		var child, parent string
+		if err := rows.Scan(&child, &parent); err != nil {
+			return nil, fmt.Errorf("read foreign keys: %w", err)
+		}
+		if in[child] && in[parent] && child != parent {
+			out[child] = append(out[child], parent)
+		}
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,106 @@
+package bulkload
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"sql-learn2/internal/sqlfake"
+)
+
+func tables(cfgs []Config) []string {
+	out := make([]string, len(cfgs))
+	for i, c := range cfgs {
+		out[i] = c.Table
+	}
+	return out
+}
+
+func TestOrderTables(t *testing.T) {
+	tests := []struct {
+		name string
+		cfgs []Config
+		want []string
+		err  string
+	}{
+		{"independent keeps order", []Config{{Table: "B"}, {Table: "A"}}, []string{"B", "A"}, ""},
+		{"children after parents", []Config{
+			{Table: "ORDER_LINE", DependsOn: []string{"orders", "PRODUCT"}},
+			{Table: "ORDERS", DependsOn: []string{"CUSTOMER"}},
+			{Table: "CUSTOMER"},
+			{Table: "PRODUCT"},
+		}, []string{"CUSTOMER", "ORDERS", "PRODUCT", "ORDER_LINE"}, ""},
+		{"outside dependency ignored", []Config{{Table: "C", DependsOn: []string{"ELSEWHERE", "C"}}}, []string{"C"}, ""},
+		{"cycle", []Config{{Table: "A", DependsOn: []string{"B"}}, {Table: "B", DependsOn: []string{"A"}}, {Table: "X"}},
+			nil, "dependency cycle between A, B"},
+		{"duplicate", []Config{{Table: "A"}, {Table: "a"}}, nil, "table a listed twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := orderTables(tt.cfgs)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tables(got), tt.want) {
+				t.Errorf("order = %v, want %v", tables(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	gen := func(n int) []any { return []any{n} }
+	cfgs := []Config{
+		{Table: "CHILD", Columns: []string{"ID"}, Generate: gen, Rows: 2, DependsOn: []string{"PARENT"}, MViews: []string{"MV_ALL"}},
+		{Table: "PARENT", Columns: []string{"ID"}, Generate: gen, Rows: 1, MViews: []string{"MV_PARENT", "mv_all"}},
+	}
+	f := sqlfake.New(t)
+	report, err := LoadAll(quiet, sqlx.NewDb(f.DB, "oracle"), cfgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	refresh := ""
+	for _, c := range f.Calls() {
+		switch {
+		case strings.HasPrefix(c.Query, "TRUNCATE"), strings.HasPrefix(c.Query, "DELETE"):
+			got = append(got, c.Query)
+		case strings.HasPrefix(c.Query, "INSERT INTO"):
+			got = append(got, strings.Fields(c.Query)[2])
+		case strings.Contains(c.Query, "DBMS_MVIEW"):
+			refresh = c.Args[0].(string)
+		}
+	}
+	want := []string{"TRUNCATE TABLE CHILD", "DELETE FROM PARENT", "PARENT", "CHILD"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+	if refresh != "MV_PARENT,mv_all" {
+		t.Errorf("refresh list = %q", refresh)
+	}
+	if len(report.Tables) != 2 || report.Tables[0].Table != "PARENT" || report.Tables[1].Rows != 2 {
+		t.Errorf("report = %+v", report.Tables)
+	}
+}
+
+func TestForeignKeyParents(t *testing.T) {
+	f := sqlfake.New(t)
+	f.OnQuery("USER_CONSTRAINTS", []string{"C", "P"},
+		[]any{"ORDERS", "CUSTOMER"}, []any{"ORDER_LINE", "ORDERS"}, []any{"ORDER_LINE", "PRODUCT"}, []any{"AUDIT", "ORDERS"})
+	got, err := ForeignKeyParents(quiet, sqlx.NewDb(f.DB, "oracle"), []string{"customer", "orders", "order_line"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{"ORDERS": {"CUSTOMER"}, "ORDER_LINE": {"ORDERS"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parents = %v, want %v", got, want)
+	}
+}
@@ -3,36 +3,49 @@ package bulkload
 import (
 	"context"
 	"fmt"
-	"log"
+	"strings"
 	"time"
 
+	"sql-learn2/logging"
+	"sql-learn2/tracing"
+
 	"github.com/jmoiron/sqlx"
 )
 
-// refreshMaterializedView refreshes the MV_BULK_DATA materialized view.
-func refreshMaterializedView(ctx context.Context, db *sqlx.DB) (time.Duration, error) {
-	log.Println("Insert committed. Refreshing MV_BULK_DATA (COMPLETE, ATOMIC) ...")
+// refreshMaterializedViews refreshes mviews in one COMPLETE, ATOMIC refresh
+// so readers see all of them switch to the new data together
+func refreshMaterializedViews(ctx context.Context, db *sqlx.DB, mviews []string) (d time.Duration, err error) {
+	if len(mviews) == 0 {
+		return 0, nil
+	}
+	list := strings.Join(mviews, ",")
+	ctx, span := tracing.Start(ctx, tracing.SpanMVRefresh, tracing.String("mview", list))
+	defer func() { span.End(err) }()
+
+	logger := logging.FromContext(ctx).With("mview", list)
+	logger.Info("Insert committed. Refreshing MV (COMPLETE, ATOMIC) ...")
 	refreshStart := time.Now()
 
 	refreshSQL := `
 BEGIN
   DBMS_MVIEW.REFRESH(
-    list           => 'MV_BULK_DATA',
+    list           => :1,
     method         => 'C',
     atomic_refresh => TRUE
   );
 END;`
 
-	result, err := db.ExecContext(ctx, refreshSQL)
+	result, err := db.ExecContext(ctx, refreshSQL, list)
 	if err != nil {
 		return 0, fmt.Errorf("refresh materialized view failed: %w", err)
 	}
 	// Check if any rows were affected
 	if result != nil {
 		rowsAffected, _ := result.RowsAffected()
-		log.Printf("Refresh result - rows affected: %d", rowsAffected)
+		logger.Debug("Refresh result", logging.FieldRows, rowsAffected)
 	}
 
-	log.Println("Refresh complete.")
-	return time.Since(refreshStart), nil
+	refreshDuration := time.Since(refreshStart)
+	logger.Info("Refresh complete.", logging.FieldDuration, refreshDuration)
+	return refreshDuration, nil
 }
@@ -3,17 +3,37 @@ package bulkload
 import (
 	"context"
 	"fmt"
-	"log"
+
+	"sql-learn2/logging"
 
 	"github.com/jmoiron/sqlx"
 )
 
-// truncateTable truncates the BULK_DATA table.
-func truncateTable(ctx context.Context, db *sqlx.DB) error {
-	log.Println("Truncating BULK_DATA ...")
-	_, err := db.ExecContext(ctx, "TRUNCATE TABLE BULK_DATA")
-	if err != nil {
-		return fmt.Errorf("truncate failed: %w", err)
+// clearTable empties table according to mode
+func clearTable(ctx context.Context, db *sqlx.DB, table string, mode ClearMode) error {
+	logger := logging.FromContext(ctx).With(logging.FieldTable, table)
+	switch mode {
+	case Append:
+		return nil
+	case Delete:
+		logger.Info("Deleting rows")
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("delete %s: %w", table, err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("delete %s: %w", table, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("delete %s: commit: %w", table, err)
+		}
+		return nil
+	default:
+		logger.Info("Truncating table")
+		if _, err := db.ExecContext(ctx, "TRUNCATE TABLE "+table); err != nil {
+			return fmt.Errorf("truncate %s failed: %w", table, err)
+		}
+		return nil
 	}
-	return nil
 }
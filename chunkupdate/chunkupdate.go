@@ -0,0 +1,296 @@
+package chunkupdate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"sql-learn2/logging"
+)
+
+// KeyMode selects how the table is split into chunks
+type KeyMode string
+
+const (
+	// KeyPK splits on a key column (numeric or string) in key order
+	KeyPK KeyMode = "pk"
+	// KeyRowID splits on ROWID, which needs no index and follows the physical layout
+	KeyRowID KeyMode = "rowid"
+)
+
+// Config describes one chunked UPDATE job
+type Config struct {
+	Job       string  // job name; progress is stored under it so a rerun resumes
+	Table     string  // table to update, optionally schema-qualified
+	Set       string  // SET clause without the keyword, e.g. "status = 'DONE', updated_at = SYSDATE"
+	Where     string  // optional extra predicate, ANDed with the chunk range
+	Mode      KeyMode // KeyPK or KeyRowID
+	KeyColumn string  // key column for KeyPK; must be unique, or chunk ranges could overlap
+	ChunkSize int     // target rows per chunk
+	Pause     time.Duration
+
+	// ProgressTable stores per-chunk state; created on first use. Defaults to CHUNK_UPDATE_PROGRESS.
+	ProgressTable string
+
+	// OnChunk is called after every chunk, e.g. to report progress
+	OnChunk func(Progress)
+
+	// Logger defaults to the logger in ctx (see logging.WithLogger)
+	Logger *slog.Logger
+}
+
+// Progress is a snapshot after one chunk
+type Progress struct {
+	Job         string
+	Chunk       int
+	ChunksDone  int
+	ChunksTotal int
+	RowsChunk   int64
+	RowsTotal   int64 // rows updated by this run
+	Elapsed     time.Duration
+	ETA         time.Duration
+}
+
+func (p Progress) String() string {
+	return fmt.Sprintf("%s: chunk %d done (%d/%d), %d rows (total %d), elapsed %v, eta %v",
+		p.Job, p.Chunk, p.ChunksDone, p.ChunksTotal, p.RowsChunk, p.RowsTotal,
+		p.Elapsed.Round(time.Second), p.ETA.Round(time.Second))
+}
+
+// Result summarizes a run
+type Result struct {
+	Resumed     bool // an earlier run's plan was reused
+	ChunksTotal int
+	ChunksRun   int   // chunks processed by this run
+	ChunksSkip  int   // chunks already done before this run
+	Rows        int64 // rows updated by this run
+	Duration    time.Duration
+}
+
+var identRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_$#]*(\.[A-Za-z][A-Za-z0-9_$#]*)?$`)
+
+func (c *Config) validate() error {
+	if c.Job == "" {
+		return fmt.Errorf("job name is required")
+	}
+	if !identRe.MatchString(c.Table) {
+		return fmt.Errorf("invalid table name %q", c.Table)
+	}
+	if strings.TrimSpace(c.Set) == "" {
+		return fmt.Errorf("SET clause is required")
+	}
+	switch c.Mode {
+	case KeyPK:
+		if !identRe.MatchString(c.KeyColumn) {
+			return fmt.Errorf("invalid key column %q", c.KeyColumn)
+		}
+	case KeyRowID:
+	default:
+		return fmt.Errorf("unknown key mode %q", c.Mode)
+	}
+	if c.ChunkSize <= 0 {
+		return fmt.Errorf("chunk size must be positive")
+	}
+	if c.ProgressTable == "" {
+		c.ProgressTable = "CHUNK_UPDATE_PROGRESS"
+	}
+	if !identRe.MatchString(c.ProgressTable) {
+		return fmt.Errorf("invalid progress table name %q", c.ProgressTable)
+	}
+	return nil
+}
+
+// keyExpr is the column the chunks are ranged on
+func (c *Config) keyExpr() string {
+	if c.Mode == KeyRowID {
+		return "ROWID"
+	}
+	return c.KeyColumn
+}
+
+// bound converts a stored chunk boundary back into a key value
+func (c *Config) bound(placeholder string) string {
+	if c.Mode == KeyRowID {
+		return "CHARTOROWID(" + placeholder + ")"
+	}
+	return placeholder
+}
+
+// planSQL splits the rows matching Where into NTILE buckets and returns each bucket's key range
+func (c *Config) planSQL() string {
+	key := c.keyExpr()
+	lo, hi := "MIN(k)", "MAX(k)"
+	if c.Mode == KeyRowID {
+		lo, hi = "ROWIDTOCHAR(MIN(k))", "ROWIDTOCHAR(MAX(k))"
+	}
+	where := ""
+	if c.Where != "" {
+		where = " WHERE " + c.Where
+	}
+	return fmt.Sprintf(`SELECT grp, %s, %s, COUNT(*)
+  FROM (SELECT %s k, NTILE(:1) OVER (ORDER BY %s) grp FROM %s%s)
+ GROUP BY grp
+ ORDER BY grp`, lo, hi, key, key, c.Table, where)
+}
+
+// updateSQL updates one chunk; binds :1 and :2 are the chunk's bounds
+func (c *Config) updateSQL() string {
+	q := fmt.Sprintf("UPDATE %s SET %s WHERE %s BETWEEN %s AND %s",
+		c.Table, c.Set, c.keyExpr(), c.bound(":1"), c.bound(":2"))
+	if c.Where != "" {
+		q += " AND (" + c.Where + ")"
+	}
+	return q
+}
+
+// Run executes the job. Each chunk's UPDATE and its progress row commit in
+// the same transaction, so a chunk is never applied twice; after a failure
+// (or kill) running the same job again continues with the remaining chunks.
+//
+// Chunk ranges are fixed when the job is planned: rows inserted later beyond
+// the last range are not updated.
+func Run(ctx context.Context, db *sql.DB, cfg Config) (*Result, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	p := &progressStore{db: db, table: cfg.ProgressTable}
+	if err := p.ensure(ctx); err != nil {
+		return nil, err
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.FromContext(ctx)
+	}
+	logger = logger.With(logging.FieldJob, cfg.Job, logging.FieldTable, cfg.Table)
+
+	start := time.Now()
+	res := &Result{}
+	chunks, err := p.load(ctx, cfg.Job)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) > 0 {
+		res.Resumed = true
+		logger.Info("Resuming, chunks planned earlier", "chunks", len(chunks))
+	} else {
+		if chunks, err = plan(ctx, db, &cfg); err != nil {
+			return nil, err
+		}
+		if err := p.save(ctx, cfg.Job, chunks); err != nil {
+			return nil, err
+		}
+		logger.Info("Planned chunks", "chunks", len(chunks), "chunk_size", cfg.ChunkSize)
+	}
+	res.ChunksTotal = len(chunks)
+
+	updateSQL := cfg.updateSQL()
+	done := 0
+	for _, ch := range chunks {
+		if ch.Status == StatusDone {
+			done++
+			res.ChunksSkip++
+		}
+	}
+	runStart := time.Now()
+	for _, ch := range chunks {
+		if ch.Status == StatusDone {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		n, err := runChunk(ctx, db, p, cfg.Job, ch, updateSQL)
+		if err != nil {
+			if ferr := p.markFailed(context.Background(), cfg.Job, ch.ID, err); ferr != nil {
+				logger.Error("Could not record chunk failure", "chunk", ch.ID, logging.FieldError, ferr)
+			}
+			res.Duration = time.Since(start)
+			return res, fmt.Errorf("chunk %d [%s .. %s]: %w", ch.ID, ch.Lo, ch.Hi, err)
+		}
+		done++
+		res.ChunksRun++
+		res.Rows += n
+		logger.Debug("Chunk done", "chunk", ch.ID, logging.FieldRows, n)
+
+		if cfg.OnChunk != nil {
+			elapsed := time.Since(runStart)
+			var eta time.Duration
+			if res.ChunksRun > 0 {
+				eta = elapsed / time.Duration(res.ChunksRun) * time.Duration(len(chunks)-done)
+			}
+			cfg.OnChunk(Progress{
+				Job: cfg.Job, Chunk: ch.ID, ChunksDone: done, ChunksTotal: len(chunks),
+				RowsChunk: n, RowsTotal: res.Rows, Elapsed: elapsed, ETA: eta,
+			})
+		}
+		if cfg.Pause > 0 {
+			select {
+			case <-ctx.Done():
+				return res, ctx.Err()
+			case <-time.After(cfg.Pause):
+			}
+		}
+	}
+	res.Duration = time.Since(start)
+	logger.Info("Update finished", "chunks", res.ChunksRun, logging.FieldRows, res.Rows, logging.FieldDuration, res.Duration)
+	return res, nil
+}
+
+// plan computes the chunk ranges
+func plan(ctx context.Context, db *sql.DB, cfg *Config) ([]Chunk, error) {
+	countSQL := "SELECT COUNT(*) FROM " + cfg.Table
+	if cfg.Where != "" {
+		countSQL += " WHERE " + cfg.Where
+	}
+	var total int64
+	if err := db.QueryRowContext(ctx, countSQL).Scan(&total); err != nil {
+		return nil, fmt.Errorf("count rows: %w", err)
+	}
+	if total == 0 {
+		return nil, nil
+	}
+	buckets := (total + int64(cfg.ChunkSize) - 1) / int64(cfg.ChunkSize)
+
+	rows, err := db.QueryContext(ctx, cfg.planSQL(), buckets)
+	if err != nil {
+		return nil, fmt.Errorf("plan chunks: %w", err)
+	}
+	defer rows.Close()
+	var chunks []Chunk
+	for rows.Next() {
+		var ch Chunk
+		if err := rows.Scan(&ch.ID, &ch.Lo, &ch.Hi, &ch.Planned); err != nil {
+			return nil, fmt.Errorf("plan chunks: %w", err)
+		}
+		ch.Status = StatusPending
+		chunks = append(chunks, ch)
+	}
+	return chunks, rows.Err()
+}
+
+// runChunk updates one chunk and marks it done in the same transaction
+func runChunk(ctx context.Context, db *sql.DB, p *progressStore, job string, ch Chunk, updateSQL string) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, updateSQL, ch.Lo, ch.Hi)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err := p.markDone(ctx, tx, job, ch.ID, n); err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
@@ -0,0 +1,69 @@
+package chunkupdate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	base := Config{Job: "j", Table: "T", Set: "a = 1", Mode: KeyPK, KeyColumn: "ID", ChunkSize: 10}
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"valid", func(*Config) {}, ""},
+		{"schema qualified", func(c *Config) { c.Table = "APP.ORDERS" }, ""},
+		{"rowid without key", func(c *Config) { c.Mode, c.KeyColumn = KeyRowID, "" }, ""},
+		{"missing job", func(c *Config) { c.Job = "" }, "job name"},
+		{"bad table", func(c *Config) { c.Table = "T; DROP TABLE X" }, "invalid table"},
+		{"missing set", func(c *Config) { c.Set = " " }, "SET clause"},
+		{"bad key", func(c *Config) { c.KeyColumn = "" }, "invalid key column"},
+		{"bad mode", func(c *Config) { c.Mode = "hash" }, "unknown key mode"},
+		{"zero chunk", func(c *Config) { c.ChunkSize = 0 }, "chunk size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := base
+			tt.modify(&c)
+			err := c.validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if c.ProgressTable != "CHUNK_UPDATE_PROGRESS" {
+					t.Errorf("default progress table not set, got %q", c.ProgressTable)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpdateSQL(t *testing.T) {
+	pk := Config{Table: "T", Set: "a = 1", Mode: KeyPK, KeyColumn: "ID", Where: "b = 2"}
+	if got, want := pk.updateSQL(), "UPDATE T SET a = 1 WHERE ID BETWEEN :1 AND :2 AND (b = 2)"; got != want {
+		t.Errorf("pk updateSQL = %q, want %q", got, want)
+	}
+	rid := Config{Table: "T", Set: "a = 1", Mode: KeyRowID}
+	if got, want := rid.updateSQL(), "UPDATE T SET a = 1 WHERE ROWID BETWEEN CHARTOROWID(:1) AND CHARTOROWID(:2)"; got != want {
+		t.Errorf("rowid updateSQL = %q, want %q", got, want)
+	}
+}
+
+func TestPlanSQL(t *testing.T) {
+	rid := Config{Table: "T", Mode: KeyRowID, Where: "b = 2"}
+	q := rid.planSQL()
+	for _, want := range []string{"ROWIDTOCHAR(MIN(k))", "NTILE(:1) OVER (ORDER BY ROWID)", "FROM T WHERE b = 2"} {
+		if !strings.Contains(q, want) {
+			t.Errorf("planSQL missing %q:\n%s", want, q)
+		}
+	}
+	pk := Config{Table: "T", Mode: KeyPK, KeyColumn: "ID"}
+	if q := pk.planSQL(); !strings.Contains(q, "SELECT grp, MIN(k), MAX(k)") || strings.Contains(q, "WHERE") {
+		t.Errorf("unexpected pk planSQL:\n%s", q)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"sql-learn2/chunkupdate"
+	"sql-learn2/logging"
+	"sql-learn2/oraconn"
+	"sql-learn2/oraerr"
+)
+
+// Chunked, resumable UPDATE. Example:
+//
+//	go run ./chunkupdate/cmd -job reset-dates -table Implicit -key id \
+//	    -set "updated_at = SYSDATE" -where "updated_at < DATE '2001-01-01'" -chunk 50000
+//
+// Interrupt it (Ctrl-C) or let a chunk fail, then run the same command again
+// to continue with the remaining chunks.
+func main() {
+	job := flag.String("job", "", "Job name; progress is stored under it and a rerun resumes it (required)")
+	table := flag.String("table", "", "Table to update (required)")
+	set := flag.String("set", "", "SET clause without the keyword, e.g. \"status = 'X'\" (required)")
+	where := flag.String("where", "", "Optional predicate selecting the rows to update")
+	key := flag.String("key", "", "Unique key column to chunk on; empty = chunk on ROWID")
+	chunk := flag.Int("chunk", 100000, "Target rows per chunk (one commit each)")
+	pause := flag.Duration("pause", 0, "Pause between chunks, to limit load on a busy system")
+	progressTable := flag.String("progress-table", "CHUNK_UPDATE_PROGRESS", "Table storing per-chunk progress")
+	reset := flag.Bool("reset", false, "Forget the job's progress and plan again before running")
+
+	var ora oraconn.Config
+	ora.RegisterFlags(flag.CommandLine)
+	var logCfg logging.Config
+	logCfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := logCfg.Setup(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if *job == "" || *table == "" || *set == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Stop after the current chunk on Ctrl-C; the next run resumes
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	db, err := oraconn.Connect(ctx, ora)
+	if err != nil {
+		oraerr.Fatal("Failed to connect", err)
+	}
+	defer db.Close()
+
+	cfg := chunkupdate.Config{
+		Job:           *job,
+		Table:         *table,
+		Set:           *set,
+		Where:         *where,
+		Mode:          chunkupdate.KeyRowID,
+		ChunkSize:     *chunk,
+		Pause:         *pause,
+		ProgressTable: *progressTable,
+		OnChunk: func(p chunkupdate.Progress) {
+			log.Println(p)
+		},
+	}
+	if *key != "" {
+		cfg.Mode, cfg.KeyColumn = chunkupdate.KeyPK, *key
+	}
+
+	if *reset {
+		if err := chunkupdate.Reset(ctx, db, *progressTable, *job); err != nil {
+			log.Printf("Warning: reset failed (progress table missing?): %v", err)
+		}
+	}
+
+	start := time.Now()
+	res, err := chunkupdate.Run(ctx, db, cfg)
+	if res != nil {
+		log.Printf("Chunks: %d total, %d run now, %d already done (resumed=%v). Rows updated now: %d. Took %v.",
+			res.ChunksTotal, res.ChunksRun, res.ChunksSkip, res.Resumed, res.Rows, time.Since(start).Round(time.Millisecond))
+	}
+	if err != nil {
+		oraerr.Fatal("Stopped (run the same command again to resume)", err)
+	}
+	log.Println("Done.")
+}
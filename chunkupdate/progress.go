@@ -0,0 +1,127 @@
+package chunkupdate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Chunk states stored in the progress table
+const (
+	StatusPending = "PENDING"
+	StatusDone    = "DONE"
+	StatusFailed  = "FAILED"
+)
+
+// Chunk is one key range of a job
+type Chunk struct {
+	ID      int
+	Lo, Hi  string // inclusive bounds; ROWIDs are stored as text
+	Planned int64  // rows in the range when planned
+	Status  string
+	Rows    int64 // rows updated, once done
+}
+
+// progressStore persists chunk state in a table so jobs can resume
+type progressStore struct {
+	db    *sql.DB
+	table string
+}
+
+func (p *progressStore) ensure(ctx context.Context) error {
+	_, err := p.db.ExecContext(ctx, fmt.Sprintf(`
+		DECLARE
+			e exception;
+			pragma exception_init(e, -955); -- ORA-00955: name is already used by an existing object
+		BEGIN
+			EXECUTE IMMEDIATE 'CREATE TABLE %s (
+				job_name     VARCHAR2(128) NOT NULL,
+				chunk_id     NUMBER NOT NULL,
+				lo_key       VARCHAR2(4000),
+				hi_key       VARCHAR2(4000),
+				planned_rows NUMBER,
+				status       VARCHAR2(10) NOT NULL,
+				rows_updated NUMBER,
+				error_text   VARCHAR2(4000),
+				updated_at   TIMESTAMP DEFAULT SYSTIMESTAMP,
+				PRIMARY KEY (job_name, chunk_id)
+			)';
+		EXCEPTION
+			WHEN e THEN NULL;
+		END;`, p.table))
+	if err != nil {
+		return fmt.Errorf("create progress table %s: %w", p.table, err)
+	}
+	return nil
+}
+
+// load returns the job's chunks in order, or none if the job is new
+func (p *progressStore) load(ctx context.Context, job string) ([]Chunk, error) {
+	rows, err := p.db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT chunk_id, lo_key, hi_key, NVL(planned_rows, 0), status, NVL(rows_updated, 0)
+		   FROM %s WHERE job_name = :1 ORDER BY chunk_id`, p.table), job)
+	if err != nil {
+		return nil, fmt.Errorf("load progress: %w", err)
+	}
+	defer rows.Close()
+	var chunks []Chunk
+	for rows.Next() {
+		var ch Chunk
+		if err := rows.Scan(&ch.ID, &ch.Lo, &ch.Hi, &ch.Planned, &ch.Status, &ch.Rows); err != nil {
+			return nil, fmt.Errorf("load progress: %w", err)
+		}
+		chunks = append(chunks, ch)
+	}
+	return chunks, rows.Err()
+}
+
+// save stores a new plan in one transaction
+func (p *progressStore) save(ctx context.Context, job string, chunks []Chunk) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(
+		`INSERT INTO %s (job_name, chunk_id, lo_key, hi_key, planned_rows, status) VALUES (:1, :2, :3, :4, :5, :6)`, p.table))
+	if err != nil {
+		return fmt.Errorf("save plan: %w", err)
+	}
+	defer stmt.Close()
+	for _, ch := range chunks {
+		if _, err := stmt.ExecContext(ctx, job, ch.ID, ch.Lo, ch.Hi, ch.Planned, StatusPending); err != nil {
+			return fmt.Errorf("save plan: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (p *progressStore) markDone(ctx context.Context, tx *sql.Tx, job string, id int, rows int64) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(
+		`UPDATE %s SET status = :1, rows_updated = :2, error_text = NULL, updated_at = SYSTIMESTAMP
+		  WHERE job_name = :3 AND chunk_id = :4`, p.table), StatusDone, rows, job, id)
+	return err
+}
+
+func (p *progressStore) markFailed(ctx context.Context, job string, id int, cause error) error {
+	msg := cause.Error()
+	if len(msg) > 4000 {
+		msg = msg[:4000]
+	}
+	_, err := p.db.ExecContext(ctx, fmt.Sprintf(
+		`UPDATE %s SET status = :1, error_text = :2, updated_at = SYSTIMESTAMP
+		  WHERE job_name = :3 AND chunk_id = :4`, p.table), StatusFailed, msg, job, id)
+	return err
+}
+
+// Reset deletes a job's progress so the next run plans from scratch
+func Reset(ctx context.Context, db *sql.DB, progressTable, job string) error {
+	if progressTable == "" {
+		progressTable = "CHUNK_UPDATE_PROGRESS"
+	}
+	if !identRe.MatchString(progressTable) {
+		return fmt.Errorf("invalid progress table name %q", progressTable)
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE job_name = :1`, progressTable), job)
+	return err
+}
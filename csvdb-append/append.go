@@ -1,19 +1,24 @@
 package csvdbappend
 
 import (
-	"bufio"
 	"context"
 	"database/sql"
 	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
+	"sql-learn2/csvfile"
 	"sql-learn2/dynamic"
+	"sql-learn2/loadhistory"
+	"sql-learn2/logging"
+	"sql-learn2/retry"
+	"sql-learn2/tracing"
 )
 
 // UpsertCSVToDB reads a CSV file and upserts its data into an existing Oracle table.
@@ -22,66 +27,157 @@ import (
 // - Row 1: column headers
 // - Row 2: data types (VARCHAR2, NUMBER, DATE, TIMESTAMP, CLOB) — used for value conversion only
 // - Row 3+: data rows
+// - Gzip-compressed files are detected and decompressed while reading
 //
 // Behavior:
 //   - The target table must already exist with compatible columns.
 //   - keyCols defines the natural key used to match existing rows. Matching rows are updated
-//     (non-key columns only). Non-matching rows are inserted.
+//     (non-key columns only). Non-matching rows are inserted. Options.Mode can limit the
+//     MERGE to either. Without keyCols the primary key of the table is used.
 //   - Column and table names are normalized to Oracle unquoted identifiers (upper-case, 30-char limit, etc.).
-func UpsertCSVToDB(ctx context.Context, db *sql.DB, csvPath, tableName string, keyCols []string) error {
+//   - Rows are merged in array-bound batches (see Options.BatchSize).
+func UpsertCSVToDB(ctx context.Context, db *sql.DB, csvPath, tableName string, keyCols []string) (Result, error) {
+	return UpsertCSVToDBWithOptions(ctx, db, csvPath, tableName, keyCols, Options{})
+}
+
+// Options tune UpsertCSVToDBWithOptions; the zero value behaves like UpsertCSVToDB
+type Options struct {
+	// NullValues are data cells loaded as NULL besides the empty one, e.g.
+	// "NULL", `\N` or "N/A"; matched exactly
+	NullValues []string
+
+	// Mode limits the MERGE to updating matched rows or inserting new ones
+	// (see MergeMode); the default does both
+	Mode MergeMode
+
+	// SkipUnchanged leaves matched rows alone when no non-key column differs
+	// (NULLs compare equal), so identical rows cost no redo and fire no
+	// update triggers; they are counted as Skipped
+	SkipUnchanged bool
+
+	// SkipNulls keeps the value a matched row has where the CSV cell is
+	// NULL (empty or one of NullValues), as delta feeds expect, instead of
+	// clearing it; inserted rows still get NULL
+	SkipNulls bool
+
+	// SurrogateKey names a column missing from the CSV that inserted rows
+	// get from SurrogateSequence (its NEXTVAL), or from the column's
+	// identity or default when no sequence is given; rows are matched on
+	// the keys given to the upsert, which must then be passed explicitly
+	SurrogateKey      string
+	SurrogateSequence string
+
+	// Sync makes the table mirror the CSV: after the merge, rows whose keys
+	// are not in the CSV are deleted, or with SyncFlagColumn kept and that
+	// column set to SyncFlagValue (default DefaultSyncFlagValue). Merged rows
+	// get the flag column cleared. Keys must be NUMBER or VARCHAR2, and a CSV
+	// without data rows is refused rather than emptying the table.
+	Sync           bool
+	SyncFlagColumn string
+	SyncFlagValue  string
+
+	// Workers is the number of sessions merging batches in parallel (default
+	// 1), each on its own connection. Rows are sharded by a hash of their
+	// key, so the rows of one key are merged by one worker in CSV order.
+	// On failure the other workers stop and every failed batch is reported.
+	Workers int
+
+	// Retry reruns a batch whose MERGE failed on a lock held by another
+	// writer: a deadlock (ORA-00060) or a busy row or object (ORA-00054,
+	// ORA-30006, ORA-04021); a Retryable set on it replaces that check. The
+	// zero value does not retry. A rerun merges the whole batch again:
+	// rows an array-bound attempt merged before it hit the lock match and
+	// get the same values once more. Result.Merged counts the rows the
+	// attempt that succeeded reported. The keys of the batches that failed
+	// for good are in Result.FailedKeys. With Staging only the final MERGE
+	// is retried, not the inserts into the staging table.
+	Retry retry.Policy
+
+	// BatchSize is the rows bound per MERGE (default DefaultBatchSize). Each
+	// MERGE binds column arrays, so a batch costs one round trip, and
+	// commits on its own: a failed upsert leaves earlier batches merged.
+	BatchSize int
+
+	// Staging loads the rows into a staging table first, in batches of
+	// BatchSize, then merges them into the target with a single set-based
+	// MERGE, which is much faster for large files and leaves the target
+	// untouched when any row fails. The staging table, StagingTable or the
+	// target name plus "_STG", is created with the target's column types
+	// and dropped afterwards; it must not exist yet. Unlike the row-wise
+	// MERGE, a key repeated in the CSV fails the merge (ORA-30926).
+	Staging      bool
+	StagingTable string
+
+	// AuditFile and AuditTable receive every change the upsert applied:
+	// one row per changed column with the key, the old and the new value,
+	// and the action (INSERT, UPDATE, or DELETE and FLAG for Sync). The file
+	// is a CSV; the table has the layout of migrations 0008_upsert_audit.sql.
+	// The whole table is read before merging to find the old values, and
+	// the audit is written once the upsert succeeded.
+	AuditFile  string
+	AuditTable string
+
+	// DryRun compares the CSV with the table and returns the Diff in
+	// Result instead of merging; nothing is written, audits included.
+	// DiffSample caps the changes listed (default DefaultDiffSample).
+	DryRun     bool
+	DiffSample int
+}
+
+// Result counts what an upsert did
+type Result struct {
+	Table  string // the target table
+	Rows   int    // data rows read from the CSV
+	Merged int    // rows the MERGE inserted or updated
+	// Inserted and Updated split Merged: MergeUpdateOnly and
+	// MergeInsertOnly set the one that applies; an upsert counts the keys
+	// missing from the target just before each batch's MERGE, or with
+	// Options.Staging the staged ones before the single MERGE
+	Inserted int
+	Updated  int
+	Skipped  int // rows Options.Mode or SkipUnchanged left alone: Rows - Merged
+	Deleted  int // rows Options.Sync deleted or flagged
+	Audited  int // changes written to Options.AuditFile or AuditTable
+	// FailedKeys are the keys of the rows read but not merged when the
+	// upsert failed, rendered as "ID=1, CODE=A": the failed batches, the
+	// ones skipped after the first failure and the rows never sent. With
+	// Options.Staging they are the rows not staged; a failed final MERGE
+	// merges no row and lists none.
+	FailedKeys []string
+	Diff       *Diff // set instead of merging with Options.DryRun
+	Duration   time.Duration
+}
+
+// UpsertCSVToDBWithOptions is UpsertCSVToDB with options. Merged comes
+// from the MERGE row counts. The result is filled as far as the upsert
+// got, also on error.
+func UpsertCSVToDBWithOptions(ctx context.Context, db *sql.DB, csvPath, tableName string, keyCols []string, opts Options) (res Result, err error) {
 	if db == nil {
-		return errors.New("db is nil")
+		return res, errors.New("db is nil")
 	}
 	if csvPath == "" {
-		return errors.New("csvPath is empty")
+		return res, errors.New("csvPath is empty")
 	}
-	if len(keyCols) == 0 {
-		return errors.New("keyCols must not be empty")
+	var run *loadhistory.Run
+	if !opts.DryRun {
+		ctx, run = loadhistory.Start(ctx, loadhistory.WorkflowUpsert, csvPath, tableName)
+		defer func() { run.End(err) }()
 	}
+	began := time.Now()
+	defer func() { res.Duration = time.Since(began) }()
 
-	f, err := os.Open(csvPath)
+	rows, err := readCSV(ctx, csvPath)
 	if err != nil {
-		return fmt.Errorf("open csv: %w", err)
-	}
-	defer f.Close()
-
-	r := csv.NewReader(bufio.NewReader(f))
-	r.TrimLeadingSpace = true
-	r.FieldsPerRecord = -1
-
-	rows := make([][]string, 0, 128)
-	for {
-		rec, err := r.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("read csv: %w", err)
-		}
-		for i := range rec {
-			rec[i] = strings.TrimSpace(rec[i])
-		}
-		// skip empty rows
-		empty := true
-		for _, v := range rec {
-			if v != "" {
-				empty = false
-				break
-			}
-		}
-		if empty {
-			continue
-		}
-		rows = append(rows, rec)
+		return res, err
 	}
 	if len(rows) < 2 {
-		return errors.New("csv must have at least 2 rows: header and types")
+		return res, errors.New("csv must have at least 2 rows: header and types")
 	}
 
 	headers := rows[0]
 	typesRow := rows[1]
 	if len(typesRow) < len(headers) {
-		return fmt.Errorf("types row has fewer cells (%d) than headers (%d)", len(typesRow), len(headers))
+		return res, fmt.Errorf("types row has fewer cells (%d) than headers (%d)", len(typesRow), len(headers))
 	}
 
 	// Derive table name if not provided
@@ -90,22 +186,25 @@ func UpsertCSVToDB(ctx context.Context, db *sql.DB, csvPath, tableName string, k
 		name := strings.TrimSuffix(base, filepath.Ext(base))
 		tableName = normalizeIdentifierForOracle(name)
 		if tableName == "" {
-			return fmt.Errorf("cannot derive valid table name from file: %s", base)
+			return res, fmt.Errorf("cannot derive valid table name from file: %s", base)
 		}
 	} else {
 		tableName = normalizeIdentifierForOracle(tableName)
 		if tableName == "" {
-			return fmt.Errorf("invalid table name")
+			return res, fmt.Errorf("invalid table name")
 		}
 	}
 
+	run.SetTarget(tableName)
+	res.Table = tableName
+
 	// Normalize headers and collect types
 	oracleCols := make([]string, 0, len(headers))
 	colTypes := make([]dynamic.DataType, 0, len(headers))
 	for i, h := range headers {
 		col := normalizeIdentifierForOracle(h)
 		if col == "" {
-			return fmt.Errorf("invalid column name at position %d: %q", i+1, h)
+			return res, fmt.Errorf("invalid column name at position %d: %q", i+1, h)
 		}
 		oracleCols = append(oracleCols, col)
 		dtStr := strings.ToUpper(strings.TrimSpace(typesRow[i]))
@@ -121,10 +220,48 @@ func UpsertCSVToDB(ctx context.Context, db *sql.DB, csvPath, tableName string, k
 		case "CLOB":
 			colTypes = append(colTypes, dynamic.Clob)
 		default:
-			return fmt.Errorf("unsupported type %q for column %s", dtStr, col)
+			return res, fmt.Errorf("unsupported type %q for column %s", dtStr, col)
 		}
 	}
 
+	dataRows := rows[2:]
+	nulls := make(map[string]bool, len(opts.NullValues))
+	for _, v := range opts.NullValues {
+		nulls[v] = true
+	}
+	i := 0
+	next := func(vals []any) (int, error) {
+		if i == len(dataRows) {
+			return 0, io.EOF
+		}
+		line := i + 3
+		rec := dataRows[i]
+		i++
+		return line, convertRow(rec, colTypes, nulls, line, vals)
+	}
+	return upsertRows(ctx, db, run, "CSV", csvPath, tableName, oracleCols, colTypes, keyCols, len(dataRows), next, opts)
+}
+
+// rowReader fills vals with the next row and returns its line or row
+// number, for errors; io.EOF after the last row
+type rowReader func(vals []any) (int, error)
+
+// upsertRows merges the rows next reads into tableName, already resolved.
+// from names the input in messages ("CSV"), source is the file or source
+// logged, and count is how many rows next has, or -1 when unknown.
+func upsertRows(ctx context.Context, db *sql.DB, run *loadhistory.Run, from, source, tableName string, oracleCols []string,
+	colTypes []dynamic.DataType, keyCols []string, count int, next rowReader, opts Options) (res Result, err error) {
+	res.Table = tableName
+	if len(keyCols) == 0 && opts.SurrogateKey != "" {
+		return res, errors.New("a surrogate key needs the natural key columns to match rows on")
+	}
+	if len(keyCols) == 0 {
+		if keyCols, err = dynamic.PrimaryKey(ctx, db, tableName, dynamic.CreateOptions{}); err != nil {
+			return res, fmt.Errorf("no key columns given: %w", err)
+		}
+		logging.FromContext(ctx).Info("Key columns from the primary key", logging.FieldTable, tableName, "keys", keyCols)
+	}
+
 	// Normalize and validate key columns
 	colIndex := make(map[string]int, len(oracleCols))
 	for i, c := range oracleCols {
@@ -134,10 +271,10 @@ func UpsertCSVToDB(ctx context.Context, db *sql.DB, csvPath, tableName string, k
 	for _, k := range keyCols {
 		kk := normalizeIdentifierForOracle(k)
 		if kk == "" {
-			return fmt.Errorf("invalid key column: %q", k)
+			return res, fmt.Errorf("invalid key column: %q", k)
 		}
 		if _, ok := colIndex[kk]; !ok {
-			return fmt.Errorf("key column %s not found in CSV headers", kk)
+			return res, fmt.Errorf("key column %s not found in %s columns", kk, from)
 		}
 		keys = append(keys, kk)
 	}
@@ -153,12 +290,69 @@ func UpsertCSVToDB(ctx context.Context, db *sql.DB, csvPath, tableName string, k
 			nonKeys = append(nonKeys, c)
 		}
 	}
+	if opts.SurrogateKey != "" {
+		if opts.SurrogateKey = normalizeIdentifierForOracle(opts.SurrogateKey); opts.SurrogateKey == "" {
+			return res, errors.New("invalid surrogate key column")
+		}
+		if _, ok := colIndex[opts.SurrogateKey]; ok {
+			return res, fmt.Errorf("surrogate key %s must not be a %s column", opts.SurrogateKey, from)
+		}
+	} else if opts.SurrogateSequence != "" {
+		return res, errors.New("SurrogateSequence needs SurrogateKey")
+	}
+	if opts.Mode == MergeUpdateOnly && len(nonKeys) == 0 {
+		return res, errors.New("update-only merge needs a column that is not a key")
+	}
 
-	if len(rows) <= 2 {
+	// A sync compares the keys of the table with those of the rows
+	keyIdx := make([]int, len(keys))
+	keyTypes := make([]dynamic.DataType, len(keys))
+	for i, k := range keys {
+		keyIdx[i] = colIndex[k]
+		keyTypes[i] = colTypes[keyIdx[i]]
+	}
+	if opts.SyncFlagColumn != "" {
+		if !opts.Sync {
+			return res, errors.New("SyncFlagColumn needs Sync")
+		}
+		if opts.SyncFlagColumn = normalizeIdentifierForOracle(opts.SyncFlagColumn); opts.SyncFlagColumn == "" {
+			return res, errors.New("invalid sync flag column")
+		}
+		if _, ok := colIndex[opts.SyncFlagColumn]; ok {
+			return res, fmt.Errorf("sync flag column %s must not be a %s column", opts.SyncFlagColumn, from)
+		}
+	}
+	if opts.Sync {
+		if err := syncKeyTypes(keys, keyTypes); err != nil {
+			return res, err
+		}
+		if count == 0 {
+			return res, fmt.Errorf("sync with no data rows would empty %s; refusing", tableName)
+		}
+	}
+
+	if count == 0 {
 		// nothing to do
-		return nil
+		if opts.DryRun {
+			res.Diff = &Diff{}
+		}
+		run.SetRows(0)
+		return res, nil
+	}
+	start := time.Now()
+	if opts.DryRun {
+		res.Diff, err = diffRows(ctx, db, tableName, next, oracleCols, colTypes, keys, keyIdx, opts)
+		if res.Diff != nil {
+			res.Rows = res.Diff.Inserts + res.Diff.Updates + res.Diff.Unchanged
+		}
+		return res, err
+	}
+	var aud *audit
+	if opts.AuditFile != "" || opts.AuditTable != "" {
+		if aud, err = snapshotAudit(ctx, db, tableName, oracleCols, colTypes, keys, keyIdx, opts); err != nil {
+			return res, err
+		}
 	}
-	dataRows := rows[2:]
 
 	// Build MERGE statement template
 	placeholders := make([]string, len(oracleCols))
@@ -174,78 +368,339 @@ func UpsertCSVToDB(ctx context.Context, db *sql.DB, csvPath, tableName string, k
 		onConds[i] = fmt.Sprintf("t.%s = s.%s", k, k)
 	}
 
-	updateClause := ""
-	if len(nonKeys) > 0 {
-		sets := make([]string, len(nonKeys))
-		for i, c := range nonKeys {
-			sets[i] = fmt.Sprintf("t.%s = s.%s", c, c)
+	var clauses []string
+	sets := make([]string, 0, len(nonKeys)+1)
+	changes := make([]string, 0, len(nonKeys)+1)
+	for _, c := range nonKeys {
+		if opts.SkipNulls {
+			sets = append(sets, fmt.Sprintf("t.%s = COALESCE(s.%s, t.%s)", c, c, c))
+			changes = append(changes, fmt.Sprintf("(s.%s IS NOT NULL AND %s)", c, changed(c, colTypes[colIndex[c]])))
+			continue
 		}
-		updateClause = fmt.Sprintf("WHEN MATCHED THEN UPDATE SET %s", strings.Join(sets, ", "))
+		sets = append(sets, fmt.Sprintf("t.%s = s.%s", c, c))
+		changes = append(changes, changed(c, colTypes[colIndex[c]]))
 	}
-
-	insertCols := strings.Join(oracleCols, ", ")
-	values := make([]string, len(oracleCols))
-	for i, c := range oracleCols {
-		values[i] = fmt.Sprintf("s.%s", c)
+	if opts.SyncFlagColumn != "" {
+		sets = append(sets, fmt.Sprintf("t.%s = NULL", opts.SyncFlagColumn)) // back in the CSV
+		changes = append(changes, fmt.Sprintf("t.%s IS NOT NULL", opts.SyncFlagColumn))
+	}
+	if len(sets) > 0 && opts.Mode != MergeInsertOnly {
+		update := fmt.Sprintf("WHEN MATCHED THEN UPDATE SET %s", strings.Join(sets, ", "))
+		if opts.SkipUnchanged {
+			update += " WHERE " + strings.Join(changes, " OR ")
+		}
+		clauses = append(clauses, update)
+	}
+	if opts.Mode != MergeUpdateOnly {
+		insertCols := oracleCols
+		values := make([]string, len(oracleCols))
+		for i, c := range oracleCols {
+			values[i] = fmt.Sprintf("s.%s", c)
+		}
+		if opts.SurrogateSequence != "" {
+			insertCols = append(slices.Clip(insertCols), opts.SurrogateKey)
+			values = append(values, strings.TrimSpace(opts.SurrogateSequence)+".NEXTVAL")
+		}
+		clauses = append(clauses, fmt.Sprintf("WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)", strings.Join(insertCols, ", "), strings.Join(values, ", ")))
 	}
-	insertClause := fmt.Sprintf("WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)", insertCols, strings.Join(values, ", "))
 
+	using := fmt.Sprintf("(SELECT %s FROM DUAL)", strings.Join(selectItems, ", "))
+	verb, loadSQL := "merge", ""
+	staging := ""
+	if opts.Staging {
+		if staging = stagingName(tableName); opts.StagingTable != "" {
+			staging = normalizeIdentifierForOracle(opts.StagingTable)
+		}
+		if staging == "" || staging == tableName {
+			return res, fmt.Errorf("invalid staging table %q", opts.StagingTable)
+		}
+		using = staging
+		verb = "stage"
+		loadSQL = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", staging, strings.Join(oracleCols, ", "), strings.Join(placeholders, ", "))
+	}
 	mergeSQL := fmt.Sprintf(
-		"MERGE INTO %s t USING (SELECT %s FROM DUAL) s ON (%s) %s %s",
+		"MERGE INTO %s t USING %s s ON (%s) %s",
 		tableName,
-		strings.Join(selectItems, ", "),
+		using,
 		strings.Join(onConds, " AND "),
-		updateClause,
-		insertClause,
+		strings.Join(clauses, " "),
 	)
+	if loadSQL == "" {
+		loadSQL = mergeSQL
+	}
 
-	stmt, err := db.PrepareContext(ctx, mergeSQL)
+	attrs := []tracing.Attr{tracing.String(tracing.AttrTable, tableName)}
+	if count >= 0 {
+		attrs = append(attrs, tracing.Int(tracing.AttrRows, count))
+	}
+	ctx, span := tracing.Start(ctx, tracing.SpanMerge, attrs...)
+	defer func() { span.End(err) }()
+
+	if staging != "" {
+		if err := createStaging(ctx, db, staging, tableName, oracleCols); err != nil {
+			return res, err
+		}
+		defer dropStaging(context.WithoutCancel(ctx), db, staging)
+	}
+	loadRetry := opts.Retry
+	if staging != "" {
+		// only the MERGE is retried: a rerun of an array INSERT that staged
+		// part of its rows would stage them twice and fail it (ORA-30926)
+		loadRetry = retry.Policy{}
+	}
+	pool, err := startMergePool(ctx, db, tableName, verb, loadSQL, opts.Workers, loadRetry)
 	if err != nil {
-		return fmt.Errorf("prepare merge: %w", err)
+		return res, err
+	}
+	// a row-wise upsert counts the new keys of each batch to split Merged
+	countNew := staging == "" && opts.Mode == MergeUpsert
+	if countNew {
+		pool.newRows = func(ctx context.Context, conn *sql.Conn, j mergeJob) (int, error) {
+			return countNewKeys(ctx, conn, tableName, keys, j.keyVals)
+		}
 	}
-	defer stmt.Close()
 
-	for rIdx, rec := range dataRows {
-		vals := make([]any, len(oracleCols))
-		for cIdx := range oracleCols {
-			cell := ""
-			if cIdx < len(rec) {
-				cell = strings.TrimSpace(rec[cIdx])
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	size := batchSize
+	if count >= 0 {
+		size = min(batchSize, count)
+	}
+	// one batch per worker, filled with the rows of its keys
+	batches := make([]*batch, pool.workers())
+	for i := range batches {
+		batches[i] = newBatch(colTypes, size)
+	}
+	flush := func(w int) bool {
+		b := batches[w]
+		if b.len() == 0 {
+			return true
+		}
+		if !pool.submit(w, mergeJob{first: b.first, last: b.last, rows: b.len(), args: b.args(), keys: b.keys, keyVals: b.keyVals}) {
+			return false // b keeps the rows, see FailedKeys below
+		}
+		b.reset(0)
+		return true
+	}
+	var csvKeys keySet
+	if opts.Sync {
+		csvKeys = make(keySet, max(count, 0))
+	}
+	var convErr error
+	vals := make([]any, len(oracleCols))
+	kv := make([]any, len(keyIdx))
+	for {
+		line, err := next(vals)
+		if err == io.EOF {
+			break
+		}
+		if convErr = err; convErr != nil {
+			break
+		}
+		res.Rows++
+		for i, c := range keyIdx {
+			kv[i] = vals[c]
+		}
+		key := keyOf(kv)
+		if csvKeys != nil {
+			csvKeys[key] = true
+		}
+		if aud != nil {
+			aud.row(vals)
+		}
+		w := shard(key, len(batches))
+		b := batches[w]
+		if b.len() == 0 {
+			b.first = line
+		}
+		b.add(vals)
+		b.keys = append(b.keys, keyText(keys, keyTypes, kv))
+		if countNew {
+			b.keyVals = append(b.keyVals, slices.Clone(kv))
+		}
+		b.last = line
+		if b.len() == batchSize && !flush(w) {
+			break
+		}
+	}
+	if convErr == nil {
+		for w := range batches {
+			if !flush(w) {
+				break
 			}
-			if cell == "" {
-				vals[cIdx] = sql.NullString{Valid: false}
-				continue
+		}
+	} else {
+		pool.cancel() // the upsert stops at the bad row
+	}
+	res.Merged, err = pool.wait()
+	if countNew {
+		res.Inserted, res.Updated = pool.inserted, res.Merged-pool.inserted
+	}
+	res.FailedKeys = pool.failed
+	// rows read but never handed to a worker were not merged either
+	for _, b := range batches {
+		res.FailedKeys = append(res.FailedKeys, b.keys...)
+	}
+	if convErr != nil {
+		return res, convErr
+	}
+	if err != nil {
+		return res, err
+	}
+	if staging != "" {
+		inserts := -1
+		if opts.Mode == MergeUpsert {
+			if inserts, err = countMissing(ctx, db, staging, tableName, onConds); err != nil {
+				return res, err
 			}
-			switch colTypes[cIdx] {
-			case dynamic.Number:
-				// Decide int64 vs float64
-				if strings.ContainsAny(cell, ".eE") {
-					if f, err := strconv.ParseFloat(cell, 64); err == nil {
-						vals[cIdx] = f
-					} else {
-						return fmt.Errorf("row %d col %d: invalid NUMBER %q: %v", rIdx+3, cIdx+1, cell, err)
-					}
-				} else {
-					if n, err := strconv.ParseInt(cell, 10, 64); err == nil {
-						vals[cIdx] = n
-					} else if f, err2 := strconv.ParseFloat(cell, 64); err2 == nil {
-						vals[cIdx] = f
-					} else {
-						return fmt.Errorf("row %d col %d: invalid NUMBER %q", rIdx+3, cIdx+1, cell)
-					}
-				}
-			default:
-				vals[cIdx] = cell
+		}
+		if res.Merged, err = mergeStaging(ctx, db, staging, mergeSQL, opts.Retry); err != nil {
+			return res, err
+		}
+		if inserts >= 0 {
+			// a writer may insert one of the keys meanwhile, turning it into an update
+			res.Inserted = min(inserts, res.Merged)
+			res.Updated = res.Merged - res.Inserted
+		}
+	}
+	if opts.Sync && res.Rows == 0 {
+		return res, fmt.Errorf("sync with no data rows would empty %s; refusing", tableName)
+	}
+	res.Skipped = res.Rows - res.Merged
+	switch opts.Mode {
+	case MergeUpdateOnly:
+		res.Updated = res.Merged
+	case MergeInsertOnly:
+		res.Inserted = res.Merged
+	}
+	if opts.Sync {
+		n, err := syncTable(ctx, db, tableName, keys, keyTypes, csvKeys, opts, batchSize, aud)
+		res.Deleted = n
+		if err != nil {
+			return res, err
+		}
+		action := "deleted"
+		if opts.SyncFlagColumn != "" {
+			action = "flagged"
+		}
+		logging.FromContext(ctx).Info("Rows missing from the "+from+" "+action, logging.FieldTable, tableName, logging.FieldRows, n)
+	}
+
+	if aud != nil {
+		if opts.AuditFile != "" {
+			if err := writeAuditFile(opts.AuditFile, tableName, aud.changes); err != nil {
+				return res, err
 			}
 		}
-		if _, err := stmt.ExecContext(ctx, vals...); err != nil {
-			return fmt.Errorf("merge row %d: %w", rIdx+3, err)
+		if opts.AuditTable != "" {
+			if err := writeAuditTable(ctx, db, strings.TrimSpace(opts.AuditTable), tableName, aud.changes, batchSize); err != nil {
+				return res, err
+			}
 		}
+		res.Audited = len(aud.changes)
+		logging.FromContext(ctx).Info("Upsert changes audited", logging.FieldTable, tableName, "changes", res.Audited)
 	}
 
+	run.SetRows(int64(res.Merged))
+	logging.FromContext(ctx).Info(from+" merged", logging.FieldTable, tableName, logging.FieldFile, source,
+		logging.FieldRows, res.Rows, "merged", res.Merged, "inserted", res.Inserted, "updated", res.Updated,
+		"skipped", res.Skipped, logging.FieldDuration, time.Since(start), "rows_per_sec", rowsPerSec(res.Rows, time.Since(start)))
+	return res, nil
+}
+
+// convertRow converts the cells of rec on CSV line into vals: nil for NULL,
+// int64 or float64 for NUMBER, the trimmed text otherwise
+func convertRow(rec []string, types []dynamic.DataType, nulls map[string]bool, line int, vals []any) error {
+	for cIdx := range types {
+		cell := ""
+		if cIdx < len(rec) {
+			cell = strings.TrimSpace(rec[cIdx])
+		}
+		vals[cIdx] = nil
+		if cell == "" || nulls[cell] {
+			continue
+		}
+		switch types[cIdx] {
+		case dynamic.Number:
+			// Decide int64 vs float64
+			if strings.ContainsAny(cell, ".eE") {
+				f, err := strconv.ParseFloat(cell, 64)
+				if err != nil {
+					return fmt.Errorf("row %d col %d: invalid NUMBER %q: %v", line, cIdx+1, cell, err)
+				}
+				vals[cIdx] = f
+			} else if n, err := strconv.ParseInt(cell, 10, 64); err == nil {
+				vals[cIdx] = n
+			} else if f, err := strconv.ParseFloat(cell, 64); err == nil {
+				vals[cIdx] = f
+			} else {
+				return fmt.Errorf("row %d col %d: invalid NUMBER %q", line, cIdx+1, cell)
+			}
+		default:
+			vals[cIdx] = cell
+		}
+	}
 	return nil
 }
 
+// changed is a condition true when column c of the source row differs from
+// the target row, NULL-safe: DECODE treats two NULLs as equal. CLOBs cannot
+// go through DECODE and are compared with DBMS_LOB.COMPARE.
+func changed(c string, t dynamic.DataType) string {
+	if t == dynamic.Clob {
+		return fmt.Sprintf("(NVL(DBMS_LOB.COMPARE(t.%s, s.%s), 1) <> 0 AND (t.%s IS NOT NULL OR s.%s IS NOT NULL))", c, c, c, c)
+	}
+	return fmt.Sprintf("DECODE(t.%s, s.%s, 0, 1) = 1", c, c)
+}
+
+// readCSV reads all non-empty records with cells trimmed, traced as one span
+func readCSV(ctx context.Context, csvPath string) (rows [][]string, err error) {
+	_, span := tracing.Start(ctx, tracing.SpanCSVRead, tracing.String(tracing.AttrFile, csvPath))
+	defer func() {
+		span.SetAttributes(tracing.Int(tracing.AttrRows, len(rows)))
+		span.End(err)
+	}()
+
+	f, err := csvfile.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("open csv: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+
+	rows = make([][]string, 0, 128)
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+		for i := range rec {
+			rec[i] = strings.TrimSpace(rec[i])
+		}
+		// skip empty rows
+		empty := true
+		for _, v := range rec {
+			if v != "" {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			continue
+		}
+		rows = append(rows, rec)
+	}
+	return rows, nil
+}
+
 // normalizeIdentifierForOracle converts a string into a valid Oracle unquoted identifier:
 // - Uppercases
 // - Replaces invalid characters with underscore
@@ -0,0 +1,342 @@
+package csvdbappend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+	"sql-learn2/logging"
+)
+
+var quiet = logging.WithLogger(context.Background(), logging.Discard())
+
+func TestUpsertCSVToDB_SQL(t *testing.T) {
+	const (
+		mergeAll = "MERGE INTO STOCK t USING (SELECT :1 AS ID, :2 AS NAME, :3 AS QTY FROM DUAL) s ON (t.ID = s.ID) " +
+			"WHEN MATCHED THEN UPDATE SET t.NAME = s.NAME, t.QTY = s.QTY " +
+			"WHEN NOT MATCHED THEN INSERT (ID, NAME, QTY) VALUES (s.ID, s.NAME, s.QTY)"
+		// every column is a key, so there is nothing to update
+		mergeKeys = "MERGE INTO T t USING (SELECT :1 AS ID, :2 AS NAME FROM DUAL) s ON (t.ID = s.ID AND t.NAME = s.NAME) " +
+			"WHEN NOT MATCHED THEN INSERT (ID, NAME) VALUES (s.ID, s.NAME)"
+		mergeUpdate = "MERGE INTO STOCK t USING (SELECT :1 AS ID, :2 AS NAME, :3 AS QTY FROM DUAL) s ON (t.ID = s.ID) " +
+			"WHEN MATCHED THEN UPDATE SET t.NAME = s.NAME, t.QTY = s.QTY"
+		mergeInsert = "MERGE INTO STOCK t USING (SELECT :1 AS ID, :2 AS NAME, :3 AS QTY FROM DUAL) s ON (t.ID = s.ID) " +
+			"WHEN NOT MATCHED THEN INSERT (ID, NAME, QTY) VALUES (s.ID, s.NAME, s.QTY)"
+		mergeChanged = "MERGE INTO STOCK t USING (SELECT :1 AS ID, :2 AS NAME, :3 AS QTY FROM DUAL) s ON (t.ID = s.ID) " +
+			"WHEN MATCHED THEN UPDATE SET t.NAME = s.NAME, t.QTY = s.QTY " +
+			"WHERE DECODE(t.NAME, s.NAME, 0, 1) = 1 OR DECODE(t.QTY, s.QTY, 0, 1) = 1 " +
+			"WHEN NOT MATCHED THEN INSERT (ID, NAME, QTY) VALUES (s.ID, s.NAME, s.QTY)"
+		mergeNulls = "MERGE INTO STOCK t USING (SELECT :1 AS ID, :2 AS NAME, :3 AS QTY FROM DUAL) s ON (t.ID = s.ID) " +
+			"WHEN MATCHED THEN UPDATE SET t.NAME = COALESCE(s.NAME, t.NAME), t.QTY = COALESCE(s.QTY, t.QTY)"
+		mergeNullsChanged = mergeNulls + " WHERE (s.NAME IS NOT NULL AND DECODE(t.NAME, s.NAME, 0, 1) = 1) " +
+			"OR (s.QTY IS NOT NULL AND DECODE(t.QTY, s.QTY, 0, 1) = 1)"
+		mergeSeq = "MERGE INTO STOCK t USING (SELECT :1 AS ID, :2 AS NAME, :3 AS QTY FROM DUAL) s ON (t.ID = s.ID) " +
+			"WHEN MATCHED THEN UPDATE SET t.NAME = s.NAME, t.QTY = s.QTY " +
+			"WHEN NOT MATCHED THEN INSERT (ID, NAME, QTY, STOCK_ID) VALUES (s.ID, s.NAME, s.QTY, STOCK_SEQ.NEXTVAL)"
+		mergeNote = "MERGE INTO STOCK t USING (SELECT :1 AS ID, :2 AS NOTE FROM DUAL) s ON (t.ID = s.ID) " +
+			"WHEN MATCHED THEN UPDATE SET t.NOTE = s.NOTE " +
+			"WHERE (NVL(DBMS_LOB.COMPARE(t.NOTE, s.NOTE), 1) <> 0 AND (t.NOTE IS NOT NULL OR s.NOTE IS NOT NULL))"
+	)
+	row := []any{
+		[]sql.NullInt64{{Int64: 1, Valid: true}},
+		[]sql.NullString{{String: "a", Valid: true}},
+		[]sql.NullInt64{{Int64: 2, Valid: true}},
+	}
+	tests := []struct {
+		name  string
+		table string
+		keys  []string
+		opts  Options
+		lines []string
+		want  []sqlfake.Call
+	}{
+		{
+			name:  "update and insert",
+			keys:  []string{"id"},
+			lines: []string{"id,name,qty", "NUMBER,VARCHAR2,NUMBER", "1,a,1.5", "2,,"},
+			want: []sqlfake.Call{{Query: mergeAll, Args: []any{
+				[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}},
+				[]sql.NullString{{String: "a", Valid: true}, {}},
+				[]sql.NullFloat64{{Float64: 1.5, Valid: true}, {}},
+			}}},
+		},
+		{
+			name:  "mixed numbers bound as text",
+			keys:  []string{"id"},
+			lines: []string{"id,name,qty", "NUMBER,VARCHAR2,NUMBER", "1,a,9007199254740993", "2,b,2.5"},
+			want: []sqlfake.Call{{Query: mergeAll, Args: []any{
+				[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}},
+				[]sql.NullString{{String: "a", Valid: true}, {String: "b", Valid: true}},
+				[]sql.NullString{{String: "9007199254740993", Valid: true}, {String: "2.5", Valid: true}},
+			}}},
+		},
+		{
+			name:  "all key columns",
+			table: "t",
+			keys:  []string{"ID", "name"},
+			lines: []string{"id,name", "NUMBER,VARCHAR2", "1,a"},
+			want: []sqlfake.Call{{Query: mergeKeys, Args: []any{
+				[]sql.NullInt64{{Int64: 1, Valid: true}},
+				[]sql.NullString{{String: "a", Valid: true}},
+			}}},
+		},
+		{
+			name:  "null markers",
+			keys:  []string{"id"},
+			opts:  Options{NullValues: []string{"NULL", `\N`}},
+			lines: []string{"id,name,qty", "NUMBER,VARCHAR2,NUMBER", `1,\N,NULL`, "2,null,3"},
+			want: []sqlfake.Call{{Query: mergeAll, Args: []any{
+				[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}},
+				[]sql.NullString{{}, {String: "null", Valid: true}},
+				[]sql.NullInt64{{}, {Int64: 3, Valid: true}},
+			}}},
+		},
+		{
+			name:  "update only",
+			keys:  []string{"id"},
+			opts:  Options{Mode: MergeUpdateOnly},
+			lines: []string{"id,name,qty", "NUMBER,VARCHAR2,NUMBER", "1,a,2"},
+			want:  []sqlfake.Call{{Query: mergeUpdate, Args: row}},
+		},
+		{
+			name:  "insert only",
+			keys:  []string{"id"},
+			opts:  Options{Mode: MergeInsertOnly},
+			lines: []string{"id,name,qty", "NUMBER,VARCHAR2,NUMBER", "1,a,2"},
+			want:  []sqlfake.Call{{Query: mergeInsert, Args: row}},
+		},
+		{
+			name:  "skip unchanged",
+			keys:  []string{"id"},
+			opts:  Options{SkipUnchanged: true},
+			lines: []string{"id,name,qty", "NUMBER,VARCHAR2,NUMBER", "1,a,2"},
+			want:  []sqlfake.Call{{Query: mergeChanged, Args: row}},
+		},
+		{
+			name:  "skip unchanged clob",
+			keys:  []string{"id"},
+			opts:  Options{Mode: MergeUpdateOnly, SkipUnchanged: true},
+			lines: []string{"id,note", "NUMBER,CLOB", "1,a"},
+			want: []sqlfake.Call{{Query: mergeNote, Args: []any{
+				[]sql.NullInt64{{Int64: 1, Valid: true}},
+				[]sql.NullString{{String: "a", Valid: true}},
+			}}},
+		},
+		{
+			name:  "skip nulls",
+			keys:  []string{"id"},
+			opts:  Options{Mode: MergeUpdateOnly, SkipNulls: true},
+			lines: []string{"id,name,qty", "NUMBER,VARCHAR2,NUMBER", "1,a,2"},
+			want:  []sqlfake.Call{{Query: mergeNulls, Args: row}},
+		},
+		{
+			name:  "skip nulls and unchanged",
+			keys:  []string{"id"},
+			opts:  Options{Mode: MergeUpdateOnly, SkipNulls: true, SkipUnchanged: true},
+			lines: []string{"id,name,qty", "NUMBER,VARCHAR2,NUMBER", "1,a,2"},
+			want:  []sqlfake.Call{{Query: mergeNullsChanged, Args: row}},
+		},
+		{
+			name:  "surrogate key from a sequence",
+			keys:  []string{"id"},
+			opts:  Options{SurrogateKey: "stock_id", SurrogateSequence: "STOCK_SEQ"},
+			lines: []string{"id,name,qty", "NUMBER,VARCHAR2,NUMBER", "1,a,2"},
+			want:  []sqlfake.Call{{Query: mergeSeq, Args: row}},
+		},
+		{
+			name:  "surrogate key from an identity",
+			keys:  []string{"id"},
+			opts:  Options{SurrogateKey: "stock_id"},
+			lines: []string{"id,name,qty", "NUMBER,VARCHAR2,NUMBER", "1,a,2"},
+			want:  []sqlfake.Call{{Query: mergeAll, Args: row}},
+		},
+		{
+			name:  "batches",
+			keys:  []string{"id"},
+			opts:  Options{BatchSize: 2},
+			lines: []string{"id,name,qty", "NUMBER,VARCHAR2,NUMBER", "1,a,1", "2,b,2", "3,c,3"},
+			want: []sqlfake.Call{
+				{Query: mergeAll, Args: []any{
+					[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}},
+					[]sql.NullString{{String: "a", Valid: true}, {String: "b", Valid: true}},
+					[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}},
+				}},
+				{Query: mergeAll, Args: []any{
+					[]sql.NullInt64{{Int64: 3, Valid: true}},
+					[]sql.NullString{{String: "c", Valid: true}},
+					[]sql.NullInt64{{Int64: 3, Valid: true}},
+				}},
+			},
+		},
+		{
+			name:  "no data rows",
+			keys:  []string{"id"},
+			lines: []string{"id,name,qty", "NUMBER,VARCHAR2,NUMBER"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFake(t)
+			path := testharness.WriteCSV(t, "stock.csv", tt.lines...)
+			if _, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, tt.table, tt.keys, tt.opts); err != nil {
+				t.Fatalf("UpsertCSVToDBWithOptions: %v", err)
+			}
+			if got := mergeCalls(f); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calls:\n got %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpsertCSVToDB_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		lines   []string
+		fail    string
+		wantErr string
+	}{
+		{"no keys or primary key", nil, []string{"id", "NUMBER"}, "", "no key columns given: table T has no primary key"},
+		{"unknown key", []string{"code"}, []string{"id", "NUMBER"}, "", "key column CODE not found"},
+		{"short types row", []string{"id"}, []string{"id,name", "NUMBER"}, "", "types row has fewer cells"},
+		{"merge fails", []string{"id"}, []string{"id", "NUMBER", "1", "2"}, "^MERGE", "merge rows 3-4: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFake(t)
+			if tt.fail != "" {
+				f.Fail(tt.fail, errors.New("boom"))
+			}
+			path := testharness.WriteCSV(t, "t.csv", tt.lines...)
+			_, err := UpsertCSVToDB(quiet, f.DB, path, "", tt.keys)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpsertCSVToDB_PrimaryKey(t *testing.T) {
+	f := newFake(t)
+	f.OnQuery("USER_CONS_COLUMNS", []string{"COLUMN_NAME"}, []any{"ID"})
+	path := testharness.WriteCSV(t, "stock.csv", "id,name", "NUMBER,VARCHAR2", "1,a")
+	if _, err := UpsertCSVToDB(quiet, f.DB, path, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "MERGE INTO STOCK t USING (SELECT :1 AS ID, :2 AS NAME FROM DUAL) s ON (t.ID = s.ID) " +
+		"WHEN MATCHED THEN UPDATE SET t.NAME = s.NAME " +
+		"WHEN NOT MATCHED THEN INSERT (ID, NAME) VALUES (s.ID, s.NAME)"
+	if q := f.Queries(); !slices.Contains(q, want) {
+		t.Errorf("queries = %q, want the MERGE on ID", q)
+	}
+}
+
+func TestUpsertCSVToDB_SurrogateKeyErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		opts    Options
+		wantErr string
+	}{
+		{"no natural key", nil, Options{SurrogateKey: "sid"}, "needs the natural key columns"},
+		{"in the csv", []string{"code"}, Options{SurrogateKey: "id"}, "surrogate key ID must not be a CSV column"},
+		{"sequence alone", []string{"code"}, Options{SurrogateSequence: "S"}, "SurrogateSequence needs SurrogateKey"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFake(t)
+			path := testharness.WriteCSV(t, "t.csv", "id,code", "NUMBER,VARCHAR2", "1,a")
+			_, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, "", tt.keys, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpsertCSVToDB_UpdateOnlyAllKeys(t *testing.T) {
+	f := newFake(t)
+	path := testharness.WriteCSV(t, "t.csv", "id", "NUMBER", "1")
+	_, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, "", []string{"id"}, Options{Mode: MergeUpdateOnly})
+	if err == nil || !strings.Contains(err.Error(), "needs a column that is not a key") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestParseMergeMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    MergeMode
+		wantErr bool
+	}{
+		{"", MergeUpsert, false},
+		{"Upsert", MergeUpsert, false},
+		{"update", MergeUpdateOnly, false},
+		{"insert-only", MergeInsertOnly, false},
+		{"delete", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMergeMode(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMergeMode(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestUpsertCSVToDB_Result(t *testing.T) {
+	lines := []string{"id,name", "NUMBER,VARCHAR2", "1,a", "2,b", "3,c"}
+	tests := []struct {
+		name   string
+		mode   MergeMode
+		merged int64
+		want   Result
+	}{
+		{"upsert", MergeUpsert, 3, Result{Rows: 3, Merged: 3, Inserted: 2, Updated: 1}},
+		{"update only", MergeUpdateOnly, 2, Result{Rows: 3, Merged: 2, Updated: 2, Skipped: 1}},
+		{"insert only", MergeInsertOnly, 1, Result{Rows: 3, Merged: 1, Inserted: 1, Skipped: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFake(t)
+			f.OnExec("^MERGE", tt.merged)
+			onCount(f, 1) // one key exists
+			path := testharness.WriteCSV(t, "stock.csv", lines...)
+			res, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, "", []string{"id"}, Options{Mode: tt.mode})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Duration <= 0 {
+				t.Errorf("Duration = %v", res.Duration)
+			}
+			res.Duration, tt.want.Table = 0, "STOCK"
+			if !reflect.DeepEqual(res, tt.want) {
+				t.Errorf("result = %+v, want %+v", res, tt.want)
+			}
+		})
+	}
+}
+
+// newFake is a sqlfake.Recorder on which a row-wise upsert finds none of
+// the keys it counts before each batch
+func newFake(t *testing.T) *sqlfake.Recorder {
+	f := sqlfake.New(t)
+	onCount(f, 0)
+	return f
+}
+
+// mergeCalls are the MERGE statements f ran, without the counts before them
+func mergeCalls(f *sqlfake.Recorder) []sqlfake.Call {
+	var merges []sqlfake.Call
+	for _, c := range f.Calls() {
+		if strings.HasPrefix(c.Query, "MERGE") {
+			merges = append(merges, c)
+		}
+	}
+	return merges
+}
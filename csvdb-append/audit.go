@@ -0,0 +1,231 @@
+package csvdbappend
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"sql-learn2/dynamic"
+)
+
+// Actions recorded in an audit
+const (
+	auditInsert = "INSERT"
+	auditUpdate = "UPDATE"
+	auditDelete = "DELETE" // by Options.Sync
+	auditFlag   = "FLAG"   // by Options.Sync with SyncFlagColumn
+)
+
+// Change is one changed column of one row, as an audit or a dry run
+// reports it; values are rendered as text
+type Change struct {
+	Action string
+	Key    string // the key columns, e.g. "ID=1, CODE=A"
+	Column string // empty for DELETE and FLAG
+	Old    sql.NullString
+	New    sql.NullString
+}
+
+// audit diffs the CSV rows against the rows the table had before the merge
+type audit struct {
+	keys      []string
+	keyIdx    []int
+	keyTypes  []dynamic.DataType
+	cols      []string
+	types     []dynamic.DataType
+	mode      MergeMode
+	skipNulls bool             // Options.SkipNulls
+	before    map[string][]any // by keyOf of the key values
+	changes   []Change
+	limit     int // keep at most this many changes when > 0
+}
+
+// snapshotAudit reads the CSV columns of every row of table
+func snapshotAudit(ctx context.Context, db *sql.DB, table string, cols []string, types []dynamic.DataType, keys []string, keyIdx []int, opts Options) (*audit, error) {
+	a := &audit{keys: keys, keyIdx: keyIdx, cols: cols, types: types, mode: opts.Mode, skipNulls: opts.SkipNulls, before: map[string][]any{}}
+	for _, c := range keyIdx {
+		a.keyTypes = append(a.keyTypes, types[c])
+	}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s", strings.Join(cols, ", "), table))
+	if err != nil {
+		return nil, fmt.Errorf("audit: read %s: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		vals := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("audit: read %s: %w", table, err)
+		}
+		a.before[keyOf(a.keyVals(vals))] = vals
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("audit: read %s: %w", table, err)
+	}
+	return a, nil
+}
+
+func (a *audit) keyVals(vals []any) []any {
+	kv := make([]any, len(a.keyIdx))
+	for i, c := range a.keyIdx {
+		kv[i] = vals[c]
+	}
+	return kv
+}
+
+// keyText renders the key values of a row as "ID=1, CODE=A"
+func keyText(keys []string, types []dynamic.DataType, kv []any) string {
+	parts := make([]string, len(kv))
+	for i, v := range kv {
+		parts[i] = keys[i] + "=" + auditText(types[i], v).String
+	}
+	return strings.Join(parts, ", ")
+}
+
+// row records what merging vals changes and returns the action, or ""
+// when the row is left as it is. A key repeated in the CSV is compared
+// with its previous row, as the MERGE applies them in order.
+func (a *audit) row(vals []any) string {
+	kv := a.keyVals(vals)
+	key := keyOf(kv)
+	old, matched := a.before[key]
+	if matched && a.mode == MergeInsertOnly || !matched && a.mode == MergeUpdateOnly {
+		return ""
+	}
+	action := auditUpdate
+	if !matched {
+		action = auditInsert
+	}
+	isKey := make(map[int]bool, len(a.keyIdx))
+	for _, c := range a.keyIdx {
+		isKey[c] = true
+	}
+	applied := append([]any(nil), vals...)
+	changed := false
+	for i, col := range a.cols {
+		if isKey[i] {
+			continue
+		}
+		if matched && a.skipNulls && vals[i] == nil {
+			applied[i] = old[i]
+			continue
+		}
+		c := Change{Action: action, Key: keyText(a.keys, a.keyTypes, kv), Column: col, New: auditText(a.types[i], vals[i])}
+		if matched {
+			if c.Old = auditText(a.types[i], old[i]); c.Old == c.New {
+				continue
+			}
+		}
+		a.add(c)
+		changed = true
+	}
+	if !matched && len(a.keyIdx) == len(a.cols) {
+		a.add(Change{Action: action, Key: keyText(a.keys, a.keyTypes, kv)})
+	}
+	a.before[key] = applied
+	if matched && !changed {
+		return ""
+	}
+	return action
+}
+
+// removed records a row Options.Sync deleted or flagged
+func (a *audit) removed(action string, kv []any) {
+	a.add(Change{Action: action, Key: keyText(a.keys, a.keyTypes, kv)})
+}
+
+func (a *audit) add(c Change) {
+	if a.limit <= 0 || len(a.changes) < a.limit {
+		a.changes = append(a.changes, c)
+	}
+}
+
+// auditText renders v as the audit stores it; NUMBERs the driver returns
+// as text and the CSV cells converted to int64 or float64 render alike
+func auditText(t dynamic.DataType, v any) sql.NullString {
+	if t == dynamic.Number {
+		v = syncValue(t, v)
+	}
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return sql.NullString{}
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case time.Time:
+		if h, m, sec := v.Clock(); h == 0 && m == 0 && sec == 0 && v.Nanosecond() == 0 {
+			s = v.Format(time.DateOnly)
+		} else {
+			s = v.Format(time.DateTime)
+		}
+	default:
+		s = fmt.Sprint(v)
+	}
+	return sql.NullString{String: s, Valid: true}
+}
+
+// writeAuditFile writes changes as a CSV with a header row; NULLs are empty
+func writeAuditFile(path, table string, changes []Change) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create audit file: %w", err)
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"TABLE_NAME", "ACTION", "KEY_VALUE", "COLUMN_NAME", "OLD_VALUE", "NEW_VALUE"})
+	for _, c := range changes {
+		w.Write([]string{table, c.Action, c.Key, c.Column, c.Old.String, c.New.String})
+	}
+	w.Flush()
+	err = w.Error()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write audit file: %w", err)
+	}
+	return nil
+}
+
+// writeAuditTable inserts changes into auditTable (see migrations
+// 0008_upsert_audit.sql) in array-bound batches of batchSize
+func writeAuditTable(ctx context.Context, db *sql.DB, auditTable, table string, changes []Change, batchSize int) error {
+	stmt, err := db.PrepareContext(ctx, fmt.Sprintf(
+		"INSERT INTO %s (TABLE_NAME, ACTION, KEY_VALUE, COLUMN_NAME, OLD_VALUE, NEW_VALUE) VALUES (:1, :2, :3, :4, :5, :6)", auditTable))
+	if err != nil {
+		return fmt.Errorf("audit: prepare: %w", err)
+	}
+	defer stmt.Close()
+	for start := 0; start < len(changes); start += batchSize {
+		part := changes[start:min(start+batchSize, len(changes))]
+		args := make([][]sql.NullString, 6)
+		for i := range args {
+			args[i] = make([]sql.NullString, len(part))
+		}
+		for j, c := range part {
+			args[0][j] = sql.NullString{String: table, Valid: true}
+			args[1][j] = sql.NullString{String: c.Action, Valid: true}
+			args[2][j] = sql.NullString{String: c.Key, Valid: true}
+			args[3][j] = sql.NullString{String: c.Column, Valid: c.Column != ""}
+			args[4][j] = c.Old
+			args[5][j] = c.New
+		}
+		if _, err := stmt.ExecContext(ctx, args[0], args[1], args[2], args[3], args[4], args[5]); err != nil {
+			return fmt.Errorf("audit: insert into %s: %w", auditTable, err)
+		}
+	}
+	return nil
+}
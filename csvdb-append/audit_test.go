@@ -0,0 +1,134 @@
+package csvdbappend
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sql-learn2/dynamic"
+	"sql-learn2/internal/testharness"
+)
+
+func TestUpsertCSVToDB_Audit(t *testing.T) {
+	f := newFake(t)
+	f.OnQuery(`^SELECT ID, NAME, QTY FROM STOCK`, []string{"ID", "NAME", "QTY"},
+		[]any{"1", "a", "2"}, []any{"9", "z", nil})
+	f.OnQuery(`^SELECT ID FROM STOCK`, []string{"ID"}, []any{"1"}, []any{"9"})
+	path := testharness.WriteCSV(t, "stock.csv", "id,name,qty", "NUMBER,VARCHAR2,NUMBER",
+		"1,a,3", "2,b,", "1,c,3")
+	auditFile := filepath.Join(t.TempDir(), "audit.csv")
+	res, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, "", []string{"id"},
+		Options{Sync: true, AuditFile: auditFile, AuditTable: "UPSERT_AUDIT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Audited != 5 {
+		t.Errorf("Audited = %d, want 5", res.Audited)
+	}
+
+	data, err := os.ReadFile(auditFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFile := strings.Join([]string{
+		"TABLE_NAME,ACTION,KEY_VALUE,COLUMN_NAME,OLD_VALUE,NEW_VALUE",
+		"STOCK,UPDATE,ID=1,QTY,2,3",
+		"STOCK,INSERT,ID=2,NAME,,b",
+		"STOCK,INSERT,ID=2,QTY,,",
+		"STOCK,UPDATE,ID=1,NAME,a,c",
+		"STOCK,DELETE,ID=9,,,",
+	}, "\n") + "\n"
+	if string(data) != wantFile {
+		t.Errorf("audit file:\n%s\nwant\n%s", data, wantFile)
+	}
+
+	str := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	var got []any
+	for _, c := range f.Calls() {
+		if strings.HasPrefix(c.Query, "INSERT INTO UPSERT_AUDIT") {
+			got = c.Args
+		}
+	}
+	want := []any{
+		[]sql.NullString{str("STOCK"), str("STOCK"), str("STOCK"), str("STOCK"), str("STOCK")},
+		[]sql.NullString{str("UPDATE"), str("INSERT"), str("INSERT"), str("UPDATE"), str("DELETE")},
+		[]sql.NullString{str("ID=1"), str("ID=2"), str("ID=2"), str("ID=1"), str("ID=9")},
+		[]sql.NullString{str("QTY"), str("NAME"), str("QTY"), str("NAME"), {}},
+		[]sql.NullString{str("2"), {}, {}, str("a"), {}},
+		[]sql.NullString{str("3"), str("b"), {}, str("c"), {}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("audit insert args:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestUpsertCSVToDB_AuditModes(t *testing.T) {
+	tests := []struct {
+		mode MergeMode
+		want []Change
+	}{
+		{MergeUpdateOnly, []Change{{Action: "UPDATE", Key: "ID=1", Column: "NAME", Old: sql.NullString{String: "a", Valid: true}, New: sql.NullString{String: "b", Valid: true}}}},
+		{MergeInsertOnly, []Change{{Action: "INSERT", Key: "ID=2", Column: "NAME", New: sql.NullString{String: "c", Valid: true}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			f := newFake(t)
+			f.OnQuery(`^SELECT ID, NAME FROM T`, []string{"ID", "NAME"}, []any{int64(1), "a"})
+			a, err := snapshotAudit(quiet, f.DB, "T", []string{"ID", "NAME"},
+				[]dynamic.DataType{dynamic.Number, dynamic.Varchar2}, []string{"ID"}, []int{0}, Options{Mode: tt.mode})
+			if err != nil {
+				t.Fatal(err)
+			}
+			a.row([]any{int64(1), "b"})
+			a.row([]any{int64(2), "c"})
+			if !reflect.DeepEqual(a.changes, tt.want) {
+				t.Errorf("changes = %+v, want %+v", a.changes, tt.want)
+			}
+		})
+	}
+}
+
+func TestAudit_SkipNulls(t *testing.T) {
+	f := newFake(t)
+	f.OnQuery(`^SELECT ID, NAME, QTY FROM T`, []string{"ID", "NAME", "QTY"}, []any{int64(1), "a", int64(5)})
+	a, err := snapshotAudit(quiet, f.DB, "T", []string{"ID", "NAME", "QTY"},
+		[]dynamic.DataType{dynamic.Number, dynamic.Varchar2, dynamic.Number}, []string{"ID"}, []int{0}, Options{SkipNulls: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.row([]any{int64(1), nil, int64(6)})
+	a.row([]any{int64(1), "b", nil}) // QTY stays 6
+	a.row([]any{int64(1), nil, int64(6)})
+	want := []Change{
+		{Action: "UPDATE", Key: "ID=1", Column: "QTY", Old: sql.NullString{String: "5", Valid: true}, New: sql.NullString{String: "6", Valid: true}},
+		{Action: "UPDATE", Key: "ID=1", Column: "NAME", Old: sql.NullString{String: "a", Valid: true}, New: sql.NullString{String: "b", Valid: true}},
+	}
+	if !reflect.DeepEqual(a.changes, want) {
+		t.Errorf("changes = %+v, want %+v", a.changes, want)
+	}
+}
+
+func TestAuditText(t *testing.T) {
+	tests := []struct {
+		typ  dynamic.DataType
+		v    any
+		want sql.NullString
+	}{
+		{dynamic.Number, nil, sql.NullString{}},
+		{dynamic.Number, int64(7), sql.NullString{String: "7", Valid: true}},
+		{dynamic.Number, "1.50", sql.NullString{String: "1.5", Valid: true}},
+		{dynamic.Number, float64(2), sql.NullString{String: "2", Valid: true}},
+		{dynamic.Varchar2, []byte("x"), sql.NullString{String: "x", Valid: true}},
+		{dynamic.Date, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), sql.NullString{String: "2024-05-01", Valid: true}},
+		{dynamic.Timestamp, time.Date(2024, 5, 1, 13, 4, 5, 0, time.UTC), sql.NullString{String: "2024-05-01 13:04:05", Valid: true}},
+	}
+	for _, tt := range tests {
+		if got := auditText(tt.typ, tt.v); got != tt.want {
+			t.Errorf("auditText(%s, %#v) = %+v, want %+v", tt.typ, tt.v, got, tt.want)
+		}
+	}
+}
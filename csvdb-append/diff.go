@@ -0,0 +1,80 @@
+package csvdbappend
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"slices"
+
+	"sql-learn2/dynamic"
+	"sql-learn2/logging"
+)
+
+// DefaultDiffSample is how many changes a dry run lists when
+// Options.DiffSample is 0
+const DefaultDiffSample = 20
+
+// Diff is what an upsert would do, returned instead of merging with
+// Options.DryRun
+type Diff struct {
+	Inserts   int      // rows the MERGE would insert
+	Updates   int      // rows it would update
+	Unchanged int      // rows it would leave as they are
+	Deletes   int      // rows Options.Sync would delete or flag
+	Sample    []Change // the first changed columns, at most Options.DiffSample
+}
+
+// diffRows compares the rows next reads with the table as the audit does,
+// without writing anything
+func diffRows(ctx context.Context, db *sql.DB, table string, next rowReader, cols []string, types []dynamic.DataType,
+	keys []string, keyIdx []int, opts Options) (*Diff, error) {
+	a, err := snapshotAudit(ctx, db, table, cols, types, keys, keyIdx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if a.limit = opts.DiffSample; a.limit <= 0 {
+		a.limit = DefaultDiffSample
+	}
+	d := &Diff{}
+	csvKeys := keySet{}
+	vals := make([]any, len(cols))
+	rows := 0
+	for {
+		if _, err := next(vals); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		rows++
+		csvKeys[keyOf(a.keyVals(vals))] = true
+		switch a.row(vals) {
+		case auditInsert:
+			d.Inserts++
+		case auditUpdate:
+			d.Updates++
+		default:
+			d.Unchanged++
+		}
+	}
+	if opts.Sync {
+		action := auditDelete
+		if opts.SyncFlagColumn != "" {
+			action = auditFlag
+		}
+		var missing []string
+		for key := range a.before {
+			if !csvKeys[key] {
+				missing = append(missing, key)
+			}
+		}
+		slices.Sort(missing) // a stable sample
+		for _, key := range missing {
+			a.removed(action, a.keyVals(a.before[key]))
+		}
+		d.Deletes = len(missing)
+	}
+	d.Sample = a.changes
+	logging.FromContext(ctx).Info("Upsert dry run", logging.FieldTable, table, logging.FieldRows, rows,
+		"inserts", d.Inserts, "updates", d.Updates, "unchanged", d.Unchanged, "deletes", d.Deletes)
+	return d, nil
+}
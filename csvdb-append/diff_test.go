@@ -0,0 +1,68 @@
+package csvdbappend
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+)
+
+func TestUpsertCSVToDB_DryRun(t *testing.T) {
+	str := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	tests := []struct {
+		name string
+		opts Options
+		want Diff
+	}{
+		{
+			name: "upsert",
+			opts: Options{DryRun: true},
+			want: Diff{Inserts: 1, Updates: 1, Unchanged: 1, Sample: []Change{
+				{Action: "UPDATE", Key: "ID=2", Column: "QTY", Old: str("3"), New: str("4")},
+				{Action: "INSERT", Key: "ID=3", Column: "NAME", New: str("c")},
+				{Action: "INSERT", Key: "ID=3", Column: "QTY"},
+			}},
+		},
+		{
+			name: "sync with a short sample",
+			opts: Options{DryRun: true, Sync: true, DiffSample: 1},
+			want: Diff{Inserts: 1, Updates: 1, Unchanged: 1, Deletes: 1, Sample: []Change{
+				{Action: "UPDATE", Key: "ID=2", Column: "QTY", Old: str("3"), New: str("4")},
+			}},
+		},
+		{
+			name: "update only",
+			opts: Options{DryRun: true, Mode: MergeUpdateOnly},
+			want: Diff{Updates: 1, Unchanged: 2, Sample: []Change{
+				{Action: "UPDATE", Key: "ID=2", Column: "QTY", Old: str("3"), New: str("4")},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery(`^SELECT ID, NAME, QTY FROM STOCK`, []string{"ID", "NAME", "QTY"},
+				[]any{"1", "a", "2"}, []any{"2", "b", "3"}, []any{"9", "z", nil})
+			path := testharness.WriteCSV(t, "stock.csv", "id,name,qty", "NUMBER,VARCHAR2,NUMBER",
+				"1,a,2", "2,b,4", "3,c,")
+			res, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, "", []string{"id"}, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Diff == nil || !reflect.DeepEqual(*res.Diff, tt.want) {
+				t.Errorf("diff = %+v, want %+v", res.Diff, tt.want)
+			}
+			if res.Merged != 0 {
+				t.Errorf("Merged = %d, want 0", res.Merged)
+			}
+			for _, q := range f.Queries() {
+				if !strings.HasPrefix(q, "SELECT") {
+					t.Errorf("dry run ran %q", q)
+				}
+			}
+		})
+	}
+}
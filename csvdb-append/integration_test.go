@@ -0,0 +1,41 @@
+//go:build integration
+
+package csvdbappend
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"sql-learn2/internal/testharness"
+)
+
+// Run with: go test -tags integration ./csvdb-append (see internal/testharness for ORA_TEST_DSN)
+func TestMain(m *testing.M) { os.Exit(testharness.Main(m)) }
+
+func TestUpsertCSVToDB_Integration(t *testing.T) {
+	db := testharness.Oracle(t)
+	table := testharness.TableName("APPEND")
+	testharness.DropOnCleanup(t, db, table)
+	testharness.Exec(t, db,
+		"CREATE TABLE "+table+" (ID NUMBER PRIMARY KEY, NAME VARCHAR2(50), QTY NUMBER)",
+		"INSERT INTO "+table+" VALUES (1, 'old', 10)",
+		"INSERT INTO "+table+" VALUES (2, 'keep', 20)",
+	)
+
+	path := testharness.WriteCSV(t, "stock.csv",
+		"ID,NAME,QTY",
+		"NUMBER,VARCHAR2,NUMBER",
+		"1,new,11",
+		"3,added,30",
+	)
+	if _, err := UpsertCSVToDB(context.Background(), db, path, table, []string{"id"}); err != nil {
+		t.Fatalf("UpsertCSVToDB: %v", err)
+	}
+	got := testharness.Rows(t, db, "SELECT ID, NAME, QTY FROM "+table+" ORDER BY ID")
+	want := [][]string{{"1", "new", "11"}, {"2", "keep", "20"}, {"3", "added", "30"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+}
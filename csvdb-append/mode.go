@@ -0,0 +1,41 @@
+package csvdbappend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MergeMode says which WHEN clauses the MERGE has
+type MergeMode int
+
+const (
+	// MergeUpsert updates matched rows and inserts the others
+	MergeUpsert MergeMode = iota
+	// MergeUpdateOnly updates matched rows; rows without a match are skipped
+	MergeUpdateOnly
+	// MergeInsertOnly inserts rows without a match; matched rows are kept as they are
+	MergeInsertOnly
+)
+
+// ParseMergeMode parses the -merge-mode flag value: upsert, update or insert
+func ParseMergeMode(s string) (MergeMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "upsert":
+		return MergeUpsert, nil
+	case "update", "update-only":
+		return MergeUpdateOnly, nil
+	case "insert", "insert-only":
+		return MergeInsertOnly, nil
+	}
+	return 0, fmt.Errorf("unknown merge mode %q (use upsert, update or insert)", s)
+}
+
+func (m MergeMode) String() string {
+	switch m {
+	case MergeUpdateOnly:
+		return "update"
+	case MergeInsertOnly:
+		return "insert"
+	}
+	return "upsert"
+}
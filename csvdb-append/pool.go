@@ -0,0 +1,203 @@
+package csvdbappend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"sql-learn2/internal/connpool"
+	"sql-learn2/logging"
+	"sql-learn2/oraerr"
+	"sql-learn2/retry"
+)
+
+// mergeJob is one converted batch waiting to be merged
+type mergeJob struct {
+	first, last int // CSV lines of the first and last row, for errors
+	rows        int
+	args        []any
+	keys        []string // of each row, as keyText renders them
+	keyVals     [][]any  // of each row, when the pool counts new rows
+}
+
+// mergePool runs jobs on its own connections, one prepared statement and
+// one queue each: MERGE batches, or staging inserts (see Options.Staging). Rows are sharded by key (see shard), so all rows of a key
+// go to the same worker in CSV order and no two workers lock the same
+// rows. A batch failing on a lock is rerun as the retry policy allows; the
+// first failure cancels the others and wait reports every failure. Each
+// finished batch is logged with the throughput so far.
+type mergePool struct {
+	parent context.Context
+	table  string
+	verb   string // what the statement does, for errors
+	retry  retry.Policy
+	start  time.Time
+	ctx    context.Context // canceled on the first failure
+	cancel context.CancelFunc
+	queues []chan mergeJob
+	wg     sync.WaitGroup
+
+	// newRows, when set, counts the rows of a job its MERGE will insert,
+	// just before each attempt and on the same session
+	newRows func(ctx context.Context, conn *sql.Conn, j mergeJob) (int, error)
+
+	mu       sync.Mutex
+	batches  int      // finished, in the order they finished
+	done     int      // rows of the finished batches
+	merged   int      // rows the statements reported
+	inserted int      // of merged, the rows newRows counted
+	failed   []string // keys of the failed batches and those skipped after
+	errs     []error
+}
+
+// startMergePool opens workers connections and prepares query on each
+// before any row is sent, so a bad statement fails once and up front.
+// Without p.Retryable only lock errors are retried: a worker's connection
+// is pinned, so a network error would fail again.
+func startMergePool(ctx context.Context, db *sql.DB, table, verb, query string, workers int, p retry.Policy) (*mergePool, error) {
+	conns, err := connpool.Open(ctx, db, query, verb, workers)
+	if err != nil {
+		return nil, fmt.Errorf("prepare %s: %w", verb, err)
+	}
+	if p.Retryable == nil {
+		p.Retryable = lockError
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	pool := &mergePool{parent: parent, table: table, verb: verb, retry: p, start: time.Now(), ctx: ctx, cancel: cancel}
+	for _, w := range conns {
+		q := make(chan mergeJob, 1)
+		pool.queues = append(pool.queues, q)
+		pool.wg.Add(1)
+		go pool.work(ctx, w.Conn, w.Stmt, q)
+	}
+	return pool, nil
+}
+
+func (p *mergePool) work(ctx context.Context, conn *sql.Conn, stmt *sql.Stmt, jobs <-chan mergeJob) {
+	defer p.wg.Done()
+	defer conn.Close()
+	defer stmt.Close()
+	for j := range jobs {
+		if ctx.Err() != nil {
+			// queued behind a failure: only its keys are kept, for the report
+			p.mu.Lock()
+			p.failed = append(p.failed, j.keys...)
+			p.mu.Unlock()
+			continue
+		}
+		began := time.Now()
+		// only the attempt that succeeds reports its rows
+		n := int64(-1)
+		inserts := 0
+		err := retry.Do(ctx, p.retryFor(ctx, j), func(ctx context.Context) (err error) {
+			if p.newRows != nil {
+				if inserts, err = p.newRows(ctx, conn, j); err != nil {
+					return err
+				}
+			}
+			r, err := stmt.ExecContext(ctx, j.args...)
+			if err != nil {
+				return err
+			}
+			if n, err = r.RowsAffected(); err != nil {
+				n = -1
+			}
+			return nil
+		})
+		p.mu.Lock()
+		if err != nil {
+			if ctx.Err() == nil {
+				p.errs = append(p.errs, fmt.Errorf("%s rows %d-%d: %w", p.verb, j.first, j.last, err))
+			}
+			p.failed = append(p.failed, j.keys...)
+			p.cancel()
+			p.mu.Unlock()
+			continue
+		}
+		if n >= 0 {
+			p.merged += int(n)
+			// a writer may insert one of the keys meanwhile, turning it into an update
+			p.inserted += min(inserts, int(n))
+		}
+		p.batches++
+		p.done += j.rows
+		batch, done := p.batches, p.done
+		p.mu.Unlock()
+		elapsed := time.Since(p.start)
+		logging.FromContext(ctx).Info("Batch "+p.verb+"d", logging.FieldTable, p.table, logging.FieldBatch, batch,
+			logging.FieldRows, j.rows, "affected", n, "lines", fmt.Sprintf("%d-%d", j.first, j.last),
+			logging.FieldDuration, time.Since(began), "done", done, "elapsed", elapsed, "rows_per_sec", rowsPerSec(done, elapsed))
+	}
+}
+
+// rowsPerSec is the throughput of rows in d, rounded to whole rows
+func rowsPerSec(rows int, d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Round(float64(rows) / d.Seconds()))
+}
+
+// retryFor is the retry policy for j, logging each rerun unless the
+// caller's policy does
+func (p *mergePool) retryFor(ctx context.Context, j mergeJob) retry.Policy {
+	rp := p.retry
+	if rp.OnRetry == nil {
+		rp.OnRetry = func(attempt int, err error, wait time.Duration) {
+			logging.FromContext(ctx).Warn("Batch hit a lock, retrying", "verb", p.verb, "first", j.first, "last", j.last,
+				"attempt", attempt, "wait", wait, logging.FieldError, err)
+		}
+	}
+	return rp
+}
+
+// lockError reports whether err is a deadlock or lock timeout, which a
+// rerun of the same batch may get past
+func lockError(err error) bool {
+	return oraerr.Classify(err) == oraerr.LockTimeout
+}
+
+// workers is how many workers rows can be sharded across
+func (p *mergePool) workers() int {
+	return len(p.queues)
+}
+
+// submit queues j for worker w and reports false once the pool has failed
+func (p *mergePool) submit(w int, j mergeJob) bool {
+	select {
+	case p.queues[w] <- j:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// wait lets the workers finish the queued jobs and returns the rows the
+// statements reported
+func (p *mergePool) wait() (int, error) {
+	for _, q := range p.queues {
+		close(q)
+	}
+	p.wg.Wait()
+	p.cancel()
+	if len(p.errs) == 0 {
+		return p.merged, p.parent.Err() // canceled from outside
+	}
+	return p.merged, errors.Join(p.errs...)
+}
+
+// shard picks the worker for a row from its key, as keyOf renders it
+func shard(key string, workers int) int {
+	if workers <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(workers))
+}
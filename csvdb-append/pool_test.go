@@ -0,0 +1,219 @@
+package csvdbappend
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"sql-learn2/internal/testharness"
+	"sql-learn2/logging"
+	"sql-learn2/retry"
+)
+
+func TestUpsertCSVToDB_Workers(t *testing.T) {
+	lines := []string{"id,name", "NUMBER,VARCHAR2"}
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, strconv.Itoa(i)+",n"+strconv.Itoa(i))
+	}
+	f := newFake(t)
+	f.OnExec("^MERGE", 1)
+	path := testharness.WriteCSV(t, "t.csv", lines...)
+	res, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, "", []string{"id"}, Options{Workers: 3, BatchSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, c := range mergeCalls(f) {
+		batch := c.Args[0].([]sql.NullInt64)
+		w := shard(keyOf([]any{batch[0].Int64}), 3)
+		for i, id := range batch {
+			// every row of a batch belongs to one worker, in CSV order
+			if got := shard(keyOf([]any{id.Int64}), 3); got != w {
+				t.Errorf("id %d sharded to %d, batched for worker %d", id.Int64, got, w)
+			}
+			if i > 0 && id.Int64 < batch[i-1].Int64 {
+				t.Errorf("batch out of order: %v", batch)
+			}
+			ids = append(ids, id.Int64)
+		}
+		if len(batch) > 4 {
+			t.Errorf("batch of %d rows, want at most 4", len(batch))
+		}
+	}
+	slices.Sort(ids)
+	if len(ids) != 20 || ids[0] != 1 || ids[19] != 20 {
+		t.Errorf("merged ids = %v, want 1-20 once each", ids)
+	}
+	if res.Merged != len(mergeCalls(f)) {
+		t.Errorf("Merged = %d, want one per MERGE", res.Merged)
+	}
+}
+
+func TestUpsertCSVToDB_WorkersFail(t *testing.T) {
+	f := newFake(t)
+	f.Fail("^MERGE", errors.New("boom"))
+	path := testharness.WriteCSV(t, "t.csv", "id", "NUMBER", "1", "2", "3", "4")
+	_, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, "", []string{"id"}, Options{Workers: 2, BatchSize: 1})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("err = %v, want the merge failure", err)
+	}
+}
+
+func TestUpsertCSVToDB_FailedKeys(t *testing.T) {
+	lines := []string{"id", "NUMBER"}
+	for i := 1; i <= 40; i++ {
+		lines = append(lines, strconv.Itoa(i))
+	}
+	f := newFake(t)
+	f.OnExec("^MERGE", 1)
+	f.FailTimes("^MERGE", 1, errors.New("boom"))
+	path := testharness.WriteCSV(t, "t.csv", lines...)
+	res, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, "", []string{"id"}, Options{Workers: 2, BatchSize: 1})
+	if err == nil {
+		t.Fatal("want the merge failure")
+	}
+	// every row read is merged or listed: failed, skipped in a queue, or never sent
+	listed := make(map[string]bool)
+	for _, k := range res.FailedKeys {
+		if listed[k] {
+			t.Errorf("%s listed twice", k)
+		}
+		listed[k] = true
+	}
+	if res.Merged+len(listed) != res.Rows {
+		t.Errorf("%d merged and %d failed keys of %d rows read", res.Merged, len(listed), res.Rows)
+	}
+
+	// a bad row stops the upsert with the rows before it still batched
+	f = newFake(t)
+	path = testharness.WriteCSV(t, "t.csv", "id", "NUMBER", "1", "2", "x")
+	res, err = UpsertCSVToDBWithOptions(quiet, f.DB, path, "", []string{"id"}, Options{})
+	if err == nil {
+		t.Fatal("want the conversion error")
+	}
+	if want := []string{"ID=1", "ID=2"}; !slices.Equal(res.FailedKeys, want) {
+		t.Errorf("FailedKeys = %q, want %q", res.FailedKeys, want)
+	}
+}
+
+func TestUpsertCSVToDB_Retry(t *testing.T) {
+	deadlock := errors.New("ORA-00060: deadlock detected while waiting for resource")
+	tests := []struct {
+		name       string
+		policy     retry.Policy
+		fails      int
+		err        error
+		wantMerges int
+		wantMerged int
+		wantFailed []string
+	}{
+		{"recovers", retry.Policy{MaxAttempts: 3, Initial: time.Millisecond}, 2, deadlock, 3, 2, nil},
+		{"exhausted", retry.Policy{MaxAttempts: 2, Initial: time.Millisecond}, 5, deadlock, 2, 0, []string{"ID=1", "ID=2"}},
+		{"no policy", retry.Policy{}, 1, deadlock, 1, 0, []string{"ID=1", "ID=2"}},
+		{"not a lock", retry.Policy{MaxAttempts: 3, Initial: time.Millisecond}, 1,
+			errors.New("ORA-00001: unique constraint violated"), 1, 0, []string{"ID=1", "ID=2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFake(t)
+			f.OnExec("^MERGE", 2)
+			f.FailTimes("^MERGE", tt.fails, tt.err)
+			path := testharness.WriteCSV(t, "t.csv", "id", "NUMBER", "1", "2")
+			res, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, "", []string{"id"}, Options{Retry: tt.policy})
+			if (err != nil) != (tt.wantFailed != nil) {
+				t.Fatalf("err = %v", err)
+			}
+			if n := len(mergeCalls(f)); n != tt.wantMerges {
+				t.Errorf("%d MERGE attempts, want %d", n, tt.wantMerges)
+			}
+			if !slices.Equal(res.FailedKeys, tt.wantFailed) {
+				t.Errorf("FailedKeys = %q, want %q", res.FailedKeys, tt.wantFailed)
+			}
+			// the failed attempts are not counted
+			if res.Merged != tt.wantMerged {
+				t.Errorf("Merged = %d, want %d", res.Merged, tt.wantMerged)
+			}
+		})
+	}
+}
+
+func TestUpsertCSVToDB_BatchLog(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := logging.WithLogger(context.Background(), slog.New(slog.NewJSONHandler(&buf, nil)))
+	f := newFake(t)
+	f.OnExec("^MERGE", 2)
+	path := testharness.WriteCSV(t, "stock.csv", "id", "NUMBER", "1", "2", "3", "4", "5")
+	if _, err := UpsertCSVToDBWithOptions(ctx, f.DB, path, "", []string{"id"}, Options{BatchSize: 2}); err != nil {
+		t.Fatal(err)
+	}
+	var batches, rows []float64
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var rec map[string]any
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatal(err)
+		}
+		if rec["msg"] != "Batch merged" {
+			continue
+		}
+		if rec[logging.FieldTable] != "STOCK" || rec["affected"] != float64(2) {
+			t.Errorf("batch record = %v", rec)
+		}
+		if _, ok := rec["rows_per_sec"].(float64); !ok {
+			t.Errorf("batch record without rows_per_sec: %v", rec)
+		}
+		batches = append(batches, rec[logging.FieldBatch].(float64))
+		rows = append(rows, rec[logging.FieldRows].(float64))
+	}
+	if want := []float64{1, 2, 3}; !slices.Equal(batches, want) {
+		t.Errorf("batches logged = %v, want %v", batches, want)
+	}
+	if want := []float64{2, 2, 1}; !slices.Equal(rows, want) {
+		t.Errorf("rows logged = %v, want %v", rows, want)
+	}
+}
+
+func TestRowsPerSec(t *testing.T) {
+	tests := []struct {
+		rows int
+		d    time.Duration
+		want int
+	}{
+		{1000, 2 * time.Second, 500},
+		{10, 3 * time.Second, 3},
+		{5, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := rowsPerSec(tt.rows, tt.d); got != tt.want {
+			t.Errorf("rowsPerSec(%d, %v) = %d, want %d", tt.rows, tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestShard(t *testing.T) {
+	for _, workers := range []int{0, 1, 4} {
+		seen := make(map[int]bool)
+		for i := range 100 {
+			key := strconv.Itoa(i)
+			w := shard(key, workers)
+			if w != shard(key, workers) {
+				t.Fatalf("shard(%q, %d) is not stable", key, workers)
+			}
+			if w < 0 || w >= max(workers, 1) {
+				t.Fatalf("shard(%q, %d) = %d, out of range", key, workers, w)
+			}
+			seen[w] = true
+		}
+		if len(seen) != max(workers, 1) {
+			t.Errorf("%d workers: only %d used", workers, len(seen))
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package csvdbappend
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	bulkloadv3 "sql-learn2/bulk_load_v3"
+	"sql-learn2/dynamic"
+	"sql-learn2/loadhistory"
+)
+
+// UpsertSource merges the rows of a bulk_load_v3 Source into an existing
+// table, like UpsertCSVToDBWithOptions does with a CSV. cols name the
+// values Convert returns, in order; their types come from the table, so
+// the values can be nil, integers, floats, strings, time.Time for DATE and
+// TIMESTAMP columns, or driver.Valuers such as sql.NullString. Strings in
+// Options.NullValues are NULL. Rows are numbered from 1 in errors.
+func UpsertSource(ctx context.Context, db *sql.DB, src bulkloadv3.Source, tableName string, cols, keyCols []string, opts Options) (res Result, err error) {
+	if db == nil {
+		return res, errors.New("db is nil")
+	}
+	if src == nil {
+		return res, errors.New("src is nil")
+	}
+	if tableName = normalizeIdentifierForOracle(tableName); tableName == "" {
+		return res, errors.New("invalid table name")
+	}
+	if len(cols) == 0 {
+		return res, errors.New("cols must not be empty")
+	}
+	source := fmt.Sprintf("%T", src)
+	var run *loadhistory.Run
+	if !opts.DryRun {
+		ctx, run = loadhistory.Start(ctx, loadhistory.WorkflowUpsert, source, tableName)
+		defer func() { run.End(err) }()
+	}
+	began := time.Now()
+	defer func() { res.Duration = time.Since(began) }()
+	res.Table = tableName
+
+	if err := src.Validate(ctx); err != nil {
+		return res, fmt.Errorf("source validation failed: %w", err)
+	}
+	tableCols, err := dynamic.TableColumns(ctx, db, tableName, dynamic.CreateOptions{})
+	if err != nil {
+		return res, err
+	}
+	kinds := make(map[string]dynamic.DataType, len(tableCols))
+	for _, c := range tableCols {
+		kinds[c.Name] = c.Kind()
+	}
+	oracleCols := make([]string, len(cols))
+	colTypes := make([]dynamic.DataType, len(cols))
+	for i, c := range cols {
+		if oracleCols[i] = normalizeIdentifierForOracle(c); oracleCols[i] == "" {
+			return res, fmt.Errorf("invalid column name at position %d: %q", i+1, c)
+		}
+		t, ok := kinds[oracleCols[i]]
+		if !ok {
+			return res, fmt.Errorf("column %s not found in %s", oracleCols[i], tableName)
+		}
+		colTypes[i] = t
+	}
+
+	nulls := make(map[string]bool, len(opts.NullValues))
+	for _, v := range opts.NullValues {
+		nulls[v] = true
+	}
+	// a DATE column binds either time.Time or strings, so it must not mix them
+	timeCols := make([]*bool, len(cols))
+	row := 0
+	next := func(vals []any) (int, error) {
+		raw, err := src.Next(ctx)
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		row++
+		if err != nil {
+			return row, fmt.Errorf("row %d: read: %w", row, err)
+		}
+		values, err := src.Convert(raw)
+		if err != nil {
+			return row, fmt.Errorf("row %d: convert: %w", row, err)
+		}
+		if len(values) != len(cols) {
+			return row, fmt.Errorf("row %d: %d values for %d columns", row, len(values), len(cols))
+		}
+		for i, v := range values {
+			if vals[i], err = sourceValue(colTypes[i], v, nulls); err != nil {
+				return row, fmt.Errorf("row %d col %s: %w", row, oracleCols[i], err)
+			}
+			if vals[i] == nil || (colTypes[i] != dynamic.Date && colTypes[i] != dynamic.Timestamp) {
+				continue
+			}
+			_, isTime := vals[i].(time.Time)
+			if timeCols[i] == nil {
+				timeCols[i] = &isTime
+			} else if *timeCols[i] != isTime {
+				return row, fmt.Errorf("row %d col %s: mixes time.Time and string values", row, oracleCols[i])
+			}
+		}
+		return row, nil
+	}
+	return upsertRows(ctx, db, run, "source", source, tableName, oracleCols, colTypes, keyCols, -1, next, opts)
+}
+
+// sourceValue converts a value from a Source to what the merge binds: nil,
+// int64 or float64 for NUMBER, time.Time or a string for DATE and
+// TIMESTAMP, a string otherwise
+func sourceValue(t dynamic.DataType, v any, nulls map[string]bool) (any, error) {
+	if dv, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = dv.Value(); err != nil {
+			return nil, err
+		}
+	}
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+	if s, ok := v.(string); ok && nulls[s] {
+		return nil, nil
+	}
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case int:
+		return numberOr(t, int64(v)), nil
+	case int8:
+		return numberOr(t, int64(v)), nil
+	case int16:
+		return numberOr(t, int64(v)), nil
+	case int32:
+		return numberOr(t, int64(v)), nil
+	case int64:
+		return numberOr(t, v), nil
+	case uint8:
+		return numberOr(t, int64(v)), nil
+	case uint16:
+		return numberOr(t, int64(v)), nil
+	case uint32:
+		return numberOr(t, int64(v)), nil
+	case uint:
+		return uintValue(t, uint64(v))
+	case uint64:
+		return uintValue(t, v)
+	case float32:
+		return numberOr(t, float64(v)), nil
+	case float64:
+		return numberOr(t, v), nil
+	case bool:
+		if t == dynamic.Number {
+			return nil, fmt.Errorf("bool %v for a NUMBER column", v)
+		}
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		if t != dynamic.Date && t != dynamic.Timestamp {
+			return nil, fmt.Errorf("time.Time for a %s column", t)
+		}
+		return v, nil
+	case string:
+		if t != dynamic.Number {
+			return v, nil
+		}
+		vals := []any{nil}
+		if err := convertRow([]string{v}, []dynamic.DataType{t}, nil, 0, vals); err != nil {
+			return nil, fmt.Errorf("invalid NUMBER %q", v)
+		}
+		return vals[0], nil
+	}
+	return nil, fmt.Errorf("unsupported value %T", v)
+}
+
+// uintValue is numberOr for an unsigned integer, which NUMBER binds as int64
+func uintValue(t dynamic.DataType, n uint64) (any, error) {
+	if n > math.MaxInt64 {
+		return nil, fmt.Errorf("uint64 %d overflows int64", n)
+	}
+	return numberOr(t, int64(n)), nil
+}
+
+// numberOr keeps n for a NUMBER column and renders it as text for others
+func numberOr(t dynamic.DataType, n any) any {
+	if t == dynamic.Number {
+		return n
+	}
+	return fmt.Sprint(n)
+}
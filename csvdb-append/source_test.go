@@ -0,0 +1,145 @@
+package csvdbappend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sql-learn2/dynamic"
+	"sql-learn2/internal/sqlfake"
+)
+
+// sliceSource is a bulk_load_v3 Source over rows already converted
+type sliceSource struct {
+	rows        [][]any
+	validateErr error
+	convertErr  error
+	i           int
+}
+
+func (s *sliceSource) Validate(context.Context) error { return s.validateErr }
+
+func (s *sliceSource) Next(context.Context) (any, error) {
+	if s.i == len(s.rows) {
+		return nil, io.EOF
+	}
+	s.i++
+	return s.rows[s.i-1], nil
+}
+
+func (s *sliceSource) Convert(raw any) ([]any, error) {
+	if s.convertErr != nil {
+		return nil, s.convertErr
+	}
+	return raw.([]any), nil
+}
+
+// onTableColumns answers the column lookup of UpsertSource for table STOCK
+func onTableColumns(f *sqlfake.Recorder) {
+	f.OnQuery("USER_TAB_COLUMNS", []string{"COLUMN_NAME", "DATA_TYPE", "CHAR_LENGTH", "NULLABLE"},
+		[]any{"ID", "NUMBER", int64(0), "N"}, []any{"NAME", "VARCHAR2", int64(20), "Y"}, []any{"AT", "DATE", int64(0), "Y"})
+}
+
+func TestUpsertSource(t *testing.T) {
+	at := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	f := newFake(t)
+	onTableColumns(f)
+	f.OnExec("^MERGE", 2)
+	src := &sliceSource{rows: [][]any{
+		{1, "a", at},
+		{int32(2), sql.NullString{}, nil},
+	}}
+	res, err := UpsertSource(quiet, f.DB, src, "stock", []string{"id", "name", "at"}, []string{"id"}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []sqlfake.Call{{
+		Query: "MERGE INTO STOCK t USING (SELECT :1 AS ID, :2 AS NAME, :3 AS AT FROM DUAL) s ON (t.ID = s.ID) " +
+			"WHEN MATCHED THEN UPDATE SET t.NAME = s.NAME, t.AT = s.AT " +
+			"WHEN NOT MATCHED THEN INSERT (ID, NAME, AT) VALUES (s.ID, s.NAME, s.AT)",
+		Args: []any{
+			[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}},
+			[]sql.NullString{{String: "a", Valid: true}, {}},
+			[]sql.NullTime{{Time: at, Valid: true}, {}},
+		},
+	}}
+	var got []sqlfake.Call
+	for _, c := range f.Calls() {
+		if strings.HasPrefix(c.Query, "MERGE") {
+			got = append(got, c)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("calls:\n got %#v\nwant %#v", got, want)
+	}
+	if res.Table != "STOCK" || res.Rows != 2 || res.Merged != 2 {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestUpsertSource_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     *sliceSource
+		cols    []string
+		wantErr string
+	}{
+		{"validate fails", &sliceSource{validateErr: errors.New("bad header")}, []string{"id"}, "source validation failed: bad header"},
+		{"unknown column", &sliceSource{}, []string{"id", "qty"}, "column QTY not found in STOCK"},
+		{"convert fails", &sliceSource{rows: [][]any{{1}}, convertErr: errors.New("boom")}, []string{"id"}, "row 1: convert: boom"},
+		{"value count", &sliceSource{rows: [][]any{{1, "a"}}}, []string{"id"}, "row 1: 2 values for 1 columns"},
+		{"bad number", &sliceSource{rows: [][]any{{"x"}}}, []string{"id"}, `row 1 col ID: invalid NUMBER "x"`},
+		{"mixed dates", &sliceSource{rows: [][]any{{1, time.Now()}, {2, "2024-01-01"}}}, []string{"id", "at"}, "row 2 col AT: mixes time.Time and string values"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFake(t)
+			onTableColumns(f)
+			_, err := UpsertSource(quiet, f.DB, tt.src, "stock", tt.cols, []string{"id"}, Options{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSourceValue(t *testing.T) {
+	at := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	nulls := map[string]bool{"N/A": true}
+	tests := []struct {
+		typ     dynamic.DataType
+		in      any
+		want    any
+		wantErr bool
+	}{
+		{dynamic.Number, 7, int64(7), false},
+		{dynamic.Number, float32(1.5), float64(1.5), false},
+		{dynamic.Number, uint(8), int64(8), false},
+		{dynamic.Number, uint64(math.MaxInt64), int64(math.MaxInt64), false},
+		{dynamic.Number, uint64(math.MaxInt64) + 1, nil, true},
+		{dynamic.Varchar2, uint64(9), "9", false},
+		{dynamic.Number, "12", int64(12), false},
+		{dynamic.Number, "2.5", 2.5, false},
+		{dynamic.Number, sql.NullInt64{Int64: 3, Valid: true}, int64(3), false},
+		{dynamic.Number, true, nil, true},
+		{dynamic.Varchar2, 7, "7", false},
+		{dynamic.Varchar2, []byte("b"), "b", false},
+		{dynamic.Varchar2, "N/A", nil, false},
+		{dynamic.Date, at, at, false},
+		{dynamic.Date, "2024-05-01", "2024-05-01", false},
+		{dynamic.Varchar2, at, nil, true},
+		{dynamic.Varchar2, struct{}{}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := sourceValue(tt.typ, tt.in, nulls)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sourceValue(%s, %#v) = %#v, %v; want %#v", tt.typ, tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package csvdbappend
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"sql-learn2/logging"
+	"sql-learn2/retry"
+)
+
+// stagingSuffix is appended to the target table to name its staging table
+const stagingSuffix = "_STG"
+
+// stagingName is the default Options.StagingTable for table, shortened so
+// the suffix survives the 30-character limit
+func stagingName(table string) string {
+	return table[:min(len(table), 30-len(stagingSuffix))] + stagingSuffix
+}
+
+// createStaging creates staging empty, with the types the target table
+// gives cols, and without redo for the rows loaded into it
+func createStaging(ctx context.Context, db *sql.DB, staging, table string, cols []string) error {
+	ddl := fmt.Sprintf("CREATE TABLE %s NOLOGGING AS SELECT %s FROM %s WHERE 1 = 0", staging, strings.Join(cols, ", "), table)
+	if _, err := db.ExecContext(ctx, ddl); err != nil {
+		return fmt.Errorf("create staging table %s: %w", staging, err)
+	}
+	logging.FromContext(ctx).Info("Staging table created", logging.FieldTable, staging)
+	return nil
+}
+
+// dropStaging drops staging, logging rather than failing the upsert since
+// the rows are merged by then
+func dropStaging(ctx context.Context, db *sql.DB, staging string) {
+	if _, err := db.ExecContext(ctx, "DROP TABLE "+staging+" PURGE"); err != nil {
+		logging.FromContext(ctx).Warn("Dropping staging table failed", logging.FieldTable, staging, logging.FieldError, err)
+	}
+}
+
+// countMissing returns how many staged rows match no target row on the
+// conditions on, the rows the MERGE will insert
+func countMissing(ctx context.Context, db *sql.DB, staging, table string, on []string) (int, error) {
+	var n int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s s WHERE NOT EXISTS (SELECT 1 FROM %s t WHERE %s)", staging, table, strings.Join(on, " AND "))
+	if err := db.QueryRowContext(ctx, query).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count new rows in %s: %w", staging, err)
+	}
+	return n, nil
+}
+
+// maxInList is the most expressions an IN list takes (ORA-01795)
+const maxInList = 1000
+
+// countNewKeys returns how many rows of a row-wise MERGE batch will be
+// inserted: its distinct keys that match no row of table, plus the rows
+// with a NULL key part, which match nothing. keyVals are the key values
+// of each row, in the order of keys.
+func countNewKeys(ctx context.Context, conn *sql.Conn, table string, keys []string, keyVals [][]any) (int, error) {
+	seen := make(keySet, len(keyVals))
+	var distinct [][]any
+	n := 0
+	for _, kv := range keyVals {
+		if slices.Contains(kv, nil) {
+			n++
+			continue
+		}
+		if k := keyOf(kv); !seen[k] {
+			seen[k] = true
+			distinct = append(distinct, kv)
+		}
+	}
+	n += len(distinct)
+	col := strings.Join(keys, ", ")
+	if len(keys) > 1 {
+		col = "(" + col + ")"
+	}
+	for start := 0; start < len(distinct); start += maxInList {
+		part := distinct[start:min(start+maxInList, len(distinct))]
+		items := make([]string, len(part))
+		args := make([]any, 0, len(part)*len(keys))
+		for i, kv := range part {
+			phs := make([]string, len(kv))
+			for j, v := range kv {
+				args = append(args, v)
+				phs[j] = fmt.Sprintf(":%d", len(args))
+			}
+			if items[i] = strings.Join(phs, ", "); len(keys) > 1 {
+				items[i] = "(" + items[i] + ")"
+			}
+		}
+		var found int
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IN (%s)", table, col, strings.Join(items, ", "))
+		if err := conn.QueryRowContext(ctx, query, args...).Scan(&found); err != nil {
+			return 0, fmt.Errorf("count new rows in %s: %w", table, err)
+		}
+		n -= found
+	}
+	return max(n, 0), nil
+}
+
+// mergeStaging merges the staged rows into the target in one statement and
+// returns how many rows it inserted or updated, rerunning it on a lock as
+// p allows (see Options.Retry)
+func mergeStaging(ctx context.Context, db *sql.DB, staging, mergeSQL string, p retry.Policy) (int, error) {
+	if p.Retryable == nil {
+		p.Retryable = lockError
+	}
+	if p.OnRetry == nil {
+		p.OnRetry = func(attempt int, err error, wait time.Duration) {
+			logging.FromContext(ctx).Warn("Merge from staging hit a lock, retrying", logging.FieldTable, staging,
+				"attempt", attempt, "wait", wait, logging.FieldError, err)
+		}
+	}
+	var r sql.Result
+	err := retry.Do(ctx, p, func(ctx context.Context) (err error) {
+		r, err = db.ExecContext(ctx, mergeSQL)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("merge from %s: %w", staging, err)
+	}
+	n, _ := r.RowsAffected()
+	return int(n), nil
+}
@@ -0,0 +1,156 @@
+package csvdbappend
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+	"sql-learn2/retry"
+)
+
+// onCount answers the count of new rows a staging upsert takes, or of
+// existing keys a row-wise one takes, with n
+func onCount(f *sqlfake.Recorder, n int64) {
+	f.OnQuery(`^SELECT COUNT\(\*\) FROM`, []string{"COUNT(*)"}, []any{n})
+}
+
+func TestUpsertCSVToDB_Staging(t *testing.T) {
+	f := sqlfake.New(t)
+	onCount(f, 1)
+	f.OnExec("^MERGE", 2)
+	path := testharness.WriteCSV(t, "stock.csv", "id,name", "NUMBER,VARCHAR2", "1,a", "2,b")
+	res, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, "", []string{"id"}, Options{Staging: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []sqlfake.Call{
+		{Query: "CREATE TABLE STOCK_STG NOLOGGING AS SELECT ID, NAME FROM STOCK WHERE 1 = 0", Args: []any{}},
+		{Query: "INSERT INTO STOCK_STG (ID, NAME) VALUES (:1, :2)", Args: []any{
+			[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}},
+			[]sql.NullString{{String: "a", Valid: true}, {String: "b", Valid: true}},
+		}},
+		{Query: "SELECT COUNT(*) FROM STOCK_STG s WHERE NOT EXISTS (SELECT 1 FROM STOCK t WHERE t.ID = s.ID)", Args: []any{}},
+		{Query: "MERGE INTO STOCK t USING STOCK_STG s ON (t.ID = s.ID) " +
+			"WHEN MATCHED THEN UPDATE SET t.NAME = s.NAME " +
+			"WHEN NOT MATCHED THEN INSERT (ID, NAME) VALUES (s.ID, s.NAME)", Args: []any{}},
+		{Query: "DROP TABLE STOCK_STG PURGE", Args: []any{}},
+	}
+	if got := f.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls:\n got %#v\nwant %#v", got, want)
+	}
+	// the one staged key missing from STOCK is inserted
+	if res.Merged != 2 || res.Inserted != 1 || res.Updated != 1 {
+		t.Errorf("merged %d, inserted %d, updated %d; want 2, 1, 1", res.Merged, res.Inserted, res.Updated)
+	}
+}
+
+func TestUpsertCSVToDB_StagingErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		fail     string
+		opts     Options
+		wantErr  string
+		wantDrop bool
+	}{
+		{"create fails", "^CREATE", Options{Staging: true}, "create staging table STOCK_STG: boom", false},
+		{"stage fails", "^INSERT", Options{Staging: true}, "stage rows 3-3: boom", true},
+		{"count fails", "^SELECT COUNT", Options{Staging: true}, "count new rows in STOCK_STG: boom", true},
+		{"merge fails", "^MERGE", Options{Staging: true}, "merge from STOCK_STG: boom", true},
+		{"target as staging", "", Options{Staging: true, StagingTable: "stock"}, "invalid staging table", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			onCount(f, 0)
+			if tt.fail != "" {
+				f.Fail(tt.fail, errors.New("boom"))
+			}
+			path := testharness.WriteCSV(t, "stock.csv", "id", "NUMBER", "1")
+			_, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, "", []string{"id"}, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+			queries := f.Queries()
+			if dropped := len(queries) > 0 && queries[len(queries)-1] == "DROP TABLE STOCK_STG PURGE"; dropped != tt.wantDrop {
+				t.Errorf("staging dropped = %v, want %v; queries %q", dropped, tt.wantDrop, queries)
+			}
+		})
+	}
+}
+
+func TestUpsertCSVToDB_StagingRetry(t *testing.T) {
+	deadlock := errors.New("ORA-00060: deadlock detected while waiting for resource")
+	policy := retry.Policy{MaxAttempts: 3, Initial: time.Millisecond}
+	tests := []struct {
+		name    string
+		fail    string
+		wantErr string
+		want    int // statements run like fail
+	}{
+		{"merge retried", "^MERGE", "", 2},
+		{"insert not retried", "^INSERT", "stage rows 3-3", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			onCount(f, 0)
+			f.OnExec("^MERGE", 1)
+			f.FailTimes(tt.fail, 1, deadlock)
+			path := testharness.WriteCSV(t, "stock.csv", "id", "NUMBER", "1")
+			_, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, "", []string{"id"}, Options{Staging: true, Retry: policy})
+			if (err == nil) != (tt.wantErr == "") || err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			n := 0
+			for _, q := range f.Queries() {
+				if regexp.MustCompile(tt.fail).MatchString(q) {
+					n++
+				}
+			}
+			if n != tt.want {
+				t.Errorf("%d statements like %s, want %d", n, tt.fail, tt.want)
+			}
+		})
+	}
+}
+
+func TestStagingName(t *testing.T) {
+	tests := []struct{ table, want string }{
+		{"STOCK", "STOCK_STG"},
+		{"A_VERY_LONG_TABLE_NAME_OF_30CH", "A_VERY_LONG_TABLE_NAME_OF__STG"},
+	}
+	for _, tt := range tests {
+		if got := stagingName(tt.table); got != tt.want {
+			t.Errorf("stagingName(%q) = %q, want %q", tt.table, got, tt.want)
+		}
+	}
+}
+
+func TestCountNewKeys(t *testing.T) {
+	f := sqlfake.New(t)
+	onCount(f, 1)
+	conn, err := f.DB.Conn(quiet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// a repeated key is inserted once; a NULL key part matches no row
+	keyVals := [][]any{{int64(1), "a"}, {int64(2), "b"}, {int64(1), "a"}, {nil, "c"}}
+	n, err := countNewKeys(quiet, conn, "T", []string{"ID", "CODE"}, keyVals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("new rows = %d, want 2", n)
+	}
+	want := []sqlfake.Call{{Query: "SELECT COUNT(*) FROM T WHERE (ID, CODE) IN ((:1, :2), (:3, :4))", Args: []any{int64(1), "a", int64(2), "b"}}}
+	if got := f.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls:\n got %#v\nwant %#v", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+package csvdbappend
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"sql-learn2/dynamic"
+)
+
+// DefaultSyncFlagValue is what Options.SyncFlagColumn is set to by default
+const DefaultSyncFlagValue = "Y"
+
+// keySet holds the keys of the CSV rows, as keyOf renders them
+type keySet map[string]bool
+
+// keyOf renders the key values of a row so that a CSV cell and the value
+// the driver returns for it compare equal, e.g. int64(1) and float64(1)
+func keyOf(vals []any) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		switch v := v.(type) {
+		case nil:
+			parts[i] = "\x00"
+		case int64:
+			parts[i] = strconv.FormatInt(v, 10)
+		case float64:
+			parts[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case []byte:
+			parts[i] = string(v)
+		case time.Time:
+			parts[i] = v.Format(time.RFC3339Nano)
+		default:
+			parts[i] = fmt.Sprint(v)
+		}
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+// syncKeyTypes checks that the key columns can be compared for a sync
+func syncKeyTypes(keys []string, types []dynamic.DataType) error {
+	for i, t := range types {
+		if t != dynamic.Number && t != dynamic.Varchar2 {
+			return fmt.Errorf("sync compares NUMBER and VARCHAR2 keys only; key column %s is %s", keys[i], t)
+		}
+	}
+	return nil
+}
+
+// syncTable deletes the rows of table whose keys are not in csvKeys, or
+// sets opts.SyncFlagColumn on them, in batches of batchSize, and returns
+// how many there were. The keys of the table are read first, so rows
+// added meanwhile are left alone. aud, if not nil, records each row.
+func syncTable(ctx context.Context, db *sql.DB, table string, keys []string, types []dynamic.DataType, csvKeys keySet, opts Options, batchSize int, aud *audit) (int, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s", strings.Join(keys, ", "), table))
+	if err != nil {
+		return 0, fmt.Errorf("sync: read keys of %s: %w", table, err)
+	}
+	defer rows.Close()
+	var missing [][]any
+	for rows.Next() {
+		vals := make([]any, len(keys))
+		ptrs := make([]any, len(keys))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return 0, fmt.Errorf("sync: read keys of %s: %w", table, err)
+		}
+		if !csvKeys[keyOf(vals)] {
+			missing = append(missing, vals)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("sync: read keys of %s: %w", table, err)
+	}
+	rows.Close()
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	conds := make([]string, len(keys))
+	for i, k := range keys {
+		conds[i] = fmt.Sprintf("%s = :%d", k, i+1)
+	}
+	stmtSQL := fmt.Sprintf("DELETE FROM %s WHERE %s", table, strings.Join(conds, " AND "))
+	action := auditDelete
+	if opts.SyncFlagColumn != "" {
+		action = auditFlag
+		flag := opts.SyncFlagValue
+		if flag == "" {
+			flag = DefaultSyncFlagValue
+		}
+		stmtSQL = fmt.Sprintf("UPDATE %s SET %s = '%s' WHERE %s", table, opts.SyncFlagColumn,
+			strings.ReplaceAll(flag, "'", "''"), strings.Join(conds, " AND "))
+	}
+	stmt, err := db.PrepareContext(ctx, stmtSQL)
+	if err != nil {
+		return 0, fmt.Errorf("sync: prepare: %w", err)
+	}
+	defer stmt.Close()
+	b := newBatch(types, min(batchSize, len(missing)))
+	for start := 0; start < len(missing); start += batchSize {
+		b.reset(0)
+		part := missing[start:min(start+batchSize, len(missing))]
+		for _, vals := range part {
+			for i, v := range vals {
+				vals[i] = syncValue(types[i], v)
+			}
+			b.add(vals)
+		}
+		if _, err := stmt.ExecContext(ctx, b.args()...); err != nil {
+			return start, fmt.Errorf("sync %s: %w", table, err)
+		}
+		if aud != nil {
+			for _, vals := range part {
+				aud.removed(action, vals)
+			}
+		}
+	}
+	return len(missing), nil
+}
+
+// syncValue converts a key value read from the table for binding it back
+func syncValue(t dynamic.DataType, v any) any {
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+	s, ok := v.(string)
+	if t != dynamic.Number || !ok {
+		return v
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return v
+}
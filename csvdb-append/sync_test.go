@@ -0,0 +1,113 @@
+package csvdbappend
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+)
+
+func TestUpsertCSVToDB_Sync(t *testing.T) {
+	ids := func(ns ...int64) []sql.NullInt64 {
+		out := make([]sql.NullInt64, len(ns))
+		for i, n := range ns {
+			out[i] = sql.NullInt64{Int64: n, Valid: true}
+		}
+		return out
+	}
+	lines := []string{"id,name", "NUMBER,VARCHAR2", "1,a", "2,b"}
+	tests := []struct {
+		name      string
+		opts      Options
+		tableKeys [][]any
+		want      []sqlfake.Call // after the MERGE and the key query
+		wantMerge string         // a part of the MERGE
+	}{
+		{
+			name:      "delete missing",
+			opts:      Options{Sync: true},
+			tableKeys: [][]any{{int64(1)}, {int64(3)}, {"4"}},
+			want:      []sqlfake.Call{{Query: "DELETE FROM STOCK WHERE ID = :1", Args: []any{ids(3, 4)}}},
+		},
+		{
+			name:      "in batches",
+			opts:      Options{Sync: true, BatchSize: 1},
+			tableKeys: [][]any{{int64(3)}, {float64(4)}},
+			want: []sqlfake.Call{
+				{Query: "DELETE FROM STOCK WHERE ID = :1", Args: []any{ids(3)}},
+				{Query: "DELETE FROM STOCK WHERE ID = :1", Args: []any{[]sql.NullFloat64{{Float64: 4, Valid: true}}}},
+			},
+		},
+		{
+			name:      "nothing missing",
+			opts:      Options{Sync: true},
+			tableKeys: [][]any{{int64(1)}, {float64(2)}},
+		},
+		{
+			name:      "flag missing",
+			opts:      Options{Sync: true, SyncFlagColumn: "deleted"},
+			tableKeys: [][]any{{int64(5)}},
+			want:      []sqlfake.Call{{Query: "UPDATE STOCK SET DELETED = 'Y' WHERE ID = :1", Args: []any{ids(5)}}},
+			wantMerge: "UPDATE SET t.NAME = s.NAME, t.DELETED = NULL ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFake(t)
+			f.OnQuery("^SELECT ID FROM STOCK$", []string{"ID"}, tt.tableKeys...)
+			path := testharness.WriteCSV(t, "stock.csv", lines...)
+			if _, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, "", []string{"id"}, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			var merges int
+			var got []sqlfake.Call
+			for _, c := range f.Calls() {
+				switch {
+				case strings.HasPrefix(c.Query, "MERGE"):
+					merges++
+					if !strings.Contains(c.Query, tt.wantMerge) {
+						t.Errorf("merge = %q, want it to contain %q", c.Query, tt.wantMerge)
+					}
+				case !strings.HasPrefix(c.Query, "SELECT"):
+					got = append(got, c)
+				}
+			}
+			if merges == 0 {
+				t.Error("no MERGE before the sync")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calls:\n got %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpsertCSVToDB_SyncErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		opts    Options
+		wantErr string
+	}{
+		{"no data rows", []string{"id,name", "NUMBER,VARCHAR2"}, Options{Sync: true}, "would empty T; refusing"},
+		{"date key", []string{"id,name", "DATE,VARCHAR2", "2024-01-01,a"}, Options{Sync: true}, "key column ID is DATE"},
+		{"flag without sync", []string{"id,name", "NUMBER,VARCHAR2", "1,a"}, Options{SyncFlagColumn: "DELETED"}, "SyncFlagColumn needs Sync"},
+		{"flag in the CSV", []string{"id,name", "NUMBER,VARCHAR2", "1,a"}, Options{Sync: true, SyncFlagColumn: "name"}, "must not be a CSV column"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFake(t)
+			path := testharness.WriteCSV(t, "t.csv", tt.lines...)
+			_, err := UpsertCSVToDBWithOptions(quiet, f.DB, path, "", []string{"id"}, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+			if q := f.Queries(); len(q) > 0 {
+				t.Errorf("queries = %q, want none", q)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package csvdb
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	go_ora "github.com/sijms/go-ora/v2"
+
+	"sql-learn2/dynamic"
+)
+
+// maxInlineString is the longest cell bound as a plain string. A CLOB batch
+// holding a longer cell is bound as go_ora.Clob, which the driver sends as
+// temporary LOBs written in chunks; short CLOB cells stay cheap strings.
+const maxInlineString = 32767
+
+// DefaultBatchSize is the rows per array-bound INSERT when Options.BatchSize is 0
+const DefaultBatchSize = 5000
+
+// batch collects converted cells column by column for one array-bound INSERT
+type batch struct {
+	types []dynamic.DataType
+	cols  [][]any // nil for NULL, int64/float64 for NUMBER, time.Time or string for dates, string otherwise
+	first int     // CSV line of the first row, for error messages
+}
+
+func newBatch(types []dynamic.DataType, size int) *batch {
+	b := &batch{types: types, cols: make([][]any, len(types))}
+	for i := range b.cols {
+		b.cols[i] = make([]any, 0, size)
+	}
+	return b
+}
+
+func (b *batch) add(vals []any) {
+	for i, v := range vals {
+		b.cols[i] = append(b.cols[i], v)
+	}
+}
+
+func (b *batch) len() int {
+	if len(b.cols) == 0 {
+		return 0
+	}
+	return len(b.cols[0])
+}
+
+// reset empties the batch; the next row added is on CSV line first
+func (b *batch) reset(first int) {
+	for i := range b.cols {
+		b.cols[i] = b.cols[i][:0]
+	}
+	b.first = first
+}
+
+// args returns one typed slice per column, as go-ora array binding expects
+func (b *batch) args() []any {
+	out := make([]any, len(b.cols))
+	for i, col := range b.cols {
+		out[i] = bindArray(b.types[i], col)
+	}
+	return out
+}
+
+// bindArray types a column: NUMBER as []sql.NullInt64 when every value is an
+// integer, []sql.NullFloat64 when none is and []sql.NullString of decimals
+// when they mix; CLOB with a cell over maxInlineString as
+// []go_ora.Clob; parsed dates as []sql.NullTime; everything else as
+// []sql.NullString
+func bindArray(t dynamic.DataType, col []any) any {
+	if isDateType(t) && hasTime(col) {
+		arr := make([]sql.NullTime, len(col))
+		for i, v := range col {
+			if d, ok := v.(time.Time); ok {
+				arr[i] = sql.NullTime{Time: d, Valid: true}
+			}
+		}
+		return arr
+	}
+	if t == dynamic.Clob && hasLongCell(col) {
+		arr := make([]go_ora.Clob, len(col))
+		for i, v := range col {
+			if s, ok := v.(string); ok {
+				arr[i] = go_ora.Clob{String: s, Valid: true}
+			}
+		}
+		return arr
+	}
+	if t != dynamic.Number {
+		arr := make([]sql.NullString, len(col))
+		for i, v := range col {
+			if s, ok := v.(string); ok {
+				arr[i] = sql.NullString{String: s, Valid: true}
+			}
+		}
+		return arr
+	}
+	var ints, floats bool
+	for _, v := range col {
+		switch v.(type) {
+		case int64:
+			ints = true
+		case float64:
+			floats = true
+		}
+	}
+	switch {
+	case !floats:
+		arr := make([]sql.NullInt64, len(col))
+		for i, v := range col {
+			if n, ok := v.(int64); ok {
+				arr[i] = sql.NullInt64{Int64: n, Valid: true}
+			}
+		}
+		return arr
+	case !ints:
+		arr := make([]sql.NullFloat64, len(col))
+		for i, v := range col {
+			if n, ok := v.(float64); ok {
+				arr[i] = sql.NullFloat64{Float64: n, Valid: true}
+			}
+		}
+		return arr
+	}
+	// Mixed: float64 cannot hold integers past 2^53, so they would be stored
+	// with other digits; decimal text keeps every digit and Oracle converts it
+	arr := make([]sql.NullString, len(col))
+	for i, v := range col {
+		switch n := v.(type) {
+		case int64:
+			arr[i] = sql.NullString{String: strconv.FormatInt(n, 10), Valid: true}
+		case float64:
+			arr[i] = sql.NullString{String: strconv.FormatFloat(n, 'f', -1, 64), Valid: true}
+		}
+	}
+	return arr
+}
+
+func hasLongCell(col []any) bool {
+	for _, v := range col {
+		if s, ok := v.(string); ok && len(s) > maxInlineString {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTime(col []any) bool {
+	for _, v := range col {
+		if _, ok := v.(time.Time); ok {
+			return true
+		}
+	}
+	return false
+}
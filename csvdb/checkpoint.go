@@ -0,0 +1,41 @@
+package csvdb
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"sql-learn2/csvfile"
+	"sql-learn2/internal/resume"
+)
+
+// checkpoint records how far a load got: every row up to CSV line Line is
+// committed, and so are the rows in Done past it, batches that committed
+// ahead of one that failed. Lines count records as the load does, header
+// included. Rejected rows up to Line are the first RejectSize bytes of the
+// reject file; a resumed load drops the rest and rejects those rows again.
+type checkpoint struct {
+	CSV        string        `json:"csv"`
+	Size       int64         `json:"size"`
+	Table      string        `json:"table"`
+	Line       int           `json:"line"`
+	Rows       int           `json:"rows"` // rows inserted up to Line and in Done
+	Done       resume.Ranges `json:"done,omitempty"`
+	Rejected   int           `json:"rejected,omitempty"`
+	RejectSize int64         `json:"reject_size,omitempty"`
+}
+
+// defaultCheckpointFile is <name>.ckpt next to the CSV, e.g. /in/orders.ckpt
+func defaultCheckpointFile(csvPath string) string {
+	return filepath.Join(filepath.Dir(csvPath), csvfile.BaseName(csvPath)+".ckpt")
+}
+
+// check reports why c cannot resume a load of csvPath (size bytes) into table
+func (c *checkpoint) check(csvPath string, size int64, table string) error {
+	switch {
+	case c.Size != size:
+		return fmt.Errorf("checkpoint is for a %d byte file, %s has %d; delete the checkpoint to load from the start", c.Size, csvPath, size)
+	case c.Table != table:
+		return fmt.Errorf("checkpoint is for table %s, not %s", c.Table, table)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package csvdb
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sql-learn2/internal/resume"
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+)
+
+func TestLoadCSVToDBWithOptions_Resume(t *testing.T) {
+	path := testharness.WriteCSV(t, "orders.csv", "id", "NUMBER", "1", "2", "x", "4")
+	ckptPath := filepath.Join(filepath.Dir(path), "orders.ckpt")
+
+	// the bad row stops the load after the first batch is committed
+	f := sqlfake.New(t)
+	f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+	_, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{BatchSize: 2, Checkpoint: true})
+	if err == nil || !strings.Contains(err.Error(), "row 5") {
+		t.Fatalf("err = %v, want row 5 to fail", err)
+	}
+	c, err := resume.Read[checkpoint](ckptPath)
+	if err != nil || c == nil {
+		t.Fatalf("checkpoint = %v, %v", c, err)
+	}
+	if c.Line != 4 || c.Rows != 2 || c.Table != "ORDERS" {
+		t.Errorf("checkpoint = %+v, want line 4, 2 rows, table ORDERS", c)
+	}
+
+	// fixed in place, the rerun loads only the rest into the existing table
+	if err := os.WriteFile(path, []byte("id\nNUMBER\n1\n2\n3\n4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f = sqlfake.New(t)
+	res, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{BatchSize: 2, Resume: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (LoadResult{Table: "ORDERS", RowsRead: 2, Loaded: 2, Resumed: 2}); !reflect.DeepEqual(counts(res), want) {
+		t.Errorf("result = %+v, want %+v", counts(res), want)
+	}
+	var inserts []any
+	for _, call := range f.Calls() {
+		if !strings.HasPrefix(call.Query, "INSERT") {
+			t.Errorf("resumed load ran %q", call.Query)
+			continue
+		}
+		inserts = append(inserts, call.Args[0])
+	}
+	want := []any{[]sql.NullInt64{{Int64: 3, Valid: true}, {Int64: 4, Valid: true}}}
+	if !reflect.DeepEqual(inserts, want) {
+		t.Errorf("inserted %v, want %v", inserts, want)
+	}
+	if _, err := os.Stat(ckptPath); !os.IsNotExist(err) {
+		t.Errorf("checkpoint left after the load completed: %v", err)
+	}
+}
+
+func TestCheckpoint_Check(t *testing.T) {
+	c := &checkpoint{Size: 10, Table: "ORDERS", Line: 4}
+	tests := []struct {
+		name    string
+		size    int64
+		table   string
+		wantErr string
+	}{
+		{"same file", 10, "ORDERS", ""},
+		{"file changed", 11, "ORDERS", "checkpoint is for a 10 byte file"},
+		{"other table", 10, "ORDERS2", "checkpoint is for table ORDERS"},
+	}
+	for _, tt := range tests {
+		err := c.check("orders.csv", tt.size, tt.table)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadCSVToDBWithOptions_ResumeCommittedAhead(t *testing.T) {
+	path := testharness.WriteCSV(t, "orders.csv", "id", "NUMBER", "1", "2", "3", "4")
+	ckptPath := filepath.Join(filepath.Dir(path), "orders.ckpt")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two workers: the batch of lines 5-6 commits, then the one of lines 3-4 fails
+	saved := checkpoint{CSV: path, Size: info.Size(), Table: "ORDERS"}
+	p := &insertPool{progress: resume.NewTracker(0, nil, job.lines)}
+	p.committed = func(run []job, rows int, done resume.Ranges) {
+		if len(run) > 0 {
+			saved.Line = run[len(run)-1].last
+		}
+		saved.Rows, saved.Done = rows, done
+		if err := resume.Write(ckptPath, saved); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.markInserted(job{seq: 1, first: 5, rows: 2, last: 6})
+	want := checkpoint{CSV: path, Size: info.Size(), Table: "ORDERS", Rows: 2, Done: resume.Ranges{{First: 5, Last: 6}}}
+	if c, err := resume.Read[checkpoint](ckptPath); err != nil || !reflect.DeepEqual(*c, want) {
+		t.Fatalf("checkpoint = %+v, %v; want %+v", c, err, want)
+	}
+
+	f := sqlfake.New(t)
+	res, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{BatchSize: 2, Workers: 2, Resume: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (LoadResult{Table: "ORDERS", RowsRead: 2, Loaded: 2, Resumed: 2}); !reflect.DeepEqual(counts(res), want) {
+		t.Errorf("result = %+v, want %+v", counts(res), want)
+	}
+	var inserts []any
+	for _, call := range f.Calls() {
+		if strings.HasPrefix(call.Query, "INSERT") {
+			inserts = append(inserts, call.Args[0])
+		}
+	}
+	// only the failed batch again, not the one committed ahead of it
+	if want := []any{[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}}}; !reflect.DeepEqual(inserts, want) {
+		t.Errorf("inserted %v, want %v", inserts, want)
+	}
+}
+
+func TestLoadCSVToDBWithOptions_ResumeRejects(t *testing.T) {
+	path := testharness.WriteCSV(t, "orders.csv", "id", "NUMBER", "1", "x", "2", "y", "z")
+	ckptPath := filepath.Join(filepath.Dir(path), "orders.ckpt")
+	rejectPath := filepath.Join(filepath.Dir(path), "orders.bad")
+
+	// the batch of lines 3-5 commits with x rejected; y is rejected past it
+	// and z stops the load
+	f := sqlfake.New(t)
+	f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+	_, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{BatchSize: 2, Checkpoint: true, SkipBadRows: true, MaxRejects: 2})
+	if err == nil || !strings.Contains(err.Error(), "more than 2 rejected rows") {
+		t.Fatalf("err = %v, want too many rejects", err)
+	}
+	c, err := resume.Read[checkpoint](ckptPath)
+	if err != nil || c == nil {
+		t.Fatalf("checkpoint = %v, %v", c, err)
+	}
+	if c.Line != 5 || c.Rejected != 1 {
+		t.Errorf("checkpoint = %+v, want line 5 with 1 rejected", c)
+	}
+
+	f = sqlfake.New(t)
+	res, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{BatchSize: 2, Resume: true, SkipBadRows: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := LoadResult{Table: "ORDERS", RowsRead: 2, Rejected: 3, RejectFile: rejectPath, Resumed: 2}
+	if !reflect.DeepEqual(counts(res), want) {
+		t.Errorf("result = %+v, want %+v", counts(res), want)
+	}
+	b, err := os.ReadFile(rejectPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, rec := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		lines = append(lines, strings.SplitN(rec, ",", 2)[0])
+	}
+	// each rejected row once, the one before the checkpoint kept
+	if wantLines := []string{"LINE", "4", "6", "7"}; !reflect.DeepEqual(lines, wantLines) {
+		t.Errorf("reject file lines %v, want %v:\n%s", lines, wantLines, b)
+	}
+}
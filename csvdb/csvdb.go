@@ -1,224 +1,926 @@
 package csvdb
 
 import (
-	"bufio"
 	"context"
 	"database/sql"
 	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"sql-learn2/csvfile"
 	"sql-learn2/dynamic"
+	"sql-learn2/internal/resume"
+	"sql-learn2/loadhistory"
+	"sql-learn2/logging"
+	"sql-learn2/numformat"
+	"sql-learn2/tracing"
 )
 
 // LoadCSVToDB reads a CSV file and creates a table based on its content, then loads data.
 //
 // Rules per requirements:
 // - Table name = CSV file name (without extension), normalized to Oracle identifier
-// - Column names = first row (header), normalized to Oracle identifiers
+// - Column names = first row (header), normalized to Oracle identifiers, unless mapped by Options.Columns
 // - Data types = second row; supported: VARCHAR2, NUMBER, DATE, TIMESTAMP, CLOB (others error)
 // - Data rows = from third row onwards
+// - Without a types row, NUMBER/DATE/VARCHAR2/CLOB are inferred from the first data rows (see Options.Types)
 // - Uses dynamic package to create or replace the table
 //
 // Notes:
 // - Whitespace around header/type cells is trimmed.
 // - If a data row has fewer cells than columns, remaining cells are treated as NULL.
 // - If a data row has more cells, extras are ignored.
+// - Rows are streamed from the file and inserted in array-bound batches (see Options.BatchSize and Workers).
 // - NUMBER values are parsed into int64 or float64 when possible; empty string => NULL.
+// - Options.Number accepts other decimal/thousands separators and currency symbols.
 // - Other types are passed as strings; empty string => NULL.
-func LoadCSVToDB(ctx context.Context, db *sql.DB, csvPath string) error {
+// - Options.DateFormats parses DATE and TIMESTAMP cells with given layouts and binds them as times.
+// - Gzip-compressed files (e.g. orders.csv.gz) are decompressed while streaming; the table is then ORDERS.
+// - Options.Comma, Comment and LazyQuotes cover pipe- or tab-delimited and loosely quoted exports.
+// - Options.Encoding reads Windows-874 (TIS-620) and UTF-16 files.
+// - Options.Progress reports rows read and inserted, bytes and an ETA while a long load runs.
+// - Options.Mode appends to or truncate-loads an existing table instead of recreating it.
+// - Options.Checkpoint and Resume let an interrupted load continue where it stopped.
+// - Options.Extra adds columns the CSV lacks, e.g. LOAD_DATE = SYSDATE or SOURCE_FILE.
+// - Options.DirectPath (and NoLogging) insert with the APPEND_VALUES hint for fast first loads.
+// - Options.PreSQL and PostSQL run statements around the load, e.g. to drop and rebuild indexes.
+//
+// Every option is a field of Options, taken by LoadCSVToDBWithOptions;
+// LoadCSVToDB and LoadCSVToDBAs are thin wrappers around it.
+func LoadCSVToDB(ctx context.Context, db *sql.DB, csvPath string) (LoadResult, error) {
 	return LoadCSVToDBAs(ctx, db, csvPath, "")
 }
 
 // LoadCSVToDBAs reads a CSV file and creates a table based on its content, then loads data.
 // If tableName is non-empty, it overrides the table name derived from the CSV filename.
-func LoadCSVToDBAs(ctx context.Context, db *sql.DB, csvPath, tableName string) error {
-	if db == nil {
-		return errors.New("db is nil")
+func LoadCSVToDBAs(ctx context.Context, db *sql.DB, csvPath, tableName string) (LoadResult, error) {
+	return LoadCSVToDBWithOptions(ctx, db, csvPath, Options{Table: tableName})
+}
+
+// Options tune LoadCSVToDBWithOptions; the zero value behaves like LoadCSVToDB
+type Options struct {
+	Table  string           // overrides the table name derived from the CSV filename
+	Number numformat.Format // how NUMBER cells are written, e.g. numformat.European for "1.234,56"
+
+	// CSV dialect; the zero values mean comma separated, strict quoting and no comments
+	Comma      rune // field delimiter, e.g. '|' or '\t' (see ParseDelimiter)
+	Comment    rune // lines starting with it are skipped
+	LazyQuotes bool // allow stray quotes, as in exports that do not escape them
+
+	// Encoding is the character set of the file, e.g. csvfile.Windows874
+	// for TIS-620 exports; cells are transcoded to UTF-8 before loading
+	Encoding csvfile.Encoding
+
+	// NullValues are data cells loaded as NULL besides the empty one, e.g.
+	// "NULL", `\N` or "N/A" (see ParseNullValues); matched exactly
+	NullValues []string
+
+	// Types says whether the second row holds the column types (default:
+	// detected). Inferred types come from the first InferRows data rows
+	// (default DefaultInferRows); see inferColumns for the rules.
+	Types     TypesMode
+	InferRows int
+
+	// Workers is the number of sessions inserting batches in parallel (default
+	// 1), each on its own connection with its own prepared statement. The
+	// table is created before any of them starts; on failure the other
+	// workers stop and every failed batch is reported.
+	Workers int
+
+	// SkipBadRows writes rows whose cells do not convert (e.g. a malformed
+	// NUMBER) to RejectFile and goes on, instead of failing the load.
+	// RejectFile defaults to <csv name>.bad next to the CSV; MaxRejects > 0
+	// fails the load once more rows than that are rejected.
+	SkipBadRows bool
+	RejectFile  string
+	MaxRejects  int
+
+	// DateFormats are Go time layouts, e.g. "02/01/2006", for DATE and
+	// TIMESTAMP cells: each cell is parsed with the first that matches and
+	// bound as a time, so the session NLS_DATE_FORMAT no longer matters and
+	// a cell matching none is a bad row. Inference then recognizes these
+	// layouts instead of the ISO ones. Unset, dates are bound as text.
+	DateFormats []string
+
+	// Columns maps CSV headers (matched case-insensitively) to table column
+	// names, e.g. {"first name": "FNAME"}; a header mapped to "" is not
+	// loaded, and with OnlyMapped neither is any header missing from Columns.
+	// Unmapped headers are normalized into column names as before.
+	Columns    map[string]string
+	OnlyMapped bool
+
+	// Transforms convert the cells of the columns they are keyed by (the
+	// column name as loaded, e.g. PRICE) before binding; see Transform.
+	// Inferred types are taken from the transformed sample cells.
+	Transforms map[string]Transform
+
+	// Extra columns are appended to every row after the CSV columns, e.g.
+	// LoadDateColumn("LOAD_DATE") and SourceFileColumn("SOURCE_FILE", path);
+	// with Mode they must exist in the table
+	Extra []ExtraColumn
+
+	// Headers tune how headers are turned into column names
+	Headers HeaderOptions
+
+	// Names are normalized to unquoted identifiers of at most
+	// MaxIdentifierLen bytes (default 30); DetectIdentifierLen asks the
+	// server instead (128 on 12.2+). QuotedIdentifiers keeps headers and
+	// the table name as quoted identifiers, case and spaces included.
+	MaxIdentifierLen    int
+	DetectIdentifierLen bool
+	QuotedIdentifiers   bool
+
+	// Progress, when set, is called every ProgressEvery data rows (default
+	// DefaultProgressEvery) and once more when the load is done
+	Progress      func(Progress)
+	ProgressEvery int
+
+	// Mode keeps an existing table instead of recreating it (see TableMode);
+	// its columns then decide how cells are converted
+	Mode TableMode
+
+	// Checkpoint records the committed CSV lines in CheckpointFile
+	// (default <csv name>.ckpt next to the CSV) after every batch, and
+	// removes the file once the load completes. Resume continues from an
+	// existing checkpoint: the table is kept and the committed rows are
+	// skipped, also batches a worker committed ahead of the one that
+	// failed; without one the load starts over. With SkipBadRows the reject
+	// file of the interrupted load is continued, not written anew.
+	Checkpoint     bool
+	CheckpointFile string
+	Resume         bool
+
+	// PreSQL runs once the table is created or truncated, before the first
+	// row is inserted, e.g. to disable constraints or drop indexes; PostSQL
+	// runs after the load, also a failed one, e.g. to rebuild them. Each
+	// list runs in order on one session, outside the insert transactions
+	// (DDL commits anyway), and stops at the first failing statement.
+	// Statements take no trailing semicolon unless they are PL/SQL blocks.
+	PreSQL  []string
+	PostSQL []string
+
+	// DryRun reads the header, types and first rows and returns the Plan
+	// in LoadResult without touching the database; db may then be nil
+	DryRun bool
+
+	// BatchSize is the rows sent per array-bound INSERT (default DefaultBatchSize).
+	// Each batch commits on its own, so a failed load leaves earlier batches in the table.
+	BatchSize int
+
+	// DirectPath inserts with the APPEND_VALUES hint: rows are written above
+	// the table's high-water mark, bypassing the buffer cache, which is much
+	// faster for first loads. The table is locked until each batch commits,
+	// so it takes one worker and a commit per batch. NoLogging also switches
+	// the table to NOLOGGING for the load, so the rows write no redo and
+	// cannot be recovered from the logs until the next backup.
+	DirectPath bool
+	NoLogging  bool
+
+	// CommitEvery > 0 groups batches into transactions of at least that many
+	// rows per worker, rounded up to whole batches. Larger transactions mean
+	// fewer commits (less redo log syncing) but more undo held until each
+	// commit, and more rows rolled back on a failure; a failed load keeps
+	// only the committed transactions. LoadResult.Loaded counts committed rows.
+	CommitEvery int
+}
+
+// LoadResult summarizes a load
+type LoadResult struct {
+	Table      string // the target table as written in SQL, e.g. ORDERS or "Orders"
+	RowsRead   int    // data rows read, rejected ones included; not those skipped by Options.Resume
+	Loaded     int    // rows inserted
+	Rejected   int    // rows skipped because a cell could not be converted (Options.SkipBadRows), with Options.Resume also the interrupted load's
+	RejectFile string // where the rejected rows were written; empty when none were
+	Plan       *Plan  // set instead of loading with Options.DryRun
+	Resumed    int    // rows committed by the interrupted load, before Loaded
+
+	// Columns are the column definitions the cells were converted by: from
+	// the types row, inferred (Inferred) or, with Options.Mode, the table's
+	Columns  []dynamic.ColumnDef
+	Inferred bool
+
+	Bytes    int64         // bytes of the file read, compressed for gzip
+	Duration time.Duration // from opening the file to the last commit
+}
+
+// LoadCSVToDBWithOptions is LoadCSVToDB with options. The result is
+// filled as far as the load got, also on error.
+func LoadCSVToDBWithOptions(ctx context.Context, db *sql.DB, csvPath string, opts Options) (res LoadResult, err error) {
+	tableName := opts.Table
+	if db == nil && !opts.DryRun {
+		return res, errors.New("db is nil")
 	}
 	if csvPath == "" {
-		return errors.New("csvPath is empty")
+		return res, errors.New("csvPath is empty")
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if opts.DirectPath && opts.Workers > 1 {
+		return res, errors.New("DirectPath loads with one worker: a direct-path insert locks the table")
+	}
+	if opts.DirectPath && opts.CommitEvery > batchSize {
+		// ORA-12838: a direct-path insert must commit before the next one
+		return res, errors.New("DirectPath commits every batch; CommitEvery cannot exceed BatchSize")
+	}
+	if opts.NoLogging && !opts.DirectPath {
+		return res, errors.New("NoLogging only applies to a DirectPath load")
+	}
+	var run *loadhistory.Run
+	if !opts.DryRun {
+		ctx, run = loadhistory.Start(ctx, loadhistory.WorkflowLoad, csvPath, tableName)
+		defer func() { run.End(err) }()
 	}
 
-	f, err := os.Open(csvPath)
+	began := time.Now()
+	r, err := openCSV(ctx, csvPath, opts)
 	if err != nil {
-		return fmt.Errorf("open csv: %w", err)
+		return res, err
 	}
-	defer f.Close()
+	defer func() {
+		res.Bytes, res.Duration = r.f.BytesRead(), time.Since(began)
+		r.close(err)
+	}()
 
-	r := csv.NewReader(bufio.NewReader(f))
-	r.TrimLeadingSpace = true
-	r.FieldsPerRecord = -1 // allow variable
+	headers, err := r.next()
+	if err == io.EOF {
+		return res, errors.New("csv must have at least 2 rows: header and types")
+	}
+	if err != nil {
+		return res, err
+	}
 
-	rows := make([][]string, 0, 128)
-	for {
-		rec, err := r.Read()
+	ident := dynamic.CreateOptions{MaxIdentifierLen: opts.MaxIdentifierLen, Quoted: opts.QuotedIdentifiers}
+	if opts.DetectIdentifierLen && db != nil {
+		if ident.MaxIdentifierLen, err = dynamic.MaxIdentifierLength(ctx, db); err != nil {
+			return res, err
+		}
+	}
+	toName := func(s string) string { return columnName(s, ident, opts.Headers) }
+	// PreserveCase is for columns; the table name is uppercased as before
+	toTable := func(s string) string {
+		return columnName(s, ident, HeaderOptions{CollapseSpaces: opts.Headers.CollapseSpaces})
+	}
+
+	// Resolve target table name (parameter wins; fallback to file name)
+	resolvedTable := ""
+	if strings.TrimSpace(tableName) != "" {
+		resolvedTable = toTable(tableName)
+		if resolvedTable == "" {
+			return res, fmt.Errorf("invalid table name: %q", tableName)
+		}
+	} else {
+		resolvedTable = toTable(csvfile.BaseName(csvPath))
+		if resolvedTable == "" {
+			return res, fmt.Errorf("cannot derive valid table name from file: %s", filepath.Base(csvPath))
+		}
+	}
+
+	run.SetTarget(resolvedTable)
+
+	keep, kept, oracleCols, err := mapColumns(headers, opts.Columns, opts.OnlyMapped, toName)
+	if err != nil {
+		return res, err
+	}
+	if len(kept) < len(headers) {
+		r.keep = keep // from here on next returns only the loaded columns
+	}
+	headers = kept
+	if r.transforms, err = columnTransforms(oracleCols, opts.Transforms); err != nil {
+		return res, err
+	}
+	extra, err := resolveExtras(opts.Extra, oracleCols, toName)
+	if err != nil {
+		return res, err
+	}
+	if opts.Headers.PreserveCase {
+		ident.Quoted = true // only quoted identifiers keep their case
+	}
+
+	// Column types come from the types row or from sampled data rows
+	second, err := r.next()
+	if err != nil && (err != io.EOF || opts.Types != TypesInfer) {
 		if err == io.EOF {
-			break
+			return res, errors.New("csv must have at least 2 rows: header and types")
+		}
+		return res, err
+	}
+	var cols []dynamic.ColumnDef
+	firstLine := 3
+	typed := opts.Types == TypesRow || opts.Types == TypesAuto && isTypesRow(second)
+	if typed {
+		if cols, err = typedColumns(oracleCols, second); err != nil {
+			return res, err
+		}
+	} else {
+		firstLine = 2
+		if cols, err = r.infer(oracleCols, second, opts); err != nil {
+			return res, err
 		}
+	}
+
+	// An existing table keeps its definition and decides the column types
+	if opts.Mode != TableReplace && !opts.DryRun {
+		existing, err := dynamic.TableColumns(ctx, db, resolvedTable, ident)
 		if err != nil {
-			return fmt.Errorf("read csv: %w", err)
+			return res, err
 		}
-		// Trim spaces for each cell
-		for i := range rec {
-			rec[i] = strings.TrimSpace(rec[i])
+		if cols, err = matchTable(resolvedTable, cols, typed, existing); err != nil {
+			return res, err
 		}
-		// Skip fully empty lines
-		empty := true
-		for _, v := range rec {
-			if v != "" {
-				empty = false
-				break
-			}
+		if _, err = matchTable(resolvedTable, extraDefs(extra), true, existing); err != nil {
+			return res, err
 		}
-		if empty {
+	}
+
+	// Prepare INSERT statement with Oracle-style placeholders :1, :2, ...
+	// and the extra columns after the CSV ones
+	placeholders := make([]string, len(cols), len(cols)+len(extra))
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf(":%d", i+1)
+	}
+	types := make([]dynamic.DataType, len(cols), len(cols)+len(extra))
+	for i, c := range cols {
+		types[i] = c.Type
+	}
+	for _, e := range extra {
+		if e.SQL != "" {
+			placeholders = append(placeholders, e.SQL)
 			continue
 		}
-		rows = append(rows, rec)
+		types = append(types, e.Column.Type)
+		placeholders = append(placeholders, fmt.Sprintf(":%d", len(types)))
 	}
-	if len(rows) < 2 {
-		return errors.New("csv must have at least 2 rows: header and types")
+	sqlTable, err := ident.Identifier(resolvedTable)
+	if err != nil {
+		return res, fmt.Errorf("invalid table name: %w", err)
 	}
-	if len(rows) < 3 {
-		// no data rows; we still create the table
+	sqlCols := make([]string, len(oracleCols), len(oracleCols)+len(extra))
+	for i, c := range oracleCols {
+		if sqlCols[i], err = ident.Identifier(c); err != nil {
+			return res, fmt.Errorf("invalid column name %q: %w", c, err)
+		}
 	}
+	for _, e := range extra {
+		c, err := ident.Identifier(e.Column.Name)
+		if err != nil {
+			return res, fmt.Errorf("invalid column name %q: %w", e.Column.Name, err)
+		}
+		sqlCols = append(sqlCols, c)
+	}
+	res.Table, res.Columns, res.Inferred = sqlTable, cols, !typed
+	hint := ""
+	if opts.DirectPath {
+		hint = "/*+ APPEND_VALUES */ "
+	}
+	insertSQL := fmt.Sprintf("INSERT %sINTO %s (%s) VALUES (%s)", hint, sqlTable, strings.Join(sqlCols, ", "), strings.Join(placeholders, ", "))
 
-	headers := rows[0]
-	typesRow := rows[1]
-
-	if len(typesRow) < len(headers) {
-		return fmt.Errorf("types row has fewer cells (%d) than headers (%d)", len(typesRow), len(headers))
+	rec, err := r.next()
+	if err != nil && err != io.EOF {
+		return res, err
+	}
+	if opts.DryRun {
+		if res.Plan, err = r.plan(resolvedTable, cols, extra, ident, insertSQL, rec, firstLine, opts.Number); err != nil {
+			return res, err
+		}
+		res.Plan.PreSQL, res.Plan.PostSQL = opts.PreSQL, opts.PostSQL
+		switch opts.Mode {
+		case TableAppend:
+			res.Plan.DDL = ""
+		case TableTruncate:
+			res.Plan.DDL = "TRUNCATE TABLE " + sqlTable
+		}
+		return res, nil
 	}
 
-	// Resolve target table name (parameter wins; fallback to file name)
-	resolvedTable := ""
-	if strings.TrimSpace(tableName) != "" {
-		resolvedTable = normalizeIdentifierForOracle(tableName)
-		if resolvedTable == "" {
-			return fmt.Errorf("invalid table name: %q", tableName)
+	log := logging.FromContext(ctx)
+	ckptPath := ""
+	if opts.Checkpoint || opts.Resume {
+		if ckptPath = opts.CheckpointFile; ckptPath == "" {
+			ckptPath = defaultCheckpointFile(csvPath)
 		}
-	} else {
-		base := filepath.Base(csvPath)
-		name := strings.TrimSuffix(base, filepath.Ext(base))
-		resolvedTable = normalizeIdentifierForOracle(name)
-		if resolvedTable == "" {
-			return fmt.Errorf("cannot derive valid table name from file: %s", base)
-		}
-	}
-
-	// Build column defs
-	cols := make([]dynamic.ColumnDef, 0, len(headers))
-	oracleCols := make([]string, 0, len(headers))
-	for i, h := range headers {
-		colName := normalizeIdentifierForOracle(h)
-		if colName == "" {
-			return fmt.Errorf("invalid column name at position %d: %q", i+1, h)
-		}
-		oracleCols = append(oracleCols, colName)
-
-		dtype := strings.ToUpper(strings.TrimSpace(typesRow[i]))
-		var dt dynamic.DataType
-		switch dtype {
-		case "VARCHAR", "VARCHAR2":
-			dt = dynamic.Varchar2
-		case "NUMBER":
-			dt = dynamic.Number
-		case "DATE":
-			dt = dynamic.Date
-		case "TIMESTAMP":
-			dt = dynamic.Timestamp
-		case "CLOB":
-			dt = dynamic.Clob
-		default:
-			return fmt.Errorf("unsupported type %q for column %s", dtype, colName)
+	}
+	var ckpt *checkpoint
+	if opts.Resume {
+		if ckpt, err = resume.Read[checkpoint](ckptPath); err != nil {
+			return res, err
+		}
+		if ckpt == nil {
+			log.Info("No checkpoint to resume, loading from the start", "checkpoint", ckptPath)
+		} else if err := ckpt.check(csvPath, r.f.Size(), sqlTable); err != nil {
+			return res, err
 		}
-		cols = append(cols, dynamic.ColumnDef{
-			Name:     colName,
-			Type:     dt,
-			Nullable: true,
-		})
 	}
 
-	// Create or replace table via dynamic package
-	if err := dynamic.CreateOrReplaceTable(ctx, db, resolvedTable, cols); err != nil {
-		return err
+	line := firstLine
+	switch {
+	case ckpt != nil:
+		// The table keeps the rows committed before; skip them in the file
+		for ; rec != nil && line <= ckpt.Line; line++ {
+			if rec, err = r.next(); err != nil && err != io.EOF {
+				return res, err
+			}
+		}
+		res.Resumed = ckpt.Rows
+		log.Info("Resuming load", logging.FieldTable, resolvedTable, "after_line", ckpt.Line, "resumed_rows", ckpt.Rows)
+	case opts.Mode == TableAppend:
+		// insert into the table as it is
+	case opts.Mode == TableTruncate:
+		if _, err := dynamic.TruncateTable(ctx, db, sqlTable, dynamic.TruncateOptions{}); err != nil {
+			return res, err
+		}
+	default:
+		// Create or replace table via dynamic package
+		all := append(cols[:len(cols):len(cols)], extraDefs(extra)...)
+		if err := dynamic.CreateOrReplaceTableWithOptions(ctx, db, resolvedTable, all, ident); err != nil {
+			return res, err
+		}
+	}
+	if err := runSQL(ctx, db, "pre-load", opts.PreSQL); err != nil {
+		return res, err
+	}
+	if len(opts.PostSQL) > 0 {
+		defer func() {
+			if perr := runSQL(context.WithoutCancel(ctx), db, "post-load", opts.PostSQL); perr != nil {
+				err = errors.Join(err, perr)
+			}
+		}()
+	}
+	finishCheckpoint := func() {
+		if ckptPath == "" {
+			return
+		}
+		if err := resume.Remove(ckptPath); err != nil {
+			log.Warn("Checkpoint not removed", "checkpoint", ckptPath, logging.FieldError, err)
+		}
 	}
 
 	// If no data rows, we're done
-	if len(rows) <= 2 {
-		return nil
+	if rec == nil {
+		run.SetRows(0)
+		finishCheckpoint()
+		return res, nil
 	}
 
-	dataRows := rows[2:]
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, tracing.SpanBatchInsert, tracing.String(tracing.AttrTable, resolvedTable))
+	inserted := 0
+	defer func() {
+		span.SetAttributes(tracing.Int(tracing.AttrRows, inserted))
+		span.End(err)
+	}()
 
-	// Prepare INSERT statement with Oracle-style placeholders :1, :2, ...
-	placeholders := make([]string, len(cols))
-	for i := range placeholders {
-		placeholders[i] = fmt.Sprintf(":%d", i+1)
+	if opts.NoLogging {
+		if err := dynamic.SetLogging(ctx, db, sqlTable, false); err != nil {
+			return res, err
+		}
+		defer func() {
+			if lerr := dynamic.SetLogging(context.WithoutCancel(ctx), db, sqlTable, true); lerr != nil {
+				err = errors.Join(err, lerr)
+			}
+		}()
+	}
+	rej := &rejects{path: opts.RejectFile, comma: opts.Comma, headers: headers, max: opts.MaxRejects}
+	if opts.SkipBadRows {
+		if rej.path == "" {
+			rej.path = defaultRejectFile(csvPath)
+		}
+		if ckpt != nil {
+			if err := rej.reopen(ckpt.Rejected, ckpt.RejectSize); err != nil {
+				return res, err
+			}
+		}
+	}
+	pool, err := startPool(ctx, db, insertSQL, opts.Workers, opts.CommitEvery)
+	if err != nil {
+		rej.close()
+		return res, err
+	}
+	if ckptPath != "" {
+		saved := checkpoint{CSV: csvPath, Size: r.f.Size(), Table: sqlTable}
+		if ckpt != nil {
+			saved = *ckpt
+		}
+		resumed := saved.Rows
+		pool.progress = resume.NewTracker(saved.Line, saved.Done, job.lines)
+		pool.committed = func(run []job, rows int, done resume.Ranges) {
+			if len(run) > 0 {
+				at := run[len(run)-1]
+				saved.Line, saved.Rejected, saved.RejectSize = at.last, at.rejected, at.rejectSize
+			}
+			saved.Rows, saved.Done = resumed+rows, done
+			if err := resume.Write(ckptPath, saved); err != nil {
+				log.Warn("Checkpoint not saved", "checkpoint", ckptPath, logging.FieldError, err)
+			}
+		}
+	}
+
+	b := newBatch(types, batchSize)
+	b.reset(line)
+	last := line
+	// rejects written up to line last
+	lastRejected, lastRejectSize := rej.count, rej.size
+	flush := func() bool {
+		if b.len() == 0 {
+			return true
+		}
+		return pool.submit(job{first: b.first, rows: b.len(), last: last, args: b.args(),
+			rejected: lastRejected, rejectSize: lastRejectSize})
 	}
-	insertSQL := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", resolvedTable, strings.Join(oracleCols, ", "), strings.Join(placeholders, ", "))
 
-	stmt, err := db.PrepareContext(ctx, insertSQL)
+	// Rows stream from the file into the batch, so memory stays at one batch
+	// being filled plus up to two per worker (queued and in flight)
+	var prog *progress
+	if opts.Progress != nil {
+		prog = newProgress(opts.Progress, opts.ProgressEvery, r.f)
+	}
+	var reject func(int, []string, error) error
+	if opts.SkipBadRows {
+		reject = func(line int, rec []string, cause error) error {
+			// a line committed ahead of the failed batch was rejected by the
+			// interrupted load too, but dropped from the file with its checkpoint
+			if ckpt == nil || !ckpt.Done.Contains(line) {
+				res.RowsRead++
+			}
+			err := rej.add(line, rec, cause)
+			prog.row(pool.done, rej.count)
+			return err
+		}
+	}
+	var row []any
+	var extraErr error
+	readErr := r.stream(rec, line, cols, opts.Number, reject, func(line int, vals []any) bool {
+		if ckpt != nil && ckpt.Done.Contains(line) {
+			// committed by the interrupted load ahead of its failed batch
+			if b.len() == 0 {
+				b.reset(line + 1)
+			}
+			return true
+		}
+		if extra != nil {
+			if row, extraErr = appendExtras(append(row[:0], vals...), extra, line, false); extraErr != nil {
+				extraErr = fmt.Errorf("row %d: %w", line, extraErr)
+				return false
+			}
+			vals = row
+		}
+		res.RowsRead++
+		b.add(vals)
+		last = line
+		lastRejected, lastRejectSize = rej.count, rej.size
+		prog.row(pool.done, rej.count)
+		if b.len() < batchSize {
+			return true
+		}
+		ok := flush()
+		b.reset(line + 1)
+		return ok
+	})
+	if readErr == nil {
+		readErr = extraErr
+	}
+	if readErr == nil {
+		flush()
+	}
+	inserted, err = pool.wait()
+	prog.report(inserted, rej.count, true)
+	var closeErr error
+	res.Loaded, res.Rejected = inserted, rej.count
+	res.RejectFile, closeErr = rej.close()
+	if err = errors.Join(readErr, err, closeErr); err != nil {
+		log.Warn("Load stopped", logging.FieldTable, resolvedTable, "rows_read", res.RowsRead,
+			"committed", res.Loaded, "resumed", res.Resumed, "rejected", res.Rejected, logging.FieldError, err)
+		return res, err
+	}
+	run.SetRows(int64(inserted))
+	finishCheckpoint()
+	log.Info("CSV loaded", logging.FieldTable, resolvedTable, logging.FieldFile, csvPath,
+		logging.FieldRows, inserted, logging.FieldDuration, time.Since(start))
+	if res.Rejected > 0 {
+		log.Warn("Rows rejected", logging.FieldTable, resolvedTable, "rejected", res.Rejected, "reject_file", res.RejectFile)
+	}
+	return res, nil
+}
+
+// typedColumns builds the column definitions from the legacy types row
+func typedColumns(names, typesRow []string) ([]dynamic.ColumnDef, error) {
+	if len(typesRow) < len(names) {
+		return nil, fmt.Errorf("types row has fewer cells (%d) than headers (%d)", len(typesRow), len(names))
+	}
+	cols := make([]dynamic.ColumnDef, len(names))
+	for i, name := range names {
+		dt, ok := columnType(typesRow[i])
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %q for column %s", strings.ToUpper(strings.TrimSpace(typesRow[i])), name)
+		}
+		cols[i] = dynamic.ColumnDef{Name: name, Type: dt, Nullable: true}
+	}
+	return cols, nil
+}
+
+// ParseDelimiter parses a delimiter flag value: one character, or one of
+// the names tab, pipe, comma and semicolon; "" is the default comma
+func ParseDelimiter(s string) (rune, error) {
+	switch strings.ToLower(s) {
+	case "", "comma":
+		return ',', nil
+	case "tab", `\t`:
+		return '\t', nil
+	case "pipe":
+		return '|', nil
+	case "semicolon":
+		return ';', nil
+	}
+	if r := []rune(s); len(r) == 1 && r[0] != '"' && r[0] != '\r' && r[0] != '\n' && r[0] != utf8.RuneError {
+		return r[0], nil
+	}
+	return 0, fmt.Errorf("invalid delimiter %q: use a single character or tab, pipe, comma, semicolon", s)
+}
+
+// ParseNullValues parses a comma-separated NULL marker list such as
+// `NULL,\N,N/A`; blanks are dropped
+func ParseNullValues(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// ParseDateFormats splits the -date-formats flag value on semicolons, as
+// layouts may hold commas; nil when it is empty
+func ParseDateFormats(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// nullSet holds the NULL markers of Options.NullValues
+type nullSet map[string]bool
+
+func newNullSet(values []string) nullSet {
+	n := make(nullSet, len(values))
+	for _, v := range values {
+		n[v] = true
+	}
+	return n
+}
+
+// is reports whether cell loads as NULL: empty or one of the markers
+func (n nullSet) is(cell string) bool {
+	return cell == "" || n[cell]
+}
+
+// parseNumber converts a NUMBER cell to int64, or float64 when it has a
+// fraction or exponent or does not fit
+func parseNumber(cell string, f numformat.Format) (any, error) {
+	n, err := f.Normalize(cell)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NUMBER %q: %w", cell, err)
+	}
+	if !strings.ContainsAny(n, ".eE") {
+		if i, err := strconv.ParseInt(n, 10, 64); err == nil {
+			return i, nil
+		}
+	}
+	v, err := strconv.ParseFloat(n, 64)
 	if err != nil {
-		return fmt.Errorf("prepare insert: %w", err)
-	}
-	defer stmt.Close()
-
-	for rIdx, rec := range dataRows {
-		vals := make([]any, len(cols))
-		for cIdx := range cols {
-			cell := ""
-			if cIdx < len(rec) {
-				cell = strings.TrimSpace(rec[cIdx])
-			}
-			if cell == "" {
-				vals[cIdx] = sql.NullString{Valid: false}
-				continue
-			}
-			switch cols[cIdx].Type {
-			case dynamic.Number:
-				// Decide int64 vs float64
-				if strings.ContainsAny(cell, ".eE") {
-					if f, err := strconv.ParseFloat(cell, 64); err == nil {
-						vals[cIdx] = f
-					} else {
-						return fmt.Errorf("row %d col %d: invalid NUMBER %q: %v", rIdx+3, cIdx+1, cell, err)
-					}
-				} else {
-					if n, err := strconv.ParseInt(cell, 10, 64); err == nil {
-						vals[cIdx] = n
-					} else if f, err2 := strconv.ParseFloat(cell, 64); err2 == nil {
-						vals[cIdx] = f
-					} else {
-						return fmt.Errorf("row %d col %d: invalid NUMBER %q", rIdx+3, cIdx+1, cell)
-					}
-				}
-			default:
-				vals[cIdx] = cell
-			}
-		}
-		if _, err := stmt.ExecContext(ctx, vals...); err != nil {
-			return fmt.Errorf("insert row %d: %w", rIdx+3, err)
+		return nil, fmt.Errorf("invalid NUMBER %q: %w", cell, err)
+	}
+	return v, nil
+}
+
+var identRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// csvReader streams the non-empty records of a file with cells trimmed.
+// One csv.read span covers the file from openCSV to close.
+type csvReader struct {
+	f       *csvfile.File
+	r       *csv.Reader
+	span    *tracing.Span
+	records int
+	pending [][]string // sampled records next returns before reading on
+	keep    []int      // when set, the positions next projects records to
+	nulls   nullSet    // data cells loaded as NULL
+	dates   []string   // Options.DateFormats
+
+	transforms []Transform // per loaded column, nil entries for none
+}
+
+func openCSV(ctx context.Context, csvPath string, opts Options) (*csvReader, error) {
+	_, span := tracing.Start(ctx, tracing.SpanCSVRead, tracing.String(tracing.AttrFile, csvPath))
+	f, err := csvfile.OpenEncoded(csvPath, opts.Encoding)
+	if err != nil {
+		err = fmt.Errorf("open csv: %w", err)
+		span.End(err)
+		return nil, err
+	}
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1 // allow variable
+	if opts.Comma != 0 {
+		r.Comma = opts.Comma
+	}
+	r.Comment = opts.Comment
+	r.LazyQuotes = opts.LazyQuotes
+	return &csvReader{f: f, r: r, span: span, nulls: newNullSet(opts.NullValues), dates: opts.DateFormats}, nil
+}
+
+// next returns the next non-empty record, or io.EOF
+func (c *csvReader) next() ([]string, error) {
+	if len(c.pending) > 0 {
+		rec := c.pending[0]
+		c.pending = c.pending[1:]
+		return rec, nil
+	}
+	for {
+		rec, err := c.r.Read()
+		if err == io.EOF {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+		empty := true
+		for i := range rec {
+			rec[i] = strings.TrimSpace(rec[i])
+			if rec[i] != "" {
+				empty = false
+			}
+		}
+		if !empty {
+			c.records++
+			return c.project(rec), nil
+		}
+	}
+}
+
+// infer samples up to opts.InferRows data rows, starting with first (nil at
+// EOF), and infers the column types from them. The sampled rows are
+// returned by next again, so they are still loaded.
+func (c *csvReader) infer(names []string, first []string, opts Options) ([]dynamic.ColumnDef, error) {
+	n := opts.InferRows
+	if n <= 0 {
+		n = DefaultInferRows
+	}
+	var samples [][]string
+	for rec := first; rec != nil && len(samples) < n; {
+		samples = append(samples, rec)
+		if len(samples) == n {
+			break
+		}
+		var err error
+		if rec, err = c.next(); err != nil && err != io.EOF {
+			return nil, err
 		}
 	}
+	c.pending = samples
+	return inferColumns(names, c.transformSamples(names, samples), opts.Number, c.nulls, c.dates), nil
+}
+
+// stream converts rec, the record on CSV line line, and every record after
+// it to column values and passes them to add, until add returns false.
+// vals is reused between calls. A row that does not convert fails the
+// stream, or goes to reject when that is set.
+func (c *csvReader) stream(rec []string, line int, cols []dynamic.ColumnDef, f numformat.Format,
+	reject func(line int, rec []string, cause error) error, add func(line int, vals []any) bool) error {
+	vals := make([]any, len(cols))
+	for ; ; line++ {
+		if err := convertRow(rec, cols, f, c.nulls, c.dates, c.transforms, vals); err == nil {
+			if !add(line, vals) {
+				return nil
+			}
+		} else if reject == nil {
+			return fmt.Errorf("row %d %w", line, err)
+		} else if err := reject(line, rec, err); err != nil {
+			return err
+		}
+		var err error
+		if rec, err = c.next(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
 
+// convertRow fills vals from rec: nil for empty, NULL marker or missing
+// cells, int64 or float64 for NUMBER, time.Time for DATE and TIMESTAMP
+// when dates are given, the text otherwise. A column with a transform (tf
+// may be nil) gets its result instead, dates parsed as well.
+func convertRow(rec []string, cols []dynamic.ColumnDef, f numformat.Format, nulls nullSet, dates []string, tf []Transform, vals []any) error {
+	for i, col := range cols {
+		cell := ""
+		if i < len(rec) {
+			cell = rec[i]
+		}
+		vals[i] = nil
+		if tf != nil && tf[i] != nil {
+			v, err := transform(tf[i], col, cell)
+			if s, ok := v.(string); ok && err == nil && dates != nil && isDateType(col.Type) {
+				v, err = parseDate(s, col.Type, dates)
+			}
+			if err != nil {
+				return fmt.Errorf("col %d: %w", i+1, err)
+			}
+			vals[i] = v
+			continue
+		}
+		if nulls.is(cell) {
+			continue
+		}
+		switch col.Type {
+		case dynamic.Number:
+			v, err := parseNumber(cell, f)
+			if err != nil {
+				return fmt.Errorf("col %d: %w", i+1, err)
+			}
+			vals[i] = v
+		case dynamic.Date, dynamic.Timestamp:
+			if dates == nil {
+				vals[i] = cell
+				break
+			}
+			v, err := parseDate(cell, col.Type, dates)
+			if err != nil {
+				return fmt.Errorf("col %d: %w", i+1, err)
+			}
+			vals[i] = v
+		default:
+			vals[i] = cell
+		}
+	}
 	return nil
 }
 
-var identRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+func (c *csvReader) project(rec []string) []string {
+	if c.keep == nil {
+		return rec
+	}
+	out := make([]string, len(c.keep))
+	for i, k := range c.keep {
+		if k < len(rec) {
+			out[i] = rec[k]
+		}
+	}
+	return out
+}
+
+func (c *csvReader) close(err error) {
+	c.f.Close()
+	c.span.SetAttributes(tracing.Int(tracing.AttrRows, c.records))
+	c.span.End(err)
+}
+
+// HeaderOptions control how headers become column names. Headers are
+// always trimmed, and a UTF-8 byte order mark before the first is dropped.
+type HeaderOptions struct {
+	// CollapseSpaces turns each run of whitespace into a single underscore
+	// (a single space with QuotedIdentifiers), e.g. "first  name" FIRST_NAME
+	CollapseSpaces bool
+	// PreserveCase keeps the letter case instead of uppercasing, e.g.
+	// "Order Id" becomes the quoted column "Order_Id". The table name is
+	// still uppercased.
+	PreserveCase bool
+}
+
+// columnName turns a header or file name into a column or table name: kept
+// as is for quoted identifiers, else normalized and cut to the length limit
+func columnName(s string, ident dynamic.CreateOptions, h HeaderOptions) string {
+	s = strings.TrimSpace(s)
+	if h.CollapseSpaces {
+		s = strings.Join(strings.Fields(s), " ")
+	}
+	if ident.Quoted {
+		return s
+	}
+	maxLen := ident.MaxIdentifierLen
+	if maxLen <= 0 {
+		maxLen = 30
+	}
+	return normalizeIdentifierForOracle(s, maxLen, h.PreserveCase)
+}
 
 // normalizeIdentifierForOracle converts a string into a valid Oracle unquoted identifier:
-// - Uppercases
+// - Uppercases, unless keepCase
 // - Replaces invalid characters with underscore
 // - Ensures it starts with a letter (prefixes with X if needed)
-// - Truncates to 30 chars
-func normalizeIdentifierForOracle(s string) string {
+// - Truncates to maxLen chars
+func normalizeIdentifierForOracle(s string, maxLen int, keepCase bool) string {
 	if s == "" {
 		return ""
 	}
@@ -233,15 +935,18 @@ func normalizeIdentifierForOracle(s string) string {
 			b = append(b, '_')
 		}
 	}
-	upper := strings.ToUpper(string(b))
+	upper := string(b)
+	if !keepCase {
+		upper = strings.ToUpper(upper)
+	}
 	if len(upper) == 0 {
 		return ""
 	}
-	if !(upper[0] >= 'A' && upper[0] <= 'Z') {
+	if c := upper[0]; !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') {
 		upper = "X" + upper
 	}
-	if len(upper) > 30 {
-		upper = upper[:30]
+	if len(upper) > maxLen {
+		upper = upper[:maxLen]
 	}
 	if !identRe.MatchString(upper) {
 		return ""
@@ -0,0 +1,498 @@
+package csvdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	go_ora "github.com/sijms/go-ora/v2"
+
+	"sql-learn2/csvfile"
+	"sql-learn2/dynamic"
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+	"sql-learn2/logging"
+	"sql-learn2/numformat"
+)
+
+var quiet = logging.WithLogger(context.Background(), logging.Discard())
+
+func TestLoadCSVToDBAs_SQL(t *testing.T) {
+	const (
+		exists = "SELECT COUNT(1) FROM USER_TABLES WHERE TABLE_NAME = :1"
+		drop   = "DROP TABLE MY_FILE CASCADE CONSTRAINTS PURGE"
+		create = "CREATE TABLE MY_FILE (\n  ID NUMBER,\n  NAME VARCHAR2(255),\n  PRICE NUMBER\n)"
+		insert = "INSERT INTO MY_FILE (ID, NAME, PRICE) VALUES (:1, :2, :3)"
+	)
+	tests := []struct {
+		name   string
+		table  string
+		exists bool
+		lines  []string
+		want   []sqlfake.Call
+	}{
+		{
+			name:   "new table from file name",
+			lines:  []string{"id,name,price", "NUMBER,VARCHAR2,NUMBER", "1,apple,1.5", "2,,"},
+			exists: false,
+			want: []sqlfake.Call{
+				{Query: exists, Args: []any{"MY_FILE"}},
+				{Query: create, Args: []any{}},
+				{Query: insert, Args: []any{
+					[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}},
+					[]sql.NullString{{String: "apple", Valid: true}, {}},
+					[]sql.NullFloat64{{Float64: 1.5, Valid: true}, {}},
+				}},
+			},
+		},
+		{
+			name:   "existing table is dropped",
+			table:  "my file",
+			lines:  []string{"id,name,price", "NUMBER,VARCHAR2,NUMBER", "7,x"},
+			exists: true,
+			want: []sqlfake.Call{
+				{Query: exists, Args: []any{"MY_FILE"}},
+				{Query: drop, Args: []any{}},
+				{Query: create, Args: []any{}},
+				{Query: insert, Args: []any{
+					[]sql.NullInt64{{Int64: 7, Valid: true}},
+					[]sql.NullString{{String: "x", Valid: true}},
+					[]sql.NullInt64{{}},
+				}},
+			},
+		},
+		{
+			name:  "mixed numbers bound as text",
+			lines: []string{"id,name,price", "NUMBER,VARCHAR2,NUMBER", "1,a,12345678901234567", "2,b,2.5"},
+			want: []sqlfake.Call{
+				{Query: exists, Args: []any{"MY_FILE"}},
+				{Query: create, Args: []any{}},
+				{Query: insert, Args: []any{
+					[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}},
+					[]sql.NullString{{String: "a", Valid: true}, {String: "b", Valid: true}},
+					[]sql.NullString{{String: "12345678901234567", Valid: true}, {String: "2.5", Valid: true}},
+				}},
+			},
+		},
+		{
+			name:  "no data rows only creates",
+			lines: []string{"id,name,price", "NUMBER,VARCHAR2,NUMBER"},
+			want: []sqlfake.Call{
+				{Query: exists, Args: []any{"MY_FILE"}},
+				{Query: create, Args: []any{}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			n := int64(0)
+			if tt.exists {
+				n = 1
+			}
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{n})
+			path := testharness.WriteCSV(t, "my file.csv", tt.lines...)
+			if _, err := LoadCSVToDBAs(quiet, f.DB, path, tt.table); err != nil {
+				t.Fatalf("LoadCSVToDBAs: %v", err)
+			}
+			if got := f.Calls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calls:\n got %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCSVToDBAs_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		fail    string // statement pattern made to fail
+		wantErr string
+	}{
+		{"unsupported type", []string{"id,x", "NUMBER,BLOB", "1,2"}, "", `unsupported type "BLOB"`},
+		{"bad number", []string{"id", "NUMBER", "abc"}, "", `row 3 col 1: invalid NUMBER "abc"`},
+		{"insert fails", []string{"id", "NUMBER", "1", "2"}, "^INSERT", "insert rows 3-4: boom"},
+		{"create fails", []string{"id", "NUMBER"}, "^CREATE", "create table failed: boom"},
+		{"malformed row", []string{"id", "NUMBER", "1", `2"x`}, "", "read csv:"},
+		{"types row missing", []string{"id"}, "", "at least 2 rows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			if tt.fail != "" {
+				f.Fail(tt.fail, errors.New("boom"))
+			}
+			path := testharness.WriteCSV(t, "t.csv", tt.lines...)
+			_, err := LoadCSVToDBAs(quiet, f.DB, path, "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		cell    string
+		f       numformat.Format
+		want    any
+		wantErr bool
+	}{
+		{"42", numformat.Default, int64(42), false},
+		{"-1.5", numformat.Default, -1.5, false},
+		{"1e3", numformat.Default, 1000.0, false},
+		{"99999999999999999999", numformat.Default, 1e20, false},
+		{"1.234,56", numformat.Default, nil, true},
+		{"1.234,56", numformat.European, 1234.56, false},
+		{"€ 1.234", numformat.European, int64(1234), false},
+		{"1.5", numformat.European, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseNumber(tt.cell, tt.f)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseNumber(%q) = %v, %v; want %v", tt.cell, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoadCSVToDBWithOptions_Batches(t *testing.T) {
+	tests := []struct {
+		name      string
+		batchSize int
+		want      []int // rows per INSERT
+	}{
+		{"default", 0, []int{5}},
+		{"split", 2, []int{2, 2, 1}},
+		{"exact", 5, []int{5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			path := testharness.WriteCSV(t, "t.csv", "id", "NUMBER", "1", "2", "3", "4", "5")
+			if _, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{BatchSize: tt.batchSize}); err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, c := range f.Calls() {
+				if strings.HasPrefix(c.Query, "INSERT") {
+					got = append(got, len(c.Args[0].([]sql.NullInt64)))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows per insert = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	f := sqlfake.New(t)
+	f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+	f.Fail("^INSERT", errors.New("boom"))
+	path := testharness.WriteCSV(t, "t.csv", "id", "NUMBER", "1", "2", "3")
+	_, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{BatchSize: 2})
+	if err == nil || err.Error() != "insert rows 3-4: boom" {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestBindArray(t *testing.T) {
+	long := strings.Repeat("x", maxInlineString+1)
+	tests := []struct {
+		name string
+		typ  dynamic.DataType
+		col  []any
+		want any
+	}{
+		{"ints", dynamic.Number, []any{int64(1), nil}, []sql.NullInt64{{Int64: 1, Valid: true}, {}}},
+		{"decimals", dynamic.Number, []any{1.5, nil}, []sql.NullFloat64{{Float64: 1.5, Valid: true}, {}}},
+		{"mixed numbers as text", dynamic.Number, []any{int64(12345678901234567), 2.5, nil},
+			[]sql.NullString{{String: "12345678901234567", Valid: true}, {String: "2.5", Valid: true}, {}}},
+		{"all null number", dynamic.Number, []any{nil}, []sql.NullInt64{{}}},
+		{"dates as text", dynamic.Date, []any{"2024-01-02", nil}, []sql.NullString{{String: "2024-01-02", Valid: true}, {}}},
+		{"short clob as text", dynamic.Clob, []any{"a", nil}, []sql.NullString{{String: "a", Valid: true}, {}}},
+		{"long clob as lob", dynamic.Clob, []any{"a", long, nil}, []go_ora.Clob{{String: "a", Valid: true}, {String: long, Valid: true}, {}}},
+	}
+	for _, tt := range tests {
+		if got := bindArray(tt.typ, tt.col); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: bindArray = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadCSVToDBWithOptions_Dialect(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		lines []string
+		want  []sql.NullString
+	}{
+		{"pipe", Options{Comma: '|'}, []string{"id|name", "NUMBER|VARCHAR2", "1|a,b"}, []sql.NullString{{String: "a,b", Valid: true}}},
+		{"tab", Options{Comma: '\t'}, []string{"id\tname", "NUMBER\tVARCHAR2", "1\tx"}, []sql.NullString{{String: "x", Valid: true}}},
+		{"comment", Options{Comment: '#'}, []string{"# exported 2024-01-02", "id,name", "NUMBER,VARCHAR2", "# 0,skipped", "1,x"}, []sql.NullString{{String: "x", Valid: true}}},
+		{"lazy quotes", Options{LazyQuotes: true}, []string{"id,name", "NUMBER,VARCHAR2", `1,5" pipe`}, []sql.NullString{{String: `5" pipe`, Valid: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			path := testharness.WriteCSV(t, "t.csv", tt.lines...)
+			if _, err := LoadCSVToDBWithOptions(quiet, f.DB, path, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			calls := f.Calls()
+			if got := calls[len(calls)-1].Args[1]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("names = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCSVToDBWithOptions_NullValues(t *testing.T) {
+	lines := []string{"id,name", "NUMBER,VARCHAR2", "NULL,a", `1,\N`, "2,N/A", "3,null"}
+	tests := []struct {
+		name      string
+		nulls     []string
+		wantIDs   []sql.NullInt64
+		wantNames []sql.NullString
+		wantErr   string
+	}{
+		{
+			name:    "markers are text by default",
+			wantErr: `row 3 col 1: invalid NUMBER "NULL"`,
+		},
+		{
+			name:      "markers load as NULL",
+			nulls:     ParseNullValues(`NULL, \N,N/A`),
+			wantIDs:   []sql.NullInt64{{}, {Int64: 1, Valid: true}, {Int64: 2, Valid: true}, {Int64: 3, Valid: true}},
+			wantNames: []sql.NullString{{String: "a", Valid: true}, {}, {}, {String: "null", Valid: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			path := testharness.WriteCSV(t, "t.csv", lines...)
+			_, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{NullValues: tt.nulls})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			calls := f.Calls()
+			args := calls[len(calls)-1].Args
+			if !reflect.DeepEqual(args[0], tt.wantIDs) || !reflect.DeepEqual(args[1], tt.wantNames) {
+				t.Errorf("args = %#v, want %#v, %#v", args, tt.wantIDs, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestLoadCSVToDBWithOptions_DateFormats(t *testing.T) {
+	day := func(y int, m time.Month, d int) sql.NullTime {
+		return sql.NullTime{Time: time.Date(y, m, d, 0, 0, 0, 0, time.UTC), Valid: true}
+	}
+	tests := []struct {
+		name    string
+		lines   []string
+		formats []string
+		want    any
+		wantErr string
+	}{
+		{
+			name:  "text without formats",
+			lines: []string{"d", "DATE", "2024-01-31"},
+			want:  []sql.NullString{{String: "2024-01-31", Valid: true}},
+		},
+		{
+			name:    "typed column parsed",
+			lines:   []string{"d,n", "DATE,NUMBER", "31/01/2024,1", ",2", "2024-02-01,3"},
+			formats: []string{"02/01/2006", "2006-01-02"},
+			want:    []sql.NullTime{day(2024, 1, 31), {}, day(2024, 2, 1)},
+		},
+		{
+			name:    "inferred with the formats",
+			lines:   []string{"d", "31/01/2024", "01/02/2024"},
+			formats: []string{"02/01/2006"},
+			want:    []sql.NullTime{day(2024, 1, 31), day(2024, 2, 1)},
+		},
+		{
+			name:    "no format matches",
+			lines:   []string{"d", "DATE", "2024-01-31"},
+			formats: []string{"02/01/2006"},
+			wantErr: `row 3 col 1: invalid DATE "2024-01-31": want layout 02/01/2006`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			path := testharness.WriteCSV(t, "t.csv", tt.lines...)
+			_, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{DateFormats: tt.formats})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			calls := f.Calls()
+			if args := calls[len(calls)-1].Args; !reflect.DeepEqual(args[0], tt.want) {
+				t.Errorf("args = %#v, want %#v", args[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDelimiter(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    rune
+		wantErr bool
+	}{
+		{"", ',', false},
+		{"tab", '\t', false},
+		{`\t`, '\t', false},
+		{"PIPE", '|', false},
+		{";", ';', false},
+		{"\t", '\t', false},
+		{"||", 0, true},
+		{`"`, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDelimiter(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDelimiter(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoadCSVToDB_Gzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.csv.gz")
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("id\nNUMBER\n1\n2\n"))
+	zw.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f := sqlfake.New(t)
+	f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+	if _, err := LoadCSVToDB(quiet, f.DB, path); err != nil {
+		t.Fatal(err)
+	}
+	want := []sqlfake.Call{
+		{Query: "SELECT COUNT(1) FROM USER_TABLES WHERE TABLE_NAME = :1", Args: []any{"ORDERS"}},
+		{Query: "CREATE TABLE ORDERS (\n  ID NUMBER\n)", Args: []any{}},
+		{Query: "INSERT INTO ORDERS (ID) VALUES (:1)", Args: []any{[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}}}},
+	}
+	if got := f.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls:\n got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestLoadCSVToDBWithOptions_Encoding(t *testing.T) {
+	f := sqlfake.New(t)
+	f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+	path := filepath.Join(t.TempDir(), "t.csv")
+	// "name\nVARCHAR2\nไทย\n" in Windows-874
+	if err := os.WriteFile(path, []byte("name\nVARCHAR2\n\xe4\xb7\xc2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{Encoding: csvfile.Windows874}); err != nil {
+		t.Fatal(err)
+	}
+	calls := f.Calls()
+	want := []sql.NullString{{String: "ไทย", Valid: true}}
+	if got := calls[len(calls)-1].Args[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %#v, want %#v", got, want)
+	}
+}
+
+func TestLoadCSVToDB_Result(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        []string
+		wantCols     []dynamic.ColumnDef
+		wantInferred bool
+	}{
+		{
+			name:     "types row",
+			lines:    []string{"id,name", "NUMBER,VARCHAR2", "1,a", "2,b"},
+			wantCols: []dynamic.ColumnDef{{Name: "ID", Type: dynamic.Number, Nullable: true}, {Name: "NAME", Type: dynamic.Varchar2, Nullable: true}},
+		},
+		{
+			name:         "inferred",
+			lines:        []string{"id,name", "1,a", "2,b"},
+			wantCols:     []dynamic.ColumnDef{{Name: "ID", Type: dynamic.Number, Nullable: true}, {Name: "NAME", Type: dynamic.Varchar2, Length: 4000, Nullable: true}},
+			wantInferred: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			path := testharness.WriteCSV(t, "t.csv", tt.lines...)
+			st, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := LoadCSVToDB(quiet, f.DB, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.RowsRead != 2 || res.Loaded != 2 {
+				t.Errorf("read %d, loaded %d; want 2 and 2", res.RowsRead, res.Loaded)
+			}
+			if !reflect.DeepEqual(res.Columns, tt.wantCols) || res.Inferred != tt.wantInferred {
+				t.Errorf("columns = %+v (inferred %v), want %+v (inferred %v)", res.Columns, res.Inferred, tt.wantCols, tt.wantInferred)
+			}
+			if res.Bytes != st.Size() || res.Duration <= 0 {
+				t.Errorf("bytes = %d (file has %d), duration %s", res.Bytes, st.Size(), res.Duration)
+			}
+		})
+	}
+}
+
+func TestLoadCSVToDBWithOptions_Headers(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		header string
+		want   string
+	}{
+		{"BOM dropped", Options{}, "\ufeffid,name", "INSERT INTO T (ID, NAME)"},
+		{"spaces each", Options{}, "id,first   name", "INSERT INTO T (ID, FIRST___NAME)"},
+		{"spaces collapsed", Options{Headers: HeaderOptions{CollapseSpaces: true}}, "id,first \t name", "INSERT INTO T (ID, FIRST_NAME)"},
+		{"case preserved", Options{Headers: HeaderOptions{PreserveCase: true}}, "Id,Order Id", `INSERT INTO "T" ("Id", "Order_Id")`},
+		{"quoted collapsed", Options{QuotedIdentifiers: true, Headers: HeaderOptions{CollapseSpaces: true}}, "id,first   name", `INSERT INTO "t" ("id", "first name")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			path := testharness.WriteCSV(t, "t.csv", tt.header, "1,a")
+			if _, err := LoadCSVToDBWithOptions(quiet, f.DB, path, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			calls := f.Calls()
+			if got := calls[len(calls)-1].Query; !strings.HasPrefix(got, tt.want+" VALUES") {
+				t.Errorf("insert = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package csvdb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"sql-learn2/dynamic"
+)
+
+// TableMode says what a load does with the target table
+type TableMode int
+
+const (
+	// TableReplace drops the table if it exists and creates it from the CSV
+	TableReplace TableMode = iota
+	// TableAppend inserts into the existing table, keeping its rows, grants
+	// and indexes; the CSV columns must exist in it
+	TableAppend
+	// TableTruncate is TableAppend on the emptied table
+	TableTruncate
+)
+
+// ParseTableMode parses the -table-mode flag value: replace, append or truncate
+func ParseTableMode(s string) (TableMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "replace":
+		return TableReplace, nil
+	case "append":
+		return TableAppend, nil
+	case "truncate":
+		return TableTruncate, nil
+	}
+	return 0, fmt.Errorf("unknown table mode %q (use replace, append or truncate)", s)
+}
+
+func (m TableMode) String() string {
+	switch m {
+	case TableAppend:
+		return "append"
+	case TableTruncate:
+		return "truncate"
+	}
+	return "replace"
+}
+
+// matchTable checks the CSV columns against the existing table and returns
+// them with the table's types, which decide how cells are converted. Types
+// from a types row (typed) must also fit: text loads into any character
+// column, DATE and TIMESTAMP into each other, the rest only into their own.
+func matchTable(table string, cols []dynamic.ColumnDef, typed bool, existing []dynamic.TableColumn) ([]dynamic.ColumnDef, error) {
+	byName := make(map[string]dynamic.TableColumn, len(existing))
+	for _, c := range existing {
+		byName[c.Name] = c
+	}
+	out := make([]dynamic.ColumnDef, len(cols))
+	var errs []error
+	for i, c := range cols {
+		tc, ok := byName[c.Name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("column %s is not in the table", c.Name))
+			continue
+		}
+		if typed && !fits(c.Type, tc.Kind()) {
+			errs = append(errs, fmt.Errorf("column %s is %s in the CSV but %s in the table", c.Name, c.Type, tc.DataType))
+		}
+		out[i] = dynamic.ColumnDef{Name: c.Name, Type: tc.Kind(), Length: tc.Length, Nullable: tc.Nullable}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("csv does not match table %s: %w", table, errors.Join(errs...))
+	}
+	return out, nil
+}
+
+// fits reports whether a CSV column of type from loads into a column of kind to
+func fits(from, to dynamic.DataType) bool {
+	switch {
+	case from == to, to == dynamic.Varchar2, to == dynamic.Clob:
+		return true
+	case from == dynamic.Date || from == dynamic.Timestamp:
+		return to == dynamic.Date || to == dynamic.Timestamp
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package csvdb
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+)
+
+func TestLoadCSVToDBWithOptions_Mode(t *testing.T) {
+	tabCols := []string{"COLUMN_NAME", "DATA_TYPE", "CHAR_LENGTH", "NULLABLE"}
+	table := [][]any{
+		{"ID", "NUMBER", int64(0), "N"},
+		{"CODE", "VARCHAR2", int64(10), "Y"},
+		{"EXTRA", "DATE", int64(0), "Y"},
+	}
+	tests := []struct {
+		name    string
+		mode    TableMode
+		lines   []string
+		want    []string // statements run, by prefix
+		wantErr string
+	}{
+		{
+			name:  "append keeps the table",
+			mode:  TableAppend,
+			lines: []string{"id,code", "NUMBER,NUMBER", "1,007"},
+			want:  []string{"SELECT COLUMN_NAME", "INSERT INTO ORDERS (ID, CODE)"},
+		},
+		{
+			name:  "truncate empties it first",
+			mode:  TableTruncate,
+			lines: []string{"code", "007"}, // inferred NUMBER, loaded as the table's VARCHAR2
+			want:  []string{"SELECT COLUMN_NAME", "TRUNCATE TABLE ORDERS", "INSERT INTO ORDERS (CODE)"},
+		},
+		{
+			name:    "column not in table",
+			mode:    TableAppend,
+			lines:   []string{"id,qty", "NUMBER,NUMBER", "1,2"},
+			want:    []string{"SELECT COLUMN_NAME"},
+			wantErr: "csv does not match table ORDERS: column QTY is not in the table",
+		},
+		{
+			name:    "type does not fit",
+			mode:    TableTruncate,
+			lines:   []string{"id", "DATE", "2024-01-02"},
+			want:    []string{"SELECT COLUMN_NAME"},
+			wantErr: "column ID is DATE in the CSV but NUMBER in the table",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TAB_COLUMNS", tabCols, table...)
+			path := testharness.WriteCSV(t, "orders.csv", tt.lines...)
+			_, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{Mode: tt.mode})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			calls := f.Calls()
+			if len(calls) != len(tt.want) {
+				t.Fatalf("ran %q, want %q", f.Queries(), tt.want)
+			}
+			for i, c := range calls {
+				if !strings.HasPrefix(c.Query, tt.want[i]) {
+					t.Errorf("statement %d = %q, want %q...", i, c.Query, tt.want[i])
+				}
+			}
+			if tt.wantErr == "" {
+				code := calls[len(calls)-1].Args[len(calls[len(calls)-1].Args)-1]
+				if want := []sql.NullString{{String: "007", Valid: true}}; !reflect.DeepEqual(code, want) {
+					t.Errorf("CODE bound as %#v, want %#v", code, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTableMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    TableMode
+		wantErr bool
+	}{
+		{"", TableReplace, false},
+		{"Append", TableAppend, false},
+		{"truncate", TableTruncate, false},
+		{"merge", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTableMode(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseTableMode(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package csvdb
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"sql-learn2/dynamic"
+)
+
+// ExtraColumn is a column the CSV does not have, filled on every inserted
+// row: by the SQL expression, else by Func, else with Value. Column names it
+// as a header would and gives its type in the created table.
+type ExtraColumn struct {
+	Column dynamic.ColumnDef
+
+	SQL   string             // e.g. SYSDATE, put in the INSERT as it is
+	Func  func(line int) any // called per row with its CSV line
+	Value any                // nil, string, int, int64, float64 or time.Time
+}
+
+// LoadDateColumn is a DATE column set to SYSDATE, e.g. LOAD_DATE
+func LoadDateColumn(name string) ExtraColumn {
+	return ExtraColumn{Column: dynamic.ColumnDef{Name: name, Type: dynamic.Date, Nullable: true}, SQL: "SYSDATE"}
+}
+
+// SourceFileColumn is a VARCHAR2 column holding the file name of csvPath,
+// e.g. SOURCE_FILE = orders.csv
+func SourceFileColumn(name, csvPath string) ExtraColumn {
+	return ExtraColumn{
+		Column: dynamic.ColumnDef{Name: name, Type: dynamic.Varchar2, Length: 255, Nullable: true},
+		Value:  filepath.Base(csvPath),
+	}
+}
+
+// sqlExpr is an ExtraColumn.SQL expression in a Plan sample row
+type sqlExpr string
+
+// resolveExtras names the extra columns with toName and checks them against
+// the CSV columns
+func resolveExtras(extra []ExtraColumn, csvCols []string, toName func(string) string) ([]ExtraColumn, error) {
+	if len(extra) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(csvCols)+len(extra))
+	for _, c := range csvCols {
+		seen[c] = true
+	}
+	out := make([]ExtraColumn, len(extra))
+	for i, e := range extra {
+		name := toName(e.Column.Name)
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("invalid extra column name: %q", e.Column.Name)
+		case seen[name]:
+			return nil, fmt.Errorf("extra column %s is already a column", name)
+		case e.SQL == "" && e.Func == nil:
+			if _, err := bindValue(e.Value, e.Column); err != nil {
+				return nil, fmt.Errorf("extra column %s: %w", name, err)
+			}
+		}
+		seen[name] = true
+		e.Column.Name = name
+		out[i] = e
+	}
+	return out, nil
+}
+
+// extraDefs returns the column definitions of extra
+func extraDefs(extra []ExtraColumn) []dynamic.ColumnDef {
+	out := make([]dynamic.ColumnDef, len(extra))
+	for i, e := range extra {
+		out[i] = e.Column
+	}
+	return out
+}
+
+// appendExtras appends the values of the extra columns for the row on CSV
+// line line to vals. SQL expressions are bound to no placeholder, so they
+// are only appended, as sqlExpr, when exprs is set.
+func appendExtras(vals []any, extra []ExtraColumn, line int, exprs bool) ([]any, error) {
+	for _, e := range extra {
+		if e.SQL != "" {
+			if exprs {
+				vals = append(vals, sqlExpr(e.SQL))
+			}
+			continue
+		}
+		v := e.Value
+		if e.Func != nil {
+			v = e.Func(line)
+		}
+		v, err := bindValue(v, e.Column)
+		if err != nil {
+			return vals, fmt.Errorf("extra column %s: %w", e.Column.Name, err)
+		}
+		vals = append(vals, v)
+	}
+	return vals, nil
+}
@@ -0,0 +1,89 @@
+package csvdb
+
+import (
+	"database/sql"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"sql-learn2/dynamic"
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+)
+
+func TestLoadCSVToDBWithOptions_Extra(t *testing.T) {
+	lineNo := ExtraColumn{Column: dynamic.ColumnDef{Name: "line no", Type: dynamic.Number, Nullable: true}, Func: func(line int) any { return line }}
+	tests := []struct {
+		name       string
+		extra      []ExtraColumn
+		wantCreate string
+		wantInsert string
+		wantArgs   []any
+		wantErr    string
+	}{
+		{
+			name:       "expression, value and func",
+			extra:      []ExtraColumn{LoadDateColumn("load_date"), SourceFileColumn("source_file", "/in/t.csv"), lineNo},
+			wantCreate: "CREATE TABLE T (\n  ID NUMBER,\n  LOAD_DATE DATE,\n  SOURCE_FILE VARCHAR2(255),\n  LINE_NO NUMBER\n)",
+			wantInsert: "INSERT INTO T (ID, LOAD_DATE, SOURCE_FILE, LINE_NO) VALUES (:1, SYSDATE, :2, :3)",
+			wantArgs: []any{
+				[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}},
+				[]sql.NullString{{String: "t.csv", Valid: true}, {String: "t.csv", Valid: true}},
+				[]sql.NullInt64{{Int64: 3, Valid: true}, {Int64: 4, Valid: true}},
+			},
+		},
+		{
+			name:    "clashes with a CSV column",
+			extra:   []ExtraColumn{SourceFileColumn("Id", "t.csv")},
+			wantErr: "extra column ID is already a column",
+		},
+		{
+			name:    "value of the wrong type",
+			extra:   []ExtraColumn{{Column: dynamic.ColumnDef{Name: "BATCH", Type: dynamic.Number}, Value: true}},
+			wantErr: "extra column BATCH: bool for NUMBER column BATCH",
+		},
+		{
+			name:    "func value of the wrong type",
+			extra:   []ExtraColumn{{Column: dynamic.ColumnDef{Name: "BATCH", Type: dynamic.Number}, Func: func(int) any { return []byte{} }}},
+			wantErr: "row 3: extra column BATCH: []uint8 for NUMBER column BATCH",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			path := testharness.WriteCSV(t, "t.csv", "id", "NUMBER", "1", "2")
+			_, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{Extra: tt.extra})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q := f.Queries(); !slices.Contains(q, tt.wantCreate) {
+				t.Errorf("queries = %q, want %q", q, tt.wantCreate)
+			}
+			calls := f.Calls()
+			last := calls[len(calls)-1]
+			if last.Query != tt.wantInsert || !reflect.DeepEqual(last.Args, tt.wantArgs) {
+				t.Errorf("insert = %q %#v, want %q %#v", last.Query, last.Args, tt.wantInsert, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestLoadCSVToDBWithOptions_ExtraDryRun(t *testing.T) {
+	path := testharness.WriteCSV(t, "t.csv", "id", "NUMBER", "1")
+	res, err := LoadCSVToDBWithOptions(quiet, nil, path, Options{DryRun: true, Extra: []ExtraColumn{LoadDateColumn("LOAD_DATE"), SourceFileColumn("SOURCE_FILE", path)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "INSERT INTO T (ID, LOAD_DATE, SOURCE_FILE) VALUES (1, SYSDATE, 't.csv');\n"
+	if got := res.Plan.SQL(); !strings.HasSuffix(got, want) {
+		t.Errorf("SQL() = %q, want suffix %q", got, want)
+	}
+}
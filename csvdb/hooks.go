@@ -0,0 +1,30 @@
+package csvdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"sql-learn2/logging"
+)
+
+// runSQL executes stmts in order on one session, stopping at the first
+// that fails; phase (pre-load or post-load) names them in logs and errors
+func runSQL(ctx context.Context, db *sql.DB, phase string, stmts []string) error {
+	if len(stmts) == 0 {
+		return nil
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s SQL: %w", phase, err)
+	}
+	defer conn.Close()
+	log := logging.FromContext(ctx)
+	for i, stmt := range stmts {
+		log.Info("Running "+phase+" SQL", "statement", i+1, "sql", stmt)
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%s SQL %d: %w", phase, i+1, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package csvdb
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+)
+
+func TestLoadCSVToDBWithOptions_PrePostSQL(t *testing.T) {
+	opts := Options{
+		PreSQL:  []string{"ALTER TABLE T DISABLE CONSTRAINT T_FK", "DROP INDEX T_IX"},
+		PostSQL: []string{"CREATE INDEX T_IX ON T (ID)", "ALTER TABLE T ENABLE CONSTRAINT T_FK"},
+	}
+	const insert = "INSERT INTO T (ID) VALUES (:1)"
+	tests := []struct {
+		name    string
+		fail    string
+		want    []string // statements after CREATE TABLE
+		wantErr string
+	}{
+		{"around the load", "", []string{opts.PreSQL[0], opts.PreSQL[1], insert, opts.PostSQL[0], opts.PostSQL[1]}, ""},
+		{"pre failure stops the load", "^DROP INDEX", []string{opts.PreSQL[0], opts.PreSQL[1]}, "pre-load SQL 2: boom"},
+		{"post runs after a failed load", "^INSERT", []string{opts.PreSQL[0], opts.PreSQL[1], insert, opts.PostSQL[0], opts.PostSQL[1]}, "boom"},
+		{"post failure", "^CREATE INDEX", []string{opts.PreSQL[0], opts.PreSQL[1], insert, opts.PostSQL[0]}, "post-load SQL 1: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			if tt.fail != "" {
+				f.Fail(tt.fail, errors.New("boom"))
+			}
+			path := testharness.WriteCSV(t, "t.csv", "id", "NUMBER", "1")
+			_, err := LoadCSVToDBWithOptions(quiet, f.DB, path, opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, q := range f.Queries() {
+				if !strings.HasPrefix(q, "SELECT") && !strings.HasPrefix(q, "CREATE TABLE") {
+					got = append(got, q)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("statements = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlan_SQLWithHooks(t *testing.T) {
+	p := &Plan{
+		Insert:  "INSERT INTO T (ID) VALUES (:1)",
+		Sample:  [][]any{{int64(1)}},
+		PreSQL:  []string{"DROP INDEX T_IX"},
+		PostSQL: []string{"BEGIN DBMS_STATS.GATHER_TABLE_STATS(USER, 'T'); END;"},
+	}
+	want := "DROP INDEX T_IX;\n" +
+		"INSERT INTO T (ID) VALUES (1);\n" +
+		"BEGIN DBMS_STATS.GATHER_TABLE_STATS(USER, 'T'); END;\n/\n"
+	if got := p.SQL(); got != want {
+		t.Errorf("SQL() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,163 @@
+package csvdb
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"sql-learn2/dynamic"
+	"sql-learn2/numformat"
+)
+
+// TypesMode says where the column types of a CSV come from
+type TypesMode int
+
+const (
+	// TypesAuto uses the second row as types row when it names a supported
+	// type, and infers the types from the data otherwise
+	TypesAuto TypesMode = iota
+	// TypesRow requires the legacy format: header row, then types row
+	TypesRow
+	// TypesInfer treats every row after the header as data and infers the types
+	TypesInfer
+)
+
+// DefaultInferRows is the data rows sampled when Options.InferRows is 0
+const DefaultInferRows = 1000
+
+// inferredVarcharLength is the VARCHAR2 length of inferred text columns; a
+// later row may be longer than any sampled one, so the sample is not used
+const inferredVarcharLength = 4000
+
+// ParseTypesMode parses the -csv-types flag value: auto, row or infer
+func ParseTypesMode(s string) (TypesMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "auto":
+		return TypesAuto, nil
+	case "row":
+		return TypesRow, nil
+	case "infer":
+		return TypesInfer, nil
+	}
+	return 0, fmt.Errorf("unknown csv types mode %q (use auto, row or infer)", s)
+}
+
+func (m TypesMode) String() string {
+	switch m {
+	case TypesRow:
+		return "row"
+	case TypesInfer:
+		return "infer"
+	}
+	return "auto"
+}
+
+// columnType maps a types row cell to a DataType
+func columnType(cell string) (dynamic.DataType, bool) {
+	switch strings.ToUpper(strings.TrimSpace(cell)) {
+	case "VARCHAR", "VARCHAR2":
+		return dynamic.Varchar2, true
+	case "NUMBER":
+		return dynamic.Number, true
+	case "DATE":
+		return dynamic.Date, true
+	case "TIMESTAMP":
+		return dynamic.Timestamp, true
+	case "CLOB":
+		return dynamic.Clob, true
+	}
+	return "", false
+}
+
+// isTypesRow reports whether rec looks like a types row. One known type
+// name is enough, so a typo in another cell still fails as unsupported
+// instead of the row being loaded as data.
+func isTypesRow(rec []string) bool {
+	for _, cell := range rec {
+		if _, ok := columnType(cell); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// inferDateLayouts are the DATE cells inference recognizes without
+// Options.DateFormats. The values are then still bound as text, so the
+// session NLS_DATE_FORMAT must match the file (see oraconn -nls-date-format).
+var inferDateLayouts = []string{"2006-01-02", "2006-01-02 15:04:05"}
+
+// inferColumns derives the column definitions from sampled data rows.
+// Per column, over the cells that are not NULL: all numbers is NUMBER, all dates of
+// one layout is DATE, any cell over the VARCHAR2 limit is CLOB, and
+// anything else (or no values at all) is VARCHAR2. Dates are matched
+// against layouts, or inferDateLayouts when it is nil.
+func inferColumns(names []string, samples [][]string, f numformat.Format, nulls nullSet, layouts []string) []dynamic.ColumnDef {
+	if layouts == nil {
+		layouts = inferDateLayouts
+	}
+	cols := make([]dynamic.ColumnDef, len(names))
+	for i, name := range names {
+		number, date, clob, seen := true, true, false, false
+		layout := ""
+		for _, rec := range samples {
+			if i >= len(rec) || nulls.is(rec[i]) {
+				continue
+			}
+			cell := rec[i]
+			seen = true
+			if len(cell) > inferredVarcharLength {
+				clob = true
+			}
+			if number {
+				_, err := parseNumber(cell, f)
+				number = err == nil
+			}
+			if date {
+				date = matchesLayout(cell, layouts, &layout)
+			}
+		}
+		c := dynamic.ColumnDef{Name: name, Type: dynamic.Varchar2, Length: inferredVarcharLength, Nullable: true}
+		switch {
+		case !seen:
+		case clob:
+			c.Type, c.Length = dynamic.Clob, 0
+		case number:
+			c.Type, c.Length = dynamic.Number, 0
+		case date:
+			c.Type, c.Length = dynamic.Date, 0
+		}
+		cols[i] = c
+	}
+	return cols
+}
+
+// matchesLayout reports whether cell parses with *layout, or with the first
+// matching entry of layouts when *layout is still empty
+func matchesLayout(cell string, layouts []string, layout *string) bool {
+	if *layout != "" {
+		_, err := time.Parse(*layout, cell)
+		return err == nil
+	}
+	for _, l := range layouts {
+		if _, err := time.Parse(l, cell); err == nil {
+			*layout = l
+			return true
+		}
+	}
+	return false
+}
+
+func isDateType(t dynamic.DataType) bool {
+	return t == dynamic.Date || t == dynamic.Timestamp
+}
+
+// parseDate converts a DATE or TIMESTAMP cell with the first of layouts it
+// matches
+func parseDate(cell string, t dynamic.DataType, layouts []string) (time.Time, error) {
+	for _, l := range layouts {
+		if v, err := time.Parse(l, cell); err == nil {
+			return v, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid %s %q: want layout %s", t, cell, strings.Join(layouts, " or "))
+}
@@ -0,0 +1,105 @@
+package csvdb
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sql-learn2/dynamic"
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+	"sql-learn2/numformat"
+)
+
+func TestInferColumns(t *testing.T) {
+	long := strings.Repeat("x", 4001)
+	tests := []struct {
+		name    string
+		samples [][]string
+		f       numformat.Format
+		want    dynamic.DataType
+	}{
+		{"ints", [][]string{{"1"}, {"-2"}}, numformat.Default, dynamic.Number},
+		{"floats with nulls", [][]string{{"1.5"}, {""}, {}}, numformat.Default, dynamic.Number},
+		{"european", [][]string{{"1.234,5"}}, numformat.European, dynamic.Number},
+		{"dates", [][]string{{"2024-01-02"}, {"2024-12-31"}}, numformat.Default, dynamic.Date},
+		{"date times", [][]string{{"2024-01-02 10:00:00"}}, numformat.Default, dynamic.Date},
+		{"mixed date layouts", [][]string{{"2024-01-02"}, {"2024-01-02 10:00:00"}}, numformat.Default, dynamic.Varchar2},
+		{"text", [][]string{{"1"}, {"a"}}, numformat.Default, dynamic.Varchar2},
+		{"long text", [][]string{{"a"}, {long}}, numformat.Default, dynamic.Clob},
+		{"all null", [][]string{{""}}, numformat.Default, dynamic.Varchar2},
+		{"null markers", [][]string{{"1"}, {"NULL"}, {`\N`}}, numformat.Default, dynamic.Number},
+	}
+	nulls := newNullSet([]string{"NULL", `\N`})
+	for _, tt := range tests {
+		cols := inferColumns([]string{"C"}, tt.samples, tt.f, nulls, nil)
+		if got := cols[0].Type; got != tt.want {
+			t.Errorf("%s: type = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadCSVToDBWithOptions_Types(t *testing.T) {
+	const (
+		typed    = "CREATE TABLE T (\n  ID NUMBER,\n  NAME VARCHAR2(255)\n)"
+		inferred = "CREATE TABLE T (\n  ID NUMBER,\n  NAME VARCHAR2(4000)\n)"
+	)
+	tests := []struct {
+		name       string
+		opts       Options
+		lines      []string
+		wantCreate string
+		wantIDs    []sql.NullInt64
+		wantErr    string
+	}{
+		{"types row detected", Options{}, []string{"id,name", "NUMBER,VARCHAR2", "1,a"}, typed, []sql.NullInt64{{Int64: 1, Valid: true}}, ""},
+		{"inferred", Options{}, []string{"id,name", "1,a", ",b", "3,c"}, inferred,
+			[]sql.NullInt64{{Int64: 1, Valid: true}, {}, {Int64: 3, Valid: true}}, ""},
+		{"sample smaller than file", Options{InferRows: 1, BatchSize: 2}, []string{"id,name", "1,a", "2,b", "3,c"}, inferred,
+			[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}}, ""},
+		{"forced infer on header only", Options{Types: TypesInfer}, []string{"id,name"},
+			"CREATE TABLE T (\n  ID VARCHAR2(4000),\n  NAME VARCHAR2(4000)\n)", nil, ""},
+		{"forced types row", Options{Types: TypesRow}, []string{"id,name", "1,a"}, "", nil, `unsupported type "1"`},
+		{"inferred type broken after sample", Options{InferRows: 1}, []string{"id,name", "1,a", "x,b"}, "", nil, `row 3 col 1: invalid NUMBER "x"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			path := testharness.WriteCSV(t, "t.csv", tt.lines...)
+			_, err := LoadCSVToDBWithOptions(quiet, f.DB, path, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			calls := f.Calls()
+			if calls[1].Query != tt.wantCreate {
+				t.Errorf("create = %q, want %q", calls[1].Query, tt.wantCreate)
+			}
+			var ids []sql.NullInt64
+			if len(calls) > 2 {
+				ids = calls[2].Args[0].([]sql.NullInt64)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("first batch ids = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestParseTypesMode(t *testing.T) {
+	for in, want := range map[string]TypesMode{"": TypesAuto, "auto": TypesAuto, "ROW": TypesRow, "infer": TypesInfer} {
+		if got, err := ParseTypesMode(in); err != nil || got != want {
+			t.Errorf("ParseTypesMode(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseTypesMode("guess"); err == nil {
+		t.Error("ParseTypesMode(guess): want error")
+	}
+}
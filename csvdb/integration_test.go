@@ -0,0 +1,46 @@
+//go:build integration
+
+package csvdb
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"sql-learn2/internal/testharness"
+)
+
+// Run with: go test -tags integration ./csvdb (see internal/testharness for ORA_TEST_DSN)
+func TestMain(m *testing.M) { os.Exit(testharness.Main(m)) }
+
+func TestLoadCSVToDBAs_Integration(t *testing.T) {
+	db := testharness.Oracle(t)
+	ctx := context.Background()
+	table := testharness.TableName("CSVDB")
+	testharness.DropOnCleanup(t, db, table)
+
+	path := testharness.WriteCSV(t, "products.csv",
+		"id,name,price",
+		"NUMBER,VARCHAR2,NUMBER",
+		"1,apple,1.5",
+		"2,pear,",
+	)
+	if _, err := LoadCSVToDBAs(ctx, db, path, table); err != nil {
+		t.Fatalf("LoadCSVToDBAs: %v", err)
+	}
+	got := testharness.Rows(t, db, "SELECT ID, NAME, PRICE FROM "+table+" ORDER BY ID")
+	want := [][]string{{"1", "apple", "1.5"}, {"2", "pear", ""}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+
+	// A second load replaces the table
+	path = testharness.WriteCSV(t, "products.csv", "id,name", "NUMBER,VARCHAR2", "3,plum")
+	if _, err := LoadCSVToDBAs(ctx, db, path, table); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if n := testharness.Count(t, db, table); n != 1 {
+		t.Errorf("rows after reload = %d, want 1", n)
+	}
+}
@@ -0,0 +1,65 @@
+package csvdb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mapColumns picks the CSV columns to load and names their table columns
+// with toName: Options.Columns entries first, then the header. keep holds the
+// positions of the loaded columns in the file, kept their headers.
+func mapColumns(headers []string, m map[string]string, onlyMapped bool, toName func(string) string) (keep []int, kept, names []string, err error) {
+	byHeader := make(map[string]string, len(m))
+	for h, col := range m {
+		byHeader[headerKey(h)] = col
+	}
+	seen := map[string]string{} // table column -> header
+	for i, h := range headers {
+		col, mapped := byHeader[headerKey(h)]
+		delete(byHeader, headerKey(h))
+		if mapped && col == "" || !mapped && onlyMapped {
+			continue
+		}
+		if !mapped {
+			col = h
+		}
+		name := toName(col)
+		if name == "" {
+			return nil, nil, nil, fmt.Errorf("invalid column name at position %d: %q", i+1, col)
+		}
+		if prev, dup := seen[name]; dup {
+			return nil, nil, nil, fmt.Errorf("headers %q and %q both load into column %s", prev, h, name)
+		}
+		seen[name] = h
+		keep = append(keep, i)
+		kept = append(kept, h)
+		names = append(names, name)
+	}
+	for h := range byHeader {
+		return nil, nil, nil, fmt.Errorf("column mapping: no CSV header %q", h)
+	}
+	if len(names) == 0 {
+		return nil, nil, nil, fmt.Errorf("no columns left to load")
+	}
+	return keep, kept, names, nil
+}
+
+func headerKey(h string) string { return strings.ToLower(strings.TrimSpace(h)) }
+
+// ParseColumnMap parses a -columns flag value: comma-separated
+// header=COLUMN pairs, where an empty COLUMN skips the header, e.g.
+// "first name=FNAME,notes="
+func ParseColumnMap(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		h, col, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(h) == "" {
+			return nil, fmt.Errorf("invalid column mapping %q: want header=COLUMN", pair)
+		}
+		m[strings.TrimSpace(h)] = strings.TrimSpace(col)
+	}
+	return m, nil
+}
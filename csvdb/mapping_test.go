@@ -0,0 +1,139 @@
+package csvdb
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sql-learn2/dynamic"
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+)
+
+func TestMapColumns(t *testing.T) {
+	headers := []string{"id", "first name", "notes (free text)"}
+	tests := []struct {
+		name       string
+		m          map[string]string
+		onlyMapped bool
+		wantKeep   []int
+		wantNames  []string
+		wantErr    string
+	}{
+		{"no mapping", nil, false, []int{0, 1, 2}, []string{"ID", "FIRST_NAME", "NOTES__FREE_TEXT_"}, ""},
+		{"rename and skip", map[string]string{"First Name": "FNAME", "notes (free text)": ""}, false, []int{0, 1}, []string{"ID", "FNAME"}, ""},
+		{"only mapped", map[string]string{"notes (free text)": "NOTES"}, true, []int{2}, []string{"NOTES"}, ""},
+		{"unknown header", map[string]string{"last name": "LNAME"}, false, nil, nil, `no CSV header "last name"`},
+		{"duplicate target", map[string]string{"first name": "ID", "notes (free text)": ""}, false, nil, nil, `both load into column ID`},
+		{"nothing left", map[string]string{}, true, nil, nil, "no columns left"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep, _, names, err := mapColumns(headers, tt.m, tt.onlyMapped, func(s string) string { return columnName(s, dynamic.CreateOptions{}, HeaderOptions{}) })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(keep, tt.wantKeep) || !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("mapColumns = %v, %v, %v; want %v, %v", keep, names, err, tt.wantKeep, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestLoadCSVToDBWithOptions_Columns(t *testing.T) {
+	f := sqlfake.New(t)
+	f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+	path := testharness.WriteCSV(t, "people.csv",
+		"id,first name,password",
+		"NUMBER,VARCHAR2,VARCHAR2",
+		"1,Ann,secret",
+		"2,Bob")
+	opts := Options{Columns: map[string]string{"first name": "FNAME", "password": ""}}
+	if _, err := LoadCSVToDBWithOptions(quiet, f.DB, path, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := []sqlfake.Call{
+		{Query: "SELECT COUNT(1) FROM USER_TABLES WHERE TABLE_NAME = :1", Args: []any{"PEOPLE"}},
+		{Query: "CREATE TABLE PEOPLE (\n  ID NUMBER,\n  FNAME VARCHAR2(255)\n)", Args: []any{}},
+		{Query: "INSERT INTO PEOPLE (ID, FNAME) VALUES (:1, :2)", Args: []any{
+			[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 2, Valid: true}},
+			[]sql.NullString{{String: "Ann", Valid: true}, {String: "Bob", Valid: true}},
+		}},
+	}
+	if got := f.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls:\n got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseColumnMap(t *testing.T) {
+	got, err := ParseColumnMap(" first name = FNAME ,notes=")
+	want := map[string]string{"first name": "FNAME", "notes": ""}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseColumnMap = %v, %v; want %v", got, err, want)
+	}
+	if m, err := ParseColumnMap(""); m != nil || err != nil {
+		t.Errorf("ParseColumnMap(\"\") = %v, %v", m, err)
+	}
+	if _, err := ParseColumnMap("id"); err == nil {
+		t.Error("ParseColumnMap(id): want error")
+	}
+}
+
+func TestLoadCSVToDBWithOptions_Identifiers(t *testing.T) {
+	const header = "customer lifetime value estimate,First Name"
+	tests := []struct {
+		name       string
+		opts       Options
+		maxLen     int64 // answer to the server limit query; 0 = not asked
+		wantCreate string
+		wantInsert string
+	}{
+		{
+			name:       "classic 30 bytes",
+			wantCreate: "CREATE TABLE T (\n  CUSTOMER_LIFETIME_VALUE_ESTIMA NUMBER,\n  FIRST_NAME VARCHAR2(255)\n)",
+			wantInsert: "INSERT INTO T (CUSTOMER_LIFETIME_VALUE_ESTIMA, FIRST_NAME) VALUES (:1, :2)",
+		},
+		{
+			name:       "detected 128",
+			opts:       Options{DetectIdentifierLen: true},
+			maxLen:     128,
+			wantCreate: "CREATE TABLE T (\n  CUSTOMER_LIFETIME_VALUE_ESTIMATE NUMBER,\n  FIRST_NAME VARCHAR2(255)\n)",
+			wantInsert: "INSERT INTO T (CUSTOMER_LIFETIME_VALUE_ESTIMATE, FIRST_NAME) VALUES (:1, :2)",
+		},
+		{
+			name:       "quoted",
+			opts:       Options{QuotedIdentifiers: true, MaxIdentifierLen: 128, Table: "t"},
+			wantCreate: "CREATE TABLE \"t\" (\n  \"customer lifetime value estimate\" NUMBER,\n  \"First Name\" VARCHAR2(255)\n)",
+			wantInsert: `INSERT INTO "t" ("customer lifetime value estimate", "First Name") VALUES (:1, :2)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			if tt.maxLen > 0 {
+				f.OnQuery("ORA_MAX_NAME_LEN_SUPPORTED", []string{"N"}, []any{tt.maxLen})
+			}
+			path := testharness.WriteCSV(t, "t.csv", header, "NUMBER,VARCHAR2", "1,Ann")
+			if _, err := LoadCSVToDBWithOptions(quiet, f.DB, path, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			var create, insert string
+			for _, q := range f.Queries() {
+				switch {
+				case strings.HasPrefix(q, "CREATE"):
+					create = q
+				case strings.HasPrefix(q, "INSERT"):
+					insert = q
+				}
+			}
+			if create != tt.wantCreate || insert != tt.wantInsert {
+				t.Errorf("create = %q\ninsert = %q\nwant %q\n     %q", create, insert, tt.wantCreate, tt.wantInsert)
+			}
+		})
+	}
+}
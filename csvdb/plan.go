@@ -0,0 +1,102 @@
+package csvdb
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"sql-learn2/dynamic"
+	"sql-learn2/numformat"
+)
+
+// dryRunSample is how many data rows a Plan shows
+const dryRunSample = 5
+
+// Plan is what a load would run, returned instead of loading with Options.DryRun
+type Plan struct {
+	Table   string
+	Columns []dynamic.ColumnDef
+	DDL     string  // CREATE TABLE (an existing table of that name is dropped first), TRUNCATE TABLE or empty
+	Insert  string  // the array-bound INSERT, one bind per column
+	Sample  [][]any // the first data rows as they would be bound
+	PreSQL  []string
+	PostSQL []string
+}
+
+// SQL renders the plan as a script: the DDL and Options.PreSQL, the sample
+// rows as literal INSERT statements, then Options.PostSQL
+func (p *Plan) SQL() string {
+	var b strings.Builder
+	if p.DDL != "" {
+		b.WriteString(p.DDL)
+		b.WriteString(";\n")
+	}
+	writeStatements(&b, p.PreSQL)
+	into, _, _ := strings.Cut(p.Insert, " VALUES ")
+	for _, row := range p.Sample {
+		lits := make([]string, len(row))
+		for i, v := range row {
+			lits[i] = literal(v)
+		}
+		fmt.Fprintf(&b, "%s VALUES (%s);\n", into, strings.Join(lits, ", "))
+	}
+	writeStatements(&b, p.PostSQL)
+	return b.String()
+}
+
+// writeStatements adds stmts to a script, a PL/SQL block (ending in
+// "END;") followed by a / line
+func writeStatements(b *strings.Builder, stmts []string) {
+	for _, s := range stmts {
+		b.WriteString(s)
+		if strings.HasSuffix(s, ";") {
+			b.WriteString("\n/\n")
+		} else {
+			b.WriteString(";\n")
+		}
+	}
+}
+
+func literal(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case sqlExpr:
+		return string(v)
+	case time.Time:
+		return "TIMESTAMP '" + v.Format("2006-01-02 15:04:05.999999999") + "'"
+	default:
+		return "'" + strings.ReplaceAll(fmt.Sprint(v), "'", "''") + "'"
+	}
+}
+
+// plan converts rec and the rows after it, up to dryRunSample, for a Plan;
+// the extra columns follow the CSV ones
+func (c *csvReader) plan(table string, cols []dynamic.ColumnDef, extra []ExtraColumn, ident dynamic.CreateOptions, insertSQL string, rec []string, line int, f numformat.Format) (*Plan, error) {
+	all := append(cols[:len(cols):len(cols)], extraDefs(extra)...)
+	ddl, err := dynamic.CreateTableDDL(table, all, ident)
+	if err != nil {
+		return nil, err
+	}
+	p := &Plan{Table: table, Columns: all, DDL: ddl, Insert: insertSQL}
+	for ; rec != nil && len(p.Sample) < dryRunSample; line++ {
+		vals := make([]any, len(cols))
+		if err := convertRow(rec, cols, f, c.nulls, c.dates, c.transforms, vals); err != nil {
+			return nil, fmt.Errorf("row %d %w", line, err)
+		}
+		if vals, err = appendExtras(vals, extra, line, true); err != nil {
+			return nil, fmt.Errorf("row %d: %w", line, err)
+		}
+		p.Sample = append(p.Sample, vals)
+		if rec, err = c.next(); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,70 @@
+package csvdb
+
+import (
+	"testing"
+
+	"sql-learn2/internal/testharness"
+)
+
+func TestLoadCSVToDBWithOptions_DryRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		lines      []string
+		wantSQL    string
+		wantInsert string
+		wantErr    string
+	}{
+		{
+			name:  "typed",
+			lines: []string{"id,name,price", "NUMBER,VARCHAR2,NUMBER", "1,O'Brien,1.5", "2,,"},
+			wantSQL: "CREATE TABLE ORDERS (\n  ID NUMBER,\n  NAME VARCHAR2(255),\n  PRICE NUMBER\n);\n" +
+				"INSERT INTO ORDERS (ID, NAME, PRICE) VALUES (1, 'O''Brien', 1.5);\n" +
+				"INSERT INTO ORDERS (ID, NAME, PRICE) VALUES (2, NULL, NULL);\n",
+			wantInsert: "INSERT INTO ORDERS (ID, NAME, PRICE) VALUES (:1, :2, :3)",
+		},
+		{
+			name:  "inferred, sample capped",
+			lines: []string{"id", "1", "2", "3", "4", "5", "6"},
+			wantSQL: "CREATE TABLE ORDERS (\n  ID NUMBER\n);\n" +
+				"INSERT INTO ORDERS (ID) VALUES (1);\n" +
+				"INSERT INTO ORDERS (ID) VALUES (2);\n" +
+				"INSERT INTO ORDERS (ID) VALUES (3);\n" +
+				"INSERT INTO ORDERS (ID) VALUES (4);\n" +
+				"INSERT INTO ORDERS (ID) VALUES (5);\n",
+			wantInsert: "INSERT INTO ORDERS (ID) VALUES (:1)",
+		},
+		{
+			name:       "no data",
+			lines:      []string{"id", "NUMBER"},
+			wantSQL:    "CREATE TABLE ORDERS (\n  ID NUMBER\n);\n",
+			wantInsert: "INSERT INTO ORDERS (ID) VALUES (:1)",
+		},
+		{
+			name:    "bad sample row",
+			lines:   []string{"id", "NUMBER", "x"},
+			wantErr: `row 3 col 1: invalid NUMBER "x": strconv.ParseFloat: parsing "x": invalid syntax`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := testharness.WriteCSV(t, "orders.csv", tt.lines...)
+			// a nil db proves nothing is executed
+			res, err := LoadCSVToDBWithOptions(quiet, nil, path, Options{DryRun: true})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.Plan.SQL(); got != tt.wantSQL {
+				t.Errorf("SQL:\n%s\nwant:\n%s", got, tt.wantSQL)
+			}
+			if res.Plan.Insert != tt.wantInsert {
+				t.Errorf("Insert = %q, want %q", res.Plan.Insert, tt.wantInsert)
+			}
+		})
+	}
+}
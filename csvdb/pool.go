@@ -0,0 +1,218 @@
+package csvdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"sql-learn2/internal/connpool"
+	"sql-learn2/internal/resume"
+)
+
+// job is one converted batch waiting to be inserted
+type job struct {
+	seq         int // submission order, set by submit
+	first, rows int // CSV lines first to first+rows-1, for errors
+	last        int // CSV line of the last row; rejected rows make it differ
+	args        []any
+
+	// rejected rows and reject file bytes written up to line last, for
+	// the checkpoint
+	rejected   int
+	rejectSize int64
+}
+
+// lines is the CSV lines of j, rejected rows included
+func (j job) lines() resume.Range {
+	return resume.Range{First: j.first, Last: j.last}
+}
+
+// insertPool inserts jobs on its own connections, one prepared statement
+// each. The first failure cancels the others; wait reports every failure.
+type insertPool struct {
+	parent context.Context
+	ctx    context.Context // canceled on the first failure
+	cancel context.CancelFunc
+	jobs   chan job
+	wg     sync.WaitGroup
+
+	commitEvery int // rows per transaction; 0 commits every job on its own
+
+	seq int // next job number
+
+	mu       sync.Mutex
+	inserted int // committed rows
+	errs     []error
+
+	// progress, when set, follows the inserted jobs by CSV line, and
+	// committed is called under mu after every one with the jobs the
+	// position moved over (the last one ends at the line every row up to
+	// is inserted), the rows inserted in all, and the lines inserted past
+	// it: with several workers a later job can commit before an earlier
+	// one fails
+	progress  *resume.Tracker[job]
+	committed func(run []job, rows int, done resume.Ranges)
+}
+
+// startPool opens workers connections and prepares insertSQL on each before
+// any row is sent, so a bad statement fails once and up front. With
+// commitEvery > 0 each worker commits once its open transaction holds that
+// many rows, rounded up to whole jobs.
+func startPool(ctx context.Context, db *sql.DB, insertSQL string, workers, commitEvery int) (*insertPool, error) {
+	conns, err := connpool.Open(ctx, db, insertSQL, "insert", workers)
+	if err != nil {
+		return nil, fmt.Errorf("prepare insert: %w", err)
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	p := &insertPool{parent: parent, ctx: ctx, cancel: cancel, jobs: make(chan job, len(conns)), commitEvery: commitEvery}
+	for _, w := range conns {
+		p.wg.Add(1)
+		go p.work(ctx, w.Conn, w.Stmt)
+	}
+	return p, nil
+}
+
+func (p *insertPool) work(ctx context.Context, conn *sql.Conn, stmt *sql.Stmt) {
+	defer p.wg.Done()
+	defer conn.Close()
+	defer stmt.Close()
+	var t *openTx
+	// keep draining after a failure so submit never blocks
+	for j := range p.jobs {
+		if ctx.Err() != nil {
+			continue
+		}
+		if p.commitEvery <= 0 {
+			_, err := stmt.ExecContext(ctx, j.args...)
+			p.mu.Lock()
+			if err == nil {
+				p.markInserted(j)
+			} else {
+				p.failed(ctx, fmt.Errorf("insert rows %d-%d: %w", j.first, j.first+j.rows-1, err))
+			}
+			p.mu.Unlock()
+			continue
+		}
+		if t == nil {
+			tx, err := conn.BeginTx(ctx, nil)
+			if err != nil {
+				p.mu.Lock()
+				p.failed(ctx, fmt.Errorf("begin: %w", err))
+				p.mu.Unlock()
+				continue
+			}
+			t = &openTx{tx: tx, stmt: tx.StmtContext(ctx, stmt)}
+		}
+		if _, err := t.stmt.ExecContext(ctx, j.args...); err != nil {
+			t.tx.Rollback()
+			err = fmt.Errorf("insert rows %d-%d: %w", j.first, j.first+j.rows-1, err)
+			if len(t.jobs) > 0 {
+				err = fmt.Errorf("%w (rows %s rolled back)", err, t.lines())
+			}
+			t = nil
+			p.mu.Lock()
+			p.failed(ctx, err)
+			p.mu.Unlock()
+			continue
+		}
+		t.jobs = append(t.jobs, j)
+		if t.rows += j.rows; t.rows >= p.commitEvery {
+			p.commit(ctx, t)
+			t = nil
+		}
+	}
+	if t != nil {
+		if ctx.Err() == nil {
+			p.commit(ctx, t)
+		} else {
+			t.tx.Rollback()
+		}
+	}
+}
+
+// openTx is a worker's transaction and the jobs inserted in it so far
+type openTx struct {
+	tx   *sql.Tx
+	stmt *sql.Stmt
+	jobs []job
+	rows int
+}
+
+// lines is the CSV line range of the jobs, e.g. "3-5002"
+func (t *openTx) lines() string {
+	first, last := t.jobs[0], t.jobs[len(t.jobs)-1]
+	return fmt.Sprintf("%d-%d", first.first, last.first+last.rows-1)
+}
+
+func (p *insertPool) commit(ctx context.Context, t *openTx) {
+	err := t.tx.Commit()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.failed(ctx, fmt.Errorf("commit rows %s: %w", t.lines(), err))
+		return
+	}
+	for _, j := range t.jobs {
+		p.markInserted(j)
+	}
+}
+
+// markInserted counts j as committed; call with mu held
+func (p *insertPool) markInserted(j job) {
+	p.inserted += j.rows
+	p.finish(j)
+}
+
+// failed records err and stops the other workers, unless err only says
+// they were stopped already; call with mu held
+func (p *insertPool) failed(ctx context.Context, err error) {
+	if ctx.Err() != nil && errors.Is(err, context.Canceled) {
+		return
+	}
+	p.errs = append(p.errs, err)
+	p.cancel()
+}
+
+// finish adds j to the progress and reports it, if the pool has one
+func (p *insertPool) finish(j job) {
+	if p.progress == nil {
+		return
+	}
+	run := p.progress.Commit(j.seq, j)
+	p.committed(run, p.inserted, p.progress.Done())
+}
+
+// done is the rows inserted so far
+func (p *insertPool) done() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.inserted
+}
+
+// submit queues j, blocking while every worker is busy. It returns false
+// once the load is canceled or a worker failed; wait has the reason.
+func (p *insertPool) submit(j job) bool {
+	j.seq = p.seq
+	p.seq++
+	select {
+	case p.jobs <- j:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// wait lets the queued jobs finish and returns the rows inserted and the
+// worker failures joined, or the cancellation of the load
+func (p *insertPool) wait() (int, error) {
+	close(p.jobs)
+	p.wg.Wait()
+	p.cancel()
+	if len(p.errs) == 0 && p.parent.Err() != nil {
+		return p.inserted, p.parent.Err()
+	}
+	return p.inserted, errors.Join(p.errs...)
+}
@@ -0,0 +1,165 @@
+package csvdb
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+)
+
+func TestLoadCSVToDBWithOptions_Workers(t *testing.T) {
+	lines := []string{"id", "NUMBER"}
+	for i := range 10 {
+		lines = append(lines, string(rune('0'+i)))
+	}
+	tests := []struct {
+		name     string
+		workers  int
+		maxConns int
+		fail     string
+		wantErr  string
+	}{
+		{"one", 1, 0, "", ""},
+		{"four", 4, 0, "", ""},
+		{"capped by pool", 4, 2, "", ""},
+		{"batch fails", 3, 0, "^INSERT", "insert rows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.DB.SetMaxOpenConns(tt.maxConns)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			if tt.fail != "" {
+				f.Fail(tt.fail, errors.New("boom"))
+			}
+			path := testharness.WriteCSV(t, "t.csv", lines...)
+			_, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{Workers: tt.workers, BatchSize: 3})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// every row inserted exactly once, whichever worker took the batch
+			seen := map[int64]bool{}
+			for _, c := range f.Calls() {
+				if strings.HasPrefix(c.Query, "INSERT") {
+					for _, v := range c.Args[0].([]sql.NullInt64) {
+						if seen[v.Int64] {
+							t.Errorf("row %d inserted twice", v.Int64)
+						}
+						seen[v.Int64] = true
+					}
+				}
+			}
+			if len(seen) != 10 {
+				t.Errorf("inserted %d distinct rows, want 10", len(seen))
+			}
+		})
+	}
+}
+
+func TestLoadCSVToDBWithOptions_CommitEvery(t *testing.T) {
+	lines := []string{"id", "NUMBER", "1", "2", "3", "4", "5"}
+	tests := []struct {
+		name        string
+		commitEvery int
+		fail        string
+		want        []string // INSERT rows or COMMIT, in order
+		wantLoaded  int
+		wantErr     string
+	}{
+		{"each batch", 0, "", []string{"2", "2", "1"}, 5, ""},
+		{"every 3 rows", 3, "", []string{"2", "2", "COMMIT", "1", "COMMIT"}, 5, ""},
+		{"one transaction", 100, "", []string{"2", "2", "1", "COMMIT"}, 5, ""},
+		{"commit fails", 3, "^COMMIT", []string{"2", "2", "COMMIT"}, 0, "commit rows 3-6: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			if tt.fail != "" {
+				f.Fail(tt.fail, errors.New("boom"))
+			}
+			path := testharness.WriteCSV(t, "t.csv", lines...)
+			res, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{BatchSize: 2, CommitEvery: tt.commitEvery})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range f.Calls() {
+				switch {
+				case strings.HasPrefix(c.Query, "INSERT"):
+					got = append(got, strconv.Itoa(len(c.Args[0].([]sql.NullInt64))))
+				case c.Query == "COMMIT":
+					got = append(got, c.Query)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("statements = %v, want %v", got, tt.want)
+			}
+			if res.Loaded != tt.wantLoaded {
+				t.Errorf("Loaded = %d, want %d", res.Loaded, tt.wantLoaded)
+			}
+		})
+	}
+}
+
+func TestLoadCSVToDBWithOptions_DirectPath(t *testing.T) {
+	const insert = "INSERT /*+ APPEND_VALUES */ INTO T (ID) VALUES (:1)"
+	tests := []struct {
+		name    string
+		opts    Options
+		fail    string
+		want    []string // statements after CREATE TABLE
+		wantErr string
+	}{
+		{"append hint", Options{DirectPath: true}, "", []string{insert}, ""},
+		{"nologging", Options{DirectPath: true, NoLogging: true}, "",
+			[]string{"ALTER TABLE T NOLOGGING", insert, "ALTER TABLE T LOGGING"}, ""},
+		{"logging restored on failure", Options{DirectPath: true, NoLogging: true}, "^INSERT",
+			[]string{"ALTER TABLE T NOLOGGING", insert, "ALTER TABLE T LOGGING"}, "boom"},
+		{"several workers", Options{DirectPath: true, Workers: 2}, "", nil, "DirectPath loads with one worker"},
+		{"larger transactions", Options{DirectPath: true, CommitEvery: DefaultBatchSize + 1}, "", nil, "CommitEvery cannot exceed BatchSize"},
+		{"nologging alone", Options{NoLogging: true}, "", nil, "NoLogging only applies to a DirectPath load"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			if tt.fail != "" {
+				f.Fail(tt.fail, errors.New("boom"))
+			}
+			path := testharness.WriteCSV(t, "t.csv", "id", "NUMBER", "1", "2")
+			_, err := LoadCSVToDBWithOptions(quiet, f.DB, path, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, q := range f.Queries() {
+				if !strings.HasPrefix(q, "SELECT") && !strings.HasPrefix(q, "CREATE") {
+					got = append(got, q)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("statements = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
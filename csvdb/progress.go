@@ -0,0 +1,78 @@
+package csvdb
+
+import (
+	"time"
+
+	"sql-learn2/csvfile"
+)
+
+// DefaultProgressEvery is the rows between Options.Progress calls when
+// Options.ProgressEvery is 0
+const DefaultProgressEvery = 100000
+
+// Progress is a snapshot of a running load
+type Progress struct {
+	RowsRead     int           // data rows read so far, rejected ones included
+	RowsInserted int           // rows the workers have inserted and committed
+	Rejected     int           // rows written to the reject file
+	Bytes        int64         // bytes of the file read, compressed for gzip
+	TotalBytes   int64         // file size on disk
+	Elapsed      time.Duration // since the first data row was read
+	ETA          time.Duration // remaining time extrapolated from Bytes; 0 when unknown
+	Done         bool          // the final report, sent once the load finished
+}
+
+// Percent is the share of the file read, 0 to 100
+func (p Progress) Percent() float64 {
+	if p.TotalBytes <= 0 {
+		return 0
+	}
+	return 100 * float64(p.Bytes) / float64(p.TotalBytes)
+}
+
+// progress calls fn every `every` rows read. It runs on the goroutine
+// reading the file, so fn needs no locking but should return quickly.
+type progress struct {
+	fn    func(Progress)
+	every int
+	file  *csvfile.File
+	start time.Time
+	read  int
+}
+
+func newProgress(fn func(Progress), every int, file *csvfile.File) *progress {
+	if every <= 0 {
+		every = DefaultProgressEvery
+	}
+	return &progress{fn: fn, every: every, file: file, start: time.Now()}
+}
+
+// row counts one more row read and reports when every rows have passed
+func (p *progress) row(inserted func() int, rejected int) {
+	if p == nil {
+		return
+	}
+	p.read++
+	if p.read%p.every == 0 {
+		p.report(inserted(), rejected, false)
+	}
+}
+
+func (p *progress) report(inserted, rejected int, done bool) {
+	if p == nil {
+		return
+	}
+	s := Progress{
+		RowsRead:     p.read,
+		RowsInserted: inserted,
+		Rejected:     rejected,
+		Bytes:        p.file.BytesRead(),
+		TotalBytes:   p.file.Size(),
+		Elapsed:      time.Since(p.start),
+		Done:         done,
+	}
+	if s.Bytes > 0 && s.Bytes < s.TotalBytes && !done {
+		s.ETA = time.Duration(float64(s.Elapsed) * float64(s.TotalBytes-s.Bytes) / float64(s.Bytes))
+	}
+	p.fn(s)
+}
@@ -0,0 +1,80 @@
+package csvdb
+
+import (
+	"fmt"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+)
+
+func TestLoadCSVToDBWithOptions_Progress(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		rows  int
+		want  []int // RowsRead per call, the last one being the final report
+		final Progress
+	}{
+		{
+			name:  "every 2 rows",
+			opts:  Options{ProgressEvery: 2, BatchSize: 2},
+			rows:  5,
+			want:  []int{2, 4, 5},
+			final: Progress{RowsRead: 5, RowsInserted: 5},
+		},
+		{
+			name:  "fewer rows than interval reports once",
+			opts:  Options{ProgressEvery: 10},
+			rows:  3,
+			want:  []int{3},
+			final: Progress{RowsRead: 3, RowsInserted: 3},
+		},
+		{
+			name:  "rejected rows count as read",
+			opts:  Options{ProgressEvery: 1, SkipBadRows: true},
+			rows:  2, // plus one bad row
+			want:  []int{1, 2, 3, 3},
+			final: Progress{RowsRead: 3, RowsInserted: 2, Rejected: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := []string{"id", "NUMBER"}
+			for i := range tt.rows {
+				lines = append(lines, fmt.Sprint(i))
+			}
+			if tt.opts.SkipBadRows {
+				lines = append(lines, "x")
+				tt.opts.RejectFile = t.TempDir() + "/t.bad"
+			}
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			path := testharness.WriteCSV(t, "t.csv", lines...)
+
+			var got []Progress
+			tt.opts.Progress = func(p Progress) { got = append(got, p) }
+			if _, err := LoadCSVToDBWithOptions(quiet, f.DB, path, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("%d reports, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, p := range got {
+				if p.RowsRead != tt.want[i] {
+					t.Errorf("report %d: RowsRead = %d, want %d", i, p.RowsRead, tt.want[i])
+				}
+				if p.Done != (i == len(got)-1) {
+					t.Errorf("report %d: Done = %v", i, p.Done)
+				}
+			}
+			last := got[len(got)-1]
+			if last.RowsRead != tt.final.RowsRead || last.RowsInserted != tt.final.RowsInserted || last.Rejected != tt.final.Rejected {
+				t.Errorf("final = %+v, want %+v", last, tt.final)
+			}
+			if last.TotalBytes == 0 || last.Bytes != last.TotalBytes || last.Percent() != 100 || last.ETA != 0 {
+				t.Errorf("final bytes = %d of %d (%.0f%%), ETA %s", last.Bytes, last.TotalBytes, last.Percent(), last.ETA)
+			}
+		})
+	}
+}
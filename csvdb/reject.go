@@ -0,0 +1,107 @@
+package csvdb
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"sql-learn2/csvfile"
+)
+
+// rejects writes skipped rows, SQL*Loader bad-file style, as a CSV with the
+// columns LINE, REASON and then the original headers. The file is created
+// with the first rejected row, and every row is flushed so size always
+// matches the file for a checkpoint.
+type rejects struct {
+	path    string
+	comma   rune
+	headers []string
+	max     int
+
+	f     *os.File
+	w     *csv.Writer
+	count int
+	size  int64 // bytes written, header included
+}
+
+// defaultRejectFile is <name>.bad next to the CSV, e.g. /in/orders.bad
+func defaultRejectFile(csvPath string) string {
+	return filepath.Join(filepath.Dir(csvPath), csvfile.BaseName(csvPath)+".bad")
+}
+
+// add writes rec and why it was rejected. Past max rejects (when max > 0)
+// the load stops with cause.
+func (r *rejects) add(line int, rec []string, cause error) error {
+	if r.max > 0 && r.count >= r.max {
+		return fmt.Errorf("more than %d rejected rows, last: %w", r.max, cause)
+	}
+	if r.w == nil {
+		f, err := os.Create(r.path)
+		if err != nil {
+			return fmt.Errorf("create reject file: %w", err)
+		}
+		r.use(f)
+		r.w.Write(append([]string{"LINE", "REASON"}, r.headers...))
+	}
+	r.count++
+	r.w.Write(append([]string{strconv.Itoa(line), cause.Error()}, rec...))
+	r.w.Flush()
+	err := r.w.Error()
+	if err == nil {
+		r.size, err = r.f.Seek(0, io.SeekCurrent)
+	}
+	if err != nil {
+		return fmt.Errorf("write reject file: %w", err)
+	}
+	return nil
+}
+
+// reopen continues the reject file of an interrupted load, whose checkpoint
+// had count rows in its first size bytes. What was written past them is
+// dropped: those lines are read and rejected again.
+func (r *rejects) reopen(count int, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(r.path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("open reject file: %w", err)
+	}
+	if err = f.Truncate(size); err == nil {
+		_, err = f.Seek(size, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("open reject file: %w", err)
+	}
+	r.use(f)
+	r.count, r.size = count, size
+	return nil
+}
+
+// use writes the rejected rows to f
+func (r *rejects) use(f *os.File) {
+	r.f, r.w = f, csv.NewWriter(f)
+	if r.comma != 0 {
+		r.w.Comma = r.comma
+	}
+}
+
+// close flushes the file and returns its path, or "" when nothing was rejected
+func (r *rejects) close() (string, error) {
+	if r.f == nil {
+		return "", nil
+	}
+	r.w.Flush()
+	err := r.w.Error()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return r.path, fmt.Errorf("write reject file: %w", err)
+	}
+	return r.path, nil
+}
@@ -0,0 +1,90 @@
+package csvdb
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+)
+
+func TestLoadCSVToDBWithOptions_SkipBadRows(t *testing.T) {
+	lines := []string{"id,name", "NUMBER,VARCHAR2", "1,a", "x,b", "3,c", "4.5.6,d"}
+	tests := []struct {
+		name       string
+		opts       Options
+		lines      []string
+		want       LoadResult
+		wantReject string // file content; the path is checked separately
+		wantErr    string
+	}{
+		{
+			name:    "off fails on first bad row",
+			lines:   lines,
+			want:    LoadResult{RowsRead: 1},
+			wantErr: `row 4 col 1: invalid NUMBER "x"`,
+		},
+		{
+			name:  "rows rejected",
+			opts:  Options{SkipBadRows: true},
+			lines: lines,
+			want:  LoadResult{RowsRead: 4, Loaded: 2, Rejected: 2},
+			wantReject: "LINE,REASON,id,name\n" +
+				"4,\"col 1: invalid NUMBER \"\"x\"\": strconv.ParseFloat: parsing \"\"x\"\": invalid syntax\",x,b\n" +
+				"6,\"col 1: invalid NUMBER \"\"4.5.6\"\": strconv.ParseFloat: parsing \"\"4.5.6\"\": invalid syntax\",4.5.6,d\n",
+		},
+		{
+			name:  "nothing rejected writes no file",
+			opts:  Options{SkipBadRows: true},
+			lines: []string{"id", "NUMBER", "1"},
+			want:  LoadResult{RowsRead: 1, Loaded: 1},
+		},
+		{
+			name:    "too many rejects",
+			opts:    Options{SkipBadRows: true, MaxRejects: 1},
+			lines:   lines,
+			want:    LoadResult{RowsRead: 4, Rejected: 1}, // the stop drops the batch being filled
+			wantErr: "more than 1 rejected rows",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			path := testharness.WriteCSV(t, "orders.csv", tt.lines...)
+			res, err := LoadCSVToDBWithOptions(quiet, f.DB, path, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			wantFile := ""
+			if tt.want.Rejected > 0 {
+				wantFile = filepath.Join(filepath.Dir(path), "orders.bad")
+			}
+			tt.want.Table, tt.want.RejectFile = "ORDERS", wantFile
+			if got := counts(res); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("result = %+v, want %+v", got, tt.want)
+			}
+			if tt.wantReject != "" {
+				b, err := os.ReadFile(wantFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(b) != tt.wantReject {
+					t.Errorf("reject file:\n%s\nwant:\n%s", b, tt.wantReject)
+				}
+			}
+		})
+	}
+}
+
+// counts keeps the LoadResult fields a test can predict exactly
+func counts(r LoadResult) LoadResult {
+	return LoadResult{Table: r.Table, RowsRead: r.RowsRead, Loaded: r.Loaded, Rejected: r.Rejected, RejectFile: r.RejectFile, Resumed: r.Resumed}
+}
@@ -0,0 +1,116 @@
+package csvdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"sql-learn2/dynamic"
+	"sql-learn2/numformat"
+)
+
+// Transform converts the raw cell of column col, e.g. "Y" to 1 or
+// "$1,200" to 1200, in place of the usual conversion. It sees every cell,
+// empty ones and NULL markers included. It may return nil for NULL, a
+// string, an int, int64 or float64, or a time.Time for a DATE or TIMESTAMP
+// column; a string for a NUMBER column is parsed as a plain number like
+// "-1234.5".
+type Transform func(col, raw string) (any, error)
+
+// columnTransforms lines up transforms, keyed by column name as loaded
+// (matched case-insensitively), with names; nil when there are none
+func columnTransforms(names []string, transforms map[string]Transform) ([]Transform, error) {
+	if len(transforms) == 0 {
+		return nil, nil
+	}
+	byName := make(map[string]Transform, len(transforms))
+	for name, tf := range transforms {
+		byName[strings.ToUpper(name)] = tf
+	}
+	out := make([]Transform, len(names))
+	for i, name := range names {
+		key := strings.ToUpper(name)
+		out[i] = byName[key]
+		delete(byName, key)
+	}
+	for name := range byName {
+		return nil, fmt.Errorf("transform for column %s, which is not loaded", name)
+	}
+	return out, nil
+}
+
+// transform runs tf on cell and checks the value against the column type
+func transform(tf Transform, col dynamic.ColumnDef, cell string) (any, error) {
+	v, err := tf(col.Name, cell)
+	if err != nil {
+		return nil, fmt.Errorf("transform: %w", err)
+	}
+	if v, err = bindValue(v, col); err != nil {
+		return nil, fmt.Errorf("transform returned %w", err)
+	}
+	return v, nil
+}
+
+// bindValue turns a value given for col into what its batch column holds:
+// nil, int64 or float64 for NUMBER, time.Time for DATE and TIMESTAMP, or
+// a string. "" is NULL and a string for NUMBER is parsed as a plain number.
+func bindValue(v any, col dynamic.ColumnDef) (any, error) {
+	number := col.Type == dynamic.Number
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		if number {
+			return parseNumber(v, numformat.Default)
+		}
+		return v, nil
+	case int:
+		if number {
+			return int64(v), nil
+		}
+		return strconv.Itoa(v), nil
+	case int64:
+		if number {
+			return v, nil
+		}
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		if number {
+			return v, nil
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		if isDateType(col.Type) {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("%T for %s column %s, want string, int, int64, float64, time.Time for dates or nil", v, col.Type, col.Name)
+}
+
+// transformSamples returns samples with the transformed columns replaced by
+// the text of their results, for type inference. A cell the transform
+// rejects stays as it is; the load reports it.
+func (c *csvReader) transformSamples(names []string, samples [][]string) [][]string {
+	if c.transforms == nil {
+		return samples
+	}
+	out := make([][]string, len(samples))
+	for r, rec := range samples {
+		out[r] = make([]string, len(rec))
+		copy(out[r], rec)
+		for i, tf := range c.transforms {
+			if tf == nil || i >= len(rec) {
+				continue
+			}
+			if v, err := transform(tf, dynamic.ColumnDef{Name: names[i], Type: dynamic.Varchar2}, rec[i]); err == nil {
+				s, _ := v.(string)
+				out[r][i] = s
+			}
+		}
+	}
+	return out
+}
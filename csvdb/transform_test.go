@@ -0,0 +1,82 @@
+package csvdb
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+)
+
+func TestLoadCSVToDBWithOptions_Transforms(t *testing.T) {
+	flag := func(_, raw string) (any, error) {
+		switch raw {
+		case "Y":
+			return 1, nil
+		case "N":
+			return 0, nil
+		}
+		return nil, errors.New("want Y or N")
+	}
+	money := func(_, raw string) (any, error) {
+		return strings.NewReplacer("$", "", ",", "").Replace(raw), nil
+	}
+	lines := []string{"active,price", "Y,\"$1,200\"", "N,$35", ",$0"}
+	tests := []struct {
+		name       string
+		transforms map[string]Transform
+		skip       bool
+		want       []any
+		wantRes    LoadResult
+		wantErr    string
+	}{
+		{
+			name:       "converted and inferred as numbers",
+			transforms: map[string]Transform{"Active": flag, "PRICE": money},
+			skip:       true,
+			want: []any{
+				[]sql.NullInt64{{Int64: 1, Valid: true}, {Int64: 0, Valid: true}},
+				[]sql.NullInt64{{Int64: 1200, Valid: true}, {Int64: 35, Valid: true}},
+			},
+			wantRes: LoadResult{RowsRead: 3, Loaded: 2, Rejected: 1},
+		},
+		{
+			name:       "error fails the load",
+			transforms: map[string]Transform{"ACTIVE": flag},
+			wantErr:    "row 4 col 1: transform: want Y or N",
+		},
+		{
+			name:       "unknown column",
+			transforms: map[string]Transform{"QTY": money},
+			wantErr:    "transform for column QTY, which is not loaded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			path := testharness.WriteCSV(t, "t.csv", lines...)
+			res, err := LoadCSVToDBWithOptions(quiet, f.DB, path, Options{Transforms: tt.transforms, SkipBadRows: tt.skip})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.wantRes.Table, tt.wantRes.RejectFile = "T", res.RejectFile
+			if got := counts(res); !reflect.DeepEqual(got, tt.wantRes) {
+				t.Errorf("result = %+v, want %+v", got, tt.wantRes)
+			}
+			calls := f.Calls()
+			if args := calls[len(calls)-1].Args; !reflect.DeepEqual(args, tt.want) {
+				t.Errorf("args = %#v, want %#v", args, tt.want)
+			}
+		})
+	}
+}
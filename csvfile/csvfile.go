@@ -0,0 +1,97 @@
+package csvfile
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// gzipMagic starts every gzip stream; no text CSV starts with it
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// File is a CSV file opened for reading
+type File struct {
+	io.Reader
+	closer io.Closer
+	size   int64
+	read   *counter
+}
+
+// Open opens a CSV file for reading. Gzip-compressed files are detected by
+// their magic bytes, whatever the name, and decompressed while reading, so
+// no uncompressed copy is written to disk.
+func Open(path string) (*File, error) {
+	return OpenEncoded(path, UTF8)
+}
+
+// OpenEncoded is Open for a file in enc, transcoded to UTF-8 while reading
+// (after decompressing gzip)
+func OpenEncoded(path string, enc Encoding) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	c := &counter{r: f}
+	br := bufio.NewReader(c)
+	if head, _ := br.Peek(len(gzipMagic)); string(head) != string(gzipMagic) {
+		return &File{Reader: decode(br, enc), closer: f, size: st.Size(), read: c}, nil
+	}
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("gzip %s: %w", path, err)
+	}
+	return &File{Reader: decode(zr, enc), closer: multiCloser{zr, f}, size: st.Size(), read: c}, nil
+}
+
+func (f *File) Close() error { return f.closer.Close() }
+
+// Size is the size of the file on disk, compressed for gzip
+func (f *File) Size() int64 { return f.size }
+
+// BytesRead is how much of Size has been read so far. Reads are buffered,
+// so it runs a little ahead of the records returned.
+func (f *File) BytesRead() int64 { return f.read.n.Load() }
+
+type counter struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *counter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// BaseName is the file name of path without directory and extensions like
+// .csv and .gz, e.g. "orders" for "/in/orders.csv.gz"
+func BaseName(path string) string {
+	name := filepath.Base(path)
+	if strings.EqualFold(filepath.Ext(name), ".gz") {
+		name = name[:len(name)-3]
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() error {
+	var first error
+	for _, c := range m {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
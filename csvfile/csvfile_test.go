@@ -0,0 +1,158 @@
+package csvfile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	const data = "id,name\nNUMBER,VARCHAR2\n1,a\n"
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(data))
+	zw.Close()
+
+	tests := []struct {
+		name    string
+		file    string
+		content []byte
+		want    string
+	}{
+		{"plain", "t.csv", []byte(data), data},
+		{"gzip", "t.csv.gz", gz.Bytes(), data},
+		{"gzip without extension", "t.csv", gz.Bytes(), data},
+		{"shorter than magic", "t.csv", []byte("x"), "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, tt.content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			rc, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rc.Close()
+			got, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "bad.gz")
+	os.WriteFile(path, []byte{0x1f, 0x8b, 0}, 0o644)
+	if _, err := Open(path); err == nil {
+		t.Error("Open(truncated gzip): want error")
+	}
+}
+
+func TestBaseName(t *testing.T) {
+	for in, want := range map[string]string{
+		"/in/orders.csv":    "orders",
+		"/in/orders.csv.gz": "orders",
+		"orders.CSV.GZ":     "orders",
+		"orders.gz":         "orders",
+		"my file":           "my file",
+	} {
+		if got := BaseName(in); got != want {
+			t.Errorf("BaseName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFile_BytesRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "t.csv")
+	content := bytes.Repeat([]byte("1,a\n"), 10000)
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if f.Size() != int64(len(content)) {
+		t.Errorf("Size = %d, want %d", f.Size(), len(content))
+	}
+	if _, err := io.ReadAll(f); err != nil {
+		t.Fatal(err)
+	}
+	if f.BytesRead() != f.Size() {
+		t.Errorf("BytesRead = %d after reading all, want %d", f.BytesRead(), f.Size())
+	}
+}
+
+func TestOpenEncoded(t *testing.T) {
+	// "ไทย,€" in Windows-874
+	thai := []byte{0xE4, 0xB7, 0xC2, ',', 0x80, '\n'}
+	utf16le := []byte{0xFF, 0xFE, 'a', 0, ',', 0, 0x3D, 0xD8, 0x00, 0xDE, '\n', 0} // BOM, "a,😀\n"
+	tests := []struct {
+		name    string
+		enc     Encoding
+		data    []byte
+		want    string
+		wantErr bool
+	}{
+		{"utf-8 unchanged", UTF8, []byte("ไทย\n"), "ไทย\n", false},
+		{"utf-8 BOM dropped", UTF8, []byte("\xef\xbb\xbfid,name\n"), "id,name\n", false},
+		{"utf-8 BOM only at the start", UTF8, []byte("a\xef\xbb\xbf"), "a\ufeff", false},
+		{"windows-874", Windows874, thai, "ไทย,€\n", false},
+		{"windows-874 undefined byte", Windows874, []byte{0xFC}, "�", false},
+		{"utf-16 with BOM", UTF16, utf16le, "a,😀\n", false},
+		{"utf-16 big-endian BOM", UTF16, []byte{0xFE, 0xFF, 0x0E, 0x01}, "ก", false},
+		{"utf-16 defaults to little-endian", UTF16, []byte{0x01, 0x0E}, "ก", false},
+		{"utf-16be", UTF16BE, []byte{0x0E, 0x01, 0, 'x'}, "กx", false},
+		{"lone surrogate", UTF16LE, []byte{0x3D, 0xD8, 'x', 0}, "�x", false},
+		{"utf-16le BOM dropped", UTF16LE, []byte{0xFF, 0xFE, 'x', 0}, "x", false},
+		{"odd length", UTF16LE, []byte{'a', 0, 'b'}, "a\ufffd", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "t.csv")
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			f, err := OpenEncoded(path, tt.enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			got, err := io.ReadAll(f)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Encoding
+		wantErr bool
+	}{
+		{"", UTF8, false},
+		{"TIS-620", Windows874, false},
+		{"cp874", Windows874, false},
+		{"UTF_16LE", UTF16LE, false},
+		{"latin1", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseEncoding(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseEncoding(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package csvfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Encoding is the character set of a CSV file; reads return UTF-8
+type Encoding int
+
+const (
+	UTF8 Encoding = iota
+	// Windows874 is the Thai code page, a superset of TIS-620
+	Windows874
+	// UTF16 is UTF-16 with a byte order mark, little-endian without one;
+	// a byte order mark also overrides UTF16LE and UTF16BE
+	UTF16
+	UTF16LE
+	UTF16BE
+)
+
+// ParseEncoding parses an -encoding flag value such as utf-8, tis-620,
+// windows-874, utf-16, utf-16le or utf-16be
+func ParseEncoding(s string) (Encoding, error) {
+	switch strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), "_", "-") {
+	case "", "utf-8", "utf8":
+		return UTF8, nil
+	case "tis-620", "tis620", "windows-874", "cp874", "x-windows-874":
+		return Windows874, nil
+	case "utf-16", "utf16":
+		return UTF16, nil
+	case "utf-16le", "utf16le":
+		return UTF16LE, nil
+	case "utf-16be", "utf16be":
+		return UTF16BE, nil
+	}
+	return 0, fmt.Errorf("unsupported encoding %q (use utf-8, tis-620, windows-874, utf-16, utf-16le or utf-16be)", s)
+}
+
+func (e Encoding) String() string {
+	switch e {
+	case Windows874:
+		return "windows-874"
+	case UTF16:
+		return "utf-16"
+	case UTF16LE:
+		return "utf-16le"
+	case UTF16BE:
+		return "utf-16be"
+	}
+	return "utf-8"
+}
+
+// utf8BOM is the byte order mark Excel and others write before UTF-8 text
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// decode returns r transcoded from e to UTF-8, without a byte order mark.
+// A UTF-16 byte order mark decides the byte order over e; bytes e cannot
+// decode, such as a trailing odd byte, read as U+FFFD.
+func decode(r io.Reader, e Encoding) io.Reader {
+	switch e {
+	case Windows874:
+		return transform.NewReader(r, charmap.Windows874.NewDecoder())
+	case UTF16, UTF16LE:
+		return transform.NewReader(r, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder())
+	case UTF16BE:
+		return transform.NewReader(r, unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewDecoder())
+	}
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	if b, _ := br.Peek(len(utf8BOM)); bytes.Equal(b, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+	return br
+}
@@ -0,0 +1,121 @@
+package dynamic
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// TableColumn is a column of an existing table as USER_TAB_COLUMNS has it
+type TableColumn struct {
+	Name     string
+	DataType string // e.g. VARCHAR2, NUMBER, TIMESTAMP(6)
+	Length   int    // CHAR_LENGTH, for character types
+	Nullable bool
+}
+
+// Kind is the DataType a value for the column is converted to
+func (c TableColumn) Kind() DataType {
+	switch t := c.DataType; {
+	case t == "NUMBER", t == "FLOAT", strings.HasPrefix(t, "BINARY_"):
+		return Number
+	case t == "DATE":
+		return Date
+	case strings.HasPrefix(t, "TIMESTAMP"):
+		return Timestamp
+	case t == "CLOB", t == "NCLOB":
+		return Clob
+	}
+	return Varchar2
+}
+
+// TableColumns returns the columns of tableName in the current schema, in
+// column order. The name is resolved as CreateOrReplaceTableWithOptions
+// would create it, and a table without columns is reported as missing.
+func TableColumns(ctx context.Context, db *sql.DB, tableName string, opt CreateOptions) ([]TableColumn, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	name, err := opt.stored(tableName)
+	if err != nil {
+		return nil, err
+	}
+	sqlStr, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Colon).
+		Select("COLUMN_NAME", "DATA_TYPE", "CHAR_LENGTH", "NULLABLE").
+		From("USER_TAB_COLUMNS").
+		Where(sq.Eq{"TABLE_NAME": name}).
+		OrderBy("COLUMN_ID").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, fmt.Errorf("columns of %s: %w", name, err)
+	}
+	defer rows.Close()
+	var cols []TableColumn
+	for rows.Next() {
+		var c TableColumn
+		var nullable string
+		if err := rows.Scan(&c.Name, &c.DataType, &c.Length, &nullable); err != nil {
+			return nil, fmt.Errorf("columns of %s: %w", name, err)
+		}
+		c.Nullable = nullable == "Y"
+		cols = append(cols, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("columns of %s: %w", name, err)
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("table %s not found", name)
+	}
+	return cols, nil
+}
+
+// PrimaryKey returns the primary key columns of tableName in the current
+// schema, in key order, resolving the name as TableColumns does. A table
+// without a primary key (or a missing table) is an error.
+func PrimaryKey(ctx context.Context, db *sql.DB, tableName string, opt CreateOptions) ([]string, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	name, err := opt.stored(tableName)
+	if err != nil {
+		return nil, err
+	}
+	sqlStr, args, err := sq.StatementBuilder.PlaceholderFormat(sq.Colon).
+		Select("cc.COLUMN_NAME").
+		From("USER_CONSTRAINTS c").
+		Join("USER_CONS_COLUMNS cc ON cc.CONSTRAINT_NAME = c.CONSTRAINT_NAME AND cc.TABLE_NAME = c.TABLE_NAME").
+		Where(sq.Eq{"c.TABLE_NAME": name, "c.CONSTRAINT_TYPE": "P"}).
+		OrderBy("cc.POSITION").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, fmt.Errorf("primary key of %s: %w", name, err)
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, fmt.Errorf("primary key of %s: %w", name, err)
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("primary key of %s: %w", name, err)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("table %s has no primary key", name)
+	}
+	return keys, nil
+}
@@ -0,0 +1,117 @@
+package dynamic
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+)
+
+func TestTableColumns(t *testing.T) {
+	const query = "SELECT COLUMN_NAME, DATA_TYPE, CHAR_LENGTH, NULLABLE FROM USER_TAB_COLUMNS WHERE TABLE_NAME = :1 ORDER BY COLUMN_ID"
+	cols := []string{"COLUMN_NAME", "DATA_TYPE", "CHAR_LENGTH", "NULLABLE"}
+	tests := []struct {
+		name     string
+		table    string
+		opt      CreateOptions
+		rows     [][]any
+		want     []TableColumn
+		wantArg  string
+		wantKind []DataType
+		wantErr  string
+	}{
+		{
+			name:  "columns in order",
+			table: "orders",
+			rows: [][]any{
+				{"ID", "NUMBER", int64(0), "N"},
+				{"NAME", "VARCHAR2", int64(50), "Y"},
+				{"AT", "TIMESTAMP(6)", int64(0), "Y"},
+				{"NOTE", "NCLOB", int64(0), "Y"},
+			},
+			want: []TableColumn{
+				{Name: "ID", DataType: "NUMBER"},
+				{Name: "NAME", DataType: "VARCHAR2", Length: 50, Nullable: true},
+				{Name: "AT", DataType: "TIMESTAMP(6)", Nullable: true},
+				{Name: "NOTE", DataType: "NCLOB", Nullable: true},
+			},
+			wantArg:  "ORDERS",
+			wantKind: []DataType{Number, Varchar2, Timestamp, Clob},
+		},
+		{
+			name:    "quoted name as given",
+			table:   "Orders",
+			opt:     CreateOptions{Quoted: true},
+			rows:    [][]any{{"Id", "BINARY_DOUBLE", int64(0), "Y"}},
+			want:    []TableColumn{{Name: "Id", DataType: "BINARY_DOUBLE", Nullable: true}},
+			wantArg: "Orders",
+		},
+		{
+			name:    "missing table",
+			table:   "nope",
+			wantArg: "NOPE",
+			wantErr: "table NOPE not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TAB_COLUMNS", cols, tt.rows...)
+			got, err := TableColumns(context.Background(), f.DB, tt.table, tt.opt)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("columns = %+v, want %+v", got, tt.want)
+			}
+			if want := []sqlfake.Call{{Query: query, Args: []any{tt.wantArg}}}; !reflect.DeepEqual(f.Calls(), want) {
+				t.Errorf("calls = %#v, want %#v", f.Calls(), want)
+			}
+			for i, k := range tt.wantKind {
+				if got[i].Kind() != k {
+					t.Errorf("%s kind = %s, want %s", got[i].Name, got[i].Kind(), k)
+				}
+			}
+		})
+	}
+}
+
+func TestPrimaryKey(t *testing.T) {
+	const query = "SELECT cc.COLUMN_NAME FROM USER_CONSTRAINTS c JOIN USER_CONS_COLUMNS cc ON cc.CONSTRAINT_NAME = c.CONSTRAINT_NAME AND cc.TABLE_NAME = c.TABLE_NAME " +
+		"WHERE c.CONSTRAINT_TYPE = :1 AND c.TABLE_NAME = :2 ORDER BY cc.POSITION"
+	tests := []struct {
+		name    string
+		table   string
+		rows    [][]any
+		want    []string
+		wantErr string
+	}{
+		{"composite key", "order_lines", [][]any{{"ORDER_ID"}, {"LINE_NO"}}, []string{"ORDER_ID", "LINE_NO"}, ""},
+		{"no primary key", "logs", nil, nil, "table LOGS has no primary key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_CONS_COLUMNS", []string{"COLUMN_NAME"}, tt.rows...)
+			got, err := PrimaryKey(context.Background(), f.DB, tt.table, CreateOptions{})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keys = %q, want %q", got, tt.want)
+			}
+			if len(f.Calls()) != 1 || f.Calls()[0].Query != query {
+				t.Errorf("calls = %#v, want %q", f.Calls(), query)
+			}
+		})
+	}
+}
@@ -8,8 +8,11 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
 	sq "github.com/Masterminds/squirrel"
+
+	"sql-learn2/oraerr"
 )
 
 // DataType represents a basic Oracle data type supported by this helper.
@@ -34,7 +37,8 @@ const (
 //   - PrimaryKey marks the column to be included in the PRIMARY KEY constraint.
 //   - Name and TableName must be simple Oracle identifiers (letters, digits, underscore), starting with a letter.
 //     They are used unquoted and automatically uppercased.
-//   - Oracle object name length is limited to 30 bytes; we enforce this for identifiers we generate.
+//   - Oracle object name length is limited to 30 bytes (128 on 12.2+); we enforce CreateOptions.MaxIdentifierLen.
+//   - With CreateOptions.Quoted, Name and TableName are used as given inside double quotes instead.
 type ColumnDef struct {
 	Name       string
 	Type       DataType
@@ -54,24 +58,75 @@ var identRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
 // the DDL statements via db.Exec. It assumes the *sql.DB is connected to Oracle
 // via a compatible driver (e.g., godror or go-ora).
 func CreateOrReplaceTable(ctx context.Context, db *sql.DB, tableName string, cols []ColumnDef) error {
+	return CreateOrReplaceTableWithOptions(ctx, db, tableName, cols, CreateOptions{})
+}
+
+// CreateOptions tune how table and column names are written; the zero value
+// is unquoted, upper-cased names of at most 30 bytes
+type CreateOptions struct {
+	// MaxIdentifierLen is the byte limit for names (default 30). Databases on
+	// 12.2+ with COMPATIBLE >= 12.2 allow 128; see MaxIdentifierLength.
+	MaxIdentifierLen int
+	// Quoted writes names as quoted identifiers, keeping case, spaces and
+	// punctuation exactly as given
+	Quoted bool
+}
+
+func (o CreateOptions) maxLen() int {
+	if o.MaxIdentifierLen <= 0 {
+		return 30
+	}
+	return o.MaxIdentifierLen
+}
+
+// Identifier validates name and returns it as written in SQL: quoted, or
+// upper-cased for unquoted names
+func (o CreateOptions) Identifier(name string) (string, error) {
+	stored, err := o.stored(name)
+	if err != nil {
+		return "", err
+	}
+	if o.Quoted {
+		return `"` + stored + `"`, nil
+	}
+	return stored, nil
+}
+
+// stored returns name as the data dictionary keeps it
+func (o CreateOptions) stored(name string) (string, error) {
+	if !o.Quoted {
+		return normalizeIdentifier(name, o.maxLen())
+	}
+	if name == "" || strings.ContainsAny(name, "\"\x00") {
+		return "", errors.New("quoted identifier must be non-empty without double quotes")
+	}
+	if len(name) > o.maxLen() {
+		return "", fmt.Errorf("identifier exceeds Oracle %d-byte limit", o.maxLen())
+	}
+	return name, nil
+}
+
+// CreateOrReplaceTableWithOptions is CreateOrReplaceTable with options
+func CreateOrReplaceTableWithOptions(ctx context.Context, db *sql.DB, tableName string, cols []ColumnDef, opt CreateOptions) error {
 	if db == nil {
 		return errors.New("db is nil")
 	}
-	name, err := normalizeIdentifier(tableName)
+	stored, err := opt.stored(tableName)
 	if err != nil {
 		return fmt.Errorf("invalid table name: %w", err)
 	}
+	name, _ := opt.Identifier(tableName)
 	if len(cols) == 0 {
 		return errors.New("at least one column is required")
 	}
 	for i := range cols {
-		if _, err := normalizeIdentifier(cols[i].Name); err != nil {
+		if _, err := opt.stored(cols[i].Name); err != nil {
 			return fmt.Errorf("invalid column name '%s': %w", cols[i].Name, err)
 		}
 	}
 
 	// 1) Drop if exists
-	exists, err := tableExists(ctx, db, name)
+	exists, err := tableExists(ctx, db, stored)
 	if err != nil {
 		return fmt.Errorf("check table exists failed: %w", err)
 	}
@@ -89,7 +144,7 @@ func CreateOrReplaceTable(ctx context.Context, db *sql.DB, tableName string, col
 	}
 
 	// 2) Build CREATE TABLE DDL
-	ddl, err := buildCreateTableDDL(name, cols)
+	ddl, err := buildCreateTableDDL(name, cols, opt)
 	if err != nil {
 		return err
 	}
@@ -101,13 +156,43 @@ func CreateOrReplaceTable(ctx context.Context, db *sql.DB, tableName string, col
 	return nil
 }
 
-// tableExists uses Squirrel to check USER_TABLES for the given table name.
+// CreateTableDDL returns the CREATE TABLE statement CreateOrReplaceTable
+// would run, without touching the database
+func CreateTableDDL(tableName string, cols []ColumnDef, opt CreateOptions) (string, error) {
+	name, err := opt.Identifier(tableName)
+	if err != nil {
+		return "", fmt.Errorf("invalid table name: %w", err)
+	}
+	for i := range cols {
+		if _, err := opt.stored(cols[i].Name); err != nil {
+			return "", fmt.Errorf("invalid column name '%s': %w", cols[i].Name, err)
+		}
+	}
+	return buildCreateTableDDL(name, cols, opt)
+}
+
+// MaxIdentifierLength asks the server for its identifier byte limit: 128
+// on 12.2+ with COMPATIBLE >= 12.2, otherwise 30
+func MaxIdentifierLength(ctx context.Context, db *sql.DB) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, "SELECT ORA_MAX_NAME_LEN_SUPPORTED FROM DUAL").Scan(&n)
+	if oraerr.Code(err) == 904 { // ORA-00904: invalid identifier, before 12.2
+		return 30, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("max identifier length: %w", err)
+	}
+	return n, nil
+}
+
+// tableExists uses Squirrel to check USER_TABLES for the given table name,
+// as stored in the dictionary.
 func tableExists(ctx context.Context, db *sql.DB, tableName string) (bool, error) {
 	builder := sq.StatementBuilder.PlaceholderFormat(sq.Colon) // Oracle-friendly :1, :2 ...
 	sqlStr, args, err := builder.
 		Select("COUNT(1)").
 		From("USER_TABLES").
-		Where(sq.Eq{"TABLE_NAME": strings.ToUpper(tableName)}).
+		Where(sq.Eq{"TABLE_NAME": tableName}).
 		ToSql()
 	if err != nil {
 		return false, err
@@ -119,14 +204,14 @@ func tableExists(ctx context.Context, db *sql.DB, tableName string) (bool, error
 	return cnt > 0, nil
 }
 
-func buildCreateTableDDL(tableName string, cols []ColumnDef) (string, error) {
+func buildCreateTableDDL(tableName string, cols []ColumnDef, opt CreateOptions) (string, error) {
 	if len(cols) == 0 {
 		return "", errors.New("no columns provided")
 	}
 	defs := make([]string, 0, len(cols))
 	pkCols := make([]string, 0, len(cols))
 	for _, c := range cols {
-		colName, _ := normalizeIdentifier(c.Name)
+		colName, _ := opt.Identifier(c.Name)
 		typeStr, err := oracleTypeString(c)
 		if err != nil {
 			return "", fmt.Errorf("column %s: %w", c.Name, err)
@@ -145,7 +230,10 @@ func buildCreateTableDDL(tableName string, cols []ColumnDef) (string, error) {
 	if len(pkCols) > 0 {
 		// Deterministic order for PK columns
 		sort.Strings(pkCols)
-		constraintName := truncateIdentifier(fmt.Sprintf("%s_PK", tableName))
+		constraintName := truncateIdentifier(strings.Trim(tableName, `"`)+"_PK", opt.maxLen())
+		if opt.Quoted {
+			constraintName = `"` + constraintName + `"`
+		}
 		defs = append(defs, fmt.Sprintf("CONSTRAINT %s PRIMARY KEY (%s)", constraintName, strings.Join(pkCols, ", ")))
 	}
 
@@ -179,21 +267,24 @@ func oracleTypeString(c ColumnDef) (string, error) {
 	}
 }
 
-func normalizeIdentifier(name string) (string, error) {
+func normalizeIdentifier(name string, maxLen int) (string, error) {
 	if !identRe.MatchString(name) {
 		return "", fmt.Errorf("identifier must match %s", identRe.String())
 	}
 	upper := strings.ToUpper(name)
-	if len(upper) > 30 {
-		return "", errors.New("identifier exceeds Oracle 30-byte limit")
+	if len(upper) > maxLen {
+		return "", fmt.Errorf("identifier exceeds Oracle %d-byte limit", maxLen)
 	}
 	return upper, nil
 }
 
-func truncateIdentifier(name string) string {
-	upper := strings.ToUpper(name)
-	if len(upper) <= 30 {
-		return upper
+// truncateIdentifier cuts name to maxLen bytes, on a rune boundary
+func truncateIdentifier(name string, maxLen int) string {
+	if len(name) <= maxLen {
+		return name
+	}
+	for maxLen > 0 && !utf8.RuneStart(name[maxLen]) {
+		maxLen--
 	}
-	return upper[:30]
+	return name[:maxLen]
 }
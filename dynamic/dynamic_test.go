@@ -0,0 +1,123 @@
+package dynamic
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sijms/go-ora/v2/network"
+
+	"sql-learn2/internal/sqlfake"
+)
+
+func TestCreateOrReplaceTableWithOptions(t *testing.T) {
+	long := "CUSTOMER_LIFETIME_VALUE_ESTIMATE" // 32 bytes
+	tests := []struct {
+		name    string
+		table   string
+		cols    []ColumnDef
+		opt     CreateOptions
+		exists  bool
+		want    []sqlfake.Call
+		wantErr string
+	}{
+		{
+			name:  "classic",
+			table: "t",
+			cols:  []ColumnDef{{Name: "id", Type: Number, PrimaryKey: true}},
+			want: []sqlfake.Call{
+				{Query: "SELECT COUNT(1) FROM USER_TABLES WHERE TABLE_NAME = :1", Args: []any{"T"}},
+				{Query: "CREATE TABLE T (\n  ID NUMBER NOT NULL,\n  CONSTRAINT T_PK PRIMARY KEY (ID)\n)", Args: []any{}},
+			},
+		},
+		{
+			name:    "long name over 30",
+			table:   "t",
+			cols:    []ColumnDef{{Name: long, Type: Number, Nullable: true}},
+			wantErr: "exceeds Oracle 30-byte limit",
+		},
+		{
+			name:  "long name with 128",
+			table: "t",
+			cols:  []ColumnDef{{Name: long, Type: Number, Nullable: true}},
+			opt:   CreateOptions{MaxIdentifierLen: 128},
+			want: []sqlfake.Call{
+				{Query: "SELECT COUNT(1) FROM USER_TABLES WHERE TABLE_NAME = :1", Args: []any{"T"}},
+				{Query: "CREATE TABLE T (\n  " + long + " NUMBER\n)", Args: []any{}},
+			},
+		},
+		{
+			name:   "quoted keeps case",
+			table:  "Sales 2024",
+			cols:   []ColumnDef{{Name: "first name", Type: Varchar2, Nullable: true}, {Name: "Id", Type: Number, PrimaryKey: true}},
+			opt:    CreateOptions{Quoted: true},
+			exists: true,
+			want: []sqlfake.Call{
+				{Query: "SELECT COUNT(1) FROM USER_TABLES WHERE TABLE_NAME = :1", Args: []any{"Sales 2024"}},
+				{Query: `DROP TABLE "Sales 2024" CASCADE CONSTRAINTS PURGE`, Args: []any{}},
+				{Query: "CREATE TABLE \"Sales 2024\" (\n  \"first name\" VARCHAR2(255),\n  \"Id\" NUMBER NOT NULL,\n  CONSTRAINT \"Sales 2024_PK\" PRIMARY KEY (\"Id\")\n)", Args: []any{}},
+			},
+		},
+		{
+			name:    "quoted rejects double quote",
+			table:   `a"b`,
+			cols:    []ColumnDef{{Name: "x", Type: Number}},
+			opt:     CreateOptions{Quoted: true},
+			wantErr: "invalid table name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			n := int64(0)
+			if tt.exists {
+				n = 1
+			}
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{n})
+			err := CreateOrReplaceTableWithOptions(context.Background(), f.DB, tt.table, tt.cols, tt.opt)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Calls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calls:\n got %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxIdentifierLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    [][]any
+		err     error
+		want    int
+		wantErr bool
+	}{
+		{"12.2+", [][]any{{int64(128)}}, nil, 128, false},
+		{"compatible below 12.2", [][]any{{int64(30)}}, nil, 30, false},
+		{"before 12.2", nil, network.NewOracleError(904), 30, false},
+		{"other error", nil, errors.New("boom"), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			if tt.err != nil {
+				f.Fail("ORA_MAX_NAME_LEN_SUPPORTED", tt.err)
+			} else {
+				f.OnQuery("ORA_MAX_NAME_LEN_SUPPORTED", []string{"ORA_MAX_NAME_LEN_SUPPORTED"}, tt.rows...)
+			}
+			got, err := MaxIdentifierLength(context.Background(), f.DB)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("MaxIdentifierLength = %d, %v; want %d", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package dynamic
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// SetLogging switches table (which may be schema-qualified) to LOGGING or
+// NOLOGGING. Direct-path inserts into a NOLOGGING table write no redo for
+// the data, so it cannot be recovered from the archived logs until the
+// next backup.
+func SetLogging(ctx context.Context, db *sql.DB, table string, logging bool) error {
+	if db == nil {
+		return errors.New("db is nil")
+	}
+	mode := "NOLOGGING"
+	if logging {
+		mode = "LOGGING"
+	}
+	if _, err := db.ExecContext(ctx, "ALTER TABLE "+table+" "+mode); err != nil {
+		return fmt.Errorf("set %s %s: %w", table, mode, err)
+	}
+	return nil
+}
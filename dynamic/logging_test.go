@@ -0,0 +1,28 @@
+package dynamic
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+)
+
+func TestSetLogging(t *testing.T) {
+	tests := []struct {
+		logging bool
+		want    string
+	}{
+		{false, "ALTER TABLE S.T NOLOGGING"},
+		{true, "ALTER TABLE S.T LOGGING"},
+	}
+	for _, tt := range tests {
+		f := sqlfake.New(t)
+		if err := SetLogging(context.Background(), f.DB, "S.T", tt.logging); err != nil {
+			t.Fatal(err)
+		}
+		if got := f.Queries(); !reflect.DeepEqual(got, []string{tt.want}) {
+			t.Errorf("SetLogging(%v) queries = %q, want %q", tt.logging, got, tt.want)
+		}
+	}
+}
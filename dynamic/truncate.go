@@ -0,0 +1,54 @@
+package dynamic
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"sql-learn2/logging"
+	"sql-learn2/oraerr"
+)
+
+// TruncateOptions tune TruncateTable; the zero value is a plain TRUNCATE TABLE
+type TruncateOptions struct {
+	// ReuseStorage keeps the table's extents allocated (REUSE STORAGE), so a
+	// table reloaded to the same size does not give back and regrow its space
+	ReuseStorage bool
+	// FallbackDelete empties the table with DELETE and a commit when TRUNCATE
+	// is refused with ORA-01031, e.g. for a table in another schema where the
+	// user only has DELETE. Much slower and generates undo for every row.
+	FallbackDelete bool
+}
+
+// TruncateTable empties table (which may be schema-qualified) and reports
+// whether it had to fall back to DELETE
+func TruncateTable(ctx context.Context, db *sql.DB, table string, opt TruncateOptions) (deleted bool, err error) {
+	if db == nil {
+		return false, errors.New("db is nil")
+	}
+	stmt := "TRUNCATE TABLE " + table
+	if opt.ReuseStorage {
+		stmt += " REUSE STORAGE"
+	}
+	_, err = db.ExecContext(ctx, stmt)
+	if err == nil {
+		return false, nil
+	}
+	if !opt.FallbackDelete || oraerr.Code(err) != 1031 { // ORA-01031: insufficient privileges
+		return false, fmt.Errorf("truncate %s: %w", table, err)
+	}
+	logging.FromContext(ctx).Warn("TRUNCATE not permitted, deleting rows instead", logging.FieldTable, table, logging.FieldError, err)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("delete %s: %w", table, err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+		return false, fmt.Errorf("delete %s: %w", table, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("delete %s: commit: %w", table, err)
+	}
+	return true, nil
+}
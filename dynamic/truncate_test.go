@@ -0,0 +1,49 @@
+package dynamic
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sijms/go-ora/v2/network"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/logging"
+)
+
+func TestTruncateTable(t *testing.T) {
+	quiet := logging.WithLogger(context.Background(), logging.Discard())
+	denied := network.NewOracleError(1031)
+	tests := []struct {
+		name        string
+		opt         TruncateOptions
+		truncateErr error
+		want        []string
+		wantDeleted bool
+		wantErr     bool
+	}{
+		{"plain", TruncateOptions{}, nil, []string{"TRUNCATE TABLE S.T"}, false, false},
+		{"reuse storage", TruncateOptions{ReuseStorage: true}, nil, []string{"TRUNCATE TABLE S.T REUSE STORAGE"}, false, false},
+		{"denied without fallback", TruncateOptions{}, denied, []string{"TRUNCATE TABLE S.T"}, false, true},
+		{"denied with fallback", TruncateOptions{ReuseStorage: true, FallbackDelete: true}, denied,
+			[]string{"TRUNCATE TABLE S.T REUSE STORAGE", "DELETE FROM S.T", "COMMIT"}, true, false},
+		{"other error not retried", TruncateOptions{FallbackDelete: true}, errors.New("ORA-00054"),
+			[]string{"TRUNCATE TABLE S.T"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			if tt.truncateErr != nil {
+				f.Fail("^TRUNCATE", tt.truncateErr)
+			}
+			deleted, err := TruncateTable(quiet, f.DB, "S.T", tt.opt)
+			if (err != nil) != tt.wantErr || deleted != tt.wantDeleted {
+				t.Errorf("TruncateTable() = %v, %v", deleted, err)
+			}
+			if got := f.Queries(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queries = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"sql-learn2/bulkinsert"
+	"sql-learn2/retry"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// dbWriter inserts generated rows straight into a table through bulkinsert,
+// one array-bound INSERT per batch.
+//
+// Values are bound as strings and converted by Oracle, so the session NLS
+// formats are pinned to the generator's defaults: '.' decimals, YYYY-MM-DD
+// dates and YYYY-MM-DD HH24:MI:SS timestamps (custom layouts are not
+// understood). Empty strings are NULL in Oracle, so null ratios carry over.
+type dbWriter struct {
+	ctx       context.Context
+	db        *sqlx.DB
+	table     string
+	batchRows int
+
+	columns []string // target column names
+	source  []int    // schema column index for each target column
+	data    [][]string
+	n       int
+
+	inserted int
+	elapsed  time.Duration
+}
+
+// sessionNLS makes the implicit string conversions match what the generator writes
+var sessionNLS = []string{
+	"ALTER SESSION SET NLS_NUMERIC_CHARACTERS = '.,'",
+	"ALTER SESSION SET NLS_DATE_FORMAT = 'YYYY-MM-DD'",
+	"ALTER SESSION SET NLS_TIMESTAMP_FORMAT = 'YYYY-MM-DD HH24:MI:SS'",
+}
+
+func newDBWriter(ctx context.Context, db *sqlx.DB, table string, batchRows int, schema *Schema) (*dbWriter, error) {
+	if batchRows <= 0 {
+		batchRows = 50000
+	}
+	// Batches hitting a dropped connection or a lock timeout are retried
+	w := &dbWriter{ctx: retry.WithPolicy(ctx, retry.Default), db: db, table: table, batchRows: batchRows}
+	for i, c := range schema.Columns {
+		if col := c.TargetColumn(); col != "" {
+			w.columns = append(w.columns, col)
+			w.source = append(w.source, i)
+		}
+	}
+	if len(w.columns) == 0 {
+		return nil, fmt.Errorf("no columns map to table %s (all db_column are \"-\")", table)
+	}
+
+	// ALTER SESSION only affects one connection, so pin the pool to it
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	for _, stmt := range sessionNLS {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+	w.reset()
+	return w, nil
+}
+
+func (w *dbWriter) reset() {
+	w.data = make([][]string, len(w.columns))
+	for i := range w.data {
+		w.data[i] = make([]string, 0, w.batchRows)
+	}
+	w.n = 0
+}
+
+// Write buffers one row and inserts the batch once it is full
+func (w *dbWriter) Write(row []string) error {
+	for i, src := range w.source {
+		w.data[i] = append(w.data[i], row[src])
+	}
+	w.n++
+	if w.n >= w.batchRows {
+		return w.insert()
+	}
+	return nil
+}
+
+// Flush is a no-op; batches are sent when full and on Close
+func (w *dbWriter) Flush() error { return nil }
+
+func (w *dbWriter) insert() error {
+	if w.n == 0 {
+		return nil
+	}
+	columnData := make([]interface{}, len(w.data))
+	for i, d := range w.data {
+		columnData[i] = d
+	}
+	d, err := bulkinsert.InsertBatched(w.ctx, w.db, w.table, w.columns, columnData...)
+	if err != nil {
+		return fmt.Errorf("insert batch into %s after %d rows: %w", w.table, w.inserted, err)
+	}
+	w.inserted += w.n
+	w.elapsed += d
+	log.Printf("Inserted %d rows into %s (batch %d rows in %v)", w.inserted, w.table, w.n, d)
+	w.reset()
+	return nil
+}
+
+// Close inserts the last partial batch
+func (w *dbWriter) Close() ([]string, error) {
+	if err := w.insert(); err != nil {
+		return nil, err
+	}
+	log.Printf("Loaded %d rows into %s, total insert time %v", w.inserted, w.table, w.elapsed)
+	return []string{w.table}, nil
+}
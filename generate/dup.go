@@ -0,0 +1,102 @@
+package main
+
+import "fmt"
+
+// DuplicateSpec controls how many rows reuse an existing key, for upsert/MERGE testing.
+//
+// Every value of a row is derived from its key number, so a row that reuses
+// key k starts out identical to the row that first had k (in this file, or in
+// a base file of ExistingRows rows generated with the same seed and no
+// duplicates). ChangeRatio of its non-key columns are then redrawn.
+type DuplicateSpec struct {
+	Ratio        float64  `yaml:"ratio"`         // fraction of rows that reuse a key
+	ChangeRatio  float64  `yaml:"change_ratio"`  // fraction of non-key columns changed on a reused key; 0 = exact copy
+	ExistingRows int      `yaml:"existing_rows"` // keys 1..ExistingRows are assumed already loaded
+	Key          []string `yaml:"key"`           // key columns, never changed
+}
+
+func (d *DuplicateSpec) validate(s *Schema) error {
+	if d.Ratio < 0 || d.Ratio > 0.95 {
+		return fmt.Errorf("duplicates.ratio must be between 0 and 0.95")
+	}
+	if d.ChangeRatio < 0 || d.ChangeRatio > 1 {
+		return fmt.Errorf("duplicates.change_ratio must be between 0 and 1")
+	}
+	if d.ExistingRows < 0 {
+		return fmt.Errorf("duplicates.existing_rows must not be negative")
+	}
+	if (d.Ratio > 0 || d.ExistingRows > 0) && len(d.Key) == 0 {
+		return fmt.Errorf("duplicates.key is required")
+	}
+	for _, k := range d.Key {
+		if !s.hasColumn(k) {
+			return fmt.Errorf("duplicates.key: unknown column %q", k)
+		}
+	}
+	return nil
+}
+
+// Salts keep the independent random streams of one row apart
+const (
+	saltValues uint64 = iota + 1
+	saltDuplicate
+	saltChange
+	saltChildren
+	saltTable
+)
+
+// rowSource is a splitmix64 math/rand Source. Unlike the default source it
+// is cheap to reseed, so every row can get its own stream.
+type rowSource struct{ state uint64 }
+
+func (s *rowSource) Seed(seed int64) { s.state = uint64(seed) }
+
+func (s *rowSource) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *rowSource) Int63() int64 { return int64(s.Uint64() >> 1) }
+
+// streamSeed mixes the run seed, a salt and a row or key number into one seed
+func streamSeed(seed int64, salt uint64, n int) int64 {
+	s := rowSource{state: uint64(seed) ^ salt*0xd1b54a32d192ed03}
+	s.state += uint64(n) * 0x9e3779b97f4a7c15
+	return int64(s.Uint64())
+}
+
+// unitFloat maps a hash to [0, 1)
+func unitFloat(seed int64) float64 {
+	return float64(uint64(seed)>>11) / (1 << 53)
+}
+
+// isDuplicate reports whether row n reuses a key; decided by hash so any
+// row can be checked without generating the ones before it
+func (d *DuplicateSpec) isDuplicate(seed int64, n int) bool {
+	return d.Ratio > 0 && unitFloat(streamSeed(seed, saltDuplicate, n)) < d.Ratio
+}
+
+// keyOf returns the key number of row n and whether it is reused.
+// New rows get key ExistingRows+n; a reused key is drawn from the existing
+// keys and the new keys of earlier rows.
+func (d *DuplicateSpec) keyOf(seed int64, n int) (int, bool) {
+	if !d.isDuplicate(seed, n) {
+		return d.ExistingRows + n, false
+	}
+	pool := d.ExistingRows + n - 1
+	if pool == 0 {
+		return d.ExistingRows + n, false
+	}
+	src := rowSource{state: uint64(streamSeed(seed, saltDuplicate, -n))}
+	for range 64 {
+		k := 1 + int(src.Uint64()%uint64(pool))
+		if k <= d.ExistingRows || !d.isDuplicate(seed, k-d.ExistingRows) {
+			return k, true
+		}
+	}
+	// Only reachable with a ratio near the limit; fall back to a new key
+	return d.ExistingRows + n, false
+}
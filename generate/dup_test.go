@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDuplicateSpec_Validate(t *testing.T) {
+	s := &Schema{Columns: []ColumnSpec{{Name: "ID", Type: "seq"}}}
+	tests := []struct {
+		name    string
+		d       DuplicateSpec
+		wantErr string
+	}{
+		{"none", DuplicateSpec{}, ""},
+		{"valid", DuplicateSpec{Ratio: 0.3, ChangeRatio: 1, ExistingRows: 10, Key: []string{"ID"}}, ""},
+		{"ratio too high", DuplicateSpec{Ratio: 0.96, Key: []string{"ID"}}, "duplicates.ratio must be between 0 and 0.95"},
+		{"negative ratio", DuplicateSpec{Ratio: -0.1, Key: []string{"ID"}}, "duplicates.ratio must be between 0 and 0.95"},
+		{"change ratio", DuplicateSpec{Ratio: 0.1, ChangeRatio: 1.5, Key: []string{"ID"}}, "duplicates.change_ratio must be between 0 and 1"},
+		{"existing rows", DuplicateSpec{ExistingRows: -1, Key: []string{"ID"}}, "duplicates.existing_rows must not be negative"},
+		{"no key", DuplicateSpec{Ratio: 0.1}, "duplicates.key is required"},
+		{"no key for existing rows", DuplicateSpec{ExistingRows: 5}, "duplicates.key is required"},
+		{"unknown key", DuplicateSpec{Ratio: 0.1, Key: []string{"CODE"}}, `duplicates.key: unknown column "CODE"`},
+	}
+	for _, tt := range tests {
+		err := tt.d.validate(s)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDuplicateSpec_KeyOf(t *testing.T) {
+	const rows = 20000
+	tests := []struct {
+		name string
+		d    DuplicateSpec
+	}{
+		{"no duplicates", DuplicateSpec{}},
+		{"ratio", DuplicateSpec{Ratio: 0.25}},
+		{"high ratio", DuplicateSpec{Ratio: 0.9}},
+		{"existing rows", DuplicateSpec{Ratio: 0.4, ExistingRows: 5000}},
+		{"existing rows only", DuplicateSpec{ExistingRows: 5000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reused, fromExisting := 0, 0
+			seen := make(map[int]bool)
+			for n := 1; n <= rows; n++ {
+				key, ok := tt.d.keyOf(7, n)
+				if !ok {
+					if key != tt.d.ExistingRows+n {
+						t.Fatalf("row %d: new key %d, want %d", n, key, tt.d.ExistingRows+n)
+					}
+					seen[key] = true
+					continue
+				}
+				reused++
+				if key <= tt.d.ExistingRows {
+					fromExisting++
+				} else if !seen[key] {
+					// a reused key is one already loaded or written by an earlier row
+					t.Fatalf("row %d reuses key %d, which no earlier row has", n, key)
+				}
+			}
+			if got := float64(reused) / rows; math.Abs(got-tt.d.Ratio) > 0.02 {
+				t.Errorf("%.3f of the rows reuse a key, want about %.2f", got, tt.d.Ratio)
+			}
+			if tt.d.ExistingRows > 0 && tt.d.Ratio > 0 && fromExisting == 0 {
+				t.Error("no row reuses one of the existing keys")
+			}
+			if tt.d.ExistingRows == 0 && fromExisting > 0 {
+				t.Errorf("%d rows reuse a key that was never written", fromExisting)
+			}
+		})
+	}
+}
+
+func TestRowGenerator_ReusedRows(t *testing.T) {
+	cols := []ColumnSpec{
+		{Name: "ID", Type: "seq"},
+		{Name: "NAME", Type: "string", Cardinality: 1000},
+		{Name: "QTY", Type: "int", Min: 1, Max: 1000000},
+		{Name: "NOTE", Type: "text", MinLength: 20, MaxLength: 20},
+	}
+	tests := []struct {
+		name        string
+		changeRatio float64
+		wantChanged bool
+	}{
+		{"exact copy", 0, false},
+		{"changed", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Schema{Columns: cols, Duplicates: DuplicateSpec{Ratio: 0.3, ChangeRatio: tt.changeRatio, Key: []string{"ID"}}}
+			g := newRowGenerator(s, 3)
+			byKey := make(map[string][]string)
+			reused := 0
+			for n := 1; n <= 2000; n++ {
+				row := make([]string, len(cols))
+				if !g.Row(n, row) {
+					byKey[row[0]] = row
+					continue
+				}
+				reused++
+				orig, ok := byKey[row[0]]
+				if !ok {
+					t.Fatalf("row %d reuses unknown key %s", n, row[0])
+				}
+				if changed := !slices.Equal(row[1:], orig[1:]); changed != tt.wantChanged {
+					t.Errorf("row %d = %v, first written as %v", n, row, orig)
+				}
+			}
+			if reused == 0 {
+				t.Fatal("no reused rows")
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// rowEncoder writes rows in one output format
+type rowEncoder interface {
+	Header() error
+	Write(row []string) error
+	Flush() error
+}
+
+// newEncoderFunc returns a constructor for the format: csv, jsonl or fixed.
+// maxKey is the largest key number generated, used to size fixed-width seq columns.
+func newEncoderFunc(format string, schema *Schema, maxKey int) (func(io.Writer) rowEncoder, error) {
+	switch format {
+	case "", "csv":
+		return func(w io.Writer) rowEncoder {
+			return &csvEncoder{w: csv.NewWriter(w), header: schema.Header()}
+		}, nil
+	case "jsonl":
+		return func(w io.Writer) rowEncoder { return newJSONLEncoder(w, schema) }, nil
+	case "fixed":
+		widths, err := schema.fixedWidths(maxKey)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer) rowEncoder { return newFixedEncoder(w, schema, widths) }, nil
+	}
+	return nil, fmt.Errorf("unknown format %q (want csv, jsonl or fixed)", format)
+}
+
+type csvEncoder struct {
+	w      *csv.Writer
+	header []string
+}
+
+func (e *csvEncoder) Header() error            { return e.w.Write(e.header) }
+func (e *csvEncoder) Write(row []string) error { return e.w.Write(row) }
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonlEncoder writes one JSON object per line. Numeric and bool columns are
+// written as JSON numbers, empty values as null, everything else as strings.
+type jsonlEncoder struct {
+	w       *bufio.Writer
+	keys    [][]byte // pre-encoded `"NAME":`
+	numeric []bool
+	buf     []byte
+}
+
+func newJSONLEncoder(w io.Writer, schema *Schema) *jsonlEncoder {
+	e := &jsonlEncoder{w: bufio.NewWriterSize(w, 1<<16)}
+	for _, c := range schema.Columns {
+		k, _ := json.Marshal(c.Name)
+		e.keys = append(e.keys, append(k, ':'))
+		e.numeric = append(e.numeric, c.isNumeric())
+	}
+	return e
+}
+
+func (e *jsonlEncoder) Header() error { return nil }
+
+func (e *jsonlEncoder) Write(row []string) error {
+	b := append(e.buf[:0], '{')
+	for i, v := range row {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, e.keys[i]...)
+		switch {
+		case v == "":
+			b = append(b, "null"...)
+		case e.numeric[i]:
+			b = append(b, v...)
+		default:
+			b = strconv.AppendQuote(b, v)
+		}
+	}
+	b = append(b, '}', '\n')
+	e.buf = b
+	_, err := e.w.Write(b)
+	return err
+}
+
+func (e *jsonlEncoder) Flush() error { return e.w.Flush() }
+
+// fixedEncoder pads every value to its column width: numbers right-aligned,
+// everything else left-aligned. There is no header line; the schema is the layout.
+type fixedEncoder struct {
+	w      *bufio.Writer
+	widths []int
+	right  []bool
+	names  []string
+}
+
+func newFixedEncoder(w io.Writer, schema *Schema, widths []int) *fixedEncoder {
+	e := &fixedEncoder{w: bufio.NewWriterSize(w, 1<<16), widths: widths}
+	for _, c := range schema.Columns {
+		e.right = append(e.right, c.isNumeric())
+		e.names = append(e.names, c.Name)
+	}
+	return e
+}
+
+func (e *fixedEncoder) Header() error { return nil }
+
+func (e *fixedEncoder) Write(row []string) error {
+	for i, v := range row {
+		pad := e.widths[i] - utf8.RuneCountInString(v)
+		if pad < 0 {
+			return fmt.Errorf("column %s: value %q longer than width %d", e.names[i], v, e.widths[i])
+		}
+		if strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("column %s: value contains a line break", e.names[i])
+		}
+		if e.right[i] {
+			e.spaces(pad)
+			e.w.WriteString(v)
+		} else {
+			e.w.WriteString(v)
+			e.spaces(pad)
+		}
+	}
+	return e.w.WriteByte('\n')
+}
+
+func (e *fixedEncoder) spaces(n int) {
+	for range n {
+		e.w.WriteByte(' ')
+	}
+}
+
+func (e *fixedEncoder) Flush() error { return e.w.Flush() }
+
+func (c ColumnSpec) isNumeric() bool {
+	switch c.Type {
+	case "int", "float", "decimal", "bool", "derived":
+		return true
+	case "seq":
+		return c.Format == ""
+	}
+	return false
+}
+
+// fixedWidths returns each column's width: Width when set, otherwise the
+// widest value the column can produce
+func (s *Schema) fixedWidths(maxKey int) ([]int, error) {
+	widths := make([]int, len(s.Columns))
+	for i, c := range s.Columns {
+		if c.Width > 0 {
+			widths[i] = c.Width
+			continue
+		}
+		switch c.Type {
+		case "seq":
+			format := c.Format
+			if format == "" {
+				format = "%d"
+			}
+			widths[i] = utf8.RuneCountInString(fmt.Sprintf(format, maxKey))
+		case "string":
+			n := c.Cardinality
+			if n <= 0 {
+				n = int(c.Max)
+			}
+			format := c.Format
+			if format == "" {
+				format = strings.ToLower(c.Name) + "_%d"
+			}
+			widths[i] = utf8.RuneCountInString(fmt.Sprintf(format, n))
+		case "int":
+			widths[i] = max(len(strconv.FormatInt(int64(c.Min), 10)), len(strconv.FormatInt(int64(c.Max), 10)))
+		case "float", "decimal":
+			widths[i] = max(len(strconv.FormatFloat(c.Min, 'f', c.Scale, 64)), len(strconv.FormatFloat(c.Max, 'f', c.Scale, 64)))
+		case "derived":
+			widths[i] = 20
+		case "bool":
+			widths[i] = 1
+		case "date", "timestamp":
+			lo, _, _ := c.timeRange()
+			widths[i] = len(lo.Format(c.timeLayout()))
+		case "text":
+			widths[i] = c.MaxLength
+		case "enum":
+			for _, v := range c.Values {
+				widths[i] = max(widths[i], utf8.RuneCountInString(v))
+			}
+		}
+	}
+	return widths, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var formatSchema = &Schema{Columns: []ColumnSpec{
+	{Name: "ID", Type: "seq"},
+	{Name: "CODE", Type: "seq", Format: "P-%03d"},
+	{Name: "NAME", Type: "string", Cardinality: 99},
+	{Name: "PRICE", Type: "decimal", Min: 0, Max: 999, Scale: 2},
+	{Name: "ACTIVE", Type: "bool"},
+}}
+
+func encode(t *testing.T, format string, maxKey int, rows ...[]string) (string, error) {
+	t.Helper()
+	newEnc, err := newEncoderFunc(format, formatSchema, maxKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	enc := newEnc(&buf)
+	if err := enc.Header(); err != nil {
+		return "", err
+	}
+	for _, row := range rows {
+		if err := enc.Write(row); err != nil {
+			return "", err
+		}
+	}
+	err = enc.Flush()
+	return buf.String(), err
+}
+
+func TestEncoders(t *testing.T) {
+	rows := [][]string{
+		{"7", "P-007", "name_12", "3.50", "1"},
+		{"12", "P-012", `a "b"`, "", "0"},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"csv", "ID,CODE,NAME,PRICE,ACTIVE\n7,P-007,name_12,3.50,1\n12,P-012,\"a \"\"b\"\"\",,0\n"},
+		{"jsonl", `{"ID":7,"CODE":"P-007","NAME":"name_12","PRICE":3.50,"ACTIVE":1}` + "\n" +
+			`{"ID":12,"CODE":"P-012","NAME":"a \"b\"","PRICE":null,"ACTIVE":0}` + "\n"},
+		// widths: ID 3 (key 100), CODE 5, NAME 7, PRICE 6, ACTIVE 1
+		{"fixed", "  7P-007name_12  3.501\n 12P-012a \"b\"        0\n"},
+	}
+	for _, tt := range tests {
+		got, err := encode(t, tt.format, 100, rows...)
+		if err != nil {
+			t.Errorf("%s: %v", tt.format, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestFixedEncoder_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		row     []string
+		wantErr string
+	}{
+		{"too wide", []string{"1000", "P-001", "name_1", "1.00", "1"}, `column ID: value "1000" longer than width 3`},
+		{"line break", []string{"1", "P-001", "a\nb", "1.00", "1"}, "column NAME: value contains a line break"},
+	}
+	for _, tt := range tests {
+		_, err := encode(t, "fixed", 100, tt.row)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNewEncoderFunc_UnknownFormat(t *testing.T) {
+	if _, err := newEncoderFunc("xml", formatSchema, 1); err == nil || !strings.Contains(err.Error(), `unknown format "xml"`) {
+		t.Errorf("err = %v, want unknown format", err)
+	}
+}
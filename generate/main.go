@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sql-learn2/bulkinsert"
+	"sql-learn2/logging"
+	"sql-learn2/oraconn"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func main() {
+	// Command line flags
+	rowCount := flag.Int("rows", 0, "Number of rows to generate (default: the schema's rows, or 1000000)")
+	preset := flag.String("preset", DefaultPreset, "Built-in schema: "+strings.Join(PresetNames(), ", "))
+	outputFile := flag.String("output", "", "Output file path (default: <preset>.csv, or <schema name>.csv)")
+	format := flag.String("format", "csv", "Output format: csv, jsonl (one JSON object per line) or fixed (fixed-width, widths from the schema)")
+	schemaFile := flag.String("schema", "", "YAML/JSON schema file describing the columns; overrides -preset")
+	gzipOut := flag.Bool("gzip", false, "Gzip-compress the output (appends .gz to the file name)")
+	chunkRows := flag.Int("chunk-rows", 0, "Split the output into files of N rows each, every file with its own header (0 = single file)")
+	workers := flag.Int("workers", 1, "Number of goroutines generating row blocks in parallel")
+	blockRows := flag.Int("block-rows", 10000, "Rows per block handed to a worker")
+	dupRatio := flag.Float64("dup-ratio", -1, "Fraction of rows reusing an existing key (overrides the schema's duplicates.ratio)")
+	dupChange := flag.Float64("dup-change", -1, "Fraction of non-key columns changed on a reused key, 0 = exact copy (overrides duplicates.change_ratio)")
+	existingRows := flag.Int("existing-rows", -1, "Keys 1..N are assumed already loaded, e.g. by an earlier run with -rows N and the same -seed; new keys start at N+1 (overrides duplicates.existing_rows)")
+	seed := flag.Int64("seed", 0, "Random seed; the same seed, schema and row count produce a byte-identical file (0 = time-based)")
+	dbTable := flag.String("db-table", "", "Insert rows straight into this table via bulkinsert instead of writing a file")
+	dbBatchRows := flag.Int("db-batch-rows", 50000, "Rows per array-bound INSERT with -db-table")
+	dbInsert := flag.String("db-insert", "array", "How -db-table batches are sent: array (array binds) or insert-all (INSERT ALL statements)")
+	var ora oraconn.Config
+	ora.RegisterFlags(flag.CommandLine)
+	var logCfg logging.Config
+	logCfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := logCfg.Setup(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	insertStrategy, err := bulkinsert.ParseStrategy(*dbInsert)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	schema, err := LoadSchema(*schemaFile, *preset)
+	if err != nil {
+		log.Fatalf("Failed to load schema: %v", err)
+	}
+	if *outputFile == "" {
+		*outputFile = defaultOutput(*schemaFile, *preset, *format)
+	}
+	if *dupRatio >= 0 {
+		schema.Duplicates.Ratio = *dupRatio
+	}
+	if *dupChange >= 0 {
+		schema.Duplicates.ChangeRatio = *dupChange
+	}
+	if *existingRows >= 0 {
+		schema.Duplicates.ExistingRows = *existingRows
+	}
+	if err := schema.Duplicates.validate(schema); err != nil {
+		log.Fatalf("Invalid duplicate options: %v", err)
+	}
+	rows := *rowCount
+	if rows <= 0 {
+		rows = schema.Rows
+	}
+	if rows <= 0 {
+		rows = 1000000
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	cfg := &runConfig{
+		output:      *outputFile,
+		format:      *format,
+		gzip:        *gzipOut,
+		chunkRows:   *chunkRows,
+		blockRows:   *blockRows,
+		workers:     *workers,
+		dbTable:     *dbTable,
+		dbBatchRows: *dbBatchRows,
+		dbInsert:    insertStrategy,
+		seed:        *seed,
+	}
+	if *dbTable != "" {
+		db, err := oraconn.ConnectX(context.Background(), ora)
+		if err != nil {
+			log.Fatalf("Failed to connect: %v", err)
+		}
+		defer db.Close()
+		log.Printf("Connected to %s", ora)
+		cfg.db = db
+	}
+
+	// Log the seed so any run can be regenerated with -seed
+	log.Printf("Generating %d table(s) with seed %d...", len(schema.Tables()), *seed)
+	start := time.Now()
+	if err := cfg.run(schema, rows, nil); err != nil {
+		log.Fatalf("%v", err)
+	}
+	log.Printf("All done in %v.", time.Since(start))
+}
+
+// runConfig holds the output options shared by every table of a run
+type runConfig struct {
+	output      string
+	format      string
+	gzip        bool
+	chunkRows   int
+	blockRows   int
+	workers     int
+	dbTable     string
+	dbBatchRows int
+	dbInsert    bulkinsert.Strategy
+	db          *sqlx.DB
+	seed        int64
+
+	tables int // tables generated so far, for per-table seeds
+}
+
+// parentTable is what a child needs from the table it hangs off
+type parentTable struct {
+	schema *Schema
+	seed   int64
+	keys   []int
+}
+
+// run generates table t and then its children
+func (cfg *runConfig) run(t *Schema, rows int, parent *parentTable) error {
+	seed := tableSeed(cfg.seed, cfg.tables)
+	cfg.tables++
+
+	name, output, table := t.Name, cfg.output, cfg.dbTable
+	if name == "" {
+		name = filepath.Base(cfg.output)
+	}
+	var newLink func() *parentLink
+	if parent != nil {
+		plan := newChildPlan(t, parent.keys, seed)
+		rows = plan.Rows()
+		name, output, table = t.Name, childOutput(cfg.output, t), t.DBTable
+		if table == "" {
+			table = t.Name
+		}
+		fk, ref := t.columnIndex(t.Parent.Column), parent.schema.columnIndex(t.Parent.References)
+		newLink = func() *parentLink {
+			return &parentLink{
+				plan:   plan,
+				parent: newRowGenerator(parent.schema, parent.seed),
+				fk:     fk,
+				ref:    ref,
+				row:    make([]string, len(parent.schema.Columns)),
+			}
+		}
+	}
+	if cfg.db == nil {
+		table = ""
+	}
+
+	target := output
+	if table != "" {
+		target = "table " + table
+	}
+	log.Printf("Generating %s: %d rows (%d columns) to %s...", name, rows, len(t.Columns), target)
+	start := time.Now()
+
+	// 1. Open output (writes the header, or prepares the session for -db-table)
+	var writer rowSink
+	var err error
+	if table != "" {
+		if writer, err = newDBWriter(bulkinsert.WithStrategy(context.Background(), cfg.dbInsert), cfg.db, table, cfg.dbBatchRows, t); err != nil {
+			return fmt.Errorf("failed to prepare insert into %s: %w", table, err)
+		}
+	} else {
+		encoder, err := newEncoderFunc(cfg.format, t, t.Duplicates.ExistingRows+rows)
+		if err != nil {
+			return fmt.Errorf("invalid output format: %w", err)
+		}
+		if writer, err = newOutputWriter(output, cfg.gzip, cfg.chunkRows, encoder); err != nil {
+			return fmt.Errorf("failed to open output: %w", err)
+		}
+	}
+
+	// 2. Write Data Rows
+	newGen := func() *rowGenerator {
+		g := newRowGenerator(t, seed)
+		if newLink != nil {
+			g.link = newLink()
+		}
+		return g
+	}
+	reused, err := generate(newGen, writer, rows, cfg.blockRows, cfg.workers)
+	if err != nil {
+		return fmt.Errorf("failed to generate %s rows: %w", name, err)
+	}
+
+	files, err := writer.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close %s output: %w", name, err)
+	}
+
+	duration := time.Since(start)
+	log.Printf("Done. Generated %d rows into %s in %v (%.0f rows/s, %d workers).",
+		rows, describeOutput(files, table), duration, rowsPerSecond(rows, duration), cfg.workers)
+	if d := t.Duplicates; d.Ratio > 0 || d.ExistingRows > 0 {
+		log.Printf("Keys: %d new, %d reused (%d existing keys, change ratio %.2f).",
+			rows-reused, reused, d.ExistingRows, d.ChangeRatio)
+	}
+	if table == "" && len(files) > 1 {
+		log.Printf("Files: %s ... %s", files[0], files[len(files)-1])
+	}
+
+	// 3. Children, each parent key exactly once
+	if len(t.Children) > 0 {
+		self := &parentTable{schema: t, seed: seed, keys: distinctKeys(t, seed, rows)}
+		for _, c := range t.Children {
+			if err := cfg.run(c, 0, self); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// defaultOutput names the file after the preset or schema file, with the format's extension
+func defaultOutput(schemaFile, preset, format string) string {
+	name := preset
+	if schemaFile != "" {
+		name = strings.TrimSuffix(filepath.Base(schemaFile), filepath.Ext(schemaFile))
+	}
+	switch format {
+	case "jsonl":
+		return name + ".jsonl"
+	case "fixed":
+		return name + ".txt"
+	}
+	return name + ".csv"
+}
+
+func describeOutput(files []string, table string) string {
+	if table != "" {
+		return "table " + table
+	}
+	return fmt.Sprintf("%d file(s)", len(files))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// rowSink receives generated rows in order
+type rowSink interface {
+	Write(row []string) error
+	Flush() error
+	// Close finishes the output and returns what was written (file names or table)
+	Close() ([]string, error)
+}
+
+// outputWriter writes rows to one file, or to a new file every chunkRows rows.
+// Each file gets its own header (for formats that have one); with gzip every file is compressed separately.
+type outputWriter struct {
+	base      string
+	gzip      bool
+	chunkRows int
+	encoder   func(io.Writer) rowEncoder
+
+	file   *os.File
+	gz     *gzip.Writer
+	enc    rowEncoder
+	chunk  int // current chunk number, 1-based
+	inFile int // rows written to the current file
+	files  []string
+}
+
+func newOutputWriter(base string, gz bool, chunkRows int, encoder func(io.Writer) rowEncoder) (*outputWriter, error) {
+	w := &outputWriter{base: base, gzip: gz, chunkRows: chunkRows, encoder: encoder}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// fileName returns the path of the current chunk.
+// product_data.csv becomes product_data_0001.csv when chunking and gets .gz appended with gzip.
+func (w *outputWriter) fileName() string {
+	name := w.base
+	if w.chunkRows > 0 {
+		ext := filepath.Ext(name)
+		name = fmt.Sprintf("%s_%04d%s", strings.TrimSuffix(name, ext), w.chunk, ext)
+	}
+	if w.gzip && !strings.HasSuffix(name, ".gz") {
+		name += ".gz"
+	}
+	return name
+}
+
+func (w *outputWriter) open() error {
+	w.chunk++
+	w.inFile = 0
+	name := w.fileName()
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", name, err)
+	}
+	w.file = f
+	var out io.Writer = f
+	if w.gzip {
+		w.gz = gzip.NewWriter(f)
+		out = w.gz
+	}
+	w.enc = w.encoder(out)
+	w.files = append(w.files, name)
+	if err := w.enc.Header(); err != nil {
+		return fmt.Errorf("write header to %s: %w", name, err)
+	}
+	return nil
+}
+
+// Write appends one row, rolling over to the next chunk when the current one is full
+func (w *outputWriter) Write(row []string) error {
+	if w.chunkRows > 0 && w.inFile >= w.chunkRows {
+		if err := w.closeFile(); err != nil {
+			return err
+		}
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	w.inFile++
+	return w.enc.Write(row)
+}
+
+// Flush pushes buffered rows to the current file
+func (w *outputWriter) Flush() error {
+	return w.enc.Flush()
+}
+
+func (w *outputWriter) closeFile() error {
+	if err := w.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	if w.gz != nil {
+		if err := w.gz.Close(); err != nil {
+			w.file.Close()
+			return err
+		}
+		w.gz = nil
+	}
+	return w.file.Close()
+}
+
+// Close finishes the last file and returns every file written
+func (w *outputWriter) Close() ([]string, error) {
+	return w.files, w.closeFile()
+}
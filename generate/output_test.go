@@ -0,0 +1,101 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// genOptions are the output settings of writeFiles; the zero value writes a
+// single CSV file on one worker
+type genOptions struct {
+	format             string
+	gzip               bool
+	chunkRows          int
+	workers, blockRows int
+}
+
+// writeFiles generates rows 1..n of s with seed into out.csv in a temp dir
+// and returns the files written and how many rows reuse a key
+func writeFiles(t *testing.T, s *Schema, seed int64, n int, o genOptions) ([]string, int) {
+	t.Helper()
+	enc, err := newEncoderFunc(o.format, s, s.Duplicates.ExistingRows+n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := newOutputWriter(filepath.Join(t.TempDir(), "out.csv"), o.gzip, o.chunkRows, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reused, err := generate(func() *rowGenerator { return newRowGenerator(s, seed) }, w, n, o.blockRows, o.workers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files, reused
+}
+
+// readOutput returns the content of a file written by outputWriter
+func readOutput(t *testing.T, name string) string {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		r = gz
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return string(b)
+}
+
+func TestOutputWriter(t *testing.T) {
+	s := testSchema(t, "columns: [{name: ID, type: seq}]")
+	tests := []struct {
+		name      string
+		gz        bool
+		chunkRows int
+		wantFiles []string
+		wantData  []string
+	}{
+		{"single", false, 0, []string{"out.csv"}, []string{"ID\n1\n2\n3\n4\n5\n"}},
+		{"gzip", true, 0, []string{"out.csv.gz"}, []string{"ID\n1\n2\n3\n4\n5\n"}},
+		{"chunks", false, 2, []string{"out_0001.csv", "out_0002.csv", "out_0003.csv"},
+			[]string{"ID\n1\n2\n", "ID\n3\n4\n", "ID\n5\n"}},
+		{"gzip chunks", true, 3, []string{"out_0001.csv.gz", "out_0002.csv.gz"},
+			[]string{"ID\n1\n2\n3\n", "ID\n4\n5\n"}},
+	}
+	for _, tt := range tests {
+		files, _ := writeFiles(t, s, 1, 5, genOptions{gzip: tt.gz, chunkRows: tt.chunkRows})
+		var names []string
+		for _, f := range files {
+			names = append(names, filepath.Base(f))
+		}
+		if !slices.Equal(names, tt.wantFiles) {
+			t.Errorf("%s: files = %v, want %v", tt.name, names, tt.wantFiles)
+			continue
+		}
+		// every chunk starts with its own header
+		for i, f := range files {
+			if got := readOutput(t, f); got != tt.wantData[i] {
+				t.Errorf("%s: %s = %q, want %q", tt.name, names[i], got, tt.wantData[i])
+			}
+		}
+	}
+}
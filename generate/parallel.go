@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// rowBlock is a contiguous range of generated rows
+type rowBlock struct {
+	first  int // 1-based number of the first row
+	rows   [][]string
+	reused int // rows reusing an existing key
+}
+
+// generateBlock fills rows [first, first+n)
+func generateBlock(gen *rowGenerator, first, n int) rowBlock {
+	b := rowBlock{first: first, rows: make([][]string, n)}
+	for i := range n {
+		row := make([]string, len(gen.schema.Columns))
+		if gen.Row(first+i, row) {
+			b.reused++
+		}
+		b.rows[i] = row
+	}
+	return b
+}
+
+// generate produces rows blocks of blockRows rows on workers goroutines and
+// writes them in order. At most 2*workers blocks are held in memory.
+// It returns how many rows reuse an existing key.
+// newGen is called once per worker since generators are not safe for concurrent use.
+func generate(newGen func() *rowGenerator, w rowSink, rows, blockRows, workers int) (int, error) {
+	if blockRows <= 0 {
+		blockRows = 10000
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	blocks := (rows + blockRows - 1) / blockRows
+
+	// order carries one result channel per block, in block order; the writer
+	// drains them sequentially while workers fill them in any order
+	type job struct {
+		block int
+		out   chan rowBlock
+	}
+	jobs := make(chan job)
+	order := make(chan chan rowBlock, 2*workers)
+	done := make(chan struct{})
+	defer close(done)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			gen := newGen()
+			for j := range jobs {
+				first := j.block*blockRows + 1
+				n := min(blockRows, rows-first+1)
+				j.out <- generateBlock(gen, first, n)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		defer close(order)
+		for b := range blocks {
+			out := make(chan rowBlock, 1)
+			select {
+			case order <- out:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- job{block: b, out: out}:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	lastReport := start
+	written, reused := 0, 0
+	var err error
+	for out := range order {
+		b := <-out
+		for i, row := range b.rows {
+			if err = w.Write(row); err != nil {
+				err = fmt.Errorf("write row %d: %w", b.first+i, err)
+				break
+			}
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			break
+		}
+		written += len(b.rows)
+		reused += b.reused
+		if time.Since(lastReport) >= 5*time.Second {
+			lastReport = time.Now()
+			log.Printf("Progress: %d/%d rows (%.0f rows/s)", written, rows, rowsPerSecond(written, time.Since(start)))
+		}
+	}
+	if err != nil {
+		// Unblock workers waiting to hand over a block nobody will read
+		go func() {
+			for out := range order {
+				<-out
+			}
+		}()
+	}
+	wg.Wait()
+	return reused, err
+}
+
+func rowsPerSecond(rows int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(rows) / d.Seconds()
+}
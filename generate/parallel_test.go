@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestGenerate_SameSeedSameOutput(t *testing.T) {
+	s := testSchema(t, `
+duplicates: {key: [ID], ratio: 0.2, change_ratio: 0.5}
+columns:
+  - {name: ID, type: seq}
+  - {name: NAME, type: string, cardinality: 100, distribution: zipf}
+  - {name: QTY, type: int, min: 1, max: 50, distribution: normal, null_ratio: 0.1}
+  - {name: PRICE, type: float, min: 1, max: 100, scale: 2}
+  - {name: TOTAL, type: derived, from: PRICE, multiply: 2, scale: 2}
+`)
+	run := func(seed int64, o genOptions) (string, int) {
+		files, reused := writeFiles(t, s, seed, 1000, o)
+		return readOutput(t, files[0]), reused
+	}
+	want, wantReused := run(42, genOptions{workers: 1, blockRows: 1000})
+	for _, o := range []genOptions{{workers: 1, blockRows: 7}, {workers: 4, blockRows: 1}, {workers: 4, blockRows: 64}, {workers: 8, blockRows: 333}} {
+		got, reused := run(42, o)
+		if got != want {
+			t.Errorf("%d workers, %d rows per block: output differs from a single block", o.workers, o.blockRows)
+		}
+		if reused != wantReused {
+			t.Errorf("%d workers, %d rows per block: reused = %d, want %d", o.workers, o.blockRows, reused, wantReused)
+		}
+	}
+	if other, _ := run(43, genOptions{}); other == want {
+		t.Error("seeds 42 and 43 produced the same output")
+	}
+}
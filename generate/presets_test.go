@@ -0,0 +1,97 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"sql-learn2/csv_reader"
+)
+
+func TestPresets(t *testing.T) {
+	names := PresetNames()
+	for _, want := range []string{"customers", "customers_orders", "duplicates", "orders", DefaultPreset} {
+		if !slices.Contains(names, want) {
+			t.Errorf("PresetNames() = %v, missing %s", names, want)
+		}
+	}
+	for _, name := range names {
+		if _, err := LoadSchema("", name); err != nil {
+			t.Errorf("preset %s: %v", name, err)
+		}
+	}
+	if _, err := LoadSchema("", "nope"); err == nil || !strings.Contains(err.Error(), `unknown preset "nope"`) {
+		t.Errorf("err = %v, want unknown preset", err)
+	}
+}
+
+// writePreset generates rows of the preset as CSV and returns the file
+func writePreset(t *testing.T, preset string, rows int) string {
+	t.Helper()
+	s, err := LoadSchema("", preset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := writeFiles(t, s, 1, rows, genOptions{workers: 2})
+	return files[0]
+}
+
+// The duplicates preset must stay readable by csv_reader/example: a header
+// row, then ID, Name and Email in that order, with some IDs repeated
+func TestPreset_Duplicates(t *testing.T) {
+	r := csv_reader.NewCSVReader(writePreset(t, "duplicates", 2000))
+	r.HasHeader = true
+	defer r.Close()
+	for i, want := range []string{"ID", "Name", "Email"} {
+		if got, err := r.Header(i); err != nil || got != want {
+			t.Errorf("Header(%d) = %q, %v; want %q", i, got, err, want)
+		}
+	}
+	if err := r.ValidateHeaderCount(3); err != nil {
+		t.Error(err)
+	}
+	lines, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2000 {
+		t.Fatalf("%d body rows, want 2000", len(lines))
+	}
+	ids := make(map[string]bool)
+	for i, l := range lines {
+		if l.CountFields() != 3 {
+			t.Fatalf("row %d has %d fields, want 3", i+1, l.CountFields())
+		}
+		if id := l.Value(0); !strings.HasSuffix(l.Value(2), id+"@example.com") {
+			t.Errorf("row %d: Email %s does not match ID %s", i+1, l.Value(2), id)
+		}
+		ids[l.Value(0)] = true
+	}
+	if len(ids) == len(lines) {
+		t.Error("no ID repeats")
+	}
+}
+
+// The default preset must stay readable by bulk_load_v3/example, which maps
+// its columns by header name
+func TestPreset_ProductData(t *testing.T) {
+	r := csv_reader.NewCSVReader(writePreset(t, DefaultPreset, 10))
+	r.HasHeader = true
+	defer r.Close()
+	if err := r.ValidateHeaderCount(20); err != nil {
+		t.Fatal(err)
+	}
+	var header []string
+	for i := range 20 {
+		h, err := r.Header(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header = append(header, h)
+	}
+	for _, want := range []string{"ID", "CODE", "NAME", "DESCRIPTION", "CATEGORY", "COST", "PRICE", "REORDER_LEVEL", "TARGET_LEVEL", "DISCONTINUED"} {
+		if !slices.Contains(header, want) {
+			t.Errorf("header %v has no %s", header, want)
+		}
+	}
+}
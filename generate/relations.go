@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ParentSpec links a child table to the table it is nested under
+type ParentSpec struct {
+	Column       string `yaml:"column"`     // foreign key column in the child
+	References   string `yaml:"references"` // referenced column in the parent, usually its key
+	Min          int    `yaml:"min"`        // children per parent
+	Max          int    `yaml:"max"`
+	Distribution string `yaml:"distribution"` // uniform (default), normal or zipf over [Min, Max]
+}
+
+func (s *Schema) validateChildren() error {
+	for _, c := range s.Children {
+		if c.Name == "" {
+			return fmt.Errorf("child table: name is required")
+		}
+		p := c.Parent
+		if p == nil {
+			return fmt.Errorf("table %s: parent is required for a child table", c.Name)
+		}
+		if !c.hasColumn(p.Column) {
+			return fmt.Errorf("table %s: parent.column %q is not a column", c.Name, p.Column)
+		}
+		if !s.hasColumn(p.References) {
+			return fmt.Errorf("table %s: parent.references %q is not a column of the parent", c.Name, p.References)
+		}
+		if p.Min < 0 || p.Max < p.Min || p.Max == 0 {
+			return fmt.Errorf("table %s: need 0 <= parent.min <= parent.max and parent.max > 0", c.Name)
+		}
+		switch p.Distribution {
+		case "", "uniform", "normal", "zipf":
+		default:
+			return fmt.Errorf("table %s: unknown parent.distribution %q", c.Name, p.Distribution)
+		}
+		if err := c.validate(); err != nil {
+			return fmt.Errorf("table %s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// Tables returns the schema and all nested children, parents before children
+func (s *Schema) Tables() []*Schema {
+	out := []*Schema{s}
+	for _, c := range s.Children {
+		out = append(out, c.Tables()...)
+	}
+	return out
+}
+
+// childPlan decides how many children each parent row gets and maps a child
+// row number back to its parent's key
+type childPlan struct {
+	keys []int   // parent key of every distinct parent, in parent row order
+	ends []int64 // ends[i] is the last child row number of parent i (cumulative)
+}
+
+func newChildPlan(child *Schema, parentKeys []int, seed int64) *childPlan {
+	p := child.Parent
+	spec := ColumnSpec{Distribution: p.Distribution}
+	rng := rand.New(&rowSource{})
+	plan := &childPlan{keys: parentKeys, ends: make([]int64, len(parentKeys))}
+	var total int64
+	for i, k := range parentKeys {
+		rng.Seed(streamSeed(seed, saltChildren, k))
+		total += int64(p.Min + spec.pick(rng, p.Max-p.Min+1))
+		plan.ends[i] = total
+	}
+	return plan
+}
+
+// Rows is the number of child rows
+func (p *childPlan) Rows() int {
+	if len(p.ends) == 0 {
+		return 0
+	}
+	return int(p.ends[len(p.ends)-1])
+}
+
+// parentKey returns the parent key of child row n (1-based)
+func (p *childPlan) parentKey(n int) int {
+	i := sort.Search(len(p.ends), func(i int) bool { return p.ends[i] >= int64(n) })
+	return p.keys[i]
+}
+
+// parentLink fills a child's foreign key with the referenced parent value
+type parentLink struct {
+	plan   *childPlan
+	parent *rowGenerator // generator of the parent table, used to rebuild referenced values
+	fk     int           // foreign key column index in the child
+	ref    int           // referenced column index in the parent
+	row    []string      // scratch parent row
+}
+
+func (l *parentLink) apply(n int, out []string) {
+	key := l.plan.parentKey(n)
+	l.parent.keyRow(key, l.row)
+	out[l.fk] = l.row[l.ref]
+}
+
+// distinctKeys lists the keys of rows 1..rows that are not reused, i.e.
+// each parent exactly once
+func distinctKeys(s *Schema, seed int64, rows int) []int {
+	keys := make([]int, 0, rows)
+	for n := 1; n <= rows; n++ {
+		if k, reused := s.Duplicates.keyOf(seed, n); !reused {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// tableSeed gives every table of a dataset its own seed; the root keeps the run seed
+func tableSeed(seed int64, index int) int64 {
+	if index == 0 {
+		return seed
+	}
+	return streamSeed(seed, saltTable, index)
+}
+
+// childOutput names a child's file after the table, next to the root output
+// (customers.csv -> orders.csv), unless the child sets output itself
+func childOutput(root string, child *Schema) string {
+	if child.Output != "" {
+		return child.Output
+	}
+	ext := filepath.Ext(strings.TrimSuffix(root, ".gz"))
+	return filepath.Join(filepath.Dir(root), child.Name+ext)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/csv"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readCSV returns the body rows of a generated CSV file
+func readCSV(t *testing.T, name string) [][]string {
+	t.Helper()
+	records, err := csv.NewReader(strings.NewReader(readOutput(t, name))).ReadAll()
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return records[1:]
+}
+
+func TestRun_ChildTables(t *testing.T) {
+	s := testSchema(t, `
+name: customers
+duplicates: {key: [ID], ratio: 0.3}
+columns:
+  - {name: ID, type: seq}
+  - {name: CODE, type: seq, format: "C-%05d"}
+children:
+  - name: orders
+    parent: {column: CUSTOMER, references: CODE, min: 2, max: 4}
+    columns:
+      - {name: ORDER_ID, type: seq}
+      - {name: CUSTOMER, type: string, cardinality: 1}
+    children:
+      - name: items
+        parent: {column: ORDER_ID, references: ORDER_ID, min: 0, max: 3, distribution: zipf}
+        columns:
+          - {name: ITEM_ID, type: seq}
+          - {name: ORDER_ID, type: int}
+`)
+	dir := t.TempDir()
+	cfg := &runConfig{output: filepath.Join(dir, "customers.csv"), format: "csv", workers: 3, blockRows: 17, seed: 5}
+	if err := cfg.run(s, 500, nil); err != nil {
+		t.Fatal(err)
+	}
+	customers := readCSV(t, filepath.Join(dir, "customers.csv"))
+	orders := readCSV(t, filepath.Join(dir, "orders.csv"))
+	items := readCSV(t, filepath.Join(dir, "items.csv"))
+
+	// children counts every parent once, however often its key repeats in the file
+	children := func(parents [][]string, ref int, rows [][]string, fk int) map[string]int {
+		t.Helper()
+		n := make(map[string]int)
+		for _, p := range parents {
+			n[p[ref]] = 0
+		}
+		for i, r := range rows {
+			if _, ok := n[r[fk]]; !ok {
+				t.Fatalf("row %d references %s, which is not a parent", i+1, r[fk])
+			}
+			n[r[fk]]++
+		}
+		return n
+	}
+	perCustomer := children(customers, 1, orders, 1)
+	if len(perCustomer) == len(customers) {
+		t.Fatal("no customer key repeats")
+	}
+	hist := make(map[int]int)
+	for code, n := range perCustomer {
+		if n < 2 || n > 4 {
+			t.Errorf("customer %s has %d orders, want 2-4", code, n)
+		}
+		hist[n]++
+	}
+	// uniform over 2-4
+	for n := 2; n <= 4; n++ {
+		if share := float64(hist[n]) / float64(len(perCustomer)); share < 0.25 || share > 0.42 {
+			t.Errorf("%.2f of the customers have %d orders, want about a third", share, n)
+		}
+	}
+
+	perOrder := children(orders, 0, items, 1)
+	hist = make(map[int]int)
+	for id, n := range perOrder {
+		if n > 3 {
+			t.Errorf("order %s has %d items, want 0-3", id, n)
+		}
+		hist[n]++
+	}
+	// zipf favours the low end of 0-3
+	if hist[0] <= hist[1] || hist[1] <= hist[3] {
+		t.Errorf("orders by item count = %v, want falling counts", hist)
+	}
+}
+
+func TestChildPlan(t *testing.T) {
+	child := &Schema{Parent: &ParentSpec{Min: 2, Max: 2}}
+	p := newChildPlan(child, []int{3, 7, 8}, 1)
+	if p.Rows() != 6 {
+		t.Errorf("Rows() = %d, want 6", p.Rows())
+	}
+	// two children each, in parent order
+	for i, want := range []int{3, 3, 7, 7, 8, 8} {
+		if got := p.parentKey(i + 1); got != want {
+			t.Errorf("parentKey(%d) = %d, want %d", i+1, got, want)
+		}
+	}
+}
+
+func TestDistinctKeys(t *testing.T) {
+	s := &Schema{Duplicates: DuplicateSpec{Ratio: 0.5, ExistingRows: 100, Key: []string{"ID"}}}
+	keys := distinctKeys(s, 4, 1000)
+	seen := make(map[int]bool)
+	for _, k := range keys {
+		if seen[k] || k <= 100 {
+			t.Fatalf("key %d listed twice or already loaded", k)
+		}
+		seen[k] = true
+	}
+	if len(keys) < 400 || len(keys) > 600 {
+		t.Errorf("%d distinct keys in 1000 rows, want about 500", len(keys))
+	}
+}
+
+func TestSchema_ValidateChildren(t *testing.T) {
+	tests := []struct {
+		name, child, wantErr string
+	}{
+		{"no name", "{parent: {column: P, references: ID, max: 1}, columns: [{name: P, type: int}]}", "child table: name is required"},
+		{"no parent", "{name: c, columns: [{name: P, type: int}]}", "table c: parent is required"},
+		{"fk column", "{name: c, parent: {column: X, references: ID, max: 1}, columns: [{name: P, type: int}]}", `parent.column "X" is not a column`},
+		{"referenced column", "{name: c, parent: {column: P, references: X, max: 1}, columns: [{name: P, type: int}]}", `parent.references "X" is not a column of the parent`},
+		{"counts", "{name: c, parent: {column: P, references: ID, min: 3, max: 2}, columns: [{name: P, type: int}]}", "need 0 <= parent.min <= parent.max"},
+		{"distribution", "{name: c, parent: {column: P, references: ID, max: 1, distribution: pareto}, columns: [{name: P, type: int}]}", `unknown parent.distribution "pareto"`},
+	}
+	for _, tt := range tests {
+		_, err := loadSchema(t, "columns: [{name: ID, type: seq}]\nchildren: ["+tt.child+"]")
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestChildOutput(t *testing.T) {
+	tests := []struct {
+		root  string
+		child Schema
+		want  string
+	}{
+		{"/out/customers.csv", Schema{Name: "orders"}, "/out/orders.csv"},
+		{"/out/customers.csv.gz", Schema{Name: "orders"}, "/out/orders.csv"},
+		{"/out/customers.jsonl", Schema{Name: "orders", Output: "o.jsonl"}, "o.jsonl"},
+	}
+	for _, tt := range tests {
+		if got := childOutput(tt.root, &tt.child); got != tt.want {
+			t.Errorf("childOutput(%s, %s) = %s, want %s", tt.root, tt.child.Name, got, tt.want)
+		}
+	}
+}
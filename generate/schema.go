@@ -0,0 +1,540 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// presets are the built-in schemas, selected by file name without .yaml
+//
+//go:embed schemas/*.yaml
+var presets embed.FS
+
+// DefaultPreset replicates the original product_data.csv layout
+const DefaultPreset = "product_data"
+
+// PresetNames lists the built-in schemas
+func PresetNames() []string {
+	entries, _ := presets.ReadDir("schemas")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".yaml"))
+	}
+	return names
+}
+
+// Schema describes the generated dataset. JSON works too (it is a subset of YAML).
+//
+// Children are tables generated after this one with a valid foreign key into
+// it; they can nest further (customers -> orders -> order items).
+type Schema struct {
+	Name       string        `yaml:"name"` // table name; required for children
+	Rows       int           `yaml:"rows"` // default row count; -rows overrides it (children: derived from parent)
+	Columns    []ColumnSpec  `yaml:"columns"`
+	Duplicates DuplicateSpec `yaml:"duplicates"`
+	Output     string        `yaml:"output"`   // child output file; defaults to <name> next to -output
+	DBTable    string        `yaml:"db_table"` // child target table with -db-table; defaults to name
+	Parent     *ParentSpec   `yaml:"parent"`
+	Children   []*Schema     `yaml:"children"`
+}
+
+// ColumnSpec describes one column.
+//
+// Types:
+//   - seq:     the 1-based key number (the row number unless keys are reused), optionally through Format (e.g. "PROD-%08d")
+//   - int:     integer in [Min, Max]
+//   - float:   decimal in [Min, Max] with Scale digits
+//   - string:  Format applied to a value index (e.g. "Customer %d"); needs Cardinality or Max
+//   - decimal: exact fixed-point number in [Min, Max] with Scale digits
+//   - enum:    one of Values, optionally weighted by Weights
+//   - bool:    "1" with probability TrueRatio, else "0"
+//   - date:    date in [Start, End] written with Layout (default 2006-01-02)
+//   - timestamp: like date with second precision (default layout 2006-01-02 15:04:05)
+//   - text:    lorem-style text of MinLength..MaxLength characters (CLOB sized if needed)
+//   - derived: value of the From column times Multiply
+type ColumnSpec struct {
+	Name         string    `yaml:"name"`
+	Type         string    `yaml:"type"`
+	Distribution string    `yaml:"distribution"` // uniform (default), normal or zipf
+	NullRatio    float64   `yaml:"null_ratio"`   // fraction of empty values
+	Cardinality  int       `yaml:"cardinality"`  // number of distinct values; 0 means unbounded
+	Min          float64   `yaml:"min"`
+	Max          float64   `yaml:"max"`
+	Scale        int       `yaml:"scale"`
+	Format       string    `yaml:"format"`
+	Values       []string  `yaml:"values"`
+	Weights      []float64 `yaml:"weights"` // relative weight of each enum value
+	Start        string    `yaml:"start"`   // date/timestamp range, in Layout or RFC 3339
+	End          string    `yaml:"end"`
+	Layout       string    `yaml:"layout"` // Go time layout for date/timestamp output
+	MinLength    int       `yaml:"min_length"`
+	MaxLength    int       `yaml:"max_length"`
+	TrueRatio    float64   `yaml:"true_ratio"`
+	From         string    `yaml:"from"`
+	Multiply     float64   `yaml:"multiply"`
+	DBColumn     string    `yaml:"db_column"` // target column for -db-table; defaults to Name, "-" skips it
+	Width        int       `yaml:"width"`     // column width for -format fixed; defaults to the widest possible value
+}
+
+// TargetColumn returns the database column the value is inserted into, or "" if it is not loaded
+func (c ColumnSpec) TargetColumn() string {
+	switch c.DBColumn {
+	case "":
+		return c.Name
+	case "-":
+		return ""
+	}
+	return c.DBColumn
+}
+
+// LoadSchema reads a schema file, or the named preset when path is empty
+func LoadSchema(path, preset string) (*Schema, error) {
+	var data []byte
+	var err error
+	if path != "" {
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("read schema: %w", err)
+		}
+	} else if data, err = presets.ReadFile("schemas/" + preset + ".yaml"); err != nil {
+		return nil, fmt.Errorf("unknown preset %q (available: %s)", preset, strings.Join(PresetNames(), ", "))
+	}
+	var s Schema
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return &s, nil
+}
+
+func (s *Schema) validate() error {
+	if len(s.Columns) == 0 {
+		return fmt.Errorf("no columns defined")
+	}
+	if err := s.Duplicates.validate(s); err != nil {
+		return err
+	}
+	if err := s.validateChildren(); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for i, c := range s.Columns {
+		if c.Name == "" {
+			return fmt.Errorf("column %d: name is required", i+1)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("column %s: duplicate name", c.Name)
+		}
+		seen[c.Name] = true
+		if c.NullRatio < 0 || c.NullRatio > 1 {
+			return fmt.Errorf("column %s: null_ratio must be between 0 and 1", c.Name)
+		}
+		switch c.Distribution {
+		case "", "uniform", "normal", "zipf":
+		default:
+			return fmt.Errorf("column %s: unknown distribution %q", c.Name, c.Distribution)
+		}
+		switch c.Type {
+		case "seq", "bool":
+		case "int", "float", "decimal":
+			if c.Max < c.Min {
+				return fmt.Errorf("column %s: max < min", c.Name)
+			}
+			if c.Type == "decimal" && (c.Scale < 0 || c.Scale > 9) {
+				return fmt.Errorf("column %s: decimal scale must be between 0 and 9", c.Name)
+			}
+		case "string":
+			if c.Cardinality <= 0 && c.Max <= 0 {
+				return fmt.Errorf("column %s: string needs cardinality or max", c.Name)
+			}
+		case "enum":
+			if len(c.Values) == 0 {
+				return fmt.Errorf("column %s: enum needs values", c.Name)
+			}
+			if len(c.Weights) > 0 && len(c.Weights) != len(c.Values) {
+				return fmt.Errorf("column %s: %d weights for %d values", c.Name, len(c.Weights), len(c.Values))
+			}
+			total := 0.0
+			for _, w := range c.Weights {
+				if w < 0 {
+					return fmt.Errorf("column %s: negative weight", c.Name)
+				}
+				total += w
+			}
+			if len(c.Weights) > 0 && total == 0 {
+				return fmt.Errorf("column %s: weights sum to zero", c.Name)
+			}
+		case "date", "timestamp":
+			lo, hi, err := c.timeRange()
+			if err != nil {
+				return fmt.Errorf("column %s: %w", c.Name, err)
+			}
+			if hi.Before(lo) {
+				return fmt.Errorf("column %s: end before start", c.Name)
+			}
+		case "text":
+			if c.MaxLength <= 0 || c.MinLength < 0 || c.MinLength > c.MaxLength {
+				return fmt.Errorf("column %s: text needs 0 <= min_length <= max_length and max_length > 0", c.Name)
+			}
+		case "derived":
+			if !seen[c.From] && !s.hasColumn(c.From) {
+				return fmt.Errorf("column %s: unknown from column %q", c.Name, c.From)
+			}
+		default:
+			return fmt.Errorf("column %s: unknown type %q", c.Name, c.Type)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) hasColumn(name string) bool {
+	for _, c := range s.Columns {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) columnIndex(name string) int {
+	for i, c := range s.Columns {
+		if c.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Header returns the column names in order
+func (s *Schema) Header() []string {
+	h := make([]string, len(s.Columns))
+	for i, c := range s.Columns {
+		h[i] = c.Name
+	}
+	return h
+}
+
+// rowGenerator turns a schema into rows of strings. Each row is generated
+// from its own random stream, so rows can be produced in any order.
+type rowGenerator struct {
+	schema  *Schema
+	seed    int64
+	rng     *rand.Rand
+	gens    []columnGen
+	derived []int // indexes of derived columns, filled after the others
+	isKey   []bool
+	index   map[string]int
+	raw     []float64   // numeric value of each column in the current row, for derived columns
+	link    *parentLink // set for child tables
+}
+
+type columnGen func(rng *rand.Rand, key int) (string, float64)
+
+func newRowGenerator(s *Schema, seed int64) *rowGenerator {
+	g := &rowGenerator{
+		schema: s,
+		seed:   seed,
+		rng:    rand.New(&rowSource{}),
+		gens:   make([]columnGen, len(s.Columns)),
+		isKey:  make([]bool, len(s.Columns)),
+		index:  make(map[string]int, len(s.Columns)),
+		raw:    make([]float64, len(s.Columns)),
+	}
+	for i, c := range s.Columns {
+		g.index[c.Name] = i
+		if c.Type == "derived" {
+			g.derived = append(g.derived, i)
+			continue
+		}
+		g.gens[i] = c.generator()
+	}
+	for _, k := range s.Duplicates.Key {
+		g.isKey[g.index[k]] = true
+	}
+	return g
+}
+
+// Row generates the 1-based row n into out (len(out) == number of columns)
+// and reports whether it reuses an existing key
+func (g *rowGenerator) Row(n int, out []string) bool {
+	dup := &g.schema.Duplicates
+	key, reused := dup.keyOf(g.seed, n)
+
+	// Values are a function of the key, so a reused key repeats its row...
+	g.values(key, out)
+	// ...except for the columns picked to change
+	if reused && dup.ChangeRatio > 0 {
+		g.rng.Seed(streamSeed(g.seed, saltChange, n))
+		for i, c := range g.schema.Columns {
+			if c.Type != "derived" && !g.isKey[i] && g.rng.Float64() < dup.ChangeRatio {
+				g.column(i, key, out)
+			}
+		}
+	}
+	if g.link != nil {
+		g.link.apply(n, out)
+	}
+	g.fillDerived(out)
+	return reused
+}
+
+// keyRow generates the row of key as first written, without changes
+func (g *rowGenerator) keyRow(key int, out []string) {
+	g.values(key, out)
+	g.fillDerived(out)
+}
+
+func (g *rowGenerator) values(key int, out []string) {
+	g.rng.Seed(streamSeed(g.seed, saltValues, key))
+	for i, c := range g.schema.Columns {
+		if c.Type != "derived" {
+			g.column(i, key, out)
+		}
+	}
+}
+
+func (g *rowGenerator) fillDerived(out []string) {
+	for _, i := range g.derived {
+		c := g.schema.Columns[i]
+		src := g.raw[g.index[c.From]]
+		if math.IsNaN(src) || (c.NullRatio > 0 && g.rng.Float64() < c.NullRatio) {
+			out[i] = ""
+			continue
+		}
+		out[i] = strconv.FormatFloat(src*c.Multiply, 'f', c.Scale, 64)
+	}
+}
+
+func (g *rowGenerator) column(i, key int, out []string) {
+	if nr := g.schema.Columns[i].NullRatio; nr > 0 && g.rng.Float64() < nr {
+		out[i], g.raw[i] = "", math.NaN()
+		return
+	}
+	out[i], g.raw[i] = g.gens[i](g.rng, key)
+}
+
+// pick draws an index in [0, n) following the column's distribution
+func (c ColumnSpec) pick(rng *rand.Rand, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	switch c.Distribution {
+	case "normal":
+		v := int(math.Round(rng.NormFloat64()*float64(n)/6 + float64(n-1)/2))
+		return min(max(v, 0), n-1)
+	case "zipf":
+		return int(rand.NewZipf(rng, 1.1, 1, uint64(n-1)).Uint64())
+	default:
+		return rng.Intn(n)
+	}
+}
+
+// pickFloat draws a value in [lo, hi] following the column's distribution
+func (c ColumnSpec) pickFloat(rng *rand.Rand, lo, hi float64) float64 {
+	switch c.Distribution {
+	case "normal":
+		v := rng.NormFloat64()*(hi-lo)/6 + (lo+hi)/2
+		return math.Min(math.Max(v, lo), hi)
+	case "zipf":
+		const buckets = 1000
+		return lo + (hi-lo)*float64(c.pick(rng, buckets))/(buckets-1)
+	default:
+		return lo + rng.Float64()*(hi-lo)
+	}
+}
+
+// timeRange parses Start and End (defaults: 2000-01-01 to 2030-12-31)
+func (c ColumnSpec) timeRange() (time.Time, time.Time, error) {
+	parse := func(v, def string) (time.Time, error) {
+		if v == "" {
+			v = def
+		}
+		for _, layout := range []string{c.Layout, time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+			if layout == "" {
+				continue
+			}
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse time %q", v)
+	}
+	lo, err := parse(c.Start, "2000-01-01")
+	if err != nil {
+		return lo, lo, err
+	}
+	hi, err := parse(c.End, "2030-12-31")
+	return lo, hi, err
+}
+
+// timeLayout returns Layout or the default for the date/timestamp type
+func (c ColumnSpec) timeLayout() string {
+	switch {
+	case c.Layout != "":
+		return c.Layout
+	case c.Type == "timestamp":
+		return "2006-01-02 15:04:05"
+	}
+	return "2006-01-02"
+}
+
+// formatUnits writes units*10^-scale without going through float formatting
+func formatUnits(units int64, scale int) string {
+	if scale == 0 {
+		return strconv.FormatInt(units, 10)
+	}
+	sign := ""
+	if units < 0 {
+		sign, units = "-", -units
+	}
+	s := strconv.FormatInt(units, 10)
+	if len(s) <= scale {
+		s = strings.Repeat("0", scale-len(s)+1) + s
+	}
+	return sign + s[:len(s)-scale] + "." + s[len(s)-scale:]
+}
+
+var loremWords = strings.Fields("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod " +
+	"tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud " +
+	"exercitation ullamco laboris nisi aliquip ex ea commodo consequat")
+
+// loremText returns exactly n characters of space-separated words
+func loremText(rng *rand.Rand, n int) string {
+	var b strings.Builder
+	b.Grow(n + 16)
+	for b.Len() < n {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(loremWords[rng.Intn(len(loremWords))])
+	}
+	return b.String()[:n]
+}
+
+func (c ColumnSpec) generator() columnGen {
+	switch c.Type {
+	case "seq":
+		return func(_ *rand.Rand, key int) (string, float64) {
+			if c.Format != "" {
+				return fmt.Sprintf(c.Format, key), float64(key)
+			}
+			return strconv.Itoa(key), float64(key)
+		}
+	case "int":
+		lo, hi := int64(c.Min), int64(c.Max)
+		span := hi - lo + 1
+		return func(rng *rand.Rand, _ int) (string, float64) {
+			var v int64
+			if c.Cardinality > 0 {
+				// Spread the distinct values evenly over the range
+				k := int64(c.pick(rng, c.Cardinality))
+				v = lo + k*span/int64(c.Cardinality)
+			} else if c.Distribution == "" || c.Distribution == "uniform" {
+				v = lo + rng.Int63n(span)
+			} else {
+				v = int64(math.Round(c.pickFloat(rng, float64(lo), float64(hi))))
+			}
+			return strconv.FormatInt(v, 10), float64(v)
+		}
+	case "float":
+		return func(rng *rand.Rand, _ int) (string, float64) {
+			var v float64
+			if c.Cardinality > 0 {
+				k := c.pick(rng, c.Cardinality)
+				v = c.Min + (c.Max-c.Min)*float64(k)/float64(max(c.Cardinality-1, 1))
+			} else {
+				v = c.pickFloat(rng, c.Min, c.Max)
+			}
+			// Round first so derived columns see the written value
+			p := math.Pow(10, float64(c.Scale))
+			v = math.Round(v*p) / p
+			return strconv.FormatFloat(v, 'f', c.Scale, 64), v
+		}
+	case "string":
+		n := c.Cardinality
+		if n <= 0 {
+			n = int(c.Max)
+		}
+		format := c.Format
+		if format == "" {
+			format = strings.ToLower(c.Name) + "_%d"
+		}
+		return func(rng *rand.Rand, _ int) (string, float64) {
+			k := c.pick(rng, n) + 1
+			return fmt.Sprintf(format, k), float64(k)
+		}
+	case "decimal":
+		// Work in integer units of 10^-Scale so the written value is exact
+		p := math.Pow(10, float64(c.Scale))
+		lo, hi := int64(math.Round(c.Min*p)), int64(math.Round(c.Max*p))
+		return func(rng *rand.Rand, _ int) (string, float64) {
+			var units int64
+			if c.Distribution == "" || c.Distribution == "uniform" {
+				units = lo + rng.Int63n(hi-lo+1)
+			} else {
+				units = int64(math.Round(c.pickFloat(rng, float64(lo), float64(hi))))
+			}
+			return formatUnits(units, c.Scale), float64(units) / p
+		}
+	case "enum":
+		if len(c.Weights) > 0 {
+			cum := make([]float64, len(c.Weights))
+			total := 0.0
+			for i, w := range c.Weights {
+				total += w
+				cum[i] = total
+			}
+			return func(rng *rand.Rand, _ int) (string, float64) {
+				r := rng.Float64() * total
+				k := sort.SearchFloat64s(cum, r)
+				k = min(k, len(c.Values)-1)
+				return c.Values[k], float64(k)
+			}
+		}
+		return func(rng *rand.Rand, _ int) (string, float64) {
+			k := c.pick(rng, len(c.Values))
+			return c.Values[k], float64(k)
+		}
+	case "date", "timestamp":
+		lo, hi, _ := c.timeRange()
+		layout := c.timeLayout()
+		unit := 24 * time.Hour
+		if c.Type == "timestamp" {
+			unit = time.Second
+		}
+		steps := int64(hi.Sub(lo)/unit) + 1
+		return func(rng *rand.Rand, _ int) (string, float64) {
+			var k int64
+			if c.Distribution == "" || c.Distribution == "uniform" {
+				k = rng.Int63n(steps)
+			} else {
+				k = int64(math.Round(c.pickFloat(rng, 0, float64(steps-1))))
+			}
+			t := lo.Add(time.Duration(k) * unit)
+			return t.Format(layout), float64(t.Unix())
+		}
+	case "text":
+		return func(rng *rand.Rand, _ int) (string, float64) {
+			n := c.MinLength + rng.Intn(c.MaxLength-c.MinLength+1)
+			return loremText(rng, n), float64(n)
+		}
+	case "bool":
+		return func(rng *rand.Rand, _ int) (string, float64) {
+			if rng.Float64() < c.TrueRatio {
+				return "1", 1
+			}
+			return "0", 0
+		}
+	}
+	panic("unreachable: validated schema has unknown type " + c.Type)
+}
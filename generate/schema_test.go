@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadSchema parses body as a schema file; JSON works as it is YAML too
+func loadSchema(t *testing.T, body string) (*Schema, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "schema.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadSchema(path, "")
+}
+
+func testSchema(t *testing.T, body string) *Schema {
+	t.Helper()
+	s, err := loadSchema(t, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// genRows generates rows 1..n of s with seed
+func genRows(s *Schema, seed int64, n int) [][]string {
+	g := newRowGenerator(s, seed)
+	out := make([][]string, n)
+	for i := range out {
+		out[i] = make([]string, len(s.Columns))
+		g.Row(i+1, out[i])
+	}
+	return out
+}
+
+func TestLoadSchema(t *testing.T) {
+	tests := []struct {
+		name, body string
+		wantRows   int
+		wantCols   []string
+	}{
+		{"yaml", `
+rows: 10
+columns:
+  - {name: ID, type: seq}
+  - {name: QTY, type: int, min: 1, max: 5, distribution: normal, null_ratio: 0.1}
+  - {name: KIND, type: enum, values: [a, b]}
+`, 10, []string{"ID", "QTY", "KIND"}},
+		{"json", `{"columns": [{"name": "ID", "type": "seq"}, {"name": "PRICE", "type": "float", "max": 9, "scale": 2}]}`,
+			0, []string{"ID", "PRICE"}},
+	}
+	for _, tt := range tests {
+		s := testSchema(t, tt.body)
+		if s.Rows != tt.wantRows || !slices.Equal(s.Header(), tt.wantCols) {
+			t.Errorf("%s: rows %d, header %v; want %d, %v", tt.name, s.Rows, s.Header(), tt.wantRows, tt.wantCols)
+		}
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns string
+		wantErr string
+	}{
+		{"no columns", "[]", "no columns defined"},
+		{"no name", "[{type: seq}]", "column 1: name is required"},
+		{"duplicate name", "[{name: A, type: seq}, {name: A, type: seq}]", "column A: duplicate name"},
+		{"null ratio", "[{name: A, type: seq, null_ratio: 2}]", "null_ratio must be between 0 and 1"},
+		{"distribution", "[{name: A, type: int, distribution: pareto}]", `unknown distribution "pareto"`},
+		{"range", "[{name: A, type: int, min: 5, max: 1}]", "column A: max < min"},
+		{"string bound", "[{name: A, type: string}]", "string needs cardinality or max"},
+		{"enum values", "[{name: A, type: enum}]", "enum needs values"},
+		{"derived", "[{name: A, type: derived, from: B}]", `unknown from column "B"`},
+		{"type", "[{name: A, type: uuid}]", `unknown type "uuid"`},
+		{"decimal scale", "[{name: A, type: decimal, max: 1, scale: 12}]", "decimal scale must be between 0 and 9"},
+		{"enum weights", "[{name: A, type: enum, values: [x, y], weights: [1]}]", "1 weights for 2 values"},
+		{"negative weight", "[{name: A, type: enum, values: [x], weights: [-1]}]", "negative weight"},
+		{"zero weights", "[{name: A, type: enum, values: [x], weights: [0]}]", "weights sum to zero"},
+		{"dates", "[{name: A, type: date, start: 2024-02-01, end: 2024-01-01}]", "end before start"},
+		{"bad date", "[{name: A, type: timestamp, start: soon}]", `cannot parse time "soon"`},
+		{"text", "[{name: A, type: text, min_length: 5, max_length: 2}]", "text needs"},
+	}
+	for _, tt := range tests {
+		_, err := loadSchema(t, "columns: "+tt.columns)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRowGenerator_Columns(t *testing.T) {
+	s := testSchema(t, `
+columns:
+  - {name: ID, type: seq, format: "P-%04d"}
+  - {name: QTY, type: int, min: 1, max: 3}
+  - {name: COST, type: float, min: 10, max: 20, scale: 2}
+  - {name: TOTAL, type: derived, from: QTY, multiply: 2}
+  - {name: NAME, type: string, cardinality: 5}
+  - {name: KIND, type: enum, values: [a, b, c]}
+  - {name: ACTIVE, type: bool, true_ratio: 1}
+  - {name: NOTE, type: int, max: 9, null_ratio: 1}
+`)
+	for i, row := range genRows(s, 1, 200) {
+		if want := fmt.Sprintf("P-%04d", i+1); row[0] != want {
+			t.Fatalf("row %d: ID = %s, want %s", i+1, row[0], want)
+		}
+		qty, err := strconv.Atoi(row[1])
+		if err != nil || qty < 1 || qty > 3 {
+			t.Errorf("row %d: QTY = %s, want 1-3", i+1, row[1])
+		}
+		if cost, err := strconv.ParseFloat(row[2], 64); err != nil || cost < 10 || cost > 20 || len(row[2]) != 5 {
+			t.Errorf("row %d: COST = %s, want 10.00-20.00", i+1, row[2])
+		}
+		if want := strconv.Itoa(2 * qty); row[3] != want {
+			t.Errorf("row %d: TOTAL = %s, want %s", i+1, row[3], want)
+		}
+		if k, err := strconv.Atoi(strings.TrimPrefix(row[4], "name_")); err != nil || k < 1 || k > 5 {
+			t.Errorf("row %d: NAME = %s, want name_1-name_5", i+1, row[4])
+		}
+		if !slices.Contains([]string{"a", "b", "c"}, row[5]) || row[6] != "1" || row[7] != "" {
+			t.Errorf("row %d: KIND, ACTIVE, NOTE = %q, %q, %q", i+1, row[5], row[6], row[7])
+		}
+	}
+}
+
+func TestRowGenerator_Seed(t *testing.T) {
+	s := testSchema(t, `
+columns:
+  - {name: ID, type: seq}
+  - {name: QTY, type: int, min: 1, max: 1000000, distribution: zipf}
+  - {name: COST, type: float, min: 0, max: 100, scale: 4, distribution: normal, null_ratio: 0.2}
+`)
+	a, b, other := genRows(s, 42, 500), genRows(s, 42, 500), genRows(s, 43, 500)
+	if !slices.EqualFunc(a, b, slices.Equal) {
+		t.Error("seed 42 generated different rows twice")
+	}
+	if slices.EqualFunc(a, other, slices.Equal) {
+		t.Error("seeds 42 and 43 generated the same rows")
+	}
+}
+
+func TestRowGenerator_TypedColumns(t *testing.T) {
+	s := testSchema(t, `
+columns:
+  - {name: DAY, type: date, start: 2024-01-30, end: 2024-02-02}
+  - {name: AT, type: timestamp, start: "2024-01-01 00:00:00", end: "2024-01-01 00:00:59", layout: "15:04:05"}
+  - {name: AMOUNT, type: decimal, min: -1, max: 1, scale: 3}
+  - {name: STATUS, type: enum, values: [NEW, OLD, GONE], weights: [3, 1, 0]}
+  - {name: NOTE, type: text, min_length: 3, max_length: 6}
+`)
+	const n = 4000
+	days, status := map[string]int{}, map[string]int{}
+	for i, row := range genRows(s, 9, n) {
+		days[row[0]]++
+		if !strings.HasPrefix(row[1], "00:00:") {
+			t.Errorf("row %d: AT = %s, want 00:00:00-00:00:59", i+1, row[1])
+		}
+		v, err := strconv.ParseFloat(row[2], 64)
+		if dot := strings.IndexByte(row[2], '.'); err != nil || v < -1 || v > 1 || dot < 0 || len(row[2])-dot != 4 {
+			t.Errorf("row %d: AMOUNT = %s, want -1.000-1.000", i+1, row[2])
+		}
+		status[row[3]]++
+		if l := len(row[4]); l < 3 || l > 6 {
+			t.Errorf("row %d: NOTE = %q, want 3-6 characters", i+1, row[4])
+		}
+	}
+	var gotDays []string
+	for d := range days {
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			t.Errorf("DAY = %s: %v", d, err)
+		}
+		gotDays = append(gotDays, d)
+	}
+	slices.Sort(gotDays)
+	if want := []string{"2024-01-30", "2024-01-31", "2024-02-01", "2024-02-02"}; !slices.Equal(gotDays, want) {
+		t.Errorf("days = %v, want %v", gotDays, want)
+	}
+	// weights 3:1:0
+	if status["GONE"] != 0 || status["NEW"]+status["OLD"] != n {
+		t.Errorf("status counts = %v", status)
+	}
+	if r := float64(status["NEW"]) / n; r < 0.72 || r > 0.78 {
+		t.Errorf("NEW in %.3f of the rows, want about 0.75", r)
+	}
+}
@@ -0,0 +1,57 @@
+// Package connpool opens the connections a loader's workers hold for a
+// whole load, each with the load's statement prepared on it
+package connpool
+
+import (
+	"context"
+	"database/sql"
+
+	"sql-learn2/logging"
+)
+
+// Worker is one pinned connection and the statement prepared on it
+type Worker struct {
+	Conn *sql.Conn
+	Stmt *sql.Stmt
+}
+
+// Close closes the statement, then the connection
+func (w Worker) Close() {
+	w.Stmt.Close()
+	w.Conn.Close()
+}
+
+// Open opens workers connections and prepares query on each before any row
+// is sent, so a bad statement fails once and up front. There is at least
+// one worker, and no more than db allows open connections: what names the
+// workers in the warning logged then, e.g. "insert". On error the
+// connections opened so far are closed again.
+func Open(ctx context.Context, db *sql.DB, query, what string, workers int) ([]Worker, error) {
+	workers = max(workers, 1)
+	if limit := db.Stats().MaxOpenConnections; limit > 0 && workers > limit {
+		logging.FromContext(ctx).Warn("Fewer connections allowed than "+what+" workers", "workers", workers, "max_open_conns", limit)
+		workers = limit
+	}
+	out := make([]Worker, 0, workers)
+	for range workers {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			closeAll(out)
+			return nil, err
+		}
+		stmt, err := conn.PrepareContext(ctx, query)
+		if err != nil {
+			conn.Close()
+			closeAll(out)
+			return nil, err
+		}
+		out = append(out, Worker{Conn: conn, Stmt: stmt})
+	}
+	return out, nil
+}
+
+func closeAll(ws []Worker) {
+	for _, w := range ws {
+		w.Close()
+	}
+}
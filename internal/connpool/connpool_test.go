@@ -0,0 +1,53 @@
+package connpool
+
+import (
+	"context"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+)
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxOpen  int
+		workers  int
+		wantOpen int
+	}{
+		{"as asked", 0, 3, 3},
+		{"at least one", 0, 0, 1},
+		{"capped by the pool", 2, 4, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.DB.SetMaxOpenConns(tt.maxOpen)
+			ws, err := Open(context.Background(), f.DB, "INSERT INTO T VALUES (:1)", "insert", tt.workers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ws) != tt.wantOpen {
+				t.Errorf("opened %d workers, want %d", len(ws), tt.wantOpen)
+			}
+			if n := f.DB.Stats().InUse; n != tt.wantOpen {
+				t.Errorf("%d connections in use, want %d", n, tt.wantOpen)
+			}
+			closeAll(ws)
+			if n := f.DB.Stats().InUse; n != 0 {
+				t.Errorf("%d connections still in use after Close", n)
+			}
+		})
+	}
+}
+
+func TestOpen_Canceled(t *testing.T) {
+	f := sqlfake.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Open(ctx, f.DB, "INSERT INTO T VALUES (:1)", "insert", 2); err == nil {
+		t.Fatal("expected the canceled context to fail the open")
+	}
+	if n := f.DB.Stats().InUse; n != 0 {
+		t.Errorf("%d connections left in use", n)
+	}
+}
@@ -0,0 +1,131 @@
+package resume
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+)
+
+// Range is the source positions First to Last, both included; a position
+// is whatever a loader counts its rows by, a CSV line or a row index
+type Range struct {
+	First int `json:"first"`
+	Last  int `json:"last"`
+}
+
+// Ranges are the rows committed past a checkpoint's position: batches that
+// committed ahead of one that failed
+type Ranges []Range
+
+// Contains reports whether pos is in one of the ranges, so a resumed load
+// must skip it
+func (rs Ranges) Contains(pos int) bool {
+	for _, r := range rs {
+		if pos >= r.First && pos <= r.Last {
+			return true
+		}
+	}
+	return false
+}
+
+// Read decodes the checkpoint in path into a new T, or returns nil when
+// there is none
+func Read[T any](path string) (*T, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+	var c T
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("read checkpoint %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Write replaces the checkpoint in path with c; the rename keeps a crash
+// from leaving half a file behind
+func Write(path string, c any) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the checkpoint of a completed load
+func Remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Tracker follows batches that workers commit out of submission order. Its
+// position moves over the contiguous run of committed batches; the ones
+// committed past a gap are kept for Done. It is not safe for concurrent
+// use, callers hold their own lock.
+type Tracker[T any] struct {
+	pos      int
+	span     func(T) Range // source positions of a batch
+	resumed  Ranges
+	finished map[int]T // committed batches past a gap in seq
+	next     int
+}
+
+// NewTracker starts at pos with the ranges resumed from an interrupted
+// load; span returns the source positions of a batch. Batches are numbered
+// from 0 in submission order.
+func NewTracker[T any](pos int, resumed Ranges, span func(T) Range) *Tracker[T] {
+	return &Tracker[T]{pos: pos, span: span, resumed: resumed, finished: map[int]T{}}
+}
+
+// Commit records batch seq as committed and returns the batches the
+// position moved over, in order; none while an earlier batch is missing
+func (t *Tracker[T]) Commit(seq int, b T) []T {
+	t.finished[seq] = b
+	var run []T
+	for {
+		f, ok := t.finished[t.next]
+		if !ok {
+			return run
+		}
+		delete(t.finished, t.next)
+		t.next++
+		t.pos = t.span(f).Last
+		run = append(run, f)
+	}
+}
+
+// Pos is the source position every row up to is committed
+func (t *Tracker[T]) Pos() int {
+	return t.pos
+}
+
+// Done is the ranges committed past Pos: the resumed ones still ahead of
+// it and the batches committed past a gap, in order
+func (t *Tracker[T]) Done() Ranges {
+	var done Ranges
+	for _, r := range t.resumed {
+		if r.First > t.pos {
+			done = append(done, r)
+		}
+	}
+	for _, f := range t.finished {
+		done = append(done, t.span(f))
+	}
+	slices.SortFunc(done, func(a, b Range) int { return a.First - b.First })
+	return done
+}
@@ -0,0 +1,67 @@
+package resume
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTracker(t *testing.T) {
+	span := func(r Range) Range { return r }
+	resumed := Ranges{{First: 9, Last: 10}, {First: 15, Last: 16}}
+	tr := NewTracker(4, resumed, span)
+
+	steps := []struct {
+		name    string
+		seq     int
+		batch   Range
+		wantRun []Range
+		wantPos int
+		want    Ranges
+	}{
+		{"ahead of a gap", 1, Range{7, 8}, nil, 4, Ranges{{7, 8}, {9, 10}, {15, 16}}},
+		{"fills the gap", 0, Range{5, 6}, []Range{{5, 6}, {7, 8}}, 8, Ranges{{9, 10}, {15, 16}}},
+		{"past a resumed range", 2, Range{11, 12}, []Range{{11, 12}}, 12, Ranges{{15, 16}}},
+		{"past all", 3, Range{17, 18}, []Range{{17, 18}}, 18, nil},
+	}
+	for _, s := range steps {
+		run := tr.Commit(s.seq, s.batch)
+		if !reflect.DeepEqual(run, s.wantRun) || tr.Pos() != s.wantPos || !reflect.DeepEqual(tr.Done(), s.want) {
+			t.Errorf("%s: run %v, pos %d, done %v; want %v, %d, %v", s.name, run, tr.Pos(), tr.Done(), s.wantRun, s.wantPos, s.want)
+		}
+	}
+}
+
+func TestRanges_Contains(t *testing.T) {
+	rs := Ranges{{First: 3, Last: 4}, {First: 8, Last: 8}}
+	for pos, want := range map[int]bool{2: false, 3: true, 4: true, 5: false, 8: true, 9: false} {
+		if got := rs.Contains(pos); got != want {
+			t.Errorf("Contains(%d) = %v, want %v", pos, got, want)
+		}
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	type state struct {
+		Table string `json:"table"`
+		Line  int    `json:"line"`
+		Done  Ranges `json:"done,omitempty"`
+	}
+	path := filepath.Join(t.TempDir(), "load.ckpt")
+	if c, err := Read[state](path); c != nil || err != nil {
+		t.Fatalf("Read of no file = %v, %v; want nil, nil", c, err)
+	}
+	want := state{Table: "ORDERS", Line: 4, Done: Ranges{{First: 7, Last: 8}}}
+	if err := Write(path, want); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := Read[state](path); err != nil || !reflect.DeepEqual(*c, want) {
+		t.Fatalf("Read = %+v, %v; want %+v", c, err, want)
+	}
+	if err := Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(path); err != nil {
+		t.Errorf("Remove of a removed checkpoint: %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+package sqlfake
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"regexp"
+	"sync"
+	"testing"
+)
+
+// Call is one statement the code under test executed
+type Call struct {
+	Query string
+	Args  []any
+}
+
+// Recorder is a database/sql driver that records every statement and answers
+// from rules, for asserting on the exact SQL a workflow generates.
+// Statements no rule matches succeed with no rows and 0 rows affected.
+type Recorder struct {
+	DB *sql.DB
+
+	mu      sync.Mutex
+	calls   []Call
+	rules   []rule
+	pingErr error
+	pings   int
+}
+
+type rule struct {
+	re       *regexp.Regexp
+	cols     []string
+	rows     [][]any
+	err      error
+	affected int64
+	limited  bool // the rule stops matching once times runs out
+	times    int
+}
+
+// New returns a Recorder whose DB is closed when t finishes
+func New(t testing.TB) *Recorder {
+	r := &Recorder{}
+	r.DB = sql.OpenDB(connector{r})
+	t.Cleanup(func() { r.DB.Close() })
+	return r
+}
+
+// Connector returns a connector for the same fake, for code that wraps connectors
+func (r *Recorder) Connector() driver.Connector { return connector{r} }
+
+// OnQuery answers queries matching pattern (a regexp) with cols and rows.
+// Later rules win over earlier ones.
+func (r *Recorder) OnQuery(pattern string, cols []string, rows ...[]any) {
+	r.add(rule{re: regexp.MustCompile(pattern), cols: cols, rows: rows})
+}
+
+// OnExec makes statements matching pattern report affected rows
+func (r *Recorder) OnExec(pattern string, affected int64) {
+	r.add(rule{re: regexp.MustCompile(pattern), affected: affected})
+}
+
+// Fail makes statements matching pattern return err
+func (r *Recorder) Fail(pattern string, err error) {
+	r.add(rule{re: regexp.MustCompile(pattern), err: err})
+}
+
+// FailTimes makes the next n statements matching pattern return err;
+// after that they are answered by the earlier rules again
+func (r *Recorder) FailTimes(pattern string, n int, err error) {
+	r.add(rule{re: regexp.MustCompile(pattern), err: err, limited: true, times: n})
+}
+
+// FailPing makes pings return err; nil makes them succeed again.
+// Pings are counted (Pings) but not recorded as calls.
+func (r *Recorder) FailPing(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pingErr = err
+}
+
+// Pings returns how many times a connection was pinged
+func (r *Recorder) Pings() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pings
+}
+
+func (r *Recorder) add(ru rule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = append(r.rules, ru)
+}
+
+// Calls returns the executed statements in order; COMMIT and ROLLBACK are included
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Queries returns just the SQL text of Calls
+func (r *Recorder) Queries() []string {
+	calls := r.Calls()
+	out := make([]string, len(calls))
+	for i, c := range calls {
+		out[i] = c.Query
+	}
+	return out
+}
+
+func (r *Recorder) record(query string, args []driver.NamedValue) *rule {
+	vals := make([]any, len(args))
+	for i, a := range args {
+		vals[i] = a.Value
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Query: query, Args: vals})
+	for i := len(r.rules) - 1; i >= 0; i-- {
+		ru := &r.rules[i]
+		if !ru.re.MatchString(query) || ru.limited && ru.times == 0 {
+			continue
+		}
+		if ru.limited {
+			ru.times--
+		}
+		return ru
+	}
+	return nil
+}
+
+func (r *Recorder) exec(query string, args []driver.NamedValue) (driver.Result, error) {
+	ru := r.record(query, args)
+	if ru == nil {
+		return driver.RowsAffected(0), nil
+	}
+	if ru.err != nil {
+		return nil, ru.err
+	}
+	return driver.RowsAffected(ru.affected), nil
+}
+
+func (r *Recorder) query(query string, args []driver.NamedValue) (driver.Rows, error) {
+	ru := r.record(query, args)
+	if ru == nil {
+		return &rows{}, nil
+	}
+	if ru.err != nil {
+		return nil, ru.err
+	}
+	return &rows{cols: ru.cols, data: ru.rows}, nil
+}
+
+type connector struct{ r *Recorder }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return &conn{c.r}, nil }
+func (c connector) Driver() driver.Driver                        { return drv{} }
+
+type drv struct{}
+
+func (drv) Open(string) (driver.Conn, error) { return nil, driver.ErrSkip }
+
+type conn struct{ r *Recorder }
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) { return &stmt{c.r, query}, nil }
+func (c *conn) Close() error                              { return nil }
+func (c *conn) Begin() (driver.Tx, error)                 { return tx{c.r}, nil }
+
+func (c *conn) Ping(context.Context) error {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	c.r.pings++
+	return c.r.pingErr
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.r.exec(query, args)
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.r.query(query, args)
+}
+
+// CheckNamedValue accepts any argument as is, e.g. go-ora array binds
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value); err == nil {
+		nv.Value = v
+	}
+	return nil
+}
+
+type tx struct{ r *Recorder }
+
+func (t tx) Commit() error {
+	_, err := t.r.exec("COMMIT", nil)
+	return err
+}
+
+func (t tx) Rollback() error {
+	_, err := t.r.exec("ROLLBACK", nil)
+	return err
+}
+
+type stmt struct {
+	r     *Recorder
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.r.exec(s.query, named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.r.query(s.query, named(args))
+}
+
+func (s *stmt) ExecContext(_ context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.r.exec(s.query, args)
+}
+
+func (s *stmt) QueryContext(_ context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.r.query(s.query, args)
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	out := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		out[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return out
+}
+
+type rows struct {
+	cols []string
+	data [][]any
+	i    int
+}
+
+func (r *rows) Columns() []string { return r.cols }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.i >= len(r.data) {
+		return io.EOF
+	}
+	for j := range dest {
+		dest[j] = r.data[r.i][j]
+	}
+	r.i++
+	return nil
+}
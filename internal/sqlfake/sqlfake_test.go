@@ -0,0 +1,97 @@
+package sqlfake
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	f := New(t)
+	f.OnQuery("FROM T", []string{"N"}, []any{int64(1)}, []any{int64(2)})
+	f.Fail("^DELETE", errors.New("boom"))
+
+	var sum int64
+	rows, err := f.DB.QueryContext(ctx, "SELECT N FROM T WHERE X = :1", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var n int64
+		if err := rows.Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		sum += n
+	}
+	rows.Close()
+	if sum != 3 {
+		t.Errorf("sum = %d, want 3", sum)
+	}
+
+	tx, err := f.DB.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM T"); err == nil {
+		t.Error("expected DELETE to fail")
+	}
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO T VALUES (:1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.ExecContext(ctx, []int64{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Call{
+		{Query: "SELECT N FROM T WHERE X = :1", Args: []any{"x"}},
+		{Query: "DELETE FROM T", Args: []any{}},
+		{Query: "INSERT INTO T VALUES (:1)", Args: []any{[]int64{1, 2}}},
+		{Query: "COMMIT", Args: []any{}},
+	}
+	if got := f.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls:\n got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestRecorder_OnExec(t *testing.T) {
+	f := New(t)
+	f.OnExec("^MERGE", 3)
+	tests := []struct {
+		query string
+		want  int64
+	}{
+		{"MERGE INTO T", 3},
+		{"DELETE FROM T", 0},
+	}
+	for _, tt := range tests {
+		res, err := f.DB.ExecContext(context.Background(), tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, _ := res.RowsAffected(); n != tt.want {
+			t.Errorf("%s: rows affected = %d, want %d", tt.query, n, tt.want)
+		}
+	}
+}
+
+func TestRecorder_FailTimes(t *testing.T) {
+	f := New(t)
+	f.OnExec("^MERGE", 3)
+	f.FailTimes("^MERGE", 2, errors.New("locked"))
+	var errs int
+	for range 4 {
+		if _, err := f.DB.ExecContext(context.Background(), "MERGE INTO T"); err != nil {
+			errs++
+		}
+	}
+	if errs != 2 {
+		t.Errorf("%d of 4 statements failed, want 2", errs)
+	}
+}
@@ -0,0 +1,223 @@
+package testharness
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sql-learn2/oraconn"
+	"sql-learn2/oraerr"
+)
+
+// Image is the Oracle XE image started when no ORA_TEST_DSN is given
+var Image = oraconn.Getenv("ORA_TEST_IMAGE", "gvenzl/oracle-xe:21-slim-faststart")
+
+const (
+	appUser = "LEARN1"
+	appPass = "Welcome"
+	readyLn = "DATABASE IS READY TO USE!"
+)
+
+var (
+	once      sync.Once
+	shared    *sql.DB
+	startErr  error
+	container string
+	seq       atomic.Int64
+)
+
+// Oracle returns the shared test database, skipping t when none is available.
+//
+// ORA_TEST_DSN points the tests at an existing database. Otherwise an Oracle
+// XE container is started with docker on first use and removed by Main;
+// set ORA_TEST_DOCKER=0 to skip instead.
+func Oracle(t testing.TB) *sql.DB {
+	t.Helper()
+	once.Do(func() { shared, startErr = start() })
+	if startErr != nil {
+		t.Skipf("no test database: %v", startErr)
+	}
+	return shared
+}
+
+// Main runs the tests and then removes the container started by Oracle.
+// Use it from TestMain: os.Exit(testharness.Main(m)).
+func Main(m *testing.M) int {
+	code := m.Run()
+	if shared != nil {
+		_ = shared.Close()
+	}
+	if container != "" && os.Getenv("ORA_TEST_KEEP") != "1" {
+		_ = exec.Command("docker", "rm", "-f", container).Run()
+	}
+	return code
+}
+
+func start() (*sql.DB, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oraconn.EnvDuration("ORA_TEST_STARTUP", 5*time.Minute))
+	defer cancel()
+
+	if dsn := os.Getenv("ORA_TEST_DSN"); dsn != "" {
+		return oraconn.Connect(ctx, oraconn.Config{DSN: dsn, PingAttempts: 5, PingBackoff: time.Second})
+	}
+	if !oraconn.EnvBool("ORA_TEST_DOCKER", true) {
+		return nil, fmt.Errorf("ORA_TEST_DSN not set and ORA_TEST_DOCKER=0")
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil, fmt.Errorf("ORA_TEST_DSN not set and docker not found")
+	}
+
+	id, err := docker(ctx, "run", "-d", "-P",
+		"-e", "ORACLE_RANDOM_PASSWORD=yes",
+		"-e", "APP_USER="+appUser,
+		"-e", "APP_USER_PASSWORD="+appPass,
+		Image)
+	if err != nil {
+		return nil, err
+	}
+	container = id
+	port, err := docker(ctx, "port", id, "1521/tcp")
+	if err != nil {
+		return nil, err
+	}
+	// "0.0.0.0:49153", possibly followed by an IPv6 line
+	port = strings.Fields(port)[0]
+	port = port[strings.LastIndex(port, ":")+1:]
+
+	if err := waitReady(ctx, id); err != nil {
+		return nil, err
+	}
+	cfg := oraconn.Config{User: appUser, Pass: appPass, Host: "localhost", Port: port, Service: "XEPDB1",
+		PingAttempts: 10, PingBackoff: time.Second}
+	return oraconn.Connect(ctx, cfg)
+}
+
+// waitReady polls the container log for the image's ready message
+func waitReady(ctx context.Context, id string) error {
+	for {
+		out, err := exec.CommandContext(ctx, "docker", "logs", id).CombinedOutput()
+		if err == nil && bytes.Contains(out, []byte(readyLn)) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("oracle container %.12s not ready: %w", id, ctx.Err())
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// TableName returns a name unique to this test run, e.g. CSVDB_1A2B_3.
+// The prefix is cut to 18 characters to stay within Oracle's 30.
+func TableName(prefix string) string {
+	prefix = strings.ToUpper(prefix)
+	if len(prefix) > 18 {
+		prefix = prefix[:18]
+	}
+	return fmt.Sprintf("%s_%04X_%d", prefix, time.Now().UnixNano()&0xFFFF, seq.Add(1))
+}
+
+// DropTable drops table, ignoring ORA-00942 (table does not exist)
+func DropTable(ctx context.Context, db *sql.DB, table string) error {
+	_, err := db.ExecContext(ctx, "DROP TABLE "+table+" PURGE")
+	if oraerr.Code(err) == 942 {
+		return nil
+	}
+	return err
+}
+
+// DropOnCleanup drops the tables when t finishes
+func DropOnCleanup(t testing.TB, db *sql.DB, tables ...string) {
+	t.Helper()
+	t.Cleanup(func() {
+		for _, tbl := range tables {
+			if err := DropTable(context.Background(), db, tbl); err != nil {
+				t.Logf("drop %s: %v", tbl, err)
+			}
+		}
+	})
+}
+
+// Exec runs each statement, failing t on the first error
+func Exec(t testing.TB, db *sql.DB, stmts ...string) {
+	t.Helper()
+	for _, s := range stmts {
+		if _, err := db.ExecContext(context.Background(), s); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+	}
+}
+
+// WriteCSV writes lines to name in a temp dir and returns the path
+func WriteCSV(t testing.TB, name string, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Count returns SELECT COUNT(*) FROM from, e.g. "T" or "T PARTITION (P1)"
+func Count(t testing.TB, db *sql.DB, from string) int64 {
+	t.Helper()
+	var n int64
+	if err := db.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM "+from).Scan(&n); err != nil {
+		t.Fatalf("count %s: %v", from, err)
+	}
+	return n
+}
+
+// Rows runs query and returns every cell as a string, NULL as ""
+func Rows(t testing.TB, db *sql.DB, query string, args ...any) [][]string {
+	t.Helper()
+	rows, err := db.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out [][]string
+	for rows.Next() {
+		cells := make([]sql.NullString, len(cols))
+		dest := make([]any, len(cols))
+		for i := range cells {
+			dest[i] = &cells[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		row := make([]string, len(cols))
+		for i, c := range cells {
+			row[i] = c.String
+		}
+		out = append(out, row)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return out
+}
@@ -0,0 +1,24 @@
+package testharness
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestTableName(t *testing.T) {
+	valid := regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,29}$`)
+	tests := []string{"csvdb", "A_VERY_LONG_PREFIX_FOR_A_TABLE_NAME", "px_master"}
+	seen := map[string]bool{}
+	for _, prefix := range tests {
+		for range 3 {
+			name := TableName(prefix)
+			if !valid.MatchString(name) {
+				t.Errorf("TableName(%q) = %q, not a valid identifier", prefix, name)
+			}
+			if seen[name] {
+				t.Errorf("TableName(%q) repeated %q", prefix, name)
+			}
+			seen[name] = true
+		}
+	}
+}
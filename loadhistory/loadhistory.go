@@ -0,0 +1,168 @@
+package loadhistory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+	"unicode/utf8"
+
+	"sql-learn2/logging"
+	"sql-learn2/oraerr"
+)
+
+// Workflow names stored in LOAD_HISTORY.WORKFLOW
+const (
+	WorkflowLoad              = "load"
+	WorkflowUpsert            = "upsert"
+	WorkflowSwap              = "swap"
+	WorkflowPartitionExchange = "partition-exchange"
+)
+
+// Statuses stored in LOAD_HISTORY.STATUS
+const (
+	StatusOK     = "OK"
+	StatusFailed = "FAILED"
+)
+
+// Record is one row of LOAD_HISTORY
+type Record struct {
+	Workflow string
+	Source   string // file the data came from
+	Target   string // table, synonym or partition written
+	Rows     sql.NullInt64
+	Started  time.Time
+	Finished time.Time
+	Err      error
+}
+
+// Writer inserts Records into the history table (see migrations 0007_load_history.sql)
+type Writer struct {
+	DB      *sql.DB
+	Table   string        // default LOAD_HISTORY
+	Timeout time.Duration // for the insert, default 10s; it runs even after the load's ctx is done
+}
+
+func (w *Writer) table() string {
+	if w.Table == "" {
+		return "LOAD_HISTORY"
+	}
+	return w.Table
+}
+
+// Write inserts r. ctx cancellation is ignored so failed and timed-out runs are still recorded.
+func (w *Writer) Write(ctx context.Context, r Record) error {
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	status, code, msg := StatusOK, sql.NullInt64{}, sql.NullString{}
+	if r.Err != nil {
+		status = StatusFailed
+		if c := oraerr.Code(r.Err); c != 0 {
+			code = sql.NullInt64{Int64: int64(c), Valid: true}
+		}
+		msg = sql.NullString{String: truncate(r.Err.Error(), 4000), Valid: true}
+	}
+	host, _ := os.Hostname()
+	osUser := ""
+	if u, err := user.Current(); err == nil {
+		osUser = u.Username
+	}
+	_, err := w.DB.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s
+  (WORKFLOW, SOURCE_FILE, TARGET, ROW_COUNT, STARTED_AT, FINISHED_AT, DURATION_MS, STATUS, ERROR_CODE, ERROR_MESSAGE, HOST_NAME, OS_USER)
+  VALUES (:1, :2, :3, :4, :5, :6, :7, :8, :9, :10, :11, :12)`, w.table()),
+		r.Workflow, truncate(r.Source, 1000), r.Target, r.Rows, r.Started, r.Finished,
+		r.Finished.Sub(r.Started).Milliseconds(), status, code, msg, truncate(host, 255), truncate(osUser, 128))
+	if err != nil {
+		return fmt.Errorf("insert into %s: %w", w.table(), err)
+	}
+	return nil
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+type writerKey struct{}
+type runKey struct{}
+
+// WithWriter makes workflows started with ctx record their runs through w
+func WithWriter(ctx context.Context, w *Writer) context.Context {
+	return context.WithValue(ctx, writerKey{}, w)
+}
+
+// Run is an in-progress history row. nil is valid and records nothing.
+type Run struct {
+	ctx    context.Context
+	w      *Writer
+	rec    Record
+	parent *Run
+}
+
+// Start begins a run when ctx carries a Writer. A run started inside another
+// one (e.g. the staging load of a partition exchange) is not recorded on its
+// own; its row count goes to the outer run unless that sets one itself.
+func Start(ctx context.Context, workflow, source, target string) (context.Context, *Run) {
+	w, _ := ctx.Value(writerKey{}).(*Writer)
+	if w == nil {
+		return ctx, nil
+	}
+	if parent, ok := ctx.Value(runKey{}).(*Run); ok {
+		return ctx, &Run{parent: parent}
+	}
+	r := &Run{ctx: ctx, w: w, rec: Record{Workflow: workflow, Source: source, Target: target, Started: time.Now()}}
+	return context.WithValue(ctx, runKey{}, r), r
+}
+
+// SetTarget replaces the target once it has been resolved
+func (r *Run) SetTarget(target string) {
+	if r == nil || r.parent != nil {
+		return
+	}
+	r.rec.Target = target
+}
+
+// SetRows records how many rows the run moved
+func (r *Run) SetRows(n int64) {
+	if r == nil {
+		return
+	}
+	if r.parent != nil {
+		if !r.parent.rec.Rows.Valid {
+			r.parent.rec.Rows = sql.NullInt64{Int64: n, Valid: true}
+		}
+		return
+	}
+	r.rec.Rows = sql.NullInt64{Int64: n, Valid: true}
+}
+
+// End writes the history row; a failure to write is logged, never returned,
+// so bookkeeping cannot fail a load
+func (r *Run) End(err error) {
+	if r == nil || r.parent != nil {
+		return
+	}
+	r.rec.Finished = time.Now()
+	r.rec.Err = err
+	if werr := r.w.Write(r.ctx, r.rec); werr != nil {
+		hint := ""
+		if oraerr.Code(werr) == 942 {
+			hint = " (create it with: go run ./migrations/cmd)"
+		}
+		logging.FromContext(r.ctx).Warn("Could not record load history"+hint,
+			logging.FieldTable, r.w.table(), logging.FieldError, werr)
+	}
+}
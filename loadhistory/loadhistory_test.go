@@ -0,0 +1,121 @@
+package loadhistory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sijms/go-ora/v2/network"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/logging"
+)
+
+var quiet = logging.WithLogger(context.Background(), logging.Discard())
+
+// historyArgs returns the binds of each LOAD_HISTORY insert
+func historyArgs(f *sqlfake.Recorder) [][]any {
+	var out [][]any
+	for _, c := range f.Calls() {
+		if strings.HasPrefix(c.Query, "INSERT INTO LOAD_HISTORY") {
+			out = append(out, c.Args)
+		}
+	}
+	return out
+}
+
+func TestStart_NoWriter(t *testing.T) {
+	ctx, run := Start(quiet, WorkflowLoad, "a.csv", "T")
+	if run != nil || ctx != quiet {
+		t.Fatalf("Start without writer = %v, %v", ctx, run)
+	}
+	run.SetTarget("X")
+	run.SetRows(1)
+	run.End(nil) // must not panic
+}
+
+func TestRun_End(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows       int64
+		err        error
+		wantStatus string
+		wantCode   any    // driver value: nil or int64
+		wantMsg    string // prefix; "" = NULL
+	}{
+		{"ok", 5, nil, StatusOK, nil, ""},
+		{"ora error", 2, fmt.Errorf("insert row 3: %w", network.NewOracleError(1)), StatusFailed,
+			int64(1), "insert row 3: ORA-00001"},
+		{"plain error", 0, errors.New("boom"), StatusFailed, nil, "boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			ctx := WithWriter(quiet, &Writer{DB: f.DB})
+			_, run := Start(ctx, WorkflowUpsert, "a.csv", "t")
+			run.SetTarget("T")
+			run.SetRows(tt.rows)
+			run.End(tt.err)
+
+			got := historyArgs(f)
+			if len(got) != 1 {
+				t.Fatalf("history inserts = %d, want 1", len(got))
+			}
+			a := got[0]
+			if a[0] != WorkflowUpsert || a[1] != "a.csv" || a[2] != "T" || a[3] != tt.rows {
+				t.Errorf("workflow/source/target/rows = %v", a[:4])
+			}
+			if a[7] != tt.wantStatus || a[8] != tt.wantCode {
+				t.Errorf("status/code = %v %v, want %v %v", a[7], a[8], tt.wantStatus, tt.wantCode)
+			}
+			if msg, _ := a[9].(string); (a[9] == nil) != (tt.wantMsg == "") || !strings.HasPrefix(msg, tt.wantMsg) {
+				t.Errorf("message = %v, want prefix %q", a[9], tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestRun_Nested(t *testing.T) {
+	f := sqlfake.New(t)
+	ctx := WithWriter(quiet, &Writer{DB: f.DB, Table: "LOAD_HISTORY"})
+	ctx, outer := Start(ctx, WorkflowPartitionExchange, "a.csv", "M PARTITION (P)")
+	_, inner := Start(ctx, WorkflowLoad, "a.csv", "STG")
+	inner.SetTarget("OTHER")
+	inner.SetRows(7)
+	inner.End(nil)
+	if n := len(historyArgs(f)); n != 0 {
+		t.Fatalf("inner run recorded %d row(s)", n)
+	}
+	outer.End(nil)
+	got := historyArgs(f)
+	if len(got) != 1 || got[0][2] != "M PARTITION (P)" || got[0][3] != int64(7) {
+		t.Errorf("history = %v", got)
+	}
+}
+
+func TestRun_WriteFailureIsNotFatal(t *testing.T) {
+	f := sqlfake.New(t)
+	f.Fail("^INSERT INTO LOAD_HISTORY", network.NewOracleError(942))
+	_, run := Start(WithWriter(quiet, &Writer{DB: f.DB}), WorkflowLoad, "a.csv", "T")
+	run.End(nil) // logs a warning only
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"abc", 5, "abc"},
+		{"abcdef", 3, "abc"},
+		{"aé", 2, "a"}, // é is two bytes
+		{"aéb", 3, "aé"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.in, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package lockflow
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Expectation declares what a step is expected to do.
+// Zero values are not checked.
+type Expectation struct {
+	RowsAffected *int64        // rows affected (DML) or returned (SELECT)
+	Error        string        // substring of the expected error, e.g. "ORA-00054"; empty means success is expected
+	MaxDuration  time.Duration // upper bound on client-observed step duration
+}
+
+// Rows is a helper for Expectation.RowsAffected
+func Rows(n int64) *int64 {
+	return &n
+}
+
+// StepResult is the observed outcome of one SQL step
+type StepResult struct {
+	Flow         string
+	Index        int // 1-based position in the flow
+	Label        string
+	Table        string
+	ID           string
+	Start        time.Time
+	Duration     time.Duration
+	RowsAffected int64
+	Err          error
+	Lock         LockOption
+	Timing       *Timing
+	Stats        *ServerStats // server-side deltas when server stats are enabled
+	Expect       *Expectation
+	Failures     []string // empty when all expectations hold
+}
+
+// Passed reports whether all declared expectations held
+func (r StepResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// ResultCollector gathers step results from concurrently running flows
+type ResultCollector struct {
+	mu      sync.Mutex
+	results []StepResult
+}
+
+// NewResultCollector creates an empty collector
+func NewResultCollector() *ResultCollector {
+	return &ResultCollector{}
+}
+
+// Record evaluates the step's expectations against its outcome and stores the result
+func (c *ResultCollector) Record(flow string, index int, step Step, start time.Time, duration time.Duration, affected int64, err error) StepResult {
+	res := StepResult{
+		Flow:         flow,
+		Index:        index,
+		Label:        step.Label,
+		Table:        step.Table,
+		ID:           step.ID,
+		Start:        start,
+		Duration:     duration,
+		RowsAffected: affected,
+		Err:          err,
+		Lock:         step.Lock,
+		Timing:       step.Timing,
+		Expect:       step.Expect,
+	}
+	if step.Expect != nil {
+		res.Failures = step.Expect.check(duration, affected, err)
+	}
+	if c != nil {
+		c.mu.Lock()
+		c.results = append(c.results, res)
+		c.mu.Unlock()
+	}
+	return res
+}
+
+// SetStats attaches server-side statistics to a recorded step
+func (c *ResultCollector) SetStats(flow string, index int, s ServerStats) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := len(c.results) - 1; i >= 0; i-- {
+		if c.results[i].Flow == flow && c.results[i].Index == index {
+			c.results[i].Stats = &s
+			return
+		}
+	}
+}
+
+// Results returns a copy of the collected results
+func (c *ResultCollector) Results() []StepResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]StepResult, len(c.results))
+	copy(out, c.results)
+	return out
+}
+
+// Failed reports whether any step with expectations failed
+func (c *ResultCollector) Failed() bool {
+	for _, r := range c.Results() {
+		if !r.Passed() {
+			return true
+		}
+	}
+	return false
+}
+
+// expectsError reports whether err is the error this step was declared to raise
+func (e *Expectation) expectsError(err error) bool {
+	return e != nil && e.Error != "" && err != nil && strings.Contains(err.Error(), e.Error)
+}
+
+func (e *Expectation) check(duration time.Duration, affected int64, err error) []string {
+	var failures []string
+	switch {
+	case e.Error == "" && err != nil:
+		failures = append(failures, fmt.Sprintf("unexpected error: %v", err))
+	case e.Error != "" && err == nil:
+		failures = append(failures, fmt.Sprintf("expected error %q, got success", e.Error))
+	case e.Error != "" && !strings.Contains(err.Error(), e.Error):
+		failures = append(failures, fmt.Sprintf("expected error %q, got %v", e.Error, err))
+	}
+	if e.RowsAffected != nil && err == nil && affected != *e.RowsAffected {
+		failures = append(failures, fmt.Sprintf("expected %d rows, got %d", *e.RowsAffected, affected))
+	}
+	if e.MaxDuration > 0 && duration > e.MaxDuration {
+		failures = append(failures, fmt.Sprintf("took %v, max %v", duration.Round(time.Millisecond), e.MaxDuration))
+	}
+	return failures
+}
+
+var oraCodeRe = regexp.MustCompile(`ORA-\d{5}`)
+
+// oraCode extracts the first ORA-xxxxx code from an error, or "" if none
+func oraCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	return oraCodeRe.FindString(err.Error())
+}
+
+// RenderAssertions prints a pass/fail line for every step that declared expectations
+func RenderAssertions(results []StepResult) {
+	var checked, failed int
+	for _, r := range results {
+		if r.Expect != nil {
+			checked++
+			if !r.Passed() {
+				failed++
+			}
+		}
+	}
+	if checked == 0 {
+		return
+	}
+
+	fmt.Println("\n=== Step Assertions ===")
+	for _, r := range results {
+		if r.Expect == nil {
+			continue
+		}
+		status := "PASS"
+		if !r.Passed() {
+			status = "FAIL"
+		}
+		outcome := fmt.Sprintf("%d rows", r.RowsAffected)
+		if r.Err != nil {
+			outcome = "error"
+			if code := oraCode(r.Err); code != "" {
+				outcome = code
+			}
+		}
+		fmt.Printf("  [%s] %-10s #%-2d %-40s %8v  %s\n", status, r.Flow, r.Index, r.Label, r.Duration.Round(time.Millisecond), outcome)
+		for _, f := range r.Failures {
+			fmt.Printf("         - %s\n", f)
+		}
+	}
+	fmt.Printf("Assertions: %d checked, %d passed, %d failed\n", checked, checked-failed, failed)
+}
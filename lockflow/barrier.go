@@ -0,0 +1,88 @@
+package lockflow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Barriers lets one flow wait until another flow reaches a named step,
+// instead of lining flows up with fixed sleeps
+type Barriers struct {
+	mu sync.Mutex
+	m  map[string]*barrier
+}
+
+type barrier struct {
+	owner     string // flow that signals it
+	done      chan struct{}
+	reached   time.Time
+	abandoned bool // owner finished (or failed) without reaching the step
+}
+
+// NewBarriers creates an empty barrier set
+func NewBarriers() *Barriers {
+	return &Barriers{m: make(map[string]*barrier)}
+}
+
+// declare registers a barrier signalled by owner. Names are global across flows.
+func (b *Barriers) declare(name, owner string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if cur, ok := b.m[name]; ok {
+		return fmt.Errorf("barrier %s is signalled by both %s and %s", name, cur.owner, owner)
+	}
+	b.m[name] = &barrier{owner: owner, done: make(chan struct{})}
+	return nil
+}
+
+// signal releases everyone waiting on name
+func (b *Barriers) signal(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if br, ok := b.m[name]; ok && br.reached.IsZero() && !br.abandoned {
+		br.reached = time.Now()
+		close(br.done)
+	}
+}
+
+// abandon releases waiters on every barrier owned by flow that was never reached
+func (b *Barriers) abandon(flow string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, br := range b.m {
+		if br.owner == flow && br.reached.IsZero() && !br.abandoned {
+			br.abandoned = true
+			close(br.done)
+		}
+	}
+}
+
+// wait blocks until name is signalled, abandoned, or ctx is done
+func (b *Barriers) wait(ctx context.Context, name string) error {
+	b.mu.Lock()
+	br, ok := b.m[name]
+	b.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("barrier %s is not signalled by any flow", name)
+	}
+	select {
+	case <-br.done:
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for barrier %s: %w", name, ctx.Err())
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if br.abandoned {
+		return fmt.Errorf("barrier %s abandoned: %s ended before reaching it", name, br.owner)
+	}
+	return nil
+}
+
+// reset clears all barriers so a runner can declare them afresh
+func (b *Barriers) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.m = make(map[string]*barrier)
+}
@@ -0,0 +1,146 @@
+package lockflow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ChaosConfig controls the chaos injector. Zero values disable the corresponding fault.
+type ChaosConfig struct {
+	Seed      int64         // 0 picks a time-based seed; the seed used is printed in the report
+	MaxDelay  time.Duration // upper bound of the random sleep injected before SQL steps
+	DelayProb float64       // probability (0..1) of sleeping before a SQL step
+	KillProb  float64       // probability (0..1) of killing the session after a successful SQL step
+	KillFlow  string        // restrict kills to this flow; empty means any transactional flow
+	MaxKills  int           // stop killing after this many sessions; 0 means 1
+}
+
+// chaosKill records one ALTER SYSTEM KILL SESSION and what the flow saw next
+type chaosKill struct {
+	Flow      string
+	AfterStep int
+	SID       int64
+	Serial    int64
+	At        time.Time
+	Err       error // error returned by the kill itself
+}
+
+// ChaosInjector injects random delays and kills flow sessions through a privileged connection
+type ChaosInjector struct {
+	cfg   ChaosConfig
+	admin *sql.DB // needs ALTER SYSTEM
+
+	mu     sync.Mutex
+	rng    *rand.Rand
+	seed   int64
+	delays int
+	kills  []chaosKill
+}
+
+// NewChaosInjector creates an injector; admin may be nil, in which case kills are disabled
+func NewChaosInjector(cfg ChaosConfig, admin *sql.DB) *ChaosInjector {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	if cfg.MaxKills <= 0 {
+		cfg.MaxKills = 1
+	}
+	return &ChaosInjector{
+		cfg:   cfg,
+		admin: admin,
+		rng:   rand.New(rand.NewSource(seed)),
+		seed:  seed,
+	}
+}
+
+// delay sleeps for a random duration before a SQL step, if the dice say so
+func (c *ChaosInjector) delay(ctx context.Context, flow string, logger *EventLogger) {
+	if c == nil || c.cfg.MaxDelay <= 0 {
+		return
+	}
+	c.mu.Lock()
+	hit := c.rng.Float64() < c.cfg.DelayProb
+	d := time.Duration(c.rng.Int63n(int64(c.cfg.MaxDelay)))
+	if hit {
+		c.delays++
+	}
+	c.mu.Unlock()
+	if !hit {
+		return
+	}
+	logger.Log(ctx, flow, fmt.Sprintf("CHAOS: sleeping %v", d.Round(time.Millisecond)))
+	time.Sleep(d)
+}
+
+// maybeKill kills the flow's session after step, if the dice say so. It reports whether a kill was issued.
+func (c *ChaosInjector) maybeKill(ctx context.Context, flow string, step int, sess SessionInfo, logger *EventLogger) bool {
+	if c == nil || c.admin == nil || c.cfg.KillProb <= 0 || sess.SID == 0 {
+		return false
+	}
+	if c.cfg.KillFlow != "" && c.cfg.KillFlow != flow {
+		return false
+	}
+	c.mu.Lock()
+	hit := len(c.kills) < c.cfg.MaxKills && c.rng.Float64() < c.cfg.KillProb
+	c.mu.Unlock()
+	if !hit {
+		return false
+	}
+
+	k := chaosKill{Flow: flow, AfterStep: step, SID: sess.SID, Serial: sess.Serial, At: time.Now()}
+	k.Err = c.kill(ctx, sess)
+	if k.Err != nil {
+		logger.Log(ctx, flow, fmt.Sprintf("CHAOS: failed to kill session %d: %v", sess.SID, k.Err))
+	} else {
+		logger.Log(ctx, flow, fmt.Sprintf("CHAOS: killed session %d,%d after step %d", sess.SID, sess.Serial, step))
+	}
+
+	c.mu.Lock()
+	c.kills = append(c.kills, k)
+	c.mu.Unlock()
+	return k.Err == nil
+}
+
+func (c *ChaosInjector) kill(ctx context.Context, sess SessionInfo) error {
+	// KILL SESSION does not accept binds
+	stmt := fmt.Sprintf("ALTER SYSTEM KILL SESSION '%d,%d' IMMEDIATE", sess.SID, sess.Serial)
+	_, err := c.admin.ExecContext(ctx, stmt)
+	return err
+}
+
+// RenderChaos prints the injected faults and how each killed flow ended
+func (c *ChaosInjector) RenderChaos(start time.Time, outcomes map[string]error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Println("\n=== Chaos ===")
+	fmt.Printf("Seed: %d (rerun with -chaos-seed %d)\n", c.seed, c.seed)
+	fmt.Printf("Injected delays: %d\n", c.delays)
+	if len(c.kills) == 0 {
+		fmt.Println("No sessions killed.")
+		return
+	}
+	for _, k := range c.kills {
+		if k.Err != nil {
+			fmt.Printf("  +%5.1fs  %-10s kill of sid %d failed: %v\n", k.At.Sub(start).Seconds(), k.Flow, k.SID, k.Err)
+			continue
+		}
+		outcome := "committed (kill was not observed)"
+		if err := outcomes[k.Flow]; err != nil {
+			outcome = "rolled back by the server"
+			if code := oraCode(err); code != "" {
+				outcome += ", client saw " + code
+			}
+		}
+		fmt.Printf("  +%5.1fs  %-10s session %d,%d killed after step %d -> %s\n",
+			k.At.Sub(start).Seconds(), k.Flow, k.SID, k.Serial, k.AfterStep, outcome)
+	}
+}
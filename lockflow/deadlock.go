@@ -0,0 +1,64 @@
+package lockflow
+
+import (
+	"fmt"
+	"time"
+)
+
+// oraDeadlock is raised in the session whose statement Oracle rolls back to break a deadlock
+const oraDeadlock = "ORA-00060"
+
+// isDeadlock reports whether err is ORA-00060 (deadlock detected while waiting for resource)
+func isDeadlock(err error) bool {
+	return oraCode(err) == oraDeadlock
+}
+
+// DefineDeadlockFlows registers two flows that lock A and B in opposite order.
+// Oracle detects the cycle after a few seconds and raises ORA-00060 in one of them (the victim);
+// only the victim's current statement is rolled back, the flow then rolls back and the other commits.
+func DefineDeadlockFlows(runner *Runner) {
+	// Each flow takes its first lock, then waits until the other holds its first lock too,
+	// so the opposite-order updates always collide
+	ab := runner.AddTxFlow("DL_AB")
+	ab.AddUpdate("A", "Updating A.id=1", "UPDATE A SET data = 'DL_AB' WHERE id = 1").Signal("AB_HOLDS_A")
+	ab.AwaitSignal("BA_HOLDS_B")
+	ab.AddUpdate("B", "Updating B.id=1", "UPDATE B SET data = 'DL_AB' WHERE id = 1")
+	ab.AddWait(1 * time.Second)
+
+	ba := runner.AddTxFlow("DL_BA")
+	ba.AddUpdate("B", "Updating B.id=1", "UPDATE B SET data = 'DL_BA' WHERE id = 1").Signal("BA_HOLDS_B")
+	ba.AwaitSignal("AB_HOLDS_A")
+	ba.AddUpdate("A", "Updating A.id=1", "UPDATE A SET data = 'DL_BA' WHERE id = 1")
+	ba.AddWait(1 * time.Second)
+}
+
+// RenderDeadlocks prints the victim(s) of any ORA-00060 and how the other flows ended
+func RenderDeadlocks(results []StepResult, flows []string, outcomes map[string]error) {
+	var victims []StepResult
+	isVictim := make(map[string]bool)
+	for _, r := range results {
+		if isDeadlock(r.Err) {
+			victims = append(victims, r)
+			isVictim[r.Flow] = true
+		}
+	}
+	if len(victims) == 0 {
+		return
+	}
+
+	fmt.Println("\n=== Deadlock ===")
+	for _, v := range victims {
+		fmt.Printf("Victim:   %-10s step #%d %q, waited %v before %s\n", v.Flow, v.Index, v.Label, v.Duration.Round(time.Millisecond), oraDeadlock)
+	}
+	for _, flow := range flows {
+		if isVictim[flow] {
+			continue
+		}
+		outcome := "committed"
+		if err := outcomes[flow]; err != nil {
+			outcome = "failed: " + err.Error()
+		}
+		fmt.Printf("Survivor: %-10s %s\n", flow, outcome)
+	}
+	fmt.Println("Oracle rolled back only the victim's blocked statement; its earlier work stayed locked until the flow rolled back.")
+}
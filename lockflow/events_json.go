@@ -0,0 +1,121 @@
+package lockflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// eventsExport is the document written by -events-json
+type eventsExport struct {
+	Start    time.Time                `json:"start"`
+	Timeline []timelineRecord         `json:"timeline"`
+	Log      []eventLogRecord         `json:"log"`
+	Blocking []blockingRecord         `json:"blocking,omitempty"`
+	Sessions map[string]sessionRecord `json:"sessions,omitempty"` // transactional flows only
+}
+
+type sessionRecord struct {
+	SID    int64 `json:"sid"`
+	Serial int64 `json:"serial"`
+	AUDSID int64 `json:"audsid"`
+}
+
+type timelineRecord struct {
+	Flow     string    `json:"flow"`
+	Table    string    `json:"table,omitempty"`
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	OffsetMS float64   `json:"offset_ms"` // relative to start
+}
+
+type eventLogRecord struct {
+	Who      string    `json:"who"`
+	SID      *int64    `json:"sid,omitempty"`
+	Msg      string    `json:"msg"`
+	Time     time.Time `json:"time"`
+	OffsetMS float64   `json:"offset_ms"`
+}
+
+type blockingRecord struct {
+	Waiter     string  `json:"waiter"`
+	WaiterSID  int64   `json:"waiter_sid"`
+	Blocker    string  `json:"blocker"`
+	BlockerSID int64   `json:"blocker_sid"`
+	Event      string  `json:"event"`
+	LockType   string  `json:"lock_type"`
+	StartMS    float64 `json:"start_ms"`
+	EndMS      float64 `json:"end_ms"`
+}
+
+func offsetMS(ts, start time.Time) float64 {
+	return float64(ts.Sub(start).Microseconds()) / 1000
+}
+
+// ExportEventsJSON writes every timeline event and EventLogger entry, ordered by time, to path
+func (r *Runner) ExportEventsJSON(path string) error {
+	events, start := r.timeline.Events()
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+
+	doc := eventsExport{Start: start}
+	for _, e := range events {
+		doc.Timeline = append(doc.Timeline, timelineRecord{
+			Flow:     e.Flow,
+			Table:    e.Table,
+			Type:     e.EventType,
+			Time:     e.Time,
+			OffsetMS: offsetMS(e.Time, start),
+		})
+	}
+
+	entries := r.logger.snapshot()
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ts.Before(entries[j].ts) })
+	for _, e := range entries {
+		var sid *int64
+		if e.session != nil {
+			sid = &e.session.SID
+		}
+		doc.Log = append(doc.Log, eventLogRecord{
+			Who:      e.who,
+			SID:      sid,
+			Msg:      e.msg,
+			Time:     e.ts,
+			OffsetMS: offsetMS(e.ts, start),
+		})
+	}
+
+	if r.monitor != nil {
+		for _, bi := range r.monitor.Intervals() {
+			doc.Blocking = append(doc.Blocking, blockingRecord{
+				Waiter:     bi.Waiter,
+				WaiterSID:  bi.WaiterSID,
+				Blocker:    bi.Blocker,
+				BlockerSID: bi.BlockerSID,
+				Event:      bi.Event,
+				LockType:   bi.LockType,
+				StartMS:    offsetMS(bi.Start, start),
+				EndMS:      offsetMS(bi.End, start),
+			})
+		}
+	}
+
+	for _, name := range r.names {
+		if s, ok := r.sessions.get(name); ok {
+			if doc.Sessions == nil {
+				doc.Sessions = make(map[string]sessionRecord)
+			}
+			doc.Sessions[name] = sessionRecord{SID: s.SID, Serial: s.Serial, AUDSID: s.AUDSID}
+		}
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal events: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package lockflow
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Gantt chart geometry (pixels)
+const (
+	ganttLabelWidth = 140
+	ganttPlotWidth  = 900
+	ganttRowHeight  = 28
+	ganttBarHeight  = 18
+	ganttTop        = 30
+	ganttBottom     = 40
+)
+
+// ganttPalette colours segments by table name
+var ganttPalette = []string{"#4e79a7", "#f28e2b", "#59a14f", "#edc948", "#b07aa1", "#76b7b2", "#ff9da7", "#9c755f"}
+
+// ExportTimeline writes the timeline as a Gantt chart.
+// The format follows the file extension: .svg for a bare image, .html for a standalone page.
+func (t *TimelineTracker) ExportTimeline(path string, showExpected bool) error {
+	l := t.layout(showExpected)
+	if l == nil {
+		return fmt.Errorf("no timeline events recorded")
+	}
+	svg := l.svg()
+
+	var out string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".svg":
+		out = svg
+	case ".html", ".htm":
+		out = ganttHTML(svg)
+	default:
+		return fmt.Errorf("unsupported timeline export format %q (use .svg or .html)", filepath.Ext(path))
+	}
+	if err := os.WriteFile(path, []byte(out), 0o644); err != nil {
+		return fmt.Errorf("write timeline: %w", err)
+	}
+	return nil
+}
+
+func (l *timelineLayout) svg() string {
+	total := l.TotalDuration
+	if total <= 0 {
+		total = 1
+	}
+	scale := ganttPlotWidth / total
+	x := func(sec float64) float64 { return ganttLabelWidth + sec*scale }
+
+	width := ganttLabelWidth + ganttPlotWidth + 20
+	height := ganttTop + len(l.Timelines)*ganttRowHeight + ganttBottom
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="12">`+"\n", width, height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n")
+
+	// Time grid
+	plotBottom := ganttTop + len(l.Timelines)*ganttRowHeight
+	for i := 0; i <= 6; i++ {
+		sec := total * float64(i) / 6.0
+		gx := x(sec)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#dddddd"/>`+"\n", gx, ganttTop-5, gx, plotBottom)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle" fill="#555555">%.1fs</text>`+"\n", gx, plotBottom+16, sec)
+	}
+
+	for row, tl := range l.Timelines {
+		y := ganttTop + row*ganttRowHeight
+		barY := y + (ganttRowHeight-ganttBarHeight)/2
+		midY := y + ganttRowHeight/2
+		expected := strings.HasSuffix(tl.Flow, " EXPECTED")
+		blocked := strings.HasSuffix(tl.Flow, " BLOCKED")
+
+		fmt.Fprintf(&b, `<text x="5" y="%d" dominant-baseline="middle">%s</text>`+"\n", midY, html.EscapeString(tl.Flow))
+
+		for _, seg := range tl.Segments {
+			if seg.Table == "SLEEP" {
+				continue
+			}
+			label := html.EscapeString(seg.Table)
+			if expected {
+				// Expected starts are points in time
+				fmt.Fprintf(&b, `<g><title>%s expected at %.2fs</title><path d="M %.1f %d l 6 %d l -6 %d l -6 %d z" fill="none" stroke="%s" stroke-width="2"/></g>`+"\n",
+					label, seg.Start, x(seg.Start), barY, ganttBarHeight/2, ganttBarHeight/2, -ganttBarHeight/2, ganttColor(seg.Table))
+				continue
+			}
+			w := math.Max((seg.End-seg.Start)*scale, 2)
+			fill := ganttColor(seg.Table)
+			title := fmt.Sprintf("%s %.2fs - %.2fs (%.2fs)", label, seg.Start, seg.End, seg.End-seg.Start)
+			if blocked {
+				fill = "#e15759"
+				title = fmt.Sprintf("blocked by %s %.2fs - %.2fs (%.2fs)", label, seg.Start, seg.End, seg.End-seg.Start)
+			}
+			fmt.Fprintf(&b, `<g><title>%s</title><rect x="%.1f" y="%d" width="%.1f" height="%d" rx="3" fill="%s" fill-opacity="0.85"/>`,
+				title, x(seg.Start), barY, w, ganttBarHeight, fill)
+			fmt.Fprintf(&b, `<text x="%.1f" y="%d" dominant-baseline="middle" fill="#ffffff">%s</text></g>`+"\n", x(seg.Start)+3, midY, label)
+		}
+
+		if ct, ok := l.CommitTimes[tl.Flow]; ok {
+			writeGanttMarker(&b, x(ct), y, "#2e7d32", "X", fmt.Sprintf("COMMIT at %.2fs", ct))
+		}
+		if rt, ok := l.RollbackTimes[tl.Flow]; ok {
+			writeGanttMarker(&b, x(rt), y, "#c62828", "R", fmt.Sprintf("ROLLBACK at %.2fs", rt))
+		}
+		for mt, m := range l.MarkerTimes[tl.Flow] {
+			writeGanttMarker(&b, x(mt), y, "#6a1b9a", string(m), fmt.Sprintf("%s at %.2fs", markerName(m), mt))
+		}
+	}
+
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+func writeGanttMarker(b *strings.Builder, x float64, rowY int, color, symbol, title string) {
+	fmt.Fprintf(b, `<g><title>%s</title><line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="%s" stroke-width="2"/>`,
+		html.EscapeString(title), x, rowY+2, x, rowY+ganttRowHeight-2, color)
+	fmt.Fprintf(b, `<text x="%.1f" y="%d" fill="%s" font-weight="bold">%s</text></g>`+"\n", x+3, rowY+10, color, html.EscapeString(symbol))
+}
+
+func markerName(m rune) string {
+	switch m {
+	case 'r':
+		return "ROLLBACK TO SAVEPOINT"
+	case 'I':
+		return "IMPLICIT COMMIT (DDL)"
+	case 'D':
+		return "DEADLOCK (ORA-00060)"
+	case 'K':
+		return "SESSION KILLED (chaos)"
+	}
+	return string(m)
+}
+
+func ganttColor(table string) string {
+	h := fnv.New32a()
+	h.Write([]byte(table))
+	return ganttPalette[h.Sum32()%uint32(len(ganttPalette))]
+}
+
+func ganttHTML(svg string) string {
+	return `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Lock Timeline</title>
+<style>
+body { font-family: sans-serif; margin: 20px; }
+.legend span { margin-right: 16px; }
+</style>
+</head>
+<body>
+<h2>Lock Timeline</h2>
+<p class="legend">
+<span><b style="color:#2e7d32">X</b> commit</span>
+<span><b style="color:#c62828">R</b> rollback</span>
+<span><b style="color:#6a1b9a">r</b> rollback to savepoint</span>
+<span><b style="color:#6a1b9a">I</b> implicit commit (DDL)</span>
+<span><b style="color:#6a1b9a">D</b> deadlock victim</span>
+<span><b style="color:#6a1b9a">K</b> session killed</span>
+<span><b style="color:#e15759">&#9632;</b> blocked (v$session)</span>
+<span>&#9671; expected start</span>
+</p>
+` + svg + `<p>Hover a bar or marker for exact timings.</p>
+</body>
+</html>
+`
+}
@@ -0,0 +1,751 @@
+package lockflow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type StepType int
+
+const (
+	StepSQL StepType = iota
+	StepWait
+	StepSavepoint  // SAVEPOINT <name>
+	StepRollbackTo // ROLLBACK TO SAVEPOINT <name>
+	StepDDL        // DDL; Oracle commits the open transaction before and after it
+	StepCall       // stored procedure call wrapped in an anonymous PL/SQL block
+	StepAwait      // block until another flow signals Barrier
+)
+
+type Step struct {
+	Type      StepType
+	Table     string
+	Label     string
+	SQL       string
+	Args      []interface{}
+	Savepoint string
+	Duration  time.Duration
+	Timeout   time.Duration
+	Lock      LockOption // FOR UPDATE modifier for query steps
+	Repeat    int        // run the step this many times; see iterPlaceholder
+	Barrier   string     // StepAwait: barrier to wait for
+	Signal    string     // barrier released once this step completes
+	ID        string     // optional name other steps' Timing.After can refer to
+	Timing    *Timing    // declared expected start/end and ordering
+	Expect    *Expectation
+}
+
+// statement returns the SQL text executed for the step
+func (s Step) statement() string {
+	switch s.Type {
+	case StepSavepoint:
+		return "SAVEPOINT " + s.Savepoint
+	case StepRollbackTo:
+		return "ROLLBACK TO SAVEPOINT " + s.Savepoint
+	case StepCall:
+		call := strings.TrimSuffix(strings.TrimSpace(s.SQL), ";")
+		return "BEGIN " + call + "; END;"
+	default:
+		return s.Lock.apply(s.SQL)
+	}
+}
+
+// TxFlow represents a transaction flow with ordered steps
+type TxFlow struct {
+	Name        string
+	Steps       []Step
+	db          *sql.DB
+	logger      *EventLogger
+	timeline    *TimelineTracker
+	results     *ResultCollector
+	chaos       *ChaosInjector // nil unless the runner enabled chaos
+	barriers    *Barriers
+	sessions    *sessionRegistry
+	serverStats bool // snapshot V$ counters around each SQL step
+	TxTimeout   time.Duration
+	Isolation   sql.IsolationLevel // LevelDefault (READ COMMITTED), LevelSerializable or LevelReadCommitted
+}
+
+// NewTxFlow creates a new flow builder
+func NewTxFlow(name string, db *sql.DB, logger *EventLogger, timeline *TimelineTracker) *TxFlow {
+	return &TxFlow{
+		Name:     name,
+		db:       db,
+		logger:   logger,
+		timeline: timeline,
+		Steps:    make([]Step, 0),
+	}
+}
+
+// SetTxTimeout sets the overall transaction timeout
+func (f *TxFlow) SetTxTimeout(d time.Duration) *TxFlow {
+	f.TxTimeout = d
+	return f
+}
+
+// SetIsolation sets the transaction isolation level.
+// Only READ COMMITTED and SERIALIZABLE are meaningful in Oracle.
+func (f *TxFlow) SetIsolation(level sql.IsolationLevel) *TxFlow {
+	f.Isolation = level
+	return f
+}
+
+// isolationStatement returns the SET TRANSACTION statement for the flow, or "" for the default
+func (f *TxFlow) isolationStatement() string {
+	switch f.Isolation {
+	case sql.LevelSerializable:
+		return "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE"
+	case sql.LevelReadCommitted:
+		return "SET TRANSACTION ISOLATION LEVEL READ COMMITTED"
+	}
+	return ""
+}
+
+// Expect attaches expectations to the most recently added step
+func (f *TxFlow) Expect(e Expectation) *TxFlow {
+	if len(f.Steps) > 0 {
+		f.Steps[len(f.Steps)-1].Expect = &e
+	}
+	return f
+}
+
+// ForUpdate sets the lock-acquisition modifier (NOWAIT, WAIT n, SKIP LOCKED) of the most recently added query
+func (f *TxFlow) ForUpdate(opt LockOption) *TxFlow {
+	if len(f.Steps) > 0 {
+		f.Steps[len(f.Steps)-1].Lock = opt
+	}
+	return f
+}
+
+// AddQuery adds a SELECT (or SELECT FOR UPDATE) step
+func (f *TxFlow) AddQuery(table, label, sqlQuery string, options ...time.Duration) *TxFlow {
+	var timeout time.Duration
+	if len(options) > 0 {
+		timeout = options[0]
+	}
+	f.Steps = append(f.Steps, Step{
+		Type:    StepSQL,
+		Table:   table,
+		Label:   label,
+		SQL:     sqlQuery,
+		Timeout: timeout,
+	})
+	return f
+}
+
+// Binds sets the bind values of the most recently added step.
+// String values may contain "{i}" when the step is repeated.
+func (f *TxFlow) Binds(args ...interface{}) *TxFlow {
+	if len(f.Steps) > 0 {
+		f.Steps[len(f.Steps)-1].Args = args
+	}
+	return f
+}
+
+// Repeat runs the most recently added step n times, substituting "{i}" in its label, SQL and binds
+func (f *TxFlow) Repeat(n int) *TxFlow {
+	if len(f.Steps) > 0 {
+		f.Steps[len(f.Steps)-1].Repeat = n
+	}
+	return f
+}
+
+// AddUpdate adds an UPDATE step
+func (f *TxFlow) AddUpdate(table, label, sqlUpdate string) *TxFlow {
+	f.Steps = append(f.Steps, Step{
+		Type:  StepSQL,
+		Table: table,
+		Label: label,
+		SQL:   sqlUpdate,
+	})
+	return f
+}
+
+// AddSavepoint adds a SAVEPOINT step
+func (f *TxFlow) AddSavepoint(name string) *TxFlow {
+	f.Steps = append(f.Steps, Step{
+		Type:      StepSavepoint,
+		Label:     "Savepoint " + name,
+		Savepoint: name,
+	})
+	return f
+}
+
+// AddRollbackTo adds a ROLLBACK TO SAVEPOINT step (partial rollback, transaction stays open)
+func (f *TxFlow) AddRollbackTo(name string) *TxFlow {
+	f.Steps = append(f.Steps, Step{
+		Type:      StepRollbackTo,
+		Label:     "Rollback to savepoint " + name,
+		Savepoint: name,
+	})
+	return f
+}
+
+// AddDDL adds a DDL step. Oracle implicitly commits the open transaction,
+// which is recorded on the timeline and in the event log.
+func (f *TxFlow) AddDDL(table, label, ddl string) *TxFlow {
+	f.Steps = append(f.Steps, Step{
+		Type:  StepDDL,
+		Table: table,
+		Label: label,
+		SQL:   ddl,
+	})
+	return f
+}
+
+// AddCall adds a stored procedure call step, e.g. AddCall("B", "Touch B", "PKG.TOUCH_B(:1)", 1)
+func (f *TxFlow) AddCall(table, label, call string, args ...interface{}) *TxFlow {
+	f.Steps = append(f.Steps, Step{
+		Type:  StepCall,
+		Table: table,
+		Label: label,
+		SQL:   call,
+		Args:  args,
+	})
+	return f
+}
+
+// ID names the most recently added step so other steps can refer to it in Timing.After
+func (f *TxFlow) ID(id string) *TxFlow {
+	if len(f.Steps) > 0 {
+		f.Steps[len(f.Steps)-1].ID = id
+	}
+	return f
+}
+
+// ExpectTiming declares when the most recently added step is expected to run
+func (f *TxFlow) ExpectTiming(t Timing) *TxFlow {
+	if len(f.Steps) > 0 {
+		f.Steps[len(f.Steps)-1].Timing = &t
+	}
+	return f
+}
+
+// Signal releases flows waiting on name once the most recently added step completes
+func (f *TxFlow) Signal(name string) *TxFlow {
+	if len(f.Steps) > 0 {
+		f.Steps[len(f.Steps)-1].Signal = name
+	}
+	return f
+}
+
+// AwaitSignal adds a step that blocks until another flow signals name
+func (f *TxFlow) AwaitSignal(name string) *TxFlow {
+	f.Steps = append(f.Steps, Step{
+		Type:    StepAwait,
+		Barrier: name,
+		Label:   "Waiting for " + name,
+	})
+	return f
+}
+
+// AddWait adds a sleep step
+func (f *TxFlow) AddWait(duration time.Duration) *TxFlow {
+	f.Steps = append(f.Steps, Step{
+		Type:     StepWait,
+		Duration: duration,
+		Label:    fmt.Sprintf("Sleeping %v", duration),
+	})
+	return f
+}
+
+func (f *TxFlow) flowSteps() []Step { return f.Steps }
+
+// Execute runs the steps in a single transaction and commits
+func (f *TxFlow) Execute(ctx context.Context) error {
+	f.logger.Log(ctx, f.Name, "BEGIN")
+
+	txCtx := ctx
+	if f.TxTimeout > 0 {
+		var cancel context.CancelFunc
+		txCtx, cancel = context.WithTimeout(ctx, f.TxTimeout)
+		defer cancel()
+	}
+
+	// Start Transaction
+	tx, err := f.db.BeginTx(txCtx, nil)
+	if err != nil {
+		f.logger.Log(ctx, f.Name, "ERROR: failed to begin transaction: "+err.Error())
+		return err
+	}
+	defer tx.Rollback()
+
+	// SET TRANSACTION must be the first statement of the transaction. It is issued explicitly
+	// rather than through sql.TxOptions so the behavior does not depend on driver support.
+	if stmt := f.isolationStatement(); stmt != "" {
+		if _, err := tx.ExecContext(txCtx, stmt); err != nil {
+			f.logger.Log(ctx, f.Name, "ERROR: failed to set isolation level: "+err.Error())
+			return err
+		}
+		f.logger.Log(ctx, f.Name, stmt)
+	}
+
+	// Tag the session so the lock monitor can map V$SESSION rows back to this flow
+	if _, err := tx.ExecContext(txCtx, "BEGIN DBMS_SESSION.SET_IDENTIFIER(:1); END;", f.Name); err != nil {
+		f.logger.Log(ctx, f.Name, "WARN: failed to set client identifier: "+err.Error())
+	}
+
+	// Capture the session identity so client events can be joined against V$ views and ASH
+	sess, err := sessionIdentity(txCtx, tx)
+	if err != nil {
+		f.logger.Log(ctx, f.Name, "WARN: failed to read session identity: "+err.Error())
+	} else {
+		f.sessions.set(f.Name, sess)
+		f.logger.SetSession(f.Name, sess)
+		f.logger.Log(ctx, f.Name, "Session "+sess.String())
+	}
+
+	steps := expandSteps(f.Steps)
+
+	// Server stats stop at the first failed read, e.g. without SELECT on the V$ views
+	statsOn := f.serverStats && sess.SID != 0
+	statsFailed := func(err error) {
+		f.logger.Log(ctx, f.Name, "WARN: server stats unavailable: "+err.Error())
+		statsOn = false
+	}
+
+	// Execute Steps
+	for i, step := range steps {
+		switch step.Type {
+		case StepWait:
+			f.logger.Log(ctx, f.Name, step.Label)
+			time.Sleep(step.Duration)
+		case StepAwait:
+			f.logger.Log(ctx, f.Name, step.Label)
+			if err := f.barriers.wait(txCtx, step.Barrier); err != nil {
+				f.logger.Log(ctx, f.Name, "ERROR: "+err.Error())
+				f.timeline.RecordRollback(f.Name)
+				return err
+			}
+		case StepSavepoint, StepRollbackTo:
+			f.logger.Log(ctx, f.Name, step.Label)
+			if _, err := tx.ExecContext(txCtx, step.statement()); err != nil {
+				f.logger.Log(ctx, f.Name, fmt.Sprintf("ERROR: %v: %v", step.Label, err))
+				f.timeline.RecordRollback(f.Name)
+				return err
+			}
+			if step.Type == StepRollbackTo {
+				f.timeline.RecordRollbackTo(f.Name)
+			}
+		default:
+			f.chaos.delay(ctx, f.Name, f.logger)
+			f.timeline.RecordStart(f.Name, step.Table)
+			f.logger.Log(ctx, f.Name, step.Label)
+
+			// Execute SQL
+			stepCtx, cancel := stepContext(txCtx, step.Timeout)
+
+			var before ServerStats
+			if statsOn {
+				if before, err = readServerStats(txCtx, tx, sess.SID); err != nil {
+					statsFailed(err)
+				}
+			}
+
+			stepStart := time.Now()
+			affected, err := f.execSQL(stepCtx, tx, step.statement(), step.Args...)
+			stepDur := time.Since(stepStart)
+			cancel()
+			f.results.Record(f.Name, i+1, step, stepStart, stepDur, affected, err)
+			if statsOn {
+				// After a deadlock or kill the session can no longer answer
+				if after, serr := readServerStats(txCtx, tx, sess.SID); serr == nil {
+					f.results.SetStats(f.Name, i+1, after.sub(before))
+				} else {
+					statsFailed(serr)
+				}
+			}
+			if err != nil && step.Expect.expectsError(err) {
+				// A failed statement only rolls back itself; the transaction carries on
+				f.logger.Log(ctx, f.Name, fmt.Sprintf("Expected error: %v: %v", step.Label, err))
+			} else if step.Lock.tolerates(err) {
+				f.logger.Log(ctx, f.Name, fmt.Sprintf("Lock not acquired (%s): %v: %v", step.Lock, step.Label, err))
+			} else if err != nil {
+				if isDeadlock(err) {
+					f.logger.Log(ctx, f.Name, "DEADLOCK: chosen as victim ("+oraDeadlock+"), rolling back")
+					f.timeline.RecordDeadlock(f.Name)
+				}
+				f.logger.Log(ctx, f.Name, fmt.Sprintf("ERROR: %v: %v", step.Label, err))
+				f.timeline.RecordRollback(f.Name)
+				return err
+			}
+
+			// Record End *after* the operation
+			f.timeline.RecordEnd(f.Name, step.Table)
+
+			if step.Type == StepDDL {
+				// Everything done so far in this transaction is now permanent
+				f.logger.Log(ctx, f.Name, "DDL implicitly committed the transaction")
+				f.timeline.RecordImplicitCommit(f.Name)
+			}
+
+			if f.chaos.maybeKill(ctx, f.Name, i+1, sess, f.logger) {
+				f.timeline.RecordKilled(f.Name)
+			}
+		}
+
+		if step.Signal != "" {
+			f.logger.Log(ctx, f.Name, "Reached "+step.Signal)
+			f.barriers.signal(step.Signal)
+		}
+	}
+
+	// Commit
+	f.logger.Log(ctx, f.Name, "Committing")
+	if err := tx.Commit(); err != nil {
+		f.logger.Log(ctx, f.Name, "ERROR: commit failed: "+err.Error())
+		f.timeline.RecordRollback(f.Name)
+		return err
+	}
+	f.timeline.RecordCommit(f.Name)
+	f.logger.Log(ctx, f.Name, "DONE")
+	return nil
+}
+
+// stepContext bounds a step by its timeout, if it has one; cancel as soon
+// as the step's statement returns
+func stepContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// execSQL runs one statement and returns rows returned (SELECT) or affected (DML)
+func (f *TxFlow) execSQL(ctx context.Context, tx *sql.Tx, sqlStmt string, args ...interface{}) (int64, error) {
+	// Simple heuristic to detect SELECT queries
+	trimmed := trimLeft(sqlStmt)
+	if len(trimmed) > 6 && (strings.EqualFold(trimmed[:6], "SELECT")) {
+		rows, err := tx.QueryContext(ctx, sqlStmt, args...)
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		results, err := processRows(rows)
+		if err != nil {
+			return 0, err
+		}
+		if len(results) > 0 {
+			for _, res := range results {
+				f.logger.Log(ctx, f.Name, "Result: "+res)
+			}
+		} else {
+			f.logger.Log(ctx, f.Name, "Result: <no rows>")
+		}
+		return int64(len(results)), nil
+	}
+
+	// For UPDATE/INSERT/DELETE
+	res, err := tx.ExecContext(ctx, sqlStmt, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	if err == nil {
+		f.logger.Log(ctx, f.Name, fmt.Sprintf("Result: %d rows affected", affected))
+	}
+	return affected, nil
+}
+
+func trimLeft(s string) string {
+	// Basic trim for heuristic
+	start := 0
+	for start < len(s) && (s[start] == ' ' || s[start] == '\t' || s[start] == '\n') {
+		start++
+	}
+	return s[start:]
+}
+
+// NonTxFlow represents a flow without a transaction
+type NonTxFlow struct {
+	Name     string
+	Steps    []Step
+	db       *sql.DB
+	logger   *EventLogger
+	timeline *TimelineTracker
+	results  *ResultCollector
+	chaos    *ChaosInjector // delays only; autocommit flows have no session to kill
+	barriers *Barriers
+}
+
+// NewNonTxFlow creates a new non-transaction flow builder
+func NewNonTxFlow(name string, db *sql.DB, logger *EventLogger, timeline *TimelineTracker) *NonTxFlow {
+	return &NonTxFlow{
+		Name:     name,
+		db:       db,
+		logger:   logger,
+		timeline: timeline,
+		Steps:    make([]Step, 0),
+	}
+}
+
+// ForUpdate sets the lock-acquisition modifier of the most recently added query.
+// In autocommit mode the lock is released as soon as the statement completes.
+func (f *NonTxFlow) ForUpdate(opt LockOption) *NonTxFlow {
+	if len(f.Steps) > 0 {
+		f.Steps[len(f.Steps)-1].Lock = opt
+	}
+	return f
+}
+
+// Expect attaches expectations to the most recently added step
+func (f *NonTxFlow) Expect(e Expectation) *NonTxFlow {
+	if len(f.Steps) > 0 {
+		f.Steps[len(f.Steps)-1].Expect = &e
+	}
+	return f
+}
+
+// AddQuery adds a SELECT step
+func (f *NonTxFlow) AddQuery(table, label, sqlQuery string, options ...time.Duration) *NonTxFlow {
+	var timeout time.Duration
+	if len(options) > 0 {
+		timeout = options[0]
+	}
+	f.Steps = append(f.Steps, Step{
+		Type:    StepSQL,
+		Table:   table,
+		Label:   label,
+		SQL:     sqlQuery,
+		Timeout: timeout,
+	})
+	return f
+}
+
+// Binds sets the bind values of the most recently added step.
+// String values may contain "{i}" when the step is repeated.
+func (f *NonTxFlow) Binds(args ...interface{}) *NonTxFlow {
+	if len(f.Steps) > 0 {
+		f.Steps[len(f.Steps)-1].Args = args
+	}
+	return f
+}
+
+// Repeat runs the most recently added step n times, substituting "{i}" in its label, SQL and binds
+func (f *NonTxFlow) Repeat(n int) *NonTxFlow {
+	if len(f.Steps) > 0 {
+		f.Steps[len(f.Steps)-1].Repeat = n
+	}
+	return f
+}
+
+// AddUpdate adds an UPDATE step
+func (f *NonTxFlow) AddUpdate(table, label, sqlUpdate string) *NonTxFlow {
+	f.Steps = append(f.Steps, Step{
+		Type:  StepSQL,
+		Table: table,
+		Label: label,
+		SQL:   sqlUpdate,
+	})
+	return f
+}
+
+// AddDDL adds a DDL step
+func (f *NonTxFlow) AddDDL(table, label, ddl string) *NonTxFlow {
+	f.Steps = append(f.Steps, Step{
+		Type:  StepDDL,
+		Table: table,
+		Label: label,
+		SQL:   ddl,
+	})
+	return f
+}
+
+// AddCall adds a stored procedure call step
+func (f *NonTxFlow) AddCall(table, label, call string, args ...interface{}) *NonTxFlow {
+	f.Steps = append(f.Steps, Step{
+		Type:  StepCall,
+		Table: table,
+		Label: label,
+		SQL:   call,
+		Args:  args,
+	})
+	return f
+}
+
+// ID names the most recently added step so other steps can refer to it in Timing.After
+func (f *NonTxFlow) ID(id string) *NonTxFlow {
+	if len(f.Steps) > 0 {
+		f.Steps[len(f.Steps)-1].ID = id
+	}
+	return f
+}
+
+// ExpectTiming declares when the most recently added step is expected to run
+func (f *NonTxFlow) ExpectTiming(t Timing) *NonTxFlow {
+	if len(f.Steps) > 0 {
+		f.Steps[len(f.Steps)-1].Timing = &t
+	}
+	return f
+}
+
+// Signal releases flows waiting on name once the most recently added step completes
+func (f *NonTxFlow) Signal(name string) *NonTxFlow {
+	if len(f.Steps) > 0 {
+		f.Steps[len(f.Steps)-1].Signal = name
+	}
+	return f
+}
+
+// AwaitSignal adds a step that blocks until another flow signals name
+func (f *NonTxFlow) AwaitSignal(name string) *NonTxFlow {
+	f.Steps = append(f.Steps, Step{
+		Type:    StepAwait,
+		Barrier: name,
+		Label:   "Waiting for " + name,
+	})
+	return f
+}
+
+// AddWait adds a sleep step
+func (f *NonTxFlow) AddWait(duration time.Duration) *NonTxFlow {
+	f.Steps = append(f.Steps, Step{
+		Type:     StepWait,
+		Duration: duration,
+		Label:    fmt.Sprintf("Sleeping %v", duration),
+	})
+	return f
+}
+
+func (f *NonTxFlow) flowSteps() []Step { return f.Steps }
+
+// Execute runs the flow without a transaction
+func (f *NonTxFlow) Execute(ctx context.Context) error {
+	f.logger.Log(ctx, f.Name, "BEGIN (Non-Tx)")
+
+	steps := expandSteps(f.Steps)
+
+	// Execute Steps
+	for i, step := range steps {
+		if step.Type == StepWait {
+			f.logger.Log(ctx, f.Name, step.Label)
+			time.Sleep(step.Duration)
+		} else if step.Type == StepAwait {
+			f.logger.Log(ctx, f.Name, step.Label)
+			if err := f.barriers.wait(ctx, step.Barrier); err != nil {
+				f.logger.Log(ctx, f.Name, "ERROR: "+err.Error())
+				return err
+			}
+		} else if step.Type == StepSavepoint || step.Type == StepRollbackTo {
+			// Savepoints are meaningless in autocommit mode
+			f.logger.Log(ctx, f.Name, "Skipped (no transaction): "+step.Label)
+		} else {
+			f.chaos.delay(ctx, f.Name, f.logger)
+			f.timeline.RecordStart(f.Name, step.Table)
+			f.logger.Log(ctx, f.Name, step.Label)
+
+			// Execute SQL directly on DB
+			stepCtx, cancel := stepContext(ctx, step.Timeout)
+			stepStart := time.Now()
+			affected, err := f.execSQL(stepCtx, step.statement(), step.Args...)
+			cancel()
+			f.results.Record(f.Name, i+1, step, stepStart, time.Since(stepStart), affected, err)
+			if err != nil && step.Expect.expectsError(err) {
+				f.logger.Log(ctx, f.Name, fmt.Sprintf("Expected error: %v: %v", step.Label, err))
+			} else if step.Lock.tolerates(err) {
+				f.logger.Log(ctx, f.Name, fmt.Sprintf("Lock not acquired (%s): %v: %v", step.Lock, step.Label, err))
+			} else if err != nil {
+				f.logger.Log(ctx, f.Name, fmt.Sprintf("ERROR: %v: %v", step.Label, err))
+				return err
+			}
+
+			f.timeline.RecordEnd(f.Name, step.Table)
+		}
+
+		if step.Signal != "" {
+			f.logger.Log(ctx, f.Name, "Reached "+step.Signal)
+			f.barriers.signal(step.Signal)
+		}
+	}
+
+	f.timeline.RecordCommit(f.Name) // Mark end
+	f.logger.Log(ctx, f.Name, "DONE")
+	return nil
+}
+
+func (f *NonTxFlow) execSQL(ctx context.Context, sqlStmt string, args ...interface{}) (int64, error) {
+	trimmed := trimLeft(sqlStmt)
+	if len(trimmed) > 6 && (strings.EqualFold(trimmed[:6], "SELECT")) {
+		rows, err := f.db.QueryContext(ctx, sqlStmt, args...)
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		results, err := processRows(rows)
+		if err != nil {
+			return 0, err
+		}
+		if len(results) > 0 {
+			for _, res := range results {
+				f.logger.Log(ctx, f.Name, "Result: "+res)
+			}
+		} else {
+			f.logger.Log(ctx, f.Name, "Result: <no rows>")
+		}
+		return int64(len(results)), nil
+	}
+
+	res, err := f.db.ExecContext(ctx, sqlStmt, args...)
+	if err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	if err == nil {
+		f.logger.Log(ctx, f.Name, fmt.Sprintf("Result: %d rows affected", affected))
+	}
+	return affected, nil
+}
+
+func processRows(rows *sql.Rows) ([]string, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []string
+	values := make([]interface{}, len(cols))
+	valuePtrs := make([]interface{}, len(cols))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, err
+		}
+
+		var parts []string
+		for i, col := range cols {
+			val := values[i]
+			var valStr string
+			if b, ok := val.([]byte); ok {
+				valStr = string(b)
+			} else {
+				valStr = fmt.Sprintf("%v", val)
+			}
+			parts = append(parts, fmt.Sprintf("%s=%s", col, valStr))
+		}
+		results = append(results, fmt.Sprintf("[%s]", strings.Join(parts, ", ")))
+	}
+	return results, rows.Err()
+}
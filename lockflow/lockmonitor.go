@@ -0,0 +1,172 @@
+package lockflow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"sql-learn2/logging"
+)
+
+// blockingQuery lists sessions of the current user that are waiting on another session,
+// together with the lock they are requesting. Flows are identified by CLIENT_IDENTIFIER,
+// which TxFlow sets to the flow name at BEGIN. Requires SELECT on V$SESSION and V$LOCK.
+const blockingQuery = `
+SELECT w.sid, NVL(w.client_identifier, '-'),
+       w.blocking_session, NVL(b.client_identifier, '-'),
+       w.event, NVL(l.type, '-')
+  FROM v$session w
+  LEFT JOIN v$session b ON b.sid = w.blocking_session
+  LEFT JOIN v$lock l ON l.sid = w.sid AND l.request > 0
+ WHERE w.blocking_session IS NOT NULL
+   AND w.username = USER`
+
+// BlockInterval is a continuous period during which one session waited on another
+type BlockInterval struct {
+	Waiter     string
+	WaiterSID  int64
+	Blocker    string
+	BlockerSID int64
+	Event      string
+	LockType   string
+	Start      time.Time
+	End        time.Time
+}
+
+// LockMonitor polls V$SESSION/V$LOCK in the background while flows run
+type LockMonitor struct {
+	db       *sql.DB
+	interval time.Duration
+
+	mu        sync.Mutex
+	open      map[string]*BlockInterval // waiter SID/blocker SID -> interval still being observed
+	intervals []BlockInterval
+	pollErr   error
+}
+
+// NewLockMonitor creates a monitor polling every interval
+func NewLockMonitor(db *sql.DB, interval time.Duration) *LockMonitor {
+	if interval <= 0 {
+		interval = 200 * time.Millisecond
+	}
+	return &LockMonitor{
+		db:       db,
+		interval: interval,
+		open:     make(map[string]*BlockInterval),
+	}
+}
+
+// Run polls until ctx is cancelled. It stops early (and reports why) if the views are not accessible.
+func (m *LockMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			m.closeAll(time.Now())
+			return
+		case <-ticker.C:
+			if err := m.poll(ctx); err != nil {
+				if ctx.Err() != nil {
+					m.closeAll(time.Now())
+					return
+				}
+				m.mu.Lock()
+				m.pollErr = err
+				m.mu.Unlock()
+				logging.FromContext(ctx).Warn("Lock monitor stopped", logging.FieldError, err)
+				m.closeAll(time.Now())
+				return
+			}
+		}
+	}
+}
+
+func (m *LockMonitor) poll(ctx context.Context) error {
+	now := time.Now()
+	rows, err := m.db.QueryContext(ctx, blockingQuery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for rows.Next() {
+		var bi BlockInterval
+		if err := rows.Scan(&bi.WaiterSID, &bi.Waiter, &bi.BlockerSID, &bi.Blocker, &bi.Event, &bi.LockType); err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%d/%d", bi.WaiterSID, bi.BlockerSID)
+		seen[key] = true
+		if cur, ok := m.open[key]; ok {
+			cur.End = now
+			continue
+		}
+		bi.Start, bi.End = now, now
+		m.open[key] = &bi
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Anything not seen in this poll has been released
+	for key, bi := range m.open {
+		if !seen[key] {
+			m.intervals = append(m.intervals, *bi)
+			delete(m.open, key)
+		}
+	}
+	return nil
+}
+
+func (m *LockMonitor) closeAll(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, bi := range m.open {
+		bi.End = now
+		m.intervals = append(m.intervals, *bi)
+		delete(m.open, key)
+	}
+}
+
+// Intervals returns the observed blocking intervals ordered by start time
+func (m *LockMonitor) Intervals() []BlockInterval {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]BlockInterval, len(m.intervals))
+	copy(out, m.intervals)
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
+
+// Err returns the error that stopped polling, if any
+func (m *LockMonitor) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pollErr
+}
+
+// RenderBlocking prints who blocked whom according to Oracle, relative to the run start
+func (m *LockMonitor) RenderBlocking(start time.Time) {
+	fmt.Println("\n=== Oracle Blocking (v$session / v$lock) ===")
+	if err := m.Err(); err != nil {
+		fmt.Printf("Monitor unavailable: %v\n", err)
+		fmt.Println("Grant SELECT_CATALOG_ROLE (or SELECT on V_$SESSION and V_$LOCK) to enable it.")
+	}
+	intervals := m.Intervals()
+	if len(intervals) == 0 {
+		fmt.Println("No blocking observed.")
+		return
+	}
+	for _, bi := range intervals {
+		fmt.Printf("  +%5.1fs .. +%5.1fs  %s (sid %d) blocked by %s (sid %d) for %v  [%s] %s\n",
+			bi.Start.Sub(start).Seconds(), bi.End.Sub(start).Seconds(),
+			bi.Waiter, bi.WaiterSID, bi.Blocker, bi.BlockerSID,
+			bi.End.Sub(bi.Start).Round(time.Millisecond), bi.LockType, bi.Event)
+	}
+}
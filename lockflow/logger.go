@@ -0,0 +1,145 @@
+package lockflow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"sql-learn2/logging"
+)
+
+// EventLogger logs events to EVENT_LOG table asynchronously
+type EventLogger struct {
+	db       *sql.DB
+	logQueue chan logEntry
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	entries  []logEntry             // in-memory copy of everything passed to Log, for exports
+	sessions map[string]SessionInfo // who -> session, stamped on later entries
+
+	fallbackPath string   // entries that cannot be written to EVENT_LOG go here; empty disables
+	fallback     *os.File // opened on first use
+	fallbackN    int
+}
+
+// persistTimeout bounds each EVENT_LOG insert so a locked-up database sends entries to the fallback file
+const persistTimeout = 2 * time.Second
+
+type logEntry struct {
+	ts      time.Time
+	who     string
+	msg     string
+	session *SessionInfo // nil for autocommit flows and the runner itself
+}
+
+func NewEventLogger(db *sql.DB) *EventLogger {
+	l := &EventLogger{
+		db:       db,
+		logQueue: make(chan logEntry, 100), // buffered channel
+		sessions: make(map[string]SessionInfo),
+	}
+
+	l.wg.Add(1)
+	go l.worker()
+	return l
+}
+
+func (l *EventLogger) worker() {
+	defer l.wg.Done()
+
+	for entry := range l.logQueue {
+		if err := l.persist(entry); err != nil {
+			slog.Error("Event logger: persist failed, writing to fallback", "flow", entry.who, logging.FieldError, err)
+			l.writeFallback(entry)
+		}
+	}
+}
+
+func (l *EventLogger) persist(entry logEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), persistTimeout)
+	defer cancel()
+	// Use a separate transaction that commits immediately
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback() // safety rollback if commit fails
+
+	// Insert with explicit timestamp to preserve ordering
+	var sid, serial, audsid sql.NullInt64
+	if s := entry.session; s != nil {
+		sid = sql.NullInt64{Int64: s.SID, Valid: true}
+		serial = sql.NullInt64{Int64: s.Serial, Valid: true}
+		audsid = sql.NullInt64{Int64: s.AUDSID, Valid: true}
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO EVENT_LOG (ts, who, msg, sid, serial_no, audsid) VALUES (:1, :2, :3, :4, :5, :6)",
+		entry.ts, entry.who, entry.msg, sid, serial, audsid)
+	if err != nil {
+		return fmt.Errorf("insert failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+// SetSession stamps subsequent entries from who with the given session identity
+func (l *EventLogger) SetSession(who string, s SessionInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sessions[who] = s
+}
+
+// Log queues an event to be logged to EVENT_LOG table
+func (l *EventLogger) Log(ctx context.Context, who, msg string) {
+	// Capture timestamp immediately
+	entry := logEntry{
+		ts:  time.Now(),
+		who: who,
+		msg: msg,
+	}
+
+	l.mu.Lock()
+	if s, ok := l.sessions[who]; ok {
+		entry.session = &s
+	}
+	l.entries = append(l.entries, entry)
+	l.mu.Unlock()
+
+	// Send to worker (non-blocking unless queue is full)
+	select {
+	case l.logQueue <- entry:
+	default:
+		slog.Warn("Event logger: queue full, writing to fallback", "flow", who, "msg", msg)
+		l.writeFallback(entry)
+	}
+}
+
+// Close closes the log queue and waits for all pending logs to be written
+func (l *EventLogger) Close() {
+	close(l.logQueue)
+	l.wg.Wait()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.fallback != nil {
+		l.fallback.Close()
+		l.fallback = nil
+		slog.Info("Event logger: entries written to fallback file", logging.FieldRows, l.fallbackN, logging.FieldFile, l.fallbackPath)
+	}
+}
+
+// snapshot returns a copy of every entry passed to Log, in call order
+func (l *EventLogger) snapshot() []logEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]logEntry, len(l.entries))
+	copy(out, l.entries)
+	return out
+}
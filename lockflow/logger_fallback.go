@@ -0,0 +1,146 @@
+package lockflow
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"sql-learn2/logging"
+)
+
+// fallbackRecord is one line of the fallback file
+type fallbackRecord struct {
+	TS  time.Time `json:"ts"`
+	Who string    `json:"who"`
+	Msg string    `json:"msg"`
+	SID *int64    `json:"sid,omitempty"`
+}
+
+// SetFallback makes entries that cannot be written to EVENT_LOG go to a JSON-lines file at path.
+// Any existing file is removed so stale entries from a previous run are not merged.
+func (l *EventLogger) SetFallback(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove old fallback file: %w", err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fallbackPath = path
+	return nil
+}
+
+// FallbackPath returns the fallback file path, or "" if none is configured
+func (l *EventLogger) FallbackPath() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fallbackPath
+}
+
+func (l *EventLogger) writeFallback(entry logEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.fallbackPath == "" {
+		slog.Warn("Event logger: no fallback file, event lost", "flow", entry.who, "msg", entry.msg)
+		return
+	}
+	if l.fallback == nil {
+		f, err := os.OpenFile(l.fallbackPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			slog.Error("Event logger: open fallback failed", "flow", entry.who, logging.FieldError, err)
+			return
+		}
+		l.fallback = f
+	}
+	rec := fallbackRecord{TS: entry.ts, Who: entry.who, Msg: entry.msg}
+	if entry.session != nil {
+		rec.SID = &entry.session.SID
+	}
+	data, _ := json.Marshal(rec)
+	if _, err := l.fallback.Write(append(data, '\n')); err != nil {
+		slog.Error("Event logger: write fallback failed", "flow", entry.who, logging.FieldError, err)
+		return
+	}
+	l.fallbackN++
+}
+
+// readFallback loads the entries written to the fallback file; a missing file means none
+func readFallback(path string) ([]fallbackRecord, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var recs []fallbackRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var r fallbackRecord
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			return recs, fmt.Errorf("parse fallback line: %w", err)
+		}
+		recs = append(recs, r)
+	}
+	return recs, sc.Err()
+}
+
+// DisplayEventLog prints all events from EVENT_LOG merged with the fallback file, ordered by timestamp.
+// Entries that only made it to the file are marked with '*'.
+func DisplayEventLog(ctx context.Context, db *sql.DB, fallbackPath string) error {
+	type row struct {
+		ts            time.Time
+		who, msg, sid string
+		fromFile      bool
+	}
+	var rows []row
+
+	dbRows, dbErr := db.QueryContext(ctx, "SELECT ts, who, msg, NVL(TO_CHAR(sid), '-') FROM EVENT_LOG ORDER BY ts")
+	if dbErr == nil {
+		defer dbRows.Close()
+		for dbRows.Next() {
+			var r row
+			if err := dbRows.Scan(&r.ts, &r.who, &r.msg, &r.sid); err != nil {
+				dbErr = err
+				break
+			}
+			rows = append(rows, r)
+		}
+		if dbErr == nil {
+			dbErr = dbRows.Err()
+		}
+	}
+
+	recs, fileErr := readFallback(fallbackPath)
+	for _, rec := range recs {
+		r := row{ts: rec.TS, who: rec.Who, msg: rec.Msg, sid: "-", fromFile: true}
+		if rec.SID != nil {
+			r.sid = fmt.Sprint(*rec.SID)
+		}
+		rows = append(rows, r)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].ts.Before(rows[j].ts) })
+	for _, r := range rows {
+		mark := " "
+		if r.fromFile {
+			mark = "*"
+		}
+		fmt.Printf(" %s%s  %-8s  sid %-5s  %s\n", mark, r.ts.Format("2006-01-02 15:04:05.000"), r.who, r.sid, r.msg)
+	}
+	if len(recs) > 0 {
+		fmt.Printf("(* = %d entries recovered from %s)\n", len(recs), fallbackPath)
+	}
+	return errors.Join(dbErr, fileErr)
+}
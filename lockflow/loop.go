@@ -0,0 +1,55 @@
+package lockflow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// iterPlaceholder is replaced with the 1-based iteration number in labels, SQL and bind values
+// of repeated steps. A bind value that is exactly "{i}" becomes the integer itself.
+const iterPlaceholder = "{i}"
+
+// expandSteps unrolls repeated steps into one step per iteration, substituting iterPlaceholder.
+// Steps without Repeat are returned unchanged.
+func expandSteps(steps []Step) []Step {
+	out := make([]Step, 0, len(steps))
+	for _, s := range steps {
+		if s.Repeat <= 1 {
+			out = append(out, s.iteration(1))
+			continue
+		}
+		for i := 1; i <= s.Repeat; i++ {
+			it := s.iteration(i)
+			if !strings.Contains(s.Label, iterPlaceholder) {
+				it.Label = fmt.Sprintf("%s [%d/%d]", it.Label, i, s.Repeat)
+			}
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// iteration returns a copy of the step with iterPlaceholder substituted by i
+func (s Step) iteration(i int) Step {
+	n := strconv.Itoa(i)
+	s.Repeat = 0
+	s.Label = strings.ReplaceAll(s.Label, iterPlaceholder, n)
+	s.SQL = strings.ReplaceAll(s.SQL, iterPlaceholder, n)
+	if len(s.Args) > 0 {
+		args := make([]interface{}, len(s.Args))
+		for j, a := range s.Args {
+			str, ok := a.(string)
+			switch {
+			case ok && str == iterPlaceholder:
+				args[j] = i
+			case ok:
+				args[j] = strings.ReplaceAll(str, iterPlaceholder, n)
+			default:
+				args[j] = a
+			}
+		}
+		s.Args = args
+	}
+	return s
+}
@@ -0,0 +1,135 @@
+package lockflow
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+)
+
+// --- Mock DB ---
+//
+// MockDB is a minimal database/sql driver. Every statement is recorded; ExecFunc and QueryFunc
+// decide the outcome. With no funcs set, Exec affects one row and Query returns no rows.
+
+type MockDB struct {
+	ExecFunc  func(query string, args []driver.NamedValue) (int64, error)
+	QueryFunc func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error)
+
+	mu         sync.Mutex
+	statements []string
+	commits    int
+	rollbacks  int
+}
+
+func newMockDB() (*MockDB, *sql.DB) {
+	m := &MockDB{}
+	return m, sql.OpenDB(m)
+}
+
+// Statements returns the recorded statements containing substr
+func (m *MockDB) Statements(substr string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []string
+	for _, s := range m.statements {
+		if strings.Contains(s, substr) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (m *MockDB) record(query string) {
+	m.mu.Lock()
+	m.statements = append(m.statements, query)
+	m.mu.Unlock()
+}
+
+// driver.Connector
+func (m *MockDB) Connect(context.Context) (driver.Conn, error) { return &mockConn{db: m}, nil }
+func (m *MockDB) Driver() driver.Driver                        { return mockDriver{} }
+
+type mockDriver struct{}
+
+func (mockDriver) Open(string) (driver.Conn, error) { return nil, driver.ErrSkip }
+
+type mockConn struct {
+	db *MockDB
+}
+
+func (c *mockConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *mockConn) Close() error                        { return nil }
+func (c *mockConn) Begin() (driver.Tx, error)           { return &mockTx{db: c.db}, nil }
+
+func (c *mockConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return &mockTx{db: c.db}, nil
+}
+
+func (c *mockConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.record(query)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.db.ExecFunc != nil {
+		n, err := c.db.ExecFunc(query, args)
+		if err != nil {
+			return nil, err
+		}
+		return driver.RowsAffected(n), nil
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *mockConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.record(query)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.db.QueryFunc != nil {
+		cols, rows, err := c.db.QueryFunc(query, args)
+		if err != nil {
+			return nil, err
+		}
+		return &mockRows{cols: cols, rows: rows}, nil
+	}
+	return &mockRows{cols: []string{"X"}}, nil
+}
+
+type mockTx struct {
+	db *MockDB
+}
+
+func (t *mockTx) Commit() error {
+	t.db.mu.Lock()
+	t.db.commits++
+	t.db.mu.Unlock()
+	return nil
+}
+
+func (t *mockTx) Rollback() error {
+	t.db.mu.Lock()
+	t.db.rollbacks++
+	t.db.mu.Unlock()
+	return nil
+}
+
+type mockRows struct {
+	cols []string
+	rows [][]driver.Value
+	pos  int
+}
+
+func (r *mockRows) Columns() []string { return r.cols }
+func (r *mockRows) Close() error      { return nil }
+
+func (r *mockRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
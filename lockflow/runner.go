@@ -0,0 +1,266 @@
+package lockflow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"sql-learn2/logging"
+)
+
+// flowExecutor is implemented by TxFlow and NonTxFlow
+type flowExecutor interface {
+	Execute(ctx context.Context) error
+	flowSteps() []Step
+}
+
+// Runner owns the shared logger/timeline and runs all registered flows concurrently
+type Runner struct {
+	db          *sql.DB
+	logger      *EventLogger
+	timeline    *TimelineTracker
+	results     *ResultCollector
+	monitor     *LockMonitor   // nil unless EnableLockMonitor was called
+	chaos       *ChaosInjector // nil unless EnableChaos was called
+	barriers    *Barriers
+	sessions    *sessionRegistry
+	timings     []timingCheck // filled by Report
+	serverStats bool
+	names       []string
+	flows       []flowExecutor
+	closeOnce   sync.Once
+
+	mu       sync.Mutex
+	outcomes map[string]error // flow name -> error returned by Execute (nil on commit)
+}
+
+// NewRunner creates a runner with its own EventLogger and TimelineTracker
+func NewRunner(db *sql.DB) *Runner {
+	return &Runner{
+		db:       db,
+		logger:   NewEventLogger(db),
+		timeline: NewTimelineTracker(time.Now()),
+		results:  NewResultCollector(),
+		barriers: NewBarriers(),
+		sessions: newSessionRegistry(),
+		outcomes: make(map[string]error),
+	}
+}
+
+// AddTxFlow registers a new transactional flow
+func (r *Runner) AddTxFlow(name string) *TxFlow {
+	f := NewTxFlow(name, r.db, r.logger, r.timeline)
+	f.results = r.results
+	f.sessions = r.sessions
+	f.serverStats = r.serverStats
+	f.chaos = r.chaos
+	f.barriers = r.barriers
+	r.register(name, f)
+	return f
+}
+
+// AddNonTxFlow registers a new non-transactional flow
+func (r *Runner) AddNonTxFlow(name string) *NonTxFlow {
+	f := NewNonTxFlow(name, r.db, r.logger, r.timeline)
+	f.results = r.results
+	f.chaos = r.chaos
+	f.barriers = r.barriers
+	r.register(name, f)
+	return f
+}
+
+func (r *Runner) register(name string, f flowExecutor) {
+	r.names = append(r.names, name)
+	r.flows = append(r.flows, f)
+	r.timeline.RegisterFlow(name)
+}
+
+// SetLogFallback sends events that cannot be written to EVENT_LOG to a local file, merged back at report time
+func (r *Runner) SetLogFallback(path string) error {
+	return r.logger.SetFallback(path)
+}
+
+// SetTimelineOptions sets the width/resolution of the ASCII timeline
+func (r *Runner) SetTimelineOptions(opts TimelineOptions) {
+	r.timeline.SetRenderOptions(opts)
+}
+
+// EnableServerStats snapshots V$SESS_TIME_MODEL/V$SESSION_EVENT around every SQL step of
+// transactional flows added afterwards, so client time can be split into execution and lock wait
+func (r *Runner) EnableServerStats() {
+	r.serverStats = true
+}
+
+// EnableLockMonitor polls V$SESSION/V$LOCK every interval while the flows run
+func (r *Runner) EnableLockMonitor(interval time.Duration) {
+	r.monitor = NewLockMonitor(r.db, interval)
+}
+
+// EnableChaos injects random delays and session kills into flows added afterwards.
+// admin is a privileged connection used for ALTER SYSTEM KILL SESSION; nil disables kills.
+func (r *Runner) EnableChaos(cfg ChaosConfig, admin *sql.DB) {
+	r.chaos = NewChaosInjector(cfg, admin)
+}
+
+// declareBarriers registers every signalled barrier and checks that awaited ones exist
+func (r *Runner) declareBarriers() error {
+	r.barriers.reset()
+	for i, f := range r.flows {
+		for _, st := range f.flowSteps() {
+			if st.Signal != "" {
+				if err := r.barriers.declare(st.Signal, r.names[i]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for i, f := range r.flows {
+		for _, st := range f.flowSteps() {
+			if st.Type != StepAwait {
+				continue
+			}
+			r.barriers.mu.Lock()
+			br, ok := r.barriers.m[st.Barrier]
+			r.barriers.mu.Unlock()
+			if !ok {
+				return fmt.Errorf("flow %s waits for barrier %s, which no flow signals", r.names[i], st.Barrier)
+			}
+			if br.owner == r.names[i] {
+				return fmt.Errorf("flow %s waits for its own barrier %s", r.names[i], st.Barrier)
+			}
+		}
+	}
+	return nil
+}
+
+// RunAll starts every flow in its own goroutine and waits for all of them
+func (r *Runner) RunAll(ctx context.Context) error {
+	if err := r.declareBarriers(); err != nil {
+		return err
+	}
+	logger := logging.FromContext(ctx)
+	logger.Info("Step 4: Launching flows...", "flows", len(r.flows))
+	start := time.Now()
+	r.timeline.Reset(start)
+
+	var monitorDone chan struct{}
+	if r.monitor != nil {
+		monCtx, stopMonitor := context.WithCancel(ctx)
+		defer stopMonitor()
+		monitorDone = make(chan struct{})
+		go func() {
+			defer close(monitorDone)
+			r.monitor.Run(monCtx)
+		}()
+		defer func() {
+			stopMonitor()
+			<-monitorDone
+			r.correlateBlocking()
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for i, f := range r.flows {
+		wg.Add(1)
+		go func(name string, f flowExecutor) {
+			defer wg.Done()
+			err := f.Execute(ctx)
+			// Never leave another flow waiting on a step this one will not reach
+			r.barriers.abandon(name)
+			if err != nil {
+				logger.Warn("Flow error", "flow", name, logging.FieldError, err)
+			}
+			r.mu.Lock()
+			r.outcomes[name] = err
+			r.mu.Unlock()
+		}(r.names[i], f)
+	}
+	wg.Wait()
+	logger.Info("✓ All flows completed", logging.FieldDuration, time.Since(start))
+	return nil
+}
+
+// correlateBlocking copies the intervals observed by the monitor onto the timeline.
+// Only waiters identified as a registered flow get a lane.
+func (r *Runner) correlateBlocking() {
+	known := make(map[string]bool, len(r.names))
+	for _, n := range r.names {
+		known[n] = true
+	}
+	for _, bi := range r.monitor.Intervals() {
+		if known[bi.Waiter] {
+			r.timeline.RecordBlocked(bi.Waiter, bi.Blocker, bi.Start, bi.End)
+		}
+	}
+}
+
+// Report flushes the logger, prints the event log and renders the timeline
+func (r *Runner) Report(ctx context.Context, showExpected bool) {
+	r.Close()
+
+	fmt.Println("\n=== Event Log (ordered by time) ===")
+	if err := DisplayEventLog(ctx, r.db, r.logger.FallbackPath()); err != nil {
+		logging.FromContext(ctx).Error("Failed to display event log", logging.FieldError, err)
+	}
+
+	RenderSessions(r.names, r.sessions)
+	r.timings = r.checkTimings()
+	r.timeline.RenderTimeline(showExpected)
+	if r.monitor != nil {
+		r.monitor.RenderBlocking(r.timeline.start)
+	}
+	RenderLockAttempts(r.results.Results())
+	RenderServerStats(r.results.Results())
+	RenderAssertions(r.results.Results())
+	RenderTimingDiff(r.timings)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	RenderDeadlocks(r.results.Results(), r.names, r.outcomes)
+	r.chaos.RenderChaos(r.timeline.start, r.outcomes)
+}
+
+// ExportTimeline writes the timeline as an SVG or HTML Gantt chart
+func (r *Runner) ExportTimeline(path string, showExpected bool) error {
+	return r.timeline.ExportTimeline(path, showExpected)
+}
+
+// checkTimings draws declared start times on the EXPECTED lanes and diffs them against the run
+func (r *Runner) checkTimings() []timingCheck {
+	events, start := r.timeline.Events()
+	commits := make(map[string]time.Time)
+	for _, e := range events {
+		if e.EventType == "COMMIT" {
+			commits[e.Flow] = e.Time
+		}
+	}
+
+	steps := make(map[string][]Step, len(r.flows))
+	for i, f := range r.flows {
+		name := r.names[i]
+		steps[name] = expandSteps(f.flowSteps())
+		for _, st := range steps[name] {
+			if st.Timing != nil && st.Timing.StartAt != nil {
+				r.timeline.RecordExpected(name, st.Table, start.Add(*st.Timing.StartAt))
+			}
+		}
+	}
+	return evaluateTimings(start, r.names, steps, r.results.Results(), commits)
+}
+
+// Failed reports whether any step assertion or declared timing failed
+func (r *Runner) Failed() bool {
+	for _, c := range r.timings {
+		if len(c.Failures) > 0 {
+			return true
+		}
+	}
+	return r.results.Failed()
+}
+
+// Close flushes pending log entries; safe to call more than once
+func (r *Runner) Close() {
+	r.closeOnce.Do(r.logger.Close)
+}
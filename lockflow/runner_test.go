@@ -0,0 +1,172 @@
+package lockflow
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sessionQuery(query string) bool {
+	return strings.Contains(query, "SYS_CONTEXT('USERENV', 'SID')")
+}
+
+func TestRunner_TxFlowCommits(t *testing.T) {
+	mock, db := newMockDB()
+	mock.QueryFunc = func(query string, _ []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if sessionQuery(query) {
+			return []string{"SID", "SERIAL", "AUDSID"}, [][]driver.Value{{int64(41), int64(7), int64(9001)}}, nil
+		}
+		return []string{"ID"}, [][]driver.Value{{int64(1)}}, nil
+	}
+
+	r := NewRunner(db)
+	r.AddTxFlow("F1").
+		AddQuery("A", "Lock A", "SELECT id FROM A WHERE id = 1 FOR UPDATE").Expect(Expectation{RowsAffected: Rows(1)}).
+		AddUpdate("B", "Update B", "UPDATE B SET data = 'x' WHERE id = 1").Expect(Expectation{RowsAffected: Rows(1)})
+
+	if err := r.RunAll(context.Background()); err != nil {
+		t.Fatalf("RunAll: %v", err)
+	}
+	r.Close()
+
+	if err := r.outcomes["F1"]; err != nil {
+		t.Errorf("F1 outcome = %v, want nil", err)
+	}
+	if r.Failed() {
+		t.Errorf("Failed() = true, results: %+v", r.results.Results())
+	}
+	if got := len(mock.Statements("UPDATE B")); got != 1 {
+		t.Errorf("UPDATE B executed %d times, want 1", got)
+	}
+	if s, ok := r.sessions.get("F1"); !ok || s.SID != 41 || s.Serial != 7 || s.AUDSID != 9001 {
+		t.Errorf("session = %+v, %v", s, ok)
+	}
+	if got := len(mock.Statements("DBMS_SESSION.SET_IDENTIFIER")); got != 1 {
+		t.Errorf("client identifier set %d times, want 1", got)
+	}
+}
+
+func TestRunner_ErrorRollsBackAndStopsFlow(t *testing.T) {
+	mock, db := newMockDB()
+	mock.ExecFunc = func(query string, _ []driver.NamedValue) (int64, error) {
+		if strings.Contains(query, "UPDATE A") {
+			return 0, errors.New("ORA-00060: deadlock detected while waiting for resource")
+		}
+		return 1, nil
+	}
+
+	r := NewRunner(db)
+	r.AddTxFlow("VICTIM").
+		AddUpdate("A", "Update A", "UPDATE A SET data = 'x' WHERE id = 1").
+		AddUpdate("B", "Update B", "UPDATE B SET data = 'x' WHERE id = 1")
+
+	if err := r.RunAll(context.Background()); err != nil {
+		t.Fatalf("RunAll: %v", err)
+	}
+	r.Close()
+
+	if err := r.outcomes["VICTIM"]; !isDeadlock(err) {
+		t.Errorf("outcome = %v, want ORA-00060", err)
+	}
+	if got := len(mock.Statements("UPDATE B")); got != 0 {
+		t.Errorf("UPDATE B executed %d times after the failure, want 0", got)
+	}
+}
+
+func TestRunner_ExpectedErrorContinues(t *testing.T) {
+	mock, db := newMockDB()
+	mock.QueryFunc = func(query string, _ []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if strings.Contains(query, "NOWAIT") {
+			return nil, nil, errors.New("ORA-00054: resource busy and acquire with NOWAIT specified")
+		}
+		return []string{"X"}, nil, nil
+	}
+
+	r := NewRunner(db)
+	r.AddTxFlow("F").
+		AddQuery("A", "Try lock", "SELECT id FROM A WHERE id = 1").ForUpdate(NoWait()).Expect(Expectation{Error: "ORA-00054"}).
+		AddUpdate("B", "Update B", "UPDATE B SET data = 'x' WHERE id = 1")
+
+	if err := r.RunAll(context.Background()); err != nil {
+		t.Fatalf("RunAll: %v", err)
+	}
+	r.Close()
+
+	if err := r.outcomes["F"]; err != nil {
+		t.Errorf("outcome = %v, want nil", err)
+	}
+	if got := mock.Statements("FOR UPDATE NOWAIT"); len(got) != 1 {
+		t.Errorf("NOWAIT statements = %v", got)
+	}
+	if got := len(mock.Statements("UPDATE B")); got != 1 {
+		t.Errorf("UPDATE B executed %d times, want 1", got)
+	}
+	if r.Failed() {
+		t.Errorf("Failed() = true, want false")
+	}
+}
+
+func TestRunner_Barrier(t *testing.T) {
+	mock, db := newMockDB()
+	var order []string
+	mock.ExecFunc = func(query string, _ []driver.NamedValue) (int64, error) {
+		if strings.HasPrefix(query, "UPDATE") {
+			order = append(order, query)
+		}
+		return 1, nil
+	}
+
+	r := NewRunner(db)
+	r.AddTxFlow("SECOND").
+		AwaitSignal("FIRST_DONE").
+		AddUpdate("B", "Update B", "UPDATE B")
+	r.AddTxFlow("FIRST").
+		AddWait(50*time.Millisecond).
+		AddUpdate("A", "Update A", "UPDATE A").Signal("FIRST_DONE")
+
+	if err := r.RunAll(context.Background()); err != nil {
+		t.Fatalf("RunAll: %v", err)
+	}
+	r.Close()
+
+	if len(order) != 2 || order[0] != "UPDATE A" || order[1] != "UPDATE B" {
+		t.Errorf("order = %v, want [UPDATE A UPDATE B]", order)
+	}
+}
+
+func TestRunner_BarrierAbandoned(t *testing.T) {
+	mock, db := newMockDB()
+	mock.ExecFunc = func(query string, _ []driver.NamedValue) (int64, error) {
+		if query == "UPDATE A" {
+			return 0, errors.New("ORA-01013: user requested cancel of current operation")
+		}
+		return 1, nil
+	}
+
+	r := NewRunner(db)
+	r.AddTxFlow("WAITER").AwaitSignal("NEVER")
+	r.AddTxFlow("OWNER").AddUpdate("A", "Update A", "UPDATE A").Signal("NEVER")
+
+	if err := r.RunAll(context.Background()); err != nil {
+		t.Fatalf("RunAll: %v", err)
+	}
+	r.Close()
+
+	if err := r.outcomes["WAITER"]; err == nil || !strings.Contains(err.Error(), "abandoned") {
+		t.Errorf("WAITER outcome = %v, want abandoned barrier", err)
+	}
+}
+
+func TestRunner_UnknownBarrier(t *testing.T) {
+	_, db := newMockDB()
+	r := NewRunner(db)
+	r.AddTxFlow("WAITER").AwaitSignal("MISSING")
+	defer r.Close()
+
+	if err := r.RunAll(context.Background()); err == nil {
+		t.Fatal("RunAll: expected error for a barrier nobody signals")
+	}
+}
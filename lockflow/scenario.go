@@ -0,0 +1,290 @@
+package lockflow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario describes a lock experiment loaded from a YAML or JSON file.
+// JSON is a subset of YAML, so both formats go through the same decoder.
+//
+// Example:
+//
+//	setup:
+//	  - INSERT INTO A (id, data) VALUES (2, 'A2')
+//	flows:
+//	  - name: CHAIN
+//	    tx_timeout: 5s
+//	    steps:
+//	      - {type: query, table: A, label: "Locked A.id=1", sql: "SELECT id FROM A WHERE id = 1 FOR UPDATE"}
+//	      - {type: wait, duration: 2s}
+type Scenario struct {
+	Setup []string   `yaml:"setup"` // extra statements run (and committed) after the tables are created
+	Flows []FlowSpec `yaml:"flows"`
+}
+
+// FlowSpec describes one flow in a scenario file
+type FlowSpec struct {
+	Name      string        `yaml:"name"`
+	NonTx     bool          `yaml:"non_tx"` // run steps in autocommit mode instead of one transaction
+	TxTimeout time.Duration `yaml:"tx_timeout"`
+	Isolation string        `yaml:"isolation"` // read_committed (default) or serializable
+	Steps     []StepSpec    `yaml:"steps"`
+}
+
+// StepSpec describes one step of a flow.
+// Type is one of: query, update, wait, savepoint, rollback_to, ddl, call, await.
+type StepSpec struct {
+	Type      string        `yaml:"type"`
+	Table     string        `yaml:"table"`
+	Label     string        `yaml:"label"`
+	SQL       string        `yaml:"sql"`       // for call steps: the procedure invocation, e.g. PKG.PROC(:1)
+	Args      []interface{} `yaml:"args"`      // bind values; strings may contain "{i}" on repeated steps
+	Savepoint string        `yaml:"savepoint"` // savepoint and rollback_to steps
+	Duration  time.Duration `yaml:"duration"`  // wait steps
+	Timeout   time.Duration `yaml:"timeout"`   // query steps
+	Lock      string        `yaml:"lock"`      // query steps: nowait, skip_locked or "wait 3s"
+	Repeat    int           `yaml:"repeat"`    // run the step this many times
+	Signal    string        `yaml:"signal"`    // barrier released once this step completes
+	Barrier   string        `yaml:"barrier"`   // await steps: barrier to wait for
+	ID        string        `yaml:"id"`        // name for other steps' timing.after
+	Timing    *TimingSpec   `yaml:"timing"`
+	Expect    *ExpectSpec   `yaml:"expect"`
+}
+
+// TimingSpec is the file form of Timing
+type TimingSpec struct {
+	StartAt   *time.Duration `yaml:"start_at"`
+	EndAt     *time.Duration `yaml:"end_at"`
+	Tolerance time.Duration  `yaml:"tolerance"`
+	After     string         `yaml:"after"` // FLOW/ID or FLOW/COMMIT
+}
+
+// ExpectSpec is the file form of Expectation
+type ExpectSpec struct {
+	Rows        *int64        `yaml:"rows"`
+	Error       string        `yaml:"error"`
+	MaxDuration time.Duration `yaml:"max_duration"`
+}
+
+// LoadScenario reads and validates a scenario file
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read scenario: %w", err)
+	}
+	var sc Scenario
+	if err := yaml.Unmarshal(data, &sc); err != nil {
+		return nil, fmt.Errorf("parse scenario %s: %w", path, err)
+	}
+	if err := sc.validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return &sc, nil
+}
+
+func (sc *Scenario) validate() error {
+	if len(sc.Flows) == 0 {
+		return fmt.Errorf("no flows defined")
+	}
+	if err := sc.validateTimingRefs(); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for i, f := range sc.Flows {
+		if strings.TrimSpace(f.Name) == "" {
+			return fmt.Errorf("flow %d: name is required", i+1)
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("flow %s: duplicate name", f.Name)
+		}
+		seen[f.Name] = true
+		if _, err := parseIsolation(f.Isolation); err != nil {
+			return fmt.Errorf("flow %s: %w", f.Name, err)
+		}
+		if f.NonTx && f.Isolation != "" {
+			return fmt.Errorf("flow %s: isolation requires a transactional flow", f.Name)
+		}
+		for j, st := range f.Steps {
+			if st.Repeat < 0 {
+				return fmt.Errorf("flow %s step %d: repeat must be >= 0", f.Name, j+1)
+			}
+			switch strings.ToLower(st.Type) {
+			case "query", "update", "ddl", "call":
+				if strings.TrimSpace(st.SQL) == "" {
+					return fmt.Errorf("flow %s step %d: sql is required", f.Name, j+1)
+				}
+				if _, err := parseLock(st.Lock); err != nil {
+					return fmt.Errorf("flow %s step %d: %w", f.Name, j+1, err)
+				}
+				if st.Lock != "" && strings.ToLower(st.Type) != "query" {
+					return fmt.Errorf("flow %s step %d: lock is only valid on query steps", f.Name, j+1)
+				}
+			case "savepoint", "rollback_to":
+				if strings.TrimSpace(st.Savepoint) == "" {
+					return fmt.Errorf("flow %s step %d: savepoint name is required", f.Name, j+1)
+				}
+				if f.NonTx {
+					return fmt.Errorf("flow %s step %d: %s requires a transactional flow", f.Name, j+1, st.Type)
+				}
+			case "wait":
+				if st.Duration <= 0 {
+					return fmt.Errorf("flow %s step %d: duration must be > 0", f.Name, j+1)
+				}
+			case "await":
+				if strings.TrimSpace(st.Barrier) == "" {
+					return fmt.Errorf("flow %s step %d: barrier is required", f.Name, j+1)
+				}
+			default:
+				return fmt.Errorf("flow %s step %d: unknown type %q", f.Name, j+1, st.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// validateTimingRefs checks that every timing.after names an existing FLOW/ID or FLOW/COMMIT
+func (sc *Scenario) validateTimingRefs() error {
+	refs := make(map[string]bool)
+	for _, f := range sc.Flows {
+		refs[f.Name+"/COMMIT"] = true
+		for _, st := range f.Steps {
+			if st.ID != "" {
+				refs[f.Name+"/"+st.ID] = true
+			}
+		}
+	}
+	for _, f := range sc.Flows {
+		for j, st := range f.Steps {
+			if st.Timing != nil && st.Timing.After != "" && !refs[st.Timing.After] {
+				return fmt.Errorf("flow %s step %d: timing.after %q does not match any FLOW/ID or FLOW/COMMIT", f.Name, j+1, st.Timing.After)
+			}
+		}
+	}
+	return nil
+}
+
+// RunSetup executes the scenario's setup statements and commits them
+func (sc *Scenario) RunSetup(ctx context.Context, db *sql.DB) error {
+	if len(sc.Setup) == 0 {
+		return nil
+	}
+	for _, stmt := range sc.Setup {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("setup %q: %w", stmt, err)
+		}
+	}
+	_, err := db.ExecContext(ctx, "COMMIT")
+	return err
+}
+
+// Apply registers the scenario's flows on the runner
+func (sc *Scenario) Apply(r *Runner) {
+	for _, f := range sc.Flows {
+		steps := make([]Step, 0, len(f.Steps))
+		for _, st := range f.Steps {
+			step := st.toStep()
+			step.Repeat = st.Repeat
+			step.Signal = st.Signal
+			step.ID = st.ID
+			if st.Timing != nil {
+				step.Timing = &Timing{
+					StartAt:   st.Timing.StartAt,
+					EndAt:     st.Timing.EndAt,
+					Tolerance: st.Timing.Tolerance,
+					After:     st.Timing.After,
+				}
+			}
+			steps = append(steps, step)
+		}
+		if f.NonTx {
+			r.AddNonTxFlow(f.Name).Steps = steps
+			continue
+		}
+		level, _ := parseIsolation(f.Isolation) // checked by validate
+		r.AddTxFlow(f.Name).SetTxTimeout(f.TxTimeout).SetIsolation(level).Steps = steps
+	}
+}
+
+func parseLock(s string) (LockOption, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	switch v {
+	case "":
+		return LockOption{}, nil
+	case "nowait":
+		return NoWait(), nil
+	case "skip_locked", "skip locked":
+		return SkipLocked(), nil
+	}
+	if rest, ok := strings.CutPrefix(v, "wait"); ok {
+		rest = strings.TrimSpace(rest)
+		if d, err := time.ParseDuration(rest); err == nil && d >= time.Second {
+			return WaitFor(d), nil
+		}
+		if n, err := strconv.Atoi(rest); err == nil && n > 0 {
+			return WaitFor(time.Duration(n) * time.Second), nil
+		}
+	}
+	return LockOption{}, fmt.Errorf("unknown lock %q (use nowait, skip_locked or \"wait <seconds>\")", s)
+}
+
+func parseIsolation(s string) (sql.IsolationLevel, error) {
+	switch strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), " ", "_")) {
+	case "":
+		return sql.LevelDefault, nil
+	case "read_committed":
+		return sql.LevelReadCommitted, nil
+	case "serializable":
+		return sql.LevelSerializable, nil
+	}
+	return sql.LevelDefault, fmt.Errorf("unknown isolation %q (use read_committed or serializable)", s)
+}
+
+func (st StepSpec) toStep() Step {
+	switch strings.ToLower(st.Type) {
+	case "wait":
+		label := st.Label
+		if label == "" {
+			label = fmt.Sprintf("Sleeping %v", st.Duration)
+		}
+		return Step{Type: StepWait, Duration: st.Duration, Label: label}
+	case "await":
+		return Step{Type: StepAwait, Label: "Waiting for " + st.Barrier, Barrier: st.Barrier}
+	case "savepoint":
+		return Step{Type: StepSavepoint, Label: "Savepoint " + st.Savepoint, Savepoint: st.Savepoint}
+	case "rollback_to":
+		return Step{Type: StepRollbackTo, Label: "Rollback to savepoint " + st.Savepoint, Savepoint: st.Savepoint}
+	}
+
+	stepType := StepSQL
+	switch strings.ToLower(st.Type) {
+	case "ddl":
+		stepType = StepDDL
+	case "call":
+		stepType = StepCall
+	}
+	step := Step{
+		Type:    stepType,
+		Table:   st.Table,
+		Label:   st.Label,
+		SQL:     st.SQL,
+		Args:    st.Args,
+		Timeout: st.Timeout,
+	}
+	step.Lock, _ = parseLock(st.Lock) // checked by validate
+	if st.Expect != nil {
+		step.Expect = &Expectation{
+			RowsAffected: st.Expect.Rows,
+			Error:        st.Expect.Error,
+			MaxDuration:  st.Expect.MaxDuration,
+		}
+	}
+	return step
+}
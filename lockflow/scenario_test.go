@@ -0,0 +1,97 @@
+package lockflow
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeScenario(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadScenario(t *testing.T) {
+	path := writeScenario(t, `
+flows:
+  - name: HOLDER
+    isolation: serializable
+    tx_timeout: 5s
+    steps:
+      - {type: query, table: A, label: "Lock", sql: "SELECT id FROM A", lock: "wait 2", id: LOCK, signal: LOCKED}
+      - {type: wait, duration: 1500ms}
+  - name: OTHER
+    non_tx: true
+    steps:
+      - {type: await, barrier: LOCKED}
+      - {type: update, table: B, label: "Upd {i}", sql: "UPDATE B SET n = :1", args: ["{i}"], repeat: 2, timing: {after: HOLDER/LOCK}}
+`)
+	sc, err := LoadScenario(path)
+	if err != nil {
+		t.Fatalf("LoadScenario: %v", err)
+	}
+
+	_, db := newMockDB()
+	r := NewRunner(db)
+	defer r.Close()
+	sc.Apply(r)
+
+	holder := r.flows[0].(*TxFlow)
+	if holder.Isolation != sql.LevelSerializable || holder.TxTimeout != 5*time.Second {
+		t.Errorf("holder = isolation %v timeout %v", holder.Isolation, holder.TxTimeout)
+	}
+	if got := holder.Steps[0].statement(); got != "SELECT id FROM A FOR UPDATE WAIT 2" {
+		t.Errorf("statement = %q", got)
+	}
+	if holder.Steps[0].Signal != "LOCKED" || holder.Steps[0].ID != "LOCK" {
+		t.Errorf("step = %+v", holder.Steps[0])
+	}
+	if holder.Steps[1].Duration != 1500*time.Millisecond {
+		t.Errorf("wait = %v", holder.Steps[1].Duration)
+	}
+
+	other := r.flows[1].(*NonTxFlow)
+	if other.Steps[0].Type != StepAwait || other.Steps[0].Barrier != "LOCKED" {
+		t.Errorf("await step = %+v", other.Steps[0])
+	}
+	if other.Steps[1].Repeat != 2 || other.Steps[1].Timing == nil || other.Steps[1].Timing.After != "HOLDER/LOCK" {
+		t.Errorf("update step = %+v", other.Steps[1])
+	}
+	if err := r.declareBarriers(); err != nil {
+		t.Errorf("declareBarriers: %v", err)
+	}
+}
+
+func TestLoadScenario_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"no flows", `flows: []`, "no flows"},
+		{"missing name", `flows: [{steps: []}]`, "name is required"},
+		{"duplicate", `flows: [{name: A}, {name: A}]`, "duplicate"},
+		{"unknown type", `flows: [{name: A, steps: [{type: sing}]}]`, "unknown type"},
+		{"missing sql", `flows: [{name: A, steps: [{type: update}]}]`, "sql is required"},
+		{"savepoint non-tx", `flows: [{name: A, non_tx: true, steps: [{type: savepoint, savepoint: S}]}]`, "transactional"},
+		{"bad lock", `flows: [{name: A, steps: [{type: query, sql: x, lock: maybe}]}]`, "unknown lock"},
+		{"lock on update", `flows: [{name: A, steps: [{type: update, sql: x, lock: nowait}]}]`, "only valid on query"},
+		{"bad isolation", `flows: [{name: A, isolation: dirty}]`, "unknown isolation"},
+		{"bad timing ref", `flows: [{name: A, steps: [{type: update, sql: x, timing: {after: B/X}}]}]`, "timing.after"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadScenario(writeScenario(t, tt.body))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
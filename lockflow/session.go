@@ -0,0 +1,72 @@
+package lockflow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// SessionInfo identifies the Oracle session a flow ran in, for joining against AWR/ASH and V$ views
+type SessionInfo struct {
+	SID    int64
+	Serial int64
+	AUDSID int64 // V$SESSION.AUDSID = SYS_CONTEXT('USERENV', 'SESSIONID')
+}
+
+func (s SessionInfo) String() string {
+	return fmt.Sprintf("sid=%d serial#=%d audsid=%d", s.SID, s.Serial, s.AUDSID)
+}
+
+// sessionIdentity reads the identity of the session running tx.
+// DBMS_DEBUG_JDWP.CURRENT_SESSION_SERIAL avoids needing SELECT on V$SESSION.
+func sessionIdentity(ctx context.Context, tx *sql.Tx) (SessionInfo, error) {
+	var s SessionInfo
+	err := tx.QueryRowContext(ctx, `SELECT TO_NUMBER(SYS_CONTEXT('USERENV', 'SID')),
+	       DBMS_DEBUG_JDWP.CURRENT_SESSION_SERIAL,
+	       TO_NUMBER(SYS_CONTEXT('USERENV', 'SESSIONID'))
+	  FROM DUAL`).Scan(&s.SID, &s.Serial, &s.AUDSID)
+	return s, err
+}
+
+// sessionRegistry maps flow names to the session they ran in
+type sessionRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]SessionInfo
+}
+
+func newSessionRegistry() *sessionRegistry {
+	return &sessionRegistry{sessions: make(map[string]SessionInfo)}
+}
+
+func (r *sessionRegistry) set(flow string, s SessionInfo) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[flow] = s
+}
+
+func (r *sessionRegistry) get(flow string) (SessionInfo, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[flow]
+	return s, ok
+}
+
+// RenderSessions prints the session of every transactional flow
+func RenderSessions(flows []string, sessions *sessionRegistry) {
+	fmt.Println("\n=== Sessions ===")
+	names := append([]string(nil), flows...)
+	sort.Strings(names)
+	for _, name := range names {
+		s, ok := sessions.get(name)
+		if !ok {
+			fmt.Printf("  %-10s  (autocommit or not started: no single session)\n", name)
+			continue
+		}
+		fmt.Printf("  %-10s  SID %-6d SERIAL# %-6d AUDSID %d\n", name, s.SID, s.Serial, s.AUDSID)
+	}
+}
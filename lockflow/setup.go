@@ -1,4 +1,4 @@
-package main
+package lockflow
 
 import (
 	"context"
@@ -38,9 +38,12 @@ func CreateTables(ctx context.Context, db *sql.DB) error {
 			early_data VARCHAR2(50)
 		)`,
 		`CREATE TABLE EVENT_LOG (
-			ts  TIMESTAMP(3) DEFAULT SYSTIMESTAMP,
-			who VARCHAR2(50),
-			msg VARCHAR2(4000)
+			ts        TIMESTAMP(3) DEFAULT SYSTIMESTAMP,
+			who       VARCHAR2(50),
+			msg       VARCHAR2(4000),
+			sid       NUMBER,
+			serial_no NUMBER,
+			audsid    NUMBER
 		)`,
 	}
 
@@ -0,0 +1,76 @@
+package lockflow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ServerStats is a snapshot (or delta) of a session's server-side counters.
+// Reading them requires SELECT on V$SESS_TIME_MODEL, V$SESSION_EVENT and V$SESSION.
+type ServerStats struct {
+	DBTime    time.Duration // V$SESS_TIME_MODEL 'DB time'
+	CPU       time.Duration // V$SESS_TIME_MODEL 'DB CPU'
+	LockWait  time.Duration // V$SESSION_EVENT enqueue waits ('enq: %')
+	OtherWait time.Duration // remaining non-idle waits
+	SQLID     string        // V$SESSION.PREV_SQL_ID: the step's statement
+}
+
+// serverStatsQuery binds the SID once; the counters read it from the outer row
+const serverStatsQuery = `
+SELECT (SELECT NVL(SUM(value), 0) FROM v$sess_time_model m WHERE m.sid = s.sid AND m.stat_name = 'DB time'),
+       (SELECT NVL(SUM(value), 0) FROM v$sess_time_model m WHERE m.sid = s.sid AND m.stat_name = 'DB CPU'),
+       (SELECT NVL(SUM(time_waited_micro), 0) FROM v$session_event e WHERE e.sid = s.sid AND e.event LIKE 'enq: %'),
+       (SELECT NVL(SUM(time_waited_micro), 0) FROM v$session_event e WHERE e.sid = s.sid AND e.wait_class <> 'Idle' AND e.event NOT LIKE 'enq: %'),
+       (SELECT NVL(prev_sql_id, '-') FROM v$session v WHERE v.sid = s.sid)
+  FROM (SELECT :1 AS sid FROM DUAL) s`
+
+// readServerStats snapshots the counters of session sid through tx.
+// The stats query itself adds a little DB time; it is small next to lock waits.
+func readServerStats(ctx context.Context, tx *sql.Tx, sid int64) (ServerStats, error) {
+	var dbTime, cpu, lockWait, otherWait int64
+	var s ServerStats
+	err := tx.QueryRowContext(ctx, serverStatsQuery, sid).Scan(&dbTime, &cpu, &lockWait, &otherWait, &s.SQLID)
+	if err != nil {
+		return s, err
+	}
+	s.DBTime = time.Duration(dbTime) * time.Microsecond
+	s.CPU = time.Duration(cpu) * time.Microsecond
+	s.LockWait = time.Duration(lockWait) * time.Microsecond
+	s.OtherWait = time.Duration(otherWait) * time.Microsecond
+	return s, nil
+}
+
+// sub returns the counter deltas from before to s; SQLID is taken from s
+func (s ServerStats) sub(before ServerStats) ServerStats {
+	return ServerStats{
+		DBTime:    s.DBTime - before.DBTime,
+		CPU:       s.CPU - before.CPU,
+		LockWait:  s.LockWait - before.LockWait,
+		OtherWait: s.OtherWait - before.OtherWait,
+		SQLID:     s.SQLID,
+	}
+}
+
+// RenderServerStats splits each step's client-side duration into server execution and lock wait
+func RenderServerStats(results []StepResult) {
+	var lines []string
+	for _, r := range results {
+		if r.Stats == nil {
+			continue
+		}
+		s := r.Stats
+		lines = append(lines, fmt.Sprintf("  %-10s #%-2d %-36s %9v %9v %9v %9v %9v  %s",
+			r.Flow, r.Index, r.Label,
+			r.Duration.Round(time.Millisecond), s.DBTime.Round(time.Millisecond), s.CPU.Round(time.Millisecond),
+			s.LockWait.Round(time.Millisecond), s.OtherWait.Round(time.Millisecond), s.SQLID))
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Println("\n=== Server-side Step Statistics ===")
+	fmt.Printf("  %-10s %-3s %-36s %9s %9s %9s %9s %9s  %s\n", "FLOW", "#", "STEP", "CLIENT", "DB TIME", "CPU", "LOCK WAIT", "OTHER", "SQL_ID")
+	fmt.Println(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,85 @@
+package lockflow
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+)
+
+func TestTxFlow_ServerStats(t *testing.T) {
+	f := sqlfake.New(t)
+	f.OnQuery(`SYS_CONTEXT\('USERENV', 'SID'\)`, []string{"SID", "SERIAL", "AUDSID"}, []any{int64(41), int64(7), int64(9001)})
+	f.OnQuery(`v\$sess_time_model`, []string{"DB_TIME", "CPU", "LOCK_WAIT", "OTHER_WAIT", "SQL_ID"},
+		[]any{int64(2000), int64(1000), int64(500), int64(0), "abc123"})
+
+	r := NewRunner(f.DB)
+	r.EnableServerStats()
+	r.AddTxFlow("F1").AddUpdate("B", "Update B", "UPDATE B SET data = 'x' WHERE id = 1")
+	if err := r.RunAll(context.Background()); err != nil {
+		t.Fatalf("RunAll: %v", err)
+	}
+	r.Close()
+
+	placeholder := regexp.MustCompile(`:\d+`)
+	reads := 0
+	for _, c := range f.Calls() {
+		if c.Query != serverStatsQuery {
+			continue
+		}
+		reads++
+		// every placeholder needs its own argument
+		if n := len(placeholder.FindAllString(c.Query, -1)); n != len(c.Args) || c.Args[0] != int64(41) {
+			t.Errorf("stats query has %d placeholders, bound %v", n, c.Args)
+		}
+	}
+	if reads != 2 {
+		t.Errorf("stats read %d times, want before and after the step", reads)
+	}
+	results := r.results.Results()
+	if len(results) != 1 || results[0].Stats == nil {
+		t.Fatalf("results = %+v, want the step with stats", results)
+	}
+	if got := *results[0].Stats; got != (ServerStats{SQLID: "abc123"}) {
+		t.Errorf("stats = %+v, want no change between the reads and SQL_ID abc123", got)
+	}
+}
+
+func TestTxFlow_ServerStatsUnavailable(t *testing.T) {
+	f := sqlfake.New(t)
+	f.OnQuery(`SYS_CONTEXT\('USERENV', 'SID'\)`, []string{"SID", "SERIAL", "AUDSID"}, []any{int64(41), int64(7), int64(9001)})
+	f.Fail(`v\$sess_time_model`, errors.New("ORA-00942: table or view does not exist"))
+
+	r := NewRunner(f.DB)
+	r.EnableServerStats()
+	r.AddTxFlow("F1").
+		AddUpdate("B", "Update B", "UPDATE B SET data = 'x' WHERE id = 1").
+		AddUpdate("C", "Update C", "UPDATE C SET data = 'x' WHERE id = 1")
+	if err := r.RunAll(context.Background()); err != nil {
+		t.Fatalf("RunAll: %v", err)
+	}
+	r.Close()
+
+	// one warning, and no further reads once the first failed
+	warnings := 0
+	for _, e := range r.logger.entries {
+		if strings.HasPrefix(e.msg, "WARN: server stats unavailable: ORA-00942") {
+			warnings++
+		}
+	}
+	if warnings != 1 {
+		t.Errorf("%d stats warnings, want 1", warnings)
+	}
+	reads := 0
+	for _, c := range f.Calls() {
+		if c.Query == serverStatsQuery {
+			reads++
+		}
+	}
+	if reads != 1 {
+		t.Errorf("stats read %d times, want 1", reads)
+	}
+}
@@ -0,0 +1,126 @@
+package lockflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLockOption_Apply(t *testing.T) {
+	tests := []struct {
+		name  string
+		opt   LockOption
+		query string
+		want  string
+	}{
+		{"default unchanged", LockOption{}, "SELECT id FROM A", "SELECT id FROM A"},
+		{"nowait adds for update", NoWait(), "SELECT id FROM A WHERE id = 1", "SELECT id FROM A WHERE id = 1 FOR UPDATE NOWAIT"},
+		{"existing for update kept", NoWait(), "SELECT id FROM A FOR UPDATE", "SELECT id FROM A FOR UPDATE NOWAIT"},
+		{"for update of column", SkipLocked(), "SELECT id FROM A FOR UPDATE OF data;", "SELECT id FROM A FOR UPDATE OF data SKIP LOCKED"},
+		{"wait rounds to seconds", WaitFor(1400 * time.Millisecond), "select id from a", "select id from a FOR UPDATE WAIT 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opt.apply(tt.query); got != tt.want {
+				t.Errorf("apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockOption_Tolerates(t *testing.T) {
+	busy := errors.New("ORA-00054: resource busy")
+	timeout := errors.New("ORA-30006: resource busy; acquire with WAIT timeout expired")
+
+	if !NoWait().tolerates(busy) || NoWait().tolerates(timeout) {
+		t.Error("NOWAIT should tolerate ORA-00054 only")
+	}
+	if !WaitFor(time.Second).tolerates(timeout) || WaitFor(time.Second).tolerates(busy) {
+		t.Error("WAIT n should tolerate ORA-30006 only")
+	}
+	if (LockOption{}).tolerates(busy) || SkipLocked().tolerates(nil) {
+		t.Error("default and SKIP LOCKED should tolerate nothing")
+	}
+}
+
+func TestExpandSteps(t *testing.T) {
+	steps := []Step{
+		{Type: StepSQL, Label: "Insert {i}", SQL: "INSERT INTO B VALUES (:1, :2)", Args: []interface{}{"{i}", "B_{i}", 7}, Repeat: 3},
+		{Type: StepWait, Label: "Sleep"},
+		{Type: StepSQL, Label: "Update", SQL: "UPDATE A SET n = {i}", Repeat: 2},
+	}
+	got := expandSteps(steps)
+	if len(got) != 6 {
+		t.Fatalf("len = %d, want 6", len(got))
+	}
+	if got[2].Label != "Insert 3" || got[2].Args[0] != 3 || got[2].Args[1] != "B_3" || got[2].Args[2] != 7 {
+		t.Errorf("iteration 3 = %+v", got[2])
+	}
+	if got[3].Label != "Sleep" || got[3].Repeat != 0 {
+		t.Errorf("non-repeated step = %+v", got[3])
+	}
+	if got[5].Label != "Update [2/2]" || got[5].SQL != "UPDATE A SET n = 2" {
+		t.Errorf("labelled iteration = %+v", got[5])
+	}
+	// The original bind slice must not be modified
+	if steps[0].Args[0] != "{i}" {
+		t.Errorf("source args modified: %v", steps[0].Args)
+	}
+}
+
+func TestExpectation_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		exp      Expectation
+		dur      time.Duration
+		affected int64
+		err      error
+		failures int
+	}{
+		{"rows match", Expectation{RowsAffected: Rows(2)}, 0, 2, nil, 0},
+		{"rows differ", Expectation{RowsAffected: Rows(2)}, 0, 1, nil, 1},
+		{"unexpected error", Expectation{}, 0, 0, errors.New("ORA-00054"), 1},
+		{"expected error", Expectation{Error: "ORA-00054"}, 0, 0, errors.New("ORA-00054: busy"), 0},
+		{"wrong error", Expectation{Error: "ORA-00054"}, 0, 0, errors.New("ORA-00060"), 1},
+		{"missing error", Expectation{Error: "ORA-00054"}, 0, 0, nil, 1},
+		{"too slow", Expectation{MaxDuration: time.Second}, 2 * time.Second, 0, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exp.check(tt.dur, tt.affected, tt.err); len(got) != tt.failures {
+				t.Errorf("check() = %v, want %d failures", got, tt.failures)
+			}
+		})
+	}
+}
+
+func TestEvaluateTimings(t *testing.T) {
+	start := time.Now()
+	steps := map[string][]Step{
+		"A": {
+			{Label: "on time", Timing: &Timing{StartAt: Offset(time.Second)}},
+			{Label: "late", Timing: &Timing{StartAt: Offset(time.Second), Tolerance: 100 * time.Millisecond}},
+			{Label: "never", Timing: &Timing{StartAt: Offset(0)}},
+		},
+		"B": {
+			{Label: "after commit", Timing: &Timing{After: "A/COMMIT"}},
+		},
+	}
+	results := []StepResult{
+		{Flow: "A", Index: 1, Start: start.Add(1100 * time.Millisecond), Duration: 10 * time.Millisecond},
+		{Flow: "A", Index: 2, Start: start.Add(1500 * time.Millisecond)},
+		{Flow: "B", Index: 1, Start: start.Add(time.Second), Duration: time.Second},
+	}
+	commits := map[string]time.Time{"A": start.Add(3 * time.Second)}
+
+	checks := evaluateTimings(start, []string{"A", "B"}, steps, results, commits)
+	want := map[string]bool{"on time": true, "late": false, "never": false, "after commit": false}
+	if len(checks) != len(want) {
+		t.Fatalf("got %d checks, want %d", len(checks), len(want))
+	}
+	for _, c := range checks {
+		if pass := len(c.Failures) == 0; pass != want[c.Label] {
+			t.Errorf("%s: pass = %v, want %v (%v)", c.Label, pass, want[c.Label], c.Failures)
+		}
+	}
+}
@@ -0,0 +1,520 @@
+package lockflow
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimelineEvent represents a single operation event (start, end, or commit)
+type TimelineEvent struct {
+	Flow      string // "CHAIN" or "EARLY"
+	Table     string // "A", "B", "C", or "" for COMMIT
+	EventType string // "START", "END", or "COMMIT"
+	Time      time.Time
+}
+
+// TimelineTracker collects timeline events from multiple goroutines
+type TimelineTracker struct {
+	mu     sync.Mutex
+	events []TimelineEvent
+	start  time.Time
+	flows  []string // display order, as registered by the Runner
+	render TimelineOptions
+}
+
+// NewTimelineTracker creates a new timeline tracker
+func NewTimelineTracker(startTime time.Time) *TimelineTracker {
+	return &TimelineTracker{
+		events: make([]TimelineEvent, 0),
+		start:  startTime,
+	}
+}
+
+// RegisterFlow adds a flow to the display order of the rendered timeline
+func (t *TimelineTracker) RegisterFlow(flow string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.flows = append(t.flows, flow)
+}
+
+// Reset clears recorded events and moves T=0 to startTime
+func (t *TimelineTracker) Reset(startTime time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = t.events[:0]
+	t.start = startTime
+}
+
+// Events returns a copy of the recorded events and the T=0 they are relative to
+func (t *TimelineTracker) Events() ([]TimelineEvent, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]TimelineEvent, len(t.events))
+	copy(out, t.events)
+	return out, t.start
+}
+
+// RecordStart records the start of an operation
+func (t *TimelineTracker) RecordStart(flow, table string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, TimelineEvent{
+		Flow:      flow,
+		Table:     table,
+		EventType: "START",
+		Time:      time.Now(),
+	})
+}
+
+// RecordExpected records the declared expected start time of an operation
+func (t *TimelineTracker) RecordExpected(flow, table string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, TimelineEvent{
+		Flow:      flow,
+		Table:     table,
+		EventType: "EXPECTED",
+		Time:      at,
+	})
+}
+
+// RecordEnd records the end of an operation
+func (t *TimelineTracker) RecordEnd(flow, table string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, TimelineEvent{
+		Flow:      flow,
+		Table:     table,
+		EventType: "END",
+		Time:      time.Now(),
+	})
+}
+
+// RecordCommit records a transaction commit event
+func (t *TimelineTracker) RecordCommit(flow string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, TimelineEvent{
+		Flow:      flow,
+		Table:     "", // No table for commit events
+		EventType: "COMMIT",
+		Time:      time.Now(),
+	})
+}
+
+// RecordRollback records a transaction rollback event
+func (t *TimelineTracker) RecordRollback(flow string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, TimelineEvent{
+		Flow:      flow,
+		Table:     "",
+		EventType: "ROLLBACK",
+		Time:      time.Now(),
+	})
+}
+
+// RecordRollbackTo records a partial rollback to a savepoint
+func (t *TimelineTracker) RecordRollbackTo(flow string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, TimelineEvent{
+		Flow:      flow,
+		Table:     "",
+		EventType: "ROLLBACK_TO",
+		Time:      time.Now(),
+	})
+}
+
+// RecordImplicitCommit records a commit caused by DDL inside a transaction
+func (t *TimelineTracker) RecordImplicitCommit(flow string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, TimelineEvent{
+		Flow:      flow,
+		Table:     "",
+		EventType: "IMPLICIT_COMMIT",
+		Time:      time.Now(),
+	})
+}
+
+// RecordDeadlock records that the flow received ORA-00060
+func (t *TimelineTracker) RecordDeadlock(flow string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, TimelineEvent{
+		Flow:      flow,
+		Table:     "",
+		EventType: "DEADLOCK",
+		Time:      time.Now(),
+	})
+}
+
+// RecordKilled records that the chaos injector killed the flow's session
+func (t *TimelineTracker) RecordKilled(flow string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, TimelineEvent{
+		Flow:      flow,
+		Table:     "",
+		EventType: "KILLED",
+		Time:      time.Now(),
+	})
+}
+
+// RecordBlocked records an interval in which Oracle reported flow waiting on blocker.
+// It is drawn on a separate "<flow> BLOCKED" lane below the flow.
+func (t *TimelineTracker) RecordBlocked(flow, blocker string, start, end time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lane := flow + " BLOCKED"
+	t.events = append(t.events,
+		TimelineEvent{Flow: lane, Table: blocker, EventType: "START", Time: start},
+		TimelineEvent{Flow: lane, Table: blocker, EventType: "END", Time: end},
+	)
+}
+
+// Segment represents a time segment for a table operation
+type Segment struct {
+	Table string
+	Start float64 // seconds from demo start
+	End   float64 // seconds from demo start
+}
+
+// FlowTimeline represents all segments for one flow
+type FlowTimeline struct {
+	Flow     string
+	Segments []Segment
+}
+
+// timelineLayout is the renderer-independent view of the recorded events,
+// shared by the ASCII renderer and the SVG/HTML exporter
+type timelineLayout struct {
+	Timelines     []FlowTimeline
+	CommitTimes   map[string]float64          // flow -> commit time in seconds
+	RollbackTimes map[string]float64          // flow -> rollback time in seconds
+	MarkerTimes   map[string]map[float64]rune // flow -> in-transaction markers
+	TotalDuration float64
+}
+
+// layout pairs START/END events into segments and collects markers; nil if nothing was recorded
+func (t *TimelineTracker) layout(showExpected bool) *timelineLayout {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.events) == 0 {
+		return nil
+	}
+
+	// Sort events by time
+	sort.Slice(t.events, func(i, j int) bool {
+		return t.events[i].Time.Before(t.events[j].Time)
+	})
+
+	// Build segments for each flow and collect commit/rollback times
+	flowSegments := make(map[string][]Segment)
+	commitTimes := make(map[string]float64)          // flow -> commit time in seconds
+	rollbackTimes := make(map[string]float64)        // flow -> rollback time in seconds
+	markerTimes := make(map[string]map[float64]rune) // flow -> in-transaction markers (rollback to savepoint, implicit commit)
+	pending := make(map[string]map[string]time.Time)
+
+	for _, event := range t.events {
+		// Handle EXPECTED events separately
+		if event.EventType == "EXPECTED" {
+			tSec := event.Time.Sub(t.start).Seconds()
+			targetFlow := event.Flow + " EXPECTED"
+			flowSegments[targetFlow] = append(flowSegments[targetFlow], Segment{
+				Table: event.Table,
+				Start: tSec,
+				End:   tSec, // Point segment
+			})
+			continue
+		}
+
+		if pending[event.Flow] == nil {
+			pending[event.Flow] = make(map[string]time.Time)
+		}
+
+		if event.EventType == "START" {
+			pending[event.Flow][event.Table] = event.Time
+		} else if event.EventType == "END" {
+			if startTime, ok := pending[event.Flow][event.Table]; ok {
+				startSec := startTime.Sub(t.start).Seconds()
+				endSec := event.Time.Sub(t.start).Seconds()
+
+				flowSegments[event.Flow] = append(flowSegments[event.Flow], Segment{
+					Table: event.Table,
+					Start: startSec,
+					End:   endSec,
+				})
+				delete(pending[event.Flow], event.Table)
+			}
+		} else if event.EventType == "COMMIT" {
+			commitTimes[event.Flow] = event.Time.Sub(t.start).Seconds()
+		} else if event.EventType == "ROLLBACK" {
+			rollbackTimes[event.Flow] = event.Time.Sub(t.start).Seconds()
+		} else if event.EventType == "ROLLBACK_TO" || event.EventType == "IMPLICIT_COMMIT" || event.EventType == "DEADLOCK" || event.EventType == "KILLED" {
+			if markerTimes[event.Flow] == nil {
+				markerTimes[event.Flow] = make(map[float64]rune)
+			}
+			marker := 'r'
+			switch event.EventType {
+			case "IMPLICIT_COMMIT":
+				marker = 'I'
+			case "DEADLOCK":
+				marker = 'D'
+			case "KILLED":
+				marker = 'K'
+			}
+			markerTimes[event.Flow][event.Time.Sub(t.start).Seconds()] = marker
+		}
+	}
+
+	// Calculate total duration (find the latest event time, including commits)
+	var maxTime time.Time
+	for _, event := range t.events {
+		if event.Time.After(maxTime) {
+			maxTime = event.Time
+		}
+	}
+	totalDuration := maxTime.Sub(t.start).Seconds()
+
+	// Create ordered flow list
+	var displayFlows []string
+	for _, flow := range t.flows {
+		if showExpected {
+			displayFlows = append(displayFlows, flow+" EXPECTED")
+		}
+		displayFlows = append(displayFlows, flow, flow+" BLOCKED")
+	}
+	timelines := make([]FlowTimeline, 0)
+	for _, flowName := range displayFlows {
+		if segs, ok := flowSegments[flowName]; ok {
+			// Sort segments by start time
+			sort.SliceStable(segs, func(i, j int) bool {
+				return segs[i].Start < segs[j].Start
+			})
+			timelines = append(timelines, FlowTimeline{
+				Flow:     flowName,
+				Segments: segs,
+			})
+		}
+	}
+
+	return &timelineLayout{
+		Timelines:     timelines,
+		CommitTimes:   commitTimes,
+		RollbackTimes: rollbackTimes,
+		MarkerTimes:   markerTimes,
+		TotalDuration: totalDuration,
+	}
+}
+
+// TimelineOptions controls the ASCII rendering
+type TimelineOptions struct {
+	Width      int           // characters for the time axis; ignored when Resolution is set (default 60)
+	Resolution time.Duration // time per character, e.g. 100ms for sub-second detail
+}
+
+// maxTimelineWidth caps the axis when a fine Resolution meets a long run
+const maxTimelineWidth = 400
+
+// SetRenderOptions configures RenderTimeline
+func (t *TimelineTracker) SetRenderOptions(opts TimelineOptions) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.render = opts
+}
+
+// columns returns the axis width and seconds per column for a run of totalDuration seconds
+func (o TimelineOptions) columns(totalDuration float64) (int, float64) {
+	width := o.Width
+	if width <= 0 {
+		width = 60
+	}
+	if o.Resolution > 0 {
+		width = int(math.Ceil(totalDuration/o.Resolution.Seconds())) + 1
+	}
+	if width > maxTimelineWidth {
+		width = maxTimelineWidth
+	}
+	if width < 10 {
+		width = 10
+	}
+	if totalDuration <= 0 {
+		return width, 1
+	}
+	return width, totalDuration / float64(width-1)
+}
+
+// RenderTimeline generates and prints an ASCII timeline graph
+func (t *TimelineTracker) RenderTimeline(showExpected bool) {
+	l := t.layout(showExpected)
+	if l == nil {
+		fmt.Println("No timeline events recorded.")
+		return
+	}
+	t.mu.Lock()
+	opts := t.render
+	t.mu.Unlock()
+
+	fmt.Println("\n=== Timeline Graph ===")
+	fmt.Printf("Total duration: %.2f seconds\n\n", l.TotalDuration)
+	for _, line := range l.ascii(opts) {
+		fmt.Println(line)
+	}
+}
+
+// ascii renders the layout as lines of text. Overlapping operations of one flow are drawn on
+// extra lanes below it instead of being shifted in time.
+func (l *timelineLayout) ascii(opts TimelineOptions) []string {
+	width, secPerCol := opts.columns(l.TotalDuration)
+	col := func(sec float64) int {
+		c := int(math.Floor(sec/secPerCol + 1e-9)) // tolerate float error at exact boundaries
+		if c < 0 {
+			return 0
+		}
+		if c >= width {
+			return width - 1
+		}
+		return c
+	}
+
+	nameW := 15
+	for _, tl := range l.Timelines {
+		if n := len([]rune(tl.Flow)); n > nameW {
+			nameW = n
+		}
+	}
+
+	var out []string
+	used := make(map[rune]bool)
+	for _, tl := range l.Timelines {
+		// Assign each segment to the first lane where it does not overlap the previous one
+		var lanes [][]rune
+		var laneEnd []int
+		for _, seg := range tl.Segments {
+			if seg.Table == "SLEEP" {
+				continue
+			}
+			start := col(seg.Start)
+			end := col(seg.End)
+			if end < start {
+				end = start
+			}
+			lane := -1
+			for i, e := range laneEnd {
+				if start > e {
+					lane = i
+					break
+				}
+			}
+			if lane < 0 {
+				lanes = append(lanes, newTimelineLane(width))
+				laneEnd = append(laneEnd, -1)
+				lane = len(lanes) - 1
+			}
+			label := []rune(seg.Table)
+			if len(label) == 0 {
+				label = []rune{'#'}
+			}
+			for i := start; i <= end; i++ {
+				lanes[lane][i] = label[(i-start)%len(label)]
+			}
+			laneEnd[lane] = end
+		}
+		if len(lanes) == 0 {
+			lanes = append(lanes, newTimelineLane(width))
+		}
+
+		// Markers go on the first lane, drawn last so they are never hidden
+		first := lanes[0]
+		if ct, ok := l.CommitTimes[tl.Flow]; ok {
+			first[col(ct)] = 'X'
+			used['X'] = true
+		}
+		if rt, ok := l.RollbackTimes[tl.Flow]; ok {
+			first[col(rt)] = 'R'
+			used['R'] = true
+		}
+		for mt, m := range l.MarkerTimes[tl.Flow] {
+			first[col(mt)] = m
+			used[m] = true
+		}
+
+		for i, lane := range lanes {
+			name := tl.Flow
+			if i > 0 {
+				name = "  +lane " + fmt.Sprint(i+1)
+			}
+			out = append(out, fmt.Sprintf("%-*s %s", nameW, name, string(lane)))
+		}
+	}
+
+	out = append(out, timelineAxis(nameW, width, secPerCol)...)
+	out = append(out, "", timelineLegend(used))
+	return out
+}
+
+func newTimelineLane(width int) []rune {
+	lane := make([]rune, width)
+	for i := range lane {
+		lane[i] = '-'
+	}
+	return lane
+}
+
+// timelineAxis draws a tick every 10 columns with its time underneath
+func timelineAxis(nameW, width int, secPerCol float64) []string {
+	ticks := make([]rune, width)
+	labels := make([]rune, width+8)
+	for i := range ticks {
+		ticks[i] = ' '
+	}
+	for i := range labels {
+		labels[i] = ' '
+	}
+	format := "%.1fs"
+	if secPerCol*10 < 1 {
+		format = "%.2fs"
+	}
+	for c := 0; c < width; c += 10 {
+		ticks[c] = '|'
+		for i, r := range fmt.Sprintf(format, float64(c)*secPerCol) {
+			if c+i < len(labels) {
+				labels[c+i] = r
+			}
+		}
+	}
+	pad := fmt.Sprintf("%-*s ", nameW, "")
+	return []string{
+		pad + string(ticks),
+		pad + strings.TrimRight(string(labels), " ") + fmt.Sprintf("   (1 char = %v)", time.Duration(secPerCol*float64(time.Second)).Round(time.Millisecond)),
+	}
+}
+
+// timelineLegend explains the markers that appear in the graph
+func timelineLegend(used map[rune]bool) string {
+	entries := []struct {
+		r    rune
+		text string
+	}{
+		{'X', "commit"},
+		{'R', "rollback"},
+		{'r', "rollback to savepoint"},
+		{'I', "implicit commit (DDL)"},
+		{'D', "deadlock victim"},
+		{'K', "session killed"},
+	}
+	parts := []string{"letters = table being accessed", "- = idle/waiting"}
+	for _, e := range entries {
+		if used[e.r] {
+			parts = append(parts, fmt.Sprintf("%c = %s", e.r, e.text))
+		}
+	}
+	return "Legend: " + strings.Join(parts, ", ")
+}
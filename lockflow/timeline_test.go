@@ -0,0 +1,41 @@
+package lockflow
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimelineLayout_ASCII(t *testing.T) {
+	start := time.Now()
+	tr := NewTimelineTracker(start)
+	tr.RegisterFlow("F")
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	tr.mu.Lock()
+	tr.events = append(tr.events,
+		TimelineEvent{Flow: "F", Table: "A", EventType: "START", Time: at(0)},
+		TimelineEvent{Flow: "F", Table: "B", EventType: "START", Time: at(200)}, // overlaps A
+		TimelineEvent{Flow: "F", Table: "A", EventType: "END", Time: at(500)},
+		TimelineEvent{Flow: "F", Table: "B", EventType: "END", Time: at(700)},
+		TimelineEvent{Flow: "F", EventType: "COMMIT", Time: at(900)},
+	)
+	tr.mu.Unlock()
+
+	lines := tr.layout(false).ascii(TimelineOptions{Resolution: 100 * time.Millisecond})
+
+	if len(lines) < 2 {
+		t.Fatalf("lines = %q", lines)
+	}
+	lane1 := strings.TrimSpace(strings.TrimPrefix(lines[0], "F"))
+	lane2 := lines[1][strings.LastIndex(lines[1], " ")+1:]
+	if lane1 != "AAAAAA---X" {
+		t.Errorf("lane 1 = %q, want AAAAAA---X", lane1)
+	}
+	if lane2 != "--BBBBBB--" {
+		t.Errorf("lane 2 = %q, want --BBBBBB--", lane2)
+	}
+	if !strings.Contains(lines[len(lines)-1], "X = commit") || strings.Contains(lines[len(lines)-1], "R = rollback") {
+		t.Errorf("legend = %q", lines[len(lines)-1])
+	}
+}
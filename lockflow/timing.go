@@ -0,0 +1,164 @@
+package lockflow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultTimingTolerance is used when a Timing does not set Tolerance
+const defaultTimingTolerance = 250 * time.Millisecond
+
+// Timing declares when a step is expected to run, relative to the start of the run.
+// Zero values are not checked.
+type Timing struct {
+	StartAt   *time.Duration // expected start offset
+	EndAt     *time.Duration // expected end offset
+	Tolerance time.Duration  // allowed deviation for StartAt/EndAt; defaultTimingTolerance if zero
+	After     string         // "FLOW/ID" or "FLOW/COMMIT": this step must not finish before that point
+}
+
+// Offset is a helper for Timing.StartAt and Timing.EndAt
+func Offset(d time.Duration) *time.Duration {
+	return &d
+}
+
+func (t Timing) tolerance() time.Duration {
+	if t.Tolerance > 0 {
+		return t.Tolerance
+	}
+	return defaultTimingTolerance
+}
+
+// timingCheck is the verdict for one step that declared a Timing
+type timingCheck struct {
+	Flow     string
+	Index    int
+	Label    string
+	Timing   Timing
+	Executed bool
+	Start    time.Duration // actual offsets, valid when Executed
+	End      time.Duration
+	Failures []string
+}
+
+// evaluateTimings compares declared timings against what happened.
+// steps are the expanded steps per flow, in registration order; commits maps flow -> commit time.
+func evaluateTimings(start time.Time, flows []string, steps map[string][]Step, results []StepResult, commits map[string]time.Time) []timingCheck {
+	type key struct {
+		flow  string
+		index int
+	}
+	byIndex := make(map[key]StepResult)
+	byID := make(map[string]StepResult)
+	for _, r := range results {
+		byIndex[key{r.Flow, r.Index}] = r
+		if r.ID != "" {
+			byID[r.Flow+"/"+r.ID] = r
+		}
+	}
+
+	var checks []timingCheck
+	for _, flow := range flows {
+		for i, st := range steps[flow] {
+			if st.Timing == nil {
+				continue
+			}
+			c := timingCheck{Flow: flow, Index: i + 1, Label: st.Label, Timing: *st.Timing}
+			r, ok := byIndex[key{flow, i + 1}]
+			if !ok {
+				c.Failures = append(c.Failures, "step never ran")
+				checks = append(checks, c)
+				continue
+			}
+			c.Executed = true
+			c.Start = r.Start.Sub(start)
+			c.End = r.Start.Add(r.Duration).Sub(start)
+			tol := c.Timing.tolerance()
+
+			if at := c.Timing.StartAt; at != nil {
+				if dev := c.Start - *at; dev > tol || dev < -tol {
+					c.Failures = append(c.Failures, fmt.Sprintf("started at %v, expected %v ±%v (off by %+v)",
+						c.Start.Round(time.Millisecond), *at, tol, dev.Round(time.Millisecond)))
+				}
+			}
+			if at := c.Timing.EndAt; at != nil {
+				if dev := c.End - *at; dev > tol || dev < -tol {
+					c.Failures = append(c.Failures, fmt.Sprintf("ended at %v, expected %v ±%v (off by %+v)",
+						c.End.Round(time.Millisecond), *at, tol, dev.Round(time.Millisecond)))
+				}
+			}
+			if ref := c.Timing.After; ref != "" {
+				refEnd, found := timingRefEnd(ref, byID, commits)
+				switch {
+				case !found:
+					c.Failures = append(c.Failures, fmt.Sprintf("reference %s never happened", ref))
+				case r.Start.Add(r.Duration).Before(refEnd):
+					c.Failures = append(c.Failures, fmt.Sprintf("finished %v before %s",
+						refEnd.Sub(r.Start.Add(r.Duration)).Round(time.Millisecond), ref))
+				}
+			}
+			checks = append(checks, c)
+		}
+	}
+	return checks
+}
+
+// timingRefEnd resolves a "FLOW/ID" or "FLOW/COMMIT" reference to the time it completed
+func timingRefEnd(ref string, byID map[string]StepResult, commits map[string]time.Time) (time.Time, bool) {
+	if flow, ok := strings.CutSuffix(ref, "/COMMIT"); ok {
+		t, found := commits[flow]
+		return t, found
+	}
+	r, ok := byID[ref]
+	if !ok || r.Err != nil {
+		return time.Time{}, false
+	}
+	return r.Start.Add(r.Duration), true
+}
+
+// RenderTimingDiff prints expected vs actual offsets for every step that declared a Timing
+func RenderTimingDiff(checks []timingCheck) {
+	if len(checks) == 0 {
+		return
+	}
+	failed := 0
+	fmt.Println("\n=== Expected vs Actual Timeline ===")
+	for _, c := range checks {
+		status := "PASS"
+		if len(c.Failures) > 0 {
+			status = "FAIL"
+			failed++
+		}
+		actual := "not run"
+		if c.Executed {
+			actual = fmt.Sprintf("%6.2fs..%6.2fs", c.Start.Seconds(), c.End.Seconds())
+		}
+		fmt.Printf("  [%s] %-10s #%-2d %-40s %s  %s\n", status, c.Flow, c.Index, c.Label, actual, c.Timing.describe())
+		for _, f := range c.Failures {
+			fmt.Printf("         - %s\n", f)
+		}
+	}
+	verdict := "PASS"
+	if failed > 0 {
+		verdict = "FAIL"
+	}
+	fmt.Printf("Timeline: %d checked, %d deviated -> %s\n", len(checks), failed, verdict)
+}
+
+func (t Timing) describe() string {
+	var parts []string
+	if t.StartAt != nil {
+		parts = append(parts, fmt.Sprintf("start %v", *t.StartAt))
+	}
+	if t.EndAt != nil {
+		parts = append(parts, fmt.Sprintf("end %v", *t.EndAt))
+	}
+	if t.StartAt != nil || t.EndAt != nil {
+		parts = append(parts, fmt.Sprintf("±%v", t.tolerance()))
+	}
+	if t.After != "" {
+		parts = append(parts, "after "+t.After)
+	}
+	return "expected " + strings.Join(parts, ", ")
+}
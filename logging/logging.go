@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Field names shared by the loader packages so log lines can be filtered the same way everywhere
+const (
+	FieldTable    = "table"
+	FieldRows     = "rows"
+	FieldDuration = "duration"
+	FieldFile     = "file"
+	FieldError    = "error"
+	FieldBatch    = "batch"
+	FieldJob      = "job"
+)
+
+type ctxKey struct{}
+
+// WithLogger attaches l to ctx; packages with ctx-only APIs (bulkinsert, bulkload, ...) log through it
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger attached by WithLogger, or slog.Default()
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok && l != nil {
+			return l
+		}
+	}
+	return slog.Default()
+}
+
+// Or returns l, or slog.Default() when l is nil
+func Or(l *slog.Logger) *slog.Logger {
+	if l != nil {
+		return l
+	}
+	return slog.Default()
+}
+
+// Discard returns a logger that drops everything, for tests and quiet callers
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// Config selects the level and output format of a command's logger
+type Config struct {
+	Level  string // debug, info, warn, error
+	Format string // text or json
+}
+
+// RegisterFlags adds -log-level and -log-format to fs, defaulting to LOG_LEVEL and LOG_FORMAT
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Level, "log-level", envOr("LOG_LEVEL", "info"), "Log level: debug, info, warn or error (env LOG_LEVEL)")
+	fs.StringVar(&c.Format, "log-format", envOr("LOG_FORMAT", "text"), "Log format: text or json (env LOG_FORMAT)")
+}
+
+// New builds a logger writing to w
+func New(w io.Writer, c Config) (*slog.Logger, error) {
+	var level slog.Level
+	if c.Level != "" {
+		if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q", c.Level)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(c.Format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q (use text or json)", c.Format)
+	}
+}
+
+// Setup builds a stderr logger and installs it as slog.Default(), which also
+// routes the standard log package through it
+func (c Config) Setup() (*slog.Logger, error) {
+	l, err := New(os.Stderr, c)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(l)
+	return l, nil
+}
+
+func envOr(key, def string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "defaults", cfg: Config{}},
+		{name: "json debug", cfg: Config{Level: "debug", Format: "json"}},
+		{name: "upper case", cfg: Config{Level: "WARN", Format: "TEXT"}},
+		{name: "bad level", cfg: Config{Level: "loud"}, wantErr: true},
+		{name: "bad format", cfg: Config{Format: "xml"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&bytes.Buffer{}, tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLevelFilters(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, Config{Level: "warn", Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("hidden")
+	l.Warn("shown", FieldTable, "T", FieldRows, 3)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, got %d: %q", len(lines), buf.String())
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["msg"] != "shown" || rec[FieldTable] != "T" || rec[FieldRows] != float64(3) {
+		t.Errorf("unexpected record %v", rec)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("expected slog.Default() without a logger")
+	}
+	l := Discard()
+	if FromContext(WithLogger(context.Background(), l)) != l {
+		t.Error("expected the attached logger")
+	}
+	if Or(nil) != slog.Default() || Or(l) != l {
+		t.Error("Or returned the wrong logger")
+	}
+}
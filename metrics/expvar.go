@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Expvar publishes the metrics under one expvar map, served at /debug/vars:
+//
+//	{"rows": {"T": 100}, "batches": {"T": 1}, "errors": {"T/insert": 0},
+//	 "batch_seconds": {"T": {"count": 1, "sum": 0.2, "buckets": {"0.25": 1, ...}}}}
+type Expvar struct {
+	rows, batches, errors, latency *expvar.Map
+
+	mu    sync.Mutex
+	hists map[string]*histogram
+}
+
+// NewExpvar publishes a map named name; like expvar.NewMap it panics if the name is taken
+func NewExpvar(name string) *Expvar {
+	e := newExpvar()
+	root := expvar.NewMap(name)
+	root.Set("rows", e.rows)
+	root.Set("batches", e.batches)
+	root.Set("errors", e.errors)
+	root.Set("batch_seconds", e.latency)
+	return e
+}
+
+func newExpvar() *Expvar {
+	return &Expvar{
+		rows:    new(expvar.Map).Init(),
+		batches: new(expvar.Map).Init(),
+		errors:  new(expvar.Map).Init(),
+		latency: new(expvar.Map).Init(),
+		hists:   make(map[string]*histogram),
+	}
+}
+
+func (e *Expvar) AddRows(table string, n int)   { e.rows.Add(table, int64(n)) }
+func (e *Expvar) IncBatches(table string)       { e.batches.Add(table, 1) }
+func (e *Expvar) IncErrors(table, stage string) { e.errors.Add(table+"/"+stage, 1) }
+
+func (e *Expvar) ObserveBatch(table string, d time.Duration) {
+	e.mu.Lock()
+	h, ok := e.hists[table]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(BatchBuckets))}
+		e.hists[table] = h
+		e.latency.Set(table, h)
+	}
+	e.mu.Unlock()
+	h.observe(d.Seconds())
+}
+
+// histogram keeps cumulative bucket counts like a Prometheus histogram
+type histogram struct {
+	mu     sync.Mutex
+	count  uint64
+	sum    float64
+	counts []uint64 // counts[i]: observations <= BatchBuckets[i]
+}
+
+func (h *histogram) observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.count++
+	h.sum += v
+	for i, b := range BatchBuckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+}
+
+// String implements expvar.Var
+func (h *histogram) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	buckets := make(map[string]uint64, len(BatchBuckets)+1)
+	for i, b := range BatchBuckets {
+		buckets[strconv.FormatFloat(b, 'g', -1, 64)] = h.counts[i]
+	}
+	buckets["+Inf"] = h.count
+	out, _ := json.Marshal(struct {
+		Count   uint64            `json:"count"`
+		Sum     float64           `json:"sum"`
+		Buckets map[string]uint64 `json:"buckets"`
+	}{h.count, h.sum, buckets})
+	return string(out)
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"time"
+)
+
+// Error stages passed to IncErrors
+const (
+	StageRead    = "read"
+	StageConvert = "convert"
+	StageInsert  = "insert"
+	StageRefresh = "refresh"
+)
+
+// Metrics receives loader measurements, labelled by target table.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	AddRows(table string, n int)
+	IncBatches(table string)
+	IncErrors(table, stage string)
+	ObserveBatch(table string, d time.Duration)
+}
+
+// Nop discards everything; it is what loaders use when no Metrics is configured
+type Nop struct{}
+
+func (Nop) AddRows(string, int)                {}
+func (Nop) IncBatches(string)                  {}
+func (Nop) IncErrors(string, string)           {}
+func (Nop) ObserveBatch(string, time.Duration) {}
+
+// Or returns m, or Nop when m is nil
+func Or(m Metrics) Metrics {
+	if m != nil {
+		return m
+	}
+	return Nop{}
+}
+
+type ctxKey struct{}
+
+// WithMetrics attaches m to ctx for packages with ctx-only APIs (bulkinsert)
+func WithMetrics(ctx context.Context, m Metrics) context.Context {
+	return context.WithValue(ctx, ctxKey{}, m)
+}
+
+// FromContext returns the Metrics attached by WithMetrics, or Nop
+func FromContext(ctx context.Context) Metrics {
+	if ctx != nil {
+		if m, ok := ctx.Value(ctxKey{}).(Metrics); ok && m != nil {
+			return m
+		}
+	}
+	return Nop{}
+}
+
+// BatchBuckets are the batch latency histogram bounds, in seconds
+var BatchBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
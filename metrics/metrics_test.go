@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestExpvar(t *testing.T) {
+	e := NewExpvar("metrics_test")
+	e.AddRows("T", 100)
+	e.AddRows("T", 50)
+	e.IncBatches("T")
+	e.IncErrors("T", StageInsert)
+	e.ObserveBatch("T", 200*time.Millisecond)
+	e.ObserveBatch("T", 20*time.Second)
+
+	var got struct {
+		Rows         map[string]int64 `json:"rows"`
+		Batches      map[string]int64 `json:"batches"`
+		Errors       map[string]int64 `json:"errors"`
+		BatchSeconds map[string]struct {
+			Count   uint64            `json:"count"`
+			Sum     float64           `json:"sum"`
+			Buckets map[string]uint64 `json:"buckets"`
+		} `json:"batch_seconds"`
+	}
+	if err := json.Unmarshal([]byte(expvar.Get("metrics_test").String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Rows["T"] != 150 || got.Batches["T"] != 1 || got.Errors["T/insert"] != 1 {
+		t.Errorf("unexpected counters %+v", got)
+	}
+	h := got.BatchSeconds["T"]
+	if h.Count != 2 || h.Sum != 20.2 {
+		t.Errorf("unexpected histogram count/sum %+v", h)
+	}
+	tests := map[string]uint64{"0.1": 0, "0.25": 1, "10": 1, "30": 2, "+Inf": 2}
+	for bucket, want := range tests {
+		if h.Buckets[bucket] != want {
+			t.Errorf("bucket %s = %d, want %d", bucket, h.Buckets[bucket], want)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()).(Nop); !ok {
+		t.Error("expected Nop without metrics")
+	}
+	e := newExpvar()
+	if FromContext(WithMetrics(context.Background(), e)) != e {
+		t.Error("expected the attached metrics")
+	}
+	if _, ok := Or(nil).(Nop); !ok {
+		t.Error("Or(nil) should be Nop")
+	}
+}
@@ -0,0 +1,53 @@
+//go:build prometheus
+
+// Build with -tags prometheus after fetching the client:
+//
+//	go get github.com/prometheus/client_golang/prometheus
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus exports loader metrics as <namespace>_rows_total, _batches_total,
+// _errors_total and _batch_duration_seconds
+type Prometheus struct {
+	rows, batches, errors *prometheus.CounterVec
+	latency               *prometheus.HistogramVec
+}
+
+// NewPrometheus registers the collectors with reg
+func NewPrometheus(reg prometheus.Registerer, namespace string) (*Prometheus, error) {
+	p := &Prometheus{
+		rows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "rows_total", Help: "Rows inserted.",
+		}, []string{"table"}),
+		batches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "batches_total", Help: "Batches inserted.",
+		}, []string{"table"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "errors_total", Help: "Load errors by stage.",
+		}, []string{"table", "stage"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "batch_duration_seconds", Help: "Batch insert latency.",
+			Buckets: BatchBuckets,
+		}, []string{"table"}),
+	}
+	for _, c := range []prometheus.Collector{p.rows, p.batches, p.errors, p.latency} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *Prometheus) AddRows(table string, n int)   { p.rows.WithLabelValues(table).Add(float64(n)) }
+func (p *Prometheus) IncBatches(table string)       { p.batches.WithLabelValues(table).Inc() }
+func (p *Prometheus) IncErrors(table, stage string) { p.errors.WithLabelValues(table, stage).Inc() }
+
+func (p *Prometheus) ObserveBatch(table string, d time.Duration) {
+	p.latency.WithLabelValues(table).Observe(d.Seconds())
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"sql-learn2/logging"
+	"sql-learn2/migrations"
+	"sql-learn2/oraconn"
+	"sql-learn2/oraerr"
+)
+
+// Applies versioned DDL files and records them in SCHEMA_VERSION. Example:
+//
+//	go run ./migrations/cmd -status
+//	go run ./migrations/cmd -dir ./db/migrations -var TABLESPACE=USERS
+//
+// Without -dir the migrations built into the migrations package are used.
+// On a schema already set up with the scripts/ files, run -baseline 6 once.
+func main() {
+	dir := flag.String("dir", "", "Directory of <version>_<name>.sql files (default: built-in migrations)")
+	table := flag.String("table", "SCHEMA_VERSION", "Table recording applied migrations")
+	to := flag.Int("to", 0, "Apply up to and including this version (0 = latest)")
+	status := flag.Bool("status", false, "Show which migrations are applied and exit")
+	baseline := flag.Int("baseline", 0, "Mark migrations up to this version as applied without running them")
+	vars := map[string]string{}
+	flag.Func("var", "NAME=VALUE substituted for ${NAME} in migration files (repeatable)", func(s string) error {
+		k, v, ok := strings.Cut(s, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("want NAME=VALUE, got %q", s)
+		}
+		vars[k] = v
+		return nil
+	})
+
+	var ora oraconn.Config
+	ora.RegisterFlags(flag.CommandLine)
+	var logCfg logging.Config
+	logCfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := logCfg.Setup(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	var (
+		migs []migrations.Migration
+		err  error
+	)
+	if *dir == "" {
+		migs, err = migrations.Load(migrations.Builtin())
+	} else {
+		migs, err = migrations.LoadDir(*dir)
+	}
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	ctx := context.Background()
+	db, err := oraconn.Connect(ctx, ora)
+	if err != nil {
+		oraerr.Fatal("Failed to connect", err)
+	}
+	defer db.Close()
+	m := &migrations.Migrator{DB: db, Table: *table, Vars: vars}
+
+	switch {
+	case *status:
+		st, err := m.Status(ctx, migs)
+		if err != nil {
+			oraerr.Fatal("Failed to read status", err)
+		}
+		for _, s := range st {
+			state := "pending"
+			switch {
+			case s.Changed:
+				state = "CHANGED since applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			case s.Applied:
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-30s %s\n", s.Version, s.Name, state)
+		}
+	case *baseline > 0:
+		done, err := m.Baseline(ctx, migs, *baseline)
+		if err != nil {
+			oraerr.Fatal("Baseline failed", err)
+		}
+		log.Printf("Marked %d migration(s) as applied", len(done))
+	default:
+		done, err := m.Up(ctx, migs, *to)
+		if err != nil {
+			oraerr.Fatal("Migration failed", err)
+		}
+		log.Printf("Applied %d migration(s)", len(done))
+	}
+}
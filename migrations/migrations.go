@@ -0,0 +1,317 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sql-learn2/logging"
+	"sql-learn2/oraerr"
+)
+
+//go:embed sql/*.sql
+var builtin embed.FS
+
+// Builtin returns the migrations shipped with the repo (the tables, MVs,
+// staging tables and EVENT_LOG the tools expect) as an fs.FS for Load
+func Builtin() fs.FS {
+	sub, _ := fs.Sub(builtin, "sql")
+	return sub
+}
+
+// Migration is one versioned DDL file, named <version>_<name>.sql
+type Migration struct {
+	Version  int
+	Name     string
+	SQL      string
+	Checksum string // SHA-256 of the file, recorded when applied
+}
+
+var fileRe = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_\-]+)\.sql$`)
+
+// Load reads every <version>_<name>.sql in the root of fsys, ordered by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+	var migs []Migration
+	seen := map[int]string{}
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" {
+			continue
+		}
+		m := fileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration file %s: want <version>_<name>.sql", e.Name())
+		}
+		v, _ := strconv.Atoi(m[1])
+		if prev, ok := seen[v]; ok {
+			return nil, fmt.Errorf("migration version %d used by %s and %s", v, prev, e.Name())
+		}
+		seen[v] = e.Name()
+		b, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", e.Name(), err)
+		}
+		sum := sha256.Sum256(b)
+		migs = append(migs, Migration{Version: v, Name: m[2], SQL: string(b), Checksum: hex.EncodeToString(sum[:])})
+	}
+	sort.Slice(migs, func(i, j int) bool { return migs[i].Version < migs[j].Version })
+	return migs, nil
+}
+
+// LoadDir is Load for a directory on disk
+func LoadDir(dir string) ([]Migration, error) {
+	return Load(os.DirFS(dir))
+}
+
+var plsqlRe = regexp.MustCompile(`(?i)^(BEGIN|DECLARE|CREATE\s+(OR\s+REPLACE\s+)?((NON)?EDITIONABLE\s+)?(PROCEDURE|FUNCTION|PACKAGE|TRIGGER|TYPE))\b`)
+
+// SplitStatements splits a file the way SQL*Plus would: plain statements end
+// with ';' at the end of a line, PL/SQL blocks (BEGIN, DECLARE, CREATE
+// PROCEDURE/FUNCTION/PACKAGE/TRIGGER/TYPE) with a line holding only '/'.
+// Full-line '--' comments and PROMPT lines outside PL/SQL are dropped.
+func SplitStatements(src string) []string {
+	var (
+		out   []string
+		cur   []string
+		plsql bool
+	)
+	flush := func() {
+		if s := strings.TrimSpace(strings.Join(cur, "\n")); s != "" {
+			out = append(out, s)
+		}
+		cur, plsql = nil, false
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "/" {
+			flush()
+			continue
+		}
+		if plsql {
+			cur = append(cur, line)
+			continue
+		}
+		upper := strings.ToUpper(trimmed)
+		if trimmed == "" && len(cur) == 0 || strings.HasPrefix(trimmed, "--") ||
+			upper == "PROMPT" || strings.HasPrefix(upper, "PROMPT ") {
+			continue
+		}
+		if len(cur) == 0 && plsqlRe.MatchString(trimmed) {
+			plsql = true
+			cur = append(cur, line)
+			continue
+		}
+		if strings.HasSuffix(trimmed, ";") {
+			cur = append(cur, strings.TrimSuffix(strings.TrimRight(line, " \t"), ";"))
+			flush()
+			continue
+		}
+		cur = append(cur, line)
+	}
+	flush()
+	return out
+}
+
+var varRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expand replaces ${NAME} with vars[NAME]; an unknown name is an error
+func expand(s string, vars map[string]string) (string, error) {
+	var missing []string
+	out := varRe.ReplaceAllStringFunc(s, func(m string) string {
+		name := varRe.FindStringSubmatch(m)[1]
+		v, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("undefined variable(s) %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// Migrator applies migrations and records them in a version table
+type Migrator struct {
+	DB     *sql.DB
+	Table  string            // default SCHEMA_VERSION
+	Vars   map[string]string // ${NAME} substitutions, e.g. a per-environment tablespace
+	Logger *slog.Logger      // defaults to the logger in ctx (see logging.WithLogger)
+}
+
+// Status is a migration and whether it has been applied
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Changed   bool // applied with a different checksum, i.e. the file was edited afterwards
+}
+
+// ErrChanged is returned by Up when an applied migration's file was edited
+var ErrChanged = errors.New("applied migration changed")
+
+func (m *Migrator) table() string {
+	if m.Table == "" {
+		return "SCHEMA_VERSION"
+	}
+	return m.Table
+}
+
+func (m *Migrator) logger(ctx context.Context) *slog.Logger {
+	if m.Logger != nil {
+		return m.Logger
+	}
+	return logging.FromContext(ctx)
+}
+
+// ensureTable creates the version table on first use
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.DB.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE %s (
+  VERSION     NUMBER PRIMARY KEY,
+  NAME        VARCHAR2(200) NOT NULL,
+  CHECKSUM    VARCHAR2(64) NOT NULL,
+  APPLIED_AT  TIMESTAMP DEFAULT SYSTIMESTAMP NOT NULL,
+  DURATION_MS NUMBER
+)`, m.table()))
+	if err != nil && oraerr.Code(err) != 955 { // ORA-00955: name is already used
+		return fmt.Errorf("create %s: %w", m.table(), err)
+	}
+	return nil
+}
+
+type applied struct {
+	checksum string
+	at       time.Time
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]applied, error) {
+	rows, err := m.DB.QueryContext(ctx, fmt.Sprintf("SELECT VERSION, CHECKSUM, APPLIED_AT FROM %s", m.table()))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", m.table(), err)
+	}
+	defer rows.Close()
+	out := map[int]applied{}
+	for rows.Next() {
+		var v int
+		var a applied
+		if err := rows.Scan(&v, &a.checksum, &a.at); err != nil {
+			return nil, fmt.Errorf("read %s: %w", m.table(), err)
+		}
+		out[v] = a
+	}
+	return out, rows.Err()
+}
+
+// Status reports which of migs have been applied
+func (m *Migrator) Status(ctx context.Context, migs []Migration) ([]Status, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, len(migs))
+	for i, mg := range migs {
+		a, ok := done[mg.Version]
+		out[i] = Status{Migration: mg, Applied: ok, AppliedAt: a.at, Changed: ok && a.checksum != mg.Checksum}
+	}
+	return out, nil
+}
+
+// Up applies the pending migrations up to and including version target
+// (0 = all) and returns the ones it applied. Oracle commits DDL implicitly,
+// so a migration failing halfway stays half applied and unrecorded; fix the
+// file or the schema, then run Up again.
+func (m *Migrator) Up(ctx context.Context, migs []Migration, target int) ([]Migration, error) {
+	status, err := m.Status(ctx, migs)
+	if err != nil {
+		return nil, err
+	}
+	maxApplied := 0
+	for _, s := range status {
+		if s.Changed {
+			return nil, fmt.Errorf("%w: %d_%s", ErrChanged, s.Version, s.Name)
+		}
+		if s.Applied {
+			maxApplied = max(maxApplied, s.Version)
+		}
+	}
+
+	logger := m.logger(ctx).With(logging.FieldTable, m.table())
+	var done []Migration
+	for _, s := range status {
+		if s.Applied || (target > 0 && s.Version > target) {
+			continue
+		}
+		if s.Version < maxApplied {
+			return done, fmt.Errorf("migration %d_%s is older than applied version %d", s.Version, s.Name, maxApplied)
+		}
+		if err := m.apply(ctx, s.Migration); err != nil {
+			return done, err
+		}
+		done = append(done, s.Migration)
+		logger.Info("Applied migration", "version", s.Version, "name", s.Name)
+	}
+	return done, nil
+}
+
+// Baseline records migrations up to version as applied without running
+// them, for schemas created earlier by the scripts/ setup files
+func (m *Migrator) Baseline(ctx context.Context, migs []Migration, version int) ([]Migration, error) {
+	status, err := m.Status(ctx, migs)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, s := range status {
+		if s.Applied || s.Version > version {
+			continue
+		}
+		if err := m.record(ctx, s.Migration, 0); err != nil {
+			return done, err
+		}
+		done = append(done, s.Migration)
+	}
+	return done, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, mg Migration) error {
+	text, err := expand(mg.SQL, m.Vars)
+	if err != nil {
+		return fmt.Errorf("migration %d_%s: %w", mg.Version, mg.Name, err)
+	}
+	start := time.Now()
+	for i, stmt := range SplitStatements(text) {
+		if _, err := m.DB.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("migration %d_%s statement %d: %w", mg.Version, mg.Name, i+1, err)
+		}
+	}
+	return m.record(ctx, mg, time.Since(start))
+}
+
+func (m *Migrator) record(ctx context.Context, mg Migration, took time.Duration) error {
+	_, err := m.DB.ExecContext(ctx,
+		fmt.Sprintf("INSERT INTO %s (VERSION, NAME, CHECKSUM, DURATION_MS) VALUES (:1, :2, :3, :4)", m.table()),
+		mg.Version, mg.Name, mg.Checksum, took.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("record migration %d_%s: %w", mg.Version, mg.Name, err)
+	}
+	return nil
+}
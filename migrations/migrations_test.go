@@ -0,0 +1,218 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/logging"
+)
+
+var quiet = logging.WithLogger(context.Background(), logging.Discard())
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"single", "CREATE TABLE T (ID NUMBER);\n", []string{"CREATE TABLE T (ID NUMBER)"}},
+		{"multi-line", "-- header\nCREATE TABLE T (\n  ID NUMBER\n);\n\nCREATE INDEX I ON T (ID);",
+			[]string{"CREATE TABLE T (\n  ID NUMBER\n)", "CREATE INDEX I ON T (ID)"}},
+		{"prompt and slash after ddl", "PROMPT === go ===\nCREATE SYNONYM S FOR T;\n/\n",
+			[]string{"CREATE SYNONYM S FOR T"}},
+		{"plsql block", "BEGIN\n  EXECUTE IMMEDIATE 'DROP TABLE T';\n-- keep\nEND;\n/\nCREATE TABLE T (ID NUMBER);",
+			[]string{"BEGIN\n  EXECUTE IMMEDIATE 'DROP TABLE T';\n-- keep\nEND;", "CREATE TABLE T (ID NUMBER)"}},
+		{"trigger", "create or replace trigger TRG before insert on T for each row\nbegin\n  :new.id := 1;\nend;\n/",
+			[]string{"create or replace trigger TRG before insert on T for each row\nbegin\n  :new.id := 1;\nend;"}},
+		{"no terminator", "DROP TABLE T", []string{"DROP TABLE T"}},
+		{"crlf", "DROP TABLE A;\r\nDROP TABLE B;\r\n", []string{"DROP TABLE A", "DROP TABLE B"}},
+		{"empty", "-- nothing\n\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitStatements(tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitStatements =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_second.sql": {Data: []byte("B;")},
+		"0001_first.sql":  {Data: []byte("A;")},
+		"README.md":       {Data: []byte("x")},
+	}
+	migs, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migs) != 2 || migs[0].Version != 1 || migs[0].Name != "first" || migs[1].Version != 2 {
+		t.Fatalf("Load = %+v", migs)
+	}
+	if len(migs[0].Checksum) != 64 || migs[0].Checksum == migs[1].Checksum {
+		t.Errorf("checksums = %s, %s", migs[0].Checksum, migs[1].Checksum)
+	}
+
+	bad := []fstest.MapFS{
+		{"first.sql": {Data: []byte("A;")}},
+		{"1_a.sql": {Data: []byte("A;")}, "0001_b.sql": {Data: []byte("B;")}},
+	}
+	for _, fsys := range bad {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("Load(%v) succeeded", fsys)
+		}
+	}
+}
+
+func TestBuiltin(t *testing.T) {
+	migs, err := Load(Builtin())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migs) == 0 {
+		t.Fatal("no builtin migrations")
+	}
+	for i, m := range migs {
+		if m.Version != i+1 {
+			t.Errorf("migration %s has version %d, want %d", m.Name, m.Version, i+1)
+		}
+		if _, err := expand(m.SQL, nil); err != nil {
+			t.Errorf("%s: %v", m.Name, err)
+		}
+		if len(SplitStatements(m.SQL)) == 0 {
+			t.Errorf("%s has no statements", m.Name)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	got, err := expand("CREATE TABLE T (ID NUMBER) TABLESPACE ${TS}", map[string]string{"TS": "USERS"})
+	if err != nil || got != "CREATE TABLE T (ID NUMBER) TABLESPACE USERS" {
+		t.Errorf("expand = %q, %v", got, err)
+	}
+	if _, err := expand("${A} ${B}", map[string]string{"A": "x"}); err == nil || !strings.Contains(err.Error(), "B") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func testMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Name: "one", SQL: "CREATE TABLE ONE (ID NUMBER);", Checksum: "c1"},
+		{Version: 2, Name: "two", SQL: "CREATE TABLE TWO (ID NUMBER);\nCREATE INDEX TWO_IX ON TWO (ID);", Checksum: "c2"},
+		{Version: 3, Name: "three", SQL: "CREATE TABLE THREE (ID NUMBER);", Checksum: "c3"},
+	}
+}
+
+func TestUp(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cols := []string{"VERSION", "CHECKSUM", "APPLIED_AT"}
+	tests := []struct {
+		name    string
+		applied [][]any
+		target  int
+		want    []string // statements after the version table is read
+		err     string
+	}{
+		{
+			name: "fresh",
+			want: []string{
+				"CREATE TABLE ONE (ID NUMBER)",
+				"INSERT INTO SCHEMA_VERSION (VERSION, NAME, CHECKSUM, DURATION_MS) VALUES (:1, :2, :3, :4)",
+				"CREATE TABLE TWO (ID NUMBER)", "CREATE INDEX TWO_IX ON TWO (ID)",
+				"INSERT INTO SCHEMA_VERSION (VERSION, NAME, CHECKSUM, DURATION_MS) VALUES (:1, :2, :3, :4)",
+				"CREATE TABLE THREE (ID NUMBER)",
+				"INSERT INTO SCHEMA_VERSION (VERSION, NAME, CHECKSUM, DURATION_MS) VALUES (:1, :2, :3, :4)",
+			},
+		},
+		{
+			name:    "partly applied with target",
+			applied: [][]any{{int64(1), "c1", at}},
+			target:  2,
+			want: []string{
+				"CREATE TABLE TWO (ID NUMBER)", "CREATE INDEX TWO_IX ON TWO (ID)",
+				"INSERT INTO SCHEMA_VERSION (VERSION, NAME, CHECKSUM, DURATION_MS) VALUES (:1, :2, :3, :4)",
+			},
+		},
+		{
+			name:    "up to date",
+			applied: [][]any{{int64(1), "c1", at}, {int64(2), "c2", at}, {int64(3), "c3", at}},
+			want:    []string{},
+		},
+		{
+			name:    "changed",
+			applied: [][]any{{int64(1), "edited", at}},
+			err:     "applied migration changed: 1_one",
+		},
+		{
+			name:    "out of order",
+			applied: [][]any{{int64(1), "c1", at}, {int64(3), "c3", at}},
+			err:     "migration 2_two is older than applied version 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("^SELECT VERSION", cols, tt.applied...)
+			m := &Migrator{DB: f.DB}
+			_, err := m.Up(quiet, testMigrations(), tt.target)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("err = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			q := f.Queries()
+			if !strings.HasPrefix(q[0], "CREATE TABLE SCHEMA_VERSION") {
+				t.Errorf("first statement = %s", q[0])
+			}
+			if got := q[2:]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("statements =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUp_StatementFails(t *testing.T) {
+	f := sqlfake.New(t)
+	f.Fail("^CREATE INDEX", errors.New("ORA-01408"))
+	m := &Migrator{DB: f.DB, Table: "MIG"}
+	done, err := m.Up(quiet, testMigrations(), 0)
+	if err == nil || err.Error() != "migration 2_two statement 2: ORA-01408" {
+		t.Fatalf("err = %v", err)
+	}
+	if len(done) != 1 || done[0].Version != 1 {
+		t.Errorf("done = %+v", done)
+	}
+	for _, c := range f.Calls() {
+		if strings.HasPrefix(c.Query, "INSERT INTO MIG") && c.Args[0] == int64(2) {
+			t.Error("failed migration was recorded")
+		}
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	f := sqlfake.New(t)
+	m := &Migrator{DB: f.DB}
+	done, err := m.Baseline(quiet, testMigrations(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 2 {
+		t.Fatalf("done = %+v", done)
+	}
+	for _, q := range f.Queries()[2:] {
+		if !strings.HasPrefix(q, "INSERT INTO SCHEMA_VERSION") {
+			t.Errorf("baseline ran %s", q)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+-- Shared audit log written by lockflow and the POC tools
+CREATE TABLE EVENT_LOG (
+  ts        TIMESTAMP(3) DEFAULT SYSTIMESTAMP,
+  who       VARCHAR2(50),
+  msg       VARCHAR2(4000),
+  sid       NUMBER,
+  serial_no NUMBER,
+  audsid    NUMBER
+);
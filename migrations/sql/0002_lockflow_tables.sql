@@ -0,0 +1,19 @@
+-- A -> B -> C chain used by the lock flow demos (see lockflow.CreateTables)
+CREATE TABLE A (
+  id   NUMBER PRIMARY KEY,
+  data VARCHAR2(50)
+);
+
+CREATE TABLE B (
+  id   NUMBER PRIMARY KEY,
+  a_id NUMBER REFERENCES A(id),
+  data VARCHAR2(50)
+);
+
+CREATE TABLE C (
+  id         NUMBER PRIMARY KEY,
+  b_id       NUMBER REFERENCES B(id),
+  data       VARCHAR2(50),
+  chain_data VARCHAR2(50),
+  early_data VARCHAR2(50)
+);
@@ -0,0 +1,22 @@
+-- Target of the bulk_load_v3 example and the MV it refreshes
+CREATE TABLE PRODUCT (
+  PRODUCT_ID     NUMBER GENERATED BY DEFAULT ON NULL AS IDENTITY PRIMARY KEY,
+  PRODUCT_CODE   VARCHAR2(50) NOT NULL,
+  PRODUCT_NAME   VARCHAR2(255) NOT NULL,
+  DESCRIPTION    VARCHAR2(1000),
+  CATEGORY       VARCHAR2(100) NOT NULL,
+  STANDARD_COST  NUMBER(10, 2) NOT NULL,
+  LIST_PRICE     NUMBER(10, 2) NOT NULL,
+  REORDER_LEVEL  NUMBER(5),
+  TARGET_LEVEL   NUMBER(5),
+  DISCONTINUED   NUMBER(1) DEFAULT 0 NOT NULL,
+  UPDATED_AT     TIMESTAMP
+);
+
+COMMENT ON TABLE PRODUCT IS 'Product catalog for bulk load demonstration';
+
+CREATE MATERIALIZED VIEW MV_PRODUCT
+BUILD IMMEDIATE
+REFRESH COMPLETE ON DEMAND
+AS
+SELECT * FROM PRODUCT;
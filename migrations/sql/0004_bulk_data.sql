@@ -0,0 +1,15 @@
+-- Base table and read MV for script_material_view_refresh
+CREATE TABLE BULK_DATA (
+  ID            NUMBER PRIMARY KEY,
+  DATA_VALUE    VARCHAR2(200),
+  DESCRIPTION   VARCHAR2(500),
+  STATUS        VARCHAR2(50),
+  CREATED_AT    DATE
+);
+
+CREATE MATERIALIZED VIEW MV_BULK_DATA
+BUILD IMMEDIATE
+REFRESH COMPLETE ON DEMAND
+AS
+SELECT ID, DATA_VALUE, DESCRIPTION, STATUS, CREATED_AT
+FROM BULK_DATA;
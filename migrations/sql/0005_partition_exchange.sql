@@ -0,0 +1,33 @@
+-- Master/staging pair for the partition exchange load (-mode pexchange)
+CREATE TABLE EXAMPLE_MASTER (
+  ID          NUMBER PRIMARY KEY,
+  FIRST_NAME  VARCHAR2(100),
+  LAST_NAME   VARCHAR2(100),
+  AGE         NUMBER,
+  SALARY      NUMBER,
+  CREATED_AT  TIMESTAMP
+)
+PARTITION BY LIST (ID) (
+  PARTITION PDATA VALUES (DEFAULT)
+);
+
+CREATE INDEX EXAMPLE_MASTER_LN_IDX ON EXAMPLE_MASTER (LAST_NAME);
+CREATE INDEX EXAMPLE_MASTER_NAME_IDX ON EXAMPLE_MASTER (FIRST_NAME, LAST_NAME);
+CREATE INDEX EXAMPLE_MASTER_SALARY_IDX ON EXAMPLE_MASTER (SALARY);
+CREATE INDEX EXAMPLE_MASTER_AGE_IDX ON EXAMPLE_MASTER (AGE);
+CREATE INDEX EXAMPLE_MASTER_CREATED_IDX ON EXAMPLE_MASTER (CREATED_AT);
+
+CREATE TABLE EXAMPLE_STAGING (
+  ID          NUMBER PRIMARY KEY,
+  FIRST_NAME  VARCHAR2(100),
+  LAST_NAME   VARCHAR2(100),
+  AGE         NUMBER,
+  SALARY      NUMBER,
+  CREATED_AT  TIMESTAMP
+);
+
+CREATE INDEX EXAMPLE_STAGING_LN_IDX ON EXAMPLE_STAGING (LAST_NAME);
+CREATE INDEX EXAMPLE_STAGING_NAME_IDX ON EXAMPLE_STAGING (FIRST_NAME, LAST_NAME);
+CREATE INDEX EXAMPLE_STAGING_SALARY_IDX ON EXAMPLE_STAGING (SALARY);
+CREATE INDEX EXAMPLE_STAGING_AGE_IDX ON EXAMPLE_STAGING (AGE);
+CREATE INDEX EXAMPLE_STAGING_CREATED_IDX ON EXAMPLE_STAGING (CREATED_AT);
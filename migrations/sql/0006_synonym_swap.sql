@@ -0,0 +1,23 @@
+-- A/B tables behind the EXAMPLE synonym for the synonym swap load (-mode swap)
+CREATE TABLE EXAMPLE_A (
+  ID         VARCHAR2(100) NOT NULL,
+  FIRST_NAME VARCHAR2(100),
+  LAST_NAME  VARCHAR2(100),
+  AGE        NUMBER,
+  SALARY     NUMBER,
+  CONSTRAINT EXAMPLE_A_PK PRIMARY KEY (ID)
+);
+
+CREATE TABLE EXAMPLE_B (
+  ID         VARCHAR2(100) NOT NULL,
+  FIRST_NAME VARCHAR2(100),
+  LAST_NAME  VARCHAR2(100),
+  AGE        NUMBER,
+  SALARY     NUMBER,
+  CONSTRAINT EXAMPLE_B_PK PRIMARY KEY (ID)
+);
+
+CREATE INDEX EXAMPLE_A_LAST_NAME_IDX ON EXAMPLE_A (LAST_NAME);
+CREATE INDEX EXAMPLE_B_LAST_NAME_IDX ON EXAMPLE_B (LAST_NAME);
+
+CREATE OR REPLACE SYNONYM EXAMPLE FOR EXAMPLE_A;
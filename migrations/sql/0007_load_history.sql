@@ -0,0 +1,18 @@
+-- One row per csvdb load, upsert, synonym swap or partition exchange (see loadhistory)
+CREATE TABLE LOAD_HISTORY (
+  RUN_ID        NUMBER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  WORKFLOW      VARCHAR2(30) NOT NULL,
+  SOURCE_FILE   VARCHAR2(1000),
+  TARGET        VARCHAR2(261),
+  ROW_COUNT     NUMBER,
+  STARTED_AT    TIMESTAMP NOT NULL,
+  FINISHED_AT   TIMESTAMP NOT NULL,
+  DURATION_MS   NUMBER,
+  STATUS        VARCHAR2(10) NOT NULL,
+  ERROR_CODE    NUMBER,
+  ERROR_MESSAGE VARCHAR2(4000),
+  HOST_NAME     VARCHAR2(255),
+  OS_USER       VARCHAR2(128)
+);
+
+CREATE INDEX LOAD_HISTORY_TARGET_IDX ON LOAD_HISTORY (TARGET, STARTED_AT);
@@ -0,0 +1,13 @@
+-- One row per column an upsert changed (see csvdb-append Options.AuditTable)
+CREATE TABLE UPSERT_AUDIT (
+  AUDIT_ID    NUMBER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  CHANGED_AT  TIMESTAMP DEFAULT SYSTIMESTAMP NOT NULL,
+  TABLE_NAME  VARCHAR2(128) NOT NULL,
+  ACTION      VARCHAR2(10) NOT NULL,
+  KEY_VALUE   VARCHAR2(4000) NOT NULL,
+  COLUMN_NAME VARCHAR2(128),
+  OLD_VALUE   VARCHAR2(4000),
+  NEW_VALUE   VARCHAR2(4000)
+);
+
+CREATE INDEX UPSERT_AUDIT_TABLE_IDX ON UPSERT_AUDIT (TABLE_NAME, CHANGED_AT);
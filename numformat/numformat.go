@@ -0,0 +1,133 @@
+package numformat
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format describes how numbers are written in a file. The zero value is the
+// plain Go/Oracle form: '.' as decimal separator and no grouping.
+type Format struct {
+	Decimal  rune     // decimal separator; default '.'
+	Grouping string   // accepted thousands separators, e.g. "." or " '"; they must split the integer part into groups of three
+	Currency []string // symbols or codes stripped before or after the number, e.g. "€", "EUR"
+}
+
+// Presets usable with Lookup
+var (
+	Default  = Format{}
+	English  = Format{Decimal: '.', Grouping: ",", Currency: []string{"$", "£", "USD", "GBP"}} // 1,234.56
+	European = Format{Decimal: ',', Grouping: ". \u00a0", Currency: []string{"€", "EUR"}}      // 1.234,56
+	French   = Format{Decimal: ',', Grouping: " \u00a0\u202f", Currency: []string{"€", "EUR"}} // 1 234,56
+	Swiss    = Format{Decimal: '.', Grouping: "'\u2019", Currency: []string{"CHF", "Fr."}}     // 1'234.56
+	Thai     = Format{Decimal: '.', Grouping: ",", Currency: []string{"฿", "THB"}}             // 1,234.56
+	presets  = map[string]Format{"": Default, "default": Default, "en": English, "eu": European, "de": European, "fr": French, "ch": Swiss, "th": Thai}
+)
+
+// Lookup returns the preset for name: default, en, eu (or de), fr, ch or th
+func Lookup(name string) (Format, error) {
+	f, ok := presets[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return Format{}, fmt.Errorf("unknown number format %q (use default, en, eu, de, fr, ch or th)", name)
+	}
+	return f, nil
+}
+
+// IsZero reports whether f is the plain format, where Normalize is a no-op
+func (f Format) IsZero() bool {
+	return (f.Decimal == 0 || f.Decimal == '.') && f.Grouping == "" && len(f.Currency) == 0
+}
+
+// Normalize rewrites s to the plain form strconv and Oracle accept, e.g.
+// "€ 1.234,56" to "1234.56" for European. A grouping separator in the wrong
+// place is an error, so "1.5" is not silently read as 15.
+func (f Format) Normalize(s string) (string, error) {
+	if f.IsZero() {
+		return s, nil
+	}
+	num := f.stripCurrency(strings.TrimSpace(s))
+	sign := ""
+	if num != "" && (num[0] == '-' || num[0] == '+') {
+		sign, num = num[:1], strings.TrimSpace(f.stripCurrency(num[1:]))
+	}
+	dec := f.Decimal
+	if dec == 0 {
+		dec = '.'
+	}
+	intPart, frac, hasFrac := strings.Cut(num, string(dec))
+	exp := ""
+	if i := strings.IndexAny(frac, "eE"); hasFrac && i >= 0 {
+		frac, exp = frac[:i], frac[i:]
+	} else if i := strings.IndexAny(intPart, "eE"); !hasFrac && i >= 0 {
+		intPart, exp = intPart[:i], intPart[i:]
+	}
+	digits, err := f.ungroup(intPart)
+	if err != nil {
+		return "", fmt.Errorf("invalid number %q: %w", s, err)
+	}
+	out := sign + digits
+	if hasFrac {
+		out += "." + frac
+	}
+	return out + exp, nil
+}
+
+// ParseFloat is Normalize followed by strconv.ParseFloat
+func (f Format) ParseFloat(s string) (float64, error) {
+	n, err := f.Normalize(s)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(n, 64)
+}
+
+// ParseInt is Normalize followed by strconv.ParseInt
+func (f Format) ParseInt(s string) (int64, error) {
+	n, err := f.Normalize(s)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(n, 10, 64)
+}
+
+// stripCurrency removes one currency symbol at either end of s
+func (f Format) stripCurrency(s string) string {
+	for _, c := range f.Currency {
+		if t, ok := strings.CutPrefix(s, c); ok {
+			return strings.TrimSpace(t)
+		}
+		if t, ok := strings.CutSuffix(s, c); ok {
+			return strings.TrimSpace(t)
+		}
+	}
+	return s
+}
+
+// ungroup drops grouping separators, checking every group after the first
+// has exactly three digits
+func (f Format) ungroup(s string) (string, error) {
+	if f.Grouping == "" {
+		return s, nil
+	}
+	var groups []string
+	from := 0
+	for i, r := range s {
+		if strings.ContainsRune(f.Grouping, r) {
+			groups = append(groups, s[from:i])
+			from = i + utf8.RuneLen(r)
+		}
+	}
+	if groups == nil {
+		return s, nil
+	}
+	groups = append(groups, s[from:])
+	for i, g := range groups {
+		if g == "" || i == 0 && len(g) > 3 || i > 0 && len(g) != 3 {
+			return "", errors.New("misplaced thousands separator")
+		}
+	}
+	return strings.Join(groups, ""), nil
+}
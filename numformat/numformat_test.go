@@ -0,0 +1,64 @@
+package numformat
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		f       Format
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"default untouched", Default, "1,234.5", "1,234.5", false},
+		{"european", European, "1.234,56", "1234.56", false},
+		{"european millions", European, "-1.234.567,8", "-1234567.8", false},
+		{"european no grouping", European, "1234,5", "1234.5", false},
+		{"european currency", European, "€ 1.234,56", "1234.56", false},
+		{"european trailing code", European, "12,50 EUR", "12.50", false},
+		{"european exponent", European, "1,5e3", "1.5e3", false},
+		{"european dot is grouping not decimal", European, "1.5", "", true},
+		{"european plain dot decimal rejected", European, "1234.56", "", true},
+		{"english", English, "$1,234.56", "1234.56", false},
+		{"english sign before currency", English, "-$1,234", "-1234", false},
+		{"english misplaced comma", English, "1,5", "", true},
+		{"english leading comma", English, ",123", "", true},
+		{"french nbsp", French, "1 234,5", "1234.5", false},
+		{"swiss", Swiss, "CHF 1'234.50", "1234.50", false},
+		{"thai", Thai, "฿1,000", "1000", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.f.Normalize(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Normalize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	if f, err := European.ParseFloat("1.234,5"); err != nil || f != 1234.5 {
+		t.Errorf("ParseFloat = %v, %v", f, err)
+	}
+	if n, err := English.ParseInt("1,000,000"); err != nil || n != 1000000 {
+		t.Errorf("ParseInt = %v, %v", n, err)
+	}
+	if _, err := European.ParseInt("1,5"); err == nil {
+		t.Error("ParseInt(1,5) expected error")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"", "default", "EN", "eu", "de", "fr", "ch", "th"} {
+		if _, err := Lookup(name); err != nil {
+			t.Errorf("Lookup(%q): %v", name, err)
+		}
+	}
+	if _, err := Lookup("xx"); err == nil {
+		t.Error("Lookup(xx) expected error")
+	}
+}
@@ -0,0 +1,40 @@
+package oraconn
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Getenv returns the environment variable or def when it is unset or blank
+func Getenv(key, def string) string {
+	if v := os.Getenv(key); strings.TrimSpace(v) != "" {
+		return v
+	}
+	return def
+}
+
+// EnvDuration parses a duration such as "250ms" or "3s", falling back to def on error
+func EnvDuration(key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(strings.TrimSpace(os.Getenv(key))); err == nil {
+		return d
+	}
+	return def
+}
+
+// EnvInt parses an integer, falling back to def on error
+func EnvInt(key string, def int) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key))); err == nil {
+		return n
+	}
+	return def
+}
+
+// EnvBool parses a boolean such as "true" or "1", falling back to def on error
+func EnvBool(key string, def bool) bool {
+	if b, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(key))); err == nil {
+		return b
+	}
+	return def
+}
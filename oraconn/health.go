@@ -0,0 +1,123 @@
+package oraconn
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"sql-learn2/oraerr"
+)
+
+// Health event kinds
+const (
+	EventUnhealthy   = "unhealthy"   // a health ping failed with a connection error
+	EventReconnected = "reconnected" // the pool was reset and a fresh connection answered
+)
+
+// HealthEvent is reported when the pool loses or regains its connection
+type HealthEvent struct {
+	When    time.Time
+	Kind    string
+	Err     error         // the failure, for EventUnhealthy
+	Latency time.Duration // of the ping that produced the event
+}
+
+// HealthChecker pings a pool periodically. When a ping fails with a
+// connection error (ORA-03135, ORA-03113, EOF, ...) it drops the idle
+// connections so later queries dial new ones, and keeps pinging until the
+// database answers again. Long-running tools run it next to their work.
+type HealthChecker struct {
+	DB       *sql.DB
+	Interval time.Duration // between pings, default 30s
+	Timeout  time.Duration // per ping, default 5s
+	MaxIdle  int           // idle pool size restored after a reset, default 2 (database/sql's default)
+	OnEvent  func(HealthEvent)
+
+	kick chan struct{}
+}
+
+// NewHealthChecker returns a checker for db; set the optional fields before Run
+func NewHealthChecker(db *sql.DB) *HealthChecker {
+	return &HealthChecker{DB: db, kick: make(chan struct{}, 1)}
+}
+
+// Kick asks for a check now, e.g. after a query failed with a connection error.
+// It never blocks.
+func (h *HealthChecker) Kick() {
+	select {
+	case h.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Run checks until ctx is done
+func (h *HealthChecker) Run(ctx context.Context) {
+	interval := h.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	healthy := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		case <-h.kick:
+		}
+		healthy = h.check(ctx, healthy)
+	}
+}
+
+// check pings once and returns whether the pool is healthy afterwards
+func (h *HealthChecker) check(ctx context.Context, wasHealthy bool) bool {
+	latency, err := h.ping(ctx)
+	if err == nil {
+		if !wasHealthy {
+			h.emit(HealthEvent{When: time.Now(), Kind: EventReconnected, Latency: latency})
+		}
+		return true
+	}
+	if ctx.Err() != nil || oraerr.Classify(err) != oraerr.Network {
+		return wasHealthy
+	}
+	if wasHealthy {
+		h.emit(HealthEvent{When: time.Now(), Kind: EventUnhealthy, Err: err, Latency: latency})
+	}
+	ResetPool(h.DB, h.MaxIdle)
+	// Try a fresh connection straight away so a short blip costs one interval at most
+	if latency, err = h.ping(ctx); err == nil {
+		h.emit(HealthEvent{When: time.Now(), Kind: EventReconnected, Latency: latency})
+		return true
+	}
+	return false
+}
+
+func (h *HealthChecker) ping(ctx context.Context) (time.Duration, error) {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	err := h.DB.PingContext(ctx)
+	return time.Since(start), err
+}
+
+func (h *HealthChecker) emit(e HealthEvent) {
+	if h.OnEvent != nil {
+		h.OnEvent(e)
+	}
+}
+
+// ResetPool closes db's idle connections so the next queries open new ones.
+// Connections in use are left alone; the driver discards them once they fail.
+func ResetPool(db *sql.DB, maxIdle int) {
+	if maxIdle <= 0 {
+		maxIdle = 2
+	}
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(maxIdle)
+}
@@ -0,0 +1,77 @@
+package oraconn
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sijms/go-ora/v2/network"
+
+	"sql-learn2/internal/sqlfake"
+)
+
+func TestHealthChecker_Check(t *testing.T) {
+	lost := network.NewOracleError(3135)
+	tests := []struct {
+		name        string
+		wasHealthy  bool
+		pingErr     error
+		recovers    bool // the ping after the pool reset succeeds
+		wantHealthy bool
+		wantEvents  []string
+	}{
+		{"healthy", true, nil, false, true, nil},
+		{"lost and reconnected", true, lost, true, true, []string{EventUnhealthy, EventReconnected}},
+		{"lost and still down", true, lost, false, false, []string{EventUnhealthy}},
+		{"still down", false, lost, false, false, nil},
+		{"back after outage", false, nil, false, true, []string{EventReconnected}},
+		{"non-connection error ignored", true, errors.New("ORA-01017"), false, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			h := NewHealthChecker(f.DB)
+			var events []string
+			h.OnEvent = func(e HealthEvent) {
+				events = append(events, e.Kind)
+				if e.Kind == EventUnhealthy {
+					if e.Err == nil {
+						t.Error("unhealthy event without error")
+					}
+					if tt.recovers {
+						f.FailPing(nil)
+					}
+				}
+			}
+			// keep a connection idle so the ping goes through the pool like in production
+			if err := f.DB.PingContext(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			f.FailPing(tt.pingErr)
+			if got := h.check(context.Background(), tt.wasHealthy); got != tt.wantHealthy {
+				t.Errorf("healthy = %v, want %v", got, tt.wantHealthy)
+			}
+			if !reflect.DeepEqual(events, tt.wantEvents) {
+				t.Errorf("events = %v, want %v", events, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestHealthChecker_Kick(t *testing.T) {
+	f := sqlfake.New(t)
+	h := NewHealthChecker(f.DB)
+	h.Kick()
+	h.Kick() // must not block when a check is already pending
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	h.OnEvent = func(HealthEvent) {}
+	go func() { h.Run(ctx); close(done) }()
+	for f.Pings() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,172 @@
+package oraconn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SecretProvider supplies the password at connect time, so it does not
+// have to sit in a flag or environment file
+type SecretProvider interface {
+	Secret(ctx context.Context) (string, error)
+}
+
+// FileSecret reads the password from a file, e.g. a mounted Docker/Kubernetes secret.
+// Surrounding whitespace, including the trailing newline, is dropped.
+type FileSecret string
+
+func (f FileSecret) Secret(context.Context) (string, error) {
+	b, err := os.ReadFile(string(f))
+	if err != nil {
+		return "", fmt.Errorf("read password file: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// ExecSecret runs a shell command and uses its trimmed stdout, e.g. "pass show oracle/learn1"
+type ExecSecret string
+
+func (e ExecSecret) Secret(ctx context.Context) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", string(e))
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("password command: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// VaultSecret reads one field of a HashiCorp Vault secret over the HTTP API.
+// Both KV v1 and v2 responses are understood; for KV v2 Path includes "data/",
+// e.g. "secret/data/oracle".
+type VaultSecret struct {
+	Addr      string // default VAULT_ADDR
+	Token     string // default VAULT_TOKEN, then ~/.vault-token
+	Namespace string // default VAULT_NAMESPACE (Vault Enterprise)
+	Path      string
+	Field     string // default "password"
+	Client    *http.Client
+}
+
+func (v VaultSecret) Secret(ctx context.Context) (string, error) {
+	addr := strings.TrimRight(firstNonEmpty(v.Addr, os.Getenv("VAULT_ADDR")), "/")
+	if addr == "" {
+		return "", fmt.Errorf("vault: address not set; use VAULT_ADDR")
+	}
+	token := firstNonEmpty(v.Token, vaultToken())
+	if token == "" {
+		return "", fmt.Errorf("vault: token not set; use VAULT_TOKEN or vault login")
+	}
+	field := v.Field
+	if field == "" {
+		field = "password"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+"/v1/"+strings.TrimLeft(v.Path, "/"), nil)
+	if err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := firstNonEmpty(v.Namespace, os.Getenv("VAULT_NAMESPACE")); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault: read %s: %s", v.Path, resp.Status)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("vault: decode %s: %w", v.Path, err)
+	}
+	data := body.Data
+	// KV v2 nests the secret under data.data
+	if raw, ok := data["data"]; ok && data["metadata"] != nil {
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return "", fmt.Errorf("vault: decode %s: %w", v.Path, err)
+		}
+	}
+	var s string
+	if raw, ok := data[field]; !ok || json.Unmarshal(raw, &s) != nil {
+		return "", fmt.Errorf("vault: %s has no string field %q", v.Path, field)
+	}
+	return s, nil
+}
+
+// vaultToken follows the vault CLI: VAULT_TOKEN, then the token helper file
+func vaultToken() string {
+	if t := os.Getenv("VAULT_TOKEN"); t != "" {
+		return t
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	b, err := os.ReadFile(filepath.Join(home, ".vault-token"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ParseSecret turns a -pass-source / ORA_PASS_SOURCE value into a provider:
+//
+//	file:/run/secrets/ora_pass
+//	exec:pass show oracle/learn1
+//	vault:secret/data/oracle#password
+func ParseSecret(spec string) (SecretProvider, error) {
+	kind, arg, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok || arg == "" {
+		return nil, fmt.Errorf("invalid password source %q; want file:PATH, exec:COMMAND or vault:PATH[#FIELD]", spec)
+	}
+	switch kind {
+	case "file":
+		return FileSecret(arg), nil
+	case "exec":
+		return ExecSecret(arg), nil
+	case "vault":
+		path, field, _ := strings.Cut(arg, "#")
+		return VaultSecret{Path: path, Field: field}, nil
+	}
+	return nil, fmt.Errorf("unknown password source %q; want file, exec or vault", kind)
+}
+
+// errSecret reports an invalid ORA_PASS_SOURCE when the password is needed
+type errSecret struct{ err error }
+
+func (e errSecret) Secret(context.Context) (string, error) { return "", e.err }
+
+func envSecret(spec string) SecretProvider {
+	p, err := ParseSecret(spec)
+	if err != nil {
+		return errSecret{err}
+	}
+	return p
+}
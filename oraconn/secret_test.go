@@ -0,0 +1,160 @@
+package oraconn
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSecret(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    SecretProvider
+		wantErr bool
+	}{
+		{"file:/run/secrets/ora", FileSecret("/run/secrets/ora"), false},
+		{"exec:pass show ora", ExecSecret("pass show ora"), false},
+		{"vault:secret/data/ora#pw", VaultSecret{Path: "secret/data/ora", Field: "pw"}, false},
+		{"vault:secret/ora", VaultSecret{Path: "secret/ora"}, false},
+		{"file:", nil, true},
+		{"s3:bucket/key", nil, true},
+		{"plaintext", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseSecret(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSecret(%q) = %#v, want %#v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileAndExecSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pass")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		p    SecretProvider
+		want string
+	}{
+		{"file", FileSecret(path), "from-file"},
+		{"exec", ExecSecret("echo from-exec"), "from-exec"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.p.Secret(context.Background())
+			if err != nil || got != tt.want {
+				t.Errorf("Secret() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+	if _, err := ExecSecret("exit 3").Secret(context.Background()); err == nil {
+		t.Error("expected error from failing command")
+	}
+}
+
+func TestVaultSecret(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "tok" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/ora":
+			w.Write([]byte(`{"data":{"data":{"password":"kv2"},"metadata":{"version":1}}}`))
+		case "/v1/kv/ora":
+			w.Write([]byte(`{"data":{"pw":"kv1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		v       VaultSecret
+		want    string
+		wantErr bool
+	}{
+		{"kv2", VaultSecret{Path: "secret/data/ora"}, "kv2", false},
+		{"kv1 field", VaultSecret{Path: "kv/ora", Field: "pw"}, "kv1", false},
+		{"missing field", VaultSecret{Path: "kv/ora"}, "", true},
+		{"not found", VaultSecret{Path: "nope"}, "", true},
+		{"bad token", VaultSecret{Path: "kv/ora", Token: "wrong"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.v
+			v.Addr = srv.URL
+			if v.Token == "" {
+				v.Token = "tok"
+			}
+			got, err := v.Secret(context.Background())
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("Secret() = %q, %v; want %q (wantErr %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"fields", Config{User: "u", Pass: "old", Host: "h", Port: "1", Service: "s", Secret: ExecSecret("echo n3w")},
+			"oracle://u:n3w@h:1/s"},
+		{"dsn", Config{DSN: "oracle://u:old@h:1/s", Secret: ExecSecret("echo n3w")}, "oracle://u:n3w@h:1/s"},
+		{"no secret", Config{User: "u", Pass: "old", Host: "h", Port: "1", Service: "s"}, "oracle://u:old@h:1/s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.cfg.Resolve(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := cfg.ConnString(); got != tt.want {
+				t.Errorf("ConnString() = %q, want %q", got, tt.want)
+			}
+			if cfg.Secret != nil {
+				t.Error("resolved config still has a Secret")
+			}
+		})
+	}
+}
+
+func TestPassSourceFlagAndEnv(t *testing.T) {
+	t.Setenv("ORA_PASS_SOURCE", "file:/env/pass")
+	if got := FromEnv().Secret; got != FileSecret("/env/pass") {
+		t.Errorf("FromEnv().Secret = %#v", got)
+	}
+
+	var cfg Config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-pass-source", "exec:echo hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Secret != ExecSecret("echo hi") {
+		t.Errorf("Secret = %#v", cfg.Secret)
+	}
+	if cfg.WithCredentials("system", "x").Secret != nil {
+		t.Error("WithCredentials should drop the Secret")
+	}
+
+	t.Setenv("ORA_PASS_SOURCE", "bogus")
+	if _, err := FromEnv().Resolve(context.Background()); err == nil {
+		t.Error("expected invalid ORA_PASS_SOURCE to fail at Resolve")
+	}
+}
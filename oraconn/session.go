@@ -0,0 +1,89 @@
+package oraconn
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	go_ora "github.com/sijms/go-ora/v2"
+)
+
+// Session is applied to every new pooled connection right after it connects,
+// so the settings hold whichever connection a statement lands on
+type Session struct {
+	ParallelDML          bool   // ALTER SESSION ENABLE PARALLEL DML
+	NLSDateFormat        string // e.g. YYYY-MM-DD HH24:MI:SS
+	NLSNumericCharacters string // decimal then group separator, e.g. ",."
+	Module               string // DBMS_APPLICATION_INFO module, shown in V$SESSION.MODULE
+	Action               string // DBMS_APPLICATION_INFO action, shown in V$SESSION.ACTION
+	ClientInfo           string // DBMS_APPLICATION_INFO client info, shown in V$SESSION.CLIENT_INFO
+}
+
+// sessionStmt is one setup statement with its binds
+type sessionStmt struct {
+	query string
+	args  []driver.NamedValue
+}
+
+// statements returns the setup SQL in the order it runs
+func (s Session) statements() []sessionStmt {
+	var out []sessionStmt
+	if s.ParallelDML {
+		out = append(out, sessionStmt{query: "ALTER SESSION ENABLE PARALLEL DML"})
+	}
+	// ALTER SESSION takes no binds; the values are quoted instead
+	if s.NLSDateFormat != "" {
+		out = append(out, sessionStmt{query: "ALTER SESSION SET NLS_DATE_FORMAT = " + quote(s.NLSDateFormat)})
+	}
+	if s.NLSNumericCharacters != "" {
+		out = append(out, sessionStmt{query: "ALTER SESSION SET NLS_NUMERIC_CHARACTERS = " + quote(s.NLSNumericCharacters)})
+	}
+	if s.Module != "" || s.Action != "" {
+		out = append(out, sessionStmt{
+			query: "BEGIN DBMS_APPLICATION_INFO.SET_MODULE(:1, :2); END;",
+			args:  []driver.NamedValue{{Ordinal: 1, Value: s.Module}, {Ordinal: 2, Value: s.Action}},
+		})
+	}
+	if s.ClientInfo != "" {
+		out = append(out, sessionStmt{
+			query: "BEGIN DBMS_APPLICATION_INFO.SET_CLIENT_INFO(:1); END;",
+			args:  []driver.NamedValue{{Ordinal: 1, Value: s.ClientInfo}},
+		})
+	}
+	return out
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sessionConnector runs the setup statements on each connection it hands out
+type sessionConnector struct {
+	driver.Connector
+	stmts []sessionStmt
+}
+
+func newSessionConnector(dsn string, s Session) driver.Connector {
+	return sessionConnector{Connector: go_ora.NewConnector(dsn), stmts: s.statements()}
+}
+
+func (c sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ex, ok := conn.(driver.ExecerContext)
+	if !ok {
+		_ = conn.Close()
+		return nil, errors.New("session setup: driver connection does not support ExecContext")
+	}
+	for _, st := range c.stmts {
+		if _, err := ex.ExecContext(ctx, st.query, st.args); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("session setup %q: %w", st.query, err)
+		}
+	}
+	return conn, nil
+}
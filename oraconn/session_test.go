@@ -0,0 +1,69 @@
+package oraconn
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+)
+
+func TestSessionStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Session
+		want []string
+	}{
+		{"zero", Session{}, nil},
+		{"parallel dml", Session{ParallelDML: true}, []string{"ALTER SESSION ENABLE PARALLEL DML"}},
+		{"nls quoted", Session{NLSDateFormat: "YYYY-MM-DD", NLSNumericCharacters: ",."}, []string{
+			"ALTER SESSION SET NLS_DATE_FORMAT = 'YYYY-MM-DD'",
+			"ALTER SESSION SET NLS_NUMERIC_CHARACTERS = ',.'",
+		}},
+		{"quote escaped", Session{NLSDateFormat: `DD "of" MM'`}, []string{`ALTER SESSION SET NLS_DATE_FORMAT = 'DD "of" MM'''`}},
+		{"application info", Session{Module: "csvdb", ClientInfo: "job 7"}, []string{
+			"BEGIN DBMS_APPLICATION_INFO.SET_MODULE(:1, :2); END;",
+			"BEGIN DBMS_APPLICATION_INFO.SET_CLIENT_INFO(:1); END;",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, st := range tt.s.statements() {
+				got = append(got, st.query)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("statements = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionConnector(t *testing.T) {
+	f := sqlfake.New(t)
+	s := Session{ParallelDML: true, Module: "load", Action: "step 1"}
+	db := sql.OpenDB(sessionConnector{Connector: f.Connector(), stmts: s.statements()})
+	defer db.Close()
+	db.SetMaxIdleConns(0) // every use opens a new connection
+
+	for range 2 {
+		if err := db.PingContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	calls := f.Calls()
+	if len(calls) != 4 {
+		t.Fatalf("calls = %v, want setup twice", calls)
+	}
+	if calls[2].Query != "ALTER SESSION ENABLE PARALLEL DML" || !reflect.DeepEqual(calls[3].Args, []any{"load", "step 1"}) {
+		t.Errorf("second connection setup = %v", calls[2:])
+	}
+
+	f.Fail("PARALLEL DML", errors.New("ORA-12838"))
+	if err := db.PingContext(context.Background()); err == nil || !strings.Contains(err.Error(), "session setup") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package oraerr
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"syscall"
+
+	"github.com/sijms/go-ora/v2/network"
+)
+
+// Class groups Oracle errors by how a caller should react
+type Class int
+
+const (
+	Unknown     Class = iota // not recognized as an Oracle or network error
+	Network                  // connection lost or database unreachable; retryable
+	LockTimeout              // row/DDL lock not acquired in time, or deadlock victim; retryable
+	Constraint               // unique, check, foreign key or NOT NULL violation
+	Resource                 // out of space, undo, temp, sessions or memory
+	Cancelled                // statement cancelled (ORA-01013) or context done
+	Other                    // an Oracle error outside the classes above
+)
+
+var classNames = [...]string{"unknown", "network", "lock timeout", "constraint violation", "resource", "cancelled", "oracle error"}
+
+func (c Class) String() string {
+	if c < 0 || int(c) >= len(classNames) {
+		return "Class(" + strconv.Itoa(int(c)) + ")"
+	}
+	return classNames[c]
+}
+
+var codeClass = map[int]Class{
+	// Network / availability
+	1033: Network, 1034: Network, 1089: Network, 1092: Network,
+	3113: Network, 3114: Network, 3135: Network, 3136: Network,
+	12170: Network, 12514: Network, 12516: Network, 12520: Network, 12528: Network,
+	12537: Network, 12541: Network, 12543: Network, 12545: Network, 12547: Network,
+	12560: Network, 12571: Network, 25408: Network,
+
+	// Locks
+	54: LockTimeout, 60: LockTimeout, 4021: LockTimeout, 30006: LockTimeout,
+
+	// Constraints
+	1: Constraint, 1400: Constraint, 1407: Constraint,
+	2290: Constraint, 2291: Constraint, 2292: Constraint,
+
+	// Resources
+	18: Resource, 20: Resource, 1536: Resource, 1555: Resource, 1628: Resource,
+	1650: Resource, 1652: Resource, 1653: Resource, 1654: Resource, 1688: Resource,
+	4030: Resource, 4031: Resource, 30036: Resource,
+
+	// Cancellation
+	1013: Cancelled,
+}
+
+var codeRe = regexp.MustCompile(`ORA-(\d{5})`)
+
+// Code returns the ORA error number of err (1 for ORA-00001), or 0 if there is none.
+// It unwraps go-ora's *network.OracleError and falls back to the first ORA-xxxxx in the message.
+func Code(err error) int {
+	if err == nil {
+		return 0
+	}
+	var oe *network.OracleError
+	if errors.As(err, &oe) && oe.ErrCode != 0 {
+		return oe.ErrCode
+	}
+	if m := codeRe.FindStringSubmatch(err.Error()); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	return 0
+}
+
+// Classify maps err to a Class; nil is Unknown
+func Classify(err error) Class {
+	if err == nil {
+		return Unknown
+	}
+	if code := Code(err); code != 0 {
+		if c, ok := codeClass[code]; ok {
+			return c
+		}
+		return Other
+	}
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return Cancelled
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE):
+		return Network
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return Network
+	}
+	return Unknown
+}
+
+// Retryable reports whether running the same statement again may succeed
+func Retryable(err error) bool {
+	switch Classify(err) {
+	case Network, LockTimeout:
+		return true
+	}
+	return false
+}
+
+// Exit codes for CLIs; 2 is left to the flag package for usage errors
+const (
+	ExitOK          = 0
+	ExitFailure     = 1
+	ExitNetwork     = 3
+	ExitLockTimeout = 4
+	ExitConstraint  = 5
+	ExitResource    = 6
+	ExitCancelled   = 7
+)
+
+// ExitCode picks a process exit code for err so scripts can react to the class
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	switch Classify(err) {
+	case Network:
+		return ExitNetwork
+	case LockTimeout:
+		return ExitLockTimeout
+	case Constraint:
+		return ExitConstraint
+	case Resource:
+		return ExitResource
+	case Cancelled:
+		return ExitCancelled
+	}
+	return ExitFailure
+}
+
+// Fatal logs err with its class and ORA code, then exits with ExitCode(err)
+func Fatal(msg string, err error) {
+	slog.Error(msg, "error", err, "class", Classify(err).String(), "ora_code", Code(err))
+	os.Exit(ExitCode(err))
+}
@@ -0,0 +1,77 @@
+package oraerr
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/sijms/go-ora/v2/network"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+		want Class
+	}{
+		{"nil", nil, 0, Unknown},
+		{"go-ora error", &network.OracleError{ErrCode: 1, ErrMsg: "ORA-00001: unique constraint violated"}, 1, Constraint},
+		{"wrapped go-ora error", fmt.Errorf("insert batch failed: %w", network.NewOracleError(54)), 54, LockTimeout},
+		{"message only", errors.New("merge row 3: ORA-02291: integrity constraint violated - parent key not found"), 2291, Constraint},
+		{"deadlock", errors.New("ORA-00060: deadlock detected while waiting for resource"), 60, LockTimeout},
+		{"wait timeout", errors.New("ORA-30006: resource busy; acquire with WAIT timeout expired"), 30006, LockTimeout},
+		{"connection lost", errors.New("ORA-03113: end-of-file on communication channel"), 3113, Network},
+		{"listener", errors.New("ORA-12541: TNS:no listener"), 12541, Network},
+		{"tablespace full", errors.New("ORA-01653: unable to extend table"), 1653, Resource},
+		{"cancel", errors.New("ORA-01013: user requested cancel of current operation"), 1013, Cancelled},
+		{"other oracle", errors.New("ORA-00942: table or view does not exist"), 942, Other},
+		{"context", fmt.Errorf("run: %w", context.DeadlineExceeded), 0, Cancelled},
+		{"bad conn", driver.ErrBadConn, 0, Network},
+		{"net error", &net.OpError{Op: "dial", Err: errors.New("refused")}, 0, Network},
+		{"plain", errors.New("csv must have at least 2 rows"), 0, Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Code(tt.err); got != tt.code {
+				t.Errorf("Code() = %d, want %d", got, tt.code)
+			}
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryableAndExitCode(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+		exit      int
+	}{
+		{nil, false, ExitOK},
+		{errors.New("ORA-03135: connection lost contact"), true, ExitNetwork},
+		{errors.New("ORA-00054: resource busy"), true, ExitLockTimeout},
+		{errors.New("ORA-00001: unique constraint"), false, ExitConstraint},
+		{errors.New("ORA-01652: unable to extend temp segment"), false, ExitResource},
+		{context.Canceled, false, ExitCancelled},
+		{errors.New("ORA-00942: table or view does not exist"), false, ExitFailure},
+	}
+	for _, tt := range tests {
+		if got := Retryable(tt.err); got != tt.retryable {
+			t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.retryable)
+		}
+		if got := ExitCode(tt.err); got != tt.exit {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.exit)
+		}
+	}
+}
+
+func TestClassString(t *testing.T) {
+	if LockTimeout.String() != "lock timeout" || Class(99).String() != "Class(99)" {
+		t.Error("unexpected Class names")
+	}
+}
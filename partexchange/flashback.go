@@ -0,0 +1,48 @@
+package partexchange
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// currentSCN reads the database SCN, first through DBMS_FLASHBACK (needs
+// EXECUTE on it) and then V$DATABASE (needs SELECT on it)
+func currentSCN(ctx context.Context, db *sql.DB) (int64, error) {
+	var scn int64
+	err := db.QueryRowContext(ctx, "SELECT DBMS_FLASHBACK.GET_SYSTEM_CHANGE_NUMBER FROM DUAL").Scan(&scn)
+	if err == nil {
+		return scn, nil
+	}
+	if err2 := db.QueryRowContext(ctx, "SELECT CURRENT_SCN FROM V$DATABASE").Scan(&scn); err2 != nil {
+		return 0, fmt.Errorf("read current SCN: %w (V$DATABASE: %v)", err, err2)
+	}
+	return scn, nil
+}
+
+// countAsOf counts the rows of from (a table or "T PARTITION (P)") as of scn
+func countAsOf(ctx context.Context, db *sql.DB, from string, scn int64) (int64, error) {
+	var n int64
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+from+" AS OF SCN :1", scn).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count %s as of SCN %d: %w", from, scn, err)
+	}
+	return n, nil
+}
+
+// verifyExchange checks that the partition now holds what staging held at the
+// SCN and staging holds what the partition held
+func verifyExchange(ctx context.Context, db *sql.DB, res *Result, partition, staging string) error {
+	for _, c := range []struct {
+		from string
+		dst  *int64
+	}{{partition, &res.PartitionRowsAfter}, {staging, &res.StagingRowsAfter}} {
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+c.from).Scan(c.dst); err != nil {
+			return fmt.Errorf("count %s: %w", c.from, err)
+		}
+	}
+	if res.PartitionRowsAfter != res.StagingRowsBefore || res.StagingRowsAfter != res.PartitionRowsBefore {
+		return fmt.Errorf("%w: partition %d rows (staging had %d at SCN %d), staging %d rows (partition had %d)",
+			ErrVerify, res.PartitionRowsAfter, res.StagingRowsBefore, res.SCN, res.StagingRowsAfter, res.PartitionRowsBefore)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+//go:build integration
+
+package partexchange
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"sql-learn2/internal/testharness"
+)
+
+// Run with: go test -tags integration ./partexchange (see internal/testharness for ORA_TEST_DSN)
+func TestMain(m *testing.M) { os.Exit(testharness.Main(m)) }
+
+func TestRun_Integration(t *testing.T) {
+	db := testharness.Oracle(t)
+	ctx := context.Background()
+	master := testharness.TableName("PX_MASTER")
+	staging := testharness.TableName("PX_STAGE")
+	testharness.DropOnCleanup(t, db, master, staging)
+
+	// Column types match what csvdb creates for NUMBER/VARCHAR2 CSV columns
+	testharness.Exec(t, db,
+		"CREATE TABLE "+master+" (ID NUMBER, NAME VARCHAR2(255)) "+
+			"PARTITION BY RANGE (ID) (PARTITION P_LOW VALUES LESS THAN (100), PARTITION P_HIGH VALUES LESS THAN (MAXVALUE))",
+		"INSERT INTO "+master+" VALUES (1, 'old')",
+		"INSERT INTO "+master+" VALUES (500, 'other partition')",
+	)
+	path := testharness.WriteCSV(t, "low.csv", "ID,NAME", "NUMBER,VARCHAR2", "1,new", "2,new", "3,new")
+
+	opt := Options{MasterTable: master, StagingTable: staging, PartitionName: "P_LOW", CSVPath: path}
+	if err := Run(ctx, db, opt); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	got := testharness.Rows(t, db, "SELECT ID, NAME FROM "+master+" ORDER BY ID")
+	want := [][]string{{"1", "new"}, {"2", "new"}, {"3", "new"}, {"500", "other partition"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("master rows = %v, want %v", got, want)
+	}
+	// The old partition contents moved into staging
+	if n := testharness.Count(t, db, staging); n != 1 {
+		t.Errorf("staging rows = %d, want 1", n)
+	}
+
+	// DropOldData truncates staging after the exchange
+	opt.DropOldData = true
+	if err := Run(ctx, db, opt); err != nil {
+		t.Fatalf("Run with DropOldData: %v", err)
+	}
+	if n := testharness.Count(t, db, staging); n != 0 {
+		t.Errorf("staging rows after DropOldData = %d, want 0", n)
+	}
+	if n := testharness.Count(t, db, master+" PARTITION (P_LOW)"); n != 3 {
+		t.Errorf("P_LOW rows = %d, want 3", n)
+	}
+}
@@ -5,10 +5,14 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 
 	"sql-learn2/csvdb"
+	"sql-learn2/dynamic"
+	"sql-learn2/loadhistory"
+	"sql-learn2/logging"
+	"sql-learn2/tracing"
 )
 
 // Options describes inputs for the partition-exchange workflow.
@@ -19,8 +23,12 @@ import (
 // CSVPath: path to the CSV file to load into the staging table before exchange.
 // Schema: optional schema/owner to qualify table names. If empty, current schema is used.
 // DropOldData: if true, will TRUNCATE the staging table after exchange to remove old data.
+// Truncate: REUSE STORAGE and DELETE fallback for that truncate.
 // WithoutValidation: if true, use WITHOUT VALIDATION for the exchange (faster, assumes compatibility).
 // IncludingIndexes: if true, add INCLUDING INDEXES clause during exchange.
+// FlashbackVerify: if true, count the partition and staging rows AS OF the SCN recorded
+// before the exchange and check afterwards that the exchange swapped exactly those rows.
+// Logger: optional; defaults to the logger in ctx (see logging.WithLogger).
 // Note: Oracle requires that the staging table is structurally compatible with the partition.
 //
 //	This workflow will create/replace the staging table based on the CSV headers/types.
@@ -32,33 +40,62 @@ type Options struct {
 	CSVPath           string
 	Schema            string
 	DropOldData       bool
+	Truncate          dynamic.TruncateOptions
 	WithoutValidation bool
 	IncludingIndexes  bool
+	FlashbackVerify   bool
+	Logger            *slog.Logger
 }
 
+// Result reports what RunWithResult observed.
+//
+// Manual rollback: while the staging table still holds the old rows
+// (DropOldData false), running the same EXCHANGE PARTITION statement again
+// swaps them back. SCN identifies the pre-exchange state for dependent
+// tables, which can be read with AS OF SCN or restored with FLASHBACK TABLE;
+// the exchanged segments themselves cannot be flashed back across the DDL.
+type Result struct {
+	SCN int64 // recorded just before the exchange; 0 if it could not be read
+
+	// Filled with FlashbackVerify
+	PartitionRowsBefore int64 // AS OF SCN
+	StagingRowsBefore   int64 // AS OF SCN, i.e. the rows loaded from the CSV
+	PartitionRowsAfter  int64
+	StagingRowsAfter    int64
+}
+
+// ErrVerify is returned when FlashbackVerify finds counts that do not match
+var ErrVerify = errors.New("exchange verification failed")
+
 // Run performs: load CSV -> exchange partition -> cleanup old data (truncate staging).
 func Run(ctx context.Context, db *sql.DB, opt Options) error {
+	_, err := RunWithResult(ctx, db, opt)
+	return err
+}
+
+// RunWithResult is Run that also returns the recorded SCN and verification counts
+func RunWithResult(ctx context.Context, db *sql.DB, opt Options) (res Result, err error) {
 	if db == nil {
-		return errors.New("db is nil")
+		return res, errors.New("db is nil")
 	}
 	if strings.TrimSpace(opt.MasterTable) == "" {
-		return errors.New("MasterTable is required")
+		return res, errors.New("MasterTable is required")
 	}
 	if strings.TrimSpace(opt.StagingTable) == "" {
-		return errors.New("StagingTable is required")
+		return res, errors.New("StagingTable is required")
 	}
 	if strings.TrimSpace(opt.PartitionName) == "" {
-		return errors.New("PartitionName is required")
+		return res, errors.New("PartitionName is required")
 	}
 	if strings.TrimSpace(opt.CSVPath) == "" {
-		return errors.New("CSVPath is required")
+		return res, errors.New("CSVPath is required")
 	}
 
 	master := normalizeIdentifierForOracle(opt.MasterTable)
 	staging := normalizeIdentifierForOracle(opt.StagingTable)
 	part := normalizeIdentifierForOracle(opt.PartitionName)
 	if master == "" || staging == "" || part == "" {
-		return fmt.Errorf("invalid identifiers: master=%q staging=%q partition=%q", opt.MasterTable, opt.StagingTable, opt.PartitionName)
+		return res, fmt.Errorf("invalid identifiers: master=%q staging=%q partition=%q", opt.MasterTable, opt.StagingTable, opt.PartitionName)
 	}
 	qual := func(name string) string {
 		if strings.TrimSpace(opt.Schema) == "" {
@@ -67,11 +104,46 @@ func Run(ctx context.Context, db *sql.DB, opt Options) error {
 		return normalizeIdentifierForOracle(opt.Schema) + "." + name
 	}
 
+	logger := opt.Logger
+	if logger == nil {
+		logger = logging.FromContext(ctx)
+	}
+	logger = logger.With(logging.FieldTable, qual(master))
+
+	ctx, run := loadhistory.Start(ctx, loadhistory.WorkflowPartitionExchange, opt.CSVPath, qual(master)+" PARTITION ("+part+")")
+	defer func() { run.End(err) }()
+
+	// One span for the workflow; the CSV load inside shows up as its child spans
+	ctx, span := tracing.Start(ctx, tracing.SpanPartitionExchange,
+		tracing.String(tracing.AttrTable, qual(master)), tracing.String("partition", part),
+		tracing.String("staging", qual(staging)), tracing.String(tracing.AttrFile, opt.CSVPath))
+	defer func() { span.End(err) }()
+
 	// 1) Load CSV into staging table (create/replace based on CSV definition)
-	if err := csvdb.LoadCSVToDBAs(ctx, db, opt.CSVPath, qual(staging)); err != nil {
-		return fmt.Errorf("load csv into staging %s: %w", qual(staging), err)
+	if _, err := csvdb.LoadCSVToDBAs(ctx, db, opt.CSVPath, qual(staging)); err != nil {
+		return res, fmt.Errorf("load csv into staging %s: %w", qual(staging), err)
+	}
+	logger.Info("Loaded CSV into staging table", logging.FieldFile, opt.CSVPath, "staging", qual(staging))
+
+	// Record the SCN before anything changes in the master
+	partition := qual(master) + " PARTITION (" + part + ")"
+	if res.SCN, err = currentSCN(ctx, db); err != nil {
+		if opt.FlashbackVerify {
+			return res, err
+		}
+		logger.Warn("Could not record SCN before exchange", logging.FieldError, err)
+	} else {
+		logger.Info("Recorded SCN before exchange", "scn", res.SCN)
+	}
+	if opt.FlashbackVerify {
+		// Both counts read the state at exactly that SCN; no DDL has happened yet
+		if res.PartitionRowsBefore, err = countAsOf(ctx, db, partition, res.SCN); err != nil {
+			return res, err
+		}
+		if res.StagingRowsBefore, err = countAsOf(ctx, db, qual(staging), res.SCN); err != nil {
+			return res, err
+		}
 	}
-	log.Printf("Loaded CSV %s into staging table %s", opt.CSVPath, qual(staging))
 
 	// 2) Exchange partition
 	// Build ALTER TABLE statement
@@ -84,20 +156,28 @@ func Run(ctx context.Context, db *sql.DB, opt Options) error {
 	}
 	stmt := fmt.Sprintf("ALTER TABLE %s EXCHANGE PARTITION %s WITH TABLE %s%s", qual(master), part, qual(staging), clause)
 	if _, err := db.ExecContext(ctx, stmt); err != nil {
-		return fmt.Errorf("exchange partition: %w", err)
+		return res, fmt.Errorf("exchange partition: %w", err)
+	}
+	logger.Info("Exchanged partition", "partition", part, "staging", qual(staging))
+
+	if opt.FlashbackVerify {
+		if err := verifyExchange(ctx, db, &res, partition, qual(staging)); err != nil {
+			return res, fmt.Errorf("%w (to roll back, run again before truncating staging: %s)", err, stmt)
+		}
+		logger.Info("Verified exchange against SCN", "scn", res.SCN,
+			"partition_rows", res.PartitionRowsAfter, "staging_rows", res.StagingRowsAfter)
 	}
-	log.Printf("Exchanged partition %s of %s with table %s", part, qual(master), qual(staging))
 
 	// 3) Delete old data: after exchange, old data moves into staging; truncate it if requested
 	if opt.DropOldData {
-		trunc := fmt.Sprintf("TRUNCATE TABLE %s", qual(staging))
-		if _, err := db.ExecContext(ctx, trunc); err != nil {
-			return fmt.Errorf("truncate staging after exchange: %w", err)
+		deleted, err := dynamic.TruncateTable(ctx, db, qual(staging), opt.Truncate)
+		if err != nil {
+			return res, fmt.Errorf("truncate staging after exchange: %w", err)
 		}
-		log.Printf("Truncated staging table %s to remove old data", qual(staging))
+		logger.Info("Truncated staging table to remove old data", "staging", qual(staging), "deleted", deleted)
 	}
 
-	return nil
+	return res, nil
 }
 
 func normalizeIdentifierForOracle(s string) string {
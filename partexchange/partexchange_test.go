@@ -0,0 +1,175 @@
+package partexchange
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+	"sql-learn2/loadhistory"
+	"sql-learn2/logging"
+)
+
+var quiet = logging.WithLogger(context.Background(), logging.Discard())
+
+func TestRun_SQL(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Options
+		want []string // statements after the staging load
+	}{
+		{
+			name: "plain",
+			opt:  Options{MasterTable: "sales", StagingTable: "sales_stg", PartitionName: "p_2024"},
+			want: []string{"ALTER TABLE SALES EXCHANGE PARTITION P_2024 WITH TABLE SALES_STG"},
+		},
+		{
+			name: "all clauses",
+			opt: Options{MasterTable: "sales", StagingTable: "sales_stg", PartitionName: "p_2024",
+				IncludingIndexes: true, WithoutValidation: true, DropOldData: true},
+			want: []string{
+				"ALTER TABLE SALES EXCHANGE PARTITION P_2024 WITH TABLE SALES_STG INCLUDING INDEXES WITHOUT VALIDATION",
+				"TRUNCATE TABLE SALES_STG",
+			},
+		},
+		{
+			name: "schema",
+			opt:  Options{MasterTable: "sales", StagingTable: "sales_stg", PartitionName: "p_2024", Schema: "app"},
+			want: []string{"ALTER TABLE APP.SALES EXCHANGE PARTITION P_2024 WITH TABLE APP.SALES_STG"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			f.OnQuery("GET_SYSTEM_CHANGE_NUMBER", []string{"SCN"}, []any{int64(4711)})
+			tt.opt.CSVPath = testharness.WriteCSV(t, "p.csv", "id", "NUMBER", "1")
+			if err := Run(quiet, f.DB, tt.opt); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			queries := f.Queries()
+			// exists check, CREATE TABLE and one INSERT come from csvdb
+			if len(queries) < 3 || !strings.HasPrefix(queries[1], "CREATE TABLE") || !strings.HasPrefix(queries[2], "INSERT INTO") {
+				t.Fatalf("expected the csvdb staging load first, got %q", queries)
+			}
+			want := append([]string{"SELECT DBMS_FLASHBACK.GET_SYSTEM_CHANGE_NUMBER FROM DUAL"}, tt.want...)
+			if got := queries[3:]; !reflect.DeepEqual(got, want) {
+				t.Errorf("statements:\n got %q\nwant %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	valid := Options{MasterTable: "m", StagingTable: "s", PartitionName: "p"}
+	tests := []struct {
+		name    string
+		opt     Options
+		fail    string
+		wantErr string
+	}{
+		{"missing master", Options{StagingTable: "s", PartitionName: "p"}, "", "MasterTable is required"},
+		{"missing partition", Options{MasterTable: "m", StagingTable: "s"}, "", "PartitionName is required"},
+		{"load fails", valid, "^CREATE", "load csv into staging S"},
+		{"exchange fails", valid, "^ALTER TABLE", "exchange partition: boom"},
+		{"truncate fails", Options{MasterTable: "m", StagingTable: "s", PartitionName: "p", DropOldData: true},
+			"^TRUNCATE", "truncate staging after exchange: truncate S: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			if tt.fail != "" {
+				f.Fail(tt.fail, errors.New("boom"))
+			}
+			tt.opt.CSVPath = testharness.WriteCSV(t, "p.csv", "id", "NUMBER", "1")
+			err := Run(quiet, f.DB, tt.opt)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRun_History(t *testing.T) {
+	f := sqlfake.New(t)
+	f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+	ctx := loadhistory.WithWriter(quiet, &loadhistory.Writer{DB: f.DB})
+	opt := Options{MasterTable: "m", StagingTable: "s", PartitionName: "p",
+		CSVPath: testharness.WriteCSV(t, "p.csv", "id", "NUMBER", "1", "2")}
+	if err := Run(ctx, f.DB, opt); err != nil {
+		t.Fatal(err)
+	}
+	var got [][]any
+	for _, c := range f.Calls() {
+		if strings.HasPrefix(c.Query, "INSERT INTO LOAD_HISTORY") {
+			got = append(got, c.Args)
+		}
+	}
+	// one row for the exchange; the staging load is part of it
+	if len(got) != 1 {
+		t.Fatalf("history rows = %d, want 1", len(got))
+	}
+	if got[0][0] != loadhistory.WorkflowPartitionExchange || got[0][2] != "M PARTITION (P)" || got[0][3] != int64(2) || got[0][7] != loadhistory.StatusOK {
+		t.Errorf("history = %v", got[0][:8])
+	}
+}
+
+func TestRunWithResult_FlashbackVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		after   [2]int64 // partition, staging counts after the exchange
+		scnErr  bool
+		wantErr string
+	}{
+		{"match", [2]int64{2, 5}, false, ""},
+		{"mismatch", [2]int64{2, 4}, false, "exchange verification failed: partition 2 rows (staging had 2 at SCN 4711), staging 4 rows (partition had 5)"},
+		{"scn fallback", [2]int64{2, 5}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("USER_TABLES", []string{"COUNT(1)"}, []any{int64(0)})
+			if tt.scnErr {
+				f.Fail("DBMS_FLASHBACK", errors.New("ORA-00904"))
+				f.OnQuery("V\\$DATABASE", []string{"SCN"}, []any{int64(4711)})
+			} else {
+				f.OnQuery("GET_SYSTEM_CHANGE_NUMBER", []string{"SCN"}, []any{int64(4711)})
+			}
+			f.OnQuery(`^SELECT COUNT\(\*\) FROM M PARTITION \(P\) AS OF SCN`, []string{"N"}, []any{int64(5)})
+			f.OnQuery(`^SELECT COUNT\(\*\) FROM S AS OF SCN`, []string{"N"}, []any{int64(2)})
+			f.OnQuery(`^SELECT COUNT\(\*\) FROM M PARTITION \(P\)$`, []string{"N"}, []any{tt.after[0]})
+			f.OnQuery(`^SELECT COUNT\(\*\) FROM S$`, []string{"N"}, []any{tt.after[1]})
+			opt := Options{MasterTable: "m", StagingTable: "s", PartitionName: "p", FlashbackVerify: true, DropOldData: true,
+				CSVPath: testharness.WriteCSV(t, "p.csv", "id", "NUMBER", "1", "2")}
+
+			res, err := RunWithResult(quiet, f.DB, opt)
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrVerify) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				for _, q := range f.Queries() {
+					if strings.HasPrefix(q, "TRUNCATE") {
+						t.Error("staging truncated after a failed verification")
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := Result{SCN: 4711, PartitionRowsBefore: 5, StagingRowsBefore: 2, PartitionRowsAfter: 2, StagingRowsAfter: 5}
+			if res != want {
+				t.Errorf("result = %+v, want %+v", res, want)
+			}
+			for _, c := range f.Calls() {
+				if strings.Contains(c.Query, "AS OF SCN") && c.Args[0] != int64(4711) {
+					t.Errorf("%s bound %v", c.Query, c.Args)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"sql-learn2/oraerr"
+)
+
+// Policy describes how often and how patiently an operation is retried.
+// The zero value runs the operation once.
+type Policy struct {
+	MaxAttempts int           // total attempts including the first (default 1)
+	Initial     time.Duration // wait before the second attempt (default 100ms)
+	Max         time.Duration // cap on a single wait (0 = no cap)
+	Multiplier  float64       // wait growth per attempt (default 2)
+	Jitter      float64       // fraction of each wait that is randomized, 0..1
+
+	// Retryable decides which errors are worth another attempt (default oraerr.Retryable)
+	Retryable func(error) bool
+	// OnRetry is called before each wait, e.g. to log the failed attempt
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// Default retries network errors and lock timeouts a few times within a few seconds
+var Default = Policy{MaxAttempts: 4, Initial: 200 * time.Millisecond, Max: 5 * time.Second, Multiplier: 2, Jitter: 0.2}
+
+// RetryableCodes returns a Policy.Retryable that accepts what
+// oraerr.Retryable does plus the errors with one of codes, given as ORA
+// numbers (1555 for ORA-01555)
+func RetryableCodes(codes ...int) func(error) bool {
+	return func(err error) bool {
+		return oraerr.Retryable(err) || slices.Contains(codes, oraerr.Code(err))
+	}
+}
+
+// ParseCodes reads a comma-separated list of ORA numbers for RetryableCodes,
+// as "1555, ORA-08177" or "8177"
+func ParseCodes(s string) ([]int, error) {
+	var codes []int
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(f), "ORA-"))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid ORA error number %q", f)
+		}
+		codes = append(codes, n)
+	}
+	return codes, nil
+}
+
+// Backoff returns the wait after the given failed attempt (1-based), before jitter
+func (p Policy) Backoff(attempt int) time.Duration {
+	d := p.Initial
+	if d <= 0 {
+		d = 100 * time.Millisecond
+	}
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 2
+	}
+	for i := 1; i < attempt; i++ {
+		d = time.Duration(float64(d) * mult)
+		if p.Max > 0 && d >= p.Max {
+			return p.Max
+		}
+	}
+	if p.Max > 0 && d > p.Max {
+		return p.Max
+	}
+	return d
+}
+
+// wait is Backoff with up to Jitter of it added or removed at random
+func (p Policy) wait(attempt int) time.Duration {
+	d := p.Backoff(attempt)
+	if j := min(p.Jitter, 1); j > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * j * float64(d))
+	}
+	return d
+}
+
+// Do runs fn until it succeeds, fails with a non-retryable error, the
+// attempts are used up or ctx is done. It returns fn's last error.
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	attempts := max(p.MaxAttempts, 1)
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = oraerr.Retryable
+	}
+	var err error
+	for i := 1; ; i++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if i >= attempts || !retryable(err) {
+			return err
+		}
+		d := p.wait(i)
+		if p.OnRetry != nil {
+			p.OnRetry(i, err, d)
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-t.C:
+		}
+	}
+}
+
+type ctxKey struct{}
+
+// WithPolicy attaches p to ctx for packages with ctx-only APIs (bulkinsert)
+func WithPolicy(ctx context.Context, p Policy) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext returns the Policy attached by WithPolicy, or the zero Policy (no retries)
+func FromContext(ctx context.Context) Policy {
+	if ctx != nil {
+		if p, ok := ctx.Value(ctxKey{}).(Policy); ok {
+			return p
+		}
+	}
+	return Policy{}
+}
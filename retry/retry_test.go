@@ -0,0 +1,146 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var (
+	errLock  = errors.New("ORA-00054: resource busy and acquire with NOWAIT specified")
+	errConst = errors.New("ORA-00001: unique constraint violated")
+)
+
+func TestDo(t *testing.T) {
+	fast := Policy{MaxAttempts: 3, Initial: time.Millisecond}
+	tests := []struct {
+		name      string
+		errs      []error // returned by successive calls; nil afterwards
+		wantCalls int
+		wantErr   error
+	}{
+		{"success", nil, 1, nil},
+		{"retryable then success", []error{errLock, errLock}, 3, nil},
+		{"retryable exhausted", []error{errLock, errLock, errLock, errLock}, 3, errLock},
+		{"not retryable", []error{errConst}, 1, errConst},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := Do(context.Background(), fast, func(context.Context) error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDo_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := Policy{MaxAttempts: 5, Initial: time.Hour, OnRetry: func(int, error, time.Duration) { cancel() }}
+	err := Do(ctx, p, func(context.Context) error { return errLock })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestDo_CustomRetryable(t *testing.T) {
+	calls := 0
+	p := Policy{MaxAttempts: 2, Initial: time.Millisecond, Retryable: func(error) bool { return true }}
+	_ = Do(context.Background(), p, func(context.Context) error {
+		calls++
+		return errConst
+	})
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 3}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 300 * time.Millisecond},
+		{3, 900 * time.Millisecond},
+		{4, time.Second},
+		{10, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.attempt), func(t *testing.T) {
+			if got := p.Backoff(tt.attempt); got != tt.want {
+				t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitJitter(t *testing.T) {
+	p := Policy{Initial: 100 * time.Millisecond, Jitter: 0.5}
+	for range 100 {
+		if d := p.wait(1); d < 50*time.Millisecond || d > 150*time.Millisecond {
+			t.Fatalf("wait %v outside jitter range", d)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if p := FromContext(context.Background()); p.MaxAttempts != 0 {
+		t.Errorf("expected zero policy, got %+v", p)
+	}
+	if p := FromContext(WithPolicy(context.Background(), Default)); p.MaxAttempts != Default.MaxAttempts {
+		t.Errorf("expected attached policy, got %+v", p)
+	}
+}
+
+func TestRetryableCodes(t *testing.T) {
+	retryable := RetryableCodes(1555, 8177)
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errLock, true},
+		{errors.New("ORA-01555: snapshot too old"), true},
+		{fmt.Errorf("insert: %w", errors.New("ORA-08177: can't serialize access")), true},
+		{errConst, false},
+		{errors.New("plain"), false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Errorf("RetryableCodes(1555, 8177)(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestParseCodes(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []int
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"1555, ORA-08177", []int{1555, 8177}, false},
+		{"ora-60,", []int{60}, false},
+		{"snapshot", nil, true},
+		{"-5", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCodes(tt.in)
+		if (err != nil) != tt.wantErr || fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("ParseCodes(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -6,45 +6,27 @@ import (
 	"fmt"
 	"time"
 
+	"sql-learn2/oraconn"
+
 	"github.com/jmoiron/sqlx"
 )
 
-// OpenOracle opens a go-ora connection using sqlx and verifies connectivity with Ping.
-// It configures the connection pool based on concurrency level to handle:
+// OpenOracle connects through oraconn with a pool sized for the concurrency level, covering:
 // - Concurrent pollers
 // - Bulk insert operations
 // - DBMS_MVIEW.REFRESH operations
-func OpenOracle(ctx context.Context, connString string, concurrency int) (*sqlx.DB, error) {
-	db, err := sqlx.Connect("oracle", connString)
-	if err != nil {
-		return nil, fmt.Errorf("connect: %w", err)
-	}
-
-	// Configure connection pool for optimal concurrency handling
-	// MaxOpenConns: Allow enough connections for all pollers + bulk operations + refresh
-	// Formula: concurrency (pollers) * 3 (safety margin) + 5 (buffer for bulk/refresh ops)
-	maxOpen := concurrency*3 + 5
-	if maxOpen < 10 {
-		maxOpen = 10 // Minimum pool size
-	}
-
-	// MaxIdleConns: Keep enough idle connections to avoid setup overhead
-	// Formula: concurrency (pollers) + 3 (buffer for operations)
-	maxIdle := concurrency + 3
-	if maxIdle < 5 {
-		maxIdle = 5 // Minimum idle connections
-	}
-
-	db.SetMaxOpenConns(maxOpen)
-	db.SetMaxIdleConns(maxIdle)
-	db.SetConnMaxLifetime(5 * time.Minute) // Recycle connections every 5 minutes
-	db.SetConnMaxIdleTime(2 * time.Minute) // Close idle connections after 2 minutes
+func OpenOracle(ctx context.Context, cfg oraconn.Config, concurrency int) (*sqlx.DB, error) {
+	cfg.MaxOpenConns, cfg.MaxIdleConns = poolSize(concurrency)
+	cfg.ConnMaxLifetime = 5 * time.Minute // Recycle connections every 5 minutes
+	cfg.ConnMaxIdleTime = 2 * time.Minute // Close idle connections after 2 minutes
+	return oraconn.ConnectX(ctx, cfg)
+}
 
-	if err := db.PingContext(ctx); err != nil {
-		_ = db.Close()
-		return nil, fmt.Errorf("ping: %w", err)
-	}
-	return db, nil
+// poolSize returns MaxOpenConns and MaxIdleConns for the given number of pollers.
+// MaxOpenConns: concurrency (pollers) * 3 (safety margin) + 5 (buffer for bulk/refresh ops), at least 10
+// MaxIdleConns: concurrency (pollers) + 3 (buffer for operations), at least 5
+func poolSize(concurrency int) (maxOpen, maxIdle int) {
+	return max(concurrency*3+5, 10), max(concurrency+3, 5)
 }
 
 // FetchMaxCreated returns MAX(CREATED_AT) formatted as 'YYYY-MM-DD HH24:MI:SS'.
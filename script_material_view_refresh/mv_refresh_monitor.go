@@ -5,10 +5,14 @@ import (
 	"encoding/csv"
 	"fmt"
 	"log"
+	"os"
 	"sort"
+	"strings"
 	"sync/atomic"
 	"time"
 
+	"sql-learn2/tracing"
+
 	"github.com/jmoiron/sqlx"
 	_ "github.com/sijms/go-ora/v2"
 )
@@ -18,14 +22,27 @@ import (
 // until it observes the CREATED_AT change. It records timings and writes a CSV log.
 
 func main() {
+	os.Exit(run())
+}
+
+// run returns the exit code once the deferred tracing shutdown has flushed
+// the spans, which os.Exit in main would otherwise skip on a failed run
+func run() int {
 	start := time.Now()
 	log.Printf("App start: MV Refresh Monitor at %s", start.Format(time.RFC3339Nano))
 	cfg := ParseConfig()
+	shutdownTracing, err := tracing.Setup(context.Background(), "mv-refresh-monitor")
+	if err != nil {
+		log.Printf("tracing: %v", err)
+		return 1
+	}
+	defer shutdownTracing(context.Background())
 	if err := runMonitor(cfg); err != nil {
 		log.Printf("App end (error) after %s: %v", time.Since(start), err)
-		log.Fatalf("mv monitor: %v", err)
+		return 1
 	}
 	log.Printf("App end (ok) after %s", time.Since(start))
+	return 0
 }
 
 // runMonitor orchestrates the end-to-end workflow using smaller helpers.
@@ -33,18 +50,15 @@ func runMonitor(cfg Config) error {
 	// Connect
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	connString, err := ResolveDSN(cfg)
-	if err != nil {
-		return err
-	}
-	db, err := OpenOracle(ctx, connString, cfg.Concurrency)
+	db, err := OpenOracle(ctx, cfg.Oracle, cfg.Concurrency)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
-	log.Printf("Connected: oracle://%s:***@%s:%s/%s (driver go-ora)", cfg.User, cfg.Host, cfg.Port, cfg.Service)
+	log.Printf("Connected: %s (driver go-ora)", cfg.Oracle)
+	maxOpen, maxIdle := poolSize(cfg.Concurrency)
 	log.Printf("Connection pool configured: concurrency=%d, maxOpen=%d, maxIdle=%d",
-		cfg.Concurrency, cfg.Concurrency*3+5, cfg.Concurrency+3)
+		cfg.Concurrency, maxOpen, maxIdle)
 
 	// CSV output
 	csvFile, w, csvPath, err := PrepareCSV(cfg.OutCSV)
@@ -58,29 +72,32 @@ func runMonitor(cfg Config) error {
 	baseline := determineBaseline(ctx, db, cfg.Table)
 	log.Printf("Baseline %s MAX(CREATED_AT)=%q", cfg.Table, baseline)
 
-	// Pollers
-	samples, wg, congestionCounter := StartPollers(ctx, db, cfg.Table, baseline, cfg.Concurrency, cfg.Interval, cfg.TPS, cfg.MaxCongestion, cfg.QueryTimeout)
+	// Pollers, with a health checker that resets the pool after a lost connection
+	health := NewHealthChecker(db, cfg.Health, maxIdle)
+	samples, wg, congestionCounter := StartPollers(ctx, db, cfg.Table, baseline, cfg.Concurrency, cfg.Interval, cfg.TPS, cfg.MaxCongestion, cfg.QueryTimeout, health)
+	StartHealthChecker(ctx, health, samples)
 
 	// Trigger
 	triggerAt, resultCh := startTrigger(ctx, db, cfg)
 
 	// Aggregate
 	observeEnd := computeObserveEnd(cfg, triggerAt)
-	firstChangeAt, firstChangeVal, finalBaseline, totalPolls, totalSuccess, totalErrors, p90, maxCongestion := aggregate(samples, w, baseline, !cfg.Quiet, observeEnd, congestionCounter)
+	firstChangeAt, firstChangeVal, finalBaseline, totalPolls, totalSuccess, totalErrors, p90, maxCongestion := aggregate(samples, w, baseline, !cfg.Quiet, observeEnd, congestionCounter, cfg.SLO)
+
+	// In SLO mode we stop at the first change, so give the script until the end of the
+	// observation window to finish before the shared context is cancelled; the
+	// pollers block on the full samples channel meanwhile and exit on cancel.
+	var wait time.Duration
+	if cfg.SLO {
+		wait = time.Until(observeEnd)
+	}
+	scriptStart, scriptEnd, scriptErr := collectScriptResult(resultCh, wait)
 
 	// Cleanup pollers
 	cancel()
 	wg.Wait()
 	w.Flush()
 
-	// Collect trigger outcome (non-blocking if already done)
-	var scriptStart, scriptEnd time.Time
-	var scriptErr error
-	select {
-	case res := <-resultCh:
-		scriptStart, scriptEnd, scriptErr = res.start, res.end, res.err
-	default:
-	}
 	if scriptErr != nil {
 		log.Printf("ERROR running simulate script: %v", scriptErr)
 	} else if !scriptStart.IsZero() && !scriptEnd.IsZero() {
@@ -88,9 +105,42 @@ func runMonitor(cfg Config) error {
 	}
 
 	printSummary(cfg.Table, csvPath, finalBaseline, triggerAt, observeEnd, scriptStart, scriptEnd, firstChangeAt, firstChangeVal, totalPolls, totalSuccess, totalErrors, p90, maxCongestion)
+
+	if cfg.SLO {
+		lagFrom := scriptStart
+		if lagFrom.IsZero() {
+			lagFrom = triggerAt
+		}
+		violations := evaluateSLO(cfg, lagFrom, firstChangeAt, p90, scriptErr)
+		printSLO(cfg, lagFrom, firstChangeAt, p90, violations)
+		if len(violations) > 0 {
+			return fmt.Errorf("SLO failed: %s", strings.Join(violations, "; "))
+		}
+	}
 	return nil
 }
 
+// collectScriptResult returns the trigger outcome, waiting up to wait for it to arrive.
+// A zero or negative wait makes it non-blocking (zero times if the script is still running).
+func collectScriptResult(resultCh <-chan scriptResult, wait time.Duration) (time.Time, time.Time, error) {
+	if wait <= 0 {
+		select {
+		case res := <-resultCh:
+			return res.start, res.end, res.err
+		default:
+			return time.Time{}, time.Time{}, nil
+		}
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case res := <-resultCh:
+		return res.start, res.end, res.err
+	case <-timer.C:
+		return time.Time{}, time.Time{}, nil
+	}
+}
+
 func determineBaseline(ctx context.Context, db *sqlx.DB, table string) string {
 	b, err := FetchMaxCreated(ctx, db, table)
 	if err != nil {
@@ -132,7 +182,8 @@ func startTrigger(ctx context.Context, db *sqlx.DB, cfg Config) (time.Time, <-ch
 }
 
 // aggregate consumes poll samples until observeEnd and writes CSV rows.
-func aggregate(samples <-chan PollSample, w *csv.Writer, baseline string, verbose bool, observeEnd time.Time, congestionCounter *int64) (time.Time, string, string, int, int, int, time.Duration, int) {
+// If stopOnChange is set, it returns as soon as the first change is observed.
+func aggregate(samples <-chan PollSample, w *csv.Writer, baseline string, verbose bool, observeEnd time.Time, congestionCounter *int64, stopOnChange bool) (time.Time, string, string, int, int, int, time.Duration, int) {
 	var firstChangeAt time.Time
 	var firstChangeVal string
 	var windowCount, windowErr, windowChanged int
@@ -161,6 +212,15 @@ func aggregate(samples <-chan PollSample, w *csv.Writer, baseline string, verbos
 	for {
 		select {
 		case s := <-samples:
+			if s.Event != "" {
+				log.Printf("Connection health: %s (ping %v, err=%v)", s.Event, s.Duration, s.Err)
+				errStr := ""
+				if s.Err != nil {
+					errStr = s.Err.Error()
+				}
+				_ = w.Write([]string{s.When.Format(time.RFC3339Nano), "health", errStr, "false", s.Event})
+				break
+			}
 			totalPolls++
 			durations = append(durations, s.Duration)             // Collect duration for overall p90 calculation
 			windowDurations = append(windowDurations, s.Duration) // Collect duration for window p90 calculation
@@ -191,7 +251,10 @@ func aggregate(samples <-chan PollSample, w *csv.Writer, baseline string, verbos
 			if s.Changed {
 				windowChanged++
 			}
-			_ = w.Write([]string{s.When.Format(time.RFC3339Nano), fmt.Sprintf("%d", s.WorkerID), safeCSV(s.Value), fmt.Sprintf("%t", s.Changed)})
+			_ = w.Write([]string{s.When.Format(time.RFC3339Nano), fmt.Sprintf("%d", s.WorkerID), safeCSV(s.Value), fmt.Sprintf("%t", s.Changed), ""})
+			if stopOnChange && !firstChangeAt.IsZero() {
+				return firstChangeAt, firstChangeVal, currentBaseline, totalPolls, totalSuccess, totalErrors, calculateP90(durations), maxCongestion
+			}
 		case <-ticker.C:
 			if verbose {
 				windowP90 := calculateP90(windowDurations)
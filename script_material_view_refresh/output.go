@@ -35,7 +35,7 @@ func PrepareCSV(outPath string) (*os.File, *csv.Writer, string, error) {
 		return nil, nil, "", err
 	}
 	w := csv.NewWriter(f)
-	_ = w.Write([]string{"ts", "worker", "value", "changed"})
+	_ = w.Write([]string{"ts", "worker", "value", "changed", "event"})
 	w.Flush()
 	return f, w, csvPath, nil
 }
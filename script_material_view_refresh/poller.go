@@ -9,6 +9,9 @@ import (
 	"sync/atomic"
 	"time"
 
+	"sql-learn2/oraconn"
+	"sql-learn2/oraerr"
+
 	"github.com/jmoiron/sqlx"
 )
 
@@ -21,6 +24,7 @@ type PollSample struct {
 	Changed    bool
 	Duration   time.Duration // Total query duration for this poll
 	Congestion int           // Number of concurrent in-flight queries at sample time
+	Event      string        // oraconn health event kind; such samples are not polls
 }
 
 // StartPollers launches N goroutines that poll CREATED_AT from a randomly chosen row
@@ -29,8 +33,9 @@ type PollSample struct {
 // If TPS <= 0, falls back to interval-based polling per worker.
 // MaxCongestion sets a hard limit on concurrent in-flight queries.
 // QueryTimeout sets the timeout for individual queries.
+// A query failing with a connection error kicks health (if not nil) to check and reset the pool.
 // Returns: samples channel, wait group, and pointer to the congestion counter for real-time monitoring.
-func StartPollers(ctx context.Context, db *sqlx.DB, table, baseline string, concurrency int, interval time.Duration, tps int, maxCongestion int, queryTimeout time.Duration) (chan PollSample, *sync.WaitGroup, *int64) {
+func StartPollers(ctx context.Context, db *sqlx.DB, table, baseline string, concurrency int, interval time.Duration, tps int, maxCongestion int, queryTimeout time.Duration, health *oraconn.HealthChecker) (chan PollSample, *sync.WaitGroup, *int64) {
 	samples := make(chan PollSample, concurrency*4)
 	var wg sync.WaitGroup
 	congestionCounter := new(int64) // Atomic counter for in-flight queries (heap-allocated for external access)
@@ -49,6 +54,15 @@ func StartPollers(ctx context.Context, db *sqlx.DB, table, baseline string, conc
 		// and no longer reference rateLimiter channel
 	}
 
+	// send gives up once ctx is done, so a caller that stopped reading
+	// samples can still cancel and wait for the workers
+	send := func(s PollSample) {
+		select {
+		case samples <- s:
+		case <-ctx.Done():
+		}
+	}
+
 	// Worker function that executes a single poll
 	executePoll := func(workerID int, rng *rand.Rand) {
 		when := time.Now()
@@ -58,7 +72,7 @@ func StartPollers(ctx context.Context, db *sqlx.DB, table, baseline string, conc
 		currentCongestion := atomic.LoadInt64(congestionCounter)
 		if maxCongestion > 0 && currentCongestion >= int64(maxCongestion) {
 			// Instantly fail due to congestion limit
-			samples <- PollSample{
+			send(PollSample{
 				When:       when,
 				WorkerID:   workerID,
 				Value:      "",
@@ -66,7 +80,7 @@ func StartPollers(ctx context.Context, db *sqlx.DB, table, baseline string, conc
 				Changed:    false,
 				Duration:   time.Since(pollStart),
 				Congestion: int(currentCongestion),
-			}
+			})
 			return
 		}
 
@@ -83,12 +97,15 @@ func StartPollers(ctx context.Context, db *sqlx.DB, table, baseline string, conc
 		var maxID sql.NullInt64
 		err := db.QueryRowContext(queryCtx, maxIDQry).Scan(&maxID)
 		if err != nil {
-			samples <- PollSample{When: when, WorkerID: workerID, Value: "", Err: err, Changed: false, Duration: time.Since(pollStart), Congestion: congestion}
+			if health != nil && oraerr.Classify(err) == oraerr.Network {
+				health.Kick()
+			}
+			send(PollSample{When: when, WorkerID: workerID, Value: "", Err: err, Changed: false, Duration: time.Since(pollStart), Congestion: congestion})
 			return
 		}
 		if !maxID.Valid || maxID.Int64 <= 0 {
 			// Table empty or invalid MAX(id)
-			samples <- PollSample{When: when, WorkerID: workerID, Value: "", Err: nil, Changed: false, Duration: time.Since(pollStart), Congestion: congestion}
+			send(PollSample{When: when, WorkerID: workerID, Value: "", Err: nil, Changed: false, Duration: time.Since(pollStart), Congestion: congestion})
 			return
 		}
 
@@ -107,7 +124,7 @@ func StartPollers(ctx context.Context, db *sqlx.DB, table, baseline string, conc
 		}
 		// If after attempts no valid value, keep val as empty and report last error if any
 		changed := baseline != "" && val != "" && val != baseline
-		samples <- PollSample{When: when, WorkerID: workerID, Value: val, Err: pickErr, Changed: changed, Duration: time.Since(pollStart), Congestion: congestion}
+		send(PollSample{When: when, WorkerID: workerID, Value: val, Err: pickErr, Changed: changed, Duration: time.Since(pollStart), Congestion: congestion})
 	}
 
 	// Launch workers
@@ -150,3 +167,29 @@ func StartPollers(ctx context.Context, db *sqlx.DB, table, baseline string, conc
 	}
 	return samples, &wg, congestionCounter
 }
+
+// NewHealthChecker returns a checker pinging the pool every interval, or nil when interval is 0
+func NewHealthChecker(db *sqlx.DB, interval time.Duration, maxIdle int) *oraconn.HealthChecker {
+	if interval <= 0 {
+		return nil
+	}
+	h := oraconn.NewHealthChecker(db.DB)
+	h.Interval = interval
+	h.MaxIdle = maxIdle
+	return h
+}
+
+// StartHealthChecker runs h, reporting lost and re-established connections
+// into samples so they land in the CSV next to the polls they affected
+func StartHealthChecker(ctx context.Context, h *oraconn.HealthChecker, samples chan<- PollSample) {
+	if h == nil {
+		return
+	}
+	h.OnEvent = func(e oraconn.HealthEvent) {
+		select {
+		case samples <- PollSample{When: e.When, WorkerID: -1, Err: e.Err, Duration: e.Latency, Event: e.Kind}:
+		case <-ctx.Done():
+		}
+	}
+	go h.Run(ctx)
+}
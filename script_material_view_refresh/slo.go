@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// evaluateSLO checks the run against the declared budgets and returns one message per violation.
+// An empty result means the run passed.
+func evaluateSLO(cfg Config, lagFrom, firstChangeAt time.Time, p90 time.Duration, scriptErr error) []string {
+	var violations []string
+	if scriptErr != nil {
+		violations = append(violations, fmt.Sprintf("simulate script failed: %v", scriptErr))
+	}
+	if firstChangeAt.IsZero() {
+		violations = append(violations, fmt.Sprintf("no change observed within %s", cfg.Observe))
+	} else if lag := firstChangeAt.Sub(lagFrom); cfg.SLOMaxLag > 0 && lag > cfg.SLOMaxLag {
+		violations = append(violations, fmt.Sprintf("lag %s exceeds budget %s", lag, cfg.SLOMaxLag))
+	}
+	if cfg.SLOMaxP90 > 0 && p90 > cfg.SLOMaxP90 {
+		violations = append(violations, fmt.Sprintf("p90 %s exceeds budget %s", p90, cfg.SLOMaxP90))
+	}
+	return violations
+}
+
+// printSLO prints the SLO verdict below the regular summary.
+func printSLO(cfg Config, lagFrom, firstChangeAt time.Time, p90 time.Duration, violations []string) {
+	fmt.Println("==== SLO ====")
+	if firstChangeAt.IsZero() {
+		fmt.Printf("Lag:  not observed (budget %s)\n", cfg.SLOMaxLag)
+	} else {
+		fmt.Printf("Lag:  %s (budget %s)\n", firstChangeAt.Sub(lagFrom), cfg.SLOMaxLag)
+	}
+	fmt.Printf("P90:  %s (budget %s)\n", p90, cfg.SLOMaxP90)
+	if len(violations) == 0 {
+		fmt.Println("Result: PASS")
+		return
+	}
+	fmt.Println("Result: FAIL")
+	for _, v := range violations {
+		fmt.Printf("  - %s\n", v)
+	}
+}
@@ -0,0 +1,126 @@
+package sessioncheck
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultModule matches the MODULE the tools set through oraconn.Session
+const DefaultModule = "sql-learn2%"
+
+// ToolSession is a session opened by one of the tools, with what it holds
+type ToolSession struct {
+	Session
+	Username    string
+	Module      string // V$SESSION.MODULE, set with DBMS_APPLICATION_INFO
+	Action      string
+	Machine     string
+	Status      string        // ACTIVE, INACTIVE, KILLED, ...
+	Idle        time.Duration // V$SESSION.LAST_CALL_ET
+	TxAge       time.Duration // age of the open transaction, 0 without one
+	UndoRecords int64
+	Locks       int64 // TM/TX locks held
+	Blocking    int64 // sessions waiting on this one
+}
+
+func (s ToolSession) String() string {
+	return fmt.Sprintf("sid=%d serial#=%d user=%s module=%s action=%s machine=%s %s idle=%v tx=%v undo=%d locks=%d blocking=%d",
+		s.SID, s.Serial, s.Username, s.Module, s.Action, s.Machine, s.Status,
+		s.Idle, s.TxAge, s.UndoRecords, s.Locks, s.Blocking)
+}
+
+// Filter selects the sessions Abandoned returns
+type Filter struct {
+	Modules []string      // LIKE patterns on MODULE; default DefaultModule
+	MinIdle time.Duration // only sessions whose last call was at least this long ago
+	MinTx   time.Duration // a transaction counts as long from this age; 0 = any open transaction
+	All     bool          // also sessions holding no locks and no long transaction
+}
+
+// holds reports whether the session holds locks or a long transaction
+func (f Filter) holds(s ToolSession) bool {
+	if f.All || s.Locks > 0 || s.Blocking > 0 {
+		return true
+	}
+	open := s.TxAge > 0 || s.UndoRecords > 0
+	return open && s.TxAge >= f.MinTx
+}
+
+// abandonedSQL builds the listing query for the module patterns
+func abandonedSQL(modules []string) string {
+	conds := make([]string, len(modules))
+	for i := range modules {
+		conds[i] = fmt.Sprintf("s.module LIKE :%d", i+1)
+	}
+	return `SELECT s.sid, s.serial#, NVL(s.username, '-'), s.module, NVL(s.action, '-'), NVL(s.machine, '-'),
+       s.status, s.last_call_et,
+       NVL(ROUND((SYSDATE - t.start_date) * 86400), 0), NVL(t.used_urec, 0),
+       (SELECT COUNT(*) FROM v$lock l WHERE l.sid = s.sid AND l.type IN ('TM', 'TX') AND l.lmode > 0),
+       (SELECT COUNT(*) FROM v$session w WHERE w.blocking_session = s.sid)
+  FROM v$session s
+  LEFT JOIN v$transaction t ON t.addr = s.taddr
+ WHERE (` + strings.Join(conds, " OR ") + `)
+   AND s.audsid <> SYS_CONTEXT('USERENV', 'SESSIONID')
+ ORDER BY s.logon_time`
+}
+
+// Abandoned lists sessions of the tools that hold locks or a long
+// transaction, through a privileged connection (SELECT on V$SESSION,
+// V$TRANSACTION and V$LOCK). A crashed load leaves such a session behind
+// until the server notices the dead client.
+func Abandoned(ctx context.Context, admin *sql.DB, f Filter) ([]ToolSession, error) {
+	modules := f.Modules
+	if len(modules) == 0 {
+		modules = []string{DefaultModule}
+	}
+	args := make([]any, len(modules))
+	for i, m := range modules {
+		args[i] = m
+	}
+	rows, err := admin.QueryContext(ctx, abandonedSQL(modules), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	defer rows.Close()
+	var out []ToolSession
+	for rows.Next() {
+		var s ToolSession
+		var idle, txAge int64
+		if err := rows.Scan(&s.SID, &s.Serial, &s.Username, &s.Module, &s.Action, &s.Machine,
+			&s.Status, &idle, &txAge, &s.UndoRecords, &s.Locks, &s.Blocking); err != nil {
+			return nil, fmt.Errorf("list sessions: %w", err)
+		}
+		s.Tag = s.Module
+		s.Idle = time.Duration(idle) * time.Second
+		s.TxAge = time.Duration(txAge) * time.Second
+		if s.Idle < f.MinIdle || !f.holds(s) {
+			continue
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	return out, nil
+}
+
+// Kill ends a session with ALTER SYSTEM KILL SESSION (needs ALTER SYSTEM).
+// Its open transaction is rolled back; immediate skips waiting for the
+// session's current call to finish.
+func Kill(ctx context.Context, admin *sql.DB, s Session, immediate bool) error {
+	if s.SID <= 0 || s.Serial <= 0 {
+		return errors.New("kill session: sid and serial# are required")
+	}
+	stmt := fmt.Sprintf("ALTER SYSTEM KILL SESSION '%d,%d'", s.SID, s.Serial)
+	if immediate {
+		stmt += " IMMEDIATE"
+	}
+	if _, err := admin.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("kill session %d,%d: %w", s.SID, s.Serial, err)
+	}
+	return nil
+}
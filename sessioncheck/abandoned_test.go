@@ -0,0 +1,86 @@
+package sessioncheck
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sql-learn2/internal/sqlfake"
+)
+
+func TestAbandoned(t *testing.T) {
+	cols := []string{"SID", "SERIAL#", "USERNAME", "MODULE", "ACTION", "MACHINE", "STATUS", "LAST_CALL_ET", "TX_AGE", "USED_UREC", "LOCKS", "BLOCKING"}
+	rows := [][]any{
+		{int64(10), int64(1), "APP", "sql-learn2", "load", "h1", "INACTIVE", int64(3600), int64(3600), int64(500), int64(2), int64(1)}, // crashed load
+		{int64(11), int64(2), "APP", "sql-learn2", "upsert", "h1", "INACTIVE", int64(600), int64(0), int64(0), int64(0), int64(0)},     // idle, holds nothing
+		{int64(12), int64(3), "APP", "sql-learn2", "swap", "h2", "ACTIVE", int64(5), int64(5), int64(10), int64(1), int64(0)},          // running right now
+		{int64(13), int64(4), "APP", "sql-learn2", "load", "h2", "INACTIVE", int64(900), int64(120), int64(1), int64(0), int64(0)},     // short transaction
+	}
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int64
+	}{
+		{"holding anything", Filter{}, []int64{10, 12, 13}},
+		{"idle", Filter{MinIdle: time.Minute}, []int64{10, 13}},
+		{"long transactions", Filter{MinIdle: time.Minute, MinTx: 10 * time.Minute}, []int64{10}},
+		{"all", Filter{All: true}, []int64{10, 11, 12, 13}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("FROM v\\$session s", cols, rows...)
+			got, err := Abandoned(context.Background(), f.DB, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sids []int64
+			for _, s := range got {
+				sids = append(sids, s.SID)
+			}
+			if !reflect.DeepEqual(sids, tt.want) {
+				t.Errorf("sids = %v, want %v", sids, tt.want)
+			}
+		})
+	}
+
+	f := sqlfake.New(t)
+	f.OnQuery("FROM v\\$session s", cols, rows[0])
+	got, err := Abandoned(context.Background(), f.DB, Filter{Modules: []string{"a%", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ToolSession{Session: Session{SID: 10, Serial: 1, Tag: "sql-learn2"}, Username: "APP", Module: "sql-learn2",
+		Action: "load", Machine: "h1", Status: "INACTIVE", Idle: time.Hour, TxAge: time.Hour, UndoRecords: 500, Locks: 2, Blocking: 1}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("Abandoned() = %+v, want %+v", got, want)
+	}
+	c := f.Calls()[0]
+	if !strings.Contains(c.Query, "(s.module LIKE :1 OR s.module LIKE :2)") || !reflect.DeepEqual(c.Args, []any{"a%", "b"}) {
+		t.Errorf("query %q args %v", c.Query, c.Args)
+	}
+}
+
+func TestKill(t *testing.T) {
+	tests := []struct {
+		immediate bool
+		want      string
+	}{
+		{false, "ALTER SYSTEM KILL SESSION '10,1'"},
+		{true, "ALTER SYSTEM KILL SESSION '10,1' IMMEDIATE"},
+	}
+	for _, tt := range tests {
+		f := sqlfake.New(t)
+		if err := Kill(context.Background(), f.DB, Session{SID: 10, Serial: 1}, tt.immediate); err != nil {
+			t.Fatal(err)
+		}
+		if got := f.Queries(); !reflect.DeepEqual(got, []string{tt.want}) {
+			t.Errorf("queries = %q, want %q", got, tt.want)
+		}
+	}
+	if err := Kill(context.Background(), sqlfake.New(t).DB, Session{}, false); err == nil {
+		t.Error("expected error for a zero session")
+	}
+}
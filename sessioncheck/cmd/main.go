@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"sql-learn2/logging"
+	"sql-learn2/oraconn"
+	"sql-learn2/oraerr"
+	"sql-learn2/sessioncheck"
+)
+
+// Lists sessions left behind by the tools (found by the MODULE they set with
+// DBMS_APPLICATION_INFO) that still hold locks or an open transaction, and
+// optionally kills them. Connect as a user with SELECT on V$SESSION,
+// V$TRANSACTION and V$LOCK, plus ALTER SYSTEM for -kill. Example:
+//
+//	go run ./sessioncheck/cmd -min-idle 10m
+//	go run ./sessioncheck/cmd -min-idle 10m -kill
+//
+// ACTIVE sessions are listed but only killed with -force, since a long
+// running load looks the same as a hung one from the outside.
+func main() {
+	var modules []string
+	flag.Func("modules", "MODULE LIKE pattern of the tools' sessions (repeatable, default "+sessioncheck.DefaultModule+")", func(s string) error {
+		modules = append(modules, s)
+		return nil
+	})
+	minIdle := flag.Duration("min-idle", 5*time.Minute, "Only sessions whose last call was at least this long ago")
+	minTx := flag.Duration("min-tx", 0, "Only count transactions open at least this long (0 = any open transaction)")
+	all := flag.Bool("all", false, "Also list sessions holding no locks and no transaction")
+	kill := flag.Bool("kill", false, "Kill the listed sessions")
+	immediate := flag.Bool("immediate", false, "Kill with IMMEDIATE instead of waiting for the current call")
+	force := flag.Bool("force", false, "Also kill ACTIVE sessions")
+
+	var ora oraconn.Config
+	ora.RegisterFlags(flag.CommandLine)
+	var logCfg logging.Config
+	logCfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if _, err := logCfg.Setup(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	ctx := context.Background()
+	db, err := oraconn.Connect(ctx, ora)
+	if err != nil {
+		oraerr.Fatal("Failed to connect", err)
+	}
+	defer db.Close()
+
+	sessions, err := sessioncheck.Abandoned(ctx, db, sessioncheck.Filter{Modules: modules, MinIdle: *minIdle, MinTx: *minTx, All: *all})
+	if err != nil {
+		oraerr.Fatal("Failed to list sessions", err)
+	}
+	if len(sessions) == 0 {
+		log.Printf("No matching sessions")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SID\tSERIAL#\tUSER\tMODULE\tACTION\tMACHINE\tSTATUS\tIDLE\tTX AGE\tUNDO\tLOCKS\tBLOCKING")
+	for _, s := range sessions {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t%v\t%v\t%d\t%d\t%d\n", s.SID, s.Serial, s.Username, s.Module,
+			s.Action, s.Machine, s.Status, s.Idle, s.TxAge, s.UndoRecords, s.Locks, s.Blocking)
+	}
+	w.Flush()
+	if !*kill {
+		return
+	}
+
+	failed := 0
+	for _, s := range sessions {
+		if s.Status == "ACTIVE" && !*force {
+			log.Printf("Skipping ACTIVE session %d,%d (use -force)", s.SID, s.Serial)
+			continue
+		}
+		if err := sessioncheck.Kill(ctx, db, s.Session, *immediate); err != nil {
+			log.Printf("%v", err)
+			failed++
+			continue
+		}
+		log.Printf("Killed session %d,%d (%s %s)", s.SID, s.Serial, s.Module, s.Action)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,170 @@
+package sessioncheck
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Session identifies the server session a client connection runs in
+type Session struct {
+	SID    int64
+	Serial int64
+	Tag    string // CLIENT_IDENTIFIER set by Identify
+}
+
+func (s Session) String() string {
+	return fmt.Sprintf("sid=%d serial#=%d tag=%s", s.SID, s.Serial, s.Tag)
+}
+
+// Identify tags the session behind conn with DBMS_SESSION.SET_IDENTIFIER and
+// returns its SID and serial#. Needs no V$ privileges.
+func Identify(ctx context.Context, conn *sql.Conn, tag string) (Session, error) {
+	s := Session{Tag: tag}
+	if _, err := conn.ExecContext(ctx, "BEGIN DBMS_SESSION.SET_IDENTIFIER(:1); END;", tag); err != nil {
+		return s, fmt.Errorf("set client identifier: %w", err)
+	}
+	err := conn.QueryRowContext(ctx, `SELECT TO_NUMBER(SYS_CONTEXT('USERENV', 'SID')),
+	       DBMS_DEBUG_JDWP.CURRENT_SESSION_SERIAL
+	  FROM DUAL`).Scan(&s.SID, &s.Serial)
+	if err != nil {
+		return s, fmt.Errorf("read session identity: %w", err)
+	}
+	return s, nil
+}
+
+// Snapshot is the server-side state of a session at one point in time
+type Snapshot struct {
+	At          time.Duration // since verification started
+	Exists      bool
+	Status      string // V$SESSION.STATUS: ACTIVE, INACTIVE, KILLED, ...
+	Event       string
+	SQLID       string
+	TxStatus    string // V$TRANSACTION.STATUS, empty when no transaction is open
+	UndoRecords int64  // V$TRANSACTION.USED_UREC, shrinks while a rollback runs
+}
+
+func (s Snapshot) String() string {
+	if !s.Exists {
+		return fmt.Sprintf("+%v session gone", s.At.Round(time.Millisecond))
+	}
+	tx := "no transaction"
+	if s.TxStatus != "" {
+		tx = fmt.Sprintf("transaction %s, %d undo records", s.TxStatus, s.UndoRecords)
+	}
+	return fmt.Sprintf("+%v %s sql_id=%s event=%q, %s", s.At.Round(time.Millisecond), s.Status, s.SQLID, s.Event, tx)
+}
+
+// Query reads the session and its transaction through a privileged connection
+// (SELECT on V$SESSION and V$TRANSACTION)
+func Query(ctx context.Context, admin *sql.DB, s Session) (Snapshot, error) {
+	var snap Snapshot
+	var txStatus sql.NullString
+	err := admin.QueryRowContext(ctx, `SELECT s.status, s.event, NVL(s.sql_id, '-'), t.status, NVL(t.used_urec, 0)
+	  FROM v$session s
+	  LEFT JOIN v$transaction t ON t.addr = s.taddr
+	 WHERE s.sid = :1 AND s.serial# = :2`, s.SID, s.Serial).
+		Scan(&snap.Status, &snap.Event, &snap.SQLID, &txStatus, &snap.UndoRecords)
+	if errors.Is(err, sql.ErrNoRows) {
+		return snap, nil
+	}
+	if err != nil {
+		return snap, err
+	}
+	snap.Exists = true
+	snap.TxStatus = txStatus.String
+	return snap, nil
+}
+
+// Verdict summarizes what happened to the session after the client gave up
+type Verdict string
+
+const (
+	VerdictClean   Verdict = "CLEAN"         // session idle with no open transaction
+	VerdictGone    Verdict = "GONE"          // session ended (connection closed or killed)
+	VerdictRunning Verdict = "STILL RUNNING" // statement kept executing server-side
+	VerdictTxOpen  Verdict = "TX OPEN"       // idle but a transaction is still open (e.g. rolling back)
+	VerdictUnknown Verdict = "UNKNOWN"       // no snapshot could be taken
+)
+
+// verdictOf classifies the last snapshot
+func verdictOf(s Snapshot) Verdict {
+	switch {
+	case !s.Exists:
+		return VerdictGone
+	case s.Status == "ACTIVE":
+		return VerdictRunning
+	case s.TxStatus != "":
+		return VerdictTxOpen
+	}
+	return VerdictClean
+}
+
+// Report is the result of Verify
+type Report struct {
+	Session   Session
+	Snapshots []Snapshot
+	Verdict   Verdict
+	Settled   time.Duration // when the session was first seen clean or gone; -1 if never
+	Err       error
+}
+
+// Verify polls the session every interval until it is idle without a
+// transaction (or gone), or until wait has passed
+func Verify(ctx context.Context, admin *sql.DB, s Session, wait, interval time.Duration) Report {
+	r := Report{Session: s, Verdict: VerdictUnknown, Settled: -1}
+	start := time.Now()
+	for {
+		snap, err := Query(ctx, admin, s)
+		if err != nil {
+			r.Err = err
+			return r
+		}
+		snap.At = time.Since(start)
+		r.Snapshots = append(r.Snapshots, snap)
+		r.Verdict = verdictOf(snap)
+		if r.Verdict == VerdictClean || r.Verdict == VerdictGone {
+			r.Settled = snap.At
+			return r
+		}
+		if time.Since(start) >= wait {
+			return r
+		}
+		select {
+		case <-ctx.Done():
+			r.Err = ctx.Err()
+			return r
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Render formats the report, collapsing repeated states
+func (r Report) Render() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== Server-side session check (%s) ===\n", r.Session)
+	last := ""
+	for _, snap := range r.Snapshots {
+		state := fmt.Sprintf("%v|%s|%s|%s", snap.Exists, snap.Status, snap.SQLID, snap.TxStatus)
+		if state == last {
+			continue
+		}
+		last = state
+		fmt.Fprintf(&b, "  %s\n", snap)
+	}
+	if r.Err != nil {
+		fmt.Fprintf(&b, "  error: %v\n", r.Err)
+	}
+	switch {
+	case r.Settled >= 0:
+		fmt.Fprintf(&b, "  verdict: %s after %v\n", r.Verdict, r.Settled.Round(time.Millisecond))
+	case len(r.Snapshots) > 0:
+		fmt.Fprintf(&b, "  verdict: %s after waiting %v\n", r.Verdict, r.Snapshots[len(r.Snapshots)-1].At.Round(time.Millisecond))
+	default:
+		fmt.Fprintf(&b, "  verdict: %s\n", r.Verdict)
+	}
+	return b.String()
+}
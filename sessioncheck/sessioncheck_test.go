@@ -0,0 +1,54 @@
+package sessioncheck
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerdictOf(t *testing.T) {
+	tests := []struct {
+		name string
+		snap Snapshot
+		want Verdict
+	}{
+		{"gone", Snapshot{}, VerdictGone},
+		{"running", Snapshot{Exists: true, Status: "ACTIVE"}, VerdictRunning},
+		{"running with tx", Snapshot{Exists: true, Status: "ACTIVE", TxStatus: "ACTIVE"}, VerdictRunning},
+		{"tx open", Snapshot{Exists: true, Status: "INACTIVE", TxStatus: "ACTIVE", UndoRecords: 10}, VerdictTxOpen},
+		{"clean", Snapshot{Exists: true, Status: "INACTIVE"}, VerdictClean},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verdictOf(tt.snap); got != tt.want {
+				t.Errorf("verdictOf() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderCollapsesRepeatedStates(t *testing.T) {
+	active := Snapshot{Exists: true, Status: "ACTIVE", SQLID: "abc", TxStatus: "ACTIVE"}
+	r := Report{
+		Session: Session{SID: 1, Serial: 2, Tag: "t"},
+		Snapshots: []Snapshot{
+			withAt(active, 0),
+			withAt(active, 100*time.Millisecond),
+			{At: 200 * time.Millisecond, Exists: true, Status: "INACTIVE"},
+		},
+		Verdict: VerdictClean,
+		Settled: 200 * time.Millisecond,
+	}
+	out := r.Render()
+	if n := strings.Count(out, "ACTIVE sql_id=abc"); n != 1 {
+		t.Errorf("expected repeated ACTIVE snapshot once, got %d in:\n%s", n, out)
+	}
+	if !strings.Contains(out, "verdict: CLEAN after 200ms") {
+		t.Errorf("missing verdict line in:\n%s", out)
+	}
+}
+
+func withAt(s Snapshot, at time.Duration) Snapshot {
+	s.At = at
+	return s
+}
@@ -0,0 +1,86 @@
+//go:build otel
+
+// Build with -tags otel after fetching the modules:
+//
+//	go get go.opentelemetry.io/otel go.opentelemetry.io/otel/sdk \
+//		go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup exports spans over OTLP/gRPC when OTEL_EXPORTER_OTLP_ENDPOINT (or the
+// traces-specific variable) is set; the exporter reads the other OTEL_* variables itself.
+// Call shutdown before exit to flush pending spans.
+func Setup(ctx context.Context, service string) (shutdown func(context.Context) error, err error) {
+	noop := func(context.Context) error { return nil }
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return noop, nil
+	}
+	exp, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return noop, fmt.Errorf("otlp exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(service)))
+	if err != nil {
+		return noop, fmt.Errorf("otel resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	SetBackend(otelBackend{tracer: tp.Tracer("sql-learn2")})
+	return tp.Shutdown, nil
+}
+
+type otelBackend struct {
+	tracer trace.Tracer
+}
+
+func (b otelBackend) Start(ctx context.Context, name string, attrs []Attr) (context.Context, BackendSpan) {
+	ctx, s := b.tracer.Start(ctx, name, trace.WithAttributes(toOtel(attrs)...))
+	return ctx, otelSpan{s}
+}
+
+type otelSpan struct {
+	s trace.Span
+}
+
+func (s otelSpan) SetAttributes(attrs []Attr) { s.s.SetAttributes(toOtel(attrs)...) }
+
+func (s otelSpan) RecordError(err error) {
+	s.s.RecordError(err)
+	s.s.SetStatus(codes.Error, err.Error())
+}
+
+func (s otelSpan) End() { s.s.End() }
+
+func toOtel(attrs []Attr) []attribute.KeyValue {
+	kv := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		switch v := a.Value.(type) {
+		case string:
+			kv = append(kv, attribute.String(a.Key, v))
+		case int64:
+			kv = append(kv, attribute.Int64(a.Key, v))
+		case float64:
+			kv = append(kv, attribute.Float64(a.Key, v))
+		case bool:
+			kv = append(kv, attribute.Bool(a.Key, v))
+		default:
+			kv = append(kv, attribute.String(a.Key, fmt.Sprint(v)))
+		}
+	}
+	return kv
+}
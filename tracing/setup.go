@@ -0,0 +1,18 @@
+//go:build !otel
+
+package tracing
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// Setup is a no-op in builds without the otel tag. It warns when an OTLP
+// endpoint is configured, since the spans would silently go nowhere.
+func Setup(ctx context.Context, service string) (shutdown func(context.Context) error, err error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" {
+		slog.Warn("OTLP endpoint set but tracing is not compiled in; rebuild with -tags otel", "service", service)
+	}
+	return func(context.Context) error { return nil }, nil
+}
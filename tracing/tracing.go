@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Span names used by the load, swap and exchange workflows
+const (
+	SpanCSVRead           = "csv.read"
+	SpanBatchInsert       = "db.batch_insert"
+	SpanMerge             = "db.merge"
+	SpanSynonymSwap       = "swap.synonym"
+	SpanPartitionExchange = "exchange.partition"
+	SpanMVRefresh         = "db.mview_refresh"
+)
+
+// Attribute keys; they match the logging field names
+const (
+	AttrTable      = "table"
+	AttrRows       = "rows"
+	AttrFile       = "file"
+	AttrDurationMS = "duration_ms"
+)
+
+// Attr is a span attribute; Value is a string, int64, float64 or bool
+type Attr struct {
+	Key   string
+	Value any
+}
+
+func String(k, v string) Attr      { return Attr{k, v} }
+func Int(k string, v int) Attr     { return Attr{k, int64(v)} }
+func Int64(k string, v int64) Attr { return Attr{k, v} }
+func Bool(k string, v bool) Attr   { return Attr{k, v} }
+
+// Backend creates spans in a tracing system; see otel.go (build tag otel) for OTLP export
+type Backend interface {
+	Start(ctx context.Context, name string, attrs []Attr) (context.Context, BackendSpan)
+}
+
+// BackendSpan is one span of a Backend
+type BackendSpan interface {
+	SetAttributes(attrs []Attr)
+	RecordError(err error)
+	End()
+}
+
+var (
+	mu      sync.RWMutex
+	backend Backend
+)
+
+// SetBackend installs b for all later spans; nil turns tracing off
+func SetBackend(b Backend) {
+	mu.Lock()
+	defer mu.Unlock()
+	backend = b
+}
+
+func current() Backend {
+	mu.RLock()
+	defer mu.RUnlock()
+	return backend
+}
+
+// Span wraps a backend span and adds the duration when it ends.
+// The zero value and nil are valid and do nothing.
+type Span struct {
+	s     BackendSpan
+	start time.Time
+}
+
+// Start begins a span; without a backend it returns ctx unchanged and a no-op span
+func Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	b := current()
+	if b == nil {
+		return ctx, nil
+	}
+	ctx, s := b.Start(ctx, name, attrs)
+	return ctx, &Span{s: s, start: time.Now()}
+}
+
+// SetAttributes adds attributes, e.g. the row count once it is known
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.s.SetAttributes(attrs)
+}
+
+// End records err (if any) and the duration, then ends the span
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	if err != nil {
+		s.s.RecordError(err)
+	}
+	s.s.SetAttributes([]Attr{Int64(AttrDurationMS, time.Since(s.start).Milliseconds())})
+	s.s.End()
+}
@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type recorder struct {
+	spans []*recSpan
+}
+
+type recSpan struct {
+	name  string
+	attrs map[string]any
+	err   error
+	ended bool
+}
+
+func (r *recorder) Start(ctx context.Context, name string, attrs []Attr) (context.Context, BackendSpan) {
+	s := &recSpan{name: name, attrs: map[string]any{}}
+	s.SetAttributes(attrs)
+	r.spans = append(r.spans, s)
+	return ctx, s
+}
+
+func (s *recSpan) SetAttributes(attrs []Attr) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+func (s *recSpan) RecordError(err error) { s.err = err }
+func (s *recSpan) End()                  { s.ended = true }
+
+func TestNoBackend(t *testing.T) {
+	SetBackend(nil)
+	ctx := context.Background()
+	got, span := Start(ctx, SpanBatchInsert)
+	if got != ctx || span != nil {
+		t.Fatal("expected unchanged ctx and nil span without a backend")
+	}
+	// nil spans are safe to use
+	span.SetAttributes(Int(AttrRows, 1))
+	span.End(errors.New("ignored"))
+}
+
+func TestSpanAttributes(t *testing.T) {
+	rec := &recorder{}
+	SetBackend(rec)
+	defer SetBackend(nil)
+
+	_, span := Start(context.Background(), SpanMerge, String(AttrTable, "T"))
+	span.SetAttributes(Int(AttrRows, 42))
+	failure := errors.New("ORA-00001")
+	span.End(failure)
+
+	if len(rec.spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(rec.spans))
+	}
+	s := rec.spans[0]
+	if s.name != SpanMerge || !s.ended || s.err != failure {
+		t.Errorf("unexpected span %+v", s)
+	}
+	if s.attrs[AttrTable] != "T" || s.attrs[AttrRows] != int64(42) {
+		t.Errorf("unexpected attributes %v", s.attrs)
+	}
+	if _, ok := s.attrs[AttrDurationMS]; !ok {
+		t.Error("duration attribute missing")
+	}
+}
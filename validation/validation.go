@@ -0,0 +1,408 @@
+package validation
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"sql-learn2/csvfile"
+	"sql-learn2/dynamic"
+	"sql-learn2/logging"
+)
+
+// Column is one compared column; Type decides which metrics are computed
+type Column struct {
+	Name string
+	Type dynamic.DataType
+}
+
+// ColumnProfile holds order-independent metrics of one column:
+//   - NUMBER: exact SUM
+//   - VARCHAR2, DATE, TIMESTAMP: sum of the first 60 bits of each value's MD5
+//     (DATE/TIMESTAMP as YYYY-MM-DD HH24:MI:SS, so fractions are ignored)
+//   - VARCHAR2 also the total length in bytes
+//   - CLOB: only the non-NULL count
+type ColumnProfile struct {
+	Column
+	NonNull int64
+	Sum     *big.Rat // NUMBER
+	Hash    *big.Int // VARCHAR2, DATE, TIMESTAMP
+	Bytes   int64    // VARCHAR2
+}
+
+// Profile is the metrics of a CSV file or table
+type Profile struct {
+	Rows    int64
+	Columns []ColumnProfile
+}
+
+// Discrepancy is one metric that differs between source and target
+type Discrepancy struct {
+	Column string // empty for the row count
+	Metric string
+	Source string
+	Target string
+}
+
+func (d Discrepancy) String() string {
+	if d.Column == "" {
+		return fmt.Sprintf("%s: source %s, target %s", d.Metric, d.Source, d.Target)
+	}
+	return fmt.Sprintf("%s %s: source %s, target %s", d.Column, d.Metric, d.Source, d.Target)
+}
+
+// Report is the result of Validate
+type Report struct {
+	Table         string
+	Source        *Profile
+	Target        *Profile
+	Discrepancies []Discrepancy
+	Duration      time.Duration
+}
+
+// OK reports whether source and target agree
+func (r *Report) OK() bool { return len(r.Discrepancies) == 0 }
+
+// Err returns nil when OK, otherwise an error listing the discrepancies
+func (r *Report) Err() error {
+	if r.OK() {
+		return nil
+	}
+	msgs := make([]string, len(r.Discrepancies))
+	for i, d := range r.Discrepancies {
+		msgs[i] = d.String()
+	}
+	return fmt.Errorf("validation of %s failed: %s", r.Table, strings.Join(msgs, "; "))
+}
+
+// dateLayouts are the CSV date formats understood when normalizing DATE/TIMESTAMP cells
+var dateLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+const dateLayout = "2006-01-02 15:04:05"
+
+// Validate profiles a CSV in csvdb format (header, types row, data) and the
+// table it was loaded into, and compares them. Use it as a post-step of a
+// load; an upsert target holding other rows will not match.
+func Validate(ctx context.Context, db *sql.DB, csvPath, table string) (*Report, error) {
+	start := time.Now()
+	src, err := ProfileCSV(csvPath)
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]Column, len(src.Columns))
+	for i, c := range src.Columns {
+		cols[i] = c.Column
+	}
+	dst, err := ProfileTable(ctx, db, table, cols)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{Table: table, Source: src, Target: dst, Discrepancies: Compare(src, dst), Duration: time.Since(start)}
+	logging.FromContext(ctx).Info("Validated load", logging.FieldTable, table, logging.FieldFile, csvPath,
+		logging.FieldRows, src.Rows, "discrepancies", len(r.Discrepancies), logging.FieldDuration, r.Duration)
+	return r, nil
+}
+
+// ProfileCSV computes the metrics of a CSV in csvdb format. Cells are
+// trimmed and empty cells count as NULL, as csvdb loads them.
+func ProfileCSV(path string) (*Profile, error) {
+	f, err := csvfile.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open csv: %w", err)
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+
+	header, err := readRecord(r)
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	types, err := readRecord(r)
+	if err != nil {
+		return nil, fmt.Errorf("read types row: %w", err)
+	}
+	if len(types) < len(header) {
+		return nil, fmt.Errorf("types row has fewer cells (%d) than headers (%d)", len(types), len(header))
+	}
+	p := &Profile{Columns: make([]ColumnProfile, len(header))}
+	for i, h := range header {
+		name := normalizeIdentifierForOracle(h)
+		if name == "" {
+			return nil, fmt.Errorf("invalid column name at position %d: %q", i+1, h)
+		}
+		dt, err := parseType(types[i])
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", name, err)
+		}
+		p.Columns[i] = newColumnProfile(Column{Name: name, Type: dt})
+	}
+
+	for line := 3; ; line++ {
+		rec, err := readRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+		p.Rows++
+		for i := range p.Columns {
+			cell := ""
+			if i < len(rec) {
+				cell = rec[i]
+			}
+			if err := p.Columns[i].add(cell); err != nil {
+				return nil, fmt.Errorf("row %d col %d: %w", line, i+1, err)
+			}
+		}
+	}
+	return p, nil
+}
+
+// readRecord returns the next record with trimmed cells, skipping empty lines like csvdb
+func readRecord(r *csv.Reader) ([]string, error) {
+	for {
+		rec, err := r.Read()
+		if err != nil {
+			return nil, err
+		}
+		empty := true
+		for i := range rec {
+			rec[i] = strings.TrimSpace(rec[i])
+			if rec[i] != "" {
+				empty = false
+			}
+		}
+		if !empty {
+			return rec, nil
+		}
+	}
+}
+
+func parseType(s string) (dynamic.DataType, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "VARCHAR", "VARCHAR2":
+		return dynamic.Varchar2, nil
+	case "NUMBER":
+		return dynamic.Number, nil
+	case "DATE":
+		return dynamic.Date, nil
+	case "TIMESTAMP":
+		return dynamic.Timestamp, nil
+	case "CLOB":
+		return dynamic.Clob, nil
+	}
+	return "", fmt.Errorf("unsupported type %q", s)
+}
+
+func newColumnProfile(c Column) ColumnProfile {
+	cp := ColumnProfile{Column: c}
+	switch c.Type {
+	case dynamic.Number:
+		cp.Sum = new(big.Rat)
+	case dynamic.Varchar2, dynamic.Date, dynamic.Timestamp:
+		cp.Hash = new(big.Int)
+	}
+	return cp
+}
+
+// add folds one CSV cell into the metrics
+func (c *ColumnProfile) add(cell string) error {
+	if cell == "" {
+		return nil
+	}
+	c.NonNull++
+	switch c.Type {
+	case dynamic.Number:
+		v, ok := new(big.Rat).SetString(cell)
+		if !ok {
+			return fmt.Errorf("invalid NUMBER %q", cell)
+		}
+		c.Sum.Add(c.Sum, v)
+	case dynamic.Varchar2:
+		c.Bytes += int64(len(cell))
+		c.Hash.Add(c.Hash, hashPrefix(cell))
+	case dynamic.Date, dynamic.Timestamp:
+		c.Hash.Add(c.Hash, hashPrefix(normalizeDate(cell)))
+	}
+	return nil
+}
+
+// hashPrefix is the first 15 hex digits (60 bits) of the MD5 of s, matching
+// TO_NUMBER(SUBSTR(RAWTOHEX(STANDARD_HASH(s, 'MD5')), 1, 15), 'XXXXXXXXXXXXXXX')
+func hashPrefix(s string) *big.Int {
+	sum := md5.Sum([]byte(s))
+	n, _ := strconv.ParseUint(hex.EncodeToString(sum[:])[:15], 16, 64)
+	return new(big.Int).SetUint64(n)
+}
+
+// normalizeDate renders a CSV date like TO_CHAR(col, 'YYYY-MM-DD HH24:MI:SS');
+// unparseable values are hashed as they are and show up as a discrepancy
+func normalizeDate(s string) string {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(dateLayout)
+		}
+	}
+	return s
+}
+
+// ProfileTable computes the same metrics as ProfileCSV over table in one query
+func ProfileTable(ctx context.Context, db *sql.DB, table string, cols []Column) (*Profile, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	query, n := profileSQL(table, cols)
+	vals := make([]sql.NullString, n)
+	dest := make([]any, n)
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	if err := db.QueryRowContext(ctx, query).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("profile table %s: %w", table, err)
+	}
+
+	p := &Profile{Columns: make([]ColumnProfile, len(cols))}
+	next := 0
+	take := func() string {
+		next++
+		return vals[next-1].String
+	}
+	var err error
+	if p.Rows, err = parseInt(take()); err != nil {
+		return nil, fmt.Errorf("profile table %s: %w", table, err)
+	}
+	for i, c := range cols {
+		cp := newColumnProfile(c)
+		if cp.NonNull, err = parseInt(take()); err != nil {
+			return nil, fmt.Errorf("profile table %s: %w", table, err)
+		}
+		switch c.Type {
+		case dynamic.Number:
+			if s := take(); s != "" {
+				if _, ok := cp.Sum.SetString(s); !ok {
+					return nil, fmt.Errorf("profile table %s: invalid SUM(%s) %q", table, c.Name, s)
+				}
+			}
+		case dynamic.Varchar2, dynamic.Date, dynamic.Timestamp:
+			if s := take(); s != "" {
+				if _, ok := cp.Hash.SetString(s, 10); !ok {
+					return nil, fmt.Errorf("profile table %s: invalid hash of %s %q", table, c.Name, s)
+				}
+			}
+			if c.Type == dynamic.Varchar2 {
+				if cp.Bytes, err = parseInt(take()); err != nil {
+					return nil, fmt.Errorf("profile table %s: %w", table, err)
+				}
+			}
+		}
+		p.Columns[i] = cp
+	}
+	return p, nil
+}
+
+// profileSQL builds the aggregate query and returns how many values it selects.
+// Aggregates are rendered with TO_CHAR(..., 'TM9') so large sums arrive exactly.
+func profileSQL(table string, cols []Column) (string, int) {
+	items := []string{"COUNT(*)"}
+	hash := func(expr string) string {
+		return fmt.Sprintf("TO_CHAR(SUM(TO_NUMBER(SUBSTR(RAWTOHEX(STANDARD_HASH(%s, 'MD5')), 1, 15), 'XXXXXXXXXXXXXXX')), 'TM9')", expr)
+	}
+	for _, c := range cols {
+		items = append(items, fmt.Sprintf("COUNT(%s)", c.Name))
+		switch c.Type {
+		case dynamic.Number:
+			items = append(items, fmt.Sprintf("TO_CHAR(SUM(%s), 'TM9')", c.Name))
+		case dynamic.Varchar2:
+			items = append(items, hash(c.Name), fmt.Sprintf("NVL(SUM(LENGTHB(%s)), 0)", c.Name))
+		case dynamic.Date, dynamic.Timestamp:
+			items = append(items, hash(fmt.Sprintf("TO_CHAR(%s, 'YYYY-MM-DD HH24:MI:SS')", c.Name)))
+		}
+	}
+	return fmt.Sprintf("SELECT %s FROM %s", strings.Join(items, ", "), table), len(items)
+}
+
+func parseInt(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// Compare lists every metric that differs between source and target.
+// Columns are matched by name; a column missing from target is reported.
+func Compare(src, dst *Profile) []Discrepancy {
+	var out []Discrepancy
+	if src.Rows != dst.Rows {
+		out = append(out, Discrepancy{Metric: "rows", Source: strconv.FormatInt(src.Rows, 10), Target: strconv.FormatInt(dst.Rows, 10)})
+	}
+	byName := make(map[string]*ColumnProfile, len(dst.Columns))
+	for i := range dst.Columns {
+		byName[dst.Columns[i].Name] = &dst.Columns[i]
+	}
+	for _, s := range src.Columns {
+		d, ok := byName[s.Name]
+		if !ok {
+			out = append(out, Discrepancy{Column: s.Name, Metric: "column", Source: "present", Target: "missing"})
+			continue
+		}
+		diff := func(metric, a, b string) {
+			if a != b {
+				out = append(out, Discrepancy{Column: s.Name, Metric: metric, Source: a, Target: b})
+			}
+		}
+		diff("non-null count", strconv.FormatInt(s.NonNull, 10), strconv.FormatInt(d.NonNull, 10))
+		if s.Sum != nil && d.Sum != nil {
+			diff("sum", s.Sum.RatString(), d.Sum.RatString())
+		}
+		if s.Hash != nil && d.Hash != nil {
+			diff("hash", s.Hash.String(), d.Hash.String())
+		}
+		if s.Type == dynamic.Varchar2 && d.Type == dynamic.Varchar2 {
+			diff("bytes", strconv.FormatInt(s.Bytes, 10), strconv.FormatInt(d.Bytes, 10))
+		}
+	}
+	return out
+}
+
+// normalizeIdentifierForOracle mirrors csvdb's header normalization so CSV
+// columns map to the loaded table's columns
+func normalizeIdentifierForOracle(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	s = strings.ReplaceAll(s, " ", "_")
+	b := make([]rune, 0, len(s))
+	for _, r := range s {
+		if (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			b = append(b, r)
+		} else {
+			b = append(b, '_')
+		}
+	}
+	upper := strings.ToUpper(string(b))
+	if !(upper[0] >= 'A' && upper[0] <= 'Z') {
+		upper = "X" + upper
+	}
+	if len(upper) > 30 {
+		upper = upper[:30]
+	}
+	return upper
+}
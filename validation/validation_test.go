@@ -0,0 +1,142 @@
+package validation
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sql-learn2/dynamic"
+	"sql-learn2/internal/sqlfake"
+	"sql-learn2/internal/testharness"
+	"sql-learn2/logging"
+)
+
+var quiet = logging.WithLogger(context.Background(), logging.Discard())
+
+func TestHashPrefix(t *testing.T) {
+	// MD5("abc") = 900150983cd24fb0d6963f7d28e17f72
+	want, _ := new(big.Int).SetString("900150983cd24fb", 16)
+	if got := hashPrefix("abc"); got.Cmp(want) != 0 {
+		t.Errorf("hashPrefix(abc) = %x, want %x", got, want)
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	tests := map[string]string{
+		"2024-03-01":                "2024-03-01 00:00:00",
+		"2024-03-01 10:11:12":       "2024-03-01 10:11:12",
+		"2024-03-01 10:11:12.345":   "2024-03-01 10:11:12",
+		"2024-03-01T10:11:12Z":      "2024-03-01 10:11:12",
+		"01/03/2024":                "01/03/2024",
+		"2024-03-01T10:11:12+07:00": "2024-03-01 10:11:12",
+	}
+	for in, want := range tests {
+		if got := normalizeDate(in); got != want {
+			t.Errorf("normalizeDate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestProfileCSV(t *testing.T) {
+	path := testharness.WriteCSV(t, "p.csv",
+		"id,Name,amount,created",
+		"NUMBER,VARCHAR2,NUMBER,DATE",
+		"1,ab,1.50,2024-01-02",
+		"",
+		"2,,2.25,",
+		"3,cd",
+	)
+	p, err := ProfileCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Rows != 3 {
+		t.Errorf("Rows = %d, want 3", p.Rows)
+	}
+	tests := []struct {
+		col     int
+		name    string
+		nonNull int64
+		sum     string
+		hash    *big.Int
+		bytes   int64
+	}{
+		{0, "ID", 3, "6", nil, 0},
+		{1, "NAME", 2, "", new(big.Int).Add(hashPrefix("ab"), hashPrefix("cd")), 4},
+		{2, "AMOUNT", 2, "15/4", nil, 0},
+		{3, "CREATED", 1, "", hashPrefix("2024-01-02 00:00:00"), 0},
+	}
+	for _, tt := range tests {
+		c := p.Columns[tt.col]
+		if c.Name != tt.name || c.NonNull != tt.nonNull || c.Bytes != tt.bytes {
+			t.Errorf("column %d = %s nonNull %d bytes %d", tt.col, c.Name, c.NonNull, c.Bytes)
+		}
+		if tt.sum != "" && c.Sum.RatString() != tt.sum {
+			t.Errorf("%s sum = %s, want %s", c.Name, c.Sum.RatString(), tt.sum)
+		}
+		if tt.hash != nil && c.Hash.Cmp(tt.hash) != 0 {
+			t.Errorf("%s hash = %s, want %s", c.Name, c.Hash, tt.hash)
+		}
+	}
+
+	bad := testharness.WriteCSV(t, "bad.csv", "id", "NUMBER", "x")
+	if _, err := ProfileCSV(bad); err == nil || !strings.Contains(err.Error(), `row 3 col 1: invalid NUMBER "x"`) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestProfileSQL(t *testing.T) {
+	cols := []Column{{"ID", dynamic.Number}, {"NAME", dynamic.Varchar2}, {"AT", dynamic.Date}, {"DOC", dynamic.Clob}}
+	got, n := profileSQL("T", cols)
+	h := func(e string) string {
+		return "TO_CHAR(SUM(TO_NUMBER(SUBSTR(RAWTOHEX(STANDARD_HASH(" + e + ", 'MD5')), 1, 15), 'XXXXXXXXXXXXXXX')), 'TM9')"
+	}
+	want := "SELECT COUNT(*), COUNT(ID), TO_CHAR(SUM(ID), 'TM9'), COUNT(NAME), " + h("NAME") +
+		", NVL(SUM(LENGTHB(NAME)), 0), COUNT(AT), " + h("TO_CHAR(AT, 'YYYY-MM-DD HH24:MI:SS')") + ", COUNT(DOC) FROM T"
+	if got != want || n != 9 {
+		t.Errorf("profileSQL =\n%s (%d)\nwant\n%s (9)", got, n, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	path := testharness.WriteCSV(t, "p.csv", "id,name", "NUMBER,VARCHAR2", "1,ab", "2,cd")
+	hash := new(big.Int).Add(hashPrefix("ab"), hashPrefix("cd")).String()
+	tests := []struct {
+		name string
+		row  []any // COUNT(*), COUNT(ID), SUM(ID), COUNT(NAME), hash, bytes
+		want []Discrepancy
+	}{
+		{"match", []any{"2", "2", "3", "2", hash, "4"}, nil},
+		{"differences", []any{"3", "2", "4", "2", hash, "5"}, []Discrepancy{
+			{Metric: "rows", Source: "2", Target: "3"},
+			{Column: "ID", Metric: "sum", Source: "3", Target: "4"},
+			{Column: "NAME", Metric: "bytes", Source: "4", Target: "5"},
+		}},
+		{"empty table", []any{"0", "0", nil, "0", nil, "0"}, []Discrepancy{
+			{Metric: "rows", Source: "2", Target: "0"},
+			{Column: "ID", Metric: "non-null count", Source: "2", Target: "0"},
+			{Column: "ID", Metric: "sum", Source: "3", Target: "0"},
+			{Column: "NAME", Metric: "non-null count", Source: "2", Target: "0"},
+			{Column: "NAME", Metric: "hash", Source: hash, Target: "0"},
+			{Column: "NAME", Metric: "bytes", Source: "4", Target: "0"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqlfake.New(t)
+			f.OnQuery("^SELECT COUNT", []string{"a", "b", "c", "d", "e", "f"}, tt.row)
+			r, err := Validate(quiet, f.DB, path, "T")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(r.Discrepancies, tt.want) {
+				t.Errorf("discrepancies:\n got %v\nwant %v", r.Discrepancies, tt.want)
+			}
+			if r.OK() != (tt.want == nil) || (r.Err() == nil) != r.OK() {
+				t.Errorf("OK() = %v, Err() = %v", r.OK(), r.Err())
+			}
+		})
+	}
+}